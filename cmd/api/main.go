@@ -2,45 +2,186 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
+	"log/slog"
 	"os"
 	"os/signal"
-	"sync"
+	"syscall"
 	"time"
+	_ "time/tzdata" // Subscriber timezones of send windows, on hosts without a tz database
 
 	"newsletter/config"
-	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/internal/infrastructure/errorlog"
+	"newsletter/internal/infrastructure/firebase"
+	"newsletter/internal/infrastructure/preflight"
+	"newsletter/internal/infrastructure/secretbox"
+	"newsletter/internal/infrastructure/secrets"
+	subscriberepo "newsletter/internal/subscriptions/infrastructure/firebase"
 	transporthttp "newsletter/transport/http"
 )
 
 func main() {
-	wp := workerpool.NewWorkerPool(config.GetEnv("WORKERS", ""), config.GetEnv("BUFFER_SIZE", ""), &sync.WaitGroup{})
-	wp.Start()
+	check := flag.Bool("check", false, "verify the configured dependencies, print a report and exit")
+	format := flag.String("format", "text", "format of the --check report: text or json")
+	timeout := flag.Duration("check-timeout", 10*time.Second, "maximum duration of each --check verification")
+	normalize := flag.Bool("normalize-subscriptions", false, "rewrite the newsletter IDs of the stored subscriptions in canonical UUID form and exit")
+	encrypt := flag.Bool("encrypt-subscriptions", false, "encrypt the email addresses of the subscriptions stored in clear with PII_ENCRYPTION_KEY and exit")
+	dryRun := flag.Bool("dry-run", false, "with --normalize-subscriptions or --encrypt-subscriptions, report the changes without writing them")
+	flag.Parse()
 
-	app := transporthttp.NewApp(wp)
+	// Secrets managers replace the secrets of the environment before the
+	// configuration is read, for the API and the commands alike.
+	secretSource, err := secrets.NewSourceFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid secrets configuration: %v", err)
+	}
+	refreshInterval, err := secrets.RefreshIntervalFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid secrets configuration: %v", err)
+	}
+	if secretSource != nil {
+		loadCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		loaded, err := secrets.Load(loadCtx, secretSource)
+		cancel()
+		if err != nil {
+			log.Fatalf("Can't load secrets! Error: %v", err)
+		}
+		slog.Info("loaded secrets", "names", loaded)
+	}
 
-	server := &http.Server{
-		Addr:    ":8001",
-		Handler: app.Routes(),
+	if *check {
+		os.Exit(runPreflight(*format, *timeout))
+	}
+	if *normalize {
+		os.Exit(runNormalizeSubscriptions(*dryRun))
+	}
+	if *encrypt {
+		os.Exit(runEncryptSubscriptions(*dryRun))
 	}
 
-	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
-		}
-	}()
+	// Keep the last errors for the administration API. The level changes
+	// when the configuration is reloaded.
+	logLevel := new(slog.LevelVar)
+	recentErrors := errorlog.NewRecorder(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}), 100)
+	slog.SetDefault(slog.New(recentErrors))
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = transporthttp.Run(ctx, cfg, transporthttp.RunOptions{
+		LogLevel:       logLevel,
+		RecentErrors:   recentErrors,
+		Secrets:        secretSource,
+		SecretsRefresh: refreshInterval,
+	})
+	if err != nil {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
+
+// runPreflight verifies the dependencies of the API and prints the report
+// to stdout. It returns the exit code: 0 if every check passed or was
+// skipped, 1 otherwise.
+func runPreflight(format string, timeout time.Duration) int {
+	report := preflight.Run(context.Background(), preflight.Checks(config.PreflightFromEnv()), timeout)
+
+	var err error
+	switch format {
+	case "json":
+		err = report.WriteJSON(os.Stdout)
+	case "text":
+		err = report.WriteText(os.Stdout)
+	default:
+		log.Printf("unknown report format %q: use text or json", format)
+		return 2
+	}
+	if err != nil {
+		log.Printf("write report: %v", err)
+		return 1
+	}
 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
-	<-stop
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// runNormalizeSubscriptions rewrites the newsletter IDs of the stored
+// subscriptions in canonical form and prints a summary to stdout. It returns
+// the exit code: 0 on success, 1 if Firestore could not be read or written.
+func runNormalizeSubscriptions(dryRun bool) int {
+	ctx := context.Background()
+	client, err := firebase.InitFirestore(ctx)
+	if err != nil {
+		log.Printf("initialize Firestore: %v", err)
+		return 1
+	}
+	defer client.Close()
 
-	log.Println("Shutting down...")
-	server.Shutdown(ctx)
+	report, err := subscriberepo.NewSubscriptionRepository(client).NormalizeNewsletterIDs(ctx, dryRun)
+	if err != nil {
+		log.Printf("normalize subscriptions: %v", err)
+		return 1
+	}
 
-	wp.Shutdown()
-	wp.Wait()
+	verb := "updated"
+	if dryRun {
+		verb = "to update"
+	}
+	fmt.Printf("%d subscriptions scanned, %d %s\n", report.Scanned, report.Updated, verb)
+	for _, id := range report.Invalid {
+		fmt.Printf("invalid newsletter ID: subscription %s\n", id)
+	}
+	return 0
+}
+
+// runEncryptSubscriptions encrypts the email addresses of the subscriptions
+// stored in clear and prints a summary to stdout. It returns the exit code:
+// 0 on success, 1 if PII_ENCRYPTION_KEY is missing or invalid or Firestore
+// could not be read or written.
+func runEncryptSubscriptions(dryRun bool) int {
+	key, err := config.PIIKeyFromEnv()
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	if key == nil {
+		log.Print("PII_ENCRYPTION_KEY is required to encrypt subscriptions")
+		return 1
+	}
+	pii, err := secretbox.NewPII(key)
+	if err != nil {
+		log.Printf("configure PII encryption: %v", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	client, err := firebase.InitFirestore(ctx)
+	if err != nil {
+		log.Printf("initialize Firestore: %v", err)
+		return 1
+	}
+	defer client.Close()
+
+	repo := subscriberepo.NewSubscriptionRepository(client)
+	repo.SetEncryption(pii)
+	scanned, encrypted, err := repo.EncryptEmails(ctx, dryRun)
+	if err != nil {
+		log.Printf("encrypt subscriptions: %v", err)
+		return 1
+	}
+
+	verb := "encrypted"
+	if dryRun {
+		verb = "to encrypt"
+	}
+	fmt.Printf("%d subscriptions scanned, %d %s\n", scanned, encrypted, verb)
+	return 0
 }
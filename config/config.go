@@ -0,0 +1,917 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"newsletter/internal/infrastructure/abuse"
+	"newsletter/internal/infrastructure/alerting"
+	"newsletter/internal/infrastructure/artifacts"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/infrastructure/workerpool"
+	limitsdomain "newsletter/internal/limits/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	notificationdomain "newsletter/internal/notifications/domain"
+	postdomain "newsletter/internal/posts/domain"
+	"newsletter/internal/posts/infrastructure/spamcheck"
+	subscriptionapp "newsletter/internal/subscriptions/application"
+	"newsletter/internal/subscriptions/infrastructure/captcha"
+	"newsletter/internal/subscriptions/infrastructure/emailcheck"
+	userapp "newsletter/internal/users/application"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config is the configuration of the API. It is loaded and validated once
+// at startup by Load and passed to the components that need it.
+type Config struct {
+	Store     string // Storage backend, "postgres" or "memory" (STORE, default "postgres")
+	DSN       string // PostgreSQL connection string (DSN), unused with the memory store
+	JWTSecret string // Key signing the access tokens (JWT_SECRET_KEY)
+	BaseURL   string // Public URL of the API, used in links (BASE_URL)
+	Email     Email
+	Workers   Workers
+	Content   Content
+
+	// LogLevel is the minimum level of the logs (LOG_LEVEL: debug, info,
+	// warn or error, default info).
+	LogLevel slog.Level
+
+	// JWTPreviousSecrets are former signing keys still accepted, so that
+	// keys rotate without signing everyone out (JWT_PREVIOUS_SECRET_KEYS,
+	// comma separated).
+	JWTPreviousSecrets []string
+
+	// Plan are the limits of the default plan, which users are on until
+	// administrators put them on another one, zero meaning unlimited
+	// (PLAN_MAX_NEWSLETTERS, PLAN_MAX_SUBSCRIBERS_PER_NEWSLETTER,
+	// PLAN_MAX_EMAILS_PER_MONTH and PLAN_MAX_REQUESTS_PER_MINUTE, default 0).
+	Plan limitsdomain.Limits
+
+	// Plans are the other plans users can be put on, named by PLANS (comma
+	// separated lowercase names, such as "pro,business"), each with the
+	// limits of PLAN_<NAME>_MAX_NEWSLETTERS and so on.
+	Plans []limitsdomain.Plan
+
+	// AnonymousRequestsPerMinute are the requests each IP address can make
+	// per minute without an access token, zero meaning unlimited
+	// (ANONYMOUS_MAX_REQUESTS_PER_MINUTE, default 0).
+	AnonymousRequestsPerMinute int
+
+	// ClickTracking replaces the links of campaign emails with short links
+	// counting their clicks (CLICK_TRACKING, default false).
+	ClickTracking bool
+
+	// TOTPKey encrypts the TOTP secrets of two-factor authentication
+	// (TOTP_ENCRYPTION_KEY, 32 bytes, base64 encoded). Two-factor
+	// authentication is disabled when it is empty.
+	TOTPKey []byte
+
+	// PIIKey encrypts the email addresses of subscribers stored in Firestore
+	// and keys the hashes they are looked up by (PII_ENCRYPTION_KEY, 32
+	// bytes, base64 encoded). Email addresses are stored in clear when it is
+	// empty.
+	PIIKey []byte
+
+	Database      Database
+	Passwords     Passwords
+	HTTP          HTTP
+	Unsubscribe   Unsubscribe
+	Subscriptions Subscriptions
+	Artifacts     artifacts.Config
+	Alerting      alerting.Config
+
+	// Abuse are the limits on clients guessing unsubscribe tokens
+	// (ABUSE_THRESHOLD, ABUSE_WINDOW and ABUSE_BLOCK), reloadable.
+	Abuse abuse.Limits
+
+	// SpamScoreThreshold is the spam score from which sending a post is
+	// refused unless forced (SPAM_SCORE_THRESHOLD, default 5); 0 disables
+	// the check.
+	SpamScoreThreshold float64
+
+	// FirestoreIndexesFile is the index manifest verified against Firestore
+	// at startup (FIRESTORE_INDEXES_FILE, default "firestore.indexes.json");
+	// the verification is skipped when it is empty.
+	FirestoreIndexesFile string
+}
+
+// Email configures the email provider.
+type Email struct {
+	Provider string // "ses", "sendgrid", "mailgun" or "smtp" (EMAIL_PROVIDER, default "ses")
+	From     string // Default sender address (EMAIL_FROM, falling back to AWS_FROM)
+
+	AWSRegion string // Region of SES (AWS_REGION); the AWS SDK default is used when empty
+
+	SendGridAPIKey  string // SENDGRID_API_KEY
+	SendGridBaseURL string // SENDGRID_BASE_URL; the provider default is used when empty
+
+	MailgunAPIKey  string // MAILGUN_API_KEY
+	MailgunDomain  string // MAILGUN_DOMAIN
+	MailgunBaseURL string // MAILGUN_BASE_URL; the provider default is used when empty
+
+	// SMTPHost and SMTPPort locate the SMTP server of the smtp provider, a
+	// local capture server such as MailHog or Mailpit (SMTP_HOST, default
+	// "localhost"; SMTP_PORT, default "1025").
+	SMTPHost string
+	SMTPPort string
+
+	// DryRun records outgoing emails instead of sending them (EMAIL_DRY_RUN).
+	// The provider settings are then not required.
+	DryRun bool
+	// DryRunFile is a file every recorded email is appended to as a line of
+	// JSON (EMAIL_DRY_RUN_FILE); emails are only kept in memory when empty.
+	DryRunFile string
+
+	// MaxAttachmentSize caps the total size of the attachments of an email,
+	// in bytes (EMAIL_MAX_ATTACHMENT_SIZE, default 5 MiB).
+	MaxAttachmentSize int
+}
+
+// Workers sizes the background worker pool.
+type Workers struct {
+	Count      int // Number of workers (WORKERS, default: number of CPUs)
+	BufferSize int // Size of each priority queue (BUFFER_SIZE, default 100)
+
+	// CampaignsPerNewsletter caps the campaigns of a newsletter sent at once
+	// (CAMPAIGN_CONCURRENCY_PER_NEWSLETTER, default 1).
+	CampaignsPerNewsletter int
+
+	// Overflow is what happens to jobs submitted while the queue is full
+	// (QUEUE_OVERFLOW, default block; QUEUE_BLOCK_TIMEOUT, default 1s).
+	Overflow workerpool.Overflow
+	// JobTimeout is the time limit of a background job (JOB_TIMEOUT,
+	// default 5m).
+	JobTimeout time.Duration
+}
+
+// Database sizes the PostgreSQL connection pool.
+type Database struct {
+	// Pool is read from DB_MAX_OPEN_CONNS (default 25), DB_MIN_CONNS
+	// (default 0), DB_CONN_MAX_LIFETIME (default 30m) and
+	// DB_CONN_MAX_IDLE_TIME (default 5m).
+	Pool database.PoolConfig
+
+	// SlowQueryThreshold is the duration beyond which statements are logged
+	// (DB_SLOW_QUERY_THRESHOLD, default 500ms); 0 disables the log.
+	SlowQueryThreshold time.Duration
+}
+
+// Passwords configures the hashing of new passwords.
+type Passwords struct {
+	Algorithm  string // "bcrypt" or "argon2id" (PASSWORD_HASH_ALGORITHM, default "bcrypt")
+	BcryptCost int    // BCRYPT_COST, default 10
+
+	// Argon2 are the parameters of argon2id (ARGON2_MEMORY in KiB, default
+	// 65536; ARGON2_ITERATIONS, default 3; ARGON2_PARALLELISM, default 2).
+	Argon2 userapp.Argon2Params
+}
+
+// HTTP configures the serving of the API.
+type HTTP struct {
+	// MetricsToken is the bearer token required by /metrics (METRICS_TOKEN);
+	// the endpoint is disabled when it is empty.
+	MetricsToken string
+	// DebugOutbox serves the emails recorded by EMAIL_DRY_RUN on
+	// GET /debug/outbox, to the clients presenting MetricsToken
+	// (DEBUG_OUTBOX, default false). For development only.
+	DebugOutbox bool
+	// SESWebhookToken is the token required by the SES webhooks
+	// (SES_WEBHOOK_TOKEN); the webhooks are disabled when it is empty.
+	SESWebhookToken string
+
+	// CompressionMinSize is the size in bytes from which responses are
+	// compressed (COMPRESSION_MIN_SIZE, default 1024).
+	CompressionMinSize int
+	// LegacySunset is the date announced in the Sunset header of the
+	// unversioned routes (LEGACY_API_SUNSET, YYYY-MM-DD); the API default
+	// is used when it is zero.
+	LegacySunset time.Time
+	// NewsletterCacheTTL is how long newsletter listings are cached
+	// (NEWSLETTER_CACHE_TTL, default 10s); 0 disables caching.
+	NewsletterCacheTTL time.Duration
+}
+
+// Unsubscribe configures the unsubscribe links of emails.
+type Unsubscribe struct {
+	// SecretKey signs unsubscribe tokens (UNSUBSCRIBE_SECRET_KEY); emails
+	// carry the random token stored with each subscription when it is empty.
+	SecretKey string
+	// PreviousSecretKeys are the keys SecretKey replaced, whose tokens are
+	// still accepted (UNSUBSCRIBE_PREVIOUS_SECRET_KEYS, comma separated).
+	PreviousSecretKeys []string
+	// TokenTTL is how long signed tokens are valid (UNSUBSCRIBE_TOKEN_TTL,
+	// default 8760h).
+	TokenTTL time.Duration
+	// LegacyTokens accepts the random tokens of emails sent before tokens
+	// were signed (UNSUBSCRIBE_LEGACY_TOKENS, default true).
+	LegacyTokens bool
+}
+
+// Subscriptions configures the public subscriptions.
+type Subscriptions struct {
+	// Cooldown is the minimum time before the same email can subscribe to
+	// the same newsletter again (SUBSCRIBE_COOLDOWN, default 0, disabled).
+	Cooldown time.Duration
+
+	Captcha    captcha.Config
+	EmailCheck emailcheck.Config
+}
+
+// Preflight are the settings verified by the preflight checks of --check.
+// They are read by PreflightFromEnv without being validated, so that the
+// checks report what is missing or invalid.
+type Preflight struct {
+	JWTSecret            string // JWT_SECRET_KEY
+	DSN                  string // DSN
+	MigrationsDir        string // MIGRATIONS_DIR, default "migrations"
+	FirestoreIndexesFile string // FIRESTORE_INDEXES_FILE, default "firestore.indexes.json"
+	RedisURL             string // REDIS_URL; Redis is not checked when empty
+	Email                Email
+}
+
+// Content limits the length of user-provided content, in characters.
+type Content struct {
+	MaxNewsletterName        int // NEWSLETTER_NAME_MAX_LENGTH, default 100
+	MaxNewsletterDescription int // NEWSLETTER_DESCRIPTION_MAX_LENGTH, default 2000
+	MaxPostBody              int // POST_BODY_MAX_LENGTH, default 1000000
+}
+
+// Storage backends. StorePostgres keeps users and newsletters in PostgreSQL
+// and subscriptions in Firestore; StoreMemory keeps them in memory, for
+// running the API locally without external dependencies.
+const (
+	StorePostgres = "postgres"
+	StoreMemory   = "memory"
+)
+
+// Email providers.
+const (
+	ProviderSES      = "ses"
+	ProviderSendGrid = "sendgrid"
+	ProviderMailgun  = "mailgun"
+	ProviderSMTP     = "smtp" // Development only, see Email.SMTPHost
+)
+
+// defaultBufferSize is the default size of the job queues.
+const defaultBufferSize = 100
+
+// defaultFirestoreIndexesFile is the default index manifest, at the root of
+// the repository.
+const defaultFirestoreIndexesFile = "firestore.indexes.json"
+
+// keySize is the size of TOTP_ENCRYPTION_KEY and PII_ENCRYPTION_KEY,
+// AES-256 keys.
+const keySize = 32
+
+// Load reads the configuration from the environment and validates it. The
+// returned error lists every missing or invalid value, so that all of them
+// can be fixed at once.
+func Load() (*Config, error) {
+	cfg := &Config{
+		Store:   GetEnv("STORE", StorePostgres),
+		DSN:     GetEnv("DSN", ""),
+		BaseURL: GetEnv("BASE_URL", ""),
+		Email:   EmailFromEnv(),
+	}
+
+	cfg.JWTSecret, cfg.JWTPreviousSecrets = JWTKeysFromEnv()
+
+	var errs []error
+	switch cfg.Store {
+	case StorePostgres:
+		if cfg.DSN == "" {
+			errs = append(errs, errors.New("DSN is required: set it to the PostgreSQL connection string"))
+		}
+	case StoreMemory:
+	default:
+		errs = append(errs, fmt.Errorf("STORE must be postgres or memory, got %q", cfg.Store))
+	}
+	if cfg.JWTSecret == "" {
+		errs = append(errs, errors.New("JWT_SECRET_KEY is required: set it to a random string of at least 32 bytes"))
+	}
+	if err := CheckBaseURL(cfg.BaseURL); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.Email.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := boolSetting("EMAIL_DRY_RUN", false); err != nil {
+		errs = append(errs, err)
+	}
+	if clickTracking, err := boolSetting("CLICK_TRACKING", false); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.ClickTracking = clickTracking
+	}
+	if key, err := keySetting("TOTP_ENCRYPTION_KEY"); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.TOTPKey = key
+	}
+	if key, err := PIIKeyFromEnv(); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.PIIKey = key
+	}
+
+	if rt, err := LoadRuntime(); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.LogLevel = rt.LogLevel
+		cfg.Workers.Count = rt.Workers
+		cfg.Workers.CampaignsPerNewsletter = rt.CampaignsPerNewsletter
+		cfg.Abuse = rt.Abuse
+	}
+
+	var err error
+	if cfg.Workers.BufferSize, err = intSetting("BUFFER_SIZE", defaultBufferSize); err != nil {
+		errs = append(errs, err)
+	} else if cfg.Workers.BufferSize < 0 {
+		errs = append(errs, fmt.Errorf("BUFFER_SIZE must not be negative, got %d", cfg.Workers.BufferSize))
+	}
+
+	for _, limit := range []struct {
+		key      string
+		value    *int
+		fallback int
+	}{
+		{"NEWSLETTER_NAME_MAX_LENGTH", &cfg.Content.MaxNewsletterName, newsletterdomain.DefaultMaxNameLength},
+		{"NEWSLETTER_DESCRIPTION_MAX_LENGTH", &cfg.Content.MaxNewsletterDescription, newsletterdomain.DefaultMaxDescriptionLength},
+		{"POST_BODY_MAX_LENGTH", &cfg.Content.MaxPostBody, postdomain.DefaultMaxBodyLength},
+		{"EMAIL_MAX_ATTACHMENT_SIZE", &cfg.Email.MaxAttachmentSize, notificationdomain.DefaultMaxAttachmentSize},
+	} {
+		if *limit.value, err = intSetting(limit.key, limit.fallback); err != nil {
+			errs = append(errs, err)
+		} else if *limit.value < 1 {
+			errs = append(errs, fmt.Errorf("%s must be at least 1, got %d", limit.key, *limit.value))
+		}
+	}
+
+	var planErrs []error
+	cfg.Plan, planErrs = planLimits("PLAN_")
+	errs = append(errs, planErrs...)
+	cfg.Plans, planErrs = plans()
+	errs = append(errs, planErrs...)
+	if cfg.AnonymousRequestsPerMinute, err = intSetting("ANONYMOUS_MAX_REQUESTS_PER_MINUTE", 0); err != nil {
+		errs = append(errs, err)
+	} else if cfg.AnonymousRequestsPerMinute < 0 {
+		errs = append(errs, fmt.Errorf("ANONYMOUS_MAX_REQUESTS_PER_MINUTE must not be negative, got %d", cfg.AnonymousRequestsPerMinute))
+	}
+
+	if cfg.Workers.Overflow, err = workerpool.ParseOverflow(GetEnv("QUEUE_OVERFLOW", ""), GetEnv("QUEUE_BLOCK_TIMEOUT", "")); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Workers.JobTimeout, err = workerpool.ParseJobTimeout(GetEnv("JOB_TIMEOUT", "")); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, load := range []func(*Config) []error{
+		loadDatabase,
+		loadPasswords,
+		loadHTTP,
+		loadUnsubscribe,
+		loadSubscriptions,
+		loadArtifacts,
+		loadAlerting,
+	} {
+		errs = append(errs, load(cfg)...)
+	}
+
+	if cfg.SpamScoreThreshold, err = floatSetting("SPAM_SCORE_THRESHOLD", spamcheck.DefaultThreshold); err != nil {
+		errs = append(errs, err)
+	} else if cfg.SpamScoreThreshold < 0 {
+		errs = append(errs, fmt.Errorf("SPAM_SCORE_THRESHOLD must not be negative, got %g", cfg.SpamScoreThreshold))
+	}
+	cfg.FirestoreIndexesFile = GetEnv("FIRESTORE_INDEXES_FILE", defaultFirestoreIndexesFile)
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// EmailFromEnv reads the email settings from the environment without
+// validating them. The preflight check uses it to report what is missing.
+func EmailFromEnv() Email {
+	dryRun, _ := boolSetting("EMAIL_DRY_RUN", false) // Reported by Load
+	return Email{
+		Provider:        GetEnv("EMAIL_PROVIDER", ProviderSES),
+		From:            GetEnv("EMAIL_FROM", GetEnv("AWS_FROM", "")),
+		AWSRegion:       GetEnv("AWS_REGION", ""),
+		SendGridAPIKey:  GetEnv("SENDGRID_API_KEY", ""),
+		SendGridBaseURL: GetEnv("SENDGRID_BASE_URL", ""),
+		MailgunAPIKey:   GetEnv("MAILGUN_API_KEY", ""),
+		MailgunDomain:   GetEnv("MAILGUN_DOMAIN", ""),
+		MailgunBaseURL:  GetEnv("MAILGUN_BASE_URL", ""),
+		SMTPHost:        GetEnv("SMTP_HOST", "localhost"),
+		SMTPPort:        GetEnv("SMTP_PORT", "1025"),
+		DryRun:          dryRun,
+		DryRunFile:      GetEnv("EMAIL_DRY_RUN_FILE", ""),
+	}
+}
+
+// PreflightFromEnv reads the settings verified by the preflight checks
+// without validating them.
+func PreflightFromEnv() Preflight {
+	return Preflight{
+		JWTSecret:            GetEnv("JWT_SECRET_KEY", ""),
+		DSN:                  GetEnv("DSN", ""),
+		MigrationsDir:        GetEnv("MIGRATIONS_DIR", "migrations"),
+		FirestoreIndexesFile: GetEnv("FIRESTORE_INDEXES_FILE", defaultFirestoreIndexesFile),
+		RedisURL:             GetEnv("REDIS_URL", ""),
+		Email:                EmailFromEnv(),
+	}
+}
+
+// validate checks that the settings of the selected provider are present.
+func (e Email) validate() error {
+	var errs []error
+	if e.From == "" {
+		errs = append(errs, errors.New("EMAIL_FROM is required: set it to the default sender address"))
+	}
+	if e.DryRun {
+		return errors.Join(errs...)
+	}
+
+	switch e.Provider {
+	case ProviderSES:
+	case ProviderSendGrid:
+		if e.SendGridAPIKey == "" {
+			errs = append(errs, errors.New("SENDGRID_API_KEY is required when EMAIL_PROVIDER is sendgrid"))
+		}
+	case ProviderMailgun:
+		if e.MailgunAPIKey == "" || e.MailgunDomain == "" {
+			errs = append(errs, errors.New("MAILGUN_API_KEY and MAILGUN_DOMAIN are required when EMAIL_PROVIDER is mailgun"))
+		}
+	case ProviderSMTP:
+		if port, err := strconv.Atoi(e.SMTPPort); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("SMTP_PORT must be a port number, got %q", e.SMTPPort))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("EMAIL_PROVIDER must be ses, sendgrid, mailgun or smtp, got %q", e.Provider))
+	}
+
+	return errors.Join(errs...)
+}
+
+// CheckBaseURL reports why baseURL cannot be used as the public URL of the
+// API: it must be an absolute http or https URL without credentials, query
+// or fragment. A path is allowed, for APIs served under a prefix.
+func CheckBaseURL(baseURL string) error {
+	if baseURL == "" {
+		return errors.New("BASE_URL is required: set it to the public URL of the API, such as https://api.example.com")
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid BASE_URL %q: %w", baseURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid BASE_URL %q: must start with http:// or https://", baseURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid BASE_URL %q: missing host", baseURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("invalid BASE_URL %q: must not contain credentials, a query or a fragment", baseURL)
+	}
+	return nil
+}
+
+// planName is the form of the names of the plans of PLANS.
+var planName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// plans reads the plans named by PLANS and their limits.
+func plans() ([]limitsdomain.Plan, []error) {
+	var plans []limitsdomain.Plan
+	var errs []error
+	seen := map[string]bool{limitsdomain.DefaultPlanName: true}
+	for _, name := range strings.Split(GetEnv("PLANS", ""), ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+			continue
+		case !planName.MatchString(name):
+			errs = append(errs, fmt.Errorf("PLANS must list lowercase names of letters, digits and underscores, got %q", name))
+			continue
+		case seen[name]:
+			errs = append(errs, fmt.Errorf("PLANS must not list %q twice, or the default plan", name))
+			continue
+		}
+		seen[name] = true
+
+		limits, limitErrs := planLimits("PLAN_" + strings.ToUpper(name) + "_")
+		errs = append(errs, limitErrs...)
+		plans = append(plans, limitsdomain.Plan{Name: name, Limits: limits})
+	}
+	return plans, errs
+}
+
+// planLimits reads the limits of a plan from the variables starting with
+// prefix, such as PLAN_MAX_NEWSLETTERS for "PLAN_". Limits default to 0,
+// unlimited.
+func planLimits(prefix string) (limitsdomain.Limits, []error) {
+	var limits limitsdomain.Limits
+	var errs []error
+	for _, limit := range []struct {
+		key   string
+		value *int
+	}{
+		{prefix + "MAX_NEWSLETTERS", &limits.Newsletters},
+		{prefix + "MAX_SUBSCRIBERS_PER_NEWSLETTER", &limits.SubscribersPerNewsletter},
+		{prefix + "MAX_EMAILS_PER_MONTH", &limits.EmailsPerMonth},
+		{prefix + "MAX_REQUESTS_PER_MINUTE", &limits.RequestsPerMinute},
+	} {
+		var err error
+		if *limit.value, err = intSetting(limit.key, 0); err != nil {
+			errs = append(errs, err)
+		} else if *limit.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", limit.key, *limit.value))
+		}
+	}
+	return limits, errs
+}
+
+// loadDatabase reads the settings of the PostgreSQL connection pool. The
+// defaults keep the API well below the default max_connections of
+// PostgreSQL, and recycle connections so that they follow failovers.
+func loadDatabase(cfg *Config) []error {
+	var errs []error
+	pool := &cfg.Database.Pool
+
+	maxConns, err := intSetting("DB_MAX_OPEN_CONNS", 25)
+	if err != nil {
+		errs = append(errs, err)
+	} else if maxConns < 1 || maxConns > math.MaxInt32 {
+		errs = append(errs, fmt.Errorf("DB_MAX_OPEN_CONNS must be at least 1, got %d", maxConns))
+	} else {
+		pool.MaxConns = int32(maxConns)
+	}
+	minConns, err := intSetting("DB_MIN_CONNS", 0)
+	if err != nil {
+		errs = append(errs, err)
+	} else if minConns < 0 || (pool.MaxConns > 0 && minConns > int(pool.MaxConns)) {
+		errs = append(errs, fmt.Errorf("DB_MIN_CONNS must be between 0 and DB_MAX_OPEN_CONNS, got %d", minConns))
+	} else {
+		pool.MinConns = int32(minConns)
+	}
+
+	for _, setting := range []struct {
+		key      string
+		value    *time.Duration
+		fallback time.Duration
+	}{
+		{"DB_CONN_MAX_LIFETIME", &pool.ConnMaxLifetime, 30 * time.Minute},
+		{"DB_CONN_MAX_IDLE_TIME", &pool.ConnMaxIdleTime, 5 * time.Minute},
+	} {
+		if *setting.value, err = durationSetting(setting.key, setting.fallback); err != nil {
+			errs = append(errs, err)
+		} else if *setting.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive duration, got %s", setting.key, *setting.value))
+		}
+	}
+
+	if cfg.Database.SlowQueryThreshold, err = durationSetting("DB_SLOW_QUERY_THRESHOLD", 500*time.Millisecond); err != nil {
+		errs = append(errs, err)
+	} else if cfg.Database.SlowQueryThreshold < 0 {
+		errs = append(errs, fmt.Errorf("DB_SLOW_QUERY_THRESHOLD must not be negative, got %s", cfg.Database.SlowQueryThreshold))
+	}
+	return errs
+}
+
+// loadPasswords reads the algorithm hashing new passwords and its cost.
+func loadPasswords(cfg *Config) []error {
+	var errs []error
+	passwords := &cfg.Passwords
+
+	passwords.Algorithm = stringSetting("PASSWORD_HASH_ALGORITHM", userapp.AlgorithmBcrypt)
+	if passwords.Algorithm != userapp.AlgorithmBcrypt && passwords.Algorithm != userapp.AlgorithmArgon2id {
+		errs = append(errs, fmt.Errorf("PASSWORD_HASH_ALGORITHM must be %s or %s, got %q", userapp.AlgorithmBcrypt, userapp.AlgorithmArgon2id, passwords.Algorithm))
+	}
+
+	var err error
+	if passwords.BcryptCost, err = intSetting("BCRYPT_COST", bcrypt.DefaultCost); err != nil {
+		errs = append(errs, err)
+	} else if passwords.BcryptCost < bcrypt.MinCost || passwords.BcryptCost > bcrypt.MaxCost {
+		errs = append(errs, fmt.Errorf("BCRYPT_COST must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, passwords.BcryptCost))
+	}
+
+	defaults := userapp.DefaultArgon2Params
+	passwords.Argon2 = defaults
+	for _, setting := range []struct {
+		key      string
+		value    *uint32
+		fallback uint32
+		max      int
+	}{
+		{"ARGON2_MEMORY", &passwords.Argon2.Memory, defaults.Memory, math.MaxUint32},
+		{"ARGON2_ITERATIONS", &passwords.Argon2.Iterations, defaults.Iterations, math.MaxUint32},
+	} {
+		n, err := intSetting(setting.key, int(setting.fallback))
+		if err != nil {
+			errs = append(errs, err)
+		} else if n < 1 || n > setting.max {
+			errs = append(errs, fmt.Errorf("%s must be between 1 and %d, got %d", setting.key, setting.max, n))
+		} else {
+			*setting.value = uint32(n)
+		}
+	}
+	parallelism, err := intSetting("ARGON2_PARALLELISM", int(defaults.Parallelism))
+	if err != nil {
+		errs = append(errs, err)
+	} else if parallelism < 1 || parallelism > math.MaxUint8 {
+		errs = append(errs, fmt.Errorf("ARGON2_PARALLELISM must be between 1 and %d, got %d", math.MaxUint8, parallelism))
+	} else {
+		passwords.Argon2.Parallelism = uint8(parallelism)
+	}
+	return errs
+}
+
+// loadHTTP reads the settings of the serving of the API.
+func loadHTTP(cfg *Config) []error {
+	var errs []error
+	var err error
+
+	cfg.HTTP.MetricsToken = GetEnv("METRICS_TOKEN", "")
+	cfg.HTTP.SESWebhookToken = GetEnv("SES_WEBHOOK_TOKEN", "")
+	if cfg.HTTP.DebugOutbox, err = boolSetting("DEBUG_OUTBOX", false); err != nil {
+		errs = append(errs, err)
+	} else if cfg.HTTP.DebugOutbox && cfg.HTTP.MetricsToken == "" {
+		errs = append(errs, errors.New("METRICS_TOKEN is required when DEBUG_OUTBOX is true"))
+	}
+
+	if cfg.HTTP.CompressionMinSize, err = intSetting("COMPRESSION_MIN_SIZE", 1024); err != nil {
+		errs = append(errs, err)
+	} else if cfg.HTTP.CompressionMinSize < 0 {
+		errs = append(errs, fmt.Errorf("COMPRESSION_MIN_SIZE must not be negative, got %d", cfg.HTTP.CompressionMinSize))
+	}
+
+	if value := strings.TrimSpace(GetEnv("LEGACY_API_SUNSET", "")); value != "" {
+		if cfg.HTTP.LegacySunset, err = time.Parse(time.DateOnly, value); err != nil {
+			errs = append(errs, fmt.Errorf("LEGACY_API_SUNSET must be a date such as 2027-04-16, got %q", value))
+		}
+	}
+
+	if cfg.HTTP.NewsletterCacheTTL, err = durationSetting("NEWSLETTER_CACHE_TTL", 10*time.Second); err != nil {
+		errs = append(errs, err)
+	} else if cfg.HTTP.NewsletterCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("NEWSLETTER_CACHE_TTL must not be negative, got %s", cfg.HTTP.NewsletterCacheTTL))
+	}
+	return errs
+}
+
+// loadUnsubscribe reads the keys signing unsubscribe tokens.
+func loadUnsubscribe(cfg *Config) []error {
+	var errs []error
+	var err error
+
+	cfg.Unsubscribe.SecretKey = GetEnv("UNSUBSCRIBE_SECRET_KEY", "")
+	cfg.Unsubscribe.PreviousSecretKeys = listSetting("UNSUBSCRIBE_PREVIOUS_SECRET_KEYS")
+
+	if cfg.Unsubscribe.TokenTTL, err = durationSetting("UNSUBSCRIBE_TOKEN_TTL", subscriptionapp.DefaultUnsubscribeTokenTTL); err != nil {
+		errs = append(errs, err)
+	} else if cfg.Unsubscribe.TokenTTL <= 0 {
+		errs = append(errs, fmt.Errorf("UNSUBSCRIBE_TOKEN_TTL must be a positive duration, got %s", cfg.Unsubscribe.TokenTTL))
+	}
+	if cfg.Unsubscribe.LegacyTokens, err = boolSetting("UNSUBSCRIBE_LEGACY_TOKENS", true); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// loadSubscriptions reads the settings of the public subscriptions: their
+// cooldown, CAPTCHA verification and email address validation.
+func loadSubscriptions(cfg *Config) []error {
+	var errs []error
+	var err error
+	subscriptions := &cfg.Subscriptions
+
+	if subscriptions.Cooldown, err = durationSetting("SUBSCRIBE_COOLDOWN", 0); err != nil {
+		errs = append(errs, err)
+	} else if subscriptions.Cooldown < 0 {
+		errs = append(errs, fmt.Errorf("SUBSCRIBE_COOLDOWN must not be negative, got %s", subscriptions.Cooldown))
+	}
+
+	subscriptions.Captcha = captcha.Config{
+		Provider:  stringSetting("CAPTCHA_PROVIDER", ""),
+		SecretKey: GetEnv("CAPTCHA_SECRET_KEY", ""),
+		SiteKey:   GetEnv("CAPTCHA_SITE_KEY", ""),
+	}
+	switch subscriptions.Captcha.Provider {
+	case "":
+	case captcha.ProviderHCaptcha, captcha.ProviderReCaptcha:
+		if subscriptions.Captcha.SecretKey == "" {
+			errs = append(errs, errors.New("CAPTCHA_SECRET_KEY is required when CAPTCHA_PROVIDER is set"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("CAPTCHA_PROVIDER must be %s or %s, got %q", captcha.ProviderHCaptcha, captcha.ProviderReCaptcha, subscriptions.Captcha.Provider))
+	}
+
+	check := &subscriptions.EmailCheck
+	if check.CheckMX, err = boolSetting("EMAIL_MX_CHECK", false); err != nil {
+		errs = append(errs, err)
+	}
+	if check.MXCacheTTL, err = durationSetting("EMAIL_MX_CACHE_TTL", time.Hour); err != nil {
+		errs = append(errs, err)
+	} else if check.MXCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("EMAIL_MX_CACHE_TTL must not be negative, got %s", check.MXCacheTTL))
+	}
+	check.Blocklist = listSetting("EMAIL_BLOCKLIST")
+	if path := GetEnv("EMAIL_BLOCKLIST_FILE", ""); path != "" {
+		domains, err := emailcheck.ReadBlocklist(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("EMAIL_BLOCKLIST_FILE must be a readable file: %w", err))
+		}
+		check.Blocklist = append(check.Blocklist, domains...)
+	}
+	return errs
+}
+
+// loadArtifacts reads the settings of the downloadable files, such as
+// exports. The bucket of the s3 backend is only required when the files are
+// enabled by ARTIFACTS_SECRET_KEY.
+func loadArtifacts(cfg *Config) []error {
+	var errs []error
+	var err error
+	store := &cfg.Artifacts
+
+	store.SecretKey = GetEnv("ARTIFACTS_SECRET_KEY", "")
+	if store.LinkTTL, err = durationSetting("ARTIFACTS_LINK_TTL", 24*time.Hour); err != nil {
+		errs = append(errs, err)
+	} else if store.LinkTTL <= 0 {
+		errs = append(errs, fmt.Errorf("ARTIFACTS_LINK_TTL must be a positive duration, got %s", store.LinkTTL))
+	}
+
+	store.Backend = stringSetting("ARTIFACTS_BACKEND", artifacts.BackendDisk)
+	store.Dir = stringSetting("ARTIFACTS_DIR", filepath.Join(os.TempDir(), "newsletter-artifacts"))
+	store.S3Bucket = GetEnv("ARTIFACTS_S3_BUCKET", "")
+	store.S3Prefix = stringSetting("ARTIFACTS_S3_PREFIX", "artifacts")
+	switch store.Backend {
+	case artifacts.BackendDisk:
+	case artifacts.BackendS3:
+		if store.SecretKey != "" && store.S3Bucket == "" {
+			errs = append(errs, errors.New("ARTIFACTS_S3_BUCKET is required when ARTIFACTS_BACKEND is s3"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("ARTIFACTS_BACKEND must be %s or %s, got %q", artifacts.BackendDisk, artifacts.BackendS3, store.Backend))
+	}
+	return errs
+}
+
+// loadAlerting reads the destinations and thresholds of the alerts on the
+// worker pool.
+func loadAlerting(cfg *Config) []error {
+	var errs []error
+	var err error
+	alerts := &cfg.Alerting
+
+	alerts.Emails = listSetting("ALERT_EMAILS")
+	alerts.WebhookURL = GetEnv("ALERT_WEBHOOK_URL", "")
+
+	if alerts.Thresholds.QueueDepth, err = intSetting("ALERT_QUEUE_DEPTH", 0); err != nil {
+		errs = append(errs, err)
+	} else if alerts.Thresholds.QueueDepth < 0 {
+		errs = append(errs, fmt.Errorf("ALERT_QUEUE_DEPTH must not be negative, got %d", alerts.Thresholds.QueueDepth))
+	}
+	if alerts.Thresholds.QueueLag, err = durationSetting("ALERT_QUEUE_LAG", 0); err != nil {
+		errs = append(errs, err)
+	} else if alerts.Thresholds.QueueLag < 0 {
+		errs = append(errs, fmt.Errorf("ALERT_QUEUE_LAG must not be negative, got %s", alerts.Thresholds.QueueLag))
+	}
+	if alerts.Thresholds.FailureRate, err = floatSetting("ALERT_FAILURE_RATE", 0); err != nil {
+		errs = append(errs, err)
+	} else if alerts.Thresholds.FailureRate < 0 || alerts.Thresholds.FailureRate > 1 {
+		errs = append(errs, fmt.Errorf("ALERT_FAILURE_RATE must be between 0 and 1, got %g", alerts.Thresholds.FailureRate))
+	}
+
+	if alerts.Interval, err = durationSetting("ALERT_INTERVAL", time.Minute); err != nil {
+		errs = append(errs, err)
+	} else if alerts.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("ALERT_INTERVAL must be a positive duration, got %s", alerts.Interval))
+	}
+	if alerts.Cooldown, err = durationSetting("ALERT_COOLDOWN", 15*time.Minute); err != nil {
+		errs = append(errs, err)
+	} else if alerts.Cooldown < 0 {
+		errs = append(errs, fmt.Errorf("ALERT_COOLDOWN must not be negative, got %s", alerts.Cooldown))
+	}
+	return errs
+}
+
+// intSetting reads the integer environment variable key, or returns
+// fallback when it is unset or blank.
+func intSetting(key string, fallback int) (int, error) {
+	value := strings.TrimSpace(GetEnv(key, ""))
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", key, value)
+	}
+	return n, nil
+}
+
+// boolSetting reads the boolean environment variable key, such as "true" or
+// "0", or returns fallback when it is unset or blank.
+func boolSetting(key string, fallback bool) (bool, error) {
+	value := strings.TrimSpace(GetEnv(key, ""))
+	if value == "" {
+		return fallback, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", key, value)
+	}
+	return b, nil
+}
+
+// stringSetting reads the environment variable key, or returns fallback
+// when it is unset or blank.
+func stringSetting(key, fallback string) string {
+	if value := strings.TrimSpace(GetEnv(key, "")); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// floatSetting reads the decimal environment variable key, or returns
+// fallback when it is unset or blank.
+func floatSetting(key string, fallback float64) (float64, error) {
+	value := strings.TrimSpace(GetEnv(key, ""))
+	if value == "" {
+		return fallback, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, got %q", key, value)
+	}
+	return f, nil
+}
+
+// durationSetting reads the duration environment variable key, such as
+// "30s" or "1h", or returns fallback when it is unset or blank.
+func durationSetting(key string, fallback time.Duration) (time.Duration, error) {
+	value := strings.TrimSpace(GetEnv(key, ""))
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as 30s or 1h, got %q", key, value)
+	}
+	return d, nil
+}
+
+// listSetting reads the comma separated environment variable key, leaving
+// out blank items.
+func listSetting(key string) []string {
+	var items []string
+	for _, item := range strings.Split(GetEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// JWTKeysFromEnv reads the key signing access tokens (JWT_SECRET_KEY) and
+// the former keys still accepted (JWT_PREVIOUS_SECRET_KEYS), for reloading
+// them when secrets change.
+func JWTKeysFromEnv() (current string, previous []string) {
+	return GetEnv("JWT_SECRET_KEY", ""), listSetting("JWT_PREVIOUS_SECRET_KEYS")
+}
+
+// PIIKeyFromEnv reads PII_ENCRYPTION_KEY, for the commands that run
+// without the rest of the configuration. It returns nil when it is unset.
+func PIIKeyFromEnv() ([]byte, error) {
+	return keySetting("PII_ENCRYPTION_KEY")
+}
+
+// keySetting reads the base64 encoded key of keySize bytes in the
+// environment variable key, or returns nil when it is unset or blank.
+func keySetting(key string) ([]byte, error) {
+	encoded := strings.TrimSpace(GetEnv(key, ""))
+	if encoded == "" {
+		return nil, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(decoded) != keySize {
+		return nil, fmt.Errorf("%s must be %d random bytes, base64 encoded", key, keySize)
+	}
+	return decoded, nil
+}
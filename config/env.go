@@ -2,12 +2,21 @@ package config
 
 import (
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+// processEnv records the variables set by the process environment rather
+// than the .env file, which ReloadEnvFile leaves unchanged.
+var processEnv = map[string]bool{}
+
 // Loads environment variables on startup.
 func init() {
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		processEnv[name] = true
+	}
 	_ = godotenv.Load()
 }
 
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	apperrors "newsletter/internal/errors"
+	"newsletter/internal/infrastructure/abuse"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// ErrInvalidSettings is returned when reloading the configuration finds an
+// invalid setting.
+var ErrInvalidSettings = apperrors.New(apperrors.Validation, "invalid settings")
+
+// Reloadable are the settings that can change while the API runs, when the
+// configuration is reloaded on SIGHUP or with POST /admin/reload.
+var Reloadable = []string{
+	"LOG_LEVEL",
+	"WORKERS",
+	"CAMPAIGN_CONCURRENCY_PER_NEWSLETTER",
+	"ABUSE_THRESHOLD",
+	"ABUSE_WINDOW",
+	"ABUSE_BLOCK",
+}
+
+// Runtime are the settings of Config that can be reloaded.
+type Runtime struct {
+	LogLevel               slog.Level // LOG_LEVEL
+	Workers                int        // WORKERS
+	CampaignsPerNewsletter int        // CAMPAIGN_CONCURRENCY_PER_NEWSLETTER
+
+	// Abuse are the limits of ABUSE_THRESHOLD (default 20 failures),
+	// ABUSE_WINDOW (default 1m) and ABUSE_BLOCK (default 15m).
+	Abuse abuse.Limits
+}
+
+// LoadRuntime reads the reloadable settings of Config from the environment
+// and validates them. The returned error lists every invalid value.
+func LoadRuntime() (Runtime, error) {
+	var rt Runtime
+	var errs []error
+	var err error
+
+	if rt.LogLevel, err = logLevelSetting("LOG_LEVEL"); err != nil {
+		errs = append(errs, err)
+	}
+	if rt.Workers, err = intSetting("WORKERS", runtime.NumCPU()); err != nil {
+		errs = append(errs, err)
+	} else if rt.Workers < 1 {
+		errs = append(errs, fmt.Errorf("WORKERS must be at least 1, got %d", rt.Workers))
+	}
+	if rt.CampaignsPerNewsletter, err = intSetting("CAMPAIGN_CONCURRENCY_PER_NEWSLETTER", 1); err != nil {
+		errs = append(errs, err)
+	} else if rt.CampaignsPerNewsletter < 1 {
+		errs = append(errs, fmt.Errorf("CAMPAIGN_CONCURRENCY_PER_NEWSLETTER must be at least 1, got %d", rt.CampaignsPerNewsletter))
+	}
+	if rt.Abuse.Threshold, err = intSetting("ABUSE_THRESHOLD", 20); err != nil {
+		errs = append(errs, err)
+	} else if rt.Abuse.Threshold < 0 {
+		errs = append(errs, fmt.Errorf("ABUSE_THRESHOLD must not be negative, got %d", rt.Abuse.Threshold))
+	}
+	for _, setting := range []struct {
+		key      string
+		value    *time.Duration
+		fallback time.Duration
+	}{
+		{"ABUSE_WINDOW", &rt.Abuse.Window, time.Minute},
+		{"ABUSE_BLOCK", &rt.Abuse.Block, 15 * time.Minute},
+	} {
+		if *setting.value, err = durationSetting(setting.key, setting.fallback); err != nil {
+			errs = append(errs, err)
+		} else if *setting.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive duration, got %s", setting.key, *setting.value))
+		}
+	}
+
+	return rt, errors.Join(errs...)
+}
+
+// ReloadEnvFile reads the .env file again and sets the Reloadable settings
+// it contains in the environment, so that edits of the file apply without
+// a restart. Settings of the process environment take precedence over the
+// file, as at startup, and are left unchanged. It returns the names of the
+// settings it changed; a missing file changes nothing.
+func ReloadEnvFile() ([]string, error) {
+	values, err := godotenv.Read()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read .env: %w", err)
+	}
+
+	var changed []string
+	for _, name := range Reloadable {
+		value, ok := values[name]
+		if !ok || processEnv[name] || os.Getenv(name) == value {
+			continue
+		}
+		os.Setenv(name, value)
+		changed = append(changed, name)
+	}
+	return changed, nil
+}
+
+// logLevelSetting reads the log level environment variable key, or returns
+// slog.LevelInfo when it is unset or blank.
+func logLevelSetting(key string) (slog.Level, error) {
+	value := strings.TrimSpace(GetEnv(key, ""))
+	if value == "" {
+		return slog.LevelInfo, nil
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("%s must be debug, info, warn or error, got %q", key, value)
+	}
+	return level, nil
+}
@@ -0,0 +1,56 @@
+package application
+
+import (
+	"context"
+	"log/slog"
+	"newsletter/internal/activity/domain"
+	"newsletter/internal/infrastructure/pagination"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ActivityService provides application-level operations related to the
+// activity feed of newsletters and it merges the events of their sources.
+type ActivityService struct {
+	sources []domain.EventSource
+}
+
+// NewActivityService creates an ActivityService reading the events of
+// sources, such as subscriptions and campaigns.
+func NewActivityService(sources ...domain.EventSource) *ActivityService {
+	return &ActivityService{sources: sources}
+}
+
+// Feed reads a page from every source, merges them in feed order and keeps
+// the first limit events. Each source returns the events following the
+// cursor, so that pages neither skip nor repeat events, and a next page
+// exists when the sources returned more events than fit in this one.
+func (as *ActivityService) Feed(newsletterID uuid.UUID, limit int, cursor string) (*domain.EventPage, error) {
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit = pagination.Limit(limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	found := make([][]*domain.Event, 0, len(as.sources))
+	total := 0
+	for _, source := range as.sources {
+		events, err := source.Events(ctx, newsletterID, after, limit+1)
+		if err != nil {
+			slog.Error("failed to read activity", "newsletter_id", newsletterID, "error", err)
+			return nil, err
+		}
+		found = append(found, events)
+		total += len(events)
+	}
+
+	page := &domain.EventPage{Events: domain.Merge(limit, found...)}
+	if total > limit {
+		page.NextCursor = page.Events[len(page.Events)-1].Cursor().Encode()
+	}
+	return page, nil
+}
@@ -0,0 +1,113 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/activity/application"
+	"newsletter/internal/activity/domain"
+	"newsletter/internal/infrastructure/pagination"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Event Source ---
+type MockEventSource struct {
+	mock.Mock
+}
+
+func (m *MockEventSource) Events(ctx context.Context, newsletterID uuid.UUID, after *pagination.Cursor, limit int) ([]*domain.Event, error) {
+	args := m.Called(newsletterID, after, limit)
+	events := args.Get(0)
+	if events == nil {
+		return nil, args.Error(1)
+	}
+	return events.([]*domain.Event), args.Error(1)
+}
+
+// sliceSource serves events from memory, filtering them as the stores do.
+type sliceSource []*domain.Event
+
+func (s sliceSource) Events(ctx context.Context, newsletterID uuid.UUID, after *pagination.Cursor, limit int) ([]*domain.Event, error) {
+	var events []*domain.Event
+	for _, event := range s {
+		if after != nil {
+			last := &domain.Event{Type: after.Key, ID: after.ID, OccurredAt: after.CreatedAt}
+			if !domain.Before(last, event) {
+				continue
+			}
+		}
+		events = append(events, event)
+	}
+	return domain.Merge(limit, events), nil
+}
+
+func TestFeed_PagesThroughEverySource(t *testing.T) {
+	at := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	subscriptions := sliceSource{
+		{Type: domain.EventSubscribed, ID: "a", OccurredAt: at},
+		{Type: domain.EventSubscribed, ID: "b", OccurredAt: at},
+		{Type: domain.EventUnsubscribed, ID: "a", OccurredAt: at.Add(time.Hour)},
+		{Type: domain.EventSubscribed, ID: "c", OccurredAt: at.Add(-time.Hour)},
+	}
+	campaigns := sliceSource{
+		{Type: domain.EventCampaignSent, ID: "x", OccurredAt: at},
+		{Type: domain.EventBounced, ID: "x/a", OccurredAt: at.Add(time.Hour)},
+	}
+	service := application.NewActivityService(subscriptions, campaigns)
+
+	var feed []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 10, "pagination does not end")
+		page, err := service.Feed(uuid.New(), 2, cursor)
+		require.NoError(t, err)
+		for _, event := range page.Events {
+			feed = append(feed, event.Type+":"+event.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	assert.Equal(t, []string{
+		"unsubscribed:a", "bounced:x/a",
+		"subscribed:b", "subscribed:a",
+		"campaign_sent:x", "subscribed:c",
+	}, feed)
+}
+
+func TestFeed_LastPageHasNoCursor(t *testing.T) {
+	source := new(MockEventSource)
+	newsletterID := uuid.New()
+	source.On("Events", newsletterID, (*pagination.Cursor)(nil), 3).Return([]*domain.Event{
+		{Type: domain.EventSubscribed, ID: "a", OccurredAt: time.Now()},
+	}, nil)
+
+	page, err := application.NewActivityService(source).Feed(newsletterID, 2, "")
+
+	require.NoError(t, err)
+	assert.Len(t, page.Events, 1)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestFeed_SourceError(t *testing.T) {
+	source := new(MockEventSource)
+	newsletterID := uuid.New()
+	source.On("Events", newsletterID, mock.Anything, mock.Anything).Return(nil, errors.New("unavailable"))
+
+	_, err := application.NewActivityService(source).Feed(newsletterID, 0, "")
+
+	assert.Error(t, err)
+}
+
+func TestFeed_InvalidCursor(t *testing.T) {
+	_, err := application.NewActivityService().Feed(uuid.New(), 0, "garbage")
+
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+}
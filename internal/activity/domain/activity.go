@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"context"
+	"newsletter/internal/infrastructure/pagination"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of the events of the activity feed.
+const (
+	EventSubscribed   = "subscribed"    // A subscriber joined the newsletter
+	EventUnsubscribed = "unsubscribed"  // A subscriber left the newsletter
+	EventBounced      = "bounced"       // A campaign email bounced
+	EventCampaignSent = "campaign_sent" // A campaign was sent to every recipient
+)
+
+// Event is an entry of the activity feed of a newsletter. Events are not
+// stored as such: each is read from the records of the module it comes from.
+type Event struct {
+	Type       string     `json:"type"`                  // Type of the event, such as "subscribed"
+	ID         string     `json:"-"`                     // Identifies the event among those of its type, for pagination
+	OccurredAt time.Time  `json:"occurred_at"`           // Time of the event
+	Email      string     `json:"email,omitempty"`       // Subscriber concerned, for subscriber and bounce events
+	CampaignID *uuid.UUID `json:"campaign_id,omitempty"` // Campaign concerned, for bounce and campaign events
+	PostID     *uuid.UUID `json:"post_id,omitempty"`     // Post sent, for campaign events
+	Detail     string     `json:"detail,omitempty"`      // Reason of a bounce, as reported by the provider, or of an unsubscription by the owner
+}
+
+// Cursor returns the position of the event in the feed.
+func (e *Event) Cursor() pagination.Cursor {
+	return pagination.Cursor{CreatedAt: e.OccurredAt, Key: e.Type, ID: e.ID}
+}
+
+// Before reports whether a comes before b in the feed: newest first, then
+// by type and ID, in descending order, for events at the same time.
+func Before(a, b *Event) bool {
+	if !a.OccurredAt.Equal(b.OccurredAt) {
+		return a.OccurredAt.After(b.OccurredAt)
+	}
+	if a.Type != b.Type {
+		return a.Type > b.Type
+	}
+	return a.ID > b.ID
+}
+
+// Merge sorts the events of several sources in feed order and keeps the
+// first limit.
+func Merge(limit int, sources ...[]*Event) []*Event {
+	events := []*Event{}
+	for _, source := range sources {
+		events = append(events, source...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return Before(events[i], events[j]) })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
+
+// Bound selects the events of one type that follow a cursor in the feed:
+// those that occurred before At, also those at At if Inclusive and, when
+// AfterID is set, those at At whose ID is lower than AfterID.
+type Bound struct {
+	At        time.Time
+	Inclusive bool
+	AfterID   string
+}
+
+// BoundOf returns the bound of the events of eventType following after, or
+// nil for the first page.
+func BoundOf(after *pagination.Cursor, eventType string) *Bound {
+	if after == nil {
+		return nil
+	}
+	switch {
+	case eventType == after.Key:
+		return &Bound{At: after.CreatedAt, AfterID: after.ID}
+	case eventType < after.Key:
+		// Sorted after the cursor at the same time.
+		return &Bound{At: after.CreatedAt, Inclusive: true}
+	default:
+		return &Bound{At: after.CreatedAt}
+	}
+}
+
+// EventPage is one page of the activity feed.
+type EventPage struct {
+	Events     []*Event `json:"events"`
+	NextCursor string   `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// ActivityService is an interface that contains a collection of method signatures
+// which will be implemented in application level and are responsible for
+// building the activity feed of newsletters.
+type ActivityService interface {
+	// Feed returns a page of the events of a newsletter, newest first,
+	// starting after the opaque cursor returned with the previous page.
+	Feed(newsletterID uuid.UUID, limit int, cursor string) (*EventPage, error)
+}
+
+// EventSource reads the events of a newsletter recorded by one module.
+type EventSource interface {
+	// Events returns, in feed order, up to limit events of the newsletter
+	// following after (see BoundOf), or the most recent ones if after is nil.
+	Events(ctx context.Context, newsletterID uuid.UUID, after *pagination.Cursor, limit int) ([]*Event, error)
+}
@@ -0,0 +1,112 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"newsletter/internal/activity/domain"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/infrastructure/secretbox"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+)
+
+// SubscriptionEventRepository reads the subscriber events of the activity
+// feed from the "subscriptions" collection of the subscriptions module.
+type SubscriptionEventRepository struct {
+	db  *firestore.Client
+	pii *secretbox.PII // nil when email addresses are stored in clear
+}
+
+func NewSubscriptionEventRepository(db *firestore.Client) *SubscriptionEventRepository {
+	return &SubscriptionEventRepository{db: db}
+}
+
+// SetEncryption decrypts with pii the email addresses encrypted by the
+// subscriptions module.
+func (sr *SubscriptionEventRepository) SetEncryption(pii *secretbox.PII) {
+	sr.pii = pii
+}
+
+// subscriber holds the fields of a subscription document read by Events.
+type subscriber struct {
+	Email             string     `firestore:"email"`
+	CreatedAt         time.Time  `firestore:"createdAt"`
+	UnsubscribedAt    *time.Time `firestore:"unsubscribedAt"`
+	UnsubscribeReason string     `firestore:"unsubscribeReason"`
+}
+
+// Events returns the subscriptions and unsubscriptions of the newsletter
+// following after. Every subscription is a subscribed event, timed by its
+// creation, and those that ended are also an unsubscribed event, detailed
+// with the reason of the owner who unsubscribed them, if any. Documents
+// unsubscribed before unsubscription times were recorded have no time and
+// are left out.
+//
+// The unsubscription query requires the composite index declared in
+// firestore.indexes.json.
+func (sr *SubscriptionEventRepository) Events(ctx context.Context, newsletterID uuid.UUID, after *pagination.Cursor, limit int) ([]*domain.Event, error) {
+	q := sr.db.
+		Collection("subscriptions").
+		Where("newsletterId", "==", newsletterID.String()).
+		Select("email", "createdAt", "unsubscribedAt", "unsubscribeReason")
+
+	subscribed, err := sr.events(ctx, q, "createdAt", domain.EventSubscribed, domain.BoundOf(after, domain.EventSubscribed), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	q = q.Where("status", "==", subscriptiondomain.StatusUnsubscribed)
+	unsubscribed, err := sr.events(ctx, q, "unsubscribedAt", domain.EventUnsubscribed, domain.BoundOf(after, domain.EventUnsubscribed), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.Merge(limit, subscribed, unsubscribed), nil
+}
+
+// events reads up to limit events of eventType, timed by field, from the
+// documents of q following bound.
+func (sr *SubscriptionEventRepository) events(ctx context.Context, q firestore.Query, field, eventType string, bound *domain.Bound, limit int) ([]*domain.Event, error) {
+	q = q.OrderBy(field, firestore.Desc).OrderBy(firestore.DocumentID, firestore.Desc)
+	switch {
+	case bound == nil:
+	case bound.AfterID != "":
+		q = q.StartAfter(bound.At, bound.AfterID)
+	case bound.Inclusive:
+		q = q.StartAt(bound.At)
+	default:
+		q = q.StartAfter(bound.At)
+	}
+
+	docs, err := q.Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	events := []*domain.Event{}
+	for _, doc := range docs {
+		var s subscriber
+		if err := doc.DataTo(&s); err != nil {
+			return nil, err
+		}
+
+		email, err := sr.pii.Open(s.Email)
+		if err != nil {
+			return nil, fmt.Errorf("subscription %s: decrypt email: %w", doc.Ref.ID, err)
+		}
+
+		event := &domain.Event{Type: eventType, ID: doc.Ref.ID, Email: email, OccurredAt: s.CreatedAt}
+		if eventType == domain.EventUnsubscribed {
+			if s.UnsubscribedAt == nil {
+				continue
+			}
+			event.OccurredAt = *s.UnsubscribedAt
+			event.Detail = s.UnsubscribeReason
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
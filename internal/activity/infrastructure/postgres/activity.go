@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"newsletter/internal/activity/domain"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/infrastructure/pagination"
+
+	"github.com/google/uuid"
+)
+
+// CampaignEventRepository reads the campaign events of the activity feed from
+// the campaigns and campaign_deliveries tables.
+type CampaignEventRepository struct {
+	db database.DB
+}
+
+func NewCampaignEventRepository(db database.DB) *CampaignEventRepository {
+	return &CampaignEventRepository{db: db}
+}
+
+// boundCondition returns the SQL condition selecting the rows whose time and
+// id follow bound, with its arguments numbered from n. A nil bound selects
+// every row. IDs are compared byte by byte, as in domain.Before, whatever
+// the collation of the database.
+func boundCondition(bound *domain.Bound, timeColumn, id string, n int) (string, []any) {
+	switch {
+	case bound == nil:
+		return "true", nil
+	case bound.AfterID != "":
+		return fmt.Sprintf(`(%[1]s < $%[3]d or (%[1]s = $%[3]d and (%[2]s) collate "C" < $%[4]d))`, timeColumn, id, n, n+1), []any{bound.At, bound.AfterID}
+	case bound.Inclusive:
+		return fmt.Sprintf(`%s <= $%d`, timeColumn, n), []any{bound.At}
+	default:
+		return fmt.Sprintf(`%s < $%d`, timeColumn, n), []any{bound.At}
+	}
+}
+
+// Events returns the bounces of the campaign emails of the newsletter and
+// its completed campaigns, following after.
+func (cr *CampaignEventRepository) Events(ctx context.Context, newsletterID uuid.UUID, after *pagination.Cursor, limit int) ([]*domain.Event, error) {
+	bounces, err := cr.bounces(ctx, newsletterID, domain.BoundOf(after, domain.EventBounced), limit)
+	if err != nil {
+		return nil, err
+	}
+	sent, err := cr.sent(ctx, newsletterID, domain.BoundOf(after, domain.EventCampaignSent), limit)
+	if err != nil {
+		return nil, err
+	}
+	return domain.Merge(limit, bounces, sent), nil
+}
+
+// bounces reads bounced deliveries, identified by campaign and recipient.
+// Bounces are timed by the last update of the delivery, when the provider
+// reported them.
+func (cr *CampaignEventRepository) bounces(ctx context.Context, newsletterID uuid.UUID, bound *domain.Bound, limit int) ([]*domain.Event, error) {
+	const id = `d.campaign_id::text || '/' || d.email`
+	condition, args := boundCondition(bound, "d.updated_at", id, 3)
+	query := `select d.campaign_id, d.email, d.error, d.updated_at, ` + id + ` from campaign_deliveries d
+		join campaigns c on c.id = d.campaign_id
+		where c.newsletter_id = $1 and d.status = 'bounced' and ` + condition + `
+		order by d.updated_at desc, (` + id + `) collate "C" desc
+		limit $2`
+
+	rows, err := cr.db.Query(ctx, query, append([]any{newsletterID, limit}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*domain.Event{}
+	for rows.Next() {
+		event := &domain.Event{Type: domain.EventBounced}
+		var campaignID uuid.UUID
+		if err := rows.Scan(&campaignID, &event.Email, &event.Detail, &event.OccurredAt, &event.ID); err != nil {
+			return nil, err
+		}
+		event.CampaignID = &campaignID
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// sent reads the campaigns that completed, timed by their completion.
+func (cr *CampaignEventRepository) sent(ctx context.Context, newsletterID uuid.UUID, bound *domain.Bound, limit int) ([]*domain.Event, error) {
+	condition, args := boundCondition(bound, "completed_at", "id::text", 3)
+	query := `select id, post_id, completed_at from campaigns
+		where newsletter_id = $1 and status = 'completed' and completed_at is not null and ` + condition + `
+		order by completed_at desc, id::text collate "C" desc
+		limit $2`
+
+	rows, err := cr.db.Query(ctx, query, append([]any{newsletterID, limit}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*domain.Event{}
+	for rows.Next() {
+		event := &domain.Event{Type: domain.EventCampaignSent}
+		var campaignID, postID uuid.UUID
+		if err := rows.Scan(&campaignID, &postID, &event.OccurredAt); err != nil {
+			return nil, err
+		}
+		event.ID = campaignID.String()
+		event.CampaignID, event.PostID = &campaignID, &postID
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,46 @@
+package postgres_test
+
+import (
+	"context"
+	"newsletter/internal/activity/domain"
+	"newsletter/internal/activity/infrastructure/postgres"
+	"newsletter/internal/infrastructure/pagination"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaignEventRepository_Events_MergesAfterCursor(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	newsletterID, campaignID, postID := uuid.New(), uuid.New(), uuid.New()
+	at := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	after := &pagination.Cursor{CreatedAt: at, Key: domain.EventBounced, ID: campaignID.String() + "/b@example.com"}
+
+	// Bounces resume after the cursor ID; campaigns, sorted before bounces
+	// at the same time, resume strictly before it.
+	mock.ExpectQuery(`from campaign_deliveries d`).
+		WithArgs(newsletterID, 2, at, after.ID).
+		WillReturnRows(pgxmock.NewRows([]string{"campaign_id", "email", "error", "updated_at", "id"}).
+			AddRow(campaignID, "a@example.com", "mailbox full", at, campaignID.String()+"/a@example.com"))
+	mock.ExpectQuery(`from campaigns\s+where newsletter_id = \$1 and status = 'completed' and completed_at is not null and completed_at < \$3`).
+		WithArgs(newsletterID, 2, at).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "post_id", "completed_at"}).
+			AddRow(campaignID, postID, at.Add(-time.Minute)))
+
+	events, err := postgres.NewCampaignEventRepository(mock).Events(context.Background(), newsletterID, after, 2)
+
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.EventBounced, events[0].Type)
+	assert.Equal(t, "mailbox full", events[0].Detail)
+	assert.Equal(t, domain.EventCampaignSent, events[1].Type)
+	assert.Equal(t, &postID, events[1].PostID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,43 @@
+package application
+
+import (
+	"context"
+	"log/slog"
+	"newsletter/internal/admin/domain"
+	"time"
+)
+
+// AdminService provides the system-wide statistics of the administration API.
+type AdminService struct {
+	sr domain.StatsRepository
+	sc domain.SubscriptionCounter
+}
+
+func NewAdminService(sr domain.StatsRepository, sc domain.SubscriptionCounter) *AdminService {
+	return &AdminService{sr: sr, sc: sc}
+}
+
+// Totals counts the users, newsletters and subscriptions of the whole system
+// and the campaign emails sent since midnight UTC.
+//
+// The counts are aggregated by the databases; a timeout is applied so that a
+// slow count does not hold the request.
+func (as *AdminService) Totals() (*domain.Totals, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	totals, err := as.sr.Totals(ctx, today)
+	if err != nil {
+		slog.Error("failed to count totals", "error", err)
+		return nil, err
+	}
+
+	totals.Subscriptions, err = as.sc.CountAllActive(ctx)
+	if err != nil {
+		slog.Error("failed to count subscriptions", "error", err)
+		return nil, err
+	}
+
+	return totals, nil
+}
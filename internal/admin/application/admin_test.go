@@ -0,0 +1,66 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/admin/application"
+	"newsletter/internal/admin/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Stats Repository ---
+type MockStatsRepository struct {
+	mock.Mock
+}
+
+func (m *MockStatsRepository) Totals(ctx context.Context, sentSince time.Time) (*domain.Totals, error) {
+	args := m.Called(ctx, sentSince)
+	totals := args.Get(0)
+	if totals == nil {
+		return nil, args.Error(1)
+	}
+	return totals.(*domain.Totals), args.Error(1)
+}
+
+// --- Mock Subscription Counter ---
+type MockSubscriptionCounter struct {
+	mock.Mock
+}
+
+func (m *MockSubscriptionCounter) CountAllActive(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func TestTotals_Success(t *testing.T) {
+	mockRepo, mockCounter := new(MockStatsRepository), new(MockSubscriptionCounter)
+	as := application.NewAdminService(mockRepo, mockCounter)
+
+	midnight := mock.MatchedBy(func(since time.Time) bool {
+		return since.Equal(time.Now().UTC().Truncate(24*time.Hour)) && since.Location() == time.UTC
+	})
+	mockRepo.On("Totals", mock.Anything, midnight).Return(&domain.Totals{Users: 3, Newsletters: 5, EmailsSentToday: 120}, nil)
+	mockCounter.On("CountAllActive", mock.Anything).Return(42, nil)
+
+	totals, err := as.Totals()
+
+	require.NoError(t, err)
+	assert.Equal(t, &domain.Totals{Users: 3, Newsletters: 5, Subscriptions: 42, EmailsSentToday: 120}, totals)
+}
+
+func TestTotals_CountFails(t *testing.T) {
+	mockRepo, mockCounter := new(MockStatsRepository), new(MockSubscriptionCounter)
+	as := application.NewAdminService(mockRepo, mockCounter)
+
+	mockRepo.On("Totals", mock.Anything, mock.Anything).Return(&domain.Totals{}, nil)
+	mockCounter.On("CountAllActive", mock.Anything).Return(0, errors.New("firestore down"))
+
+	_, err := as.Totals()
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Totals are the system-wide counts shown to administrators.
+type Totals struct {
+	Users           int `json:"users"`             // Registered accounts
+	Newsletters     int `json:"newsletters"`       // Newsletters of every account
+	Subscriptions   int `json:"subscriptions"`     // Subscriptions that are not unsubscribed
+	EmailsSentToday int `json:"emails_sent_today"` // Campaign emails sent since midnight UTC
+}
+
+// AdminService is an interface that contains a collection of method signatures
+// which will be implemented in application level and are responsible for
+// computing system-wide statistics.
+type AdminService interface {
+	Totals() (*Totals, error)
+}
+
+// StatsRepository computes the aggregates stored in PostgreSQL.
+type StatsRepository interface {
+	// Totals counts the users and newsletters, and the campaign emails sent
+	// since the given time. Subscriptions are left to SubscriptionCounter.
+	Totals(ctx context.Context, sentSince time.Time) (*Totals, error)
+}
+
+// SubscriptionCounter counts the subscriptions, stored in Firestore.
+type SubscriptionCounter interface {
+	CountAllActive(ctx context.Context) (int, error)
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"newsletter/internal/admin/domain"
+	"newsletter/internal/infrastructure/database"
+	"time"
+)
+
+// StatsRepository computes system-wide aggregates with PostgreSQL.
+type StatsRepository struct {
+	db database.DB
+}
+
+func NewStatsRepository(db database.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// Totals counts the users, newsletters and the campaign emails sent since
+// sentSince in a single round trip.
+func (sr *StatsRepository) Totals(ctx context.Context, sentSince time.Time) (*domain.Totals, error) {
+	query := `select
+		(select count(*) from users),
+		(select count(*) from newsletters),
+		(select count(*) from campaign_deliveries where sent_at >= $1)`
+
+	var totals domain.Totals
+	if err := sr.db.QueryRow(ctx, query, sentSince).Scan(&totals.Users, &totals.Newsletters, &totals.EmailsSentToday); err != nil {
+		return nil, err
+	}
+
+	return &totals, nil
+}
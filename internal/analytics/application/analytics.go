@@ -0,0 +1,132 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"newsletter/internal/analytics/domain"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnalyticsService provides application-level operations related to
+// newsletter analytics.
+type AnalyticsService struct {
+	ar domain.AnalyticsRepository
+}
+
+func NewAnalyticsService(ar domain.AnalyticsRepository) *AnalyticsService {
+	return &AnalyticsService{ar: ar}
+}
+
+// Growth returns the subscriber growth of a newsletter as a time series.
+//
+// Subscriptions are never deleted, so the counts of any past day are
+// derived from the subscription and unsubscription times of every
+// subscription of the newsletter. Days are UTC days; weeks start on Monday
+// and the first and last periods are cut to the requested range.
+//
+// Returns domain.ErrInvalidGranularity for an unknown granularity and
+// domain.ErrInvalidRange if to is before from or the series would have
+// more than domain.MaxPoints points.
+func (as *AnalyticsService) Growth(newsletterID uuid.UUID, from, to time.Time, granularity domain.Granularity) (*domain.Series, error) {
+	if !granularity.Valid() {
+		return nil, fmt.Errorf("%w: %q", domain.ErrInvalidGranularity, granularity)
+	}
+
+	from, to = day(from), day(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: %s is before %s", domain.ErrInvalidRange, to.Format(time.DateOnly), from.Format(time.DateOnly))
+	}
+
+	var periods []time.Time
+	for start := periodStart(from, granularity); !start.After(to); start = nextPeriod(start, granularity) {
+		if len(periods) == domain.MaxPoints {
+			return nil, fmt.Errorf("%w: more than %d points", domain.ErrInvalidRange, domain.MaxPoints)
+		}
+		periods = append(periods, start)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	spans, err := as.ar.Spans(ctx, newsletterID)
+	if err != nil {
+		slog.Error("failed to load subscription history", "newsletter_id", newsletterID, "error", err)
+		return nil, err
+	}
+
+	// Sorted event times let each count be answered by a binary search.
+	var subscribed, unsubscribed []time.Time
+	for _, span := range spans {
+		subscribed = append(subscribed, span.SubscribedAt)
+		if span.UnsubscribedAt != nil {
+			unsubscribed = append(unsubscribed, *span.UnsubscribedAt)
+		}
+	}
+	sortTimes(subscribed)
+	sortTimes(unsubscribed)
+
+	end := to.AddDate(0, 0, 1)
+	series := &domain.Series{NewsletterID: newsletterID, From: from, To: to, Granularity: granularity, Points: make([]domain.Point, 0, len(periods))}
+	for _, start := range periods {
+		periodFrom, periodEnd := start, nextPeriod(start, granularity)
+		if periodFrom.Before(from) {
+			periodFrom = from
+		}
+		if periodEnd.After(end) {
+			periodEnd = end
+		}
+
+		series.Points = append(series.Points, domain.Point{
+			Date:         start,
+			Subscribers:  countBefore(subscribed, periodEnd) - countBefore(unsubscribed, periodEnd),
+			Subscribed:   countBefore(subscribed, periodEnd) - countBefore(subscribed, periodFrom),
+			Unsubscribed: countBefore(unsubscribed, periodEnd) - countBefore(unsubscribed, periodFrom),
+		})
+	}
+
+	return series, nil
+}
+
+// day returns midnight UTC of the day of t.
+func day(t time.Time) time.Time {
+	year, month, d := t.UTC().Date()
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// periodStart returns the start of the period of granularity containing the day d.
+func periodStart(d time.Time, granularity domain.Granularity) time.Time {
+	switch granularity {
+	case domain.GranularityWeek:
+		// time.Sunday is 0: Sundays belong to the week started six days before.
+		return d.AddDate(0, 0, -(int(d.Weekday())+6)%7)
+	case domain.GranularityMonth:
+		return time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return d
+	}
+}
+
+// nextPeriod returns the start of the period following the one starting at start.
+func nextPeriod(start time.Time, granularity domain.Granularity) time.Time {
+	switch granularity {
+	case domain.GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case domain.GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func sortTimes(times []time.Time) {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+}
+
+// countBefore returns the number of sorted times strictly before t.
+func countBefore(times []time.Time, t time.Time) int {
+	return sort.Search(len(times), func(i int) bool { return !times[i].Before(t) })
+}
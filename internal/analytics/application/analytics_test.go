@@ -0,0 +1,117 @@
+package application_test
+
+import (
+	"context"
+	"newsletter/internal/analytics/application"
+	"newsletter/internal/analytics/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Analytics Repository ---
+type MockAnalyticsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAnalyticsRepository) Spans(ctx context.Context, newsletterID uuid.UUID) ([]domain.Span, error) {
+	args := m.Called(ctx, newsletterID)
+	spans := args.Get(0)
+	if spans == nil {
+		return nil, args.Error(1)
+	}
+	return spans.([]domain.Span), args.Error(1)
+}
+
+func date(value string) time.Time {
+	t, err := time.Parse(time.DateTime, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func ptr(t time.Time) *time.Time { return &t }
+
+func TestGrowth_Daily(t *testing.T) {
+	mockRepo := new(MockAnalyticsRepository)
+	as := application.NewAnalyticsService(mockRepo)
+
+	newsletterID := uuid.New()
+	mockRepo.On("Spans", mock.Anything, newsletterID).Return([]domain.Span{
+		{SubscribedAt: date("2026-01-01 10:00:00")},
+		{SubscribedAt: date("2026-01-02 09:00:00"), UnsubscribedAt: ptr(date("2026-01-03 12:00:00"))},
+		{SubscribedAt: date("2026-01-03 23:59:59")},
+		{SubscribedAt: date("2026-01-05 00:00:00")}, // after the range
+	}, nil)
+
+	series, err := as.Growth(newsletterID, date("2026-01-02 15:00:00"), date("2026-01-04 00:00:00"), domain.GranularityDay)
+
+	require.NoError(t, err)
+	assert.Equal(t, date("2026-01-02 00:00:00"), series.From)
+	assert.Equal(t, []domain.Point{
+		{Date: date("2026-01-02 00:00:00"), Subscribers: 2, Subscribed: 1, Unsubscribed: 0},
+		{Date: date("2026-01-03 00:00:00"), Subscribers: 2, Subscribed: 1, Unsubscribed: 1},
+		{Date: date("2026-01-04 00:00:00"), Subscribers: 2, Subscribed: 0, Unsubscribed: 0},
+	}, series.Points)
+}
+
+func TestGrowth_WeeklyCutsPeriodsToRange(t *testing.T) {
+	mockRepo := new(MockAnalyticsRepository)
+	as := application.NewAnalyticsService(mockRepo)
+
+	newsletterID := uuid.New()
+	mockRepo.On("Spans", mock.Anything, newsletterID).Return([]domain.Span{
+		{SubscribedAt: date("2026-01-05 08:00:00")}, // Monday, before the range
+		{SubscribedAt: date("2026-01-08 08:00:00")}, // Thursday
+		{SubscribedAt: date("2026-01-12 08:00:00")}, // next Monday
+	}, nil)
+
+	// Wednesday to the next Monday
+	series, err := as.Growth(newsletterID, date("2026-01-07 00:00:00"), date("2026-01-12 00:00:00"), domain.GranularityWeek)
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Point{
+		{Date: date("2026-01-05 00:00:00"), Subscribers: 2, Subscribed: 1},
+		{Date: date("2026-01-12 00:00:00"), Subscribers: 3, Subscribed: 1},
+	}, series.Points)
+}
+
+func TestGrowth_Monthly(t *testing.T) {
+	mockRepo := new(MockAnalyticsRepository)
+	as := application.NewAnalyticsService(mockRepo)
+
+	newsletterID := uuid.New()
+	mockRepo.On("Spans", mock.Anything, newsletterID).Return([]domain.Span{
+		{SubscribedAt: date("2026-01-31 08:00:00"), UnsubscribedAt: ptr(date("2026-02-01 08:00:00"))},
+	}, nil)
+
+	series, err := as.Growth(newsletterID, date("2026-01-15 00:00:00"), date("2026-03-31 00:00:00"), domain.GranularityMonth)
+
+	require.NoError(t, err)
+	require.Len(t, series.Points, 3)
+	assert.Equal(t, domain.Point{Date: date("2026-01-01 00:00:00"), Subscribers: 1, Subscribed: 1}, series.Points[0])
+	assert.Equal(t, domain.Point{Date: date("2026-02-01 00:00:00"), Subscribers: 0, Unsubscribed: 1}, series.Points[1])
+	assert.Equal(t, date("2026-03-01 00:00:00"), series.Points[2].Date)
+}
+
+func TestGrowth_InvalidRequest(t *testing.T) {
+	mockRepo := new(MockAnalyticsRepository)
+	as := application.NewAnalyticsService(mockRepo)
+	from := date("2026-01-10 00:00:00")
+
+	_, err := as.Growth(uuid.New(), from, from, "hour")
+	assert.ErrorIs(t, err, domain.ErrInvalidGranularity)
+
+	_, err = as.Growth(uuid.New(), from, from.AddDate(0, 0, -1), domain.GranularityDay)
+	assert.ErrorIs(t, err, domain.ErrInvalidRange)
+
+	_, err = as.Growth(uuid.New(), from, from.AddDate(0, 0, domain.MaxPoints), domain.GranularityDay)
+	assert.ErrorIs(t, err, domain.ErrInvalidRange)
+
+	mockRepo.AssertNotCalled(t, "Spans", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"context"
+	apperrors "newsletter/internal/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Granularities of a time series: the length of the period summarized by
+// each point.
+type Granularity string
+
+const (
+	GranularityDay   Granularity = "day"
+	GranularityWeek  Granularity = "week" // ISO weeks, starting on Monday
+	GranularityMonth Granularity = "month"
+)
+
+// MaxPoints is the maximum number of points of a time series.
+const MaxPoints = 1000
+
+var (
+	// ErrInvalidGranularity is returned when the granularity is not day, week or month.
+	ErrInvalidGranularity = apperrors.New(apperrors.Validation, "invalid granularity")
+	// ErrInvalidRange is returned when the end of a time series is before its
+	// start or the series would have more than MaxPoints points.
+	ErrInvalidRange = apperrors.New(apperrors.Validation, "invalid date range")
+)
+
+// Valid reports whether g is one of the supported granularities.
+func (g Granularity) Valid() bool {
+	return g == GranularityDay || g == GranularityWeek || g == GranularityMonth
+}
+
+// Span is a period a subscription was active: it counts as a subscriber
+// from SubscribedAt until UnsubscribedAt, if any. A re-subscribed
+// subscription has one per subscription.
+type Span struct {
+	SubscribedAt   time.Time
+	UnsubscribedAt *time.Time
+}
+
+// Point summarizes one period of a time series.
+type Point struct {
+	Date         time.Time `json:"date"`         // Start of the period, at midnight UTC
+	Subscribers  int       `json:"subscribers"`  // Active subscribers at the end of the period
+	Subscribed   int       `json:"subscribed"`   // New subscriptions during the period
+	Unsubscribed int       `json:"unsubscribed"` // Unsubscriptions during the period
+}
+
+// Series is the subscriber growth of a newsletter between two dates.
+type Series struct {
+	NewsletterID uuid.UUID   `json:"newsletter_id"`
+	From         time.Time   `json:"from"` // First day included, at midnight UTC
+	To           time.Time   `json:"to"`   // Last day included, at midnight UTC
+	Granularity  Granularity `json:"granularity"`
+	Points       []Point     `json:"points"`
+}
+
+// AnalyticsService is an interface that contains a collection of method signatures
+// which will be implemented in application level.
+type AnalyticsService interface {
+	// Growth returns the subscriber counts of a newsletter from the day of
+	// from to the day of to, both included, with one point per period.
+	Growth(newsletterID uuid.UUID, from, to time.Time, granularity Granularity) (*Series, error)
+}
+
+// AnalyticsRepository is an interface that contains a collection of method signatures
+// which will be implemented in persistence level.
+type AnalyticsRepository interface {
+	// Spans returns every period a subscription to a newsletter was
+	// active, including those that ended.
+	Spans(ctx context.Context, newsletterID uuid.UUID) ([]Span, error)
+}
@@ -0,0 +1,68 @@
+package firebase
+
+import (
+	"context"
+	"newsletter/internal/analytics/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+)
+
+// AnalyticsRepository reads the subscription history stored by the
+// subscriptions module in the "subscriptions" collection.
+type AnalyticsRepository struct {
+	db *firestore.Client
+}
+
+func NewAnalyticsRepository(db *firestore.Client) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// span holds the fields of a subscription document read by Spans.
+type span struct {
+	Status         string                            `firestore:"status"`
+	CreatedAt      time.Time                         `firestore:"createdAt"`
+	UnsubscribedAt *time.Time                        `firestore:"unsubscribedAt"`
+	History        []subscriptiondomain.ConsentEvent `firestore:"history"`
+}
+
+// Spans returns every period the subscriptions of a newsletter were active,
+// one per subscription and re-subscription of their history.
+//
+// Only the needed fields are fetched. Subscriptions unsubscribed before
+// unsubscription times were recorded are treated as unsubscribed at their
+// creation, so they never count as subscribers.
+func (ar *AnalyticsRepository) Spans(ctx context.Context, newsletterID uuid.UUID) ([]domain.Span, error) {
+	iter := ar.db.
+		Collection("subscriptions").
+		Where("newsletterId", "==", newsletterID.String()).
+		Select("status", "createdAt", "unsubscribedAt", "history").
+		Documents(ctx)
+	defer iter.Stop()
+
+	spans := []domain.Span{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return spans, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var s span
+		if err := doc.DataTo(&s); err != nil {
+			return nil, err
+		}
+		if s.Status == subscriptiondomain.StatusUnsubscribed && s.UnsubscribedAt == nil && len(s.History) == 0 {
+			s.UnsubscribedAt = &s.CreatedAt
+		}
+		subscription := subscriptiondomain.Subscription{CreatedAt: s.CreatedAt, UnsubscribedAt: s.UnsubscribedAt, History: s.History}
+		for _, period := range subscription.Periods() {
+			spans = append(spans, domain.Span{SubscribedAt: period.From, UnsubscribedAt: period.To})
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/pagination"
+	limitsdomain "newsletter/internal/limits/domain"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CampaignService provides application-level operations related to campaigns
+// and it orchestrates domain logic and persistence concerns.
+type CampaignService struct {
+	cr domain.CampaignRepository
+
+	limits limitsdomain.Enforcer // nil skips plan limits
+}
+
+func NewCampaignService(cr domain.CampaignRepository) *CampaignService {
+	return &CampaignService{cr: cr}
+}
+
+// SetLimits sets the enforcer of the monthly email limits of owners. A nil
+// enforcer disables them.
+func (cs *CampaignService) SetLimits(limits limitsdomain.Enforcer) {
+	cs.limits = limits
+}
+
+// CheckLimits returns limitsdomain.ErrEmailLimit if a campaign of the
+// newsletter would exceed the monthly emails of the owner's plan.
+func (cs *CampaignService) CheckLimits(newsletterID uuid.UUID) error {
+	if cs.limits == nil {
+		return nil
+	}
+	return cs.limits.CheckEmails(newsletterID)
+}
+
+// Create queues a new campaign sending a post to the subscribers of a
+// newsletter. With an A/B test, the campaign first sends two subject lines
+// to a sample of the subscribers (see domain.ABTest); with a send window,
+// subscribers are only emailed within it (see domain.SendWindow); with a
+// segment, only the subscribers in it when it is dispatched; with an
+// envelope, the emails get its reply-to address, copies and headers instead
+// of those of the newsletter. Campaigns
+// that would exceed the monthly emails of the owner's plan are refused.
+func (cs *CampaignService) Create(newsletterID, postID uuid.UUID, options domain.SendOptions) (*domain.Campaign, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cs.CheckLimits(newsletterID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	campaign, err := cs.cr.Create(ctx, &domain.Campaign{
+		NewsletterID: newsletterID,
+		PostID:       postID,
+		Status:       domain.StatusQueued,
+		ABTest:       options.ABTest,
+		SendWindow:   options.SendWindow,
+		SegmentID:    options.SegmentID,
+		Envelope:     options.Envelope,
+	})
+	if err != nil {
+		slog.Error("failed to create campaign", "newsletter_id", newsletterID, "post_id", postID, "error", err)
+		return nil, err
+	}
+
+	slog.Info("campaign queued", "campaign_id", campaign.ID, "post_id", postID)
+	return campaign, nil
+}
+
+// Get returns a campaign with its delivery progress.
+func (cs *CampaignService) Get(id uuid.UUID) (*domain.Campaign, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	return cs.cr.Get(ctx, id)
+}
+
+// Start moves a queued campaign, or one interrupted while sending, to sending.
+func (cs *CampaignService) Start(id uuid.UUID) (*domain.Campaign, error) {
+	return cs.transition(id, []string{domain.StatusQueued, domain.StatusSending}, domain.StatusSending, "")
+}
+
+// Pause stops a queued or sending campaign after its current delivery.
+func (cs *CampaignService) Pause(id uuid.UUID) (*domain.Campaign, error) {
+	return cs.transition(id, []string{domain.StatusQueued, domain.StatusSending}, domain.StatusPaused, "")
+}
+
+// Resume queues a paused or failed campaign again. Recipients that already
+// have a delivery are skipped when it is sent.
+func (cs *CampaignService) Resume(id uuid.UUID) (*domain.Campaign, error) {
+	return cs.transition(id, []string{domain.StatusPaused, domain.StatusFailed}, domain.StatusQueued, "")
+}
+
+// Complete marks a sending campaign as completed.
+func (cs *CampaignService) Complete(id uuid.UUID) (*domain.Campaign, error) {
+	return cs.transition(id, []string{domain.StatusSending}, domain.StatusCompleted, "")
+}
+
+// Fail marks a sending campaign as failed because of cause.
+func (cs *CampaignService) Fail(id uuid.UUID, cause error) (*domain.Campaign, error) {
+	return cs.transition(id, []string{domain.StatusSending}, domain.StatusFailed, cause.Error())
+}
+
+// EndSample moves a campaign whose A/B test sample was sent to testing.
+// Opens are measured until the end of the test window.
+func (cs *CampaignService) EndSample(id uuid.UUID) (*domain.Campaign, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	campaign, err := cs.cr.EndSample(ctx, id)
+	if err != nil {
+		slog.Warn("failed to start A/B test window", "campaign_id", id, "error", err)
+		return nil, err
+	}
+
+	slog.Info("campaign status changed", "campaign_id", id, "status", domain.StatusTesting, "ends_at", campaign.ABTest.EndsAt)
+	return campaign, nil
+}
+
+// PickWinner chooses the subject with the higher open rate in the sample of
+// a testing campaign, and moves the campaign to sending so that the winner
+// is sent to the remaining subscribers.
+func (cs *CampaignService) PickWinner(id uuid.UUID) (*domain.Campaign, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	campaign, err := cs.cr.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.ABTest == nil || campaign.Status != domain.StatusTesting {
+		return nil, domain.ErrInvalidTransition
+	}
+
+	test := campaign.ABTest
+	winner := test.ChooseWinner()
+	campaign, err = cs.cr.SetWinner(ctx, id, winner)
+	if err != nil {
+		slog.Warn("failed to store A/B test winner", "campaign_id", id, "error", err)
+		return nil, err
+	}
+
+	slog.Info("A/B test winner chosen", "campaign_id", id, "winner", winner,
+		"a_sent", test.A.Sent, "a_opened", test.A.Opened, "b_sent", test.B.Sent, "b_opened", test.B.Opened)
+	return campaign, nil
+}
+
+// ScheduleBatch records that the subscribers of a sending campaign waiting
+// for their send window are emailed at the given time.
+func (cs *CampaignService) ScheduleBatch(id uuid.UUID, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	if err := cs.cr.SetNextBatch(ctx, id, at); err != nil {
+		return err
+	}
+
+	slog.Info("campaign batch scheduled", "campaign_id", id, "at", at)
+	return nil
+}
+
+func (cs *CampaignService) transition(id uuid.UUID, from []string, to, reason string) (*domain.Campaign, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	campaign, err := cs.cr.Transition(ctx, id, from, to, reason)
+	if err != nil {
+		slog.Warn("failed to change campaign status", "campaign_id", id, "to", to, "error", err)
+		return nil, err
+	}
+
+	slog.Info("campaign status changed", "campaign_id", id, "status", to)
+	return campaign, nil
+}
+
+// Unfinished returns the campaigns that are queued, were sending or are
+// waiting for the end of an A/B test.
+func (cs *CampaignService) Unfinished() ([]*domain.Campaign, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return cs.cr.ListByStatus(ctx, []string{domain.StatusQueued, domain.StatusSending, domain.StatusTesting})
+}
+
+// Reserve records a pending delivery of a campaign and reports whether the
+// recipient still has to be emailed.
+func (cs *CampaignService) Reserve(delivery *domain.Delivery) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	return cs.cr.ReserveDelivery(ctx, delivery)
+}
+
+// Delivered reports whether a campaign already has a delivery to email,
+// whatever its outcome.
+func (cs *CampaignService) Delivered(campaignID uuid.UUID, email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	return cs.cr.HasDelivery(ctx, campaignID, email)
+}
+
+// Record stores the outcome of a reserved delivery. messageID is the
+// identifier the provider assigned to the email, empty if it failed.
+func (cs *CampaignService) Record(campaignID uuid.UUID, email, messageID string, sendErr error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	status, reason := domain.DeliverySent, ""
+	if sendErr != nil {
+		status, reason = domain.DeliveryFailed, sendErr.Error()
+	}
+
+	return cs.cr.UpdateDelivery(ctx, campaignID, email, status, messageID, reason)
+}
+
+// Deliveries returns a page of the deliveries of a campaign matching filter.
+//
+// Parameters:
+//   - campaignID: the campaign whose deliveries are listed
+//   - filter: optional recipient and status filters
+//   - limit: page size, clamped to [1, pagination.MaxLimit] (default pagination.DefaultLimit)
+//   - cursor: the NextCursor of the previous page, or empty for the first page
+//
+// Returns:
+//   - the page of deliveries, in the order they were reserved, with the cursor of the next page
+//   - an error wrapping domain.ErrInvalidDeliveryFilter, pagination.ErrInvalidCursor,
+//     or any repository error
+func (cs *CampaignService) Deliveries(campaignID uuid.UUID, filter domain.DeliveryFilter, limit int, cursor string) (*domain.DeliveryPage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	limit = pagination.Limit(limit)
+	deliveries, err := cs.cr.ListDeliveries(ctx, campaignID, filter, limit+1, after)
+	if err != nil {
+		slog.Error("failed to list deliveries", "campaign_id", campaignID, "error", err)
+		return nil, err
+	}
+
+	page := &domain.DeliveryPage{Deliveries: deliveries}
+	if len(deliveries) > limit {
+		page.Deliveries = deliveries[:limit]
+		last := page.Deliveries[limit-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.Email}.Encode()
+	}
+
+	return page, nil
+}
+
+// reportBatchSize is the number of deliveries Report reads at once.
+const reportBatchSize = 500
+
+// Report reads the deliveries of a campaign batch by batch, each with its
+// own timeout, resuming after the last delivery of the previous batch.
+func (cs *CampaignService) Report(campaignID uuid.UUID, fn func(*domain.Delivery) error) error {
+	var after *pagination.Cursor
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		deliveries, err := cs.cr.ListDeliveries(ctx, campaignID, domain.DeliveryFilter{}, reportBatchSize, after)
+		cancel()
+		if err != nil {
+			slog.Error("failed to read campaign report", "campaign_id", campaignID, "error", err)
+			return err
+		}
+
+		for _, delivery := range deliveries {
+			if err := fn(delivery); err != nil {
+				return err
+			}
+		}
+		if len(deliveries) < reportBatchSize {
+			return nil
+		}
+		last := deliveries[len(deliveries)-1]
+		after = &pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.Email}
+	}
+}
+
+// RecordEvent moves the delivery with messageID to status, e.g. when the
+// provider reports that the email bounced. detail describes a bounce or
+// complaint. Events that would move a delivery backwards are ignored.
+func (cs *CampaignService) RecordEvent(messageID, status, detail string) error {
+	from := domain.DeliveryEventFrom(status)
+	if from == nil {
+		return fmt.Errorf("unsupported delivery event status %q", status)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	if err := cs.cr.UpdateDeliveryByMessageID(ctx, messageID, from, status, detail); err != nil {
+		return err
+	}
+
+	slog.Info("delivery event recorded", "message", messageID, "status", status)
+	return nil
+}
+
+// RecordOpen records that the delivery with trackingID was opened, when its
+// tracking pixel was loaded.
+func (cs *CampaignService) RecordOpen(trackingID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	return cs.cr.RecordOpen(ctx, trackingID)
+}
+
+// RecordReply stores a reply to a campaign email. The campaign is found from
+// the delivery whose message ID the reply references; texts longer than
+// domain.MaxReplyLength are truncated. Replies redelivered by the provider
+// are recorded once: RecordReply then returns false.
+func (cs *CampaignService) RecordReply(reply *domain.Reply, references []string) (bool, error) {
+	messageIDs := domain.ReferencedMessageIDs(references)
+	if len(messageIDs) == 0 {
+		return false, domain.ErrDeliveryNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	delivery, err := cs.cr.FindDelivery(ctx, messageIDs)
+	if err != nil {
+		return false, err
+	}
+
+	reply.CampaignID = delivery.CampaignID
+	if len(reply.Text) > domain.MaxReplyLength {
+		reply.Text = strings.ToValidUTF8(reply.Text[:domain.MaxReplyLength], "")
+	}
+
+	created, err := cs.cr.CreateReply(ctx, reply)
+	if err != nil {
+		slog.Error("failed to record reply", "campaign_id", delivery.CampaignID, "error", err)
+		return false, err
+	}
+	if created {
+		slog.Info("campaign reply recorded", "campaign_id", delivery.CampaignID, "reply_id", reply.ID)
+	}
+	return created, nil
+}
+
+// Replies returns a page of the replies to a campaign, in the order they
+// were received.
+//
+// Parameters:
+//   - campaignID: the campaign whose replies are listed
+//   - limit: page size, clamped to [1, pagination.MaxLimit] (default pagination.DefaultLimit)
+//   - cursor: the NextCursor of the previous page, or empty for the first page
+//
+// Returns:
+//   - the page of replies with the cursor of the next page
+//   - an error wrapping pagination.ErrInvalidCursor, or any repository error
+func (cs *CampaignService) Replies(campaignID uuid.UUID, limit int, cursor string) (*domain.ReplyPage, error) {
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	limit = pagination.Limit(limit)
+	replies, err := cs.cr.ListReplies(ctx, campaignID, limit+1, after)
+	if err != nil {
+		slog.Error("failed to list replies", "campaign_id", campaignID, "error", err)
+		return nil, err
+	}
+
+	page := &domain.ReplyPage{Replies: replies}
+	if len(replies) > limit {
+		page.Replies = replies[:limit]
+		last := page.Replies[limit-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.ReceivedAt, ID: last.ID.String()}.Encode()
+	}
+
+	return page, nil
+}
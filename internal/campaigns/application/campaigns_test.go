@@ -0,0 +1,419 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"newsletter/internal/campaigns/application"
+	"newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/pagination"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Campaign Repository ---
+type MockCampaignRepository struct {
+	mock.Mock
+}
+
+func (m *MockCampaignRepository) campaign(args mock.Arguments) (*domain.Campaign, error) {
+	c := args.Get(0)
+	if c == nil {
+		return nil, args.Error(1)
+	}
+	return c.(*domain.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignRepository) Create(ctx context.Context, campaign *domain.Campaign) (*domain.Campaign, error) {
+	return m.campaign(m.Called(ctx, campaign))
+}
+
+func (m *MockCampaignRepository) Get(ctx context.Context, id uuid.UUID) (*domain.Campaign, error) {
+	return m.campaign(m.Called(ctx, id))
+}
+
+func (m *MockCampaignRepository) Transition(ctx context.Context, id uuid.UUID, from []string, to, reason string) (*domain.Campaign, error) {
+	return m.campaign(m.Called(ctx, id, from, to, reason))
+}
+
+func (m *MockCampaignRepository) ListByStatus(ctx context.Context, statuses []string) ([]*domain.Campaign, error) {
+	args := m.Called(ctx, statuses)
+	return args.Get(0).([]*domain.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignRepository) EndSample(ctx context.Context, id uuid.UUID) (*domain.Campaign, error) {
+	return m.campaign(m.Called(ctx, id))
+}
+
+func (m *MockCampaignRepository) SetWinner(ctx context.Context, id uuid.UUID, winner string) (*domain.Campaign, error) {
+	return m.campaign(m.Called(ctx, id, winner))
+}
+
+func (m *MockCampaignRepository) SetNextBatch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return m.Called(ctx, id, at).Error(0)
+}
+
+func (m *MockCampaignRepository) HasDelivery(ctx context.Context, campaignID uuid.UUID, email string) (bool, error) {
+	args := m.Called(ctx, campaignID, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCampaignRepository) ReserveDelivery(ctx context.Context, delivery *domain.Delivery) (bool, error) {
+	args := m.Called(ctx, delivery)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCampaignRepository) UpdateDelivery(ctx context.Context, campaignID uuid.UUID, email, status, messageID, reason string) error {
+	return m.Called(ctx, campaignID, email, status, messageID, reason).Error(0)
+}
+
+func (m *MockCampaignRepository) ListDeliveries(ctx context.Context, campaignID uuid.UUID, filter domain.DeliveryFilter, limit int, after *pagination.Cursor) ([]*domain.Delivery, error) {
+	args := m.Called(ctx, campaignID, filter, limit, after)
+	return args.Get(0).([]*domain.Delivery), args.Error(1)
+}
+
+func (m *MockCampaignRepository) UpdateDeliveryByMessageID(ctx context.Context, messageID string, from []string, to, reason string) error {
+	return m.Called(ctx, messageID, from, to, reason).Error(0)
+}
+
+func (m *MockCampaignRepository) RecordOpen(ctx context.Context, trackingID uuid.UUID) error {
+	return m.Called(ctx, trackingID).Error(0)
+}
+
+func (m *MockCampaignRepository) FindDelivery(ctx context.Context, messageIDs []string) (*domain.Delivery, error) {
+	args := m.Called(ctx, messageIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Delivery), args.Error(1)
+}
+
+func (m *MockCampaignRepository) CreateReply(ctx context.Context, reply *domain.Reply) (bool, error) {
+	args := m.Called(ctx, reply)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCampaignRepository) ListReplies(ctx context.Context, campaignID uuid.UUID, limit int, after *pagination.Cursor) ([]*domain.Reply, error) {
+	args := m.Called(ctx, campaignID, limit, after)
+	return args.Get(0).([]*domain.Reply), args.Error(1)
+}
+
+// --- Tests ---
+
+func TestCreateCampaign_StartsQueued(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	newsletterID, postID := uuid.New(), uuid.New()
+	created := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletterID, PostID: postID, Status: domain.StatusQueued}
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(c *domain.Campaign) bool {
+		return c.Status == domain.StatusQueued && c.PostID == postID
+	})).Return(created, nil)
+
+	campaign, err := cs.Create(newsletterID, postID, domain.SendOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, created, campaign)
+}
+
+func TestCreateCampaign_InvalidABTest(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	tests := map[string]domain.ABTest{
+		"missing subject":  {SubjectA: "Issue #1", SamplePercent: 10, WindowMinutes: 60},
+		"same subjects":    {SubjectA: "Issue #1", SubjectB: "Issue #1", SamplePercent: 10, WindowMinutes: 60},
+		"multiline":        {SubjectA: "Issue #1", SubjectB: "Issue\r\nBcc: x@example.com", SamplePercent: 10, WindowMinutes: 60},
+		"sample too large": {SubjectA: "Issue #1", SubjectB: "Issue #2", SamplePercent: 51, WindowMinutes: 60},
+		"no window":        {SubjectA: "Issue #1", SubjectB: "Issue #2", SamplePercent: 10},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := cs.Create(uuid.New(), uuid.New(), domain.SendOptions{ABTest: &test})
+			assert.ErrorIs(t, err, domain.ErrInvalidABTest)
+		})
+	}
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCreateCampaign_InvalidSendWindow(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	windows := map[string]domain.SendWindow{
+		"bad start":        {Start: "9am", End: "17:00"},
+		"bad end":          {Start: "09:00", End: "25:00"},
+		"empty":            {Start: "09:00", End: "09:00"},
+		"unknown timezone": {Start: "09:00", End: "17:00", Timezone: "Mars/Olympus"},
+		"server timezone":  {Start: "09:00", End: "17:00", Timezone: "Local"},
+	}
+	for name, window := range windows {
+		t.Run(name, func(t *testing.T) {
+			_, err := cs.Create(uuid.New(), uuid.New(), domain.SendOptions{SendWindow: &window})
+			assert.ErrorIs(t, err, domain.ErrInvalidSendWindow)
+		})
+	}
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestSendWindow_Next(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	day := window("09:00", "17:00")
+	night := window("22:00", "06:00")
+
+	tests := []struct {
+		name   string
+		window *domain.SendWindow
+		now    time.Time
+		want   time.Time
+	}{
+		{"open", day, time.Date(2026, 3, 2, 10, 0, 0, 0, berlin), time.Date(2026, 3, 2, 10, 0, 0, 0, berlin)},
+		{"before", day, time.Date(2026, 3, 2, 7, 30, 0, 0, berlin), time.Date(2026, 3, 2, 9, 0, 0, 0, berlin)},
+		{"after", day, time.Date(2026, 3, 2, 17, 0, 0, 0, berlin), time.Date(2026, 3, 3, 9, 0, 0, 0, berlin)},
+		{"after at month end", day, time.Date(2026, 3, 31, 18, 0, 0, 0, berlin), time.Date(2026, 4, 1, 9, 0, 0, 0, berlin)},
+		{"overnight open late", night, time.Date(2026, 3, 2, 23, 0, 0, 0, berlin), time.Date(2026, 3, 2, 23, 0, 0, 0, berlin)},
+		{"overnight open early", night, time.Date(2026, 3, 2, 5, 59, 0, 0, berlin), time.Date(2026, 3, 2, 5, 59, 0, 0, berlin)},
+		{"overnight closed", night, time.Date(2026, 3, 2, 12, 0, 0, 0, berlin), time.Date(2026, 3, 2, 22, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.want.Equal(tt.window.Next(tt.now.UTC(), berlin)), "got %v", tt.window.Next(tt.now.UTC(), berlin))
+		})
+	}
+}
+
+func TestSendWindow_Location(t *testing.T) {
+	w := &domain.SendWindow{Start: "09:00", End: "17:00", Timezone: "Asia/Tokyo"}
+
+	assert.Equal(t, "America/New_York", w.Location("America/New_York").String())
+	assert.Equal(t, "Asia/Tokyo", w.Location("").String())
+	assert.Equal(t, "Asia/Tokyo", w.Location("Not/AZone").String())
+	assert.Equal(t, time.UTC, window("09:00", "17:00").Location(""))
+}
+
+// window returns a send window in UTC.
+func window(start, end string) *domain.SendWindow {
+	return &domain.SendWindow{Start: start, End: end}
+}
+
+func TestABTest_VariantOfSplitsSample(t *testing.T) {
+	test := &domain.ABTest{SamplePercent: 10}
+	campaignID := uuid.New()
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		email := uuid.NewString() + "@example.com"
+		variant := test.VariantOf(campaignID, email)
+		assert.Equal(t, variant, test.VariantOf(campaignID, email), "assignment must be stable")
+		counts[variant]++
+	}
+
+	assert.InDelta(t, 1000, counts[domain.VariantA], 150)
+	assert.InDelta(t, 1000, counts[domain.VariantB], 150)
+	assert.InDelta(t, 8000, counts[""], 300)
+}
+
+func TestPickWinner_HigherOpenRate(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	id := uuid.New()
+	waiting := &domain.Campaign{ID: id, Status: domain.StatusTesting, ABTest: &domain.ABTest{
+		A: domain.VariantStats{Sent: 100, Opened: 30},
+		B: domain.VariantStats{Sent: 50, Opened: 20},
+	}}
+	mockRepo.On("Get", mock.Anything, id).Return(waiting, nil)
+	mockRepo.On("SetWinner", mock.Anything, id, domain.VariantB).Return(&domain.Campaign{ID: id, Status: domain.StatusSending}, nil)
+
+	campaign, err := cs.PickWinner(id)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusSending, campaign.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPickWinner_TieGoesToA(t *testing.T) {
+	test := &domain.ABTest{A: domain.VariantStats{Sent: 10, Opened: 5}, B: domain.VariantStats{Sent: 20, Opened: 10}}
+	assert.Equal(t, domain.VariantA, test.ChooseWinner())
+
+	empty := &domain.ABTest{}
+	assert.Equal(t, domain.VariantA, empty.ChooseWinner())
+}
+
+func TestPickWinner_NotTesting(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	id := uuid.New()
+	mockRepo.On("Get", mock.Anything, id).Return(&domain.Campaign{ID: id, Status: domain.StatusSending, ABTest: &domain.ABTest{Winner: domain.VariantA}}, nil)
+
+	_, err := cs.PickWinner(id)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidTransition)
+	mockRepo.AssertNotCalled(t, "SetWinner", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResumeCampaign_OnlyPausedOrFailed(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	id := uuid.New()
+	mockRepo.On("Transition", mock.Anything, id, []string{domain.StatusPaused, domain.StatusFailed}, domain.StatusQueued, "").
+		Return(nil, domain.ErrInvalidTransition)
+
+	_, err := cs.Resume(id)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidTransition)
+}
+
+func TestFailCampaign_StoresReason(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	id := uuid.New()
+	mockRepo.On("Transition", mock.Anything, id, []string{domain.StatusSending}, domain.StatusFailed, "provider down").
+		Return(&domain.Campaign{ID: id, Status: domain.StatusFailed, Error: "provider down"}, nil)
+
+	campaign, err := cs.Fail(id, errors.New("provider down"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "provider down", campaign.Error)
+}
+
+func TestRecordDelivery(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	id := uuid.New()
+	mockRepo.On("UpdateDelivery", mock.Anything, id, "a@example.com", domain.DeliverySent, "msg-1", "").Return(nil)
+	mockRepo.On("UpdateDelivery", mock.Anything, id, "b@example.com", domain.DeliveryFailed, "", "rejected").Return(nil)
+
+	assert.NoError(t, cs.Record(id, "a@example.com", "msg-1", nil))
+	assert.NoError(t, cs.Record(id, "b@example.com", "", errors.New("rejected")))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeliveries_Paginates(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	id := uuid.New()
+	now := time.Now()
+	mockRepo.On("ListDeliveries", mock.Anything, id, domain.DeliveryFilter{}, 3, (*pagination.Cursor)(nil)).Return([]*domain.Delivery{
+		{Email: "a@example.com", CreatedAt: now},
+		{Email: "b@example.com", CreatedAt: now},
+		{Email: "c@example.com", CreatedAt: now},
+	}, nil)
+
+	page, err := cs.Deliveries(id, domain.DeliveryFilter{}, 2, "")
+
+	require.NoError(t, err)
+	assert.Len(t, page.Deliveries, 2)
+	cursor, err := pagination.Decode(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, "b@example.com", cursor.ID)
+}
+
+func TestDeliveries_InvalidStatus(t *testing.T) {
+	cs := application.NewCampaignService(new(MockCampaignRepository))
+
+	_, err := cs.Deliveries(uuid.New(), domain.DeliveryFilter{Status: "lost"}, 0, "")
+
+	assert.ErrorIs(t, err, domain.ErrInvalidDeliveryFilter)
+}
+
+func TestReport_ReadsEveryBatch(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	id := uuid.New()
+	now := time.Now()
+	batch := make([]*domain.Delivery, 500)
+	for i := range batch {
+		batch[i] = &domain.Delivery{Email: fmt.Sprintf("%03d@example.com", i), CreatedAt: now}
+	}
+	mockRepo.On("ListDeliveries", mock.Anything, id, domain.DeliveryFilter{}, 500, (*pagination.Cursor)(nil)).Return(batch, nil)
+	mockRepo.On("ListDeliveries", mock.Anything, id, domain.DeliveryFilter{}, 500, &pagination.Cursor{CreatedAt: now, ID: "499@example.com"}).Return([]*domain.Delivery{
+		{Email: "last@example.com", CreatedAt: now},
+	}, nil)
+
+	var emails []string
+	err := cs.Report(id, func(delivery *domain.Delivery) error {
+		emails = append(emails, delivery.Email)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Len(t, emails, 501)
+	assert.Equal(t, "last@example.com", emails[500])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRecordEvent_NeverOverwritesBounce(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	mockRepo.On("UpdateDeliveryByMessageID", mock.Anything, "msg-1", []string{domain.DeliverySent}, domain.DeliveryDelivered, "").Return(nil)
+
+	assert.NoError(t, cs.RecordEvent("msg-1", domain.DeliveryDelivered, ""))
+	assert.Error(t, cs.RecordEvent("msg-1", domain.DeliveryPending, ""))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRecordReply(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	campaignID := uuid.New()
+	mockRepo.On("FindDelivery", mock.Anything, []string{"<0100-abc@email.amazonses.com>", "0100-abc"}).
+		Return(&domain.Delivery{CampaignID: campaignID, Email: "reader@example.com"}, nil)
+	mockRepo.On("CreateReply", mock.Anything, mock.MatchedBy(func(r *domain.Reply) bool {
+		return r.CampaignID == campaignID && len(r.Text) == domain.MaxReplyLength
+	})).Return(true, nil)
+
+	reply := &domain.Reply{Email: "reader@example.com", Text: strings.Repeat("a", domain.MaxReplyLength+10)}
+	created, err := cs.RecordReply(reply, []string{"<0100-abc@email.amazonses.com>", "no-brackets"})
+
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, campaignID, reply.CampaignID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRecordReply_NoReferences(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	_, err := cs.RecordReply(&domain.Reply{Email: "reader@example.com"}, nil)
+
+	assert.ErrorIs(t, err, domain.ErrDeliveryNotFound)
+	mockRepo.AssertNotCalled(t, "FindDelivery", mock.Anything, mock.Anything)
+}
+
+func TestReplies_Paginates(t *testing.T) {
+	mockRepo := new(MockCampaignRepository)
+	cs := application.NewCampaignService(mockRepo)
+
+	campaignID := uuid.New()
+	now := time.Now().UTC()
+	replies := []*domain.Reply{
+		{ID: uuid.New(), CampaignID: campaignID, ReceivedAt: now},
+		{ID: uuid.New(), CampaignID: campaignID, ReceivedAt: now.Add(time.Minute)},
+	}
+	mockRepo.On("ListReplies", mock.Anything, campaignID, 2, (*pagination.Cursor)(nil)).Return(replies, nil)
+
+	page, err := cs.Replies(campaignID, 1, "")
+
+	require.NoError(t, err)
+	assert.Len(t, page.Replies, 1)
+	cursor, err := pagination.Decode(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, replies[0].ID.String(), cursor.ID)
+}
@@ -0,0 +1,77 @@
+package application
+
+import (
+	"maps"
+	"newsletter/internal/campaigns/domain"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Throttle implements domain.Throttle in memory. Limits apply per instance:
+// with several instances, a newsletter may send up to its cap on each.
+type Throttle struct {
+	mu      sync.Mutex
+	limits  domain.ThrottleLimits
+	running map[uuid.UUID]int
+}
+
+// NewThrottle creates a Throttle allowing perNewsletter campaigns of every
+// newsletter to be sent at once.
+func NewThrottle(perNewsletter int) *Throttle {
+	return &Throttle{
+		limits:  domain.ThrottleLimits{PerNewsletter: perNewsletter},
+		running: make(map[uuid.UUID]int),
+	}
+}
+
+func (t *Throttle) Acquire(newsletterID uuid.UUID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running[newsletterID] >= t.limits.Limit(newsletterID) {
+		return false
+	}
+	t.running[newsletterID]++
+	return true
+}
+
+func (t *Throttle) Release(newsletterID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running[newsletterID] <= 1 {
+		delete(t.running, newsletterID)
+		return
+	}
+	t.running[newsletterID]--
+}
+
+func (t *Throttle) Limits() domain.ThrottleLimits {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limits := t.limits
+	limits.Overrides = maps.Clone(t.limits.Overrides)
+	return limits
+}
+
+func (t *Throttle) SetLimits(limits domain.ThrottleLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limits.Overrides = maps.Clone(limits.Overrides)
+	t.limits = limits
+	return nil
+}
+
+func (t *Throttle) Running() map[uuid.UUID]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return maps.Clone(t.running)
+}
@@ -0,0 +1,45 @@
+package application_test
+
+import (
+	"newsletter/internal/campaigns/application"
+	"newsletter/internal/campaigns/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle_AcquireAndRelease(t *testing.T) {
+	throttle := application.NewThrottle(1)
+	first, second := uuid.New(), uuid.New()
+
+	assert.True(t, throttle.Acquire(first))
+	assert.False(t, throttle.Acquire(first), "the newsletter is at its cap")
+	assert.True(t, throttle.Acquire(second), "other newsletters are not held back")
+	assert.Equal(t, map[uuid.UUID]int{first: 1, second: 1}, throttle.Running())
+
+	throttle.Release(first)
+	assert.True(t, throttle.Acquire(first))
+
+	throttle.Release(first)
+	throttle.Release(second)
+	assert.Empty(t, throttle.Running())
+}
+
+func TestThrottle_SetLimits(t *testing.T) {
+	throttle := application.NewThrottle(1)
+	large := uuid.New()
+
+	require.NoError(t, throttle.SetLimits(domain.ThrottleLimits{PerNewsletter: 1, Overrides: map[uuid.UUID]int{large: 2}}))
+	assert.True(t, throttle.Acquire(large))
+	assert.True(t, throttle.Acquire(large))
+	assert.False(t, throttle.Acquire(large))
+	assert.Equal(t, 2, throttle.Limits().Overrides[large])
+
+	err := throttle.SetLimits(domain.ThrottleLimits{PerNewsletter: 0})
+	assert.ErrorIs(t, err, domain.ErrInvalidThrottle)
+	err = throttle.SetLimits(domain.ThrottleLimits{PerNewsletter: 1, Overrides: map[uuid.UUID]int{large: -1}})
+	assert.ErrorIs(t, err, domain.ErrInvalidThrottle)
+	assert.Equal(t, 2, throttle.Limits().Limit(large), "invalid limits are not applied")
+}
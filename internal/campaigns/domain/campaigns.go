@@ -0,0 +1,537 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	apperrors "newsletter/internal/errors"
+	"newsletter/internal/infrastructure/pagination"
+	notificationdomain "newsletter/internal/notifications/domain"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Campaign statuses.
+//
+//	queued -> sending -> completed
+//	                  -> failed
+//	queued, sending -> paused -> queued (resume)
+//	failed -> queued (resume)
+//
+// Campaigns with an A/B test wait in testing between the sending of the
+// sample and the sending of the winning subject to the remainder:
+//
+//	queued -> sending -> testing -> sending -> completed
+const (
+	StatusQueued    = "queued"
+	StatusSending   = "sending"
+	StatusTesting   = "testing"
+	StatusPaused    = "paused"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Delivery statuses. A delivery is reserved as pending before the email is
+// handed to the provider, so that a send interrupted by a crash never emails
+// the same recipient twice when it is resumed. Sent deliveries are later
+// updated from the events reported by the provider:
+//
+//	pending -> sent -> delivered -> bounced | complained
+//	        -> failed  sent -> bounced | complained
+const (
+	DeliveryPending    = "pending"
+	DeliverySent       = "sent"
+	DeliveryFailed     = "failed"
+	DeliveryDelivered  = "delivered"
+	DeliveryBounced    = "bounced"
+	DeliveryComplained = "complained"
+)
+
+// DeliveryEventFrom returns the delivery statuses a provider event moving a
+// delivery to status may apply to. Events can arrive out of order, so a
+// bounce or complaint is never overwritten by a later delivery event. It
+// returns nil for statuses that are not set by provider events.
+func DeliveryEventFrom(status string) []string {
+	switch status {
+	case DeliveryDelivered:
+		return []string{DeliverySent}
+	case DeliveryBounced, DeliveryComplained:
+		return []string{DeliverySent, DeliveryDelivered}
+	default:
+		return nil
+	}
+}
+
+var (
+	// ErrCampaignNotFound is returned when a campaign does not exist.
+	ErrCampaignNotFound = apperrors.New(apperrors.NotFound, "campaign not found")
+	// ErrInvalidTransition is returned when a campaign cannot change to the requested status.
+	ErrInvalidTransition = apperrors.New(apperrors.Conflict, "invalid campaign status transition")
+	// ErrDeliveryNotFound is returned when no delivery matches a provider message ID.
+	ErrDeliveryNotFound = apperrors.New(apperrors.NotFound, "delivery not found")
+	// ErrInvalidDeliveryFilter is returned when a delivery listing filter is malformed.
+	ErrInvalidDeliveryFilter = apperrors.New(apperrors.Validation, "invalid delivery filter")
+	// ErrInvalidABTest is returned when the settings of an A/B test are malformed.
+	ErrInvalidABTest = apperrors.New(apperrors.Validation, "invalid A/B test")
+	// ErrInvalidSendWindow is returned when a send window is malformed.
+	ErrInvalidSendWindow = apperrors.New(apperrors.Validation, "invalid send window")
+	// ErrInvalidThrottle is returned when delivery throttling limits are malformed.
+	ErrInvalidThrottle = apperrors.New(apperrors.Validation, "invalid throttling limits")
+)
+
+// SendOptions are the optional settings of a new campaign.
+type SendOptions struct {
+	ABTest     *ABTest     `json:"ab_test,omitempty"`     // Subject lines to test before sending to everybody
+	SendWindow *SendWindow `json:"send_window,omitempty"` // Local times at which emails may be sent
+	SegmentID  *uuid.UUID  `json:"segment_id,omitempty"`  // Segment of the newsletter the post is sent to, instead of every subscriber
+	// Reply-To, copies and custom headers overriding those of the newsletter
+	Envelope *notificationdomain.Envelope `json:"envelope,omitempty"`
+}
+
+// Validate checks the options, returning an error wrapping ErrInvalidABTest,
+// ErrInvalidSendWindow or notificationdomain.ErrInvalidEnvelope.
+func (o SendOptions) Validate() error {
+	if o.ABTest != nil {
+		if err := o.ABTest.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.SendWindow != nil {
+		if err := o.SendWindow.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.Envelope != nil {
+		return o.Envelope.Validate()
+	}
+	return nil
+}
+
+// SendWindow restricts campaign emails to a daily range of local times, in
+// the timezone of each subscriber, so that a global audience receives them
+// during the day. Subscribers without a known timezone use Timezone.
+type SendWindow struct {
+	Start    string `json:"start"`              // Local time sending starts, "HH:MM"
+	End      string `json:"end"`                // Local time sending stops, "HH:MM"; before Start for windows spanning midnight
+	Timezone string `json:"timezone,omitempty"` // IANA timezone of subscribers without one, such as "Europe/Berlin"; UTC by default
+}
+
+// clock parses a "HH:MM" time of day into minutes after midnight.
+func clock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate checks the window, returning an error wrapping ErrInvalidSendWindow.
+func (w *SendWindow) Validate() error {
+	start, err := clock(w.Start)
+	if err != nil {
+		return fmt.Errorf("%w: start must be a time such as 09:00", ErrInvalidSendWindow)
+	}
+	end, err := clock(w.End)
+	if err != nil {
+		return fmt.Errorf("%w: end must be a time such as 17:00", ErrInvalidSendWindow)
+	}
+	if start == end {
+		return fmt.Errorf("%w: start and end must differ", ErrInvalidSendWindow)
+	}
+	if w.Timezone != "" {
+		if _, err := LoadTimezone(w.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidSendWindow, w.Timezone)
+		}
+	}
+	return nil
+}
+
+// LoadTimezone returns the location of an IANA timezone name. Unlike
+// time.LoadLocation, it rejects the empty name and "Local", whose meaning
+// depends on the server.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// Location returns the timezone the window applies to for a subscriber in
+// timezone, falling back to the window timezone and then to UTC when it is
+// empty or unknown.
+func (w *SendWindow) Location(timezone string) *time.Location {
+	for _, name := range []string{timezone, w.Timezone} {
+		if loc, err := LoadTimezone(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// Next returns the earliest time at or after now that falls within the
+// window in loc: now itself when the window is open.
+func (w *SendWindow) Next(now time.Time, loc *time.Location) time.Time {
+	start, _ := clock(w.Start)
+	end, _ := clock(w.End)
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	open := start <= minute && minute < end
+	if start > end {
+		open = minute >= start || minute < end
+	}
+	if open {
+		return now
+	}
+
+	day := local.Day()
+	if minute >= start {
+		day++
+	}
+	return time.Date(local.Year(), local.Month(), day, start/60, start%60, 0, 0, loc)
+}
+
+// A/B test variants. Deliveries to the remainder of the subscribers, sent
+// the winning subject once the test ended, have no variant.
+const (
+	VariantA = "a"
+	VariantB = "b"
+)
+
+// Limits of the settings of an A/B test.
+const (
+	MaxSamplePercent = 50          // Each variant is sent to at most half of the subscribers
+	MaxTestWindow    = 7 * 24 * 60 // Opens are measured for at most a week, in minutes
+	MaxSubjectLength = 200
+)
+
+// ABTest compares two subject lines of a campaign. Each subject is sent to
+// SamplePercent of the subscribers; once WindowMinutes have passed, the
+// subject with the higher open rate is sent to the remaining subscribers.
+type ABTest struct {
+	SubjectA      string       `json:"subject_a"`         // First subject; defaults to the post title
+	SubjectB      string       `json:"subject_b"`         // Second subject
+	SamplePercent int          `json:"sample_percent"`    // Percentage of the subscribers receiving each subject
+	WindowMinutes int          `json:"window_minutes"`    // Time opens are measured for after the sample was sent
+	EndsAt        *time.Time   `json:"ends_at,omitempty"` // End of the test window, set once the sample was sent
+	Winner        string       `json:"winner,omitempty"`  // Variant sent to the remainder, "a" or "b", once chosen
+	A             VariantStats `json:"a"`                 // Results of the first subject
+	B             VariantStats `json:"b"`                 // Results of the second subject
+}
+
+// VariantStats counts the recipients of a variant and how many opened it.
+type VariantStats struct {
+	Sent   int `json:"sent"`
+	Opened int `json:"opened"`
+}
+
+// Validate checks the settings of the test, returning an error wrapping
+// ErrInvalidABTest.
+func (t *ABTest) Validate() error {
+	switch {
+	case t.SubjectB == "":
+		return fmt.Errorf("%w: subject_b is required", ErrInvalidABTest)
+	case len(t.SubjectA) > MaxSubjectLength || len(t.SubjectB) > MaxSubjectLength:
+		return fmt.Errorf("%w: subjects must be at most %d characters", ErrInvalidABTest, MaxSubjectLength)
+	case strings.ContainsAny(t.SubjectA+t.SubjectB, "\r\n"):
+		return fmt.Errorf("%w: subjects must be a single line", ErrInvalidABTest)
+	case t.SubjectA == t.SubjectB:
+		return fmt.Errorf("%w: subjects must differ", ErrInvalidABTest)
+	case t.SamplePercent < 1 || t.SamplePercent > MaxSamplePercent:
+		return fmt.Errorf("%w: sample_percent must be between 1 and %d", ErrInvalidABTest, MaxSamplePercent)
+	case t.WindowMinutes < 1 || t.WindowMinutes > MaxTestWindow:
+		return fmt.Errorf("%w: window_minutes must be between 1 and %d", ErrInvalidABTest, MaxTestWindow)
+	}
+	return nil
+}
+
+// VariantOf returns the variant sent to email during the test, or "" if the
+// recipient is not part of the sample. Recipients are assigned by a hash of
+// the campaign and address, so a resumed campaign assigns them the same way.
+func (t *ABTest) VariantOf(campaignID uuid.UUID, email string) string {
+	hash := fnv.New32a()
+	hash.Write(campaignID[:])
+	hash.Write([]byte(email))
+
+	switch bucket := int(hash.Sum32() % 100); {
+	case bucket < t.SamplePercent:
+		return VariantA
+	case bucket < 2*t.SamplePercent:
+		return VariantB
+	default:
+		return ""
+	}
+}
+
+// Subject returns the subject of variant, or of the winner for the
+// remainder of the subscribers.
+func (t *ABTest) Subject(variant string) string {
+	if variant == "" {
+		variant = t.Winner
+	}
+	if variant == VariantB {
+		return t.SubjectB
+	}
+	return t.SubjectA
+}
+
+// ChooseWinner returns the variant with the higher open rate. Ties go to
+// the first subject.
+func (t *ABTest) ChooseWinner() string {
+	// a.Opened/a.Sent < b.Opened/b.Sent, without dividing by zero.
+	if t.A.Opened*t.B.Sent < t.B.Opened*t.A.Sent {
+		return VariantB
+	}
+	return VariantA
+}
+
+// Campaign is the delivery of a post to the subscribers of its newsletter.
+type Campaign struct {
+	ID           uuid.UUID   `json:"id"`                      // ID of the campaign
+	NewsletterID uuid.UUID   `json:"newsletter_id"`           // Newsletter whose subscribers receive the post
+	PostID       uuid.UUID   `json:"post_id"`                 // Post being sent
+	Status       string      `json:"status"`                  // Status of the campaign
+	Sent         int         `json:"sent"`                    // Number of recipients the post was sent to
+	Failed       int         `json:"failed"`                  // Number of recipients the post could not be sent to
+	Pending      int         `json:"pending"`                 // Number of deliveries in progress or interrupted
+	ABTest       *ABTest     `json:"ab_test,omitempty"`       // Subject line test, if any
+	SendWindow   *SendWindow `json:"send_window,omitempty"`   // Local times emails are sent at, if restricted
+	SegmentID    *uuid.UUID  `json:"segment_id,omitempty"`    // Segment the post is sent to, resolved at dispatch time; every subscriber if nil
+	NextBatchAt  *time.Time  `json:"next_batch_at,omitempty"` // Time the subscribers waiting for their send window are emailed
+	Error        string      `json:"error,omitempty"`         // Reason of a failed campaign
+	CreatedAt    time.Time   `json:"created_at"`              // Creation time of the campaign
+	StartedAt    *time.Time  `json:"started_at,omitempty"`    // Time sending first started
+	CompletedAt  *time.Time  `json:"completed_at,omitempty"`  // Time sending completed
+	UpdatedAt    time.Time   `json:"updated_at"`              // Time of the last status change
+
+	// Envelope overrides the Reply-To, copies and custom headers of the newsletter, if set
+	Envelope *notificationdomain.Envelope `json:"envelope,omitempty"`
+}
+
+// Delivery records the delivery of a campaign to one recipient.
+type Delivery struct {
+	CampaignID uuid.UUID  `json:"campaign_id"`          // Campaign the email belongs to
+	Email      string     `json:"email"`                // Recipient
+	Status     string     `json:"status"`               // Delivery status
+	Variant    string     `json:"variant,omitempty"`    // A/B test variant sent to the recipient
+	TrackingID uuid.UUID  `json:"-"`                    // Identifies the delivery in the open tracking pixel
+	MessageID  string     `json:"message_id,omitempty"` // Identifier assigned by the email provider, e.g. the SES MessageId
+	Error      string     `json:"error,omitempty"`      // Reason of a failure, bounce or complaint
+	CreatedAt  time.Time  `json:"created_at"`           // Time the delivery was reserved
+	SentAt     *time.Time `json:"sent_at,omitempty"`    // Time the provider accepted the email
+	OpenedAt   *time.Time `json:"opened_at,omitempty"`  // Time the email was first opened, for tracked deliveries
+	UpdatedAt  time.Time  `json:"updated_at"`           // Time of the last status change
+}
+
+// DeliveryFilter narrows a delivery listing. Zero values match everything.
+type DeliveryFilter struct {
+	Email  string // Exact recipient address
+	Status string // One of the delivery statuses
+}
+
+// Validate checks the filter, returning an error wrapping ErrInvalidDeliveryFilter.
+func (f DeliveryFilter) Validate() error {
+	switch f.Status {
+	case "", DeliveryPending, DeliverySent, DeliveryFailed, DeliveryDelivered, DeliveryBounced, DeliveryComplained:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidDeliveryFilter, f.Status)
+	}
+}
+
+// DeliveryPage is a page of deliveries in the order they were reserved.
+type DeliveryPage struct {
+	Deliveries []*Delivery `json:"deliveries"`
+	NextCursor string      `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// MaxReplyLength is the number of bytes of the text of a reply that are
+// kept; longer replies are truncated.
+const MaxReplyLength = 10000
+
+// Reply is an email a recipient sent in answer to a campaign email.
+type Reply struct {
+	ID         uuid.UUID `json:"id"`          // ID of the reply
+	CampaignID uuid.UUID `json:"campaign_id"` // Campaign whose email was answered
+	Email      string    `json:"email"`       // Sender of the reply
+	Subject    string    `json:"subject"`     // Subject of the reply
+	Text       string    `json:"text"`        // Text written by the sender, without the quoted email
+	MessageID  string    `json:"-"`           // Message-ID header of the reply, identifying redeliveries
+	ReceivedAt time.Time `json:"received_at"` // Time the reply was received
+}
+
+// ReplyPage is a page of the replies to a campaign, in the order they were
+// received.
+type ReplyPage struct {
+	Replies    []*Reply `json:"replies"`
+	NextCursor string   `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// ReferencedMessageIDs returns the delivery message IDs the message IDs
+// referenced by a reply may match. Providers record the Message-ID header
+// they set, such as "<id@example.com>", or only its left part, like the
+// MessageId of SES, whose header is "<MessageId@email.amazonses.com>".
+func ReferencedMessageIDs(references []string) []string {
+	ids := []string{}
+	for _, reference := range references {
+		local, _, found := strings.Cut(strings.Trim(reference, "<>"), "@")
+		if !found || local == "" {
+			continue
+		}
+		ids = append(ids, reference, local)
+	}
+	return ids
+}
+
+// ThrottleLimits caps how many campaigns of a newsletter are sent at once,
+// so that a large campaign cannot hold every worker while the campaigns of
+// other newsletters wait.
+type ThrottleLimits struct {
+	PerNewsletter int               `json:"per_newsletter"`      // Default cap of every newsletter
+	Overrides     map[uuid.UUID]int `json:"overrides,omitempty"` // Caps of single newsletters, by newsletter ID
+}
+
+// Validate checks the limits, returning an error wrapping ErrInvalidThrottle.
+func (l ThrottleLimits) Validate() error {
+	if l.PerNewsletter < 1 {
+		return fmt.Errorf("%w: per_newsletter must be at least 1", ErrInvalidThrottle)
+	}
+	for id, limit := range l.Overrides {
+		if limit < 1 {
+			return fmt.Errorf("%w: limit of newsletter %s must be at least 1", ErrInvalidThrottle, id)
+		}
+	}
+	return nil
+}
+
+// Limit returns the cap of a newsletter.
+func (l ThrottleLimits) Limit(newsletterID uuid.UUID) int {
+	if limit, ok := l.Overrides[newsletterID]; ok {
+		return limit
+	}
+	return l.PerNewsletter
+}
+
+// Throttle counts the campaigns being sent by newsletter and enforces
+// ThrottleLimits. It is safe for concurrent use.
+type Throttle interface {
+	// Acquire takes a sending slot of the newsletter, returning false if all
+	// its slots are taken. Every successful Acquire must be followed by a
+	// Release.
+	Acquire(newsletterID uuid.UUID) bool
+	// Release gives back a slot taken with Acquire.
+	Release(newsletterID uuid.UUID)
+	// Limits returns the current limits.
+	Limits() ThrottleLimits
+	// SetLimits replaces the limits. Campaigns above a lowered cap finish
+	// their current batch before it applies.
+	SetLimits(limits ThrottleLimits) error
+	// Running returns the number of campaigns being sent by newsletter.
+	Running() map[uuid.UUID]int
+}
+
+// CampaignService is an interface that contains a collection of method signatures
+// which will be implemented in application level and are responsible for
+// tracking the progress of campaigns.
+type CampaignService interface {
+	// Create queues a campaign sending a post with the given options.
+	Create(newsletterID, postID uuid.UUID, options SendOptions) (*Campaign, error)
+	// CheckLimits returns the error Create would return because a campaign
+	// of the newsletter exceeds the plan limits of its owner.
+	CheckLimits(newsletterID uuid.UUID) error
+	Get(id uuid.UUID) (*Campaign, error)
+	// Start moves a queued campaign to sending. Resuming a campaign that was
+	// interrupted while sending is allowed.
+	Start(id uuid.UUID) (*Campaign, error)
+	Pause(id uuid.UUID) (*Campaign, error)
+	// Resume queues a paused or failed campaign again.
+	Resume(id uuid.UUID) (*Campaign, error)
+	Complete(id uuid.UUID) (*Campaign, error)
+	Fail(id uuid.UUID, cause error) (*Campaign, error)
+	// EndSample moves a sending campaign whose A/B test sample was sent to
+	// testing, starting the test window.
+	EndSample(id uuid.UUID) (*Campaign, error)
+	// PickWinner chooses the winning subject of a testing campaign and moves
+	// it back to sending, for the remainder of the subscribers.
+	PickWinner(id uuid.UUID) (*Campaign, error)
+	// ScheduleBatch records when a sending campaign emails the subscribers
+	// waiting for their send window.
+	ScheduleBatch(id uuid.UUID, at time.Time) error
+	// Unfinished returns the campaigns that are queued, were sending or are
+	// testing, e.g. when the process stopped before they completed.
+	Unfinished() ([]*Campaign, error)
+	// Reserve records a pending delivery. It returns false if the campaign
+	// already has a delivery to the recipient, who must then be skipped.
+	Reserve(delivery *Delivery) (bool, error)
+	// Delivered reports whether the campaign already has a delivery to email.
+	Delivered(campaignID uuid.UUID, email string) (bool, error)
+	// Record stores the outcome of a reserved delivery; a nil sendErr marks
+	// it as sent with the message ID assigned by the provider.
+	Record(campaignID uuid.UUID, email, messageID string, sendErr error) error
+	// Deliveries lists the deliveries of a campaign matching filter.
+	Deliveries(campaignID uuid.UUID, filter DeliveryFilter, limit int, cursor string) (*DeliveryPage, error)
+	// Report calls fn with every delivery of a campaign, in the order of
+	// Deliveries, reading them in batches so that large campaigns are never
+	// held in memory. It stops at the first error returned by fn.
+	Report(campaignID uuid.UUID, fn func(*Delivery) error) error
+	// RecordEvent applies a delivery, bounce or complaint event reported by
+	// the provider for messageID. It returns ErrDeliveryNotFound if no
+	// campaign delivery has that message ID.
+	RecordEvent(messageID, status, detail string) error
+	// RecordOpen records the first open of the delivery with trackingID.
+	// Unknown tracking IDs are ignored.
+	RecordOpen(trackingID uuid.UUID) error
+	// RecordReply stores reply against the campaign of the delivery whose
+	// email it answers, found from the message IDs it references, and sets
+	// its ID and CampaignID. It returns false if the reply was already
+	// recorded, and ErrDeliveryNotFound if it answers no campaign email.
+	RecordReply(reply *Reply, references []string) (bool, error)
+	// Replies lists the replies to a campaign.
+	Replies(campaignID uuid.UUID, limit int, cursor string) (*ReplyPage, error)
+}
+
+// CampaignRepository is an interface that contains a collection of method signatures
+// which will be implemented in persistence level.
+type CampaignRepository interface {
+	Create(ctx context.Context, campaign *Campaign) (*Campaign, error)
+	Get(ctx context.Context, id uuid.UUID) (*Campaign, error)
+	// Transition changes the status of a campaign whose status is one of
+	// from. It returns ErrInvalidTransition otherwise.
+	Transition(ctx context.Context, id uuid.UUID, from []string, to, reason string) (*Campaign, error)
+	ListByStatus(ctx context.Context, statuses []string) ([]*Campaign, error)
+	// EndSample moves a sending campaign to testing, ending its A/B test
+	// window after the configured number of minutes from now.
+	EndSample(ctx context.Context, id uuid.UUID) (*Campaign, error)
+	// SetWinner stores the winning variant of a testing campaign and moves
+	// it to sending. It returns ErrInvalidTransition if it is not testing.
+	SetWinner(ctx context.Context, id uuid.UUID, winner string) (*Campaign, error)
+	// SetNextBatch sets the time of the next batch of a sending campaign.
+	// Status changes clear it.
+	SetNextBatch(ctx context.Context, id uuid.UUID, at time.Time) error
+	ReserveDelivery(ctx context.Context, delivery *Delivery) (bool, error)
+	HasDelivery(ctx context.Context, campaignID uuid.UUID, email string) (bool, error)
+	UpdateDelivery(ctx context.Context, campaignID uuid.UUID, email, status, messageID, reason string) error
+	// ListDeliveries returns up to limit deliveries of a campaign matching
+	// filter, ordered by creation time and email, starting after cursor.
+	ListDeliveries(ctx context.Context, campaignID uuid.UUID, filter DeliveryFilter, limit int, after *pagination.Cursor) ([]*Delivery, error)
+	// UpdateDeliveryByMessageID changes the status of the delivery with
+	// messageID if its status is one of from. It returns ErrDeliveryNotFound
+	// if no delivery has that message ID; a delivery in another status is
+	// left unchanged without error.
+	UpdateDeliveryByMessageID(ctx context.Context, messageID string, from []string, to, reason string) error
+	// RecordOpen sets the open time of the delivery with trackingID unless
+	// it was already opened.
+	RecordOpen(ctx context.Context, trackingID uuid.UUID) error
+	// FindDelivery returns a delivery whose message ID is one of
+	// messageIDs, or ErrDeliveryNotFound.
+	FindDelivery(ctx context.Context, messageIDs []string) (*Delivery, error)
+	// CreateReply stores a reply, setting its ID. It returns false, without
+	// error, if a reply with the same message ID exists.
+	CreateReply(ctx context.Context, reply *Reply) (bool, error)
+	// ListReplies returns up to limit replies to a campaign, ordered by
+	// reception time and ID, starting after cursor.
+	ListReplies(ctx context.Context, campaignID uuid.UUID, limit int, after *pagination.Cursor) ([]*Reply, error)
+}
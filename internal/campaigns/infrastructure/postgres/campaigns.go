@@ -0,0 +1,485 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/infrastructure/pagination"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+type CampaignRepository struct {
+	db database.DB
+}
+
+func NewCampaignRepository(db database.DB) *CampaignRepository {
+	return &CampaignRepository{db: db}
+}
+
+// campaignColumns lists the columns scanned by scanCampaign, in order. The
+// delivery counters are aggregated from campaign_deliveries; deliveries
+// updated by provider events still count as sent. The A/B test columns are
+// followed by the sent and opened counters of each variant, then by the send
+// window and the segment.
+const campaignColumns = `id, newsletter_id, post_id, status, error, created_at, started_at, completed_at, updated_at,
+	(select count(*) from campaign_deliveries d where d.campaign_id = campaigns.id and d.status in ('sent', 'delivered', 'bounced', 'complained')),
+	(select count(*) from campaign_deliveries d where d.campaign_id = campaigns.id and d.status = 'failed'),
+	(select count(*) from campaign_deliveries d where d.campaign_id = campaigns.id and d.status = 'pending'),
+	ab_subject_a, ab_subject_b, ab_sample_percent, ab_window_minutes, ab_ends_at, ab_winner,
+	(select count(*) from campaign_deliveries d where d.campaign_id = campaigns.id and d.variant = 'a' and d.status in ('sent', 'delivered', 'bounced', 'complained')),
+	(select count(*) from campaign_deliveries d where d.campaign_id = campaigns.id and d.variant = 'a' and d.opened_at is not null),
+	(select count(*) from campaign_deliveries d where d.campaign_id = campaigns.id and d.variant = 'b' and d.status in ('sent', 'delivered', 'bounced', 'complained')),
+	(select count(*) from campaign_deliveries d where d.campaign_id = campaigns.id and d.variant = 'b' and d.opened_at is not null),
+	send_window_start, send_window_end, send_window_timezone, next_batch_at, segment_id, envelope`
+
+// scanner is implemented by both pgx.Row and pgx.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanCampaign scans a row selected with campaignColumns into a domain.Campaign.
+// Campaigns without a sample percentage have no A/B test, and campaigns
+// without a window start no send window.
+func scanCampaign(row scanner) (*domain.Campaign, error) {
+	var campaign domain.Campaign
+	var test domain.ABTest
+	var window domain.SendWindow
+	var samplePercent *int
+	var envelope []byte
+
+	err := row.Scan(
+		&campaign.ID,
+		&campaign.NewsletterID,
+		&campaign.PostID,
+		&campaign.Status,
+		&campaign.Error,
+		&campaign.CreatedAt,
+		&campaign.StartedAt,
+		&campaign.CompletedAt,
+		&campaign.UpdatedAt,
+		&campaign.Sent,
+		&campaign.Failed,
+		&campaign.Pending,
+		&test.SubjectA,
+		&test.SubjectB,
+		&samplePercent,
+		&test.WindowMinutes,
+		&test.EndsAt,
+		&test.Winner,
+		&test.A.Sent,
+		&test.A.Opened,
+		&test.B.Sent,
+		&test.B.Opened,
+		&window.Start,
+		&window.End,
+		&window.Timezone,
+		&campaign.NextBatchAt,
+		&campaign.SegmentID,
+		&envelope,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if samplePercent != nil {
+		test.SamplePercent = *samplePercent
+		campaign.ABTest = &test
+	}
+	if window.Start != "" {
+		campaign.SendWindow = &window
+	}
+	if envelope != nil {
+		if err := json.Unmarshal(envelope, &campaign.Envelope); err != nil {
+			return nil, err
+		}
+	}
+
+	return &campaign, nil
+}
+
+// textArray converts a string slice into a Postgres TEXT[] parameter.
+func textArray(values []string) (pgtype.TextArray, error) {
+	var array pgtype.TextArray
+	err := array.Set(values)
+	return array, err
+}
+
+// Create inserts a new campaign with its A/B test settings, send window,
+// segment and envelope, if any.
+func (cr *CampaignRepository) Create(ctx context.Context, campaign *domain.Campaign) (*domain.Campaign, error) {
+	query := `insert into campaigns (newsletter_id, post_id, status, created_at, updated_at, ab_subject_a, ab_subject_b, ab_sample_percent, ab_window_minutes,
+			send_window_start, send_window_end, send_window_timezone, segment_id, envelope)
+		values ($1, $2, $3, $4, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) returning ` + campaignColumns
+
+	var subjectA, subjectB string
+	var samplePercent *int
+	var windowMinutes int
+	if test := campaign.ABTest; test != nil {
+		subjectA, subjectB, samplePercent, windowMinutes = test.SubjectA, test.SubjectB, &test.SamplePercent, test.WindowMinutes
+	}
+	var window domain.SendWindow
+	if campaign.SendWindow != nil {
+		window = *campaign.SendWindow
+	}
+	var envelope []byte
+	if campaign.Envelope != nil {
+		var err error
+		if envelope, err = json.Marshal(campaign.Envelope); err != nil {
+			return nil, err
+		}
+	}
+
+	return scanCampaign(cr.db.QueryRow(ctx, query, campaign.NewsletterID, campaign.PostID, campaign.Status, time.Now(),
+		subjectA, subjectB, samplePercent, windowMinutes, window.Start, window.End, window.Timezone, campaign.SegmentID, envelope))
+}
+
+// Get retrieves a campaign with its delivery counters.
+//
+// If no campaign exists with the given ID, Get returns domain.ErrCampaignNotFound.
+func (cr *CampaignRepository) Get(ctx context.Context, id uuid.UUID) (*domain.Campaign, error) {
+	query := `select ` + campaignColumns + ` from campaigns where id = $1`
+
+	campaign, err := scanCampaign(cr.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCampaignNotFound
+	}
+
+	return campaign, err
+}
+
+// Transition changes the status of a campaign currently in one of the from
+// statuses, recording the start and completion times and clearing the time
+// of the next batch. reason is stored as the campaign error.
+//
+// It returns domain.ErrInvalidTransition if the campaign is in another status
+// and domain.ErrCampaignNotFound if it does not exist.
+func (cr *CampaignRepository) Transition(ctx context.Context, id uuid.UUID, from []string, to, reason string) (*domain.Campaign, error) {
+	statuses, err := textArray(from)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `update campaigns
+		set status = $1::text,
+			error = $2,
+			updated_at = $3,
+			started_at = case when $1::text = 'sending' then coalesce(started_at, $3) else started_at end,
+			completed_at = case when $1::text = 'completed' then $3 else completed_at end,
+			next_batch_at = null
+		where id = $4 and status = any($5)
+		returning ` + campaignColumns
+
+	return cr.transitioned(ctx, id, cr.db.QueryRow(ctx, query, to, reason, time.Now(), id, statuses))
+}
+
+// EndSample moves a sending campaign to testing and sets the end of its A/B
+// test window.
+//
+// It returns domain.ErrInvalidTransition if the campaign is not sending and
+// domain.ErrCampaignNotFound if it does not exist.
+func (cr *CampaignRepository) EndSample(ctx context.Context, id uuid.UUID) (*domain.Campaign, error) {
+	query := `update campaigns
+		set status = 'testing',
+			updated_at = $1,
+			ab_ends_at = $1 + ab_window_minutes * interval '1 minute'
+		where id = $2 and status = 'sending' and ab_sample_percent is not null
+		returning ` + campaignColumns
+
+	return cr.transitioned(ctx, id, cr.db.QueryRow(ctx, query, time.Now(), id))
+}
+
+// SetWinner stores the winning variant of a testing campaign and moves it
+// back to sending.
+//
+// It returns domain.ErrInvalidTransition if the campaign is not testing and
+// domain.ErrCampaignNotFound if it does not exist.
+func (cr *CampaignRepository) SetWinner(ctx context.Context, id uuid.UUID, winner string) (*domain.Campaign, error) {
+	query := `update campaigns
+		set status = 'sending', ab_winner = $1, updated_at = $2
+		where id = $3 and status = 'testing'
+		returning ` + campaignColumns
+
+	return cr.transitioned(ctx, id, cr.db.QueryRow(ctx, query, winner, time.Now(), id))
+}
+
+// transitioned scans the campaign returned by a status change, telling a
+// missing campaign from one in another status when no row was updated.
+func (cr *CampaignRepository) transitioned(ctx context.Context, id uuid.UUID, row pgx.Row) (*domain.Campaign, error) {
+	campaign, err := scanCampaign(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := cr.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrInvalidTransition
+	}
+
+	return campaign, err
+}
+
+// SetNextBatch sets the time of the next batch of a sending campaign. Other
+// campaigns are left unchanged.
+func (cr *CampaignRepository) SetNextBatch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `update campaigns set next_batch_at = $1 where id = $2 and status = 'sending'`
+
+	_, err := cr.db.Exec(ctx, query, at, id)
+	return err
+}
+
+// ListByStatus retrieves the campaigns in any of the given statuses, oldest first.
+func (cr *CampaignRepository) ListByStatus(ctx context.Context, statuses []string) ([]*domain.Campaign, error) {
+	array, err := textArray(statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `select ` + campaignColumns + ` from campaigns where status = any($1) order by created_at`
+
+	rows, err := cr.db.Query(ctx, query, array)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := []*domain.Campaign{}
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+
+	return campaigns, rows.Err()
+}
+
+// ReserveDelivery records a pending delivery of a campaign to a recipient.
+// It returns false, without error, if a delivery to the recipient already
+// exists. A tracking ID is generated when the delivery has none.
+func (cr *CampaignRepository) ReserveDelivery(ctx context.Context, delivery *domain.Delivery) (bool, error) {
+	query := `insert into campaign_deliveries (campaign_id, email, status, variant, tracking_id, created_at, updated_at)
+		values ($1, $2, $3, $4, coalesce($5, gen_random_uuid()), $6, $6)
+		on conflict (campaign_id, email) do nothing`
+
+	var trackingID *uuid.UUID
+	if delivery.TrackingID != uuid.Nil {
+		trackingID = &delivery.TrackingID
+	}
+
+	result, err := cr.db.Exec(ctx, query, delivery.CampaignID, delivery.Email, domain.DeliveryPending, delivery.Variant, trackingID, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// HasDelivery reports whether a delivery of a campaign to email exists.
+func (cr *CampaignRepository) HasDelivery(ctx context.Context, campaignID uuid.UUID, email string) (bool, error) {
+	query := `select exists(select 1 from campaign_deliveries where campaign_id = $1 and email = $2)`
+
+	var exists bool
+	err := cr.db.QueryRow(ctx, query, campaignID, email).Scan(&exists)
+	return exists, err
+}
+
+// UpdateDelivery stores the outcome of a delivery. Sent deliveries record
+// the time and the message ID assigned by the provider.
+func (cr *CampaignRepository) UpdateDelivery(ctx context.Context, campaignID uuid.UUID, email, status, messageID, reason string) error {
+	query := `update campaign_deliveries
+		set status = $1::text,
+			message_id = nullif($2, ''),
+			error = $3,
+			updated_at = $4,
+			sent_at = case when $1::text = 'sent' then $4 else sent_at end
+		where campaign_id = $5 and email = $6`
+
+	_, err := cr.db.Exec(ctx, query, status, messageID, reason, time.Now(), campaignID, email)
+	return err
+}
+
+// deliveryColumns lists the columns scanned by scanDelivery, in order.
+const deliveryColumns = `campaign_id, email, status, variant, tracking_id, coalesce(message_id, ''), error, created_at, sent_at, opened_at, updated_at`
+
+// scanDelivery scans a row selected with deliveryColumns into a domain.Delivery.
+func scanDelivery(row scanner) (*domain.Delivery, error) {
+	var delivery domain.Delivery
+
+	err := row.Scan(
+		&delivery.CampaignID,
+		&delivery.Email,
+		&delivery.Status,
+		&delivery.Variant,
+		&delivery.TrackingID,
+		&delivery.MessageID,
+		&delivery.Error,
+		&delivery.CreatedAt,
+		&delivery.SentAt,
+		&delivery.OpenedAt,
+		&delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// ListDeliveries retrieves up to limit deliveries of a campaign matching
+// filter, ordered by creation time and email, starting after the cursor.
+func (cr *CampaignRepository) ListDeliveries(ctx context.Context, campaignID uuid.UUID, filter domain.DeliveryFilter, limit int, after *pagination.Cursor) ([]*domain.Delivery, error) {
+	query := `select ` + deliveryColumns + ` from campaign_deliveries where campaign_id = $1`
+	args := []any{campaignID}
+
+	if filter.Email != "" {
+		args = append(args, filter.Email)
+		query += fmt.Sprintf(" and email = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" and status = $%d", len(args))
+	}
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		query += fmt.Sprintf(" and (created_at, email) > ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(" order by created_at, email limit $%d", len(args))
+
+	rows, err := cr.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*domain.Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// UpdateDeliveryByMessageID changes the status of the delivery with
+// messageID when its status is one of from.
+//
+// It returns domain.ErrDeliveryNotFound if no delivery has that message ID.
+func (cr *CampaignRepository) UpdateDeliveryByMessageID(ctx context.Context, messageID string, from []string, to, reason string) error {
+	statuses, err := textArray(from)
+	if err != nil {
+		return err
+	}
+
+	query := `update campaign_deliveries set status = $1, error = $2, updated_at = $3 where message_id = $4 and status = any($5)`
+
+	result, err := cr.db.Exec(ctx, query, to, reason, time.Now(), messageID, statuses)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := cr.db.QueryRow(ctx, `select exists(select 1 from campaign_deliveries where message_id = $1)`, messageID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// RecordOpen sets the open time of the delivery with trackingID, keeping the
+// time of the first open.
+func (cr *CampaignRepository) RecordOpen(ctx context.Context, trackingID uuid.UUID) error {
+	query := `update campaign_deliveries set opened_at = $1 where tracking_id = $2 and opened_at is null`
+
+	_, err := cr.db.Exec(ctx, query, time.Now(), trackingID)
+	return err
+}
+
+// FindDelivery returns a delivery whose message ID is one of messageIDs.
+//
+// It returns domain.ErrDeliveryNotFound if there is none.
+func (cr *CampaignRepository) FindDelivery(ctx context.Context, messageIDs []string) (*domain.Delivery, error) {
+	ids, err := textArray(messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `select ` + deliveryColumns + ` from campaign_deliveries where message_id = any($1) limit 1`
+
+	delivery, err := scanDelivery(cr.db.QueryRow(ctx, query, ids))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrDeliveryNotFound
+	}
+	return delivery, err
+}
+
+// CreateReply inserts a reply to a campaign and sets its ID. Replies are
+// unique by message ID, so that a reply delivered twice is stored once: it
+// returns false, without error, for the second one.
+func (cr *CampaignRepository) CreateReply(ctx context.Context, reply *domain.Reply) (bool, error) {
+	query := `insert into campaign_replies (campaign_id, email, subject, text, message_id, received_at)
+		values ($1, $2, $3, $4, nullif($5, ''), $6)
+		on conflict (message_id) where message_id is not null do nothing
+		returning id`
+
+	err := cr.db.QueryRow(ctx, query, reply.CampaignID, reply.Email, reply.Subject, reply.Text, reply.MessageID, reply.ReceivedAt).Scan(&reply.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListReplies retrieves up to limit replies to a campaign, ordered by
+// reception time and ID, starting after the cursor.
+func (cr *CampaignRepository) ListReplies(ctx context.Context, campaignID uuid.UUID, limit int, after *pagination.Cursor) ([]*domain.Reply, error) {
+	query := `select id, campaign_id, email, subject, text, coalesce(message_id, ''), received_at from campaign_replies where campaign_id = $1`
+	args := []any{campaignID}
+
+	if after != nil {
+		afterID, err := uuid.Parse(after.ID)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+		args = append(args, after.CreatedAt, afterID)
+		query += " and (received_at, id) > ($2, $3)"
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(" order by received_at, id limit $%d", len(args))
+
+	rows, err := cr.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replies := []*domain.Reply{}
+	for rows.Next() {
+		var reply domain.Reply
+		if err := rows.Scan(&reply.ID, &reply.CampaignID, &reply.Email, &reply.Subject, &reply.Text, &reply.MessageID, &reply.ReceivedAt); err != nil {
+			return nil, err
+		}
+		replies = append(replies, &reply)
+	}
+
+	return replies, rows.Err()
+}
@@ -0,0 +1,71 @@
+// Package errors classifies the errors of every module into a few kinds,
+// so that the transport layer can translate them without knowing each
+// module's errors.
+//
+// Modules declare their sentinel errors with New and a kind:
+//
+//	ErrPostNotFound = apperrors.New(apperrors.NotFound, "post not found")
+//
+// Callers still match a specific error with errors.Is(err, ErrPostNotFound),
+// or its kind with errors.Is(err, apperrors.NotFound). Errors without a kind,
+// such as database failures, are of kind Internal.
+package errors
+
+import (
+	"errors"
+)
+
+// Kinds of errors. They are only meant to be matched with errors.Is.
+var (
+	NotFound     = errors.New("not found")    // The resource does not exist, or is not visible to the caller
+	Conflict     = errors.New("conflict")     // The request conflicts with the current state of a resource
+	Unauthorized = errors.New("unauthorized") // Credentials or tokens are missing, invalid or expired
+	Validation   = errors.New("validation")   // The input is malformed or breaks a rule
+	Internal     = errors.New("internal")     // Any other failure
+)
+
+// Error is a sentinel error of a module, classified by its kind.
+type Error struct {
+	kind    error
+	message string
+}
+
+// New returns a sentinel error of the given kind, one of the kinds of this
+// package.
+func New(kind error, message string) *Error {
+	return &Error{kind: kind, message: message}
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+// Is reports whether target is the kind of e, so that errors.Is matches
+// kinds through any wrapping.
+func (e *Error) Is(target error) bool {
+	return target == e.kind
+}
+
+// Kind returns the kind of e.
+func (e *Error) Kind() error {
+	return e.kind
+}
+
+// As returns the first sentinel error in the chain of err, and false when
+// there is none.
+func As(err error) (*Error, bool) {
+	var known *Error
+	if errors.As(err, &known) {
+		return known, true
+	}
+	return nil, false
+}
+
+// KindOf returns the kind of err: the kind of the first sentinel error in
+// its chain, or Internal when there is none.
+func KindOf(err error) error {
+	if known, ok := As(err); ok {
+		return known.kind
+	}
+	return Internal
+}
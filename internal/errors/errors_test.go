@@ -0,0 +1,31 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_MatchesItsKind(t *testing.T) {
+	errNotFound := New(NotFound, "thing not found")
+	wrapped := fmt.Errorf("get thing: %w", errNotFound)
+
+	assert.Equal(t, "thing not found", errNotFound.Error())
+	assert.ErrorIs(t, wrapped, errNotFound)
+	assert.ErrorIs(t, wrapped, NotFound)
+	assert.NotErrorIs(t, wrapped, Conflict)
+	assert.Equal(t, NotFound, KindOf(wrapped))
+
+	known, ok := As(wrapped)
+	assert.True(t, ok)
+	assert.Same(t, errNotFound, known)
+}
+
+func TestKindOf_Unclassified(t *testing.T) {
+	assert.Equal(t, Internal, KindOf(errors.New("connection refused")))
+
+	_, ok := As(errors.New("connection refused"))
+	assert.False(t, ok)
+}
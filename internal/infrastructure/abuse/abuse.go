@@ -0,0 +1,156 @@
+package abuse
+
+import (
+	"sync"
+	"time"
+)
+
+// Limits configures when a client is blocked: after Threshold failures
+// within Window, its requests are refused for Block.
+type Limits struct {
+	Threshold int           // failures tolerated within Window; 0 disables blocking
+	Window    time.Duration // period failures are counted over
+	Block     time.Duration // time a client is blocked for
+}
+
+// Stats are the counters of a Detector, cumulative since it was created
+// except for Blocked.
+type Stats struct {
+	Failures int64 // failures recorded
+	Blocks   int64 // clients blocked
+	Refused  int64 // requests refused because their client was blocked
+	Blocked  int   // clients currently blocked
+}
+
+// Detector counts the failures of clients, such as requests with invalid
+// unsubscribe tokens, and blocks the clients that fail too often in a short
+// time. Clients are identified by keys chosen by the caller, such as
+// "ip:203.0.113.7" or "user:<id>". State is kept in memory, per instance.
+type Detector struct {
+	limits Limits
+
+	mu        sync.Mutex
+	failures  map[string][]time.Time // recent failures of each client, oldest first
+	blocked   map[string]time.Time   // end of the block of each blocked client
+	lastPurge time.Time
+	stats     Stats
+}
+
+func NewDetector(limits Limits) *Detector {
+	return &Detector{
+		limits:   limits,
+		failures: make(map[string][]time.Time),
+		blocked:  make(map[string]time.Time),
+	}
+}
+
+// Limits returns the limits of the detector.
+func (d *Detector) Limits() Limits {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.limits
+}
+
+// SetLimits replaces the limits of the detector, such as when the
+// configuration is reloaded. Clients already blocked stay blocked until
+// their block ends.
+func (d *Detector) SetLimits(limits Limits) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.limits = limits
+}
+
+// Blocked reports whether any of keys is blocked at now, and for how long
+// the longest block lasts. A blocked request is counted as refused.
+func (d *Detector) Blocked(now time.Time, keys ...string) (time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.purge(now)
+
+	var remaining time.Duration
+	for _, key := range keys {
+		if until, ok := d.blocked[key]; ok && until.Sub(now) > remaining {
+			remaining = until.Sub(now)
+		}
+	}
+	if remaining <= 0 {
+		return 0, false
+	}
+	d.stats.Refused++
+	return remaining, true
+}
+
+// Fail records a failure of each of keys at now, and returns the keys it
+// blocked.
+func (d *Detector) Fail(now time.Time, keys ...string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.purge(now)
+
+	var blocked []string
+	for _, key := range keys {
+		d.stats.Failures++
+		if d.limits.Threshold == 0 {
+			continue
+		}
+
+		failures := append(recent(d.failures[key], now.Add(-d.limits.Window)), now)
+		if len(failures) < d.limits.Threshold {
+			d.failures[key] = failures
+			continue
+		}
+
+		delete(d.failures, key)
+		if _, ok := d.blocked[key]; !ok {
+			d.stats.Blocks++
+			blocked = append(blocked, key)
+		}
+		d.blocked[key] = now.Add(d.limits.Block)
+	}
+	return blocked
+}
+
+// Stats returns the counters of the detector.
+func (d *Detector) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := d.stats
+	stats.Blocked = len(d.blocked)
+	return stats
+}
+
+// purge forgets expired blocks and failures older than the window, at most
+// once per window so that busy instances do not scan every client on every
+// request.
+func (d *Detector) purge(now time.Time) {
+	if now.Sub(d.lastPurge) < d.limits.Window {
+		return
+	}
+	d.lastPurge = now
+
+	for key, until := range d.blocked {
+		if !until.After(now) {
+			delete(d.blocked, key)
+		}
+	}
+	for key, failures := range d.failures {
+		if failures = recent(failures, now.Add(-d.limits.Window)); len(failures) == 0 {
+			delete(d.failures, key)
+		} else {
+			d.failures[key] = failures
+		}
+	}
+}
+
+// recent returns the failures after since.
+func recent(failures []time.Time, since time.Time) []time.Time {
+	for i, at := range failures {
+		if at.After(since) {
+			return failures[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package abuse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetector_BlocksAfterBurst(t *testing.T) {
+	d := NewDetector(Limits{Threshold: 3, Window: time.Minute, Block: 10 * time.Minute})
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	assert.Empty(t, d.Fail(now, "ip:203.0.113.7"))
+	assert.Empty(t, d.Fail(now.Add(time.Second), "ip:203.0.113.7"))
+	_, blocked := d.Blocked(now.Add(2*time.Second), "ip:203.0.113.7")
+	assert.False(t, blocked)
+
+	assert.Equal(t, []string{"ip:203.0.113.7"}, d.Fail(now.Add(2*time.Second), "ip:203.0.113.7"))
+
+	remaining, blocked := d.Blocked(now.Add(3*time.Second), "ip:198.51.100.1", "ip:203.0.113.7")
+	assert.True(t, blocked)
+	assert.Equal(t, 10*time.Minute-time.Second, remaining)
+
+	_, blocked = d.Blocked(now.Add(11*time.Minute), "ip:203.0.113.7")
+	assert.False(t, blocked)
+
+	assert.Equal(t, Stats{Failures: 3, Blocks: 1, Refused: 1, Blocked: 0}, d.Stats())
+}
+
+func TestDetector_ForgetsOldFailures(t *testing.T) {
+	d := NewDetector(Limits{Threshold: 2, Window: time.Minute, Block: time.Minute})
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	d.Fail(now, "ip:203.0.113.7")
+	assert.Empty(t, d.Fail(now.Add(2*time.Minute), "ip:203.0.113.7"))
+
+	_, blocked := d.Blocked(now.Add(2*time.Minute), "ip:203.0.113.7")
+	assert.False(t, blocked)
+}
+
+func TestDetector_Disabled(t *testing.T) {
+	d := NewDetector(Limits{Window: time.Minute, Block: time.Minute})
+	now := time.Now()
+
+	for range 100 {
+		assert.Empty(t, d.Fail(now, "ip:203.0.113.7"))
+	}
+	_, blocked := d.Blocked(now, "ip:203.0.113.7")
+	assert.False(t, blocked)
+	assert.Equal(t, int64(100), d.Stats().Failures)
+}
@@ -0,0 +1,247 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/infrastructure/workerpool"
+	notifications "newsletter/internal/notifications/domain"
+	"sync"
+	"time"
+)
+
+// Alert names, used as cooldown keys.
+const (
+	AlertQueueDepth  = "queue_depth"
+	AlertQueueLag    = "queue_lag"
+	AlertFailureRate = "failure_rate"
+	AlertOverflow    = "queue_overflow"
+)
+
+// Alert describes a threshold breach detected by the Monitor.
+type Alert struct {
+	Name    string    // identifier of the checked condition
+	Message string    // human readable description
+	At      time.Time // time the breach was detected
+}
+
+// Notifier delivers alerts to operators.
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+// StatsSource provides worker pool statistics. It is implemented by
+// *workerpool.WorkerPool.
+type StatsSource interface {
+	Stats() workerpool.Stats
+}
+
+// Thresholds configures when alerts fire. A zero value disables the check.
+type Thresholds struct {
+	QueueDepth  int           // alert when more jobs than this are waiting
+	QueueLag    time.Duration // alert when a job waited longer than this before starting
+	FailureRate float64       // alert when the share of failed jobs in an interval exceeds this (0-1)
+}
+
+// Monitor periodically samples the worker pool and notifies operators when
+// a threshold is crossed. Each alert is sent at most once per cooldown
+// period to prevent alert storms.
+type Monitor struct {
+	source     StatsSource
+	thresholds Thresholds
+	notifiers  []Notifier
+	interval   time.Duration
+	cooldown   time.Duration
+
+	mu        sync.Mutex
+	previous  workerpool.Stats
+	lastFired map[string]time.Time
+}
+
+func NewMonitor(source StatsSource, thresholds Thresholds, notifiers []Notifier, interval, cooldown time.Duration) *Monitor {
+	return &Monitor{
+		source:     source,
+		thresholds: thresholds,
+		notifiers:  notifiers,
+		interval:   interval,
+		cooldown:   cooldown,
+		previous:   source.Stats(),
+		lastFired:  make(map[string]time.Time),
+	}
+}
+
+// Run evaluates the thresholds every interval until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.Check(now)
+		}
+	}
+}
+
+// Check samples the worker pool once and sends an alert for every breached
+// threshold that is not in its cooldown period. Failure rates are computed
+// over the jobs processed since the previous check. Jobs rejected or dropped
+// because the queue was full always raise an alert.
+func (m *Monitor) Check(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.source.Stats()
+	previous := m.previous
+	m.previous = stats
+
+	if m.thresholds.QueueDepth > 0 && stats.QueueDepth > m.thresholds.QueueDepth {
+		m.fire(Alert{
+			Name:    AlertQueueDepth,
+			Message: fmt.Sprintf("worker queue depth is %d/%d (threshold %d)", stats.QueueDepth, stats.Capacity, m.thresholds.QueueDepth),
+			At:      now,
+		})
+	}
+
+	if m.thresholds.QueueLag > 0 && stats.LastWait > m.thresholds.QueueLag {
+		m.fire(Alert{
+			Name:    AlertQueueLag,
+			Message: fmt.Sprintf("jobs wait %s in the queue before starting (threshold %s)", stats.LastWait.Round(time.Millisecond), m.thresholds.QueueLag),
+			At:      now,
+		})
+	}
+
+	if lost := (stats.Rejected - previous.Rejected) + (stats.Dropped - previous.Dropped); lost > 0 {
+		m.fire(Alert{
+			Name:    AlertOverflow,
+			Message: fmt.Sprintf("worker queue is saturated: %d jobs rejected and %d dropped in the last interval (capacity %d)", stats.Rejected-previous.Rejected, stats.Dropped-previous.Dropped, stats.Capacity),
+			At:      now,
+		})
+	}
+
+	processed := stats.Processed - previous.Processed
+	failed := stats.Failed - previous.Failed
+	if m.thresholds.FailureRate > 0 && processed > 0 {
+		rate := float64(failed) / float64(processed)
+		if rate > m.thresholds.FailureRate {
+			m.fire(Alert{
+				Name:    AlertFailureRate,
+				Message: fmt.Sprintf("%d of %d jobs failed in the last interval (%.0f%%, threshold %.0f%%)", failed, processed, rate*100, m.thresholds.FailureRate*100),
+				At:      now,
+			})
+		}
+	}
+}
+
+// fire notifies all notifiers unless the alert is still cooling down.
+func (m *Monitor) fire(alert Alert) {
+	if last, ok := m.lastFired[alert.Name]; ok && alert.At.Sub(last) < m.cooldown {
+		slog.Debug("alert suppressed by cooldown", "alert", alert.Name)
+		return
+	}
+	m.lastFired[alert.Name] = alert.At
+
+	slog.Warn("alert fired", "alert", alert.Name, "message", alert.Message)
+
+	for _, notifier := range m.notifiers {
+		if err := notifier.Notify(alert); err != nil {
+			slog.Error("failed to deliver alert", "alert", alert.Name, "error", err)
+		}
+	}
+}
+
+// EmailNotifier sends alerts to a list of admin email addresses.
+//
+// Emails are sent directly through the email service rather than through
+// the worker pool, since a saturated pool is one of the conditions being
+// reported.
+type EmailNotifier struct {
+	es         notifications.EmailService
+	recipients []string
+}
+
+func NewEmailNotifier(es notifications.EmailService, recipients []string) *EmailNotifier {
+	return &EmailNotifier{es: es, recipients: recipients}
+}
+
+// Notify emails the alert to every recipient.
+func (n *EmailNotifier) Notify(alert Alert) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var errs []error
+	for _, recipient := range n.recipients {
+		err := n.es.Send(ctx, &notifications.Email{
+			To:      recipient,
+			Subject: "[newsletter] Alert: " + alert.Name,
+			Text:    alert.Message + "\n\nDetected at " + alert.At.UTC().Format(time.RFC3339),
+			HTML:    "<p>" + alert.Message + "</p><p>Detected at " + alert.At.UTC().Format(time.RFC3339) + "</p>",
+		})
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// WebhookNotifier posts alerts to an HTTP endpoint using a Slack-compatible
+// payload ({"text": "..."}), which also works with Slack incoming webhooks.
+type WebhookNotifier struct {
+	client *http.Client
+	url    string
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{client: &http.Client{Timeout: 5 * time.Second}, url: url}
+}
+
+// Notify posts the alert to the webhook.
+func (n *WebhookNotifier) Notify(alert Alert) error {
+	payload, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf(":rotating_light: *%s*: %s", alert.Name, alert.Message),
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Config configures a Monitor: where alerts are sent, and when.
+type Config struct {
+	Emails     []string // Admin email addresses notified
+	WebhookURL string   // Slack-compatible webhook notified
+	Thresholds Thresholds
+	Interval   time.Duration // How often the thresholds are evaluated
+	Cooldown   time.Duration // Minimum time between two identical alerts
+}
+
+// NewMonitorFromConfig creates the Monitor configured by cfg, sending emails
+// through es. It returns nil when no alert destination is configured.
+func NewMonitorFromConfig(source StatsSource, es notifications.EmailService, cfg Config) *Monitor {
+	var notifiers []Notifier
+	if len(cfg.Emails) > 0 {
+		notifiers = append(notifiers, NewEmailNotifier(es, cfg.Emails))
+	}
+	if cfg.WebhookURL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(cfg.WebhookURL))
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+
+	return NewMonitor(source, cfg.Thresholds, notifiers, cfg.Interval, cfg.Cooldown)
+}
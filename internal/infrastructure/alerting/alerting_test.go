@@ -0,0 +1,119 @@
+package alerting
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/infrastructure/workerpool"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Fakes ---
+
+type fakeSource struct {
+	stats workerpool.Stats
+}
+
+func (f *fakeSource) Stats() workerpool.Stats {
+	return f.stats
+}
+
+type recordingNotifier struct {
+	alerts []Alert
+}
+
+func (r *recordingNotifier) Notify(alert Alert) error {
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+// --- Tests ---
+
+func TestMonitor_QueueDepthAlertRespectsCooldown(t *testing.T) {
+	source := &fakeSource{}
+	notifier := &recordingNotifier{}
+	m := NewMonitor(source, Thresholds{QueueDepth: 10}, []Notifier{notifier}, time.Minute, 15*time.Minute)
+
+	now := time.Now()
+	source.stats = workerpool.Stats{QueueDepth: 50, Capacity: 100}
+
+	m.Check(now)
+	m.Check(now.Add(time.Minute))
+	m.Check(now.Add(16 * time.Minute))
+
+	assert.Len(t, notifier.alerts, 2)
+	assert.Equal(t, AlertQueueDepth, notifier.alerts[0].Name)
+}
+
+func TestMonitor_NoAlertBelowThresholds(t *testing.T) {
+	source := &fakeSource{}
+	notifier := &recordingNotifier{}
+	m := NewMonitor(source, Thresholds{QueueDepth: 10, QueueLag: time.Second, FailureRate: 0.5}, []Notifier{notifier}, time.Minute, time.Minute)
+
+	source.stats = workerpool.Stats{QueueDepth: 5, LastWait: 100 * time.Millisecond, Processed: 10, Failed: 1}
+	m.Check(time.Now())
+
+	assert.Empty(t, notifier.alerts)
+}
+
+func TestMonitor_OverflowAlert(t *testing.T) {
+	source := &fakeSource{stats: workerpool.Stats{Rejected: 3}}
+	notifier := &recordingNotifier{}
+	m := NewMonitor(source, Thresholds{}, []Notifier{notifier}, time.Minute, time.Minute)
+
+	m.Check(time.Now())
+	assert.Empty(t, notifier.alerts)
+
+	source.stats = workerpool.Stats{Rejected: 5, Dropped: 1, Capacity: 100}
+	m.Check(time.Now())
+	assert.Len(t, notifier.alerts, 1)
+	assert.Equal(t, AlertOverflow, notifier.alerts[0].Name)
+	assert.Contains(t, notifier.alerts[0].Message, "2 jobs rejected and 1 dropped")
+}
+
+func TestMonitor_FailureRateUsesIntervalDelta(t *testing.T) {
+	source := &fakeSource{stats: workerpool.Stats{Processed: 100, Failed: 90}}
+	notifier := &recordingNotifier{}
+	m := NewMonitor(source, Thresholds{FailureRate: 0.5}, []Notifier{notifier}, time.Minute, time.Minute)
+
+	// Historic failures before the monitor started are ignored
+	source.stats = workerpool.Stats{Processed: 110, Failed: 91}
+	m.Check(time.Now())
+	assert.Empty(t, notifier.alerts)
+
+	source.stats = workerpool.Stats{Processed: 120, Failed: 99}
+	m.Check(time.Now().Add(2 * time.Minute))
+	assert.Len(t, notifier.alerts, 1)
+	assert.Equal(t, AlertFailureRate, notifier.alerts[0].Name)
+}
+
+func TestMonitor_QueueLagAlert(t *testing.T) {
+	source := &fakeSource{}
+	notifier := &recordingNotifier{}
+	m := NewMonitor(source, Thresholds{QueueLag: time.Second}, []Notifier{notifier}, time.Minute, time.Minute)
+
+	source.stats = workerpool.Stats{LastWait: 5 * time.Second}
+	m.Check(time.Now())
+
+	assert.Len(t, notifier.alerts, 1)
+	assert.Equal(t, AlertQueueLag, notifier.alerts[0].Name)
+}
+
+func TestWebhookNotifier_PostsSlackPayload(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		body = string(payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL).Notify(Alert{Name: AlertQueueDepth, Message: "queue is full"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, body, `"text"`)
+	assert.Contains(t, body, "queue is full")
+}
@@ -0,0 +1,206 @@
+package artifacts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	apperrors "newsletter/internal/errors"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when an artifact does not exist.
+	ErrNotFound = apperrors.New(apperrors.NotFound, "artifact not found")
+	// ErrInvalidLink is returned when a download link is malformed or its signature does not match.
+	ErrInvalidLink = apperrors.New(apperrors.Unauthorized, "invalid download link")
+	// ErrLinkExpired is returned when a download link is used after its expiry.
+	ErrLinkExpired = apperrors.New(apperrors.NotFound, "download link expired")
+	// ErrLinkUsed is returned when a single-use download link is used again.
+	ErrLinkUsed = apperrors.New(apperrors.NotFound, "download link already used")
+)
+
+// namePattern matches the names generated by Create, so that names taken
+// from URLs can never escape the store directory or bucket prefix.
+var namePattern = regexp.MustCompile(`^[a-z]+-[0-9a-f]{32}\.[a-z]+$`)
+
+// Object is the content of a stored artifact. It must be closed by the caller.
+type Object struct {
+	io.ReadCloser
+	Size    int64
+	ModTime time.Time
+}
+
+// Backend persists the content of artifacts. Names are always generated by
+// Store.Create and validated before they reach a backend.
+type Backend interface {
+	Put(ctx context.Context, name string, content io.ReadSeeker) error
+	// Open returns ErrNotFound if no artifact is stored under name.
+	Open(ctx context.Context, name string) (*Object, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// Store keeps generated files, such as exports, in a Backend and hands them
+// out through expiring signed links.
+type Store struct {
+	backend Backend
+	secret  []byte
+	ttl     time.Duration
+
+	// claimed holds the names of artifacts whose single-use link has been
+	// used by this process, so that concurrent downloads cannot both succeed.
+	claimed sync.Map
+}
+
+func NewStore(backend Backend, secret string, ttl time.Duration) *Store {
+	return &Store{backend: backend, secret: []byte(secret), ttl: ttl}
+}
+
+// Backends of a Store.
+const (
+	BackendDisk = "disk"
+	BackendS3   = "s3"
+)
+
+// Config configures a Store and its backend.
+type Config struct {
+	SecretKey string        // Key signing download links
+	LinkTTL   time.Duration // Validity of download links
+	Backend   string        // BackendDisk or BackendS3
+	Dir       string        // Directory of the disk backend
+	S3Bucket  string        // Bucket of the s3 backend
+	S3Prefix  string        // Key prefix of the s3 backend
+}
+
+// NewStoreFromConfig creates the Store configured by cfg. It returns nil
+// when cfg has no secret key, which disables features that produce
+// downloadable files.
+func NewStoreFromConfig(cfg Config) (*Store, error) {
+	if cfg.SecretKey == "" {
+		return nil, nil
+	}
+
+	var backend Backend
+	var err error
+	switch cfg.Backend {
+	case BackendDisk:
+		backend, err = NewDiskBackend(cfg.Dir)
+	case BackendS3:
+		backend, err = NewS3BackendFromAWS(cfg.S3Bucket, cfg.S3Prefix)
+	default:
+		err = fmt.Errorf("unknown artifacts backend %q", cfg.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStore(backend, cfg.SecretKey, cfg.LinkTTL), nil
+}
+
+// Create stores the content written by write under a new random name made
+// of kind and ext, e.g. "export-<random>.zip", and returns that name.
+//
+// The content is staged in a temporary file so that backends receive a
+// seekable reader of known size.
+func (s *Store) Create(ctx context.Context, kind, ext string, write func(io.Writer) error) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	name := kind + "-" + hex.EncodeToString(random) + "." + ext
+
+	staging, err := os.CreateTemp("", "artifact-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(staging.Name())
+	defer staging.Close()
+
+	if err := write(staging); err != nil {
+		return "", err
+	}
+	if _, err := staging.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	if err := s.backend.Put(ctx, name, staging); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Open returns the content of a stored artifact.
+func (s *Store) Open(ctx context.Context, name string) (*Object, error) {
+	if !namePattern.MatchString(name) {
+		return nil, ErrNotFound
+	}
+	return s.backend.Open(ctx, name)
+}
+
+// Consume claims the artifact behind a single-use link and deletes it. It
+// returns ErrLinkUsed if the artifact was already claimed. The caller must
+// have opened the artifact before consuming it.
+func (s *Store) Consume(ctx context.Context, name string) error {
+	if _, claimed := s.claimed.LoadOrStore(name, struct{}{}); claimed {
+		return ErrLinkUsed
+	}
+	return s.backend.Delete(ctx, name)
+}
+
+// Sign returns the query string ("expires=...&signature=...") of a link to
+// name that is valid until the link TTL has elapsed from now. Single-use
+// links also carry "once=1" and stop working after their first download.
+func (s *Store) Sign(name string, now time.Time, singleUse bool) url.Values {
+	expires := now.Add(s.ttl).Unix()
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expires, 10)},
+		"signature": {base64.RawURLEncoding.EncodeToString(s.mac(name, expires, singleUse))},
+	}
+	if singleUse {
+		query.Set("once", "1")
+	}
+	return query
+}
+
+// Verify checks the query string of a link to name produced by Sign and
+// reports whether the link is single-use.
+func (s *Store) Verify(name string, query url.Values, now time.Time) (bool, error) {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return false, ErrInvalidLink
+	}
+	singleUse := query.Get("once") == "1"
+
+	signature, err := base64.RawURLEncoding.DecodeString(query.Get("signature"))
+	if err != nil || !hmac.Equal(signature, s.mac(name, expires, singleUse)) {
+		return false, ErrInvalidLink
+	}
+
+	if now.Unix() > expires {
+		return false, ErrLinkExpired
+	}
+	return singleUse, nil
+}
+
+// TTL returns how long signed links stay valid.
+func (s *Store) TTL() time.Duration {
+	return s.ttl
+}
+
+func (s *Store) mac(name string, expires int64, singleUse bool) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("artifact:" + name + ":" + strconv.FormatInt(expires, 10)))
+	if singleUse {
+		mac.Write([]byte(":once"))
+	}
+	return mac.Sum(nil)
+}
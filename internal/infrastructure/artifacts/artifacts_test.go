@@ -0,0 +1,144 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	backend, err := NewDiskBackend(t.TempDir())
+	require.NoError(t, err)
+	return NewStore(backend, "secret", time.Hour)
+}
+
+func createArtifact(t *testing.T, store *Store, content string) string {
+	t.Helper()
+
+	name, err := store.Create(context.Background(), "export", "zip", func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	})
+	require.NoError(t, err)
+	return name
+}
+
+func TestStore_CreateAndOpen(t *testing.T) {
+	store := newTestStore(t)
+
+	name := createArtifact(t, store, "content")
+	assert.True(t, strings.HasPrefix(name, "export-"))
+
+	object, err := store.Open(context.Background(), name)
+	require.NoError(t, err)
+	defer object.Close()
+
+	content, _ := io.ReadAll(object)
+	assert.Equal(t, "content", string(content))
+	assert.Equal(t, int64(len("content")), object.Size)
+}
+
+func TestStore_OpenRejectsForeignNames(t *testing.T) {
+	store := newTestStore(t)
+
+	_, err := store.Open(context.Background(), "../../etc/passwd")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_SignedLinks(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Now()
+	name := "export-0123456789abcdef0123456789abcdef.zip"
+
+	query := store.Sign(name, now, false)
+
+	singleUse, err := store.Verify(name, query, now)
+	assert.NoError(t, err)
+	assert.False(t, singleUse)
+
+	_, err = store.Verify("export-ffffffffffffffffffffffffffffffff.zip", query, now)
+	assert.ErrorIs(t, err, ErrInvalidLink)
+	_, err = store.Verify(name, query, now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, ErrLinkExpired)
+
+	query.Set("expires", "9999999999")
+	_, err = store.Verify(name, query, now)
+	assert.ErrorIs(t, err, ErrInvalidLink)
+}
+
+func TestStore_SingleUseLinks(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Now()
+	name := createArtifact(t, store, "content")
+
+	query := store.Sign(name, now, true)
+	singleUse, err := store.Verify(name, query, now)
+	require.NoError(t, err)
+	assert.True(t, singleUse)
+
+	// The flag is covered by the signature
+	query.Del("once")
+	_, err = store.Verify(name, query, now)
+	assert.ErrorIs(t, err, ErrInvalidLink)
+
+	require.NoError(t, store.Consume(context.Background(), name))
+	assert.ErrorIs(t, store.Consume(context.Background(), name), ErrLinkUsed)
+
+	_, err = store.Open(context.Background(), name)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// fakeS3 keeps objects in memory.
+type fakeS3 struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	content, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[aws.ToString(params.Key)] = content
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) GetObject(_ context.Context, params *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	content, ok := f.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(content)), ContentLength: aws.Int64(int64(len(content)))}, nil
+}
+
+func (f *fakeS3) DeleteObject(_ context.Context, params *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	delete(f.objects, aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestS3Backend(t *testing.T) {
+	client := &fakeS3{objects: map[string][]byte{}}
+	store := NewStore(NewS3Backend(client, "bucket", "artifacts"), "secret", time.Hour)
+
+	name := createArtifact(t, store, "content")
+	assert.Contains(t, client.objects, "artifacts/"+name)
+
+	object, err := store.Open(context.Background(), name)
+	require.NoError(t, err)
+	content, _ := io.ReadAll(object)
+	assert.Equal(t, "content", string(content))
+
+	require.NoError(t, store.Consume(context.Background(), name))
+	_, err = store.Open(context.Background(), name)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
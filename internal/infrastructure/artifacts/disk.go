@@ -0,0 +1,68 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// DiskBackend stores artifacts as files of a local directory.
+type DiskBackend struct {
+	dir string
+}
+
+func NewDiskBackend(dir string) (*DiskBackend, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &DiskBackend{dir: dir}, nil
+}
+
+// Put writes content to a new file named name.
+func (db *DiskBackend) Put(_ context.Context, name string, content io.ReadSeeker) error {
+	file, err := os.OpenFile(filepath.Join(db.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// Open opens the file named name.
+func (db *DiskBackend) Open(_ context.Context, name string) (*Object, error) {
+	file, err := os.Open(filepath.Join(db.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Object{ReadCloser: file, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// Delete removes the file named name. Deleting a missing file is not an error.
+func (db *DiskBackend) Delete(_ context.Context, name string) error {
+	err := os.Remove(filepath.Join(db.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
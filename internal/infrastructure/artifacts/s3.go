@@ -0,0 +1,88 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"io"
+	awsclient "newsletter/internal/infrastructure/aws"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3API is the subset of the S3 client used by S3Backend.
+type s3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// S3Backend stores artifacts as objects of an S3 bucket, under an optional
+// key prefix. Expired objects can be removed with a bucket lifecycle rule on
+// that prefix.
+type S3Backend struct {
+	client s3API
+	bucket string
+	prefix string
+}
+
+func NewS3Backend(client s3API, bucket, prefix string) *S3Backend {
+	return &S3Backend{client: client, bucket: bucket, prefix: prefix}
+}
+
+// NewS3BackendFromAWS creates an S3Backend storing artifacts in bucket
+// under prefix, using the default AWS credentials.
+func NewS3BackendFromAWS(bucket, prefix string) (*S3Backend, error) {
+	if bucket == "" {
+		return nil, errors.New("no bucket for the s3 artifacts backend")
+	}
+
+	client, err := awsclient.InitS3Client()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewS3Backend(client, bucket, prefix), nil
+}
+
+func (sb *S3Backend) key(name string) string {
+	return path.Join(sb.prefix, name)
+}
+
+// Put uploads content as the object name.
+func (sb *S3Backend) Put(ctx context.Context, name string, content io.ReadSeeker) error {
+	_, err := sb.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(sb.bucket),
+		Key:    aws.String(sb.key(name)),
+		Body:   content,
+	})
+	return err
+}
+
+// Open downloads the object name.
+func (sb *S3Backend) Open(ctx context.Context, name string) (*Object, error) {
+	out, err := sb.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(sb.bucket),
+		Key:    aws.String(sb.key(name)),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Object{ReadCloser: out.Body, Size: aws.ToInt64(out.ContentLength), ModTime: aws.ToTime(out.LastModified)}, nil
+}
+
+// Delete removes the object name.
+func (sb *S3Backend) Delete(ctx context.Context, name string) error {
+	_, err := sb.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(sb.bucket),
+		Key:    aws.String(sb.key(name)),
+	})
+	return err
+}
@@ -0,0 +1,30 @@
+package aws
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// InitS3Client initializes and returns an AWS S3 client.
+//
+// The configuration is loaded the same way as for InitSESClient, from
+// environment variables or default credentials.
+func InitS3Client() (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		slog.Error(
+			"failed to load AWS SDK config",
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(cfg)
+
+	slog.Info("AWS S3 client initialized successfully")
+
+	return client, nil
+}
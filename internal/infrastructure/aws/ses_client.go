@@ -8,21 +8,26 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ses"
 )
 
-// InitSESClient initializes and returns an AWS SES client.
+// InitSESClient initializes and returns an AWS SES client for region.
 //
 // This function loads the AWS configuration from environment variables or
 // default credentials. It should be called once at application startup.
+// When region is empty, the region of the AWS configuration is used.
 //
 // Environment variables used:
 //   - AWS_ACCESS_KEY_ID
 //   - AWS_SECRET_ACCESS_KEY
-//   - AWS_REGION
 //
 // Returns:
 //   - A pointer to a fully initialized SES client (*ses.Client).
 //   - Panics if the AWS configuration cannot be loaded (for production, consider returning error instead).
-func InitSESClient() (*ses.Client, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+func InitSESClient(region string) (*ses.Client, error) {
+	var options []func(*config.LoadOptions) error
+	if region != "" {
+		options = append(options, config.WithRegion(region))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(), options...)
 	if err != nil {
 		slog.Error(
 			"failed to load AWS SDK config",
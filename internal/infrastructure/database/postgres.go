@@ -1,36 +1,76 @@
 package database
 
 import (
-	"database/sql"
+	"context"
 	"log"
-	"newsletter/config"
 	"time"
 
-	_ "github.com/jackc/pgconn"
-	_ "github.com/jackc/pgx/v4"
-	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
-// ConnectWithRetry establishes a PostgreSQL connection using the pgx driver.
+// DB is the part of *pgxpool.Pool used by the repositories. pgx.Tx
+// implements it too, so that repositories can run in a transaction.
 //
-// The function reads the DSN from configuration (DSN env variable) and attempts
+// Statements are prepared on first use and kept in a per-connection cache
+// of 512 statements. Behind a transaction-mode pooler such as PgBouncer, add
+// statement_cache_mode=describe to the DSN.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults
+}
+
+// PoolConfig sizes the connection pool.
+type PoolConfig struct {
+	MaxConns        int32         // Maximum number of open connections
+	MinConns        int32         // Connections kept open even when idle
+	ConnMaxLifetime time.Duration // Connections are closed after this age
+	ConnMaxIdleTime time.Duration // Idle connections are closed after this time
+}
+
+// Apply sizes the pool configured by poolConfig.
+func (cfg PoolConfig) Apply(poolConfig *pgxpool.Config) {
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.ConnMaxLifetime
+	poolConfig.MaxConnIdleTime = cfg.ConnMaxIdleTime
+}
+
+// InitPostgres establishes a PostgreSQL connection pool using pgx.
+//
+// The function connects to the database identified by dsn and attempts
 // to connect multiple times with a fixed backoff. This is useful in containerized
 // or distributed environments where the database may not be immediately available.
 //
-// On successful connection, a ready-to-use *sql.DB is returned.
-// If the database cannot be reached after all retries, the application
-// terminates with a fatal log message.
+// The connection pool is sized with pool, and statements slower than
+// slowQueryThreshold are logged (see LogSlowQueries).
+//
+// On successful connection, a ready-to-use *pgxpool.Pool is returned.
+// If the configuration is invalid or the database cannot be reached after
+// all retries, the application terminates with a fatal log message.
 //
 // This function belongs to the infrastructure layer and should only be called
 // from the application's root.
-func InitPostgres() *sql.DB {
-	dsn := config.GetEnv("DSN", "")
+func InitPostgres(dsn string, pool PoolConfig, slowQueryThreshold time.Duration) *pgxpool.Pool {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		log.Fatalf("Invalid database configuration: invalid DSN: %v", err)
+	}
+
+	pool.Apply(poolConfig)
+	LogSlowQueries(poolConfig.ConnConfig, slowQueryThreshold)
 
 	for i := 0; i < 10; i++ {
-		db, err := sql.Open("pgx", dsn)
-		if err == nil && db.Ping() == nil {
-			log.Println("Connected to Postgres")
-			return db
+		db, err := pgxpool.ConnectConfig(context.Background(), poolConfig)
+		if err == nil {
+			if err = db.Ping(context.Background()); err == nil {
+				log.Println("Connected to Postgres")
+				return db
+			}
+			db.Close()
 		}
 
 		log.Println("Postgres not ready, retrying...")
@@ -40,3 +80,32 @@ func InitPostgres() *sql.DB {
 	log.Fatal("Could not connect to Postgres")
 	return nil
 }
+
+// Stats is a snapshot of the statistics of a connection pool.
+type Stats struct {
+	MaxConns          int32         // Maximum size of the pool
+	TotalConns        int32         // Open connections, in use, idle or being established
+	AcquiredConns     int32         // Connections in use
+	IdleConns         int32         // Idle connections
+	AcquireCount      int64         // Connections acquired since the pool was created
+	EmptyAcquireCount int64         // Acquisitions that had to wait for a connection
+	AcquireDuration   time.Duration // Total time spent acquiring connections
+	LifetimeClosed    int64         // Connections closed for reaching their maximum lifetime
+	IdleClosed        int64         // Connections closed for being idle too long
+}
+
+// PoolStats returns the statistics of pool.
+func PoolStats(pool *pgxpool.Pool) Stats {
+	stat := pool.Stat()
+	return Stats{
+		MaxConns:          stat.MaxConns(),
+		TotalConns:        stat.TotalConns(),
+		AcquiredConns:     stat.AcquiredConns(),
+		IdleConns:         stat.IdleConns(),
+		AcquireCount:      stat.AcquireCount(),
+		EmptyAcquireCount: stat.EmptyAcquireCount(),
+		AcquireDuration:   stat.AcquireDuration(),
+		LifetimeClosed:    stat.MaxLifetimeDestroyCount(),
+		IdleClosed:        stat.MaxIdleDestroyCount(),
+	}
+}
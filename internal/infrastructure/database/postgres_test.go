@@ -0,0 +1,36 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowQueryLogger(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	var connConfig pgx.ConnConfig
+	LogSlowQueries(&connConfig, 100*time.Millisecond)
+	require.NotNil(t, connConfig.Logger)
+
+	connConfig.Logger.Log(context.Background(), pgx.LogLevelInfo, "Query", map[string]any{
+		"sql": "select 1", "args": []any{"reader@example.com"}, "time": 10 * time.Millisecond,
+	})
+	assert.Empty(t, logs.String())
+
+	connConfig.Logger.Log(context.Background(), pgx.LogLevelInfo, "Query", map[string]any{
+		"sql": "select pg_sleep(1)", "args": []any{"reader@example.com"}, "time": time.Second,
+	})
+	assert.Contains(t, logs.String(), "slow database query")
+	assert.Contains(t, logs.String(), "select pg_sleep(1)")
+	assert.NotContains(t, logs.String(), "reader@example.com")
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// maxLoggedQuery is the length beyond which logged statements are truncated.
+const maxLoggedQuery = 1000
+
+// LogSlowQueries makes the connections of connConfig log the statements that
+// take longer than threshold, with their duration. Arguments are not logged,
+// since they may hold personal data. A zero threshold logs nothing.
+//
+// Queries are timed until their rows are closed, so the time the caller
+// spends reading them is included.
+func LogSlowQueries(connConfig *pgx.ConnConfig, threshold time.Duration) {
+	if threshold <= 0 {
+		return
+	}
+	connConfig.Logger = slowQueryLogger{threshold: threshold}
+	connConfig.LogLevel = pgx.LogLevelInfo
+}
+
+// slowQueryLogger is a pgx.Logger keeping the statements slower than
+// threshold. pgx reports the duration of every statement in its "time" field.
+type slowQueryLogger struct {
+	threshold time.Duration
+}
+
+// Log logs the statement described by data if it is slow.
+func (l slowQueryLogger) Log(_ context.Context, _ pgx.LogLevel, msg string, data map[string]any) {
+	elapsed, ok := data["time"].(time.Duration)
+	if !ok || elapsed < l.threshold {
+		return
+	}
+
+	query, _ := data["sql"].(string)
+	if len(query) > maxLoggedQuery {
+		query = query[:maxLoggedQuery] + "..."
+	}
+	args, _ := data["args"].([]any)
+
+	slog.Warn(
+		"slow database query",
+		"operation", msg,
+		"duration", elapsed,
+		"threshold", l.threshold,
+		"query", query,
+		"args", len(args),
+		"error", data["err"],
+	)
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Unavailable returns a DB whose every statement fails with err. It stands
+// in for PostgreSQL when the API runs without a database, so that the
+// features needing one report err instead of panicking.
+func Unavailable(err error) DB {
+	return unavailable{err: err}
+}
+
+type unavailable struct {
+	err error
+}
+
+func (u unavailable) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return nil, u.err
+}
+
+func (u unavailable) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return nil, u.err
+}
+
+func (u unavailable) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return unavailableRow(u)
+}
+
+func (u unavailable) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	return unavailableBatch(u)
+}
+
+// unavailableRow is the pgx.Row of an unavailable DB.
+type unavailableRow unavailable
+
+func (r unavailableRow) Scan(dest ...any) error {
+	return r.err
+}
+
+// unavailableBatch is the pgx.BatchResults of an unavailable DB.
+type unavailableBatch unavailable
+
+func (b unavailableBatch) Exec() (pgconn.CommandTag, error) {
+	return nil, b.err
+}
+
+func (b unavailableBatch) Query() (pgx.Rows, error) {
+	return nil, b.err
+}
+
+func (b unavailableBatch) QueryRow() pgx.Row {
+	return unavailableRow(b)
+}
+
+func (b unavailableBatch) QueryFunc(scans []any, f func(pgx.QueryFuncRow) error) (pgconn.CommandTag, error) {
+	return nil, b.err
+}
+
+func (b unavailableBatch) Close() error {
+	return nil
+}
@@ -0,0 +1,161 @@
+package errorlog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Entry is an error logged by the application.
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// Recorder is a slog.Handler that keeps the most recent records of level
+// Error or above in memory, so that operators can review them without access
+// to the logs. Every record is passed on to the wrapped handler.
+//
+// Usage:
+//
+//	recorder := errorlog.NewRecorder(slog.NewTextHandler(os.Stderr, nil), 100)
+//	slog.SetDefault(slog.New(recorder))
+//
+// The wrapped handler must not be the handler of the default logger, which
+// writes through the log package and would deadlock once slog.SetDefault
+// redirects the log package to the recorder.
+type Recorder struct {
+	next   slog.Handler
+	attrs  []slog.Attr
+	group  string
+	buffer *ring
+}
+
+// NewRecorder returns a Recorder keeping the last capacity errors logged
+// through it and passing every record on to next.
+func NewRecorder(next slog.Handler, capacity int) *Recorder {
+	return &Recorder{next: next, buffer: &ring{entries: make([]Entry, capacity)}}
+}
+
+// Enabled reports whether the wrapped handler handles records of level.
+// Errors are always enabled.
+func (r *Recorder) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelError || r.next.Enabled(ctx, level)
+}
+
+// Handle records errors and passes the record on to the wrapped handler.
+func (r *Recorder) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelError {
+		entry := Entry{Time: record.Time, Message: record.Message, Attrs: map[string]string{}}
+		for _, attr := range r.attrs {
+			addAttr(entry.Attrs, "", attr)
+		}
+		record.Attrs(func(attr slog.Attr) bool {
+			addAttr(entry.Attrs, r.group, attr)
+			return true
+		})
+		r.buffer.add(entry)
+	}
+
+	if !r.next.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return r.next.Handle(ctx, record)
+}
+
+// WithAttrs returns a Recorder sharing the errors of r whose records carry attrs.
+func (r *Recorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefixed := make([]slog.Attr, 0, len(r.attrs)+len(attrs))
+	prefixed = append(prefixed, r.attrs...)
+	for _, attr := range attrs {
+		if r.group != "" {
+			attr.Key = r.group + "." + attr.Key
+		}
+		prefixed = append(prefixed, attr)
+	}
+	return &Recorder{next: r.next.WithAttrs(attrs), attrs: prefixed, group: r.group, buffer: r.buffer}
+}
+
+// WithGroup returns a Recorder sharing the errors of r whose attributes are
+// qualified by name.
+func (r *Recorder) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return r
+	}
+	group := name
+	if r.group != "" {
+		group = r.group + "." + name
+	}
+	return &Recorder{next: r.next.WithGroup(name), attrs: r.attrs, group: group, buffer: r.buffer}
+}
+
+// Recent returns up to limit of the recorded errors, newest first.
+func (r *Recorder) Recent(limit int) []Entry {
+	return r.buffer.recent(limit)
+}
+
+// addAttr stores attr in attrs under its key qualified by group. Groups are
+// flattened into dotted keys.
+func addAttr(attrs map[string]string, group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	key := attr.Key
+	if group != "" && key != "" {
+		key = group + "." + key
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, member := range attr.Value.Group() {
+			addAttr(attrs, key, member)
+		}
+		return
+	}
+	if key != "" {
+		attrs[key] = attr.Value.String()
+	}
+}
+
+// ring is a fixed-size circular buffer of entries.
+type ring struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int  // index of the next entry to overwrite
+	full    bool // whether every slot was written at least once
+}
+
+// add stores entry, overwriting the oldest one when the buffer is full.
+func (b *ring) add(entry Entry) {
+	if len(b.entries) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// recent returns up to limit entries, newest first.
+func (b *ring) recent(limit int) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	size := b.next
+	if b.full {
+		size = len(b.entries)
+	}
+	if limit <= 0 || limit > size {
+		limit = size
+	}
+
+	entries := make([]Entry, 0, limit)
+	for i := 1; i <= limit; i++ {
+		entries = append(entries, b.entries[(b.next-i+len(b.entries))%len(b.entries)])
+	}
+	return entries
+}
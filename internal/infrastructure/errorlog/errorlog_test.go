@@ -0,0 +1,44 @@
+package errorlog
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder_KeepsMostRecentErrors(t *testing.T) {
+	var output bytes.Buffer
+	recorder := NewRecorder(slog.NewTextHandler(&output, nil), 2)
+	logger := slog.New(recorder)
+
+	logger.Info("started")
+	logger.Error("first", "n", 1)
+	logger.With("component", "campaigns").WithGroup("job").Error("second", "id", "a")
+	logger.Error("third")
+
+	entries := recorder.Recent(10)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "third", entries[0].Message)
+		assert.Equal(t, "second", entries[1].Message)
+		assert.Equal(t, map[string]string{"component": "campaigns", "job.id": "a"}, entries[1].Attrs)
+	}
+	assert.Contains(t, output.String(), "msg=started")
+	assert.Contains(t, output.String(), "msg=first")
+}
+
+func TestRecorder_RecentLimit(t *testing.T) {
+	recorder := NewRecorder(slog.NewTextHandler(&bytes.Buffer{}, nil), 10)
+	logger := slog.New(recorder)
+
+	assert.Empty(t, recorder.Recent(5))
+
+	logger.Error("first")
+	logger.Error("second")
+
+	entries := recorder.Recent(1)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "second", entries[0].Message)
+	}
+}
@@ -0,0 +1,286 @@
+package firebase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	admin "cloud.google.com/go/firestore/apiv1/admin"
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/iterator"
+)
+
+// DeployIndexesCommand deploys the indexes declared in the manifest.
+const DeployIndexesCommand = "firebase deploy --only firestore:indexes"
+
+// IndexField is a field of a composite index. Fields are either ordered
+// (Order is ASCENDING or DESCENDING) or arrays (ArrayConfig is CONTAINS).
+type IndexField struct {
+	FieldPath   string `json:"fieldPath"`
+	Order       string `json:"order,omitempty"`
+	ArrayConfig string `json:"arrayConfig,omitempty"`
+}
+
+// Index is a composite index.
+type Index struct {
+	CollectionGroup string       `json:"collectionGroup"`
+	QueryScope      string       `json:"queryScope"`
+	Fields          []IndexField `json:"fields"`
+}
+
+// String describes the index, e.g. "subscriptions (newsletterId ASCENDING,
+// createdAt DESCENDING)". The document name, which Firestore appends to
+// every index, is left out.
+func (i Index) String() string {
+	fields := make([]string, 0, len(i.Fields))
+	for _, field := range i.fields() {
+		fields = append(fields, field.FieldPath+" "+field.Order+field.ArrayConfig)
+	}
+	return i.CollectionGroup + " (" + strings.Join(fields, ", ") + ")"
+}
+
+// fields returns the fields of the index without the trailing document name.
+func (i Index) fields() []IndexField {
+	if n := len(i.Fields); n > 0 && i.Fields[n-1].FieldPath == "__name__" {
+		return i.Fields[:n-1]
+	}
+	return i.Fields
+}
+
+// matches reports whether the index serves the same queries as other.
+func (i Index) matches(other Index) bool {
+	mine, theirs := i.fields(), other.fields()
+	if i.CollectionGroup != other.CollectionGroup || i.QueryScope != other.QueryScope || len(mine) != len(theirs) {
+		return false
+	}
+	for n := range mine {
+		if mine[n] != theirs[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// FieldIndex is a single-field index.
+type FieldIndex struct {
+	Order       string `json:"order,omitempty"`
+	ArrayConfig string `json:"arrayConfig,omitempty"`
+	QueryScope  string `json:"queryScope"`
+}
+
+// FieldOverride declares the single-field indexes of a field, replacing the
+// ones Firestore creates automatically.
+type FieldOverride struct {
+	CollectionGroup string       `json:"collectionGroup"`
+	FieldPath       string       `json:"fieldPath"`
+	Indexes         []FieldIndex `json:"indexes"`
+}
+
+// IndexManifest is the index definition file deployed with the Firebase
+// CLI, firestore.indexes.json.
+type IndexManifest struct {
+	Indexes        []Index         `json:"indexes"`
+	FieldOverrides []FieldOverride `json:"fieldOverrides"`
+}
+
+// LoadIndexManifest reads the index definition file at path.
+func LoadIndexManifest(path string) (*IndexManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest IndexManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// IndexReader reads the indexes of a Firestore database that are ready to
+// serve queries.
+type IndexReader interface {
+	// CompositeIndexes returns the ready composite indexes of a collection group.
+	CompositeIndexes(ctx context.Context, collectionGroup string) ([]Index, error)
+	// FieldIndexes returns the ready single-field indexes of a field.
+	FieldIndexes(ctx context.Context, collectionGroup, fieldPath string) ([]FieldIndex, error)
+}
+
+// MissingIndexes returns the indexes of manifest that reader does not find,
+// described as in Index.String. Indexes still being built are missing too,
+// as queries needing them fail until they are ready.
+func MissingIndexes(ctx context.Context, reader IndexReader, manifest *IndexManifest) ([]string, error) {
+	var missing []string
+
+	existing := map[string][]Index{} // By collection group
+	for _, required := range manifest.Indexes {
+		indexes, ok := existing[required.CollectionGroup]
+		if !ok {
+			var err error
+			if indexes, err = reader.CompositeIndexes(ctx, required.CollectionGroup); err != nil {
+				return nil, fmt.Errorf("list indexes of %s: %w", required.CollectionGroup, err)
+			}
+			existing[required.CollectionGroup] = indexes
+		}
+
+		found := false
+		for _, index := range indexes {
+			if required.matches(index) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, required.String())
+		}
+	}
+
+	for _, override := range manifest.FieldOverrides {
+		indexes, err := reader.FieldIndexes(ctx, override.CollectionGroup, override.FieldPath)
+		if err != nil {
+			return nil, fmt.Errorf("get indexes of %s.%s: %w", override.CollectionGroup, override.FieldPath, err)
+		}
+		for _, required := range override.Indexes {
+			found := false
+			for _, index := range indexes {
+				if index == required {
+					found = true
+					break
+				}
+			}
+			if !found {
+				missing = append(missing, fmt.Sprintf("%s (%s %s%s, single field)", override.CollectionGroup, override.FieldPath, required.Order, required.ArrayConfig))
+			}
+		}
+	}
+
+	return missing, nil
+}
+
+// automaticFieldIndexes are the single-field indexes Firestore maintains for
+// every field without an override.
+var automaticFieldIndexes = []FieldIndex{
+	{Order: "ASCENDING", QueryScope: "COLLECTION"},
+	{Order: "DESCENDING", QueryScope: "COLLECTION"},
+	{ArrayConfig: "CONTAINS", QueryScope: "COLLECTION"},
+}
+
+// AdminIndexReader implements IndexReader with the Firestore Admin API. The
+// credentials need the datastore.indexes.list permission, granted for
+// example by the Cloud Datastore Index Admin or Viewer roles.
+type AdminIndexReader struct {
+	client   *admin.FirestoreAdminClient
+	database string // projects/{project}/databases/(default)
+}
+
+// NewAdminIndexReader connects to the Firestore Admin API with Application
+// Default Credentials, like InitFirestore. The project is read from
+// GOOGLE_CLOUD_PROJECT, or else from the credentials. The caller must Close
+// the reader.
+func NewAdminIndexReader(ctx context.Context) (*AdminIndexReader, error) {
+	project := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if project == "" {
+		credentials, err := google.FindDefaultCredentials(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if project = credentials.ProjectID; project == "" {
+			return nil, errors.New("no Google Cloud project: set GOOGLE_CLOUD_PROJECT")
+		}
+	}
+
+	client, err := admin.NewFirestoreAdminClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &AdminIndexReader{client: client, database: "projects/" + project + "/databases/(default)"}, nil
+}
+
+func (r *AdminIndexReader) Close() error {
+	return r.client.Close()
+}
+
+func (r *AdminIndexReader) CompositeIndexes(ctx context.Context, collectionGroup string) ([]Index, error) {
+	it := r.client.ListIndexes(ctx, &adminpb.ListIndexesRequest{Parent: r.database + "/collectionGroups/" + collectionGroup})
+
+	var indexes []Index
+	for {
+		index, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return indexes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if index.GetState() != adminpb.Index_READY {
+			continue
+		}
+
+		converted := Index{CollectionGroup: collectionGroup, QueryScope: index.GetQueryScope().String()}
+		for _, field := range index.GetFields() {
+			converted.Fields = append(converted.Fields, indexField(field))
+		}
+		indexes = append(indexes, converted)
+	}
+}
+
+func (r *AdminIndexReader) FieldIndexes(ctx context.Context, collectionGroup, fieldPath string) ([]FieldIndex, error) {
+	field, err := r.client.GetField(ctx, &adminpb.GetFieldRequest{Name: r.database + "/collectionGroups/" + collectionGroup + "/fields/" + fieldPath})
+	if err != nil {
+		return nil, err
+	}
+	if field.GetIndexConfig().GetUsesAncestorConfig() {
+		return automaticFieldIndexes, nil
+	}
+
+	var indexes []FieldIndex
+	for _, index := range field.GetIndexConfig().GetIndexes() {
+		if index.GetState() != adminpb.Index_READY || len(index.GetFields()) != 1 {
+			continue
+		}
+		converted := indexField(index.GetFields()[0])
+		indexes = append(indexes, FieldIndex{Order: converted.Order, ArrayConfig: converted.ArrayConfig, QueryScope: index.GetQueryScope().String()})
+	}
+	return indexes, nil
+}
+
+// indexField converts a field of an index returned by the Admin API.
+func indexField(field *adminpb.Index_IndexField) IndexField {
+	converted := IndexField{FieldPath: field.GetFieldPath()}
+	if order := field.GetOrder(); order != adminpb.Index_IndexField_ORDER_UNSPECIFIED {
+		converted.Order = order.String()
+	}
+	if config := field.GetArrayConfig(); config != adminpb.Index_IndexField_ARRAY_CONFIG_UNSPECIFIED {
+		converted.ArrayConfig = config.String()
+	}
+	return converted
+}
+
+// VerifyIndexes checks that the indexes declared in the manifest at path
+// are deployed and ready. It returns the number of indexes checked, or an
+// error that lists the missing ones and how to deploy them.
+func VerifyIndexes(ctx context.Context, path string) (int, error) {
+	manifest, err := LoadIndexManifest(path)
+	if err != nil {
+		return 0, fmt.Errorf("load index manifest (set FIRESTORE_INDEXES_FILE): %w", err)
+	}
+
+	reader, err := NewAdminIndexReader(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("connect to the Firestore Admin API (check GOOGLE_APPLICATION_CREDENTIALS): %w", err)
+	}
+	defer reader.Close()
+
+	missing, err := MissingIndexes(ctx, reader, manifest)
+	if err != nil {
+		return 0, fmt.Errorf("%w (the credentials need the datastore.indexes.list permission)", err)
+	}
+	if len(missing) > 0 {
+		return 0, fmt.Errorf("%d Firestore indexes of %s missing or still building, deploy them with %q: %s", len(missing), path, DeployIndexesCommand, strings.Join(missing, "; "))
+	}
+	return len(manifest.Indexes) + len(manifest.FieldOverrides), nil
+}
@@ -0,0 +1,88 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIndexReader struct {
+	composite map[string][]Index
+	fields    map[string][]FieldIndex
+	err       error
+}
+
+func (f fakeIndexReader) CompositeIndexes(ctx context.Context, collectionGroup string) ([]Index, error) {
+	return f.composite[collectionGroup], f.err
+}
+
+func (f fakeIndexReader) FieldIndexes(ctx context.Context, collectionGroup, fieldPath string) ([]FieldIndex, error) {
+	return f.fields[collectionGroup+"."+fieldPath], f.err
+}
+
+func TestLoadIndexManifest_RepositoryManifest(t *testing.T) {
+	manifest, err := LoadIndexManifest(filepath.Join("..", "..", "..", "firestore.indexes.json"))
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, manifest.Indexes)
+	assert.Contains(t, manifest.FieldOverrides, FieldOverride{
+		CollectionGroup: "subscriptions",
+		FieldPath:       "unsubscribeToken",
+		Indexes:         []FieldIndex{{Order: "ASCENDING", QueryScope: "COLLECTION"}},
+	})
+}
+
+func TestLoadIndexManifest_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "firestore.indexes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"indexes": [`), 0o600))
+
+	_, err := LoadIndexManifest(path)
+	assert.ErrorContains(t, err, "parse "+path)
+}
+
+func TestMissingIndexes(t *testing.T) {
+	byDate := Index{CollectionGroup: "subscriptions", QueryScope: "COLLECTION", Fields: []IndexField{
+		{FieldPath: "newsletterId", Order: "ASCENDING"},
+		{FieldPath: "createdAt", Order: "DESCENDING"},
+		{FieldPath: "__name__", Order: "DESCENDING"},
+	}}
+	byTag := Index{CollectionGroup: "subscriptions", QueryScope: "COLLECTION", Fields: []IndexField{
+		{FieldPath: "newsletterId", Order: "ASCENDING"},
+		{FieldPath: "tags", ArrayConfig: "CONTAINS"},
+	}}
+	manifest := &IndexManifest{
+		Indexes: []Index{byDate, byTag},
+		FieldOverrides: []FieldOverride{{
+			CollectionGroup: "subscriptions",
+			FieldPath:       "unsubscribeToken",
+			Indexes:         []FieldIndex{{Order: "ASCENDING", QueryScope: "COLLECTION"}},
+		}},
+	}
+
+	// Firestore lists the document name even when the manifest leaves it out.
+	deployed := byDate
+	deployed.Fields = deployed.Fields[:2]
+	reader := fakeIndexReader{
+		composite: map[string][]Index{"subscriptions": {deployed}},
+		fields:    map[string][]FieldIndex{"subscriptions.unsubscribeToken": automaticFieldIndexes},
+	}
+
+	missing, err := MissingIndexes(context.Background(), reader, manifest)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"subscriptions (newsletterId ASCENDING, tags CONTAINS)"}, missing)
+
+	reader.fields = map[string][]FieldIndex{"subscriptions.unsubscribeToken": {{Order: "DESCENDING", QueryScope: "COLLECTION"}}}
+	missing, err = MissingIndexes(context.Background(), reader, manifest)
+
+	require.NoError(t, err)
+	assert.Contains(t, missing, "subscriptions (unsubscribeToken ASCENDING, single field)")
+
+	_, err = MissingIndexes(context.Background(), fakeIndexReader{err: errors.New("permission denied")}, manifest)
+	assert.ErrorContains(t, err, "list indexes of subscriptions: permission denied")
+}
@@ -0,0 +1,74 @@
+// Package fixtures builds deterministic domain objects for tests.
+//
+// The n-th fixture of a kind always has the same ID, email, name and
+// timestamps, so that expectations can be written against literal values
+// and failures are reproducible. IDs are name-based UUIDs, distinct across
+// kinds.
+package fixtures
+
+import (
+	"fmt"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	postdomain "newsletter/internal/posts/domain"
+	userdomain "newsletter/internal/users/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Epoch is the creation time of the first fixture of every kind; the n-th
+// fixture is created n minutes later.
+var Epoch = time.Date(2026, time.January, 10, 12, 0, 0, 0, time.UTC)
+
+// namespace scopes the IDs of fixtures.
+var namespace = uuid.MustParse("5d1f0c3e-8a4b-4e69-9a47-0f6c2b7d9e15")
+
+// ID returns the ID of the n-th fixture of kind, such as "user".
+func ID(kind string, n int) uuid.UUID {
+	return uuid.NewSHA1(namespace, []byte(fmt.Sprintf("%s/%d", kind, n)))
+}
+
+// createdAt returns the creation time of the n-th fixture.
+func createdAt(n int) time.Time {
+	return Epoch.Add(time.Duration(n) * time.Minute)
+}
+
+// User returns the n-th user, user<n>@example.com, with a placeholder
+// password hash.
+func User(n int) *userdomain.User {
+	return &userdomain.User{
+		ID:        ID("user", n),
+		Password:  "$2a$10$fixturefixturefixturefixturefixturefixturefixtureabc",
+		Email:     fmt.Sprintf("user%d@example.com", n),
+		Role:      userdomain.RoleUser,
+		CreatedAt: createdAt(n),
+	}
+}
+
+// Newsletter returns the n-th newsletter of owner, named "Newsletter <n>"
+// with the slug "newsletter-<n>".
+func Newsletter(owner *userdomain.User, n int) *newsletterdomain.Newsletter {
+	return &newsletterdomain.Newsletter{
+		ID:          ID("newsletter", n),
+		OwnerID:     owner.ID,
+		Name:        fmt.Sprintf("Newsletter %d", n),
+		Slug:        fmt.Sprintf("newsletter-%d", n),
+		Description: fmt.Sprintf("Description of newsletter %d", n),
+		Settings:    newsletterdomain.Settings{AllowedOrigins: []string{}},
+		CreatedAt:   createdAt(n),
+	}
+}
+
+// Post returns the n-th post of newsletter, a draft titled "Post <n>".
+func Post(newsletter *newsletterdomain.Newsletter, n int) *postdomain.Post {
+	return &postdomain.Post{
+		ID:           ID("post", n),
+		NewsletterID: newsletter.ID,
+		Title:        fmt.Sprintf("Post %d", n),
+		Body:         fmt.Sprintf("<p>Body of post %d</p>", n),
+		Status:       postdomain.StatusDraft,
+		Version:      1,
+		CreatedAt:    createdAt(n),
+		UpdatedAt:    createdAt(n),
+	}
+}
@@ -0,0 +1,121 @@
+// Package i18n localizes the system emails sent by the service, such as
+// subscription confirmations and export download links.
+//
+// Messages are kept in the JSON catalogs of the locales directory, one file
+// per language, and embedded in the binary. English is the source language:
+// messages missing from another catalog fall back to it.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"log/slog"
+
+	goi18n "github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+// Languages lists the languages system emails are translated to. The first
+// one is the default.
+var Languages = []language.Tag{
+	language.English,
+	language.German,
+	language.Spanish,
+	language.French,
+}
+
+// DefaultLanguage is the language used when no preference matches.
+var DefaultLanguage = Languages[0].String()
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var (
+	bundle  = loadBundle()
+	matcher = language.NewMatcher(Languages)
+)
+
+// loadBundle parses the embedded catalogs. It panics on a malformed catalog,
+// which is a build defect.
+func loadBundle() *goi18n.Bundle {
+	bundle := goi18n.NewBundle(Languages[0])
+	bundle.RegisterUnmarshalFunc("json", json.Unmarshal)
+
+	for _, tag := range Languages {
+		if _, err := bundle.LoadMessageFileFS(locales, "locales/"+tag.String()+".json"); err != nil {
+			panic("i18n: " + err.Error())
+		}
+	}
+	return bundle
+}
+
+// Supported reports whether lang is the code of a language of Languages,
+// such as "de".
+func Supported(lang string) bool {
+	for _, tag := range Languages {
+		if tag.String() == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the code of the supported language best matching the first
+// preference that matches any. Each preference is a language code or the
+// value of an Accept-Language header; empty preferences are skipped. It
+// returns DefaultLanguage when no preference matches.
+//
+// Usage:
+//
+//	lang := i18n.Match(subscription.Language, r.Header.Get("Accept-Language"), newsletter.Language)
+func Match(preferences ...string) string {
+	for _, preference := range preferences {
+		if preference == "" {
+			continue
+		}
+
+		tags, _, err := language.ParseAcceptLanguage(preference)
+		if err != nil || len(tags) == 0 {
+			continue
+		}
+		if _, index, confidence := matcher.Match(tags...); confidence != language.No {
+			return Languages[index].String()
+		}
+	}
+	return DefaultLanguage
+}
+
+// Localizer translates messages to a single language.
+type Localizer struct {
+	lang      string
+	localizer *goi18n.Localizer
+}
+
+// New returns a Localizer for lang, a code returned by Match. Unsupported
+// codes localize to DefaultLanguage.
+func New(lang string) *Localizer {
+	if !Supported(lang) {
+		lang = DefaultLanguage
+	}
+	return &Localizer{lang: lang, localizer: goi18n.NewLocalizer(bundle, lang)}
+}
+
+// Language returns the code of the language of l.
+func (l *Localizer) Language() string {
+	return l.lang
+}
+
+// T returns the message id in the language of l, with the template fields
+// such as {{.Link}} replaced by data. Values inserted in HTML messages must
+// be escaped by the caller.
+func (l *Localizer) T(id string, data map[string]any) string {
+	message, err := l.localizer.Localize(&goi18n.LocalizeConfig{MessageID: id, TemplateData: data})
+	if err != nil {
+		// The English message, if any, is returned along with the error.
+		slog.Warn("missing translation", "message", id, "language", l.lang, "error", err)
+		if message == "" {
+			return id
+		}
+	}
+	return message
+}
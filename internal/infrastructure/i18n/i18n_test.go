@@ -0,0 +1,45 @@
+package i18n
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	assert.Equal(t, "de", Match("de-AT"))
+	assert.Equal(t, "fr", Match("", "fr-CH,fr;q=0.9,en;q=0.8"))
+	assert.Equal(t, "es", Match("ja", "es"), "unsupported preferences are skipped")
+	assert.Equal(t, DefaultLanguage, Match("", "ja"))
+	assert.Equal(t, DefaultLanguage, Match())
+}
+
+func TestLocalizer(t *testing.T) {
+	l := New("de")
+	assert.Equal(t, "de", l.Language())
+	assert.Equal(t, "Bestätigung", l.T("ConfirmationSubject", nil))
+	assert.Contains(t, l.T("PostUnsubscribeText", map[string]any{"Link": "https://example.com/u"}), "https://example.com/u")
+
+	assert.Equal(t, DefaultLanguage, New("xx").Language())
+}
+
+// Every catalog translates every message of the English catalog.
+func TestCatalogs_Complete(t *testing.T) {
+	read := func(lang string) map[string]string {
+		content, err := locales.ReadFile("locales/" + lang + ".json")
+		require.NoError(t, err)
+		var messages map[string]string
+		require.NoError(t, json.Unmarshal(content, &messages))
+		return messages
+	}
+
+	source := read(DefaultLanguage)
+	for _, tag := range Languages[1:] {
+		catalog := read(tag.String())
+		for id := range source {
+			assert.NotEmpty(t, catalog[id], "%s is missing %s", tag, id)
+		}
+	}
+}
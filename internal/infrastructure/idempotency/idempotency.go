@@ -0,0 +1,65 @@
+// Package idempotency remembers the responses to requests sent with an
+// Idempotency-Key header, so that a client retrying a request after a
+// network failure gets the original response instead of executing it twice,
+// such as sending a campaign or subscribing twice.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	apperrors "newsletter/internal/errors"
+	"time"
+)
+
+const (
+	// TTL is how long a key and its response are remembered.
+	TTL = 24 * time.Hour
+	// LockTimeout is how long a key stays reserved by a request that did not
+	// complete, such as one interrupted by a crash, before another request
+	// may take it over.
+	LockTimeout = time.Minute
+)
+
+var (
+	// ErrInProgress is returned when the request of a key has not completed yet.
+	ErrInProgress = apperrors.New(apperrors.Conflict, "a request with this idempotency key is in progress")
+	// ErrKeyReused is returned when a key is sent again with a different request.
+	ErrKeyReused = apperrors.New(apperrors.Validation, "idempotency key already used for a different request")
+)
+
+// Response is the stored response to a request.
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Store remembers idempotency keys and the responses to their requests.
+// Keys are scoped, for example by user, so that two clients choosing the
+// same key do not see each other's responses.
+type Store interface {
+	// Begin reserves key for the request with the given hash (see Hash).
+	//
+	// It returns the stored response when the key was already used for the
+	// same request, ErrInProgress when that request has not completed and
+	// ErrKeyReused when the key was used for another request. Otherwise the
+	// key is reserved and it returns nil: the caller must then Complete or
+	// Release it. Keys older than TTL are forgotten.
+	Begin(ctx context.Context, scope, key, hash string, now time.Time) (*Response, error)
+
+	// Complete stores the response to the request of a reserved key.
+	Complete(ctx context.Context, scope, key string, response Response, now time.Time) error
+
+	// Release forgets a reserved key, so that the request can be retried.
+	Release(ctx context.Context, scope, key string) error
+}
+
+// Hash returns the fingerprint of a request, telling a retry from another
+// request reusing the same key.
+func Hash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
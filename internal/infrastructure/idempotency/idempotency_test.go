@@ -0,0 +1,97 @@
+package idempotency
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Now()
+	hash := Hash("POST", "/subscriptions/1", []byte(`{"email":"a@example.com"}`))
+
+	stored, err := store.Begin(ctx, "public", "key-1", hash, now)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "the first request is executed")
+
+	_, err = store.Begin(ctx, "public", "key-1", hash, now)
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	require.NoError(t, store.Complete(ctx, "public", "key-1", Response{Status: 201, ContentType: "application/json", Body: []byte(`{}`)}, now))
+
+	stored, err = store.Begin(ctx, "public", "key-1", hash, now)
+	require.NoError(t, err)
+	assert.Equal(t, &Response{Status: 201, ContentType: "application/json", Body: []byte(`{}`)}, stored)
+
+	_, err = store.Begin(ctx, "public", "key-1", Hash("POST", "/subscriptions/2", nil), now)
+	assert.ErrorIs(t, err, ErrKeyReused)
+
+	stored, err = store.Begin(ctx, "user:1", "key-1", hash, now)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "keys are scoped")
+
+	stored, err = store.Begin(ctx, "public", "key-1", hash, now.Add(TTL+time.Second))
+	require.NoError(t, err)
+	assert.Nil(t, stored, "expired keys are forgotten")
+}
+
+func TestMemoryStore_ReleaseAndAbandon(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Now()
+
+	_, err := store.Begin(ctx, "public", "key-1", "hash", now)
+	require.NoError(t, err)
+	require.NoError(t, store.Release(ctx, "public", "key-1"))
+
+	stored, err := store.Begin(ctx, "public", "key-1", "hash", now)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "released keys can be retried")
+
+	stored, err = store.Begin(ctx, "public", "key-1", "hash", now.Add(LockTimeout+time.Second))
+	require.NoError(t, err)
+	assert.Nil(t, stored, "abandoned keys are taken over")
+}
+
+func TestPostgresStore_Begin(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+	store := NewPostgresStore(mock)
+	now := time.Now()
+
+	mock.ExpectQuery(`insert into idempotency_keys`).
+		WithArgs("user:1", "key-1", "hash", now, now.Add(-TTL), now.Add(-LockTimeout)).
+		WillReturnRows(pgxmock.NewRows([]string{"reserved"}).AddRow(true))
+
+	stored, err := store.Begin(context.Background(), "user:1", "key-1", "hash", now)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	status, contentType := 202, "application/json"
+	mock.ExpectQuery(`insert into idempotency_keys`).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`select request_hash, status, content_type, body from idempotency_keys where scope = $1 and key = $2`)).
+		WithArgs("user:1", "key-1").
+		WillReturnRows(pgxmock.NewRows([]string{"request_hash", "status", "content_type", "body"}).AddRow("hash", &status, &contentType, []byte(`{}`)))
+
+	stored, err = store.Begin(context.Background(), "user:1", "key-1", "hash", now)
+	require.NoError(t, err)
+	assert.Equal(t, &Response{Status: 202, ContentType: "application/json", Body: []byte(`{}`)}, stored)
+
+	mock.ExpectQuery(`insert into idempotency_keys`).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectQuery(`select request_hash`).
+		WillReturnRows(pgxmock.NewRows([]string{"request_hash", "status", "content_type", "body"}).AddRow("other", nil, nil, nil))
+
+	_, err = store.Begin(context.Background(), "user:1", "key-1", "hash", now)
+	assert.ErrorIs(t, err, ErrKeyReused)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,76 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// record is a key remembered by MemoryStore.
+type record struct {
+	hash      string
+	createdAt time.Time
+	response  *Response // nil until the request completes
+}
+
+// MemoryStore keeps idempotency keys in memory, for running with
+// STORE=memory. Keys are lost on restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*record)}
+}
+
+func (s *MemoryStore) Begin(ctx context.Context, scope, key, hash string, now time.Time) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purge(now)
+
+	id := scope + "\x00" + key
+	existing, ok := s.records[id]
+	abandoned := ok && existing.response == nil && now.Sub(existing.createdAt) > LockTimeout
+	if !ok || abandoned {
+		s.records[id] = &record{hash: hash, createdAt: now}
+		return nil, nil
+	}
+
+	switch {
+	case existing.hash != hash:
+		return nil, ErrKeyReused
+	case existing.response == nil:
+		return nil, ErrInProgress
+	}
+	response := *existing.response
+	return &response, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, scope, key string, response Response, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[scope+"\x00"+key]; ok {
+		existing.response = &response
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, scope+"\x00"+key)
+	return nil
+}
+
+// purge forgets the keys older than TTL.
+func (s *MemoryStore) purge(now time.Time) {
+	for id, existing := range s.records {
+		if now.Sub(existing.createdAt) > TTL {
+			delete(s.records, id)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/infrastructure/database"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// PostgresStore keeps idempotency keys in the idempotency_keys table, so
+// that retries reaching another instance are recognized too.
+type PostgresStore struct {
+	db database.DB
+}
+
+func NewPostgresStore(db database.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Begin reserves the key with a single upsert, which only replaces keys that
+// expired or were abandoned, so that concurrent retries cannot both reserve
+// it. When the key is not reserved, the existing record tells why.
+func (s *PostgresStore) Begin(ctx context.Context, scope, key, hash string, now time.Time) (*Response, error) {
+	query := `insert into idempotency_keys (scope, key, request_hash, created_at) values ($1, $2, $3, $4)
+		on conflict (scope, key) do update
+		set request_hash = excluded.request_hash, status = null, content_type = null, body = null,
+			created_at = excluded.created_at, completed_at = null
+		where idempotency_keys.created_at < $5
+			or (idempotency_keys.completed_at is null and idempotency_keys.created_at < $6)
+		returning true`
+
+	var reserved bool
+	err := s.db.QueryRow(ctx, query, scope, key, hash, now, now.Add(-TTL), now.Add(-LockTimeout)).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	var (
+		storedHash  string
+		status      *int
+		contentType *string
+		body        []byte
+	)
+	query = `select request_hash, status, content_type, body from idempotency_keys where scope = $1 and key = $2`
+	err = s.db.QueryRow(ctx, query, scope, key).Scan(&storedHash, &status, &contentType, &body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Released in between by the request holding the key.
+		return nil, ErrInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case storedHash != hash:
+		return nil, ErrKeyReused
+	case status == nil:
+		return nil, ErrInProgress
+	}
+	response := &Response{Status: *status, Body: body}
+	if contentType != nil {
+		response.ContentType = *contentType
+	}
+	return response, nil
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, scope, key string, response Response, now time.Time) error {
+	query := `update idempotency_keys set status = $3, content_type = $4, body = $5, completed_at = $6 where scope = $1 and key = $2`
+
+	_, err := s.db.Exec(ctx, query, scope, key, response.Status, response.ContentType, response.Body, now)
+	return err
+}
+
+func (s *PostgresStore) Release(ctx context.Context, scope, key string) error {
+	query := `delete from idempotency_keys where scope = $1 and key = $2 and completed_at is null`
+
+	_, err := s.db.Exec(ctx, query, scope, key)
+	return err
+}
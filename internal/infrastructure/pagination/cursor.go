@@ -0,0 +1,69 @@
+// Package pagination provides opaque cursors for keyset ("start after")
+// pagination over listings ordered by creation time and ID.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	apperrors "newsletter/internal/errors"
+	"time"
+)
+
+const (
+	// DefaultLimit is the page size used when none is requested.
+	DefaultLimit = 50
+	// MaxLimit is the largest page size a client may request.
+	MaxLimit = 100
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded.
+var ErrInvalidCursor = apperrors.New(apperrors.Validation, "invalid cursor")
+
+// Cursor identifies the last item of a page. Listings ordered by creation
+// time and ID resume right after it, which keeps pages stable while new
+// items are added. Listings ordered by another field, such as an email
+// address, store its value in Key instead of CreatedAt.
+type Cursor struct {
+	CreatedAt time.Time `json:"c"`
+	Key       string    `json:"k,omitempty"`
+	ID        string    `json:"i"`
+}
+
+// Encode returns the opaque string representation of the cursor.
+func (c Cursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// Decode parses a cursor produced by Encode. An empty string decodes to nil,
+// meaning the first page.
+func Decode(value string) (*Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// Limit clamps a requested page size to [1, MaxLimit], using DefaultLimit
+// when no size was requested.
+func Limit(requested int) int {
+	switch {
+	case requested <= 0:
+		return DefaultLimit
+	case requested > MaxLimit:
+		return MaxLimit
+	default:
+		return requested
+	}
+}
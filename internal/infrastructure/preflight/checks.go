@@ -0,0 +1,328 @@
+package preflight
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"newsletter/config"
+	"newsletter/internal/infrastructure/firebase"
+	notificationdomain "newsletter/internal/notifications/domain"
+	notificationinfra "newsletter/internal/notifications/infrastructure"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v4"
+	"google.golang.org/api/iterator"
+)
+
+// minJWTSecretLength is the shortest JWT_SECRET_KEY accepted, 256 bits as
+// recommended for HS256.
+const minJWTSecretLength = 32
+
+// Checks returns the checks of every dependency of the API configured by
+// settings, in the order they should run. Checks of dependencies that are
+// not configured skip.
+func Checks(settings config.Preflight) []Check {
+	return []Check{
+		{Name: "jwt", Run: func(ctx context.Context) (string, error) { return CheckJWT(ctx, settings.JWTSecret) }},
+		{Name: "postgres", Run: func(ctx context.Context) (string, error) { return CheckPostgres(ctx, settings.DSN) }},
+		{Name: "migrations", Run: func(ctx context.Context) (string, error) {
+			return CheckMigrations(ctx, settings.MigrationsDir, settings.DSN)
+		}},
+		{Name: "firestore", Run: CheckFirestore},
+		{Name: "firestore-indexes", Run: func(ctx context.Context) (string, error) {
+			return CheckFirestoreIndexes(ctx, settings.FirestoreIndexesFile)
+		}},
+		{Name: "email", Run: func(ctx context.Context) (string, error) { return CheckEmail(ctx, settings.Email) }},
+		{Name: "redis", Run: func(ctx context.Context) (string, error) { return CheckRedis(ctx, settings.RedisURL) }},
+	}
+}
+
+// CheckJWT verifies that secret, JWT_SECRET_KEY, is set, long enough, and
+// can sign and verify a token.
+func CheckJWT(_ context.Context, secret string) (string, error) {
+	if secret == "" {
+		return "", errors.New("JWT_SECRET_KEY is not set: tokens cannot be issued")
+	}
+	if len(secret) < minJWTSecretLength {
+		return "", fmt.Errorf("JWT_SECRET_KEY is %d bytes long: use at least %d random bytes", len(secret), minJWTSecretLength)
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "preflight"}).SignedString([]byte(secret))
+	if err != nil {
+		return "", fmt.Errorf("sign test token: %w", err)
+	}
+	if _, err := jwt.Parse(signed, func(*jwt.Token) (any, error) { return []byte(secret), nil }); err != nil {
+		return "", fmt.Errorf("verify test token: %w", err)
+	}
+
+	return fmt.Sprintf("HS256 key of %d bytes", len(secret)), nil
+}
+
+// openPostgres connects to the database of dsn, without the retries done at startup.
+func openPostgres(ctx context.Context, dsn string) (*pgx.Conn, error) {
+	if dsn == "" {
+		return nil, errors.New("DSN is not set")
+	}
+
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %w", err)
+	}
+	db, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach Postgres: %w", err)
+	}
+	return db, nil
+}
+
+// CheckPostgres verifies that the database of dsn is reachable.
+func CheckPostgres(ctx context.Context, dsn string) (string, error) {
+	db, err := openPostgres(ctx, dsn)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close(context.Background())
+
+	var version string
+	if err := db.QueryRow(ctx, "show server_version").Scan(&version); err != nil {
+		return "", fmt.Errorf("query server version: %w", err)
+	}
+
+	return "connected to PostgreSQL " + version, nil
+}
+
+var (
+	createTablePattern = regexp.MustCompile(`(?is)^create\s+table\s+(?:if\s+not\s+exists\s+)?(\w+)`)
+	alterTablePattern  = regexp.MustCompile(`(?is)^alter\s+table\s+(?:if\s+exists\s+)?(\w+)`)
+	addColumnPattern   = regexp.MustCompile(`(?is)add\s+column\s+(?:if\s+not\s+exists\s+)?(\w+)`)
+)
+
+// schemaObject is a table, or a column when column is set, that an up
+// migration creates.
+type schemaObject struct {
+	migration string
+	table     string
+	column    string
+}
+
+// expectedSchema lists the tables and columns created by the up migrations
+// found in dir, in migration order.
+func expectedSchema(dir string) ([]schemaObject, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.up.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var objects []schemaObject
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, statement := range strings.Split(string(content), ";") {
+			statement = strings.TrimSpace(statement)
+			if match := createTablePattern.FindStringSubmatch(statement); match != nil {
+				objects = append(objects, schemaObject{migration: file, table: strings.ToLower(match[1])})
+				continue
+			}
+			if match := alterTablePattern.FindStringSubmatch(statement); match != nil {
+				for _, column := range addColumnPattern.FindAllStringSubmatch(statement, -1) {
+					objects = append(objects, schemaObject{migration: file, table: strings.ToLower(match[1]), column: strings.ToLower(column[1])})
+				}
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+// CheckMigrations verifies that every table and column created by the up
+// migrations of dir, MIGRATIONS_DIR, exists in the database of dsn, and
+// names the first migration that is not applied otherwise.
+func CheckMigrations(ctx context.Context, dir, dsn string) (string, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("%w: migrations directory %q not found (set MIGRATIONS_DIR)", ErrSkipped, dir)
+	}
+
+	objects, err := expectedSchema(dir)
+	if err != nil {
+		return "", fmt.Errorf("read migrations: %w", err)
+	}
+
+	db, err := openPostgres(ctx, dsn)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close(context.Background())
+
+	var pending []string
+	for _, object := range objects {
+		var exists bool
+		if object.column == "" {
+			err = db.QueryRow(ctx, `select exists(select 1 from information_schema.tables where table_schema = current_schema() and table_name = $1)`, object.table).Scan(&exists)
+		} else {
+			err = db.QueryRow(ctx, `select exists(select 1 from information_schema.columns where table_schema = current_schema() and table_name = $1 and column_name = $2)`, object.table, object.column).Scan(&exists)
+		}
+		if err != nil {
+			return "", fmt.Errorf("inspect schema: %w", err)
+		}
+		if !exists && (len(pending) == 0 || pending[len(pending)-1] != object.migration) {
+			pending = append(pending, object.migration)
+		}
+	}
+
+	if len(pending) > 0 {
+		return "", fmt.Errorf("%d migration(s) not applied, starting with %s", len(pending), pending[0])
+	}
+
+	return fmt.Sprintf("%d tables and columns of %s present", len(objects), dir), nil
+}
+
+// CheckFirestore verifies that the Firestore project is reachable with the
+// configured credentials by reading a subscription.
+func CheckFirestore(ctx context.Context) (string, error) {
+	client, err := firebase.InitFirestore(ctx)
+	if err != nil {
+		return "", fmt.Errorf("initialize Firestore (check GOOGLE_APPLICATION_CREDENTIALS): %w", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("subscriptions").Limit(1).Documents(ctx).Next()
+	if err != nil && !errors.Is(err, iterator.Done) {
+		return "", fmt.Errorf("read subscriptions collection: %w", err)
+	}
+
+	return "subscriptions collection readable", nil
+}
+
+// CheckFirestoreIndexes verifies that the indexes declared in the manifest
+// at path, FIRESTORE_INDEXES_FILE, are deployed and ready, as the queries
+// needing them fail otherwise.
+func CheckFirestoreIndexes(ctx context.Context, path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%w: index manifest %q not found (set FIRESTORE_INDEXES_FILE)", ErrSkipped, path)
+	}
+
+	checked, err := firebase.VerifyIndexes(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d indexes of %s ready", checked, path), nil
+}
+
+// CheckEmail verifies that the email provider can be configured and, for
+// providers verifying senders such as SES, that the default sender address
+// or its domain is verified.
+func CheckEmail(_ context.Context, settings config.Email) (string, error) {
+	if settings.DryRun {
+		return "", fmt.Errorf("%w: EMAIL_DRY_RUN is enabled, emails are not sent", ErrSkipped)
+	}
+	provider, err := notificationinfra.NewProvider(settings)
+	if err != nil {
+		return "", fmt.Errorf("configure email provider: %w", err)
+	}
+
+	verifier, ok := provider.(notificationdomain.SenderVerifier)
+	if !ok {
+		return provider.Name() + " configured; sender verification is managed by the provider", nil
+	}
+
+	address, err := mail.ParseAddress(settings.From)
+	if err != nil {
+		return "", fmt.Errorf("invalid EMAIL_FROM %q: %w", settings.From, err)
+	}
+
+	identities := []string{address.Address}
+	if at := strings.LastIndex(address.Address, "@"); at >= 0 {
+		identities = append(identities, address.Address[at+1:])
+	}
+	for _, identity := range identities {
+		status, err := verifier.VerificationStatus(identity)
+		if err != nil {
+			return "", fmt.Errorf("query %s identity %s: %w", provider.Name(), identity, err)
+		}
+		if status == notificationdomain.VerificationSuccess {
+			return fmt.Sprintf("%s identity %s verified", provider.Name(), identity), nil
+		}
+	}
+
+	return "", fmt.Errorf("%s sender %s is not verified: verify the address or its domain", provider.Name(), address.Address)
+}
+
+// CheckRedis verifies that the Redis server of raw, REDIS_URL
+// (redis://[:password@]host:port), answers PING. It skips when REDIS_URL is
+// not set.
+func CheckRedis(ctx context.Context, raw string) (string, error) {
+	if raw == "" {
+		return "", fmt.Errorf("%w: REDIS_URL is not set", ErrSkipped)
+	}
+
+	target, err := url.Parse(raw)
+	if err != nil || (target.Scheme != "redis" && target.Scheme != "rediss") {
+		return "", errors.New("invalid REDIS_URL: expected redis://[:password@]host:port")
+	}
+	if target.Scheme == "rediss" {
+		return "", fmt.Errorf("%w: TLS connections are not checked", ErrSkipped)
+	}
+	host := target.Host
+	if target.Port() == "" {
+		host = net.JoinHostPort(target.Hostname(), "6379")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return "", fmt.Errorf("cannot reach Redis: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+	}
+
+	reader := bufio.NewReader(conn)
+	if password, ok := target.User.Password(); ok {
+		if err := redisCommand(conn, reader, "+OK", "AUTH", password); err != nil {
+			return "", fmt.Errorf("authenticate with Redis: %w", err)
+		}
+	}
+	if err := redisCommand(conn, reader, "+PONG", "PING"); err != nil {
+		return "", fmt.Errorf("ping Redis: %w", err)
+	}
+
+	return "PING answered by " + host, nil
+}
+
+// redisCommand sends a command in the RESP protocol and checks its reply.
+func redisCommand(conn net.Conn, reader *bufio.Reader, want string, args ...string) error {
+	command := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		command += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(command)); err != nil {
+		return err
+	}
+
+	reply, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if reply = strings.TrimSpace(reply); reply != want {
+		return fmt.Errorf("unexpected reply %q", reply)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package preflight verifies that the dependencies of the API are reachable
+// and correctly configured before it is rolled out.
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusFail Status = "fail"
+	// StatusSkip is reported for dependencies that are not configured.
+	StatusSkip Status = "skip"
+)
+
+// ErrSkipped is returned by a check whose dependency is not configured. The
+// message wrapping it explains why.
+var ErrSkipped = errors.New("skipped")
+
+// Check verifies a single dependency. Run returns a short description of
+// what was verified, or an error describing what is wrong and how to fix it.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of a Check.
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Detail   string        `json:"detail"`
+	Duration time.Duration `json:"-"`
+}
+
+// MarshalJSON reports the duration in milliseconds.
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	return json.Marshal(struct {
+		result
+		Duration int64 `json:"duration_ms"`
+	}{result(r), r.Duration.Milliseconds()})
+}
+
+// Report is the outcome of all checks.
+type Report struct {
+	Passed  bool     `json:"passed"`
+	Results []Result `json:"results"`
+}
+
+// Run executes checks in order, giving each at most timeout. Skipped checks
+// do not fail the report.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) Report {
+	report := Report{Passed: true, Results: make([]Result, 0, len(checks))}
+
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		detail, err := check.Run(checkCtx)
+		cancel()
+
+		result := Result{Name: check.Name, Status: StatusPass, Detail: detail, Duration: time.Since(start)}
+		switch {
+		case errors.Is(err, ErrSkipped):
+			result.Status, result.Detail = StatusSkip, err.Error()
+		case err != nil:
+			result.Status, result.Detail = StatusFail, err.Error()
+			report.Passed = false
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	return report
+}
+
+// WriteText writes the report as an aligned table followed by a summary line.
+func (r Report) WriteText(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CHECK\tSTATUS\tTIME\tDETAIL")
+	for _, result := range r.Results {
+		fmt.Fprintf(table, "%s\t%s\t%dms\t%s\n", result.Name, result.Status, result.Duration.Milliseconds(), result.Detail)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	summary := "preflight passed"
+	if !r.Passed {
+		summary = "preflight FAILED"
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
+
+// WriteJSON writes the report as a JSON document.
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
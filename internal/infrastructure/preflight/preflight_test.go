@@ -0,0 +1,100 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_Report(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		{Name: "ok", Run: func(context.Context) (string, error) { return "reachable", nil }},
+		{Name: "optional", Run: func(context.Context) (string, error) { return "", fmt.Errorf("%w: not configured", ErrSkipped) }},
+		{Name: "broken", Run: func(context.Context) (string, error) { return "", errors.New("connection refused") }},
+	}, time.Second)
+
+	assert.False(t, report.Passed)
+	require.Len(t, report.Results, 3)
+	assert.Equal(t, StatusPass, report.Results[0].Status)
+	assert.Equal(t, StatusSkip, report.Results[1].Status)
+	assert.Equal(t, "skipped: not configured", report.Results[1].Detail)
+	assert.Equal(t, StatusFail, report.Results[2].Status)
+
+	var text bytes.Buffer
+	require.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "connection refused")
+	assert.Contains(t, text.String(), "preflight FAILED")
+
+	var decoded struct {
+		Passed  bool             `json:"passed"`
+		Results []map[string]any `json:"results"`
+	}
+	var out bytes.Buffer
+	require.NoError(t, report.WriteJSON(&out))
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.False(t, decoded.Passed)
+	assert.Contains(t, decoded.Results[0], "duration_ms")
+}
+
+func TestRun_SkippedChecksPass(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		{Name: "optional", Run: func(context.Context) (string, error) { return "", ErrSkipped }},
+	}, time.Second)
+
+	assert.True(t, report.Passed)
+}
+
+func TestRun_Timeout(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		{Name: "slow", Run: func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}},
+	}, 10*time.Millisecond)
+
+	assert.False(t, report.Passed)
+	assert.Equal(t, StatusFail, report.Results[0].Status)
+}
+
+func TestExpectedSchema(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "users"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users", "000001_create_users.up.sql"), []byte(
+		"CREATE TABLE IF NOT EXISTS users (id uuid primary key);\nCREATE INDEX users_idx ON users (id);"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users", "000002_add_name.up.sql"), []byte(
+		"ALTER TABLE users ADD COLUMN name text, ADD COLUMN IF NOT EXISTS locale text;"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users", "000002_add_name.down.sql"), []byte(
+		"ALTER TABLE users DROP COLUMN name;"), 0o644))
+
+	objects, err := expectedSchema(dir)
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+	assert.Equal(t, "users", objects[0].table)
+	assert.Empty(t, objects[0].column)
+	assert.Equal(t, "name", objects[1].column)
+	assert.Equal(t, "locale", objects[2].column)
+	assert.Contains(t, objects[2].migration, "000002_add_name.up.sql")
+}
+
+func TestCheckJWT(t *testing.T) {
+	_, err := CheckJWT(context.Background(), "short")
+	assert.Error(t, err)
+
+	detail, err := CheckJWT(context.Background(), "0123456789abcdef0123456789abcdef")
+	assert.NoError(t, err)
+	assert.Contains(t, detail, "32 bytes")
+}
+
+func TestCheckRedis_NotConfigured(t *testing.T) {
+	_, err := CheckRedis(context.Background(), "")
+	assert.ErrorIs(t, err, ErrSkipped)
+}
@@ -0,0 +1,86 @@
+// Package ratelimit counts the requests of API clients over fixed windows,
+// so that they can be told how many they have left and refused beyond
+// their limit.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Window is the period the requests of clients are counted over, which
+// limits are expressed per.
+const Window = time.Minute
+
+// Decision is the outcome of counting a request against a limit.
+type Decision struct {
+	Limit     int       // Requests allowed per window
+	Remaining int       // Requests left in the current window
+	Reset     time.Time // End of the current window, when Remaining goes back to Limit
+	Allowed   bool      // Whether the request is within the limit
+}
+
+// Limiter counts the requests of clients over fixed windows aligned on
+// multiples of the window, such as every minute. Clients are identified by
+// keys chosen by the caller, such as "ip:203.0.113.7" or "user:<id>", and
+// each request is counted against the limit given with it, so that clients
+// may have limits of their own. State is kept in memory, per instance.
+type Limiter struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	counts    map[string]*count // requests of each client in its current window
+	lastPurge time.Time
+}
+
+// count is the number of requests of a client in the window starting at start.
+type count struct {
+	start    time.Time
+	requests int
+}
+
+// New creates a Limiter counting requests over windows of window.
+func New(window time.Duration) *Limiter {
+	return &Limiter{window: window, counts: make(map[string]*count)}
+}
+
+// Take counts a request of key at now against limit requests per window,
+// and reports whether it is allowed. Refused requests are counted too: a
+// client must wait for the next window.
+func (l *Limiter) Take(now time.Time, key string, limit int) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.purge(now)
+
+	start := now.Truncate(l.window)
+	c, ok := l.counts[key]
+	if !ok || !c.start.Equal(start) {
+		c = &count{start: start}
+		l.counts[key] = c
+	}
+	c.requests++
+
+	return Decision{
+		Limit:     limit,
+		Remaining: max(0, limit-c.requests),
+		Reset:     start.Add(l.window),
+		Allowed:   c.requests <= limit,
+	}
+}
+
+// purge forgets the counts of past windows, at most once per window so that
+// busy instances do not scan every client on every request.
+func (l *Limiter) purge(now time.Time) {
+	if now.Sub(l.lastPurge) < l.window {
+		return
+	}
+	l.lastPurge = now
+
+	start := now.Truncate(l.window)
+	for key, c := range l.counts {
+		if c.start.Before(start) {
+			delete(l.counts, key)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Take(t *testing.T) {
+	l := New(time.Minute)
+	now := time.Date(2026, 1, 5, 12, 0, 10, 0, time.UTC)
+	reset := time.Date(2026, 1, 5, 12, 1, 0, 0, time.UTC)
+
+	assert.Equal(t, Decision{Limit: 2, Remaining: 1, Reset: reset, Allowed: true}, l.Take(now, "user:1", 2))
+	assert.Equal(t, Decision{Limit: 2, Remaining: 0, Reset: reset, Allowed: true}, l.Take(now.Add(time.Second), "user:1", 2))
+	assert.Equal(t, Decision{Limit: 2, Remaining: 0, Reset: reset, Allowed: false}, l.Take(now.Add(2*time.Second), "user:1", 2))
+
+	// Clients are counted separately, against limits of their own.
+	assert.Equal(t, Decision{Limit: 10, Remaining: 9, Reset: reset, Allowed: true}, l.Take(now, "user:2", 10))
+
+	// The count starts over with the next window.
+	next := l.Take(reset, "user:1", 2)
+	assert.True(t, next.Allowed)
+	assert.Equal(t, 1, next.Remaining)
+	assert.Equal(t, reset.Add(time.Minute), next.Reset)
+}
+
+func TestLimiter_ForgetsPastWindows(t *testing.T) {
+	l := New(time.Minute)
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	l.Take(now, "ip:203.0.113.7", 5)
+	l.Take(now.Add(2*time.Minute), "ip:198.51.100.1", 5)
+
+	assert.NotContains(t, l.counts, "ip:203.0.113.7")
+	assert.Contains(t, l.counts, "ip:198.51.100.1")
+}
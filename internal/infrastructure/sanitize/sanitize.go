@@ -0,0 +1,71 @@
+// Package sanitize cleans user-provided content before it is stored, so that
+// it can be rendered on public pages and in outgoing emails without
+// injecting scripts or markup.
+package sanitize
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	// textPolicy removes every element, keeping only text.
+	textPolicy = bluemonday.StrictPolicy()
+
+	// htmlPolicy keeps the formatting of user-generated content: headings,
+	// lists, tables, images and links to http, https and mailto URLs, plus
+	// the few inline styles email clients commonly rely on. Scripts, event
+	// handlers, forms and frames are removed.
+	htmlPolicy = newHTMLPolicy()
+
+	// templateTag matches the merge tags expanded when a post is sent, such
+	// as {{ email }} or {{ attributes.first_name | there }}.
+	templateTag = regexp.MustCompile(`\{\{[^{}<>"]*\}\}`)
+)
+
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowURLSchemes("http", "https", "mailto")
+	policy.AllowStyles("color", "background-color", "text-align", "font-weight", "font-style", "text-decoration").Globally()
+	policy.AllowAttrs("align", "width", "height").OnElements("img", "table", "td", "th")
+	return policy
+}
+
+// Text returns s without any HTML element, for plain-text fields such as
+// names and descriptions. Entities are decoded, so that "Tom &amp; Jerry"
+// is stored as "Tom & Jerry": the result must still be escaped when
+// rendered as HTML.
+func Text(s string) string {
+	return strings.TrimSpace(html.UnescapeString(textPolicy.Sanitize(s)))
+}
+
+// HTML returns s with only safe elements and attributes, for rich content
+// such as the body of a post. Merge tags are kept as written, including in
+// links, where they would otherwise be URL-encoded: they contain neither
+// markup nor quotes, and their values are escaped when expanded.
+func HTML(s string) string {
+	tags := templateTag.FindAllString(s, -1)
+	if len(tags) == 0 {
+		return htmlPolicy.Sanitize(s)
+	}
+
+	// The tags are swapped for placeholders that the policy leaves alone.
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	prefix := "mergetag" + hex.EncodeToString(nonce) + "_"
+	restore := make([]string, 0, 2*len(tags))
+	n := 0
+	s = templateTag.ReplaceAllStringFunc(s, func(tag string) string {
+		placeholder := fmt.Sprintf("%s%d_", prefix, n)
+		restore = append(restore, placeholder, tag)
+		n++
+		return placeholder
+	})
+	return strings.NewReplacer(restore...).Replace(htmlPolicy.Sanitize(s))
+}
@@ -0,0 +1,47 @@
+package sanitize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Weekly news", "Weekly news"},
+		{"  Tom &amp; Jerry ", "Tom & Jerry"},
+		{"<b>Bold</b> name", "Bold name"},
+		{`Hi<script>alert("x")</script>`, "Hi"},
+		{`<img src=x onerror="alert(1)">News`, "News"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.want, Text(tt.in))
+		})
+	}
+}
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"formatting", `<h1>Title</h1><p>Some <strong>bold</strong> text</p>`, `<h1>Title</h1><p>Some <strong>bold</strong> text</p>`},
+		{"script", `<p>Hi</p><script>alert(1)</script>`, `<p>Hi</p>`},
+		{"event handler", `<img src="https://example.com/a.png" onerror="alert(1)">`, `<img src="https://example.com/a.png">`},
+		{"javascript link", `<a href="javascript:alert(1)">click</a>`, `click`},
+		{"link", `<a href="https://example.com">site</a>`, `<a href="https://example.com" rel="nofollow">site</a>`},
+		{"style", `<p style="color: red; position: fixed">red</p>`, `<p style="color: red">red</p>`},
+		{"iframe", `<iframe src="https://evil.example"></iframe>`, ``},
+		{"merge tags", `<p>Hi {{ attributes.first_name | there }}</p><a href="{{unsubscribe_url}}">Unsubscribe</a>`, `<p>Hi {{ attributes.first_name | there }}</p><a href="{{unsubscribe_url}}" rel="nofollow">Unsubscribe</a>`},
+		{"merge tag with markup", `<p>{{ <script>alert(1)</script> }}</p>`, `<p>{{  }}</p>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HTML(tt.in))
+		})
+	}
+}
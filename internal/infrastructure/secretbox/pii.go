@@ -0,0 +1,99 @@
+package secretbox
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix marks the values sealed by PII, so that values stored in
+// clear before encryption was enabled are still read.
+const sealedPrefix = "enc:v1:"
+
+// PII encrypts the personal data stored in documents, such as the email
+// addresses of subscribers, and computes the blind indexes that documents
+// are looked up by in place of the encrypted values.
+//
+// A nil *PII stores values in clear and computes no index, so that callers
+// need not tell both cases apart.
+type PII struct {
+	box      *Box
+	indexKey []byte
+}
+
+// NewPII returns a PII deriving its encryption and index keys from key,
+// which must be KeySize bytes long.
+func NewPII(key []byte) (*PII, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secretbox: key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	box, err := New(derive(key, "pii-encryption"))
+	if err != nil {
+		return nil, err
+	}
+	return &PII{box: box, indexKey: derive(key, "pii-index")}, nil
+}
+
+// Seal returns the stored form of value: its ciphertext, base64 encoded
+// behind a version prefix.
+func (p *PII) Seal(value string) (string, error) {
+	if p == nil || value == "" {
+		return value, nil
+	}
+
+	ciphertext, err := p.box.Encrypt([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Open returns the value of a stored form returned by Seal. Values stored
+// in clear are returned as they are.
+func (p *PII) Open(stored string) (string, error) {
+	encoded, sealed := strings.CutPrefix(stored, sealedPrefix)
+	if !sealed {
+		return stored, nil
+	}
+	if p == nil {
+		return "", ErrDecrypt
+	}
+
+	ciphertext, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	value, err := p.box.Decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// Sealed reports whether stored was returned by Seal.
+func Sealed(stored string) bool {
+	return strings.HasPrefix(stored, sealedPrefix)
+}
+
+// Index returns the blind index of value: a keyed hash, equal for equal
+// values, that reveals nothing about value without the key.
+func (p *PII) Index(value string) string {
+	if p == nil {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, p.indexKey)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// derive returns the subkey of key for purpose, so that the encryption and
+// index keys are independent.
+func derive(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,66 @@
+// Package secretbox encrypts small secrets stored in the database, such as
+// the TOTP secrets of two-factor authentication, and the personal data of
+// subscribers with AES-256-GCM.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size in bytes of the keys of a Box.
+const KeySize = 32
+
+// ErrDecrypt is returned when a ciphertext was not produced by the key of
+// the Box or was altered.
+var ErrDecrypt = errors.New("secretbox: cannot decrypt")
+
+// Box encrypts and authenticates secrets with a single key. Each ciphertext
+// starts with its random nonce.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New returns a Box using key, which must be KeySize bytes long.
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secretbox: key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Encrypt returns the ciphertext of plaintext.
+func (b *Box) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt returns the plaintext of a ciphertext returned by Encrypt, or
+// ErrDecrypt.
+func (b *Box) Decrypt(ciphertext []byte) ([]byte, error) {
+	size := b.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := b.aead.Open(nil, ciphertext[:size], ciphertext[size:], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
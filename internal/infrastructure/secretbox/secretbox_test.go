@@ -0,0 +1,86 @@
+package secretbox
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBox_RoundTrip(t *testing.T) {
+	box, err := New(bytes.Repeat([]byte{7}, KeySize))
+	require.NoError(t, err)
+
+	ciphertext, err := box.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "secret")
+
+	plaintext, err := box.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+}
+
+func TestBox_Decrypt_RejectsOtherKeyAndTampering(t *testing.T) {
+	box, _ := New(bytes.Repeat([]byte{7}, KeySize))
+	other, _ := New(bytes.Repeat([]byte{8}, KeySize))
+
+	ciphertext, err := box.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = other.Decrypt(ciphertext)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	ciphertext[len(ciphertext)-1] ^= 1
+	_, err = box.Decrypt(ciphertext)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = box.Decrypt([]byte("short"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestPII_SealOpen(t *testing.T) {
+	pii, err := NewPII(bytes.Repeat([]byte{7}, KeySize))
+	require.NoError(t, err)
+
+	sealed, err := pii.Seal("ada@example.com")
+	require.NoError(t, err)
+	assert.True(t, Sealed(sealed))
+	assert.NotContains(t, sealed, "ada")
+
+	again, _ := pii.Seal("ada@example.com")
+	assert.NotEqual(t, sealed, again)
+
+	value, err := pii.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", value)
+
+	value, err = pii.Open("grace@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "grace@example.com", value)
+
+	other, _ := NewPII(bytes.Repeat([]byte{8}, KeySize))
+	_, err = other.Open(sealed)
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestPII_Index(t *testing.T) {
+	pii, _ := NewPII(bytes.Repeat([]byte{7}, KeySize))
+	other, _ := NewPII(bytes.Repeat([]byte{8}, KeySize))
+
+	assert.Equal(t, pii.Index("ada@example.com"), pii.Index("ada@example.com"))
+	assert.NotEqual(t, pii.Index("ada@example.com"), pii.Index("grace@example.com"))
+	assert.NotEqual(t, pii.Index("ada@example.com"), other.Index("ada@example.com"))
+}
+
+func TestPII_Nil(t *testing.T) {
+	var pii *PII
+
+	sealed, err := pii.Seal("ada@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", sealed)
+	assert.Empty(t, pii.Index("ada@example.com"))
+
+	_, err = NewPII([]byte("short"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// AWSSource reads a secret of AWS Secrets Manager whose value is a JSON
+// object. Requests are signed with the credentials of the AWS configuration
+// loaded when the source is created, such as the role of the instance, so
+// that AWS credentials loaded from the secret are not used to read it.
+type AWSSource struct {
+	client      *http.Client
+	endpoint    string
+	region      string
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	secretID    string
+}
+
+// NewAWSSource returns an AWSSource reading secretID, its name or ARN.
+func NewAWSSource(ctx context.Context, secretID string) (*AWSSource, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("AWS_REGION is required to read secrets from AWS Secrets Manager")
+	}
+
+	return &AWSSource{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		endpoint:    "https://secretsmanager." + cfg.Region + ".amazonaws.com/",
+		region:      cfg.Region,
+		credentials: cfg.Credentials,
+		signer:      v4.NewSigner(),
+		secretID:    secretID,
+	}, nil
+}
+
+// Fetch returns the values of the current version of the secret, with the
+// GetSecretValue action.
+func (s *AWSSource) Fetch(ctx context.Context) (map[string]string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": s.secretID})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	credentials, err := s.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve AWS credentials: %w", err)
+	}
+	hash := sha256.Sum256(body)
+	if err := s.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), "secretsmanager", s.region, time.Now()); err != nil {
+		return nil, fmt.Errorf("sign request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("read secret %s: %w", s.secretID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("read secret %s: AWS Secrets Manager responded with status %d: %s", s.secretID, resp.StatusCode, message)
+	}
+
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("read secret %s: %w", s.secretID, err)
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal([]byte(result.SecretString), &values); err != nil {
+		return nil, fmt.Errorf("secret %s is not a JSON object of strings", s.secretID)
+	}
+	return values, nil
+}
@@ -0,0 +1,141 @@
+// Package secrets loads the secrets of the API, such as the database DSN
+// and the JWT signing key, from a secret manager instead of the
+// environment, and refreshes them while the API runs.
+//
+// Secrets are stored as a JSON object whose keys are the names of the
+// environment variables they replace. Loaded secrets are set in the
+// environment of the process, so that the settings read with
+// config.GetEnv, at startup or at call time, use them.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"newsletter/config"
+	"os"
+	"slices"
+	"time"
+)
+
+// Backends selected by SECRETS_BACKEND.
+const (
+	BackendEnv   = "env" // Secrets are read from the environment only (default)
+	BackendAWS   = "aws"
+	BackendVault = "vault"
+)
+
+// Names are the environment variables secrets can set. Other keys of a
+// secret are ignored, so that a shared secret cannot change the rest of the
+// configuration.
+var Names = []string{
+	"DSN",
+	"JWT_SECRET_KEY",
+	"JWT_PREVIOUS_SECRET_KEYS",
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+}
+
+// Source reads the current values of the secrets.
+type Source interface {
+	// Fetch returns the values of the secrets by environment variable name.
+	Fetch(ctx context.Context) (map[string]string, error)
+}
+
+// NewSourceFromEnv returns the Source selected by SECRETS_BACKEND, or nil
+// when secrets are read from the environment:
+//   - "aws": the AWS Secrets Manager secret SECRETS_AWS_SECRET_ID, in the
+//     region and with the credentials of the AWS configuration
+//   - "vault": the HashiCorp Vault secret SECRETS_VAULT_PATH, such as
+//     "secret/data/newsletter", of the server VAULT_ADDR with VAULT_TOKEN
+func NewSourceFromEnv() (Source, error) {
+	switch backend := config.GetEnv("SECRETS_BACKEND", BackendEnv); backend {
+	case BackendEnv, "":
+		return nil, nil
+	case BackendAWS:
+		secretID := config.GetEnv("SECRETS_AWS_SECRET_ID", "")
+		if secretID == "" {
+			return nil, fmt.Errorf("SECRETS_AWS_SECRET_ID is required when SECRETS_BACKEND is aws")
+		}
+		return NewAWSSource(context.Background(), secretID)
+	case BackendVault:
+		addr, token, path := config.GetEnv("VAULT_ADDR", ""), config.GetEnv("VAULT_TOKEN", ""), config.GetEnv("SECRETS_VAULT_PATH", "")
+		if addr == "" || token == "" || path == "" {
+			return nil, fmt.Errorf("VAULT_ADDR, VAULT_TOKEN and SECRETS_VAULT_PATH are required when SECRETS_BACKEND is vault")
+		}
+		return NewVaultSource(addr, token, path), nil
+	default:
+		return nil, fmt.Errorf("SECRETS_BACKEND must be env, aws or vault, got %q", backend)
+	}
+}
+
+// RefreshIntervalFromEnv returns how often secrets are refreshed
+// (SECRETS_REFRESH_INTERVAL, default 5m), 0 disabling refreshes.
+func RefreshIntervalFromEnv() (time.Duration, error) {
+	value := config.GetEnv("SECRETS_REFRESH_INTERVAL", "5m")
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid SECRETS_REFRESH_INTERVAL: %q", value)
+	}
+	return interval, nil
+}
+
+// Load fetches the secrets of source and sets them in the environment. It
+// returns the names of the variables it changed.
+func Load(ctx context.Context, source Source) ([]string, error) {
+	values, err := source.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Apply(values), nil
+}
+
+// Apply sets values in the environment, for the variables listed in Names,
+// and returns the names of the variables it changed.
+func Apply(values map[string]string) []string {
+	var changed []string
+	for _, name := range Names {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		if current, set := os.LookupEnv(name); set && current == value {
+			continue
+		}
+		os.Setenv(name, value)
+		changed = append(changed, name)
+	}
+	return changed
+}
+
+// Refresh loads the secrets of source every interval until ctx is done, and
+// calls onChange with the names of the variables that changed. Failures are
+// logged and the previous values kept, so that an unavailable secret
+// manager does not stop the API.
+func Refresh(ctx context.Context, source Source, interval time.Duration, onChange func(changed []string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fetchCtx, cancel := context.WithTimeout(ctx, interval)
+		changed, err := Load(fetchCtx, source)
+		cancel()
+		if err != nil {
+			slog.Error("failed to refresh secrets", "error", err)
+			continue
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		slog.Info("secrets changed", "names", changed)
+		onChange(slices.Clone(changed))
+	}
+}
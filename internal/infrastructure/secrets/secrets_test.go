@@ -0,0 +1,107 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	t.Setenv("DSN", "postgres://old")
+	t.Setenv("JWT_SECRET_KEY", "same")
+	t.Setenv("BASE_URL", "https://api.example.com")
+
+	changed := Apply(map[string]string{
+		"DSN":            "postgres://new",
+		"JWT_SECRET_KEY": "same",
+		"BASE_URL":       "https://evil.example.com",
+	})
+
+	assert.Equal(t, []string{"DSN"}, changed)
+	assert.Equal(t, "postgres://new", os.Getenv("DSN"))
+	assert.Equal(t, "https://api.example.com", os.Getenv("BASE_URL"))
+}
+
+func TestVaultSource_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/newsletter":
+			w.Write([]byte(`{"data":{"data":{"JWT_SECRET_KEY":"from-v2"},"metadata":{"version":3}}}`))
+		case "/v1/kv/newsletter":
+			w.Write([]byte(`{"data":{"JWT_SECRET_KEY":"from-v1","ttl":60}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	values, err := NewVaultSource(server.URL+"/", "token", "/secret/data/newsletter").Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"JWT_SECRET_KEY": "from-v2"}, values)
+
+	values, err = NewVaultSource(server.URL, "token", "kv/newsletter").Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"JWT_SECRET_KEY": "from-v1"}, values)
+
+	_, err = NewVaultSource(server.URL, "wrong", "kv/newsletter").Fetch(context.Background())
+	assert.ErrorContains(t, err, "status 403")
+}
+
+func TestAWSSource_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+
+		var input map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&input))
+		assert.Equal(t, "newsletter/production", input["SecretId"])
+
+		w.Write([]byte(`{"Name":"newsletter/production","SecretString":"{\"DSN\":\"postgres://db\"}"}`))
+	}))
+	defer server.Close()
+
+	source := &AWSSource{
+		client:   &http.Client{Timeout: time.Second},
+		endpoint: server.URL,
+		region:   "eu-west-1",
+		credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+		signer:   v4.NewSigner(),
+		secretID: "newsletter/production",
+	}
+
+	values, err := source.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"DSN": "postgres://db"}, values)
+}
+
+func TestNewSourceFromEnv(t *testing.T) {
+	t.Setenv("SECRETS_BACKEND", "")
+	source, err := NewSourceFromEnv()
+	require.NoError(t, err)
+	assert.Nil(t, source)
+
+	t.Setenv("SECRETS_BACKEND", "vault")
+	t.Setenv("VAULT_ADDR", "")
+	_, err = NewSourceFromEnv()
+	assert.ErrorContains(t, err, "VAULT_ADDR")
+
+	t.Setenv("SECRETS_BACKEND", "keychain")
+	_, err = NewSourceFromEnv()
+	assert.ErrorContains(t, err, "SECRETS_BACKEND must be env, aws or vault")
+}
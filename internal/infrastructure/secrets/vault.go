@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultSource reads a secret of HashiCorp Vault with a token. Both versions
+// of the key/value secrets engine are supported: version 2 paths include
+// "data/", such as "secret/data/newsletter".
+type VaultSource struct {
+	client *http.Client
+	addr   string
+	token  string
+	path   string
+}
+
+// NewVaultSource returns a VaultSource reading path from the server addr,
+// such as "https://vault.example.com:8200".
+func NewVaultSource(addr, token, path string) *VaultSource {
+	return &VaultSource{
+		client: &http.Client{Timeout: 10 * time.Second},
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		path:   strings.Trim(path, "/"),
+	}
+}
+
+// vaultResponse is the subset of a Vault read response used here. Version 1
+// engines return the values in data, version 2 engines in data.data.
+type vaultResponse struct {
+	Data map[string]any `json:"data"`
+}
+
+// Fetch returns the values of the latest version of the secret.
+func (s *VaultSource) Fetch(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.addr+"/v1/"+s.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("read secret %s: %w", s.path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("read secret %s: Vault responded with status %d: %s", s.path, resp.StatusCode, message)
+	}
+
+	var result vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("read secret %s: %w", s.path, err)
+	}
+
+	data := result.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+
+	values := make(map[string]string, len(data))
+	for name, value := range data {
+		if s, ok := value.(string); ok {
+			values[name] = s
+		}
+	}
+	return values, nil
+}
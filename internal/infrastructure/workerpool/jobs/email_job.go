@@ -1,13 +1,67 @@
 package jobs
 
-import "newsletter/internal/notifications/domain"
+import (
+	"context"
+	"log/slog"
+	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/internal/notifications/domain"
+	"time"
+)
+
+const (
+	// maxSendAttempts is the number of delivery attempts made for retryable failures.
+	maxSendAttempts = 3
+	// retryBackoff is the delay before the first retry; it doubles after each attempt.
+	retryBackoff = time.Second
+)
 
 type SendEmailJob struct {
 	Email   domain.Email
 	Service domain.EmailService
+
+	// Backoff overrides the initial delay between attempts. Zero uses retryBackoff.
+	Backoff time.Duration
+
+	// Transactional marks emails a user is waiting for, such as subscription
+	// confirmations. They are queued ahead of other jobs.
+	Transactional bool
+}
+
+// Priority queues transactional emails with workerpool.PriorityHigh.
+func (job *SendEmailJob) Priority() workerpool.Priority {
+	if job.Transactional {
+		return workerpool.PriorityHigh
+	}
+	return workerpool.PriorityNormal
 }
 
-func (job *SendEmailJob) Process() error {
-	err := job.Service.Send(&job.Email)
+// Process sends the email, retrying with exponential backoff when the
+// provider reports a retryable failure. Permanent failures are returned
+// immediately, and so is the last failure when ctx is cancelled while
+// waiting to retry.
+func (job *SendEmailJob) Process(ctx context.Context) error {
+	backoff := job.Backoff
+	if backoff == 0 {
+		backoff = retryBackoff
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxSendAttempts; attempt++ {
+		err = job.Service.Send(ctx, &job.Email)
+		if err == nil || !domain.IsRetryable(err) {
+			return err
+		}
+
+		if attempt < maxSendAttempts {
+			slog.Info("Retrying email delivery", "to", job.Email.To, "attempt", attempt, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+
 	return err
 }
@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/internal/notifications/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mock email service ---
+
+type MockEmailService struct {
+	mock.Mock
+}
+
+func (m *MockEmailService) Send(ctx context.Context, email *domain.Email) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+// --- Tests ---
+
+func TestSendEmailJob_Success(t *testing.T) {
+	es := new(MockEmailService)
+	job := &SendEmailJob{Email: domain.Email{To: "user@test.com"}, Service: es}
+
+	es.On("Send", &job.Email).Return(nil).Once()
+
+	err := job.Process(context.Background())
+
+	assert.NoError(t, err)
+	es.AssertExpectations(t)
+}
+
+func TestSendEmailJob_RetriesRetryableFailures(t *testing.T) {
+	es := new(MockEmailService)
+	job := &SendEmailJob{Email: domain.Email{To: "user@test.com"}, Service: es, Backoff: time.Millisecond}
+
+	throttled := fmt.Errorf("%w: throttled", domain.ErrRetryable)
+	es.On("Send", &job.Email).Return(throttled).Once()
+	es.On("Send", &job.Email).Return(nil).Once()
+
+	err := job.Process(context.Background())
+
+	assert.NoError(t, err)
+	es.AssertNumberOfCalls(t, "Send", 2)
+}
+
+func TestSendEmailJob_GivesUpAfterMaxAttempts(t *testing.T) {
+	es := new(MockEmailService)
+	job := &SendEmailJob{Email: domain.Email{To: "user@test.com"}, Service: es, Backoff: time.Millisecond}
+
+	es.On("Send", &job.Email).Return(fmt.Errorf("%w: outage", domain.ErrRetryable))
+
+	err := job.Process(context.Background())
+
+	assert.ErrorIs(t, err, domain.ErrRetryable)
+	es.AssertNumberOfCalls(t, "Send", maxSendAttempts)
+}
+
+func TestSendEmailJob_DoesNotRetryPermanentFailures(t *testing.T) {
+	es := new(MockEmailService)
+	job := &SendEmailJob{Email: domain.Email{To: "user@test.com"}, Service: es, Backoff: time.Millisecond}
+
+	es.On("Send", &job.Email).Return(fmt.Errorf("%w: rejected", domain.ErrPermanent))
+
+	err := job.Process(context.Background())
+
+	assert.ErrorIs(t, err, domain.ErrPermanent)
+	es.AssertNumberOfCalls(t, "Send", 1)
+}
+
+func TestClassifyHTTPStatus(t *testing.T) {
+	assert.ErrorIs(t, domain.ClassifyHTTPStatus("test", 429, ""), domain.ErrRetryable)
+	assert.ErrorIs(t, domain.ClassifyHTTPStatus("test", 503, ""), domain.ErrRetryable)
+	assert.ErrorIs(t, domain.ClassifyHTTPStatus("test", 400, ""), domain.ErrPermanent)
+	assert.ErrorIs(t, domain.ClassifyHTTPStatus("test", 401, ""), domain.ErrPermanent)
+}
+
+func TestSendEmailJob_Priority(t *testing.T) {
+	assert.Equal(t, workerpool.PriorityNormal, (&SendEmailJob{}).Priority())
+	assert.Equal(t, workerpool.PriorityHigh, (&SendEmailJob{Transactional: true}).Priority())
+}
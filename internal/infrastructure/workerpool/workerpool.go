@@ -1,78 +1,702 @@
 package workerpool
 
 import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"log/slog"
+	apperrors "newsletter/internal/errors"
+	"runtime"
+	"runtime/debug"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Job represents a unit of work that can be processed by the worker pool.
-// Each Job must implement the Process method.
+// Each Job must implement the Process method. Jobs that should not run with
+// PriorityNormal also implement Prioritized, and jobs that need another time
+// limit than the pool's job timeout implement TimeLimited.
 type Job interface {
-	// Process executes the job's logic.
+	// Process executes the job's logic. ctx is cancelled when the job
+	// exceeds its time limit; long-running jobs should stop then.
 	// It should return an error if the job fails.
-	Process() error
+	Process(ctx context.Context) error
+}
+
+// TimeLimited is implemented by jobs that override the job timeout of the
+// pool. A zero timeout lets the job run until it returns.
+type TimeLimited interface {
+	Timeout() time.Duration
+}
+
+// DefaultJobTimeout is the time limit of a job when none is configured.
+const DefaultJobTimeout = 5 * time.Minute
+
+// ParseJobTimeout parses the job timeout, as read from the JOB_TIMEOUT
+// environment variable. An empty value defaults to DefaultJobTimeout.
+func ParseJobTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return DefaultJobTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("JOB_TIMEOUT must be a positive duration, got %q", value)
+	}
+	return timeout, nil
+}
+
+// Priority orders queued jobs: workers always take a job of the highest
+// priority waiting. The zero value is PriorityNormal.
+type Priority int
+
+const (
+	// PriorityLow is for bulk work, such as sending campaigns.
+	PriorityLow Priority = -1
+	// PriorityNormal is for jobs without a priority.
+	PriorityNormal Priority = 0
+	// PriorityHigh is for transactional emails users wait for, such as
+	// subscription confirmations.
+	PriorityHigh Priority = 1
+)
+
+func (p Priority) String() string {
+	switch {
+	case p > PriorityNormal:
+		return "high"
+	case p < PriorityNormal:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// Prioritized is implemented by jobs that declare their priority.
+type Prioritized interface {
+	Priority() Priority
+}
+
+// priorityOf returns the priority of job, PriorityNormal if it declares none.
+func priorityOf(job Job) Priority {
+	if prioritized, ok := job.(Prioritized); ok {
+		return prioritized.Priority()
+	}
+	return PriorityNormal
 }
 
 // JobSubmiter contains Submit method whicj will me imlemented by WorkerPool
 // It was necessary to create this for testing.
 type JobSubmiter interface {
 	Submit(job Job)
+	// TrySubmit queues job without waiting indefinitely for room in the
+	// queue. It returns ErrQueueFull or ErrShutdown when the job was not
+	// queued.
+	TrySubmit(job Job) error
+}
+
+// JobScheduler queues jobs at a later time. It is implemented by WorkerPool.
+type JobScheduler interface {
+	SubmitAt(job Job, at time.Time)
+	SubmitAfter(job Job, delay time.Duration)
+}
+
+// JobQueue submits jobs now or at a later time. It is implemented by WorkerPool.
+type JobQueue interface {
+	JobSubmiter
+	JobScheduler
+}
+
+// ErrQueueFull is returned by TrySubmit when the queue has no room for a job.
+var ErrQueueFull = apperrors.New(apperrors.Internal, "job queue is full")
+
+// ErrShutdown is returned by TrySubmit once the pool is shut down.
+var ErrShutdown = apperrors.New(apperrors.Internal, "job queue is shut down")
+
+// OverflowPolicy decides what TrySubmit does when the queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits up to the overflow timeout for room in the queue.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowReject fails immediately with ErrQueueFull.
+	OverflowReject OverflowPolicy = "reject"
+	// OverflowDropOldest discards the oldest queued jobs to make room.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+)
+
+// DefaultOverflowTimeout is how long OverflowBlock waits when no timeout is configured.
+const DefaultOverflowTimeout = time.Second
+
+// Overflow configures TrySubmit. The zero value blocks for DefaultOverflowTimeout.
+type Overflow struct {
+	Policy  OverflowPolicy
+	Timeout time.Duration // maximum wait of OverflowBlock
+}
+
+// ParseOverflow parses the overflow policy and block timeout, as read from
+// the QUEUE_OVERFLOW and QUEUE_BLOCK_TIMEOUT environment variables. Empty
+// values default to blocking for DefaultOverflowTimeout.
+func ParseOverflow(policy, timeout string) (Overflow, error) {
+	overflow := Overflow{Policy: OverflowPolicy(strings.TrimSpace(policy)), Timeout: DefaultOverflowTimeout}
+
+	switch overflow.Policy {
+	case "":
+		overflow.Policy = OverflowBlock
+	case OverflowBlock, OverflowReject, OverflowDropOldest:
+	default:
+		return Overflow{}, fmt.Errorf("QUEUE_OVERFLOW must be %s, %s or %s, got %q", OverflowBlock, OverflowReject, OverflowDropOldest, policy)
+	}
+
+	if timeout = strings.TrimSpace(timeout); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return Overflow{}, fmt.Errorf("QUEUE_BLOCK_TIMEOUT must be a positive duration, got %q", timeout)
+		}
+		overflow.Timeout = d
+	}
+
+	return overflow, nil
+}
+
+// Stats is a point-in-time snapshot of the worker pool counters.
+type Stats struct {
+	QueueDepth int           // number of jobs waiting in the queue
+	Capacity   int           // size of the job queue buffer
+	Processed  uint64        // total number of jobs processed since start
+	Failed     uint64        // total number of jobs that returned an error
+	LastWait   time.Duration // time the most recently started job spent in the queue
+	Rejected   uint64        // total number of jobs TrySubmit could not queue
+	Dropped    uint64        // total number of queued jobs discarded by OverflowDropOldest
+	Scheduled  int           // number of jobs waiting for their SubmitAt time
+	Panicked   uint64        // total number of jobs that panicked, also counted as failed
+}
+
+// queuedJob is a job together with the time it was submitted, used to
+// measure queue lag.
+type queuedJob struct {
+	job        Job
+	enqueuedAt time.Time
 }
 
 // WorkerPool manages a fixed number of workers that process
-// submitted jobs concurrently.
+// submitted jobs concurrently. Each priority has its own queue, so a full
+// queue of bulk jobs does not hold back transactional ones.
 type WorkerPool struct {
-	workers int             // number of worker goroutines
-	jobs    chan Job        // channel used to queue jobs
-	wg      *sync.WaitGroup // wait group to track job completion
+	workers int               // number of worker goroutines started by Start
+	queues  [3]chan queuedJob // queues of high, normal and low priority jobs, in that order
+	wg      *sync.WaitGroup   // wait group to track job completion
+	started atomic.Bool       // set by Start
+	closed  atomic.Bool       // set by Shutdown
+
+	// submitting is held for reading while a job is submitted and for
+	// writing by Shutdown, so that no job is sent on a closed queue.
+	submitting sync.RWMutex
+
+	overflow   Overflow      // behaviour of TrySubmit when the queue is full
+	jobTimeout time.Duration // time limit of jobs that are not TimeLimited
+
+	mu        sync.Mutex      // guards scheduled and retire
+	scheduled delayedJobs     // jobs submitted with SubmitAt, earliest first
+	retire    []chan struct{} // closed to stop a running worker, one per worker
+	wake      chan struct{}   // signals the scheduler that the earliest job changed
+	stop      chan struct{}   // closed by Shutdown to stop the scheduler
+	stopped   chan struct{}   // closed when the scheduler has returned
+
+	processed atomic.Uint64 // number of processed jobs
+	rejected  atomic.Uint64 // number of jobs rejected by TrySubmit
+	dropped   atomic.Uint64 // number of queued jobs dropped by TrySubmit
+	failed    atomic.Uint64 // number of failed jobs
+	panicked  atomic.Uint64 // number of jobs that panicked
+	lastWait  atomic.Int64  // queue wait of the last started job, in nanoseconds
+}
+
+// DefaultQueueSize is the size of the job queue buffer when none is configured.
+const DefaultQueueSize = 100
+
+// NewWorkerPool creates a worker pool from the number of workers and the
+// size of the job queue of each priority, as read from the WORKERS and
+// BUFFER_SIZE environment variables. Empty values default to
+// runtime.NumCPU() workers and queues of DefaultQueueSize jobs.
+//
+// It returns an error if a value is not an integer, or if there would be no
+// worker or a negative queue size. A queue size of 0 makes Submit wait for
+// an idle worker.
+func NewWorkerPool(workersStr, sizeStr string, wg *sync.WaitGroup) (*WorkerPool, error) {
+	workers, err := parseSetting("WORKERS", workersStr, runtime.NumCPU())
+	if err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		return nil, fmt.Errorf("WORKERS must be at least 1, got %d", workers)
+	}
+
+	size, err := parseSetting("BUFFER_SIZE", sizeStr, DefaultQueueSize)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("BUFFER_SIZE must not be negative, got %d", size)
+	}
+
+	return New(workers, size, wg), nil
+}
+
+// New creates a WorkerPool of workers workers with queues of size jobs per
+// priority. The sizes must already be validated, as by config.Load.
+func New(workers, size int, wg *sync.WaitGroup) *WorkerPool {
+	wp := &WorkerPool{
+		workers:    workers,
+		wg:         wg,
+		overflow:   Overflow{Policy: OverflowBlock, Timeout: DefaultOverflowTimeout},
+		jobTimeout: DefaultJobTimeout,
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	for i := range wp.queues {
+		wp.queues[i] = make(chan queuedJob, size)
+	}
+	return wp
+}
+
+// queue returns the queue of jobs of priority p.
+func (wp *WorkerPool) queue(p Priority) chan queuedJob {
+	switch {
+	case p > PriorityNormal:
+		return wp.queues[0]
+	case p < PriorityNormal:
+		return wp.queues[2]
+	default:
+		return wp.queues[1]
+	}
 }
 
-func NewWorkerPool(workersStr, sizeStr string, wg *sync.WaitGroup) *WorkerPool {
-	workers, _ := strconv.Atoi(workersStr)
-	size, _ := strconv.Atoi(sizeStr)
+// parseSetting parses the integer setting name, returning def when value is empty.
+func parseSetting(name, value string, def int) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", name, value)
+	}
+	return n, nil
+}
 
-	return &WorkerPool{
-		workers: workers,
-		jobs:    make(chan Job, size),
-		wg:      wg,
+// SetOverflow configures what TrySubmit does when the queue is full. It
+// must be called before jobs are submitted.
+func (wp *WorkerPool) SetOverflow(overflow Overflow) {
+	if overflow.Policy == "" {
+		overflow.Policy = OverflowBlock
+	}
+	if overflow.Timeout <= 0 {
+		overflow.Timeout = DefaultOverflowTimeout
+	}
+	wp.overflow = overflow
+}
+
+// SetJobTimeout sets the time limit of jobs that are not TimeLimited. It
+// must be called before the pool is started.
+func (wp *WorkerPool) SetJobTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultJobTimeout
+	}
+	wp.jobTimeout = timeout
+}
+
+// Workers returns the number of worker goroutines of the pool.
+func (wp *WorkerPool) Workers() int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if !wp.started.Load() {
+		return wp.workers
+	}
+	return len(wp.retire)
+}
+
+// Resize changes the number of worker goroutines of a started pool, such as
+// when the configuration is reloaded. Removed workers finish the job they
+// are processing before they stop, so that no job is interrupted. It is a
+// no-op once the pool is shut down.
+func (wp *WorkerPool) Resize(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if !wp.started.Load() {
+		wp.workers = workers
+		return
+	}
+	if wp.closed.Load() {
+		return
+	}
+
+	for len(wp.retire) < workers {
+		retire := make(chan struct{})
+		wp.retire = append(wp.retire, retire)
+		go wp.worker(len(wp.retire)-1, retire)
+	}
+	for len(wp.retire) > workers {
+		last := len(wp.retire) - 1
+		close(wp.retire[last])
+		wp.retire = wp.retire[:last]
+	}
+}
+
+// QueueDepth returns the number of jobs waiting in the queues.
+func (wp *WorkerPool) QueueDepth() int {
+	depth := 0
+	for _, queue := range wp.queues {
+		depth += len(queue)
+	}
+	return depth
+}
+
+// QueueCapacity returns the total size of the job queue buffers.
+func (wp *WorkerPool) QueueCapacity() int {
+	capacity := 0
+	for _, queue := range wp.queues {
+		capacity += cap(queue)
+	}
+	return capacity
+}
+
+// next waits for the next job to process, taking jobs of higher priority
+// first. It returns false once the pool is shut down and every queue is
+// drained, or once retire is closed.
+func (wp *WorkerPool) next(retire <-chan struct{}) (queuedJob, bool) {
+	for {
+		select {
+		case <-retire:
+			return queuedJob{}, false
+		default:
+		}
+
+		for _, queue := range wp.queues {
+			select {
+			case queued, ok := <-queue:
+				if ok {
+					return queued, true
+				}
+			default:
+			}
+		}
+
+		if wp.closed.Load() && wp.QueueDepth() == 0 {
+			return queuedJob{}, false
+		}
+
+		select {
+		case <-retire:
+			return queuedJob{}, false
+		case queued, ok := <-wp.queues[0]:
+			if ok {
+				return queued, true
+			}
+		case queued, ok := <-wp.queues[1]:
+			if ok {
+				return queued, true
+			}
+		case queued, ok := <-wp.queues[2]:
+			if ok {
+				return queued, true
+			}
+		}
 	}
 }
 
 // worker runs as a goroutine and continuously processes jobs
-// received from the job channel until the channel is closed.
-func (wp *WorkerPool) worker(i int) {
-	for job := range wp.jobs {
-		slog.Info("Worker processes job", "worker", i)
-		err := job.Process()
+// received from the job queues until the pool is shut down or retire is
+// closed.
+func (wp *WorkerPool) worker(i int, retire <-chan struct{}) {
+	for {
+		queued, ok := wp.next(retire)
+		if !ok {
+			return
+		}
+		wp.lastWait.Store(int64(time.Since(queued.enqueuedAt)))
+
+		slog.Info("Worker processes job", "worker", i, "priority", priorityOf(queued.job))
+		err := wp.process(i, queued.job)
 		if err != nil {
+			wp.failed.Add(1)
 			log.Println("Error while processing the job:", err)
 			slog.Warn("Error while processing the job:", "error", err)
 		}
+		wp.processed.Add(1)
 		wp.wg.Done()
 	}
 }
 
-// Start launches all worker goroutines.
+// process runs job within its time limit, recovering from a panic so that
+// the worker keeps processing jobs. A panic is logged with its stack and
+// returned as an error.
+func (wp *WorkerPool) process(worker int, job Job) (err error) {
+	timeout := wp.jobTimeout
+	if limited, ok := job.(TimeLimited); ok {
+		timeout = limited.Timeout()
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			wp.panicked.Add(1)
+			slog.Error("job panicked", "worker", worker, "job", fmt.Sprintf("%T", job), "panic", recovered, "stack", string(debug.Stack()))
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+
+	err = job.Process(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("job exceeded its %s timeout: %w", timeout, err)
+	}
+	return err
+}
+
+// Start launches all worker goroutines and the scheduler of delayed jobs.
 // This method should be called before submitting jobs.
 func (wp *WorkerPool) Start() {
+	wp.mu.Lock()
 	for i := 0; i < wp.workers; i++ {
-		go wp.worker(i)
+		retire := make(chan struct{})
+		wp.retire = append(wp.retire, retire)
+		go wp.worker(i, retire)
 	}
+	wp.started.Store(true)
+	wp.mu.Unlock()
+
+	go wp.schedule()
 }
 
-// Submit adds a job to the worker pool queue.
-// It increments the WaitGroup counter before enqueuing the job.
+// Submit adds a job to the queue of its priority.
+// It increments the WaitGroup counter before enqueuing the job. Jobs
+// submitted once the pool is shut down are dropped with a warning.
 func (wp *WorkerPool) Submit(job Job) {
+	wp.submitting.RLock()
+	defer wp.submitting.RUnlock()
+
+	if wp.closed.Load() {
+		slog.Warn("worker pool shut down, dropped job", "job", fmt.Sprintf("%T", job), "priority", priorityOf(job))
+		return
+	}
 	wp.wg.Add(1)
-	wp.jobs <- job
+	wp.queue(priorityOf(job)) <- queuedJob{job: job, enqueuedAt: time.Now()}
 }
 
-// Shutdown closes the job channel, signaling workers
-// that no more jobs will be submitted.
+// TrySubmit adds a job to the queue of its priority, applying the overflow
+// policy when that queue is full: OverflowBlock waits up to the overflow
+// timeout, OverflowReject fails immediately and OverflowDropOldest discards
+// the oldest queued jobs. Drop-oldest on an unbuffered queue rejects.
+//
+// It returns ErrQueueFull if the job was not queued, or ErrShutdown once the
+// pool is shut down.
+func (wp *WorkerPool) TrySubmit(job Job) error {
+	wp.submitting.RLock()
+	defer wp.submitting.RUnlock()
+
+	if wp.closed.Load() {
+		wp.rejected.Add(1)
+		return ErrShutdown
+	}
+	wp.wg.Add(1)
+	queued := queuedJob{job: job, enqueuedAt: time.Now()}
+	jobs := wp.queue(priorityOf(job))
+
+	select {
+	case jobs <- queued:
+		return nil
+	default:
+	}
+
+	switch wp.overflow.Policy {
+	case OverflowBlock:
+		timer := time.NewTimer(wp.overflow.Timeout)
+		defer timer.Stop()
+
+		select {
+		case jobs <- queued:
+			return nil
+		case <-timer.C:
+		}
+	case OverflowDropOldest:
+		for cap(jobs) > 0 {
+			select {
+			case jobs <- queued:
+				return nil
+			default:
+			}
+
+			// The queue was full: make room by discarding its oldest job.
+			select {
+			case oldest := <-jobs:
+				wp.dropped.Add(1)
+				wp.wg.Done()
+				slog.Warn("job queue saturated, dropped oldest job",
+					"job", fmt.Sprintf("%T", oldest.job), "priority", priorityOf(job), "queued_for", time.Since(oldest.enqueuedAt), "capacity", cap(jobs))
+			default:
+			}
+		}
+	}
+
+	wp.rejected.Add(1)
+	wp.wg.Done()
+	slog.Warn("job queue saturated, rejected job",
+		"job", fmt.Sprintf("%T", job), "priority", priorityOf(job), "policy", wp.overflow.Policy, "depth", len(jobs), "capacity", cap(jobs))
+	return ErrQueueFull
+}
+
+// delayedJob is a job submitted with SubmitAt.
+type delayedJob struct {
+	job Job
+	at  time.Time
+}
+
+// delayedJobs is a min-heap of delayed jobs ordered by time.
+type delayedJobs []delayedJob
+
+func (d delayedJobs) Len() int           { return len(d) }
+func (d delayedJobs) Less(i, j int) bool { return d[i].at.Before(d[j].at) }
+func (d delayedJobs) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d *delayedJobs) Push(x any)        { *d = append(*d, x.(delayedJob)) }
+func (d *delayedJobs) Pop() any {
+	old := *d
+	last := old[len(old)-1]
+	*d = old[:len(old)-1]
+	return last
+}
+
+// SubmitAt queues job at the given time, with Submit. Jobs still waiting
+// when the pool is shut down are discarded.
+func (wp *WorkerPool) SubmitAt(job Job, at time.Time) {
+	wp.mu.Lock()
+	heap.Push(&wp.scheduled, delayedJob{job: job, at: at})
+	earliest := wp.scheduled[0].at.Equal(at)
+	wp.mu.Unlock()
+
+	if earliest {
+		select {
+		case wp.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SubmitAfter queues job once delay has elapsed. See SubmitAt.
+func (wp *WorkerPool) SubmitAfter(job Job, delay time.Duration) {
+	wp.SubmitAt(job, time.Now().Add(delay))
+}
+
+// due pops the scheduled jobs whose time has come and returns them with the
+// time of the next scheduled job, zero if there is none.
+func (wp *WorkerPool) due(now time.Time) ([]Job, time.Time) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	var jobs []Job
+	for len(wp.scheduled) > 0 && !wp.scheduled[0].at.After(now) {
+		jobs = append(jobs, heap.Pop(&wp.scheduled).(delayedJob).job)
+	}
+	if len(wp.scheduled) == 0 {
+		return jobs, time.Time{}
+	}
+	return jobs, wp.scheduled[0].at
+}
+
+// schedule runs as a goroutine and submits delayed jobs when they are due,
+// until Shutdown is called.
+func (wp *WorkerPool) schedule() {
+	defer close(wp.stopped)
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		jobs, next := wp.due(time.Now())
+		for _, job := range jobs {
+			wp.Submit(job)
+		}
+
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-wp.stop:
+			return
+		case <-wp.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// Stats returns a snapshot of the queue and job counters.
+func (wp *WorkerPool) Stats() Stats {
+	wp.mu.Lock()
+	scheduled := len(wp.scheduled)
+	wp.mu.Unlock()
+
+	return Stats{
+		QueueDepth: wp.QueueDepth(),
+		Capacity:   wp.QueueCapacity(),
+		Processed:  wp.processed.Load(),
+		Failed:     wp.failed.Load(),
+		LastWait:   time.Duration(wp.lastWait.Load()),
+		Rejected:   wp.rejected.Load(),
+		Dropped:    wp.dropped.Load(),
+		Scheduled:  scheduled,
+		Panicked:   wp.panicked.Load(),
+	}
+}
+
+// Shutdown stops the scheduler and closes the job queues, signaling
+// workers that no more jobs will be submitted. Delayed jobs that are not
+// due yet are discarded, and so are jobs submitted afterwards. It waits for
+// submissions in progress to be queued.
 func (wp *WorkerPool) Shutdown() {
-	close(wp.jobs)
+	close(wp.stop)
+	if wp.started.Load() {
+		<-wp.stopped
+	}
+
+	wp.mu.Lock()
+	if pending := len(wp.scheduled); pending > 0 {
+		slog.Warn("discarding delayed jobs on shutdown", "jobs", pending)
+	}
+	wp.scheduled = nil
+	wp.mu.Unlock()
+
+	wp.submitting.Lock()
+	defer wp.submitting.Unlock()
+
+	wp.closed.Store(true)
+	for _, queue := range wp.queues {
+		close(queue)
+	}
 }
 
 // Wait blocks until all submitted jobs have finished processing.
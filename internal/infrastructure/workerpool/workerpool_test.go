@@ -0,0 +1,376 @@
+package workerpool
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWorkerPool_Defaults(t *testing.T) {
+	wp, err := NewWorkerPool("", " ", &sync.WaitGroup{})
+	require.NoError(t, err)
+
+	assert.Equal(t, runtime.NumCPU(), wp.Workers())
+	assert.Equal(t, 3*DefaultQueueSize, wp.QueueCapacity())
+	assert.Equal(t, 0, wp.QueueDepth())
+}
+
+func TestNewWorkerPool_Invalid(t *testing.T) {
+	tests := []struct {
+		name, workers, size string
+	}{
+		{"workers not a number", "four", "10"},
+		{"no workers", "0", "10"},
+		{"negative workers", "-2", "10"},
+		{"size not a number", "2", "big"},
+		{"negative size", "2", "-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wp, err := NewWorkerPool(tt.workers, tt.size, &sync.WaitGroup{})
+			assert.Error(t, err)
+			assert.Nil(t, wp)
+		})
+	}
+}
+
+type jobFunc func() error
+
+func (f jobFunc) Process(context.Context) error { return f() }
+
+func TestWorkerPool_ProcessesJobs(t *testing.T) {
+	wp, err := NewWorkerPool("2", "0", &sync.WaitGroup{})
+	require.NoError(t, err)
+	wp.Start()
+
+	var mu sync.Mutex
+	done := 0
+	for i := 0; i < 5; i++ {
+		wp.Submit(jobFunc(func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			return nil
+		}))
+	}
+	wp.Shutdown()
+	wp.Wait()
+
+	assert.Equal(t, 5, done)
+	assert.Equal(t, uint64(5), wp.Stats().Processed)
+}
+
+func TestParseOverflow(t *testing.T) {
+	overflow, err := ParseOverflow("", "")
+	require.NoError(t, err)
+	assert.Equal(t, Overflow{Policy: OverflowBlock, Timeout: DefaultOverflowTimeout}, overflow)
+
+	overflow, err = ParseOverflow("drop-oldest", "250ms")
+	require.NoError(t, err)
+	assert.Equal(t, Overflow{Policy: OverflowDropOldest, Timeout: 250 * time.Millisecond}, overflow)
+
+	_, err = ParseOverflow("discard", "")
+	assert.Error(t, err)
+	_, err = ParseOverflow("block", "-1s")
+	assert.Error(t, err)
+}
+
+// fullPool returns a pool whose single queue slot is taken by a job, with no
+// worker started.
+func fullPool(t *testing.T, overflow Overflow) (*WorkerPool, Job) {
+	t.Helper()
+
+	wp, err := NewWorkerPool("1", "1", &sync.WaitGroup{})
+	require.NoError(t, err)
+	wp.SetOverflow(overflow)
+
+	first := jobFunc(func() error { return nil })
+	require.NoError(t, wp.TrySubmit(first))
+	return wp, first
+}
+
+func TestTrySubmit_Reject(t *testing.T) {
+	wp, _ := fullPool(t, Overflow{Policy: OverflowReject})
+
+	assert.ErrorIs(t, wp.TrySubmit(jobFunc(func() error { return nil })), ErrQueueFull)
+	assert.Equal(t, uint64(1), wp.Stats().Rejected)
+	assert.Equal(t, 1, wp.QueueDepth())
+}
+
+func TestTrySubmit_BlockTimesOut(t *testing.T) {
+	wp, _ := fullPool(t, Overflow{Policy: OverflowBlock, Timeout: 20 * time.Millisecond})
+
+	start := time.Now()
+	assert.ErrorIs(t, wp.TrySubmit(jobFunc(func() error { return nil })), ErrQueueFull)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestTrySubmit_DropOldest(t *testing.T) {
+	wp, _ := fullPool(t, Overflow{Policy: OverflowDropOldest})
+
+	ran := false
+	require.NoError(t, wp.TrySubmit(jobFunc(func() error { ran = true; return nil })))
+	assert.Equal(t, uint64(1), wp.Stats().Dropped)
+
+	wp.Start()
+	wp.Shutdown()
+	wp.Wait()
+
+	assert.True(t, ran)
+	assert.Equal(t, uint64(1), wp.Stats().Processed)
+}
+
+type prioritizedJob struct {
+	jobFunc
+	priority Priority
+}
+
+func (j prioritizedJob) Priority() Priority { return j.priority }
+
+func TestWorkerPool_HigherPrioritiesFirst(t *testing.T) {
+	wp, err := NewWorkerPool("1", "10", &sync.WaitGroup{})
+	require.NoError(t, err)
+
+	var order []string
+	record := func(name string) jobFunc {
+		return func() error { order = append(order, name); return nil }
+	}
+	wp.Submit(prioritizedJob{record("campaign"), PriorityLow})
+	wp.Submit(record("export"))
+	wp.Submit(prioritizedJob{record("confirmation"), PriorityHigh})
+	assert.Equal(t, 3, wp.QueueDepth())
+	assert.Equal(t, 30, wp.QueueCapacity())
+
+	// A single worker started after the jobs were queued takes them by priority
+	wp.Start()
+	wp.Shutdown()
+	wp.Wait()
+
+	assert.Equal(t, []string{"confirmation", "export", "campaign"}, order)
+}
+
+func TestTrySubmit_FullLowQueueDoesNotRejectHighPriority(t *testing.T) {
+	wp, err := NewWorkerPool("1", "1", &sync.WaitGroup{})
+	require.NoError(t, err)
+	wp.SetOverflow(Overflow{Policy: OverflowReject})
+
+	require.NoError(t, wp.TrySubmit(prioritizedJob{jobFunc(func() error { return nil }), PriorityLow}))
+	assert.ErrorIs(t, wp.TrySubmit(prioritizedJob{jobFunc(func() error { return nil }), PriorityLow}), ErrQueueFull)
+	assert.NoError(t, wp.TrySubmit(prioritizedJob{jobFunc(func() error { return nil }), PriorityHigh}))
+}
+
+func TestSubmitAt_RunsJobsInTimeOrder(t *testing.T) {
+	wp, err := NewWorkerPool("1", "10", &sync.WaitGroup{})
+	require.NoError(t, err)
+	wp.Start()
+
+	var mu sync.Mutex
+	var order []string
+	done := make(chan struct{}, 3)
+	record := func(name string) jobFunc {
+		return func() error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			done <- struct{}{}
+			return nil
+		}
+	}
+
+	now := time.Now()
+	wp.SubmitAt(record("third"), now.Add(60*time.Millisecond))
+	wp.SubmitAfter(record("second"), 30*time.Millisecond)
+	wp.SubmitAt(record("first"), now.Add(-time.Second))
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("delayed jobs did not run")
+		}
+	}
+	wp.Shutdown()
+	wp.Wait()
+
+	assert.Equal(t, []string{"first", "second", "third"}, order)
+	assert.Equal(t, 0, wp.Stats().Scheduled)
+}
+
+func TestShutdown_DiscardsPendingDelayedJobs(t *testing.T) {
+	wp, err := NewWorkerPool("1", "10", &sync.WaitGroup{})
+	require.NoError(t, err)
+	wp.Start()
+
+	ran := false
+	wp.SubmitAfter(jobFunc(func() error { ran = true; return nil }), time.Hour)
+	assert.Equal(t, 1, wp.Stats().Scheduled)
+
+	wp.Shutdown()
+	wp.Wait()
+
+	assert.False(t, ran)
+	assert.Equal(t, 0, wp.Stats().Scheduled)
+}
+
+func TestSubmit_AfterShutdown(t *testing.T) {
+	wp, err := NewWorkerPool("1", "10", &sync.WaitGroup{})
+	require.NoError(t, err)
+	wp.Start()
+	wp.Shutdown()
+
+	ran := false
+	job := jobFunc(func() error { ran = true; return nil })
+
+	assert.NotPanics(t, func() { wp.Submit(job) })
+	assert.ErrorIs(t, wp.TrySubmit(job), ErrShutdown)
+	wp.Wait()
+
+	assert.False(t, ran)
+	assert.Equal(t, uint64(1), wp.Stats().Rejected)
+}
+
+func TestSubmit_DuringShutdown(t *testing.T) {
+	wp, err := NewWorkerPool("2", "1", &sync.WaitGroup{})
+	require.NoError(t, err)
+	wp.Start()
+
+	var submitted sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		submitted.Add(1)
+		go func() {
+			defer submitted.Done()
+			wp.Submit(jobFunc(func() error { return nil }))
+			_ = wp.TrySubmit(jobFunc(func() error { return nil }))
+		}()
+	}
+	wp.Shutdown()
+	submitted.Wait()
+	wp.Wait()
+}
+
+func TestWorkerPool_RecoversFromPanics(t *testing.T) {
+	wp, err := NewWorkerPool("1", "10", &sync.WaitGroup{})
+	require.NoError(t, err)
+
+	ran := false
+	wp.Submit(jobFunc(func() error { panic("boom") }))
+	wp.Submit(jobFunc(func() error { ran = true; return nil }))
+
+	wp.Start()
+	wp.Shutdown()
+	wp.Wait()
+
+	// The worker survives the panic and processes the next job
+	assert.True(t, ran)
+	stats := wp.Stats()
+	assert.Equal(t, uint64(2), stats.Processed)
+	assert.Equal(t, uint64(1), stats.Failed)
+	assert.Equal(t, uint64(1), stats.Panicked)
+}
+
+type timeLimitedJob struct {
+	timeout time.Duration
+	err     chan error
+}
+
+func (j timeLimitedJob) Timeout() time.Duration { return j.timeout }
+
+func (j timeLimitedJob) Process(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		j.err <- ctx.Err()
+	case <-time.After(100 * time.Millisecond):
+		j.err <- nil
+	}
+	return nil
+}
+
+func TestWorkerPool_JobTimeout(t *testing.T) {
+	wp, err := NewWorkerPool("1", "10", &sync.WaitGroup{})
+	require.NoError(t, err)
+	wp.SetJobTimeout(10 * time.Millisecond)
+	wp.Start()
+
+	// The pool timeout cancels the context of jobs without their own limit
+	cancelled := make(chan error, 1)
+	wp.Submit(jobFunc(func() error { return nil }))
+	wp.Submit(timeLimitedJob{timeout: 0, err: cancelled})
+	defaulted := make(chan error, 1)
+	wp.Submit(pooledTimeoutJob{err: defaulted})
+	wp.Shutdown()
+	wp.Wait()
+
+	assert.NoError(t, <-cancelled, "jobs with a zero Timeout are not limited")
+	assert.ErrorIs(t, <-defaulted, context.DeadlineExceeded)
+	assert.Equal(t, uint64(1), wp.Stats().Failed)
+}
+
+// pooledTimeoutJob waits for its context to be cancelled by the pool timeout.
+type pooledTimeoutJob struct {
+	err chan error
+}
+
+func (j pooledTimeoutJob) Process(ctx context.Context) error {
+	<-ctx.Done()
+	j.err <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestParseJobTimeout(t *testing.T) {
+	timeout, err := ParseJobTimeout("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultJobTimeout, timeout)
+
+	timeout, err = ParseJobTimeout("30s")
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, timeout)
+
+	_, err = ParseJobTimeout("0s")
+	assert.Error(t, err)
+}
+
+func TestWorkerPool_Resize(t *testing.T) {
+	wp := New(2, 10, &sync.WaitGroup{})
+	wp.Start()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	blocking := jobFunc(func() error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+
+	// A removed worker finishes its job before it stops.
+	wp.Submit(blocking)
+	<-started
+	wp.Resize(1)
+	assert.Equal(t, 1, wp.Workers())
+	close(release)
+	require.Eventually(t, func() bool { return wp.Stats().Processed == 1 }, time.Second, time.Millisecond)
+
+	release = make(chan struct{})
+	wp.Resize(3)
+	assert.Equal(t, 3, wp.Workers())
+	for range 3 {
+		wp.Submit(blocking)
+	}
+	for range 3 {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("jobs were not processed concurrently by the added workers")
+		}
+	}
+	close(release)
+
+	wp.Shutdown()
+	wp.Wait()
+	assert.Equal(t, uint64(4), wp.Stats().Processed)
+}
@@ -0,0 +1,293 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"newsletter/internal/limits/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newsletterBatch is the page size used to list every newsletter of a user.
+const newsletterBatch = 100
+
+// planCacheTTL is how long the plan of a user is kept once looked up, as it
+// is needed by every request of the user for rate limiting. Plans set by
+// another instance apply after at most this long.
+const planCacheTTL = time.Minute
+
+// cachedPlan is a plan looked up for a user.
+type cachedPlan struct {
+	plan    domain.Plan
+	expires time.Time
+}
+
+// LimitService resolves the plan of users and enforces its limits.
+type LimitService struct {
+	plan  domain.Plan            // Default plan
+	plans map[string]domain.Plan // Every plan, by name
+	ps    domain.PlanStore
+	nr    domain.NewsletterStore
+	sc    newsletterdomain.SubscriberCounter
+	ec    domain.EmailCounter
+
+	mu        sync.Mutex
+	cached    map[uuid.UUID]cachedPlan
+	lastPurge time.Time
+}
+
+// NewLimitService creates a LimitService putting every user on plan, the
+// default plan, until SetPlans provides the plans of users. ec may be nil
+// when campaigns are unavailable, in which case emails are not counted.
+func NewLimitService(plan domain.Plan, nr domain.NewsletterStore, sc newsletterdomain.SubscriberCounter, ec domain.EmailCounter) *LimitService {
+	return &LimitService{
+		plan: plan, plans: map[string]domain.Plan{plan.Name: plan},
+		nr: nr, sc: sc, ec: ec,
+		cached: make(map[uuid.UUID]cachedPlan),
+	}
+}
+
+// SetPlans makes the service look up the plan of each user in ps, among
+// the default plan and plans. Users on a plan that is no longer configured
+// are on the default plan.
+func (ls *LimitService) SetPlans(ps domain.PlanStore, plans ...domain.Plan) {
+	ls.ps = ps
+	for _, plan := range plans {
+		ls.plans[plan.Name] = plan
+	}
+}
+
+// PlanOf returns the plan of a user, looked up at most once per
+// planCacheTTL. Without a plan store, every user is on the default plan.
+func (ls *LimitService) PlanOf(userID uuid.UUID) (domain.Plan, error) {
+	if ls.ps == nil {
+		return ls.plan, nil
+	}
+
+	now := time.Now()
+	ls.mu.Lock()
+	cached, ok := ls.cached[userID]
+	ls.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.plan, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	name, err := ls.ps.PlanName(ctx, userID)
+	if err != nil {
+		slog.Error("failed to get plan", "user_id", userID, "error", err)
+		return ls.plan, err
+	}
+	plan, ok := ls.plans[name]
+	if !ok {
+		slog.Warn("user on a plan that is not configured, using the default plan", "user_id", userID, "plan", name)
+		plan = ls.plan
+	}
+
+	ls.cache(now, userID, plan)
+	return plan, nil
+}
+
+// SetPlan puts a user on the plan with the given name.
+func (ls *LimitService) SetPlan(userID uuid.UUID, name string) (domain.Plan, error) {
+	plan, ok := ls.plans[name]
+	if !ok {
+		return domain.Plan{}, fmt.Errorf("%w: %q", domain.ErrUnknownPlan, name)
+	}
+	if ls.ps == nil {
+		return domain.Plan{}, domain.ErrPlansNotStored
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := ls.ps.SetPlanName(ctx, userID, name); err != nil {
+		slog.Error("failed to set plan", "user_id", userID, "plan", name, "error", err)
+		return domain.Plan{}, err
+	}
+
+	ls.cache(time.Now(), userID, plan)
+	slog.Info("user plan changed", "user_id", userID, "plan", name)
+	return plan, nil
+}
+
+// cache keeps the plan of a user for planCacheTTL, forgetting expired
+// plans at most once per planCacheTTL.
+func (ls *LimitService) cache(now time.Time, userID uuid.UUID, plan domain.Plan) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if now.Sub(ls.lastPurge) >= planCacheTTL {
+		ls.lastPurge = now
+		for id, cached := range ls.cached {
+			if !now.Before(cached.expires) {
+				delete(ls.cached, id)
+			}
+		}
+	}
+	ls.cached[userID] = cachedPlan{plan: plan, expires: now.Add(planCacheTTL)}
+}
+
+// ownerPlan returns the plan of the owner of a newsletter, and the owner.
+func (ls *LimitService) ownerPlan(ctx context.Context, newsletterID uuid.UUID) (domain.Plan, uuid.UUID, error) {
+	newsletter, err := ls.nr.Get(ctx, newsletterID)
+	if err != nil {
+		return domain.Plan{}, uuid.Nil, err
+	}
+	plan, err := ls.PlanOf(newsletter.OwnerID)
+	if err != nil {
+		return domain.Plan{}, uuid.Nil, err
+	}
+	return plan, newsletter.OwnerID, nil
+}
+
+// CheckNewsletters counts the newsletters of the owner. The limit is soft:
+// see domain.Enforcer.
+func (ls *LimitService) CheckNewsletters(ownerID uuid.UUID) error {
+	plan, err := ls.PlanOf(ownerID)
+	if err != nil {
+		return err
+	}
+	limit := plan.Limits.Newsletters
+	if limit == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	count, err := ls.nr.Count(ctx, ownerID, newsletterdomain.NewsletterFilter{})
+	if err != nil {
+		slog.Error("failed to count newsletters", "owner_id", ownerID, "error", err)
+		return err
+	}
+	if count >= limit {
+		return fmt.Errorf("%w: %d of %d newsletters", domain.ErrNewsletterLimit, count, limit)
+	}
+	return nil
+}
+
+// CheckSubscribers counts the active subscribers of the newsletter. The
+// limit is soft: see domain.Enforcer.
+func (ls *LimitService) CheckSubscribers(newsletterID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	plan, _, err := ls.ownerPlan(ctx, newsletterID)
+	if err != nil {
+		return err
+	}
+	limit := plan.Limits.SubscribersPerNewsletter
+	if limit == 0 {
+		return nil
+	}
+
+	counts, err := ls.sc.CountActive(ctx, []uuid.UUID{newsletterID})
+	if err != nil {
+		slog.Error("failed to count subscribers", "newsletter_id", newsletterID, "error", err)
+		return err
+	}
+	if counts[newsletterID] >= limit {
+		slog.Warn("subscription refused by plan limit", "newsletter_id", newsletterID, "limit", limit)
+		return domain.ErrSubscriberLimit
+	}
+	return nil
+}
+
+// CheckEmails counts the emails sent since the start of the month by every
+// newsletter of the owner, and the active subscribers the campaign would
+// email. Campaigns are refused as a whole rather than stopped midway.
+func (ls *LimitService) CheckEmails(newsletterID uuid.UUID) error {
+	if ls.ec == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	plan, ownerID, err := ls.ownerPlan(ctx, newsletterID)
+	if err != nil {
+		return err
+	}
+	limit := plan.Limits.EmailsPerMonth
+	if limit == 0 {
+		return nil
+	}
+
+	sent, err := ls.ec.CountEmailsSent(ctx, ownerID, domain.MonthStart(time.Now()))
+	if err != nil {
+		slog.Error("failed to count emails sent", "owner_id", ownerID, "error", err)
+		return err
+	}
+	counts, err := ls.sc.CountActive(ctx, []uuid.UUID{newsletterID})
+	if err != nil {
+		slog.Error("failed to count subscribers", "newsletter_id", newsletterID, "error", err)
+		return err
+	}
+	if recipients := counts[newsletterID]; sent+recipients > limit {
+		return fmt.Errorf("%w: %d of %d emails sent this month, the campaign would send %d", domain.ErrEmailLimit, sent, limit, recipients)
+	}
+	return nil
+}
+
+// Report counts the newsletters of the user, their active subscribers and
+// the emails sent this month.
+func (ls *LimitService) Report(userID uuid.UUID) (*domain.Report, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	plan, err := ls.PlanOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	report := &domain.Report{
+		Plan:        plan.Name,
+		Limits:      plan.Limits,
+		Usage:       domain.Usage{Subscribers: map[uuid.UUID]int{}},
+		PeriodStart: domain.MonthStart(time.Now()),
+	}
+
+	var ids []uuid.UUID
+	for batch := 1; ; batch++ {
+		newsletters, err := ls.nr.GetAll(ctx, userID, newsletterdomain.NewsletterFilter{}, newsletterdomain.NewsletterSort{Field: newsletterdomain.SortCreatedAt}, newsletterBatch, batch)
+		if err != nil {
+			slog.Error("failed to list newsletters", "owner_id", userID, "error", err)
+			return nil, err
+		}
+		for _, newsletter := range newsletters {
+			ids = append(ids, newsletter.ID)
+		}
+		if len(newsletters) < newsletterBatch {
+			break
+		}
+	}
+	report.Usage.Newsletters = len(ids)
+
+	if len(ids) > 0 {
+		counts, err := ls.sc.CountActive(ctx, ids)
+		if err != nil {
+			slog.Error("failed to count subscribers", "owner_id", userID, "error", err)
+			return nil, err
+		}
+		for _, id := range ids {
+			report.Usage.Subscribers[id] = counts[id]
+		}
+	}
+
+	if ls.ec != nil {
+		sent, err := ls.ec.CountEmailsSent(ctx, userID, report.PeriodStart)
+		if err != nil {
+			slog.Error("failed to count emails sent", "owner_id", userID, "error", err)
+			return nil, err
+		}
+		report.Usage.EmailsThisMonth = sent
+	}
+
+	return report, nil
+}
@@ -0,0 +1,284 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/limits/application"
+	"newsletter/internal/limits/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Newsletter Store ---
+type MockNewsletterStore struct {
+	mock.Mock
+}
+
+func (m *MockNewsletterStore) GetAll(ctx context.Context, ownerID uuid.UUID, filter newsletterdomain.NewsletterFilter, sort newsletterdomain.NewsletterSort, limit, page int) ([]*newsletterdomain.Newsletter, error) {
+	args := m.Called(ownerID, page)
+	return args.Get(0).([]*newsletterdomain.Newsletter), args.Error(1)
+}
+
+func (m *MockNewsletterStore) Count(ctx context.Context, ownerID uuid.UUID, filter newsletterdomain.NewsletterFilter) (int, error) {
+	args := m.Called(ownerID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockNewsletterStore) Get(ctx context.Context, id uuid.UUID) (*newsletterdomain.Newsletter, error) {
+	args := m.Called(id)
+	newsletter := args.Get(0)
+	if newsletter == nil {
+		return nil, args.Error(1)
+	}
+	return newsletter.(*newsletterdomain.Newsletter), args.Error(1)
+}
+
+// --- Mock Subscriber Counter ---
+type MockSubscriberCounter struct {
+	mock.Mock
+}
+
+func (m *MockSubscriberCounter) CountActive(ctx context.Context, newsletterIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	args := m.Called(newsletterIDs)
+	return args.Get(0).(map[uuid.UUID]int), args.Error(1)
+}
+
+// --- Mock Email Counter ---
+type MockEmailCounter struct {
+	mock.Mock
+}
+
+func (m *MockEmailCounter) CountEmailsSent(ctx context.Context, ownerID uuid.UUID, since time.Time) (int, error) {
+	args := m.Called(ownerID, since)
+	return args.Int(0), args.Error(1)
+}
+
+// --- Mock Plan Store ---
+type MockPlanStore struct {
+	mock.Mock
+}
+
+func (m *MockPlanStore) PlanName(ctx context.Context, userID uuid.UUID) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPlanStore) SetPlanName(ctx context.Context, userID uuid.UUID, name string) error {
+	return m.Called(userID, name).Error(0)
+}
+
+var plan = domain.Plan{Name: domain.DefaultPlanName, Limits: domain.Limits{Newsletters: 2, SubscribersPerNewsletter: 100, EmailsPerMonth: 1000}}
+
+func TestCheckNewsletters(t *testing.T) {
+	mockNR := new(MockNewsletterStore)
+	ls := application.NewLimitService(plan, mockNR, nil, nil)
+	below, atLimit := uuid.New(), uuid.New()
+
+	mockNR.On("Count", below).Return(1, nil)
+	mockNR.On("Count", atLimit).Return(2, nil)
+
+	assert.NoError(t, ls.CheckNewsletters(below))
+	assert.ErrorIs(t, ls.CheckNewsletters(atLimit), domain.ErrNewsletterLimit)
+}
+
+func TestCheckNewsletters_Unlimited(t *testing.T) {
+	mockNR := new(MockNewsletterStore)
+	ls := application.NewLimitService(domain.Plan{Name: domain.DefaultPlanName}, mockNR, nil, nil)
+
+	assert.NoError(t, ls.CheckNewsletters(uuid.New()))
+	mockNR.AssertNotCalled(t, "Count", mock.Anything)
+}
+
+func TestCheckSubscribers(t *testing.T) {
+	mockNR, mockSC := new(MockNewsletterStore), new(MockSubscriberCounter)
+	ls := application.NewLimitService(plan, mockNR, mockSC, nil)
+	open, full := uuid.New(), uuid.New()
+
+	mockNR.On("Get", mock.Anything).Return(&newsletterdomain.Newsletter{OwnerID: uuid.New()}, nil)
+	mockSC.On("CountActive", []uuid.UUID{open}).Return(map[uuid.UUID]int{open: 99}, nil)
+	mockSC.On("CountActive", []uuid.UUID{full}).Return(map[uuid.UUID]int{full: 100}, nil)
+
+	assert.NoError(t, ls.CheckSubscribers(open))
+	assert.ErrorIs(t, ls.CheckSubscribers(full), domain.ErrSubscriberLimit)
+}
+
+func TestCheckEmails(t *testing.T) {
+	mockNR, mockSC, mockEC := new(MockNewsletterStore), new(MockSubscriberCounter), new(MockEmailCounter)
+	ls := application.NewLimitService(plan, mockNR, mockSC, mockEC)
+	ownerID, newsletterID := uuid.New(), uuid.New()
+
+	mockNR.On("Get", newsletterID).Return(&newsletterdomain.Newsletter{ID: newsletterID, OwnerID: ownerID}, nil)
+	monthStart := mock.MatchedBy(func(since time.Time) bool {
+		return since.Equal(domain.MonthStart(time.Now())) && since.Day() == 1
+	})
+	mockEC.On("CountEmailsSent", ownerID, monthStart).Return(900, nil)
+	mockSC.On("CountActive", []uuid.UUID{newsletterID}).Return(map[uuid.UUID]int{newsletterID: 100}, nil).Once()
+
+	assert.NoError(t, ls.CheckEmails(newsletterID), "exactly the remaining emails")
+
+	mockSC.On("CountActive", []uuid.UUID{newsletterID}).Return(map[uuid.UUID]int{newsletterID: 101}, nil).Once()
+
+	err := ls.CheckEmails(newsletterID)
+	assert.ErrorIs(t, err, domain.ErrEmailLimit)
+	assert.ErrorContains(t, err, "900 of 1000 emails sent this month, the campaign would send 101")
+}
+
+func TestCheckEmails_NotCounted(t *testing.T) {
+	ls := application.NewLimitService(plan, new(MockNewsletterStore), nil, nil)
+
+	assert.NoError(t, ls.CheckEmails(uuid.New()))
+}
+
+func TestReport(t *testing.T) {
+	mockNR, mockSC, mockEC := new(MockNewsletterStore), new(MockSubscriberCounter), new(MockEmailCounter)
+	ls := application.NewLimitService(plan, mockNR, mockSC, mockEC)
+	userID, first, second := uuid.New(), uuid.New(), uuid.New()
+
+	mockNR.On("GetAll", userID, 1).Return([]*newsletterdomain.Newsletter{{ID: first}, {ID: second}}, nil)
+	mockSC.On("CountActive", []uuid.UUID{first, second}).Return(map[uuid.UUID]int{first: 12}, nil)
+	mockEC.On("CountEmailsSent", userID, mock.Anything).Return(340, nil)
+
+	report, err := ls.Report(userID)
+
+	require.NoError(t, err)
+	assert.Equal(t, &domain.Report{
+		Plan:        domain.DefaultPlanName,
+		Limits:      plan.Limits,
+		Usage:       domain.Usage{Newsletters: 2, Subscribers: map[uuid.UUID]int{first: 12, second: 0}, EmailsThisMonth: 340},
+		PeriodStart: domain.MonthStart(time.Now()),
+	}, report)
+}
+
+func TestReport_CountFails(t *testing.T) {
+	mockNR := new(MockNewsletterStore)
+	ls := application.NewLimitService(plan, mockNR, nil, nil)
+
+	mockNR.On("GetAll", mock.Anything, 1).Return([]*newsletterdomain.Newsletter(nil), errors.New("db down"))
+
+	_, err := ls.Report(uuid.New())
+
+	assert.Error(t, err)
+}
+
+func TestMonthStart(t *testing.T) {
+	at := time.Date(2026, 3, 31, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*3600))
+
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), domain.MonthStart(at))
+}
+
+func TestPlanOf(t *testing.T) {
+	mockPS := new(MockPlanStore)
+	pro := domain.Plan{Name: "pro", Limits: domain.Limits{Newsletters: 10}}
+	ls := application.NewLimitService(plan, new(MockNewsletterStore), nil, nil)
+	ls.SetPlans(mockPS, pro)
+	free, paying, legacy := uuid.New(), uuid.New(), uuid.New()
+
+	mockPS.On("PlanName", free).Return(domain.DefaultPlanName, nil)
+	mockPS.On("PlanName", paying).Return("pro", nil).Once()
+	mockPS.On("PlanName", legacy).Return("gold", nil)
+
+	got, err := ls.PlanOf(free)
+	require.NoError(t, err)
+	assert.Equal(t, plan, got)
+
+	for range 2 {
+		got, err = ls.PlanOf(paying)
+		require.NoError(t, err)
+		assert.Equal(t, pro, got, "looked up once, then cached")
+	}
+
+	got, err = ls.PlanOf(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, plan, got, "plan no longer configured")
+}
+
+func TestPlanOf_NoStore(t *testing.T) {
+	ls := application.NewLimitService(plan, new(MockNewsletterStore), nil, nil)
+
+	got, err := ls.PlanOf(uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, plan, got)
+}
+
+func TestPlanOf_Fails(t *testing.T) {
+	mockPS := new(MockPlanStore)
+	mockNR := new(MockNewsletterStore)
+	ls := application.NewLimitService(plan, mockNR, nil, nil)
+	ls.SetPlans(mockPS)
+	userID := uuid.New()
+	dbErr := errors.New("db down")
+
+	mockPS.On("PlanName", userID).Return("", dbErr)
+
+	got, err := ls.PlanOf(userID)
+	assert.ErrorIs(t, err, dbErr)
+	assert.Equal(t, plan, got, "default plan on failure")
+	assert.ErrorIs(t, ls.CheckNewsletters(userID), dbErr)
+	mockNR.AssertNotCalled(t, "Count", mock.Anything)
+}
+
+func TestSetPlan(t *testing.T) {
+	mockPS := new(MockPlanStore)
+	pro := domain.Plan{Name: "pro", Limits: domain.Limits{Newsletters: 10}}
+	ls := application.NewLimitService(plan, new(MockNewsletterStore), nil, nil)
+	ls.SetPlans(mockPS, pro)
+	userID := uuid.New()
+
+	mockPS.On("PlanName", userID).Return(domain.DefaultPlanName, nil).Once()
+	mockPS.On("SetPlanName", userID, "pro").Return(nil)
+
+	got, err := ls.PlanOf(userID)
+	require.NoError(t, err)
+	require.Equal(t, plan, got)
+
+	got, err = ls.SetPlan(userID, "pro")
+	require.NoError(t, err)
+	assert.Equal(t, pro, got)
+
+	got, err = ls.PlanOf(userID)
+	require.NoError(t, err)
+	assert.Equal(t, pro, got, "applies at once on this instance")
+
+	_, err = ls.SetPlan(userID, "gold")
+	assert.ErrorIs(t, err, domain.ErrUnknownPlan)
+	mockPS.AssertNotCalled(t, "SetPlanName", userID, "gold")
+}
+
+func TestSetPlan_NoStore(t *testing.T) {
+	ls := application.NewLimitService(plan, new(MockNewsletterStore), nil, nil)
+
+	_, err := ls.SetPlan(uuid.New(), domain.DefaultPlanName)
+	assert.ErrorIs(t, err, domain.ErrPlansNotStored)
+}
+
+// The count and the creation are separate, so concurrent checks below the
+// limit all pass: the limit can be exceeded by the requests in flight.
+func TestCheckNewsletters_Soft(t *testing.T) {
+	mockNR := new(MockNewsletterStore)
+	ls := application.NewLimitService(plan, mockNR, nil, nil)
+	ownerID := uuid.New()
+
+	mockNR.On("Count", ownerID).Return(plan.Limits.Newsletters-1, nil)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = ls.CheckNewsletters(ownerID)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+}
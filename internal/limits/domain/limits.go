@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"context"
+	apperrors "newsletter/internal/errors"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPlanName is the plan of users who were not put on another plan.
+const DefaultPlanName = "free"
+
+var (
+	// ErrNewsletterLimit is returned when a user creating a newsletter already has as many as their plan allows.
+	ErrNewsletterLimit = apperrors.New(apperrors.Conflict, "newsletter limit of the plan reached")
+	// ErrSubscriberLimit is returned when a newsletter already has as many active subscribers as the plan of its owner allows.
+	ErrSubscriberLimit = apperrors.New(apperrors.Conflict, "the newsletter does not accept more subscribers")
+	// ErrEmailLimit is returned when a campaign would send more emails this month than the plan of the owner allows.
+	ErrEmailLimit = apperrors.New(apperrors.Conflict, "monthly email limit of the plan reached")
+	// ErrUnknownPlan is returned when putting a user on a plan that is not configured.
+	ErrUnknownPlan = apperrors.New(apperrors.Validation, "unknown plan")
+	// ErrPlansNotStored is returned when putting a user on a plan with a store that does not keep plans.
+	ErrPlansNotStored = apperrors.New(apperrors.Internal, "plans cannot be assigned with this store")
+)
+
+// Limits are the caps of a plan. Zero means unlimited.
+type Limits struct {
+	Newsletters              int `json:"newsletters"`                // Newsletters per user
+	SubscribersPerNewsletter int `json:"subscribers_per_newsletter"` // Active subscribers of each newsletter
+	EmailsPerMonth           int `json:"emails_per_month"`           // Campaign emails per user and calendar month (UTC)
+	// RequestsPerMinute are the API requests each user can make per minute
+	// on each instance; further requests are refused until the next minute
+	RequestsPerMinute int `json:"requests_per_minute"`
+}
+
+// Plan is a named set of limits.
+type Plan struct {
+	Name   string `json:"name"`
+	Limits Limits `json:"limits"`
+}
+
+// Usage is how much of the limits of their plan a user consumes.
+type Usage struct {
+	Newsletters     int               `json:"newsletters"`
+	Subscribers     map[uuid.UUID]int `json:"subscribers"`       // Active subscribers, by newsletter
+	EmailsThisMonth int               `json:"emails_this_month"` // Campaign emails sent since PeriodStart
+}
+
+// Report is the plan of a user with their usage.
+type Report struct {
+	Plan        string    `json:"plan"`
+	Limits      Limits    `json:"limits"`
+	Usage       Usage     `json:"usage"`
+	PeriodStart time.Time `json:"period_start"` // Start of the month of EmailsThisMonth
+}
+
+// MonthStart returns the start of the calendar month of t, in UTC, from
+// which monthly limits are counted.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Enforcer checks actions against the plan of the user they are performed
+// for. The services of the modules call it before the action, and return
+// its error.
+//
+// Limits are soft: usage is counted when checking, not when acting, so
+// concurrent actions may each pass the check and exceed a limit by up to
+// their number. Usage is never reduced to fit a limit afterwards.
+type Enforcer interface {
+	// CheckNewsletters returns ErrNewsletterLimit if ownerID cannot create
+	// another newsletter.
+	CheckNewsletters(ownerID uuid.UUID) error
+	// CheckSubscribers returns ErrSubscriberLimit if the newsletter cannot
+	// accept another subscriber.
+	CheckSubscribers(newsletterID uuid.UUID) error
+	// CheckEmails returns ErrEmailLimit if a campaign of the newsletter,
+	// emailing each of its active subscribers, would exceed the monthly
+	// emails of the owner.
+	CheckEmails(newsletterID uuid.UUID) error
+}
+
+// LimitService is an interface that contains a collection of method signatures
+// which will be implemented in application level and are responsible for
+// resolving the plan of users and enforcing its limits.
+type LimitService interface {
+	Enforcer
+	// PlanOf returns the plan of a user. When the plan cannot be looked up,
+	// it returns the default plan with the error.
+	PlanOf(userID uuid.UUID) (Plan, error)
+	// SetPlan puts a user on the plan with the given name, failing with
+	// ErrUnknownPlan when no such plan is configured.
+	SetPlan(userID uuid.UUID, name string) (Plan, error)
+	// Report returns the plan of a user with their usage.
+	Report(userID uuid.UUID) (*Report, error)
+}
+
+// NewsletterStore reads the newsletters of users. It is implemented by the
+// newsletter repositories.
+type NewsletterStore interface {
+	GetAll(ctx context.Context, ownerID uuid.UUID, filter newsletterdomain.NewsletterFilter, sort newsletterdomain.NewsletterSort, limit, page int) ([]*newsletterdomain.Newsletter, error)
+	Count(ctx context.Context, ownerID uuid.UUID, filter newsletterdomain.NewsletterFilter) (int, error)
+	Get(ctx context.Context, id uuid.UUID) (*newsletterdomain.Newsletter, error)
+}
+
+// PlanStore stores the plan each user is on. It is implemented by the
+// repositories of users.
+type PlanStore interface {
+	// PlanName returns the name of the plan of a user.
+	PlanName(ctx context.Context, userID uuid.UUID) (string, error)
+	// SetPlanName puts a user on the plan with the given name.
+	SetPlanName(ctx context.Context, userID uuid.UUID, name string) error
+}
+
+// EmailCounter counts the campaign emails sent to the subscribers of the
+// newsletters of a user.
+type EmailCounter interface {
+	CountEmailsSent(ctx context.Context, ownerID uuid.UUID, since time.Time) (int, error)
+}
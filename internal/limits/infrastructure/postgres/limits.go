@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/infrastructure/database"
+	userdomain "newsletter/internal/users/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// UsageRepository counts the usage of plans stored in PostgreSQL.
+type UsageRepository struct {
+	db database.DB
+}
+
+func NewUsageRepository(db database.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// CountEmailsSent counts the campaign emails sent since the given time by
+// every newsletter of ownerID.
+func (ur *UsageRepository) CountEmailsSent(ctx context.Context, ownerID uuid.UUID, since time.Time) (int, error) {
+	query := `select count(*) from campaign_deliveries d
+		join campaigns c on c.id = d.campaign_id
+		join newsletters n on n.id = c.newsletter_id
+		where n.owner_id = $1 and d.sent_at >= $2`
+
+	var count int
+	if err := ur.db.QueryRow(ctx, query, ownerID, since).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// PlanRepository stores the plans of users in the plan column of the users
+// table.
+type PlanRepository struct {
+	db database.DB
+}
+
+func NewPlanRepository(db database.DB) *PlanRepository {
+	return &PlanRepository{db: db}
+}
+
+// PlanName returns the name of the plan of a user, or
+// userdomain.ErrUserNotFound.
+func (pr *PlanRepository) PlanName(ctx context.Context, userID uuid.UUID) (string, error) {
+	query := `select plan from users where id = $1`
+
+	var name string
+	err := pr.db.QueryRow(ctx, query, userID).Scan(&name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", userdomain.ErrUserNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// SetPlanName puts a user on a plan, or returns userdomain.ErrUserNotFound.
+func (pr *PlanRepository) SetPlanName(ctx context.Context, userID uuid.UUID, name string) error {
+	query := `update users set plan = $1 where id = $2`
+
+	tag, err := pr.db.Exec(ctx, query, name, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return userdomain.ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package postgres_test
+
+import (
+	"context"
+	"newsletter/internal/limits/infrastructure/postgres"
+	userdomain "newsletter/internal/users/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMock returns a mocked database whose expectations are checked at the
+// end of the test.
+func newMock(t *testing.T) pgxmock.PgxPoolIface {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		mock.Close()
+	})
+	return mock
+}
+
+func TestPlanRepository_PlanName(t *testing.T) {
+	mock := newMock(t)
+	userID, missing := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`select plan from users where id = \$1`).WithArgs(userID).
+		WillReturnRows(pgxmock.NewRows([]string{"plan"}).AddRow("pro"))
+	mock.ExpectQuery(`select plan from users where id = \$1`).WithArgs(missing).
+		WillReturnError(pgx.ErrNoRows)
+
+	repo := postgres.NewPlanRepository(mock)
+	name, err := repo.PlanName(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, "pro", name)
+
+	_, err = repo.PlanName(context.Background(), missing)
+	assert.ErrorIs(t, err, userdomain.ErrUserNotFound)
+}
+
+func TestPlanRepository_SetPlanName(t *testing.T) {
+	mock := newMock(t)
+	userID, missing := uuid.New(), uuid.New()
+
+	mock.ExpectExec(`update users set plan = \$1 where id = \$2`).WithArgs("pro", userID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`update users set plan = \$1 where id = \$2`).WithArgs("pro", missing).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	repo := postgres.NewPlanRepository(mock)
+	assert.NoError(t, repo.SetPlanName(context.Background(), userID, "pro"))
+	assert.ErrorIs(t, repo.SetPlanName(context.Background(), missing, "pro"), userdomain.ErrUserNotFound)
+}
@@ -0,0 +1,113 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"newsletter/internal/links/domain"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// codeAlphabet are the characters of the codes of short links.
+const codeAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// maxCodeAttempts is how many codes are generated for a link before giving
+// up, should they all be taken.
+const maxCodeAttempts = 5
+
+// LinkService provides application-level operations related to short links
+// and it orchestrates domain logic and persistence concerns.
+type LinkService struct {
+	lr domain.LinkRepository
+
+	redirects  atomic.Uint64
+	notFound   atomic.Uint64
+	collisions atomic.Uint64
+}
+
+func NewLinkService(lr domain.LinkRepository) *LinkService {
+	return &LinkService{lr: lr}
+}
+
+// Shorten returns the short link to url in the emails of a campaign. A new
+// link gets a random code, generated again when it is taken by another
+// link, up to maxCodeAttempts times.
+func (ls *LinkService) Shorten(campaignID uuid.UUID, url string) (*domain.Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for range maxCodeAttempts {
+		code, err := newCode()
+		if err != nil {
+			return nil, err
+		}
+
+		link, err := ls.lr.Create(ctx, &domain.Link{Code: code, URL: url, CampaignID: campaignID})
+		if errors.Is(err, domain.ErrCodeTaken) {
+			ls.collisions.Add(1)
+			slog.Warn("short link code taken, generating another", "campaign_id", campaignID, "code", code)
+			continue
+		}
+		if err != nil {
+			slog.Error("failed to create short link", "campaign_id", campaignID, "error", err)
+			return nil, err
+		}
+		return link, nil
+	}
+
+	return nil, fmt.Errorf("no free short link code after %d attempts", maxCodeAttempts)
+}
+
+// Follow counts a click on the link with code and returns it.
+func (ls *LinkService) Follow(code string) (*domain.Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	link, err := ls.lr.RecordClick(ctx, code)
+	if errors.Is(err, domain.ErrLinkNotFound) {
+		ls.notFound.Add(1)
+		return nil, err
+	}
+	if err != nil {
+		slog.Error("failed to record click", "code", code, "error", err)
+		return nil, err
+	}
+
+	ls.redirects.Add(1)
+	return link, nil
+}
+
+// Stats returns the counters of the links served by this instance.
+func (ls *LinkService) Stats() domain.Stats {
+	return domain.Stats{
+		Redirects:  ls.redirects.Load(),
+		NotFound:   ls.notFound.Load(),
+		Collisions: ls.collisions.Load(),
+	}
+}
+
+// newCode returns a random code of domain.CodeLength characters of
+// codeAlphabet. Random bytes beyond the largest multiple of the alphabet
+// size are discarded, so that every character is equally likely.
+func newCode() (string, error) {
+	const limit = 256 - 256%len(codeAlphabet)
+
+	code := make([]byte, 0, domain.CodeLength)
+	random := make([]byte, domain.CodeLength*2)
+	for len(code) < domain.CodeLength {
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+		for _, b := range random {
+			if int(b) < limit && len(code) < domain.CodeLength {
+				code = append(code, codeAlphabet[int(b)%len(codeAlphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}
@@ -0,0 +1,88 @@
+package application_test
+
+import (
+	"context"
+	"newsletter/internal/links/application"
+	"newsletter/internal/links/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Link Repository ---
+type MockLinkRepository struct {
+	mock.Mock
+}
+
+func (m *MockLinkRepository) Create(ctx context.Context, link *domain.Link) (*domain.Link, error) {
+	args := m.Called(link)
+	l := args.Get(0)
+	if l == nil {
+		return nil, args.Error(1)
+	}
+	return l.(*domain.Link), args.Error(1)
+}
+
+func (m *MockLinkRepository) RecordClick(ctx context.Context, code string) (*domain.Link, error) {
+	args := m.Called(code)
+	l := args.Get(0)
+	if l == nil {
+		return nil, args.Error(1)
+	}
+	return l.(*domain.Link), args.Error(1)
+}
+
+func TestShorten_RetriesTakenCodes(t *testing.T) {
+	repo := new(MockLinkRepository)
+	ls := application.NewLinkService(repo)
+	campaignID := uuid.New()
+
+	var codes []string
+	validLink := mock.MatchedBy(func(link *domain.Link) bool {
+		return link.CampaignID == campaignID && link.URL == "https://example.com/a"
+	})
+	record := func(args mock.Arguments) { codes = append(codes, args.Get(0).(*domain.Link).Code) }
+	repo.On("Create", validLink).Return(nil, domain.ErrCodeTaken).Run(record).Once()
+	repo.On("Create", validLink).Return(&domain.Link{Code: "Ab3dE6gH", URL: "https://example.com/a", CampaignID: campaignID}, nil).Run(record).Once()
+
+	link, err := ls.Shorten(campaignID, "https://example.com/a")
+
+	require.NoError(t, err)
+	assert.Equal(t, "Ab3dE6gH", link.Code)
+	require.Len(t, codes, 2)
+	for _, code := range codes {
+		assert.Regexp(t, `^[0-9A-Za-z]{8}$`, code)
+	}
+	assert.NotEqual(t, codes[0], codes[1])
+	assert.Equal(t, uint64(1), ls.Stats().Collisions)
+}
+
+func TestShorten_GivesUp(t *testing.T) {
+	repo := new(MockLinkRepository)
+	ls := application.NewLinkService(repo)
+	repo.On("Create", mock.Anything).Return(nil, domain.ErrCodeTaken)
+
+	_, err := ls.Shorten(uuid.New(), "https://example.com/a")
+
+	assert.Error(t, err)
+	repo.AssertNumberOfCalls(t, "Create", 5)
+}
+
+func TestFollow(t *testing.T) {
+	repo := new(MockLinkRepository)
+	ls := application.NewLinkService(repo)
+	repo.On("RecordClick", "Ab3dE6gH").Return(&domain.Link{Code: "Ab3dE6gH", URL: "https://example.com/a", Clicks: 1}, nil)
+	repo.On("RecordClick", "unknown").Return(nil, domain.ErrLinkNotFound)
+
+	link, err := ls.Follow("Ab3dE6gH")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", link.URL)
+
+	_, err = ls.Follow("unknown")
+	assert.ErrorIs(t, err, domain.ErrLinkNotFound)
+
+	assert.Equal(t, domain.Stats{Redirects: 1, NotFound: 1}, ls.Stats())
+}
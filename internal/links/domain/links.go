@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	apperrors "newsletter/internal/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrLinkNotFound is returned when no short link has the requested code.
+	ErrLinkNotFound = apperrors.New(apperrors.NotFound, "link not found")
+	// ErrCodeTaken is returned by LinkRepository.Create when the code of a
+	// new link is already used by another one.
+	ErrCodeTaken = errors.New("link code already taken")
+)
+
+// CodeLength is the number of characters of the codes of short links.
+// Codes are drawn from the 62 ASCII letters and digits, so that collisions
+// stay rare as links add up.
+const CodeLength = 8
+
+// Link is a short link, /r/{code}, redirecting to a URL of the emails of a
+// campaign. Each URL of a campaign has a single link, shared by its
+// recipients, whose clicks are counted.
+type Link struct {
+	Code       string    `json:"code"`        // Code of the link, see CodeLength
+	URL        string    `json:"url"`         // Address the link redirects to
+	CampaignID uuid.UUID `json:"campaign_id"` // Campaign whose emails carry the link
+	Clicks     int       `json:"clicks"`      // Number of times the link was followed
+	CreatedAt  time.Time `json:"created_at"`  // Creation time of the link
+}
+
+// Stats are the counters of the short links served since the API started.
+type Stats struct {
+	Redirects  uint64 // Links followed
+	NotFound   uint64 // Requests for unknown codes
+	Collisions uint64 // Codes generated again because they were taken
+}
+
+// LinkService is an interface that contains a collection of method signatures
+// which will be implemented in application level and are responsible for
+// shortening the links of campaigns and following them.
+type LinkService interface {
+	// Shorten returns the short link to url in the emails of a campaign,
+	// creating it the first time.
+	Shorten(campaignID uuid.UUID, url string) (*Link, error)
+	// Follow counts a click on the link with code and returns it. It fails
+	// with ErrLinkNotFound for unknown codes.
+	Follow(code string) (*Link, error)
+	// Stats returns the counters of the links served.
+	Stats() Stats
+}
+
+// LinkRepository is an interface that contains a collection of method signatures
+// which will be implemented in persistence level.
+type LinkRepository interface {
+	// Create stores a new link, unless its campaign already has a link to
+	// its URL, which is returned instead. It returns ErrCodeTaken if the
+	// code of link is used by another one.
+	Create(ctx context.Context, link *Link) (*Link, error)
+	// RecordClick counts a click on the link with code and returns it. It
+	// returns ErrLinkNotFound if no link has that code.
+	RecordClick(ctx context.Context, code string) (*Link, error)
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/links/domain"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// uniqueViolation is the Postgres error code of a unique constraint violation.
+const uniqueViolation = "23505"
+
+type LinkRepository struct {
+	db database.DB
+}
+
+func NewLinkRepository(db database.DB) *LinkRepository {
+	return &LinkRepository{db: db}
+}
+
+// linkColumns lists the columns scanned by scanLink, in order.
+const linkColumns = `code, url, campaign_id, clicks, created_at`
+
+// scanLink scans a row selected with linkColumns into a domain.Link.
+func scanLink(row pgx.Row) (*domain.Link, error) {
+	var link domain.Link
+	if err := row.Scan(&link.Code, &link.URL, &link.CampaignID, &link.Clicks, &link.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Create inserts a new link. The link of the campaign to the same URL is
+// returned instead when it exists, so that campaigns resumed or sent in
+// several batches reuse their links.
+//
+// If the code is used by another link, Create returns domain.ErrCodeTaken.
+func (lr *LinkRepository) Create(ctx context.Context, link *domain.Link) (*domain.Link, error) {
+	query := `insert into short_links (code, url, campaign_id, created_at) values ($1, $2, $3, $4)
+		on conflict (campaign_id, url) do update set url = excluded.url
+		returning ` + linkColumns
+
+	created, err := scanLink(lr.db.QueryRow(ctx, query, link.Code, link.URL, link.CampaignID, time.Now()))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return nil, domain.ErrCodeTaken
+	}
+
+	return created, err
+}
+
+// RecordClick increments the clicks of the link with code.
+//
+// If no such link exists, RecordClick returns domain.ErrLinkNotFound.
+func (lr *LinkRepository) RecordClick(ctx context.Context, code string) (*domain.Link, error) {
+	query := `update short_links set clicks = clicks + 1 where code = $1 returning ` + linkColumns
+
+	link, err := scanLink(lr.db.QueryRow(ctx, query, code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrLinkNotFound
+	}
+
+	return link, err
+}
@@ -0,0 +1,71 @@
+package postgres_test
+
+import (
+	"context"
+	"newsletter/internal/links/domain"
+	"newsletter/internal/links/infrastructure/postgres"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMock returns a mocked database whose expectations are checked at the
+// end of the test.
+func newMock(t *testing.T) pgxmock.PgxPoolIface {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		mock.Close()
+	})
+	return mock
+}
+
+func TestLinkRepository_Create_ReusesLinkOfURL(t *testing.T) {
+	mock := newMock(t)
+	campaignID := uuid.New()
+	now := time.Now().UTC()
+
+	// The campaign already links to the URL with another code.
+	mock.ExpectQuery(`insert into short_links .* on conflict \(campaign_id, url\)`).
+		WithArgs("newcode1", "https://example.com/a", campaignID, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"code", "url", "campaign_id", "clicks", "created_at"}).
+			AddRow("oldcode1", "https://example.com/a", campaignID, 3, now))
+
+	link, err := postgres.NewLinkRepository(mock).Create(context.Background(), &domain.Link{Code: "newcode1", URL: "https://example.com/a", CampaignID: campaignID})
+
+	require.NoError(t, err)
+	assert.Equal(t, &domain.Link{Code: "oldcode1", URL: "https://example.com/a", CampaignID: campaignID, Clicks: 3, CreatedAt: now}, link)
+}
+
+func TestLinkRepository_Create_CodeTaken(t *testing.T) {
+	mock := newMock(t)
+	campaignID := uuid.New()
+
+	mock.ExpectQuery(`insert into short_links`).
+		WithArgs("taken123", "https://example.com/a", campaignID, pgxmock.AnyArg()).
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "short_links_pkey"})
+
+	_, err := postgres.NewLinkRepository(mock).Create(context.Background(), &domain.Link{Code: "taken123", URL: "https://example.com/a", CampaignID: campaignID})
+
+	assert.ErrorIs(t, err, domain.ErrCodeTaken)
+}
+
+func TestLinkRepository_RecordClick_NotFound(t *testing.T) {
+	mock := newMock(t)
+
+	mock.ExpectQuery(`update short_links set clicks = clicks \+ 1 where code = \$1`).
+		WithArgs("unknown1").
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err := postgres.NewLinkRepository(mock).RecordClick(context.Background(), "unknown1")
+
+	assert.ErrorIs(t, err, domain.ErrLinkNotFound)
+}
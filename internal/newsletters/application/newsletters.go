@@ -2,9 +2,21 @@ package application
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/mail"
+	"net/url"
+	"newsletter/internal/infrastructure/i18n"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/infrastructure/sanitize"
+	limitsdomain "newsletter/internal/limits/domain"
 	"newsletter/internal/newsletters/domain"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -13,12 +25,42 @@ import (
 // and it orchestrates domain logic and persistence concerns.
 type NewsletterService struct {
 	nr domain.NewsletterRepository
+	sc domain.SubscriberCounter
+
+	maxNameLength        int
+	maxDescriptionLength int
+
+	limits limitsdomain.Enforcer // nil skips plan limits
+}
+
+// NewNewsletterService returns a NewsletterService. sc may be nil, in which
+// case listings cannot be sorted by subscriber count.
+func NewNewsletterService(nr domain.NewsletterRepository, sc domain.SubscriberCounter) *NewsletterService {
+	return &NewsletterService{
+		nr:                   nr,
+		sc:                   sc,
+		maxNameLength:        domain.DefaultMaxNameLength,
+		maxDescriptionLength: domain.DefaultMaxDescriptionLength,
+	}
+}
+
+// SetLengthLimits sets the maximum number of characters of the name and
+// description of new newsletters, replacing domain.DefaultMaxNameLength and
+// domain.DefaultMaxDescriptionLength.
+func (ns *NewsletterService) SetLengthLimits(name, description int) {
+	ns.maxNameLength, ns.maxDescriptionLength = name, description
 }
 
-func NewNewsletterService(nr domain.NewsletterRepository) *NewsletterService {
-	return &NewsletterService{nr: nr}
+// SetLimits sets the enforcer of the plan limits of owners. A nil enforcer
+// disables them.
+func (ns *NewsletterService) SetLimits(limits limitsdomain.Enforcer) {
+	ns.limits = limits
 }
 
+// slugAttempts is the number of generated slugs tried when creating a
+// newsletter before giving up.
+const slugAttempts = 5
+
 // Create creates a new newsletter.
 //
 // This method applies application-level orchestration, including logging
@@ -26,9 +68,44 @@ func NewNewsletterService(nr domain.NewsletterRepository) *NewsletterService {
 // the repository and returns the newly created newsletter populated with
 // persistence-related fields (such as ID and creation timestamp).
 //
+// The name and description are plain text: any HTML is removed before they
+// are stored, as they are shown on public pages and in emails. Once cleaned,
+// they must fit the length limits, otherwise domain.ErrInvalidNewsletter is
+// returned.
+//
+// A newsletter without slug gets one derived from its name (see
+// domain.Slugify); when it is taken, a random suffix is appended. A slug
+// chosen by the owner must be valid, otherwise domain.ErrInvalidSlug is
+// returned, and free, otherwise domain.ErrSlugTaken is returned.
+//
+// Owners with as many newsletters as their plan allows get
+// limitsdomain.ErrNewsletterLimit, if limits are set.
+//
 // A context with a fixed timeout is used to prevent the operation from
 // blocking indefinitely.
 func (ns *NewsletterService) Create(newsletter *domain.Newsletter) (*domain.Newsletter, error) {
+	newsletter.Name = sanitize.Text(newsletter.Name)
+	newsletter.Description = sanitize.Text(newsletter.Description)
+	if utf8.RuneCountInString(newsletter.Name) > ns.maxNameLength {
+		return nil, fmt.Errorf("%w: name must be at most %d characters", domain.ErrInvalidNewsletter, ns.maxNameLength)
+	}
+	if utf8.RuneCountInString(newsletter.Description) > ns.maxDescriptionLength {
+		return nil, fmt.Errorf("%w: description must be at most %d characters", domain.ErrInvalidNewsletter, ns.maxDescriptionLength)
+	}
+
+	if ns.limits != nil {
+		if err := ns.limits.CheckNewsletters(newsletter.OwnerID); err != nil {
+			return nil, err
+		}
+	}
+
+	generated := newsletter.Slug == ""
+	if generated {
+		newsletter.Slug = domain.Slugify(newsletter.Name)
+	} else if !domain.ValidSlug(newsletter.Slug) {
+		return nil, fmt.Errorf("%w: %q must be %d to %d lowercase letters, digits and single hyphens", domain.ErrInvalidSlug, newsletter.Slug, domain.MinSlugLength, domain.MaxSlugLength)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
@@ -36,9 +113,15 @@ func (ns *NewsletterService) Create(newsletter *domain.Newsletter) (*domain.News
 		"creating newsletter",
 		"owner_id", newsletter.OwnerID,
 		"name", newsletter.Name,
+		"slug", newsletter.Slug,
 	)
 
+	base := newsletter.Slug
 	newNewsletter, err := ns.nr.Create(ctx, newsletter)
+	for attempt := 1; generated && errors.Is(err, domain.ErrSlugTaken) && attempt < slugAttempts; attempt++ {
+		newsletter.Slug = base + "-" + uuid.NewString()[:6]
+		newNewsletter, err = ns.nr.Create(ctx, newsletter)
+	}
 	if err != nil {
 		slog.Error(
 			"failed to create newsletter",
@@ -55,29 +138,450 @@ func (ns *NewsletterService) Create(newsletter *domain.Newsletter) (*domain.News
 // GetAll retrieves all newsletters belonging to a specific owner.
 //
 // It queries the persistence layer for all newsletter records associated
-// with the provided ownerID. A 3-second timeout is enforced to ensure
-// responsiveness.
+// with the provided ownerID and matching filter, ordered by sort. A
+// 500-millisecond timeout is enforced to ensure responsiveness.
 //
-// On success, it returns a slice of newsletters. If no newsletters are found,
-// it returns an empty slice and no error.
-func (ns *NewsletterService) GetAll(ownerID uuid.UUID, limit, page int) ([]*domain.Newsletter, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
-	defer cancel()
+// Sorting by domain.SortSubscriberCount needs the subscriber counts of every
+// matching newsletter, which live in another store: all of them are loaded
+// and counted before the page is cut, within a 5-second timeout. An unknown
+// sort field is rejected with domain.ErrInvalidSort.
+//
+// On success, it returns a slice of newsletters and the total number of
+// newsletters matching filter, across all pages, so that clients can page
+// through them. If no newsletters are found, it returns an empty slice, a
+// total of 0 and no error.
+func (ns *NewsletterService) GetAll(ownerID uuid.UUID, filter domain.NewsletterFilter, sort domain.NewsletterSort, limit, page int) ([]*domain.Newsletter, int, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+
+	switch sort.Field {
+	case "", domain.SortCreatedAt, domain.SortName:
+	case domain.SortSubscriberCount:
+		if ns.sc == nil {
+			return nil, 0, fmt.Errorf("%w: subscriber counts are not available", domain.ErrInvalidSort)
+		}
+	default:
+		return nil, 0, fmt.Errorf("%w: %q", domain.ErrInvalidSort, sort.Field)
+	}
 
 	slog.Info(
 		"listing of newsletters",
 		"owner_id", ownerID,
+		"query", filter.Query,
+		"sort", sort.Field,
 	)
 
-	newNewsletters, err := ns.nr.GetAll(ctx, ownerID, limit, page)
+	var newNewsletters []*domain.Newsletter
+	var total int
+	var err error
+	if sort.Field == domain.SortSubscriberCount {
+		newNewsletters, total, err = ns.getAllBySubscriberCount(ownerID, filter, sort.Descending, limit, page)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		newNewsletters, err = ns.nr.GetAll(ctx, ownerID, filter, sort, limit, page)
+		if err == nil {
+			total, err = ns.nr.Count(ctx, ownerID, filter)
+		}
+	}
 	if err != nil {
 		slog.Error(
 			"failed to get the newsletters",
 			"owner_id", ownerID,
 			"error", err,
 		)
+		return nil, 0, err
+	}
+
+	return newNewsletters, total, nil
+}
+
+// subscriberCountBatch is the number of newsletters read from the repository
+// at a time when sorting by subscriber count.
+const subscriberCountBatch = 100
+
+// getAllBySubscriberCount returns a page of the newsletters of ownerID
+// matching filter, ordered by their number of active subscribers, and the
+// number of matching newsletters. Newsletters with the same count keep their
+// creation order.
+func (ns *NewsletterService) getAllBySubscriberCount(ownerID uuid.UUID, filter domain.NewsletterFilter, descending bool, limit, page int) ([]*domain.Newsletter, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var all []*domain.Newsletter
+	for batch := 1; ; batch++ {
+		newsletters, err := ns.nr.GetAll(ctx, ownerID, filter, domain.NewsletterSort{Field: domain.SortCreatedAt}, subscriberCountBatch, batch)
+		if err != nil {
+			return nil, 0, err
+		}
+		all = append(all, newsletters...)
+		if len(newsletters) < subscriberCountBatch {
+			break
+		}
+	}
+
+	ids := make([]uuid.UUID, len(all))
+	for i, newsletter := range all {
+		ids[i] = newsletter.ID
+	}
+	counts, err := ns.sc.CountActive(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		if descending {
+			return counts[all[i].ID] > counts[all[j].ID]
+		}
+		return counts[all[i].ID] < counts[all[j].ID]
+	})
+
+	if page < 1 {
+		page = 1
+	}
+	start := min((page-1)*limit, len(all))
+	end := min(start+limit, len(all))
+
+	return all[start:end], len(all), nil
+}
+
+// List returns a page of the newsletters of ownerID matching filter, with
+// keyset pagination instead of the pages of GetAll.
+//
+// Parameters:
+//   - ownerID: the owner whose newsletters are listed
+//   - filter: optional search and creation date range filters
+//   - descending: whether the newest newsletters come first
+//   - limit: page size, clamped to [1, pagination.MaxLimit] (default pagination.DefaultLimit)
+//   - cursor: the NextCursor of the previous page, or empty for the first page
+//
+// Returns:
+//   - the page of newsletters, ordered by creation time, with the cursor of the next page
+//   - pagination.ErrInvalidCursor if the cursor cannot be decoded, or any repository error
+func (ns *NewsletterService) List(ownerID uuid.UUID, filter domain.NewsletterFilter, descending bool, limit int, cursor string) (*domain.NewsletterPage, error) {
+	after, err := pagination.Decode(cursor)
+	if err != nil {
 		return nil, err
 	}
+	if after != nil {
+		if _, err := uuid.Parse(after.ID); err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	page, err := ns.nr.List(ctx, ownerID, domain.NewsletterQuery{
+		Filter:     filter,
+		Descending: descending,
+		Limit:      pagination.Limit(limit),
+		After:      after,
+	})
+	if err != nil {
+		slog.Error("failed to list the newsletters", "owner_id", ownerID, "error", err)
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Get retrieves a single newsletter by its ID.
+//
+// It is used by public endpoints (such as the embeddable subscribe form) that
+// need to read newsletter details without an authenticated owner.
+//
+// If the newsletter does not exist, domain.ErrNewsletterNotFound is returned.
+func (ns *NewsletterService) Get(id uuid.UUID) (*domain.Newsletter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	newsletter, err := ns.nr.Get(ctx, id)
+	if err != nil {
+		slog.Error(
+			"failed to get the newsletter",
+			"newsletter_id", id,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return newsletter, nil
+}
+
+// GetBySlug retrieves a single newsletter by its slug, for the public
+// pages of the newsletter.
+//
+// If no newsletter has the slug, domain.ErrNewsletterNotFound is returned.
+func (ns *NewsletterService) GetBySlug(slug string) (*domain.Newsletter, error) {
+	if !domain.ValidSlug(slug) {
+		return nil, domain.ErrNewsletterNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	newsletter, err := ns.nr.GetBySlug(ctx, slug)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNewsletterNotFound) {
+			slog.Error(
+				"failed to get the newsletter",
+				"slug", slug,
+				"error", err,
+			)
+		}
+		return nil, err
+	}
+
+	return newsletter, nil
+}
+
+// UpdateSlug changes the slug of a newsletter owned by ownerID. The previous
+// slug is released: its public URLs stop working.
+//
+// The slug must be valid, otherwise domain.ErrInvalidSlug is returned, and
+// free, otherwise domain.ErrSlugTaken is returned. If the newsletter does
+// not exist or belongs to another owner, domain.ErrNewsletterNotFound is
+// returned.
+func (ns *NewsletterService) UpdateSlug(id, ownerID uuid.UUID, slug string) (*domain.Newsletter, error) {
+	if !domain.ValidSlug(slug) {
+		return nil, fmt.Errorf("%w: %q must be %d to %d lowercase letters, digits and single hyphens", domain.ErrInvalidSlug, slug, domain.MinSlugLength, domain.MaxSlugLength)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	slog.Info(
+		"updating newsletter slug",
+		"newsletter_id", id,
+		"owner_id", ownerID,
+		"slug", slug,
+	)
+
+	newsletter, err := ns.nr.UpdateSlug(ctx, id, ownerID, slug)
+	if err != nil {
+		slog.Error(
+			"failed to update newsletter slug",
+			"newsletter_id", id,
+			"owner_id", ownerID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return newsletter, nil
+}
+
+// UpdateSettings replaces the settings of a newsletter owned by ownerID.
+//
+// Every allowed origin must be either the "*" wildcard or a bare http(s)
+// origin such as "https://example.com" (scheme and host, without path).
+// Invalid origins are rejected with domain.ErrInvalidOrigin.
+//
+// The sender address, when set, must be a plain email address and the sender
+// name must fit on a single line; otherwise domain.ErrInvalidSender is
+// returned. A new sender address has to be verified before it is used.
+//
+// The language, when set, must be one of i18n.Languages; otherwise
+// domain.ErrInvalidLanguage is returned.
+//
+// The unsubscribe redirect and logo URLs, when set, must be absolute http(s)
+// URLs, the brand color a hex color such as "#0055ff", and the footer at
+// most domain.MaxFooterLength characters; otherwise domain.ErrInvalidBranding
+// is returned.
+//
+// The reply-to address, copies and custom headers must pass
+// notificationdomain.Envelope.Validate; otherwise an error wrapping
+// notificationdomain.ErrInvalidEnvelope is returned.
+//
+// If the newsletter does not exist or belongs to another owner,
+// domain.ErrNewsletterNotFound is returned.
+func (ns *NewsletterService) UpdateSettings(id, ownerID uuid.UUID, settings domain.Settings) (*domain.Newsletter, error) {
+	for _, origin := range settings.AllowedOrigins {
+		if !validOrigin(origin) {
+			return nil, fmt.Errorf("%w: %q", domain.ErrInvalidOrigin, origin)
+		}
+	}
+	if err := validateSender(settings.FromName, settings.FromEmail); err != nil {
+		return nil, err
+	}
+	if settings.Language != "" && !i18n.Supported(settings.Language) {
+		return nil, fmt.Errorf("%w: %q", domain.ErrInvalidLanguage, settings.Language)
+	}
+	if err := validateBranding(settings.Branding); err != nil {
+		return nil, err
+	}
+	if err := settings.Envelope.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	slog.Info(
+		"updating newsletter settings",
+		"newsletter_id", id,
+		"owner_id", ownerID,
+	)
+
+	newsletter, err := ns.nr.UpdateSettings(ctx, id, ownerID, settings)
+	if err != nil {
+		slog.Error(
+			"failed to update newsletter settings",
+			"newsletter_id", id,
+			"owner_id", ownerID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return newsletter, nil
+}
+
+// SetSenderVerified records the verification result of the sender address
+// fromEmail of a newsletter. It is a no-op returning domain.ErrNewsletterNotFound
+// if fromEmail is no longer the configured sender address.
+func (ns *NewsletterService) SetSenderVerified(id uuid.UUID, fromEmail string, verified bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	slog.Info(
+		"updating newsletter sender verification",
+		"newsletter_id", id,
+		"from_email", fromEmail,
+		"verified", verified,
+	)
+
+	if err := ns.nr.SetSenderVerified(ctx, id, fromEmail, verified); err != nil {
+		slog.Error(
+			"failed to update newsletter sender verification",
+			"newsletter_id", id,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// Duplicate creates a newsletter of ownerID with the description and
+// settings of the newsletter id, for seasonal or spin-off publications. The
+// name defaults to the one of the newsletter followed by " (copy)", and the
+// slug is chosen as by Create. The verification of the sender address
+// carries over, as the address is the same. Posts and subscribers are not
+// copied.
+//
+// If the newsletter does not exist or belongs to another owner,
+// domain.ErrNewsletterNotFound is returned; otherwise the errors of Create.
+// Once created, the duplicate is returned even if its settings or the
+// verification of its sender cannot be copied: the failure is logged and
+// the owner can set them again.
+func (ns *NewsletterService) Duplicate(id, ownerID uuid.UUID, name, slug string) (*domain.Newsletter, error) {
+	source, err := ns.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if source.OwnerID != ownerID {
+		return nil, domain.ErrNewsletterNotFound
+	}
+
+	if strings.TrimSpace(name) == "" {
+		name = source.Name + " (copy)"
+	}
+	duplicate, err := ns.Create(&domain.Newsletter{
+		OwnerID:     ownerID,
+		Name:        name,
+		Slug:        slug,
+		Description: source.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	updated, err := ns.nr.UpdateSettings(ctx, duplicate.ID, ownerID, source.Settings)
+	if err == nil {
+		duplicate = updated
+		if source.SenderVerified {
+			err = ns.nr.SetSenderVerified(ctx, duplicate.ID, source.FromEmail, true)
+			duplicate.SenderVerified = err == nil
+		}
+	}
+	if err != nil {
+		slog.Error(
+			"failed to copy newsletter settings",
+			"newsletter_id", duplicate.ID,
+			"source_id", source.ID,
+			"owner_id", ownerID,
+			"error", err,
+		)
+	}
+
+	return duplicate, nil
+}
+
+// validateSender checks the sender name and address of the settings.
+// Both are optional.
+func validateSender(name, email string) error {
+	if strings.ContainsAny(name, "\r\n") {
+		return fmt.Errorf("%w: sender name must not contain line breaks", domain.ErrInvalidSender)
+	}
+	if email == "" {
+		return nil
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return fmt.Errorf("%w: %q is not a valid email address", domain.ErrInvalidSender, email)
+	}
+
+	return nil
+}
+
+// validateBranding checks the URLs, color and footer of a newsletter's branding.
+func validateBranding(branding domain.Branding) error {
+	urls := []struct{ field, value string }{
+		{"unsubscribe redirect URL", branding.UnsubscribeRedirectURL},
+		{"logo URL", branding.LogoURL},
+	}
+	for _, u := range urls {
+		if u.value != "" && !validURL(u.value) {
+			return fmt.Errorf("%w: %s %q must be an absolute http or https URL", domain.ErrInvalidBranding, u.field, u.value)
+		}
+	}
+	if branding.BrandColor != "" && !brandColor.MatchString(branding.BrandColor) {
+		return fmt.Errorf("%w: brand color %q must be a hex color such as #0055ff", domain.ErrInvalidBranding, branding.BrandColor)
+	}
+	if utf8.RuneCountInString(branding.FooterText) > domain.MaxFooterLength {
+		return fmt.Errorf("%w: footer must be at most %d characters", domain.ErrInvalidBranding, domain.MaxFooterLength)
+	}
+	return nil
+}
+
+// brandColor matches hex colors such as "#05f" or "#0055ff".
+var brandColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// validURL reports whether rawURL is an absolute http(s) URL.
+func validURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil
+}
+
+// validOrigin reports whether origin is the wildcard or a bare http(s) origin.
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
 
-	return newNewsletters, nil
+	return (u.Scheme == "http" || u.Scheme == "https") &&
+		u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
 }
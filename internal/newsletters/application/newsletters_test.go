@@ -3,8 +3,11 @@ package application_test
 import (
 	"context"
 	"errors"
+	"newsletter/internal/infrastructure/pagination"
 	"newsletter/internal/newsletters/application"
 	"newsletter/internal/newsletters/domain"
+	notificationdomain "newsletter/internal/notifications/domain"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,8 +30,8 @@ func (m *MockNewsletterRepository) Create(ctx context.Context, n *domain.Newslet
 	return news.(*domain.Newsletter), args.Error(1)
 }
 
-func (m *MockNewsletterRepository) GetAll(ctx context.Context, ownerID uuid.UUID, limit, page int) ([]*domain.Newsletter, error) {
-	args := m.Called(ctx, ownerID, limit, page)
+func (m *MockNewsletterRepository) GetAll(ctx context.Context, ownerID uuid.UUID, filter domain.NewsletterFilter, sort domain.NewsletterSort, limit, page int) ([]*domain.Newsletter, error) {
+	args := m.Called(ctx, ownerID, filter, sort, limit, page)
 	news := args.Get(0)
 	if news == nil {
 		return nil, args.Error(1)
@@ -36,11 +39,80 @@ func (m *MockNewsletterRepository) GetAll(ctx context.Context, ownerID uuid.UUID
 	return news.([]*domain.Newsletter), args.Error(1)
 }
 
+func (m *MockNewsletterRepository) Count(ctx context.Context, ownerID uuid.UUID, filter domain.NewsletterFilter) (int, error) {
+	args := m.Called(ctx, ownerID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockNewsletterRepository) List(ctx context.Context, ownerID uuid.UUID, query domain.NewsletterQuery) (*domain.NewsletterPage, error) {
+	args := m.Called(ctx, ownerID, query)
+	page := args.Get(0)
+	if page == nil {
+		return nil, args.Error(1)
+	}
+	return page.(*domain.NewsletterPage), args.Error(1)
+}
+
+func (m *MockNewsletterRepository) Get(ctx context.Context, id uuid.UUID) (*domain.Newsletter, error) {
+	args := m.Called(ctx, id)
+	news := args.Get(0)
+	if news == nil {
+		return nil, args.Error(1)
+	}
+	return news.(*domain.Newsletter), args.Error(1)
+}
+
+func (m *MockNewsletterRepository) GetBySlug(ctx context.Context, slug string) (*domain.Newsletter, error) {
+	args := m.Called(ctx, slug)
+	news := args.Get(0)
+	if news == nil {
+		return nil, args.Error(1)
+	}
+	return news.(*domain.Newsletter), args.Error(1)
+}
+
+func (m *MockNewsletterRepository) UpdateSlug(ctx context.Context, id, ownerID uuid.UUID, slug string) (*domain.Newsletter, error) {
+	args := m.Called(ctx, id, ownerID, slug)
+	news := args.Get(0)
+	if news == nil {
+		return nil, args.Error(1)
+	}
+	return news.(*domain.Newsletter), args.Error(1)
+}
+
+func (m *MockNewsletterRepository) UpdateSettings(ctx context.Context, id, ownerID uuid.UUID, settings domain.Settings) (*domain.Newsletter, error) {
+	args := m.Called(ctx, id, ownerID, settings)
+	news := args.Get(0)
+	if news == nil {
+		return nil, args.Error(1)
+	}
+	return news.(*domain.Newsletter), args.Error(1)
+}
+
+func (m *MockNewsletterRepository) SetSenderVerified(ctx context.Context, id uuid.UUID, fromEmail string, verified bool) error {
+	args := m.Called(ctx, id, fromEmail, verified)
+	return args.Error(0)
+}
+
+// --- Mock Subscriber Counter ---
+type MockSubscriberCounter struct {
+	mock.Mock
+}
+
+func (m *MockSubscriberCounter) CountActive(ctx context.Context, newsletterIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	args := m.Called(ctx, newsletterIDs)
+	counts := args.Get(0)
+	if counts == nil {
+		return nil, args.Error(1)
+	}
+	return counts.(map[uuid.UUID]int), args.Error(1)
+}
+
 // --- Tests for Create ---
 
 func TestCreateNewsletter_Success(t *testing.T) {
 	mockRepo := new(MockNewsletterRepository)
-	ns := application.NewNewsletterService(mockRepo)
+	ns := application.NewNewsletterService(mockRepo, nil)
 
 	newsletter := &domain.Newsletter{
 		OwnerID: uuid.New(),
@@ -63,9 +135,84 @@ func TestCreateNewsletter_Success(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestCreateNewsletter_GeneratesSlug(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	newsletter := &domain.Newsletter{OwnerID: uuid.New(), Name: "Tech News"}
+
+	var slugs []string
+	mockRepo.On("Create", mock.Anything, newsletter).Run(func(args mock.Arguments) {
+		slugs = append(slugs, args.Get(1).(*domain.Newsletter).Slug)
+	}).Return(nil, domain.ErrSlugTaken).Once()
+	mockRepo.On("Create", mock.Anything, newsletter).Return(newsletter, nil).Once()
+
+	result, err := ns.Create(newsletter)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tech-news"}, slugs)
+	assert.Regexp(t, `^tech-news-[0-9a-f]{6}$`, result.Slug)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateNewsletter_ChosenSlug(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	taken := &domain.Newsletter{OwnerID: uuid.New(), Name: "Tech News", Slug: "tech"}
+	mockRepo.On("Create", mock.Anything, taken).Return(nil, domain.ErrSlugTaken).Once()
+
+	_, err := ns.Create(taken)
+	assert.ErrorIs(t, err, domain.ErrSlugTaken)
+
+	_, err = ns.Create(&domain.Newsletter{Name: "Tech News", Slug: "Tech News"})
+	assert.ErrorIs(t, err, domain.ErrInvalidSlug)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateNewsletter_SanitizesContent(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	newsletter := &domain.Newsletter{
+		OwnerID:     uuid.New(),
+		Name:        `Tech <script>alert(1)</script>&amp; News`,
+		Description: `<b>Weekly</b> <img src=x onerror="alert(1)">updates`,
+	}
+	mockRepo.On("Create", mock.Anything, newsletter).Return(newsletter, nil)
+
+	result, err := ns.Create(newsletter)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Tech & News", result.Name)
+	assert.Equal(t, "Weekly updates", result.Description)
+	assert.Equal(t, "tech-news", result.Slug)
+}
+
+func TestCreateNewsletter_LengthLimits(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+	ns.SetLengthLimits(10, 20)
+
+	_, err := ns.Create(&domain.Newsletter{Name: "Much Too Long Name"})
+	assert.ErrorIs(t, err, domain.ErrInvalidNewsletter)
+
+	_, err = ns.Create(&domain.Newsletter{Name: "Tech", Description: strings.Repeat("é", 21)})
+	assert.ErrorIs(t, err, domain.ErrInvalidNewsletter)
+
+	// Markup does not count towards the limit.
+	newsletter := &domain.Newsletter{Name: "<em>Tech News</em>", Description: strings.Repeat("é", 20)}
+	mockRepo.On("Create", mock.Anything, newsletter).Return(newsletter, nil)
+	_, err = ns.Create(newsletter)
+	assert.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+}
+
 func TestCreateNewsletter_Failure(t *testing.T) {
 	mockRepo := new(MockNewsletterRepository)
-	ns := application.NewNewsletterService(mockRepo)
+	ns := application.NewNewsletterService(mockRepo, nil)
 
 	newsletter := &domain.Newsletter{
 		OwnerID: uuid.New(),
@@ -83,10 +230,24 @@ func TestCreateNewsletter_Failure(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestGetAllNewsletters_TrimsQuery(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	ownerID := uuid.New()
+	mockRepo.On("GetAll", mock.Anything, ownerID, domain.NewsletterFilter{Query: "tech"}, domain.NewsletterSort{}, 10, 1).Return([]*domain.Newsletter{}, nil)
+	mockRepo.On("Count", mock.Anything, ownerID, domain.NewsletterFilter{Query: "tech"}).Return(0, nil)
+
+	_, _, err := ns.GetAll(ownerID, domain.NewsletterFilter{Query: "  tech "}, domain.NewsletterSort{}, 10, 1)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
 // Timeout / context test
 func TestCreateNewsletter_ContextTimeout(t *testing.T) {
 	mockRepo := new(MockNewsletterRepository)
-	ns := application.NewNewsletterService(mockRepo)
+	ns := application.NewNewsletterService(mockRepo, nil)
 
 	newsletter := &domain.Newsletter{
 		OwnerID: uuid.New(),
@@ -111,7 +272,7 @@ func TestCreateNewsletter_ContextTimeout(t *testing.T) {
 
 func TestGetAllNewsletters_Success(t *testing.T) {
 	mockRepo := new(MockNewsletterRepository)
-	ns := application.NewNewsletterService(mockRepo)
+	ns := application.NewNewsletterService(mockRepo, nil)
 
 	ownerID := uuid.New()
 	newsletters := []*domain.Newsletter{
@@ -119,25 +280,27 @@ func TestGetAllNewsletters_Success(t *testing.T) {
 		{ID: uuid.New(), OwnerID: ownerID, Name: "Science"},
 	}
 
-	mockRepo.On("GetAll", mock.Anything, ownerID, 10, 1).Return(newsletters, nil)
+	mockRepo.On("GetAll", mock.Anything, ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 10, 1).Return(newsletters, nil)
+	mockRepo.On("Count", mock.Anything, ownerID, domain.NewsletterFilter{}).Return(12, nil)
 
-	result, err := ns.GetAll(ownerID, 10, 1)
+	result, total, err := ns.GetAll(ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 10, 1)
 
 	assert.NoError(t, err)
 	assert.Equal(t, newsletters, result)
+	assert.Equal(t, 12, total)
 
 	mockRepo.AssertExpectations(t)
 }
 
 func TestGetAllNewsletters_Failure(t *testing.T) {
 	mockRepo := new(MockNewsletterRepository)
-	ns := application.NewNewsletterService(mockRepo)
+	ns := application.NewNewsletterService(mockRepo, nil)
 
 	ownerID := uuid.New()
 
-	mockRepo.On("GetAll", mock.Anything, ownerID, 10, 1).Return(nil, errors.New("db error"))
+	mockRepo.On("GetAll", mock.Anything, ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 10, 1).Return(nil, errors.New("db error"))
 
-	result, err := ns.GetAll(ownerID, 10, 1)
+	result, _, err := ns.GetAll(ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 10, 1)
 
 	assert.Nil(t, result)
 	assert.Error(t, err)
@@ -149,20 +312,369 @@ func TestGetAllNewsletters_Failure(t *testing.T) {
 // Timeout / context test
 func TestGetAllNewsletters_ContextTimeout(t *testing.T) {
 	mockRepo := new(MockNewsletterRepository)
-	ns := application.NewNewsletterService(mockRepo)
+	ns := application.NewNewsletterService(mockRepo, nil)
 
 	ownerID := uuid.New()
 
-	mockRepo.On("GetAll", mock.Anything, ownerID, 10, 1).Run(func(args mock.Arguments) {
+	mockRepo.On("GetAll", mock.Anything, ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 10, 1).Run(func(args mock.Arguments) {
 		ctx := args.Get(0).(context.Context)
 		<-ctx.Done()
 	}).Return(nil, context.DeadlineExceeded)
 
 	start := time.Now()
-	_, err := ns.GetAll(ownerID, 10, 1)
+	_, _, err := ns.GetAll(ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 10, 1)
 	elapsed := time.Since(start)
 
 	assert.ErrorIs(t, err, context.DeadlineExceeded)
 	assert.LessOrEqual(t, elapsed.Milliseconds(), int64(1000)) // 500ms + small overhead
 	mockRepo.AssertExpectations(t)
 }
+
+func TestGetAllNewsletters_BySubscriberCount(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	mockCounter := new(MockSubscriberCounter)
+	ns := application.NewNewsletterService(mockRepo, mockCounter)
+
+	ownerID := uuid.New()
+	small := &domain.Newsletter{ID: uuid.New(), Name: "Small"}
+	large := &domain.Newsletter{ID: uuid.New(), Name: "Large"}
+	empty := &domain.Newsletter{ID: uuid.New(), Name: "Empty"}
+	filter := domain.NewsletterFilter{Query: "news"}
+
+	mockRepo.On("GetAll", mock.Anything, ownerID, filter, domain.NewsletterSort{Field: domain.SortCreatedAt}, 100, 1).
+		Return([]*domain.Newsletter{small, large, empty}, nil)
+	mockCounter.On("CountActive", mock.Anything, []uuid.UUID{small.ID, large.ID, empty.ID}).
+		Return(map[uuid.UUID]int{small.ID: 3, large.ID: 40}, nil)
+
+	result, total, err := ns.GetAll(ownerID, filter, domain.NewsletterSort{Field: domain.SortSubscriberCount, Descending: true}, 2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []*domain.Newsletter{large, small}, result)
+	assert.Equal(t, 3, total)
+
+	result, _, err = ns.GetAll(ownerID, filter, domain.NewsletterSort{Field: domain.SortSubscriberCount, Descending: true}, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []*domain.Newsletter{empty}, result)
+
+	result, _, err = ns.GetAll(ownerID, filter, domain.NewsletterSort{Field: domain.SortSubscriberCount, Descending: true}, 2, 3)
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+
+	mockRepo.AssertExpectations(t)
+	mockCounter.AssertExpectations(t)
+}
+
+func TestGetAllNewsletters_InvalidSort(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+
+	_, _, err := application.NewNewsletterService(mockRepo, new(MockSubscriberCounter)).
+		GetAll(uuid.New(), domain.NewsletterFilter{}, domain.NewsletterSort{Field: "name; drop table newsletters"}, 10, 1)
+	assert.ErrorIs(t, err, domain.ErrInvalidSort)
+
+	// Without a subscriber counter, newsletters cannot be sorted by subscriber count.
+	_, _, err = application.NewNewsletterService(mockRepo, nil).
+		GetAll(uuid.New(), domain.NewsletterFilter{}, domain.NewsletterSort{Field: domain.SortSubscriberCount}, 10, 1)
+	assert.ErrorIs(t, err, domain.ErrInvalidSort)
+
+	mockRepo.AssertNotCalled(t, "GetAll")
+}
+
+func TestListNewsletters(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	service := application.NewNewsletterService(mockRepo, nil)
+	ownerID := uuid.New()
+	cursor := pagination.Cursor{CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), ID: uuid.NewString()}
+
+	expected := &domain.NewsletterPage{Newsletters: []*domain.Newsletter{}}
+	mockRepo.On("List", mock.Anything, ownerID, domain.NewsletterQuery{
+		Filter:     domain.NewsletterFilter{Query: "go"},
+		Descending: true,
+		Limit:      pagination.MaxLimit,
+		After:      &cursor,
+	}).Return(expected, nil)
+
+	page, err := service.List(ownerID, domain.NewsletterFilter{Query: " go "}, true, 1000, cursor.Encode())
+	assert.NoError(t, err)
+	assert.Same(t, expected, page)
+
+	_, err = service.List(ownerID, domain.NewsletterFilter{}, false, 10, "not a cursor")
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+	_, err = service.List(ownerID, domain.NewsletterFilter{}, false, 10, pagination.Cursor{ID: "not a uuid"}.Encode())
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+
+	mockRepo.AssertExpectations(t)
+}
+
+// --- Tests for Get ---
+
+func TestGetNewsletter_NotFound(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	id := uuid.New()
+
+	mockRepo.On("Get", mock.Anything, id).Return(nil, domain.ErrNewsletterNotFound)
+
+	result, err := ns.Get(id)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, domain.ErrNewsletterNotFound)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetNewsletterBySlug(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	newsletter := &domain.Newsletter{ID: uuid.New(), Slug: "tech-news"}
+	mockRepo.On("GetBySlug", mock.Anything, "tech-news").Return(newsletter, nil)
+
+	result, err := ns.GetBySlug("tech-news")
+	assert.NoError(t, err)
+	assert.Equal(t, newsletter, result)
+
+	// Invalid slugs cannot exist and are not looked up.
+	_, err = ns.GetBySlug("../admin")
+	assert.ErrorIs(t, err, domain.ErrNewsletterNotFound)
+
+	mockRepo.AssertExpectations(t)
+}
+
+// --- Tests for UpdateSlug ---
+
+func TestUpdateSlug_Success(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	id, ownerID := uuid.New(), uuid.New()
+	updated := &domain.Newsletter{ID: id, OwnerID: ownerID, Slug: "weekly"}
+	mockRepo.On("UpdateSlug", mock.Anything, id, ownerID, "weekly").Return(updated, nil)
+
+	result, err := ns.UpdateSlug(id, ownerID, "weekly")
+
+	assert.NoError(t, err)
+	assert.Equal(t, updated, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateSlug_Invalid(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	for _, slug := range []string{"", "ab", "Weekly", "weekly news", "-weekly", "weekly--news", "weekly-", strings.Repeat("a", 65)} {
+		_, err := ns.UpdateSlug(uuid.New(), uuid.New(), slug)
+		assert.ErrorIs(t, err, domain.ErrInvalidSlug, slug)
+	}
+
+	mockRepo.AssertNotCalled(t, "UpdateSlug")
+}
+
+// --- Tests for UpdateSettings ---
+
+func TestUpdateSettings_Success(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	id, ownerID := uuid.New(), uuid.New()
+	settings := domain.Settings{AllowedOrigins: []string{"https://example.com", "http://localhost:3000", "*"}}
+	updated := &domain.Newsletter{ID: id, OwnerID: ownerID, Settings: settings}
+
+	mockRepo.On("UpdateSettings", mock.Anything, id, ownerID, settings).Return(updated, nil)
+
+	result, err := ns.UpdateSettings(id, ownerID, settings)
+
+	assert.NoError(t, err)
+	assert.Equal(t, updated, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateSettings_InvalidOrigin(t *testing.T) {
+	invalid := []string{"example.com", "https://example.com/path", "ftp://example.com", "https://"}
+
+	for _, origin := range invalid {
+		mockRepo := new(MockNewsletterRepository)
+		ns := application.NewNewsletterService(mockRepo, nil)
+
+		settings := domain.Settings{AllowedOrigins: []string{origin}}
+
+		result, err := ns.UpdateSettings(uuid.New(), uuid.New(), settings)
+
+		assert.Nil(t, result, origin)
+		assert.ErrorIs(t, err, domain.ErrInvalidOrigin, origin)
+		mockRepo.AssertNotCalled(t, "UpdateSettings", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+func TestUpdateSettings_InvalidSender(t *testing.T) {
+	invalid := []domain.Settings{
+		{FromEmail: "not-an-address"},
+		{FromEmail: "Weekly <news@example.com>"},
+		{FromName: "Weekly\r\nBcc: victim@example.com", FromEmail: "news@example.com"},
+	}
+
+	for _, settings := range invalid {
+		mockRepo := new(MockNewsletterRepository)
+		ns := application.NewNewsletterService(mockRepo, nil)
+
+		result, err := ns.UpdateSettings(uuid.New(), uuid.New(), settings)
+
+		assert.Nil(t, result, settings)
+		assert.ErrorIs(t, err, domain.ErrInvalidSender, settings)
+		mockRepo.AssertNotCalled(t, "UpdateSettings", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+func TestUpdateSettings_InvalidBranding(t *testing.T) {
+	invalid := []domain.Branding{
+		{UnsubscribeRedirectURL: "/goodbye"},
+		{UnsubscribeRedirectURL: "javascript:alert(1)"},
+		{LogoURL: "ftp://example.com/logo.png"},
+		{BrandColor: "red"},
+		{BrandColor: "#12345"},
+		{FooterText: strings.Repeat("a", domain.MaxFooterLength+1)},
+	}
+
+	for _, branding := range invalid {
+		mockRepo := new(MockNewsletterRepository)
+		ns := application.NewNewsletterService(mockRepo, nil)
+
+		result, err := ns.UpdateSettings(uuid.New(), uuid.New(), domain.Settings{Branding: branding})
+
+		assert.Nil(t, result, branding)
+		assert.ErrorIs(t, err, domain.ErrInvalidBranding, branding)
+		mockRepo.AssertNotCalled(t, "UpdateSettings", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+func TestUpdateSettings_InvalidEnvelope(t *testing.T) {
+	invalid := []notificationdomain.Envelope{
+		{ReplyTo: "not an address"},
+		{Cc: []string{"a@example.com", "b@"}},
+		{Bcc: make([]string, notificationdomain.MaxCopies+1)},
+		{Headers: map[string]string{"List-Unsubscribe": "<https://example.com>"}},
+		{Headers: map[string]string{"subject": "Hijacked"}},
+		{Headers: map[string]string{"X Campaign": "spring"}},
+		{Headers: map[string]string{"X-Campaign": "spring\r\nBcc: victim@example.com"}},
+		{Headers: map[string]string{"X-Campaign": ""}},
+	}
+
+	for _, envelope := range invalid {
+		mockRepo := new(MockNewsletterRepository)
+		ns := application.NewNewsletterService(mockRepo, nil)
+
+		result, err := ns.UpdateSettings(uuid.New(), uuid.New(), domain.Settings{Envelope: envelope})
+
+		assert.Nil(t, result, envelope)
+		assert.ErrorIs(t, err, notificationdomain.ErrInvalidEnvelope, envelope)
+		mockRepo.AssertNotCalled(t, "UpdateSettings", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+// --- Tests for SetSenderVerified ---
+
+func TestSetSenderVerified_Success(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	id := uuid.New()
+	mockRepo.On("SetSenderVerified", mock.Anything, id, "news@example.com", true).Return(nil)
+
+	err := ns.SetSenderVerified(id, "news@example.com", true)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDuplicate_CopiesSettings(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	ownerID := uuid.New()
+	source := &domain.Newsletter{
+		ID:             uuid.New(),
+		OwnerID:        ownerID,
+		Name:           "Tech",
+		Description:    "Tech news",
+		Settings:       domain.Settings{FromEmail: "news@example.com", Language: "de"},
+		SenderVerified: true,
+	}
+	created := &domain.Newsletter{ID: uuid.New(), OwnerID: ownerID, Name: "Tech (copy)", Slug: "tech-copy", Description: "Tech news"}
+	updated := *created
+	updated.Settings = source.Settings
+
+	mockRepo.On("Get", mock.Anything, source.ID).Return(source, nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(n *domain.Newsletter) bool {
+		return n.OwnerID == ownerID && n.Name == "Tech (copy)" && n.Slug == "tech-copy" && n.Description == "Tech news"
+	})).Return(created, nil)
+	mockRepo.On("UpdateSettings", mock.Anything, created.ID, ownerID, source.Settings).Return(&updated, nil)
+	mockRepo.On("SetSenderVerified", mock.Anything, created.ID, "news@example.com", true).Return(nil)
+
+	duplicate, err := ns.Duplicate(source.ID, ownerID, "", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, duplicate.ID)
+	assert.Equal(t, "de", duplicate.Language)
+	assert.True(t, duplicate.SenderVerified)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDuplicate_SettingsFail(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	ownerID := uuid.New()
+	source := &domain.Newsletter{
+		ID:             uuid.New(),
+		OwnerID:        ownerID,
+		Name:           "Tech",
+		Settings:       domain.Settings{FromEmail: "news@example.com"},
+		SenderVerified: true,
+	}
+	created := &domain.Newsletter{ID: uuid.New(), OwnerID: ownerID, Name: "Tech (copy)", Slug: "tech-copy"}
+
+	mockRepo.On("Get", mock.Anything, source.ID).Return(source, nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(created, nil)
+	mockRepo.On("UpdateSettings", mock.Anything, created.ID, ownerID, source.Settings).Return(nil, errors.New("db down"))
+
+	duplicate, err := ns.Duplicate(source.ID, ownerID, "", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, created, duplicate, "created without the settings")
+	assert.False(t, duplicate.SenderVerified)
+	mockRepo.AssertNotCalled(t, "SetSenderVerified", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDuplicate_OtherOwner(t *testing.T) {
+	mockRepo := new(MockNewsletterRepository)
+	ns := application.NewNewsletterService(mockRepo, nil)
+
+	source := &domain.Newsletter{ID: uuid.New(), OwnerID: uuid.New(), Name: "Tech"}
+	mockRepo.On("Get", mock.Anything, source.ID).Return(source, nil)
+
+	_, err := ns.Duplicate(source.ID, uuid.New(), "Spin-off", "")
+
+	assert.ErrorIs(t, err, domain.ErrNewsletterNotFound)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestNewsletter_Sender(t *testing.T) {
+	newsletter := &domain.Newsletter{Settings: domain.Settings{FromName: "Weekly News", FromEmail: "news@example.com"}}
+	assert.Empty(t, newsletter.Sender(), "unverified sender must not be used")
+
+	newsletter.SenderVerified = true
+	assert.Equal(t, `"Weekly News" <news@example.com>`, newsletter.Sender())
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Tech News":                 "tech-news",
+		"  Go -- Weekly!  ":         "go-weekly",
+		"Café Crème 2026":           "caf-cr-me-2026",
+		"Ω":                         "newsletter",
+		strings.Repeat("word ", 20): "word-word-word-word-word-word-word-word-word-wor",
+	}
+	for name, want := range tests {
+		slug := domain.Slugify(name)
+		assert.Equal(t, want, slug, name)
+		assert.True(t, domain.ValidSlug(slug), slug)
+	}
+}
@@ -0,0 +1,217 @@
+// Package domaintest provides the conformance tests that every
+// implementation of domain.NewsletterRepository must pass, so that the
+// services behave the same whichever backend stores newsletters.
+package domaintest
+
+import (
+	"context"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/newsletters/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewsletterRepositoryContract runs the conformance tests against
+// repository. newOwner returns the ID of a new user that newsletters can
+// belong to, such as a row of the users table referenced by the
+// newsletters. Every test works on owners and slugs of its own, so the
+// repository may be shared with other tests and need not be empty.
+func NewsletterRepositoryContract(t *testing.T, repository domain.NewsletterRepository, newOwner func(t *testing.T) uuid.UUID) {
+	ctx := context.Background()
+
+	create := func(t *testing.T, ownerID uuid.UUID, name string) *domain.Newsletter {
+		t.Helper()
+		newsletter, err := repository.Create(ctx, &domain.Newsletter{OwnerID: ownerID, Name: name, Slug: uniqueSlug(), Description: name + " news"})
+		require.NoError(t, err)
+		return newsletter
+	}
+
+	t.Run("Create assigns an ID and a creation time", func(t *testing.T) {
+		ownerID := newOwner(t)
+		slug := uniqueSlug()
+
+		newsletter, err := repository.Create(ctx, &domain.Newsletter{OwnerID: ownerID, Name: "Tech", Slug: slug, Description: "Tech news"})
+
+		require.NoError(t, err)
+		assert.NotEqual(t, uuid.Nil, newsletter.ID)
+		assert.WithinDuration(t, time.Now(), newsletter.CreatedAt, time.Minute)
+		assert.Equal(t, ownerID, newsletter.OwnerID)
+		assert.Equal(t, "Tech", newsletter.Name)
+		assert.Equal(t, slug, newsletter.Slug)
+		assert.Equal(t, []string{}, newsletter.AllowedOrigins)
+		assert.False(t, newsletter.SenderVerified)
+	})
+
+	t.Run("Create with a used slug returns slug taken", func(t *testing.T) {
+		existing := create(t, newOwner(t), "Tech")
+
+		_, err := repository.Create(ctx, &domain.Newsletter{OwnerID: newOwner(t), Name: "Other", Slug: existing.Slug})
+
+		assert.ErrorIs(t, err, domain.ErrSlugTaken)
+	})
+
+	t.Run("Get and GetBySlug return the newsletter", func(t *testing.T) {
+		newsletter := create(t, newOwner(t), "Tech")
+
+		found, err := repository.Get(ctx, newsletter.ID)
+		require.NoError(t, err)
+		assert.Equal(t, newsletter.Name, found.Name)
+
+		found, err = repository.GetBySlug(ctx, newsletter.Slug)
+		require.NoError(t, err)
+		assert.Equal(t, newsletter.ID, found.ID)
+	})
+
+	t.Run("Get and GetBySlug of an unknown newsletter return not found", func(t *testing.T) {
+		_, err := repository.Get(ctx, uuid.New())
+		assert.ErrorIs(t, err, domain.ErrNewsletterNotFound)
+
+		_, err = repository.GetBySlug(ctx, uniqueSlug())
+		assert.ErrorIs(t, err, domain.ErrNewsletterNotFound)
+	})
+
+	t.Run("GetAll pages through the newsletters of the owner and Count counts them", func(t *testing.T) {
+		ownerID := newOwner(t)
+		var created []*domain.Newsletter
+		for _, name := range []string{"Charlie", "alpha", "Bravo"} {
+			created = append(created, create(t, ownerID, name))
+			time.Sleep(time.Millisecond) // Distinct creation times
+		}
+		create(t, newOwner(t), "Other owner")
+
+		first, err := repository.GetAll(ctx, ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 2, 1)
+		require.NoError(t, err)
+		second, err := repository.GetAll(ctx, ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 2, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Charlie", "alpha", "Bravo"}, names(append(first, second...)))
+
+		byName, err := repository.GetAll(ctx, ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{Field: domain.SortName, Descending: true}, 10, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Charlie", "Bravo", "alpha"}, names(byName))
+
+		count, err := repository.Count(ctx, ownerID, domain.NewsletterFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+
+		since := domain.NewsletterFilter{CreatedAfter: created[1].CreatedAt}
+		recent, err := repository.GetAll(ctx, ownerID, since, domain.NewsletterSort{}, 10, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alpha", "Bravo"}, names(recent))
+
+		count, err = repository.Count(ctx, ownerID, since)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("List pages through the newsletters of the owner with cursors", func(t *testing.T) {
+		ownerID := newOwner(t)
+		for _, name := range []string{"Charlie", "alpha", "Bravo"} {
+			create(t, ownerID, name)
+			time.Sleep(time.Millisecond) // Distinct creation times
+		}
+		create(t, newOwner(t), "Other owner")
+
+		var listed []*domain.Newsletter
+		query := domain.NewsletterQuery{Limit: 2}
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 3, "the last page has a next cursor")
+			page, err := repository.List(ctx, ownerID, query)
+			require.NoError(t, err)
+			listed = append(listed, page.Newsletters...)
+			if page.NextCursor == "" {
+				break
+			}
+			query.After, err = pagination.Decode(page.NextCursor)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []string{"Charlie", "alpha", "Bravo"}, names(listed))
+
+		newest, err := repository.List(ctx, ownerID, domain.NewsletterQuery{Descending: true, Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bravo", "alpha"}, names(newest.Newsletters))
+		after, err := pagination.Decode(newest.NextCursor)
+		require.NoError(t, err)
+		oldest, err := repository.List(ctx, ownerID, domain.NewsletterQuery{Descending: true, Limit: 2, After: after})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Charlie"}, names(oldest.Newsletters))
+		assert.Empty(t, oldest.NextCursor)
+
+		empty, err := repository.List(ctx, newOwner(t), domain.NewsletterQuery{Limit: 2})
+		require.NoError(t, err)
+		assert.Empty(t, empty.Newsletters)
+		assert.Empty(t, empty.NextCursor)
+	})
+
+	t.Run("GetAll with an unknown sort field returns invalid sort", func(t *testing.T) {
+		_, err := repository.GetAll(ctx, newOwner(t), domain.NewsletterFilter{}, domain.NewsletterSort{Field: "popularity"}, 10, 1)
+		assert.ErrorIs(t, err, domain.ErrInvalidSort)
+	})
+
+	t.Run("UpdateSettings and UpdateSlug only change newsletters of the owner", func(t *testing.T) {
+		ownerID := newOwner(t)
+		newsletter := create(t, ownerID, "Tech")
+
+		_, err := repository.UpdateSettings(ctx, newsletter.ID, newOwner(t), domain.Settings{Language: "de"})
+		assert.ErrorIs(t, err, domain.ErrNewsletterNotFound)
+		_, err = repository.UpdateSlug(ctx, newsletter.ID, newOwner(t), uniqueSlug())
+		assert.ErrorIs(t, err, domain.ErrNewsletterNotFound)
+
+		updated, err := repository.UpdateSettings(ctx, newsletter.ID, ownerID, domain.Settings{Language: "de", AllowedOrigins: []string{"https://example.com"}})
+		require.NoError(t, err)
+		assert.Equal(t, "de", updated.Language)
+		assert.Equal(t, []string{"https://example.com"}, updated.AllowedOrigins)
+
+		slug := uniqueSlug()
+		updated, err = repository.UpdateSlug(ctx, newsletter.ID, ownerID, slug)
+		require.NoError(t, err)
+		assert.Equal(t, slug, updated.Slug)
+	})
+
+	t.Run("UpdateSlug to a used slug returns slug taken", func(t *testing.T) {
+		ownerID := newOwner(t)
+		newsletter, other := create(t, ownerID, "Tech"), create(t, ownerID, "Science")
+
+		_, err := repository.UpdateSlug(ctx, newsletter.ID, ownerID, other.Slug)
+
+		assert.ErrorIs(t, err, domain.ErrSlugTaken)
+	})
+
+	t.Run("SetSenderVerified only applies to the configured sender", func(t *testing.T) {
+		ownerID := newOwner(t)
+		newsletter := create(t, ownerID, "Tech")
+		_, err := repository.UpdateSettings(ctx, newsletter.ID, ownerID, domain.Settings{FromEmail: "news@example.com"})
+		require.NoError(t, err)
+
+		err = repository.SetSenderVerified(ctx, newsletter.ID, "old@example.com", true)
+		assert.ErrorIs(t, err, domain.ErrNewsletterNotFound)
+
+		require.NoError(t, repository.SetSenderVerified(ctx, newsletter.ID, "news@example.com", true))
+		found, err := repository.Get(ctx, newsletter.ID)
+		require.NoError(t, err)
+		assert.True(t, found.SenderVerified)
+
+		// Changing the sender address invalidates the verification.
+		updated, err := repository.UpdateSettings(ctx, newsletter.ID, ownerID, domain.Settings{FromEmail: "other@example.com"})
+		require.NoError(t, err)
+		assert.False(t, updated.SenderVerified)
+	})
+}
+
+// uniqueSlug returns a slug no other test uses.
+func uniqueSlug() string {
+	return "contract-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:20]
+}
+
+// names returns the names of newsletters, in order.
+func names(newsletters []*domain.Newsletter) []string {
+	result := make([]string, len(newsletters))
+	for i, newsletter := range newsletters {
+		result[i] = newsletter.Name
+	}
+	return result
+}
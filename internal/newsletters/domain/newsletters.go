@@ -2,32 +2,239 @@ package domain
 
 import (
 	"context"
+	"net/mail"
+	apperrors "newsletter/internal/errors"
+	"newsletter/internal/infrastructure/pagination"
+	notificationdomain "newsletter/internal/notifications/domain"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrNewsletterNotFound is returned when a newsletter does not exist or
+	// is not owned by the requesting user.
+	ErrNewsletterNotFound = apperrors.New(apperrors.NotFound, "newsletter not found")
+	// ErrInvalidOrigin is returned when a configured CORS origin is not a valid origin.
+	ErrInvalidOrigin = apperrors.New(apperrors.Validation, "invalid origin")
+	// ErrInvalidSender is returned when the configured sender name or address is invalid.
+	ErrInvalidSender = apperrors.New(apperrors.Validation, "invalid sender")
+	// ErrInvalidLanguage is returned when the configured language is not supported.
+	ErrInvalidLanguage = apperrors.New(apperrors.Validation, "unsupported language")
+	// ErrInvalidBranding is returned when the unsubscribe redirect, logo,
+	// brand color or footer of a newsletter is invalid.
+	ErrInvalidBranding = apperrors.New(apperrors.Validation, "invalid branding")
+	// ErrInvalidSort is returned when a newsletter listing is sorted by an
+	// unknown field.
+	ErrInvalidSort = apperrors.New(apperrors.Validation, "invalid sort")
+	// ErrInvalidSlug is returned when a slug does not follow the slug format.
+	ErrInvalidSlug = apperrors.New(apperrors.Validation, "invalid slug")
+	// ErrSlugTaken is returned when a slug is already used by another newsletter.
+	ErrSlugTaken = apperrors.New(apperrors.Conflict, "slug already taken")
+	// ErrInvalidNewsletter is returned when the name or description of a
+	// newsletter is too long.
+	ErrInvalidNewsletter = apperrors.New(apperrors.Validation, "invalid newsletter")
+)
+
+// MaxFooterLength is the maximum number of characters of the custom footer.
+const MaxFooterLength = 1000
+
+// Default limits of the name and description of a newsletter, in
+// characters. They can be changed with NewsletterService.SetLengthLimits.
+const (
+	DefaultMaxNameLength        = 100
+	DefaultMaxDescriptionLength = 2000
+)
+
+// Bounds of the length of a slug.
+const (
+	MinSlugLength = 3
+	MaxSlugLength = 64
+)
+
+// slugPattern matches slugs: lowercase letters and digits, in words separated
+// by single hyphens.
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ValidSlug reports whether slug can identify a newsletter in public URLs.
+func ValidSlug(slug string) bool {
+	return len(slug) >= MinSlugLength && len(slug) <= MaxSlugLength && slugPattern.MatchString(slug)
+}
+
+// Slugify derives a slug from the name of a newsletter, such as
+// "weekly-tech-news" from "Weekly Tech News!". Characters other than ASCII
+// letters and digits separate words. Names without enough of them give
+// "newsletter". The slug is cut so that a suffix can still be appended to
+// make it unique.
+func Slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+
+	slug := strings.Join(words, "-")
+	if len(slug) > MaxSlugLength-16 {
+		slug = strings.TrimRight(slug[:MaxSlugLength-16], "-")
+	}
+	if len(slug) < MinSlugLength {
+		return "newsletter"
+	}
+	return slug
+}
+
+// Settings holds the owner-configurable options of a newsletter.
+type Settings struct {
+	AllowedOrigins []string `json:"allowed_origins"` // Origins allowed to call the public subscribe endpoint from a browser
+	FromName       string   `json:"from_name"`       // Display name used as sender of outgoing emails
+	FromEmail      string   `json:"from_email"`      // Address used as sender of outgoing emails, once verified
+	Language       string   `json:"language"`        // Default language of system emails, such as "de"; empty for English
+	Branding                // Look of the unsubscribe pages and emails
+
+	// Reply-To, copies and custom headers of the emails of every campaign,
+	// which campaigns may override
+	notificationdomain.Envelope
+}
+
+// Branding customizes what subscribers see of a newsletter: the footer of
+// its emails and the page shown when they unsubscribe.
+type Branding struct {
+	// UnsubscribeRedirectURL is where subscribers are sent after unsubscribing
+	// from an email link; empty to show the confirmation page instead.
+	UnsubscribeRedirectURL string `json:"unsubscribe_redirect_url"`
+	LogoURL                string `json:"logo_url"`    // Image shown on the unsubscribe pages
+	BrandColor             string `json:"brand_color"` // Accent color of the unsubscribe pages, such as "#0055ff"
+	FooterText             string `json:"footer_text"` // Plain text appended to every outgoing email
+}
+
+// AllowsOrigin reports whether a browser origin may call the public endpoints
+// of the newsletter. The wildcard "*" allows every origin.
+func (s Settings) AllowsOrigin(origin string) bool {
+	for _, allowed := range s.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// NewsletterFilter narrows a newsletter listing. Zero values disable a filter.
+type NewsletterFilter struct {
+	// Query is a full-text search of the name and description. It accepts
+	// web search syntax: "quoted phrases", "or" and -excluded words.
+	Query string
+	// CreatedAfter and CreatedBefore restrict the listing to the newsletters
+	// created in [CreatedAfter, CreatedBefore).
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// Fields a newsletter listing can be sorted by.
+const (
+	SortCreatedAt       = "created_at"
+	SortName            = "name"
+	SortSubscriberCount = "subscriber_count" // Active subscribers
+)
+
+// NewsletterSort orders a newsletter listing. The zero value orders search
+// results by relevance and other listings by creation time, oldest first.
+type NewsletterSort struct {
+	Field      string // One of the Sort constants, or empty for the default order
+	Descending bool
+}
+
+// NewsletterQuery selects a page of a newsletter listing with keyset
+// pagination, which orders the newsletters by creation time then ID. Unlike
+// pages of GetAll, its pages do not shift when newsletters are created, and
+// do not get slower to fetch the further they are.
+type NewsletterQuery struct {
+	Filter     NewsletterFilter
+	Descending bool // Newest first
+	Limit      int
+	// After is the position of the last newsletter of the previous page,
+	// nil for the first page.
+	After *pagination.Cursor
+}
+
+// NewsletterPage is a page of a newsletter listing with keyset pagination.
+type NewsletterPage struct {
+	Newsletters []*Newsletter `json:"newsletters"`
+	// NextCursor selects the next page; empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SubscriberCounter counts the active subscribers of newsletters. It is
+// implemented by the subscription store, which lives outside the newsletter
+// database, and is needed to sort listings by SortSubscriberCount.
+type SubscriberCounter interface {
+	CountActive(ctx context.Context, newsletterIDs []uuid.UUID) (map[uuid.UUID]int, error)
+}
+
 // Newsletter represents a newsletter object.
 type Newsletter struct {
 	ID          uuid.UUID `json:"id"`          // ID of the newsletter
 	OwnerID     uuid.UUID `json:"owner_id"`    // There is only one owner for each newsletter
 	Name        string    `json:"name"`        // Name of the newsletter
+	Slug        string    `json:"slug"`        // Unique identifier of the newsletter in public URLs
 	Description string    `json:"description"` // Description of the newsletter
-	CreatedAt   time.Time `json:"created_at"`  // Creation time of the newsletter
+	Settings              // Owner-configurable options
+	// SenderVerified reports whether FromEmail has been verified with the email provider
+	SenderVerified bool      `json:"sender_verified"`
+	CreatedAt      time.Time `json:"created_at"` // Creation time of the newsletter
+}
+
+// Sender returns the formatted "from" address of the newsletter, such as
+// "Weekly News <news@example.com>". It returns an empty string when no
+// verified sender address is configured, in which case the default sender
+// of the service should be used.
+func (n *Newsletter) Sender() string {
+	if n.FromEmail == "" || !n.SenderVerified {
+		return ""
+	}
+	return (&mail.Address{Name: n.FromName, Address: n.FromEmail}).String()
 }
 
 // NewsletterService is an interface that contains a collection of method signatures
-// which will be implemented in application level and are responsible for creating a newsletter
-// and getting a list of all of them that belong to a particular user.
+// which will be implemented in application level and are responsible for creating a newsletter,
+// getting a list of all of them that belong to a particular user, and managing their settings.
 type NewsletterService interface {
 	Create(newsletter *Newsletter) (*Newsletter, error)
-	GetAll(ownerID uuid.UUID, limit, page int) ([]*Newsletter, error)
+	// GetAll returns a page of the newsletters of ownerID matching filter,
+	// along with the total number of matching newsletters.
+	GetAll(ownerID uuid.UUID, filter NewsletterFilter, sort NewsletterSort, limit, page int) ([]*Newsletter, int, error)
+	// List returns the page of the newsletters of ownerID matching filter
+	// that follows cursor, the first one when cursor is empty. It returns
+	// pagination.ErrInvalidCursor when cursor was not returned by List.
+	List(ownerID uuid.UUID, filter NewsletterFilter, descending bool, limit int, cursor string) (*NewsletterPage, error)
+	Get(id uuid.UUID) (*Newsletter, error)
+	GetBySlug(slug string) (*Newsletter, error)
+	UpdateSettings(id, ownerID uuid.UUID, settings Settings) (*Newsletter, error)
+	UpdateSlug(id, ownerID uuid.UUID, slug string) (*Newsletter, error)
+	SetSenderVerified(id uuid.UUID, fromEmail string, verified bool) error
+	// Duplicate creates a newsletter of ownerID with the description and
+	// settings of the newsletter id, named name, or after it when empty.
+	Duplicate(id, ownerID uuid.UUID, name, slug string) (*Newsletter, error)
 }
 
 // NewsletterRepository is an interface that contains a collection of method signatures
-// which will be implemented in persistence level and are responsible for creating a newsletter
-// and getting a list of all of them that belong to a particular user.
+// which will be implemented in persistence level and are responsible for creating a newsletter,
+// getting a list of all of them that belong to a particular user, and managing their settings.
 type NewsletterRepository interface {
+	// Create returns ErrSlugTaken when the slug of newsletter is used.
 	Create(ctx context.Context, newsletter *Newsletter) (*Newsletter, error)
-	GetAll(ctx context.Context, ownerID uuid.UUID, limit, page int) ([]*Newsletter, error)
+	// GetAll supports every sort field but SortSubscriberCount, which the
+	// service applies itself with a SubscriberCounter.
+	GetAll(ctx context.Context, ownerID uuid.UUID, filter NewsletterFilter, sort NewsletterSort, limit, page int) ([]*Newsletter, error)
+	// Count returns the number of newsletters of ownerID matching filter,
+	// that is the number of items GetAll pages through.
+	Count(ctx context.Context, ownerID uuid.UUID, filter NewsletterFilter) (int, error)
+	// List returns a page of the newsletters of ownerID matching the
+	// filter of query, fetching up to query.Limit newsletters.
+	List(ctx context.Context, ownerID uuid.UUID, query NewsletterQuery) (*NewsletterPage, error)
+	Get(ctx context.Context, id uuid.UUID) (*Newsletter, error)
+	GetBySlug(ctx context.Context, slug string) (*Newsletter, error)
+	UpdateSettings(ctx context.Context, id, ownerID uuid.UUID, settings Settings) (*Newsletter, error)
+	// UpdateSlug returns ErrSlugTaken when slug is used by another newsletter.
+	UpdateSlug(ctx context.Context, id, ownerID uuid.UUID, slug string) (*Newsletter, error)
+	SetSenderVerified(ctx context.Context, id uuid.UUID, fromEmail string, verified bool) error
 }
@@ -0,0 +1,309 @@
+// Package memory stores newsletters in memory, for local development
+// without a database. Everything is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/newsletters/domain"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewsletterRepository implements domain.NewsletterRepository in memory.
+type NewsletterRepository struct {
+	mu          sync.RWMutex
+	newsletters []*domain.Newsletter // In creation order
+}
+
+func NewNewsletterRepository() *NewsletterRepository {
+	return &NewsletterRepository{}
+}
+
+// clone returns a copy of newsletter that callers may modify.
+func clone(newsletter *domain.Newsletter) *domain.Newsletter {
+	copied := *newsletter
+	copied.AllowedOrigins = append([]string{}, newsletter.AllowedOrigins...)
+	return &copied
+}
+
+// find returns the stored newsletter id, or nil. The caller holds mu.
+func (nr *NewsletterRepository) find(id uuid.UUID) *domain.Newsletter {
+	for _, newsletter := range nr.newsletters {
+		if newsletter.ID == id {
+			return newsletter
+		}
+	}
+	return nil
+}
+
+// slugTaken reports whether slug is used by another newsletter than id.
+// The caller holds mu.
+func (nr *NewsletterRepository) slugTaken(slug string, id uuid.UUID) bool {
+	for _, newsletter := range nr.newsletters {
+		if newsletter.Slug == slug && newsletter.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+// Create stores a new newsletter. It returns domain.ErrSlugTaken if the
+// slug is used.
+func (nr *NewsletterRepository) Create(ctx context.Context, newsletter *domain.Newsletter) (*domain.Newsletter, error) {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	if nr.slugTaken(newsletter.Slug, uuid.Nil) {
+		return nil, domain.ErrSlugTaken
+	}
+
+	stored := &domain.Newsletter{
+		ID:          uuid.New(),
+		OwnerID:     newsletter.OwnerID,
+		Name:        newsletter.Name,
+		Slug:        newsletter.Slug,
+		Description: newsletter.Description,
+		Settings:    domain.Settings{AllowedOrigins: newsletter.AllowedOrigins},
+		CreatedAt:   time.Now(),
+	}
+	nr.newsletters = append(nr.newsletters, stored)
+
+	return clone(stored), nil
+}
+
+// GetAll returns a page of the newsletters of ownerID matching filter.
+//
+// The full-text search is an approximation of the PostgreSQL one: every
+// word or "quoted phrase" must occur in the name or description, words
+// prefixed with "-" must not, and "or" separates alternatives. Matches are
+// listed newest first, as relevance is not computed.
+func (nr *NewsletterRepository) GetAll(ctx context.Context, ownerID uuid.UUID, filter domain.NewsletterFilter, sort domain.NewsletterSort, limit, page int) ([]*domain.Newsletter, error) {
+	compare, err := ordering(filter, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := nr.matching(ownerID, filter)
+	slices.SortStableFunc(matches, compare)
+
+	if page < 1 {
+		page = 1
+	}
+	start := min((page-1)*limit, len(matches))
+	end := min(start+limit, len(matches))
+
+	return matches[start:end], nil
+}
+
+// Count returns the number of newsletters of ownerID matching filter.
+func (nr *NewsletterRepository) Count(ctx context.Context, ownerID uuid.UUID, filter domain.NewsletterFilter) (int, error) {
+	return len(nr.matching(ownerID, filter)), nil
+}
+
+// List returns a page of the newsletters of ownerID matching the filter of
+// query, ordered by creation time then ID, starting after query.After.
+func (nr *NewsletterRepository) List(ctx context.Context, ownerID uuid.UUID, query domain.NewsletterQuery) (*domain.NewsletterPage, error) {
+	position := func(createdAt time.Time, id string) func(*domain.Newsletter) int {
+		return func(newsletter *domain.Newsletter) int {
+			result := newsletter.CreatedAt.Compare(createdAt)
+			if result == 0 {
+				result = strings.Compare(newsletter.ID.String(), id)
+			}
+			if query.Descending {
+				result = -result
+			}
+			return result
+		}
+	}
+
+	matches := nr.matching(ownerID, query.Filter)
+	slices.SortFunc(matches, func(a, b *domain.Newsletter) int {
+		return position(b.CreatedAt, b.ID.String())(a)
+	})
+	if query.After != nil {
+		after := position(query.After.CreatedAt, query.After.ID)
+		matches = slices.DeleteFunc(matches, func(newsletter *domain.Newsletter) bool { return after(newsletter) <= 0 })
+	}
+
+	page := &domain.NewsletterPage{Newsletters: matches}
+	if len(matches) > query.Limit {
+		page.Newsletters = matches[:query.Limit]
+		last := page.Newsletters[len(page.Newsletters)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID.String()}.Encode()
+	}
+	return page, nil
+}
+
+// matching returns copies of the newsletters of ownerID matching filter,
+// in creation order.
+func (nr *NewsletterRepository) matching(ownerID uuid.UUID, filter domain.NewsletterFilter) []*domain.Newsletter {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+
+	matches := []*domain.Newsletter{}
+	for _, newsletter := range nr.newsletters {
+		switch {
+		case newsletter.OwnerID != ownerID:
+		case !filter.CreatedAfter.IsZero() && newsletter.CreatedAt.Before(filter.CreatedAfter):
+		case !filter.CreatedBefore.IsZero() && !newsletter.CreatedAt.Before(filter.CreatedBefore):
+		case filter.Query != "" && !search(filter.Query, newsletter.Name+" "+newsletter.Description):
+		default:
+			matches = append(matches, clone(newsletter))
+		}
+	}
+	return matches
+}
+
+// ordering returns the comparison ordering newsletters by sort, ties being
+// broken by ID as in PostgreSQL.
+func ordering(filter domain.NewsletterFilter, sort domain.NewsletterSort) (func(a, b *domain.Newsletter) int, error) {
+	var compare func(a, b *domain.Newsletter) int
+	switch sort.Field {
+	case "":
+		compare = func(a, b *domain.Newsletter) int { return a.CreatedAt.Compare(b.CreatedAt) }
+		if filter.Query != "" {
+			compare = func(a, b *domain.Newsletter) int { return b.CreatedAt.Compare(a.CreatedAt) }
+		}
+		return compare, nil
+	case domain.SortCreatedAt:
+		compare = func(a, b *domain.Newsletter) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	case domain.SortName:
+		compare = func(a, b *domain.Newsletter) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", domain.ErrInvalidSort, sort.Field)
+	}
+
+	return func(a, b *domain.Newsletter) int {
+		result := compare(a, b)
+		if sort.Descending {
+			result = -result
+		}
+		if result == 0 {
+			result = strings.Compare(a.ID.String(), b.ID.String())
+		}
+		return result
+	}, nil
+}
+
+// search reports whether text matches query, see GetAll.
+func search(query, text string) bool {
+	text = strings.ToLower(text)
+	for _, alternative := range strings.Split(strings.ToLower(query), " or ") {
+		if matchesAll(terms(alternative), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAll reports whether text contains every term and no excluded one.
+func matchesAll(terms []string, text string) bool {
+	for _, term := range terms {
+		if excluded, ok := strings.CutPrefix(term, "-"); ok {
+			if excluded != "" && strings.Contains(text, excluded) {
+				return false
+			}
+		} else if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return len(terms) > 0
+}
+
+// terms splits query into words and "quoted phrases".
+func terms(query string) []string {
+	var result []string
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				result = append(result, phrase)
+			}
+			continue
+		}
+		result = append(result, strings.Fields(part)...)
+	}
+	return result
+}
+
+// Get returns the newsletter id, or domain.ErrNewsletterNotFound.
+func (nr *NewsletterRepository) Get(ctx context.Context, id uuid.UUID) (*domain.Newsletter, error) {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+
+	newsletter := nr.find(id)
+	if newsletter == nil {
+		return nil, domain.ErrNewsletterNotFound
+	}
+	return clone(newsletter), nil
+}
+
+// GetBySlug returns the newsletter with slug, or domain.ErrNewsletterNotFound.
+func (nr *NewsletterRepository) GetBySlug(ctx context.Context, slug string) (*domain.Newsletter, error) {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+
+	for _, newsletter := range nr.newsletters {
+		if newsletter.Slug == slug {
+			return clone(newsletter), nil
+		}
+	}
+	return nil, domain.ErrNewsletterNotFound
+}
+
+// UpdateSettings replaces the settings of a newsletter owned by ownerID. The
+// sender verification is kept only if the sender address is unchanged.
+func (nr *NewsletterRepository) UpdateSettings(ctx context.Context, id, ownerID uuid.UUID, settings domain.Settings) (*domain.Newsletter, error) {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	newsletter := nr.find(id)
+	if newsletter == nil || newsletter.OwnerID != ownerID {
+		return nil, domain.ErrNewsletterNotFound
+	}
+
+	newsletter.SenderVerified = newsletter.SenderVerified && newsletter.FromEmail == settings.FromEmail
+	newsletter.Settings = settings
+	newsletter.AllowedOrigins = append([]string{}, settings.AllowedOrigins...)
+
+	return clone(newsletter), nil
+}
+
+// UpdateSlug changes the slug of a newsletter owned by ownerID.
+func (nr *NewsletterRepository) UpdateSlug(ctx context.Context, id, ownerID uuid.UUID, slug string) (*domain.Newsletter, error) {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	newsletter := nr.find(id)
+	if newsletter == nil || newsletter.OwnerID != ownerID {
+		return nil, domain.ErrNewsletterNotFound
+	}
+	if nr.slugTaken(slug, id) {
+		return nil, domain.ErrSlugTaken
+	}
+
+	newsletter.Slug = slug
+	return clone(newsletter), nil
+}
+
+// SetSenderVerified records whether the sender address of a newsletter is
+// verified, provided fromEmail is still its sender address.
+func (nr *NewsletterRepository) SetSenderVerified(ctx context.Context, id uuid.UUID, fromEmail string, verified bool) error {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	newsletter := nr.find(id)
+	if newsletter == nil || newsletter.FromEmail != fromEmail {
+		return domain.ErrNewsletterNotFound
+	}
+
+	newsletter.SenderVerified = verified
+	return nil
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"newsletter/internal/newsletters/domain"
+	"newsletter/internal/newsletters/domain/domaintest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewsletterRepositoryContract(t *testing.T) {
+	domaintest.NewsletterRepositoryContract(t, NewNewsletterRepository(), func(t *testing.T) uuid.UUID { return uuid.New() })
+}
+
+func TestGetAll_Search(t *testing.T) {
+	repository := NewNewsletterRepository()
+	ownerID := uuid.New()
+	for i, name := range []string{"Go weekly", "Rust weekly", "Go monthly digest"} {
+		_, err := repository.Create(context.Background(), &domain.Newsletter{OwnerID: ownerID, Name: name, Slug: string(rune('a' + i))})
+		require.NoError(t, err)
+	}
+
+	search := func(query string) []string {
+		newsletters, err := repository.GetAll(context.Background(), ownerID, domain.NewsletterFilter{Query: query}, domain.NewsletterSort{Field: domain.SortName}, 10, 1)
+		require.NoError(t, err)
+		var names []string
+		for _, newsletter := range newsletters {
+			names = append(names, newsletter.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Go weekly", "Rust weekly"}, search("weekly"))
+	assert.Equal(t, []string{"Go weekly"}, search("go -monthly"))
+	assert.Equal(t, []string{"Go monthly digest"}, search(`"monthly digest"`))
+	assert.Equal(t, []string{"Go monthly digest", "Rust weekly"}, search("rust or digest"))
+}
@@ -2,51 +2,174 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/infrastructure/pagination"
 	"newsletter/internal/newsletters/domain"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
 )
 
+// uniqueViolation is the Postgres error code of a unique constraint violation.
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a violation of a unique constraint.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
 type NewsletterRepository struct {
-	db *sql.DB
+	db database.DB
 }
 
-func NewNewsletterRepository(db *sql.DB) *NewsletterRepository {
+func NewNewsletterRepository(db database.DB) *NewsletterRepository {
 	return &NewsletterRepository{db: db}
 }
 
+// newsletterColumns lists the columns scanned by scanNewsletter, in order.
+const newsletterColumns = `id, owner_id, name, slug, description, allowed_origins, from_name, from_email, from_email_verified, language, unsubscribe_redirect_url, logo_url, brand_color, footer_text, envelope, created_at`
+
+// scanner is implemented by both pgx.Row and pgx.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanNewsletter scans a row selected with newsletterColumns into a domain.Newsletter.
+func scanNewsletter(row scanner) (*domain.Newsletter, error) {
+	var newsletter domain.Newsletter
+	var allowedOrigins pgtype.TextArray
+	var envelope []byte
+
+	err := row.Scan(
+		&newsletter.ID,
+		&newsletter.OwnerID,
+		&newsletter.Name,
+		&newsletter.Slug,
+		&newsletter.Description,
+		&allowedOrigins,
+		&newsletter.FromName,
+		&newsletter.FromEmail,
+		&newsletter.SenderVerified,
+		&newsletter.Language,
+		&newsletter.UnsubscribeRedirectURL,
+		&newsletter.LogoURL,
+		&newsletter.BrandColor,
+		&newsletter.FooterText,
+		&envelope,
+		&newsletter.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(envelope, &newsletter.Envelope); err != nil {
+		return nil, err
+	}
+
+	newsletter.AllowedOrigins = []string{}
+	if err := allowedOrigins.AssignTo(&newsletter.AllowedOrigins); err != nil {
+		return nil, err
+	}
+
+	return &newsletter, nil
+}
+
+// textArray converts a string slice into a Postgres TEXT[] parameter.
+// A nil slice is stored as an empty array.
+func textArray(values []string) (pgtype.TextArray, error) {
+	var array pgtype.TextArray
+	if values == nil {
+		values = []string{}
+	}
+	err := array.Set(values)
+	return array, err
+}
+
 // Create inserts a new newsletter record into the database for a user.
+//
+// If the slug of the newsletter is used by another newsletter, Create
+// returns domain.ErrSlugTaken.
 func (nr *NewsletterRepository) Create(ctx context.Context, newsletter *domain.Newsletter) (*domain.Newsletter, error) {
-	var newsletterDB *domain.Newsletter = &domain.Newsletter{}
-	query := `insert into newsletters (owner_id, name, description, created_at) values ($1, $2, $3, $4) returning id, owner_id, name, description, created_at`
+	allowedOrigins, err := textArray(newsletter.AllowedOrigins)
+	if err != nil {
+		return nil, err
+	}
 
-	err := nr.db.QueryRowContext(
+	query := `insert into newsletters (owner_id, name, slug, description, allowed_origins, created_at) values ($1, $2, $3, $4, $5, $6) returning ` + newsletterColumns
+
+	created, err := scanNewsletter(nr.db.QueryRow(
 		ctx,
 		query,
 		newsletter.OwnerID,
 		newsletter.Name,
+		newsletter.Slug,
 		newsletter.Description,
+		allowedOrigins,
 		time.Now(),
-	).Scan(&newsletterDB.ID, &newsletterDB.OwnerID, &newsletterDB.Name, &newsletterDB.Description, &newsletterDB.CreatedAt)
-	if err != nil {
-		return nil, err
+	))
+	if isUniqueViolation(err) {
+		return nil, domain.ErrSlugTaken
 	}
 
-	return newsletterDB, nil
+	return created, err
 }
 
-// GetAll retrieves all newsletters belonging to a specific owner.
-func (nr *NewsletterRepository) GetAll(ctx context.Context, ownerID uuid.UUID, limit, page int) ([]*domain.Newsletter, error) {
+// sortColumns maps the sort fields accepted by GetAll to their SQL
+// expression. Only these expressions are ever interpolated into the query.
+var sortColumns = map[string]string{
+	domain.SortCreatedAt: "created_at",
+	domain.SortName:      "lower(name)",
+}
+
+// GetAll retrieves the newsletters belonging to a specific owner that match filter.
+//
+// The full-text search uses the generated search column and its GIN index;
+// unless sorted otherwise, matches are ordered by relevance. Ties are broken
+// by ID so that pages are stable. An unknown sort field is rejected with
+// domain.ErrInvalidSort.
+func (nr *NewsletterRepository) GetAll(ctx context.Context, ownerID uuid.UUID, filter domain.NewsletterFilter, sort domain.NewsletterSort, limit, page int) ([]*domain.Newsletter, error) {
 	if page < 1 {
 		page = 1
 	}
 	offset := (page - 1) * limit
 
-	query := `select id, owner_id, name, description, created_at from newsletters where owner_id = $1 limit $2 offset $3`
+	where, args, search := newsletterConditions(ownerID, filter)
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	order := "created_at"
+	if search != "" {
+		order = "ts_rank(search, websearch_to_tsquery('simple', " + search + ")) desc, created_at desc"
+	}
+
+	if sort.Field != "" {
+		column, ok := sortColumns[sort.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", domain.ErrInvalidSort, sort.Field)
+		}
+		order = column + " asc"
+		if sort.Descending {
+			order = column + " desc"
+		}
+	}
+
+	query := `select ` + newsletterColumns + ` from newsletters
+		where ` + where + `
+		order by ` + order + `, id
+		limit ` + arg(limit) + ` offset ` + arg(offset)
 
-	rows, err := nr.db.QueryContext(ctx, query, ownerID, limit, offset)
+	rows, err := nr.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -54,20 +177,214 @@ func (nr *NewsletterRepository) GetAll(ctx context.Context, ownerID uuid.UUID, l
 
 	var newsletters []*domain.Newsletter
 	for rows.Next() {
-		var newsletter domain.Newsletter
-		err := rows.Scan(
-			&newsletter.ID,
-			&newsletter.OwnerID,
-			&newsletter.Name,
-			&newsletter.Description,
-			&newsletter.CreatedAt,
-		)
+		newsletter, err := scanNewsletter(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		newsletters = append(newsletters, newsletter)
+	}
+
+	return newsletters, rows.Err()
+}
+
+// Count returns the number of newsletters belonging to ownerID that match filter.
+func (nr *NewsletterRepository) Count(ctx context.Context, ownerID uuid.UUID, filter domain.NewsletterFilter) (int, error) {
+	where, args, _ := newsletterConditions(ownerID, filter)
+
+	var count int
+	err := nr.db.QueryRow(ctx, `select count(*) from newsletters where `+where, args...).Scan(&count)
+	return count, err
+}
+
+// List retrieves a page of the newsletters belonging to ownerID that match
+// the filter of query, ordered by creation time then ID. The next page
+// starts right after the last newsletter of this one, using the
+// (owner_id, created_at, id) index rather than an offset, so pages are as
+// fast to fetch whatever their position. Search results are not ordered by
+// relevance.
+func (nr *NewsletterRepository) List(ctx context.Context, ownerID uuid.UUID, query domain.NewsletterQuery) (*domain.NewsletterPage, error) {
+	where, args, _ := newsletterConditions(ownerID, query.Filter)
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	direction, comparison := "asc", ">"
+	if query.Descending {
+		direction, comparison = "desc", "<"
+	}
+	if query.After != nil {
+		id, err := uuid.Parse(query.After.ID)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+		where += " and (created_at, id) " + comparison + " (" + arg(query.After.CreatedAt) + ", " + arg(id) + ")"
+	}
+
+	// One more newsletter than the page tells whether there is a next page.
+	rows, err := nr.db.Query(ctx, `select `+newsletterColumns+` from newsletters
+		where `+where+`
+		order by created_at `+direction+`, id `+direction+`
+		limit `+arg(query.Limit+1), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &domain.NewsletterPage{Newsletters: []*domain.Newsletter{}}
+	for rows.Next() {
+		newsletter, err := scanNewsletter(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		newsletters = append(newsletters, &newsletter)
+		page.Newsletters = append(page.Newsletters, newsletter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Newsletters) > query.Limit {
+		page.Newsletters = page.Newsletters[:query.Limit]
+		last := page.Newsletters[len(page.Newsletters)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID.String()}.Encode()
+	}
+
+	return page, nil
+}
+
+// newsletterConditions returns the where clause selecting the newsletters
+// of ownerID matching filter, its arguments, and the placeholder of the
+// full-text search terms, if any.
+func newsletterConditions(ownerID uuid.UUID, filter domain.NewsletterFilter) (string, []any, string) {
+	args := []any{ownerID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"owner_id = $1"}
+	search := ""
+	if filter.Query != "" {
+		search = arg(filter.Query)
+		conditions = append(conditions, "search @@ websearch_to_tsquery('simple', "+search+")")
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(filter.CreatedBefore))
+	}
+
+	return strings.Join(conditions, " and "), args, search
+}
+
+// Get retrieves a single newsletter by its ID.
+//
+// If no newsletter exists with the given ID, Get returns domain.ErrNewsletterNotFound.
+func (nr *NewsletterRepository) Get(ctx context.Context, id uuid.UUID) (*domain.Newsletter, error) {
+	query := `select ` + newsletterColumns + ` from newsletters where id = $1`
+
+	newsletter, err := scanNewsletter(nr.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNewsletterNotFound
+	}
+
+	return newsletter, err
+}
+
+// GetBySlug retrieves a single newsletter by its slug.
+//
+// If no newsletter has the given slug, GetBySlug returns domain.ErrNewsletterNotFound.
+func (nr *NewsletterRepository) GetBySlug(ctx context.Context, slug string) (*domain.Newsletter, error) {
+	query := `select ` + newsletterColumns + ` from newsletters where slug = $1`
+
+	newsletter, err := scanNewsletter(nr.db.QueryRow(ctx, query, slug))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNewsletterNotFound
+	}
+
+	return newsletter, err
+}
+
+// UpdateSlug changes the slug of a newsletter owned by ownerID.
+//
+// If the slug is used by another newsletter, UpdateSlug returns
+// domain.ErrSlugTaken. If the newsletter does not exist or belongs to
+// another owner, it returns domain.ErrNewsletterNotFound.
+func (nr *NewsletterRepository) UpdateSlug(ctx context.Context, id, ownerID uuid.UUID, slug string) (*domain.Newsletter, error) {
+	query := `update newsletters set slug = $1 where id = $2 and owner_id = $3 returning ` + newsletterColumns
+
+	newsletter, err := scanNewsletter(nr.db.QueryRow(ctx, query, slug, id, ownerID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNewsletterNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, domain.ErrSlugTaken
+	}
+
+	return newsletter, err
+}
+
+// UpdateSettings replaces the settings of a newsletter owned by ownerID.
+// The sender verification is kept only if the sender address is unchanged.
+//
+// If the newsletter does not exist or belongs to another owner, UpdateSettings
+// returns domain.ErrNewsletterNotFound.
+func (nr *NewsletterRepository) UpdateSettings(ctx context.Context, id, ownerID uuid.UUID, settings domain.Settings) (*domain.Newsletter, error) {
+	allowedOrigins, err := textArray(settings.AllowedOrigins)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := json.Marshal(settings.Envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changing the sender address invalidates a previous verification.
+	query := `update newsletters
+		set allowed_origins = $1,
+			from_name = $2,
+			from_email = $3,
+			from_email_verified = from_email_verified and from_email = $3,
+			language = $4,
+			unsubscribe_redirect_url = $5,
+			logo_url = $6,
+			brand_color = $7,
+			footer_text = $8,
+			envelope = $9
+		where id = $10 and owner_id = $11
+		returning ` + newsletterColumns
+
+	newsletter, err := scanNewsletter(nr.db.QueryRow(ctx, query,
+		allowedOrigins, settings.FromName, settings.FromEmail, settings.Language,
+		settings.UnsubscribeRedirectURL, settings.LogoURL, settings.BrandColor, settings.FooterText,
+		envelope, id, ownerID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNewsletterNotFound
+	}
+
+	return newsletter, err
+}
+
+// SetSenderVerified records whether the sender address of a newsletter is verified.
+//
+// The update only applies while fromEmail is still the configured sender
+// address, so a verification result never applies to a newer address.
+// If no such newsletter exists, SetSenderVerified returns domain.ErrNewsletterNotFound.
+func (nr *NewsletterRepository) SetSenderVerified(ctx context.Context, id uuid.UUID, fromEmail string, verified bool) error {
+	query := `update newsletters set from_email_verified = $1 where id = $2 and from_email = $3`
+
+	result, err := nr.db.Exec(ctx, query, verified, id, fromEmail)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrNewsletterNotFound
 	}
 
-	return newsletters, nil
+	return nil
 }
@@ -0,0 +1,133 @@
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"newsletter/internal/infrastructure/fixtures"
+	"newsletter/internal/newsletters/domain"
+	"newsletter/internal/newsletters/infrastructure/postgres"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMock returns a mocked database whose expectations are checked at the
+// end of the test.
+func newMock(t *testing.T) pgxmock.PgxPoolIface {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		mock.Close()
+	})
+	return mock
+}
+
+// newsletterRows returns the rows selected with the newsletter columns.
+func newsletterRows(newsletters ...*domain.Newsletter) *pgxmock.Rows {
+	rows := pgxmock.NewRows([]string{
+		"id", "owner_id", "name", "slug", "description", "allowed_origins", "from_name", "from_email",
+		"from_email_verified", "language", "unsubscribe_redirect_url", "logo_url", "brand_color", "footer_text", "envelope", "created_at",
+	})
+	for _, n := range newsletters {
+		origins := "{}"
+		if len(n.AllowedOrigins) > 0 {
+			origins = "{" + n.AllowedOrigins[0] + "}"
+		}
+		envelope, _ := json.Marshal(n.Envelope)
+		rows.AddRow(n.ID, n.OwnerID, n.Name, n.Slug, n.Description, origins, n.FromName, n.FromEmail,
+			n.SenderVerified, n.Language, n.UnsubscribeRedirectURL, n.LogoURL, n.BrandColor, n.FooterText, envelope, n.CreatedAt)
+	}
+	return rows
+}
+
+func TestNewsletterRepository_Create(t *testing.T) {
+	mock := newMock(t)
+	newsletter := fixtures.Newsletter(fixtures.User(1), 1)
+	newsletter.AllowedOrigins = []string{"https://example.com"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`insert into newsletters (owner_id, name, slug, description, allowed_origins, created_at) values ($1, $2, $3, $4, $5, $6) returning id,`)).
+		WithArgs(newsletter.OwnerID, newsletter.Name, newsletter.Slug, newsletter.Description, pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(newsletterRows(newsletter))
+
+	created, err := postgres.NewNewsletterRepository(mock).Create(context.Background(), newsletter)
+
+	require.NoError(t, err)
+	assert.Equal(t, newsletter, created)
+}
+
+func TestNewsletterRepository_Create_SlugTaken(t *testing.T) {
+	mock := newMock(t)
+	newsletter := fixtures.Newsletter(fixtures.User(1), 1)
+
+	mock.ExpectQuery(`insert into newsletters`).
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "newsletters_slug_key"})
+
+	_, err := postgres.NewNewsletterRepository(mock).Create(context.Background(), newsletter)
+
+	assert.ErrorIs(t, err, domain.ErrSlugTaken)
+}
+
+func TestNewsletterRepository_Get_NotFound(t *testing.T) {
+	mock := newMock(t)
+	id := fixtures.ID("newsletter", 404)
+
+	mock.ExpectQuery(`select .* from newsletters where id = \$1`).
+		WithArgs(id).
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err := postgres.NewNewsletterRepository(mock).Get(context.Background(), id)
+
+	assert.ErrorIs(t, err, domain.ErrNewsletterNotFound)
+}
+
+func TestNewsletterRepository_GetAll(t *testing.T) {
+	mock := newMock(t)
+	owner := fixtures.User(1)
+	first, second := fixtures.Newsletter(owner, 1), fixtures.Newsletter(owner, 2)
+	after := fixtures.Epoch
+
+	mock.ExpectQuery(regexp.QuoteMeta(`where owner_id = $1 and search @@ websearch_to_tsquery('simple', $2) and created_at >= $3
+		order by lower(name) desc, id
+		limit $4 offset $5`)).
+		WithArgs(owner.ID, "tech", after, 2, 2).
+		WillReturnRows(newsletterRows(second, first))
+
+	newsletters, err := postgres.NewNewsletterRepository(mock).GetAll(context.Background(), owner.ID,
+		domain.NewsletterFilter{Query: "tech", CreatedAfter: after},
+		domain.NewsletterSort{Field: domain.SortName, Descending: true}, 2, 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*domain.Newsletter{second, first}, newsletters)
+}
+
+func TestNewsletterRepository_GetAll_InvalidSort(t *testing.T) {
+	mock := newMock(t)
+
+	_, err := postgres.NewNewsletterRepository(mock).GetAll(context.Background(), fixtures.ID("user", 1),
+		domain.NewsletterFilter{}, domain.NewsletterSort{Field: "name; drop table newsletters"}, 10, 1)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidSort)
+}
+
+func TestNewsletterRepository_Count(t *testing.T) {
+	mock := newMock(t)
+	owner := fixtures.User(1)
+	before := fixtures.Epoch.Add(time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`select count(*) from newsletters where owner_id = $1 and created_at < $2`)).
+		WithArgs(owner.ID, before).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(7))
+
+	count, err := postgres.NewNewsletterRepository(mock).Count(context.Background(), owner.ID, domain.NewsletterFilter{CreatedBefore: before})
+
+	require.NoError(t, err)
+	assert.Equal(t, 7, count)
+}
@@ -0,0 +1,265 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"newsletter/internal/notifications/domain"
+	"strings"
+	"time"
+)
+
+// spfIncludes maps the email providers to the domain their SPF record is
+// included from.
+var spfIncludes = map[string]string{
+	"ses":      "amazonses.com",
+	"sendgrid": "sendgrid.net",
+	"mailgun":  "mailgun.org",
+}
+
+// SPFInclude returns the SPF domain of the provider named providerName, or
+// "" if it has none, e.g. for SMTP relays.
+func SPFInclude(providerName string) string {
+	return spfIncludes[providerName]
+}
+
+// DomainChecker checks the DNS records that authenticate the emails of a
+// sending domain: SPF, DKIM and DMARC. Receivers send the emails of poorly
+// authenticated domains to spam, or reject them.
+type DomainChecker struct {
+	resolver   domain.Resolver
+	spfInclude string                 // "" skips checking that SPF includes the provider
+	dkim       domain.DKIMTokenSource // nil when the provider does not publish DKIM tokens
+}
+
+// NewDomainChecker creates a DomainChecker reading records with resolver.
+// spfInclude is the SPF domain of the email provider (see SPFInclude) and
+// dkim, which may be nil, provides its DKIM tokens.
+func NewDomainChecker(resolver domain.Resolver, spfInclude string, dkim domain.DKIMTokenSource) *DomainChecker {
+	return &DomainChecker{resolver: resolver, spfInclude: spfInclude, dkim: dkim}
+}
+
+// validDomain reports whether name is a fully qualified host name.
+func validDomain(name string) bool {
+	labels := strings.Split(name, ".")
+	if len(name) > 253 || len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Check reads the SPF, DKIM and DMARC records of name and reports what is
+// missing or wrong. DNS failures make the affected checks unknown rather
+// than failing the whole report. It returns domain.ErrInvalidDomain if
+// name is not a host name.
+func (dc *DomainChecker) Check(name string) (*domain.DomainReport, error) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	if !validDomain(name) {
+		return nil, domain.ErrInvalidDomain
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	report := &domain.DomainReport{
+		Domain: name,
+		Status: domain.CheckPass,
+		Checks: []domain.RecordCheck{dc.spf(ctx, name), dc.dkimCheck(ctx, name), dc.dmarc(ctx, name)},
+	}
+	for _, check := range report.Checks {
+		if check.Status.Severity() > report.Status.Severity() {
+			report.Status = check.Status
+		}
+	}
+	return report, nil
+}
+
+// lookupTXT returns the TXT records of name starting with prefix, ignoring
+// case. A name without records has none.
+func (dc *DomainChecker) lookupTXT(ctx context.Context, name, prefix string) ([]string, error) {
+	records, err := dc.resolver.LookupTXT(ctx, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return []string{}, nil
+	}
+	if err != nil {
+		slog.Warn("failed to read DNS records", "name", name, "error", err)
+		return nil, err
+	}
+
+	matching := []string{}
+	for _, record := range records {
+		if strings.HasPrefix(strings.ToLower(record), strings.ToLower(prefix)) {
+			matching = append(matching, record)
+		}
+	}
+	return matching, nil
+}
+
+// unknown marks check as unknown because its records could not be read.
+func unknown(check domain.RecordCheck, err error) domain.RecordCheck {
+	check.Status = domain.CheckUnknown
+	check.Guidance = []string{"The DNS records could not be read, try again later: " + err.Error()}
+	return check
+}
+
+// spf checks that name publishes a single SPF record that includes the
+// email provider and does not let every server send as the domain.
+func (dc *DomainChecker) spf(ctx context.Context, name string) domain.RecordCheck {
+	check := domain.RecordCheck{Mechanism: domain.MechanismSPF, Name: name, Status: domain.CheckPass}
+	records, err := dc.lookupTXT(ctx, name, "v=spf1")
+	if err != nil {
+		return unknown(check, err)
+	}
+	check.Records = records
+
+	include := dc.spfInclude
+	if include == "" {
+		include = "<SPF domain of your email provider>"
+	}
+	switch len(records) {
+	case 0:
+		check.Status = domain.CheckFail
+		check.Guidance = []string{fmt.Sprintf("Publish a TXT record on %s: v=spf1 include:%s ~all", name, include)}
+		return check
+	case 1:
+	default:
+		check.Status = domain.CheckFail
+		check.Guidance = []string{"Merge the SPF records into one: receivers treat several records as an error"}
+		return check
+	}
+
+	terms := strings.Fields(strings.ToLower(records[0]))
+	all := ""
+	included := false
+	for _, term := range terms[1:] {
+		switch {
+		case strings.TrimPrefix(term, "+") == "include:"+dc.spfInclude:
+			included = true
+		case strings.HasSuffix(term, "all") && len(term) <= 4:
+			all = term
+		case strings.HasPrefix(term, "redirect="):
+			all = term
+		}
+	}
+
+	if dc.spfInclude != "" && !included {
+		check.Status = domain.CheckWarn
+		check.Guidance = append(check.Guidance, fmt.Sprintf("Add include:%s to the SPF record, so that receivers accept the emails sent through the provider", dc.spfInclude))
+	}
+	switch all {
+	case "all", "+all":
+		check.Status = domain.CheckFail
+		check.Guidance = append(check.Guidance, "Replace +all with ~all or -all: +all lets any server send emails as the domain")
+	case "?all", "":
+		if check.Status != domain.CheckFail {
+			check.Status = domain.CheckWarn
+		}
+		check.Guidance = append(check.Guidance, "End the SPF record with ~all or -all, so that receivers distrust the other servers")
+	}
+	return check
+}
+
+// dkimCheck checks that the CNAME records of the DKIM keys of the provider
+// are published under name.
+func (dc *DomainChecker) dkimCheck(ctx context.Context, name string) domain.RecordCheck {
+	check := domain.RecordCheck{Mechanism: domain.MechanismDKIM, Name: "_domainkey." + name, Status: domain.CheckPass, Records: []string{}}
+	if dc.dkim == nil {
+		check.Status = domain.CheckUnknown
+		check.Guidance = []string{"The email provider does not report its DKIM keys: publish the DKIM records listed in its console"}
+		return check
+	}
+
+	tokens, err := dc.dkim.DKIMTokens(ctx, name)
+	if err != nil {
+		slog.Warn("failed to read DKIM tokens", "domain", name, "error", err)
+		check.Status = domain.CheckUnknown
+		check.Guidance = []string{"The DKIM keys of the domain could not be read from the email provider, try again later"}
+		return check
+	}
+	if len(tokens) == 0 {
+		check.Status = domain.CheckFail
+		check.Guidance = []string{"Set up DKIM for the domain with the email provider (Easy DKIM with AWS SES), then publish the CNAME records it lists"}
+		return check
+	}
+
+	for _, token := range tokens {
+		host := token + "._domainkey." + name
+		target := dc.dkim.DKIMTarget(token)
+
+		cname, err := dc.resolver.LookupCNAME(ctx, host)
+		var dnsErr *net.DNSError
+		if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			return unknown(check, err)
+		}
+
+		cname = strings.TrimSuffix(strings.ToLower(cname), ".")
+		if cname != "" && cname != host {
+			check.Records = append(check.Records, host+" CNAME "+cname)
+		}
+		if cname != strings.ToLower(target) {
+			check.Status = domain.CheckFail
+			check.Guidance = append(check.Guidance, fmt.Sprintf("Publish a CNAME record on %s pointing to %s", host, target))
+		}
+	}
+	return check
+}
+
+// dmarc checks that name publishes a single DMARC record with a policy that
+// protects the domain and an address receiving the aggregate reports.
+func (dc *DomainChecker) dmarc(ctx context.Context, name string) domain.RecordCheck {
+	check := domain.RecordCheck{Mechanism: domain.MechanismDMARC, Name: "_dmarc." + name, Status: domain.CheckPass}
+	records, err := dc.lookupTXT(ctx, check.Name, "v=DMARC1")
+	if err != nil {
+		return unknown(check, err)
+	}
+	check.Records = records
+
+	switch len(records) {
+	case 0:
+		check.Status = domain.CheckFail
+		check.Guidance = []string{fmt.Sprintf("Publish a TXT record on %s: v=DMARC1; p=none; rua=mailto:dmarc@%s, then move to p=quarantine once the reports show your emails pass", check.Name, name)}
+		return check
+	case 1:
+	default:
+		check.Status = domain.CheckFail
+		check.Guidance = []string{"Keep a single DMARC record: receivers ignore the policy when there are several"}
+		return check
+	}
+
+	tags := map[string]string{}
+	for _, tag := range strings.Split(records[0], ";") {
+		if key, value, ok := strings.Cut(tag, "="); ok {
+			tags[strings.ToLower(strings.TrimSpace(key))] = strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+
+	switch tags["p"] {
+	case "quarantine", "reject":
+	case "none":
+		check.Status = domain.CheckWarn
+		check.Guidance = append(check.Guidance, "p=none only monitors: move to p=quarantine or p=reject once the reports show your emails pass")
+	default:
+		check.Status = domain.CheckFail
+		check.Guidance = append(check.Guidance, "Set the policy of the DMARC record: p=none, p=quarantine or p=reject")
+	}
+	if tags["rua"] == "" {
+		if check.Status == domain.CheckPass {
+			check.Status = domain.CheckWarn
+		}
+		check.Guidance = append(check.Guidance, fmt.Sprintf("Add rua=mailto:dmarc@%s to the DMARC record to receive the aggregate reports", name))
+	}
+	return check
+}
@@ -0,0 +1,152 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"newsletter/internal/notifications/application"
+	"newsletter/internal/notifications/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver serves DNS records from memory; unknown names do not exist.
+type fakeResolver struct {
+	txt   map[string][]string
+	cname map[string]string
+	err   error
+}
+
+func (f fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	records, ok := f.txt[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func (f fakeResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	cname, ok := f.cname[host]
+	if !ok {
+		return "", &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return cname, nil
+}
+
+// fakeDKIM is a provider publishing DKIM keys under fixed tokens.
+type fakeDKIM []string
+
+func (f fakeDKIM) DKIMTokens(ctx context.Context, domain string) ([]string, error) {
+	return f, nil
+}
+
+func (f fakeDKIM) DKIMTarget(token string) string {
+	return token + ".dkim.amazonses.com"
+}
+
+// checkOf returns the check of mechanism in report.
+func checkOf(t *testing.T, report *domain.DomainReport, mechanism string) domain.RecordCheck {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.Mechanism == mechanism {
+			return check
+		}
+	}
+	t.Fatalf("no %s check", mechanism)
+	return domain.RecordCheck{}
+}
+
+func TestDomainChecker_Pass(t *testing.T) {
+	resolver := fakeResolver{
+		txt: map[string][]string{
+			"example.com":        {"google-site-verification=abc", "v=spf1 include:amazonses.com -all"},
+			"_dmarc.example.com": {"v=DMARC1; p=quarantine; rua=mailto:dmarc@example.com"},
+		},
+		cname: map[string]string{
+			"tok1._domainkey.example.com": "tok1.dkim.amazonses.com.",
+			"tok2._domainkey.example.com": "tok2.dkim.amazonses.com.",
+		},
+	}
+	checker := application.NewDomainChecker(resolver, application.SPFInclude("ses"), fakeDKIM{"tok1", "tok2"})
+
+	report, err := checker.Check(" Example.com. ")
+
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", report.Domain)
+	assert.Equal(t, domain.CheckPass, report.Status, report.Checks)
+	assert.Equal(t, []string{"v=spf1 include:amazonses.com -all"}, checkOf(t, report, domain.MechanismSPF).Records)
+}
+
+func TestDomainChecker_MissingRecords(t *testing.T) {
+	checker := application.NewDomainChecker(fakeResolver{}, "amazonses.com", fakeDKIM{"tok1"})
+
+	report, err := checker.Check("example.com")
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.CheckFail, report.Status)
+	for _, check := range report.Checks {
+		assert.Equal(t, domain.CheckFail, check.Status, check.Mechanism)
+		assert.NotEmpty(t, check.Guidance, check.Mechanism)
+	}
+	assert.Equal(t, []string{"Publish a CNAME record on tok1._domainkey.example.com pointing to tok1.dkim.amazonses.com"},
+		checkOf(t, report, domain.MechanismDKIM).Guidance)
+}
+
+func TestDomainChecker_Weaknesses(t *testing.T) {
+	resolver := fakeResolver{txt: map[string][]string{
+		"example.com":        {"v=spf1 include:_spf.google.com +all"},
+		"_dmarc.example.com": {"v=DMARC1; p=none"},
+	}}
+	checker := application.NewDomainChecker(resolver, "amazonses.com", nil)
+
+	report, err := checker.Check("example.com")
+
+	require.NoError(t, err)
+	spf := checkOf(t, report, domain.MechanismSPF)
+	assert.Equal(t, domain.CheckFail, spf.Status)
+	assert.Len(t, spf.Guidance, 2, "missing include and +all")
+
+	dmarc := checkOf(t, report, domain.MechanismDMARC)
+	assert.Equal(t, domain.CheckWarn, dmarc.Status)
+	assert.Len(t, dmarc.Guidance, 2, "p=none and no rua")
+
+	assert.Equal(t, domain.CheckUnknown, checkOf(t, report, domain.MechanismDKIM).Status)
+}
+
+func TestDomainChecker_DuplicateSPF(t *testing.T) {
+	resolver := fakeResolver{txt: map[string][]string{
+		"example.com": {"v=spf1 include:amazonses.com ~all", "v=spf1 include:sendgrid.net ~all"},
+	}}
+
+	report, err := application.NewDomainChecker(resolver, "amazonses.com", nil).Check("example.com")
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.CheckFail, checkOf(t, report, domain.MechanismSPF).Status)
+}
+
+func TestDomainChecker_DNSFailure(t *testing.T) {
+	checker := application.NewDomainChecker(fakeResolver{err: errors.New("i/o timeout")}, "amazonses.com", nil)
+
+	report, err := checker.Check("example.com")
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.CheckUnknown, checkOf(t, report, domain.MechanismSPF).Status)
+	assert.Equal(t, domain.CheckUnknown, checkOf(t, report, domain.MechanismDMARC).Status)
+}
+
+func TestDomainChecker_InvalidDomain(t *testing.T) {
+	checker := application.NewDomainChecker(fakeResolver{}, "", nil)
+
+	for _, name := range []string{"", "localhost", "exa mple.com", "-example.com", "example..com"} {
+		_, err := checker.Check(name)
+		assert.ErrorIs(t, err, domain.ErrInvalidDomain, name)
+	}
+}
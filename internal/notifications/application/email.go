@@ -2,69 +2,81 @@ package application
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
-	"newsletter/config"
 	"newsletter/internal/notifications/domain"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/ses"
-	"github.com/aws/aws-sdk-go-v2/service/ses/types"
+	"strings"
 )
 
-// EmailService is responsible for sending emails using AWS SES.
+// EmailService is responsible for sending emails through the configured
+// email provider (AWS SES, SendGrid or Mailgun).
 type EmailService struct {
-	client *ses.Client
+	provider          domain.Provider
+	maxAttachmentSize int // Total size of the attachments of an email, in bytes
+}
+
+func NewEmailService(provider domain.Provider) *EmailService {
+	return &EmailService{provider: provider, maxAttachmentSize: domain.DefaultMaxAttachmentSize}
+}
+
+// SetMaxAttachmentSize caps the total size of the attachments of an email,
+// in bytes, instead of domain.DefaultMaxAttachmentSize.
+func (es *EmailService) SetMaxAttachmentSize(size int) {
+	es.maxAttachmentSize = size
+}
+
+// MaxAttachmentSize returns the total size of the attachments an email may
+// have, in bytes.
+func (es *EmailService) MaxAttachmentSize() int {
+	return es.maxAttachmentSize
 }
 
-func NewEmailService(client *ses.Client) *EmailService {
-	return &EmailService{client: client}
+// checkAttachments rejects the attachments of email that could not be sent,
+// with errors wrapping domain.ErrPermanent.
+func (es *EmailService) checkAttachments(email *domain.Email) error {
+	for _, attachment := range email.Attachments {
+		if strings.TrimSpace(attachment.Filename) == "" || strings.ContainsAny(attachment.Filename, "\r\n") {
+			return fmt.Errorf("%w: %w: %q", domain.ErrPermanent, domain.ErrInvalidAttachment, attachment.Filename)
+		}
+	}
+	if size := email.AttachmentSize(); size > es.maxAttachmentSize {
+		return fmt.Errorf("%w: %w: %d bytes, at most %d", domain.ErrPermanent, domain.ErrAttachmentsTooLarge, size, es.maxAttachmentSize)
+	}
+	return nil
 }
 
 // Send sends an email to a recipient.
 //
 // Parameters:
+//   - ctx: Bounds the call to the provider; cancelling it aborts the delivery.
 //   - email: A pointer to domain.Email containing recipient info, subject, and body.
 //
 // Behavior:
-//   - Constructs both HTML and plain text versions of the email.
-//   - Sends the email via AWS SES.
-//
-// Notes:
-//   - The "from" address must be verified in AWS SES (sandbox or production).
-//   - In the SES sandbox, recipient addresses must also be verified.
+//   - Rejects attachments without a file name or larger together than the
+//     size cap, without calling the provider.
+//   - Delegates delivery to the configured provider.
+//   - Logs whether a failure is retryable or permanent.
 //
 // Returns:
-//   - An error if sending the email fails; otherwise nil.
-func (es *EmailService) Send(email *domain.Email) error {
-	// Construct the SES SendEmailInput
-	input := &ses.SendEmailInput{
-		Destination: &types.Destination{
-			ToAddresses: []string{email.To},
-		},
-		Message: &types.Message{
-			Body: &types.Body{
-				Html: &types.Content{
-					Data: aws.String(email.HTML),
-				},
-				Text: &types.Content{
-					Data: aws.String(email.Text),
-				},
-			},
-			Subject: &types.Content{
-				Data: aws.String(email.Subject),
-			},
-		},
-		Source: aws.String(config.GetEnv("AWS_FROM", "")),
+//   - An error wrapping domain.ErrRetryable or domain.ErrPermanent if sending
+//     the email fails; otherwise nil.
+func (es *EmailService) Send(ctx context.Context, email *domain.Email) error {
+	if err := es.checkAttachments(email); err != nil {
+		slog.Warn("Message was not sent to the provider", "provider", es.provider.Name(), "error", err)
+		return err
 	}
 
-	// Send the email
-	response, err := es.client.SendEmail(context.TODO(), input)
+	err := es.provider.Send(ctx, email)
 	if err != nil {
-		slog.Warn("Message was not delivered to recipient", "error", err)
+		slog.Warn("Message was not delivered to recipient",
+			"provider", es.provider.Name(),
+			"retryable", domain.IsRetryable(err),
+			"error", err,
+		)
 		return err
 	}
 
-	slog.Info("Message was delivered successfully", "message", response.MessageId)
+	slog.Info("Message was delivered successfully", "provider", es.provider.Name())
 
 	return nil
 }
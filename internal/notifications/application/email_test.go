@@ -0,0 +1,52 @@
+package application_test
+
+import (
+	"context"
+	"newsletter/internal/notifications/application"
+	"newsletter/internal/notifications/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingProvider accepts every email and counts them.
+type countingProvider struct {
+	sent int
+}
+
+func (p *countingProvider) Name() string {
+	return "counting"
+}
+
+func (p *countingProvider) Send(ctx context.Context, email *domain.Email) error {
+	p.sent++
+	return nil
+}
+
+func TestEmailService_Attachments(t *testing.T) {
+	provider := &countingProvider{}
+	es := application.NewEmailService(provider)
+	es.SetMaxAttachmentSize(10)
+
+	small := &domain.Email{To: "a@example.com", Attachments: []domain.Attachment{
+		{Filename: "a.txt", Data: []byte("12345")},
+		{Filename: "b.txt", Data: []byte("67890")},
+	}}
+	assert.NoError(t, es.Send(context.Background(), small))
+
+	large := &domain.Email{To: "a@example.com", Attachments: []domain.Attachment{{Filename: "a.txt", Data: []byte("12345678901")}}}
+	err := es.Send(context.Background(), large)
+	assert.ErrorIs(t, err, domain.ErrAttachmentsTooLarge)
+	assert.ErrorIs(t, err, domain.ErrPermanent, "not retried")
+
+	unnamed := &domain.Email{To: "a@example.com", Attachments: []domain.Attachment{{Filename: "a\r\nb.txt", Data: []byte("1")}}}
+	assert.ErrorIs(t, es.Send(context.Background(), unnamed), domain.ErrInvalidAttachment)
+
+	assert.Equal(t, 1, provider.sent)
+}
+
+func TestAttachment_Type(t *testing.T) {
+	assert.Equal(t, "application/pdf", domain.Attachment{Filename: "invoice.pdf"}.Type())
+	assert.Equal(t, "text/csv", domain.Attachment{Filename: "data.bin", ContentType: "text/csv"}.Type())
+	assert.Equal(t, "application/octet-stream", domain.Attachment{Filename: "data"}.Type())
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	apperrors "newsletter/internal/errors"
+)
+
+// ErrInvalidDomain is returned when the domain to check is not a valid
+// host name.
+var ErrInvalidDomain = apperrors.New(apperrors.Validation, "invalid sending domain")
+
+// CheckStatus is the outcome of a DNS authentication check.
+type CheckStatus string
+
+// Check statuses, from best to worst. The status of a report is the worst
+// of its checks.
+const (
+	CheckPass    CheckStatus = "pass"    // The records are correct
+	CheckUnknown CheckStatus = "unknown" // The records could not be checked
+	CheckWarn    CheckStatus = "warn"    // The records work but should be improved
+	CheckFail    CheckStatus = "fail"    // The records are missing or wrong
+)
+
+// Severity orders statuses from best to worst.
+func (s CheckStatus) Severity() int {
+	switch s {
+	case CheckPass:
+		return 0
+	case CheckUnknown:
+		return 1
+	case CheckWarn:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Authentication mechanisms checked by a DomainChecker.
+const (
+	MechanismSPF   = "spf"
+	MechanismDKIM  = "dkim"
+	MechanismDMARC = "dmarc"
+)
+
+// RecordCheck is the outcome of checking the records of one mechanism.
+type RecordCheck struct {
+	Mechanism string      `json:"mechanism"`          // "spf", "dkim" or "dmarc"
+	Status    CheckStatus `json:"status"`             // Outcome of the check
+	Name      string      `json:"name"`               // DNS name the records were read from
+	Records   []string    `json:"records"`            // Records found
+	Guidance  []string    `json:"guidance,omitempty"` // What to change, when the status is not pass
+}
+
+// DomainReport is the outcome of checking the email authentication of a
+// sending domain.
+type DomainReport struct {
+	Domain string        `json:"domain"`
+	Status CheckStatus   `json:"status"` // Worst status of the checks
+	Checks []RecordCheck `json:"checks"`
+}
+
+// Resolver reads DNS records. *net.Resolver implements it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+// DKIMTokenSource is implemented by providers that sign emails with DKIM
+// keys they publish under tokens, such as the Easy DKIM of AWS SES.
+type DKIMTokenSource interface {
+	// DKIMTokens returns the tokens of the DKIM keys of domain, which the
+	// domain publishes as CNAME records, or none if DKIM is not set up for
+	// it.
+	DKIMTokens(ctx context.Context, domain string) ([]string, error)
+	// DKIMTarget returns the host the CNAME record of token points to.
+	DKIMTarget(token string) string
+}
+
+// DomainChecker checks the SPF, DKIM and DMARC records of sending domains.
+type DomainChecker interface {
+	Check(domain string) (*DomainReport, error)
+}
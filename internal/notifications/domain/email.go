@@ -1,12 +1,264 @@
 package domain
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	apperrors "newsletter/internal/errors"
+	"path"
+	"strings"
+)
+
 type Email struct {
+	// From overrides the default sender of the provider, formatted as an
+	// RFC 5322 address such as "Weekly News <news@example.com>". The address
+	// must be verified with the provider.
+	From    string
 	To      string
 	Subject string
 	Text    string
 	HTML    string
+
+	Envelope // Reply-To, copies and custom headers, if any
+
+	// Attachments are files sent with the email, such as invoices or data
+	// exports. Their total size is capped by the EmailService.
+	Attachments []Attachment
+
+	// MessageID is set by the provider to the identifier it assigned to the
+	// email once it was accepted, e.g. the SES MessageId.
+	MessageID string
+}
+
+// Sender returns the address the email is sent from: From when set, the
+// provider default otherwise.
+func (e *Email) Sender(fallback string) string {
+	if e.From != "" {
+		return e.From
+	}
+	return fallback
+}
+
+// Attachment is a file attached to an email.
+type Attachment struct {
+	Filename    string // Name shown to the recipient, such as "invoice.pdf"
+	ContentType string // MIME type of Data; derived from Filename when empty
+	Data        []byte
+}
+
+// Type returns the MIME type of the attachment: ContentType when set, the
+// type of the extension of Filename otherwise, or application/octet-stream.
+func (a Attachment) Type() string {
+	if a.ContentType != "" {
+		return a.ContentType
+	}
+	if byExtension := mime.TypeByExtension(path.Ext(a.Filename)); byExtension != "" {
+		return byExtension
+	}
+	return "application/octet-stream"
+}
+
+// AttachmentSize returns the total size of the attachments of the email,
+// in bytes, before encoding.
+func (e *Email) AttachmentSize() int {
+	size := 0
+	for _, attachment := range e.Attachments {
+		size += len(attachment.Data)
+	}
+	return size
+}
+
+// DefaultMaxAttachmentSize caps the total size of the attachments of an
+// email, in bytes. Encoding grows attachments by a third, which keeps
+// messages under the 10 MB limit of AWS SES.
+const DefaultMaxAttachmentSize = 5 << 20
+
+var (
+	// ErrAttachmentsTooLarge is returned when the attachments of an email
+	// exceed the configured size cap.
+	ErrAttachmentsTooLarge = apperrors.New(apperrors.Validation, "attachments too large")
+	// ErrInvalidAttachment is returned when an attachment has no file name,
+	// or one spanning several lines.
+	ErrInvalidAttachment = apperrors.New(apperrors.Validation, "invalid attachment")
+)
+
+// ErrInvalidEnvelope is returned when the reply-to address, the copies or
+// the custom headers of emails are invalid.
+var ErrInvalidEnvelope = apperrors.New(apperrors.Validation, "invalid email headers")
+
+// Limits of an Envelope.
+const (
+	MaxCopies  = 10 // Cc and Bcc addresses, together
+	MaxHeaders = 20 // Custom headers
+)
+
+// reservedHeaders are set by the service or the provider and cannot be
+// custom headers. List-Unsubscribe holds a link of each recipient.
+var reservedHeaders = map[string]bool{
+	"Bcc": true, "Cc": true, "Content-Transfer-Encoding": true, "Content-Type": true,
+	"Date": true, "Dkim-Signature": true, "From": true, "List-Unsubscribe": true,
+	"List-Unsubscribe-Post": true, "Message-Id": true, "Mime-Version": true,
+	"Reply-To": true, "Return-Path": true, "Sender": true, "Subject": true, "To": true,
+}
+
+// Envelope holds the optional addressing and headers of an email, beyond
+// its sender and recipient.
+type Envelope struct {
+	ReplyTo string            `json:"reply_to,omitempty"` // Address replies are sent to, instead of the sender
+	Cc      []string          `json:"cc,omitempty"`       // Addresses receiving a visible copy
+	Bcc     []string          `json:"bcc,omitempty"`      // Addresses receiving a hidden copy
+	Headers map[string]string `json:"headers,omitempty"`  // Custom headers, such as List-ID, by name
+}
+
+// Validate checks the addresses and headers of the envelope, returning an
+// error wrapping ErrInvalidEnvelope. Header names must be valid and not set
+// by the service, such as Subject or List-Unsubscribe, and values must fit
+// on one line, so that they cannot inject other headers.
+func (e Envelope) Validate() error {
+	if e.ReplyTo != "" {
+		if _, err := mail.ParseAddress(e.ReplyTo); err != nil {
+			return fmt.Errorf("%w: invalid reply-to address %q", ErrInvalidEnvelope, e.ReplyTo)
+		}
+	}
+	if len(e.Cc)+len(e.Bcc) > MaxCopies {
+		return fmt.Errorf("%w: at most %d cc and bcc addresses", ErrInvalidEnvelope, MaxCopies)
+	}
+	for _, address := range append(append([]string{}, e.Cc...), e.Bcc...) {
+		if _, err := mail.ParseAddress(address); err != nil {
+			return fmt.Errorf("%w: invalid copy address %q", ErrInvalidEnvelope, address)
+		}
+	}
+
+	if len(e.Headers) > MaxHeaders {
+		return fmt.Errorf("%w: at most %d headers", ErrInvalidEnvelope, MaxHeaders)
+	}
+	for name, value := range e.Headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("%w: invalid header name %q", ErrInvalidEnvelope, name)
+		}
+		if reservedHeaders[textproto.CanonicalMIMEHeaderKey(name)] {
+			return fmt.Errorf("%w: header %s is set by the service", ErrInvalidEnvelope, name)
+		}
+		if value == "" || strings.ContainsAny(value, "\r\n") || len(value) > 998 {
+			return fmt.Errorf("%w: header %s must be a single line of at most 998 characters", ErrInvalidEnvelope, name)
+		}
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is a header field name: printable
+// ASCII characters but colon (RFC 5322, section 2.2).
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c <= ' ' || c > '~' || c == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge returns the envelope with the settings of override replacing its
+// own: the reply-to address and copies when set, and headers by name.
+func (e Envelope) Merge(override Envelope) Envelope {
+	merged := Envelope{ReplyTo: e.ReplyTo, Cc: e.Cc, Bcc: e.Bcc}
+	if override.ReplyTo != "" {
+		merged.ReplyTo = override.ReplyTo
+	}
+	if len(override.Cc) > 0 {
+		merged.Cc = override.Cc
+	}
+	if len(override.Bcc) > 0 {
+		merged.Bcc = override.Bcc
+	}
+	if len(e.Headers)+len(override.Headers) > 0 {
+		merged.Headers = make(map[string]string, len(e.Headers)+len(override.Headers))
+		for name, value := range e.Headers {
+			merged.Headers[textproto.CanonicalMIMEHeaderKey(name)] = value
+		}
+		for name, value := range override.Headers {
+			merged.Headers[textproto.CanonicalMIMEHeaderKey(name)] = value
+		}
+	}
+	return merged
+}
+
+// SetHeader sets the header name of the email, unless a custom header of
+// that name is set already.
+func (e *Email) SetHeader(name, value string) {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	if _, ok := e.Headers[name]; ok {
+		return
+	}
+	if e.Headers == nil {
+		e.Headers = map[string]string{}
+	}
+	e.Headers[name] = value
 }
 
 type EmailService interface {
-	Send(email *Email) error
+	Send(ctx context.Context, email *Email) error
+}
+
+// Provider delivers emails through an external email service such as
+// AWS SES, SendGrid or Mailgun.
+//
+// Implementations must wrap delivery failures with ErrRetryable or
+// ErrPermanent so that callers can decide whether to try again.
+type Provider interface {
+	// Name returns a short identifier of the provider used in logs.
+	Name() string
+	// Send delivers a single email. ctx bounds the call to the provider API.
+	Send(ctx context.Context, email *Email) error
+}
+
+// VerificationStatus is the state of a sender identity verification.
+type VerificationStatus string
+
+// Verification statuses reported by a SenderVerifier.
+const (
+	VerificationNotStarted VerificationStatus = "not_started"
+	VerificationPending    VerificationStatus = "pending"
+	VerificationSuccess    VerificationStatus = "success"
+	VerificationFailed     VerificationStatus = "failed"
+)
+
+// SenderVerifier is implemented by providers that verify ownership of sender
+// addresses before they can be used as a "from" address.
+type SenderVerifier interface {
+	// StartVerification asks the provider to send a verification email to address.
+	StartVerification(address string) error
+	// VerificationStatus returns the verification state of address.
+	VerificationStatus(address string) (VerificationStatus, error)
+}
+
+var (
+	// ErrRetryable marks delivery failures that may succeed if attempted
+	// again later (throttling, provider outages, network errors).
+	ErrRetryable = errors.New("retryable delivery failure")
+	// ErrPermanent marks delivery failures that will not succeed on retry
+	// (rejected message, invalid recipient, bad credentials).
+	ErrPermanent = errors.New("permanent delivery failure")
+)
+
+// IsRetryable reports whether a delivery error is worth retrying.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRetryable)
+}
+
+// ClassifyHTTPStatus maps an HTTP status code returned by an email provider
+// API to a delivery error. Rate limiting and server errors are retryable,
+// all other failures are permanent.
+func ClassifyHTTPStatus(provider string, status int, body string) error {
+	category := ErrPermanent
+	if status == 429 || status >= 500 {
+		category = ErrRetryable
+	}
+
+	return fmt.Errorf("%w: %s responded with status %d: %s", category, provider, status, body)
 }
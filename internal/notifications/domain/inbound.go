@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// InboundEmail is an email received by the service, such as a reply of a
+// subscriber to a campaign.
+type InboundEmail struct {
+	MessageID  string    // Message-ID header, such as "<abc@mail.example.com>"
+	From       string    // Bare address of the sender
+	Subject    string    // Decoded subject
+	References []string  // Message IDs of the emails it answers, from In-Reply-To and References, nearest first
+	Text       string    // Plain text body, converted from HTML when the email has no text part
+	ReceivedAt time.Time // Time the email was received
+}
+
+// attributions end the line that introduces the quoted email in the
+// replies written by the common mail clients, in the languages of the
+// service.
+var attributions = []string{"wrote:", "schrieb:", "a écrit :", "a écrit:", "escribió:"}
+
+// attributionStarts begin such lines, which some clients wrap in two.
+var attributionStarts = []string{"On ", "Am ", "Le ", "El "}
+
+// separators start the signature or the quoted email on a line of their
+// own, once trimmed.
+var separators = []string{"--", "-----Original Message-----", "________________________________"}
+
+// Reply returns the text written by the sender, without the quoted email it
+// answers or the signature. The whole text is returned if nothing is left.
+func (e *InboundEmail) Reply() string {
+	lines := strings.Split(strings.ReplaceAll(e.Text, "\r\n", "\n"), "\n")
+
+	kept := []string{}
+cut:
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if slices.Contains(separators, trimmed) {
+			break
+		}
+		for _, attribution := range attributions {
+			if !strings.HasSuffix(trimmed, attribution) {
+				continue
+			}
+			if i > 0 && len(kept) > 0 && !hasAnyPrefix(trimmed, attributionStarts) && hasAnyPrefix(strings.TrimSpace(lines[i-1]), attributionStarts) {
+				kept = kept[:len(kept)-1]
+			}
+			break cut
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, " \t"))
+	}
+
+	reply := strings.TrimSpace(strings.Join(kept, "\n"))
+	if reply == "" {
+		return strings.TrimSpace(e.Text)
+	}
+	return reply
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// InboundMailStore reads the emails a provider stored on reception, such as
+// the S3 objects written by the receipt rules of AWS SES.
+type InboundMailStore interface {
+	// Fetch returns the raw MIME message stored as key in bucket.
+	Fetch(ctx context.Context, bucket, key string) ([]byte, error)
+}
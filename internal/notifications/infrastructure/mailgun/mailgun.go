@@ -0,0 +1,144 @@
+package mailgun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"newsletter/internal/notifications/domain"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Mailgun API endpoint for the US region.
+// Domains hosted in the EU region use https://api.eu.mailgun.net instead.
+const DefaultBaseURL = "https://api.mailgun.net"
+
+// Provider sends emails using the Mailgun Messages API.
+type Provider struct {
+	client  *http.Client
+	baseURL string
+	domain  string
+	apiKey  string
+	from    string
+}
+
+func NewProvider(baseURL, domain, apiKey, from string) *Provider {
+	return &Provider{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		baseURL: baseURL,
+		domain:  domain,
+		apiKey:  apiKey,
+		from:    from,
+	}
+}
+
+// Name returns the provider identifier.
+func (p *Provider) Name() string {
+	return "mailgun"
+}
+
+// Send sends an email to a recipient with both plain text and HTML bodies,
+// its reply-to address, copies, custom headers and attachments.
+//
+// Returns:
+//   - An error wrapping domain.ErrRetryable for rate limiting (429), server
+//     errors (5xx) and network failures, domain.ErrPermanent for any other
+//     rejected request; otherwise nil.
+func (p *Provider) Send(ctx context.Context, email *domain.Email) error {
+	form := url.Values{}
+	form.Set("from", email.Sender(p.from))
+	form.Set("to", email.To)
+	form.Set("subject", email.Subject)
+	form.Set("text", email.Text)
+	form.Set("html", email.HTML)
+	for _, address := range email.Cc {
+		form.Add("cc", address)
+	}
+	for _, address := range email.Bcc {
+		form.Add("bcc", address)
+	}
+	if email.ReplyTo != "" {
+		form.Set("h:Reply-To", email.ReplyTo)
+	}
+	for name, value := range email.Headers {
+		form.Set("h:"+name, value)
+	}
+
+	endpoint := fmt.Sprintf("%s/v3/%s/messages", p.baseURL, url.PathEscape(p.domain))
+
+	body, contentType, err := encodeForm(form, email.Attachments)
+	if err != nil {
+		return fmt.Errorf("%w: mailgun: %v", domain.ErrPermanent, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("%w: mailgun: %v", domain.ErrPermanent, err)
+	}
+	req.SetBasicAuth("api", p.apiKey)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: mailgun: %v", domain.ErrRetryable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return domain.ClassifyHTTPStatus(p.Name(), resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		slog.Warn("failed to decode Mailgun response", "error", err)
+	}
+
+	email.MessageID = response.ID
+	slog.Info("Mailgun accepted message", "message", email.MessageID)
+
+	return nil
+}
+
+// encodeForm returns the body of a Messages API request with form and its
+// content type: URL encoded, or multipart with the files of attachments.
+func encodeForm(form url.Values, attachments []domain.Attachment) (io.Reader, string, error) {
+	if len(attachments) == 0 {
+		return strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, values := range form {
+		for _, value := range values {
+			if err := writer.WriteField(key, value); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	for _, attachment := range attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Disposition": {mime.FormatMediaType("form-data", map[string]string{"name": "attachment", "filename": attachment.Filename})},
+			"Content-Type":        {attachment.Type()},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(attachment.Data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return &body, writer.FormDataContentType(), nil
+}
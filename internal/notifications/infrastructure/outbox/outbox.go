@@ -0,0 +1,121 @@
+// Package outbox records outgoing emails instead of sending them, so that
+// development and staging environments never email real subscribers.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"newsletter/internal/notifications/domain"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Capacity is the number of recent emails kept in memory.
+const Capacity = 200
+
+// Message is an email recorded by the outbox.
+type Message struct {
+	ID      string    `json:"id"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text,omitempty"`
+	HTML    string    `json:"html,omitempty"`
+	SentAt  time.Time `json:"sent_at"`
+
+	domain.Envelope // Reply-To, copies and custom headers, if any
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment describes a file attached to a recorded email. Its content is
+// not kept.
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"` // In bytes
+}
+
+// Provider implements domain.Provider by recording emails: the most recent
+// ones are kept in memory and, when a writer is configured, every email is
+// appended to it as a line of JSON.
+type Provider struct {
+	from string
+	log  io.Writer // nil when emails are only kept in memory
+
+	mu       sync.Mutex
+	messages []Message // Oldest first, at most Capacity
+}
+
+// NewProvider creates an outbox recording emails sent from the default
+// sender from. log, which may be nil, receives every email as JSON.
+func NewProvider(from string, log io.Writer) *Provider {
+	return &Provider{from: from, log: log}
+}
+
+func (p *Provider) Name() string {
+	return "outbox"
+}
+
+// Send records email and sets its MessageID. Failures to write the log are
+// retryable.
+func (p *Provider) Send(ctx context.Context, email *domain.Email) error {
+	message := Message{
+		ID:      "outbox-" + uuid.NewString(),
+		From:    email.Sender(p.from),
+		To:      email.To,
+		Subject: email.Subject,
+		Text:    email.Text,
+		HTML:    email.HTML,
+		SentAt:  time.Now(),
+
+		Envelope: email.Envelope,
+	}
+	for _, attachment := range email.Attachments {
+		message.Attachments = append(message.Attachments, Attachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.Type(),
+			Size:        len(attachment.Data),
+		})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.log != nil {
+		line, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("%w: encode email: %v", domain.ErrPermanent, err)
+		}
+		if _, err := p.log.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("%w: record email: %v", domain.ErrRetryable, err)
+		}
+	}
+
+	if len(p.messages) == Capacity {
+		p.messages = p.messages[1:]
+	}
+	p.messages = append(p.messages, message)
+	email.MessageID = message.ID
+
+	return nil
+}
+
+// Messages returns the recorded emails, newest first. When to is not empty,
+// only the emails sent to it are returned.
+func (p *Provider) Messages(to string) []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	messages := []Message{}
+	for i := len(p.messages) - 1; i >= 0; i-- {
+		if to == "" || p.messages[i].To == to {
+			messages = append(messages, p.messages[i])
+		}
+	}
+	return messages
+}
@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"newsletter/internal/notifications/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend_RecordsEmails(t *testing.T) {
+	var log bytes.Buffer
+	provider := NewProvider("news@example.com", &log)
+
+	first := &domain.Email{To: "a@example.com", Subject: "First", Text: "Hello"}
+	require.NoError(t, provider.Send(context.Background(), first))
+	require.NoError(t, provider.Send(context.Background(), &domain.Email{From: "Tech <tech@example.com>", To: "b@example.com", Subject: "Second"}))
+
+	assert.NotEmpty(t, first.MessageID)
+
+	messages := provider.Messages("")
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Second", messages[0].Subject)
+	assert.Equal(t, "Tech <tech@example.com>", messages[0].From)
+	assert.Equal(t, "news@example.com", messages[1].From)
+
+	assert.Equal(t, []string{"First"}, subjects(provider.Messages("a@example.com")))
+
+	var logged Message
+	require.NoError(t, json.NewDecoder(&log).Decode(&logged))
+	assert.Equal(t, first.MessageID, logged.ID)
+	assert.Equal(t, "Hello", logged.Text)
+}
+
+func TestSend_KeepsTheMostRecentEmails(t *testing.T) {
+	provider := NewProvider("news@example.com", nil)
+
+	for i := 0; i < Capacity+1; i++ {
+		require.NoError(t, provider.Send(context.Background(), &domain.Email{To: "a@example.com", Subject: "Issue"}))
+	}
+	require.NoError(t, provider.Send(context.Background(), &domain.Email{To: "a@example.com", Subject: "Last"}))
+
+	messages := provider.Messages("")
+	assert.Len(t, messages, Capacity)
+	assert.Equal(t, "Last", messages[0].Subject)
+}
+
+func subjects(messages []Message) []string {
+	result := make([]string, len(messages))
+	for i, message := range messages {
+		result[i] = message.Subject
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package infrastructure
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"newsletter/config"
+	awsrepo "newsletter/internal/infrastructure/aws"
+	"newsletter/internal/notifications/domain"
+	"newsletter/internal/notifications/infrastructure/mailgun"
+	"newsletter/internal/notifications/infrastructure/outbox"
+	"newsletter/internal/notifications/infrastructure/sendgrid"
+	"newsletter/internal/notifications/infrastructure/ses"
+	"newsletter/internal/notifications/infrastructure/smtp"
+	"os"
+)
+
+// NewProvider builds the email provider selected by cfg.Provider.
+//
+// Supported values:
+//   - "ses" (default): AWS SES, configured through the standard AWS variables
+//   - "sendgrid": SendGrid, requires an API key
+//   - "mailgun": Mailgun, requires an API key and a sending domain
+//   - "smtp": a local SMTP capture server such as MailHog or Mailpit, for development
+//
+// Emails are sent from cfg.From unless a newsletter has its own sender.
+// With cfg.DryRun, emails are recorded by an *outbox.Provider instead,
+// whatever the selected provider.
+func NewProvider(cfg config.Email) (domain.Provider, error) {
+	if cfg.From == "" {
+		return nil, errors.New("sender address is missing: set EMAIL_FROM")
+	}
+
+	if cfg.DryRun {
+		slog.Warn("EMAIL_DRY_RUN is enabled: emails are recorded instead of being sent", "file", cfg.DryRunFile)
+		if cfg.DryRunFile == "" {
+			return outbox.NewProvider(cfg.From, nil), nil
+		}
+		file, err := os.OpenFile(cfg.DryRunFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("open EMAIL_DRY_RUN_FILE: %w", err)
+		}
+		return outbox.NewProvider(cfg.From, file), nil
+	}
+
+	slog.Info("initializing email provider", "provider", cfg.Provider)
+
+	switch cfg.Provider {
+	case config.ProviderSES:
+		client, err := awsrepo.InitSESClient(cfg.AWSRegion)
+		if err != nil {
+			return nil, err
+		}
+		return ses.NewProvider(client, cfg.From), nil
+
+	case config.ProviderSendGrid:
+		if cfg.SendGridAPIKey == "" {
+			return nil, errors.New("SENDGRID_API_KEY is missing")
+		}
+		return sendgrid.NewProvider(orDefault(cfg.SendGridBaseURL, sendgrid.DefaultBaseURL), cfg.SendGridAPIKey, cfg.From), nil
+
+	case config.ProviderMailgun:
+		if cfg.MailgunAPIKey == "" || cfg.MailgunDomain == "" {
+			return nil, errors.New("MAILGUN_API_KEY and MAILGUN_DOMAIN are required")
+		}
+		return mailgun.NewProvider(orDefault(cfg.MailgunBaseURL, mailgun.DefaultBaseURL), cfg.MailgunDomain, cfg.MailgunAPIKey, cfg.From), nil
+
+	case config.ProviderSMTP:
+		slog.Warn("emails are delivered to a local SMTP server", "host", cfg.SMTPHost, "port", cfg.SMTPPort)
+		return smtp.NewProvider(net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort), cfg.From), nil
+
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", cfg.Provider)
+	}
+}
+
+// NewInboundMailStore reads the received emails that the S3 action of SES
+// receipt rules stores, with the default AWS credentials.
+func NewInboundMailStore() (domain.InboundMailStore, error) {
+	client, err := awsrepo.InitS3Client()
+	if err != nil {
+		return nil, err
+	}
+	return ses.NewMailStore(client), nil
+}
+
+// orDefault returns value, or fallback when value is empty.
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
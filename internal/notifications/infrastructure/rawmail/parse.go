@@ -0,0 +1,170 @@
+package rawmail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"newsletter/internal/infrastructure/sanitize"
+	"newsletter/internal/notifications/domain"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// messageIDPattern matches the message IDs listed in In-Reply-To and
+// References headers.
+var messageIDPattern = regexp.MustCompile(`<[^<>\s]+>`)
+
+// wordDecoder decodes the encoded words of headers in any charset known to
+// browsers, not only UTF-8 and ISO-8859-1.
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// Parse reads a received MIME message, such as a reply to a campaign email.
+// The text of the email is its first text/plain part, or its first
+// text/html part without markup; attachments are ignored.
+func Parse(raw []byte) (*domain.InboundEmail, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	from, err := parser.Parse(message.Header.Get("From"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender: %w", err)
+	}
+
+	subject, err := wordDecoder.DecodeHeader(message.Header.Get("Subject"))
+	if err != nil {
+		subject = message.Header.Get("Subject")
+	}
+
+	receivedAt, err := message.Header.Date()
+	if err != nil {
+		receivedAt = time.Now()
+	}
+
+	// In-Reply-To names the email answered; References lists the thread,
+	// oldest first.
+	references := messageIDPattern.FindAllString(message.Header.Get("In-Reply-To"), -1)
+	thread := messageIDPattern.FindAllString(message.Header.Get("References"), -1)
+	slices.Reverse(thread)
+	for _, id := range thread {
+		if !slices.Contains(references, id) {
+			references = append(references, id)
+		}
+	}
+
+	text, html, err := bodies(textproto.MIMEHeader(message.Header), message.Body)
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		text = sanitize.Text(html)
+	}
+
+	return &domain.InboundEmail{
+		MessageID:  strings.TrimSpace(message.Header.Get("Message-ID")),
+		From:       from.Address,
+		Subject:    subject,
+		References: references,
+		Text:       text,
+		ReceivedAt: receivedAt.UTC(),
+	}, nil
+}
+
+// bodies returns the first plain text and HTML bodies of a part with header,
+// looking into nested multipart parts.
+func bodies(header textproto.MIMEHeader, body io.Reader) (text, html string, err error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition")); disposition == "attachment" {
+		return "", "", nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		parts := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := parts.NextPart()
+			if errors.Is(err, io.EOF) {
+				return text, html, nil
+			}
+			if err != nil {
+				return "", "", fmt.Errorf("invalid multipart body: %w", err)
+			}
+
+			partText, partHTML, err := bodies(part.Header, part)
+			if err != nil {
+				return "", "", err
+			}
+			if text == "" {
+				text = partText
+			}
+			if html == "" {
+				html = partHTML
+			}
+		}
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", "", nil
+	}
+
+	content, err := decode(header, params["charset"], body)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid %s body: %w", mediaType, err)
+	}
+	if mediaType == "text/html" {
+		return "", content, nil
+	}
+	return content, "", nil
+}
+
+// decode reads body according to its transfer encoding and charset.
+// multipart.Reader already decodes quoted-printable parts and removes their
+// Content-Transfer-Encoding header.
+func decode(header textproto.MIMEHeader, charset string, body io.Reader) (string, error) {
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	// Bodies in unknown charsets are kept as they are.
+	if reader, err := charsetReader(charset, body); err == nil {
+		body = reader
+	}
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// charsetReader converts input from charset to UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "us-ascii":
+		return input, nil
+	}
+
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return encoding.NewDecoder().Reader(input), nil
+}
@@ -0,0 +1,105 @@
+package rawmail
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crlf joins lines with CRLF line endings, as in messages on the wire.
+func crlf(lines ...string) []byte {
+	return []byte(strings.Join(lines, "\r\n"))
+}
+
+func TestParse_MultipartReply(t *testing.T) {
+	raw := crlf(
+		"From: =?utf-8?q?Ren=C3=A9e?= <renee@example.com>",
+		"To: news@example.com",
+		"Subject: =?utf-8?q?Re:_Gr=C3=BC=C3=9Fe?=",
+		"Date: Mon, 12 Jan 2026 10:00:00 +0100",
+		"Message-ID: <reply-1@mail.example.com>",
+		"In-Reply-To: <0100018c-abc@email.amazonses.com>",
+		"References: <root@example.com> <0100018c-abc@email.amazonses.com>",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/alternative; boundary="b1"`,
+		"",
+		"--b1",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"Thanks, I loved this issue!",
+		"",
+		"On Mon, Jan 12, 2026 at 9:00 AM Tech News <",
+		"news@example.com> wrote:",
+		"> Issue #1",
+		"--b1",
+		"Content-Type: text/html; charset=utf-8",
+		"",
+		"<p>Thanks, I loved this issue!</p>",
+		"--b1--",
+	)
+
+	email, err := Parse(raw)
+
+	require.NoError(t, err)
+	assert.Equal(t, "renee@example.com", email.From)
+	assert.Equal(t, "Re: Grüße", email.Subject)
+	assert.Equal(t, "<reply-1@mail.example.com>", email.MessageID)
+	assert.Equal(t, []string{"<0100018c-abc@email.amazonses.com>", "<root@example.com>"}, email.References)
+	assert.Equal(t, time.Date(2026, 1, 12, 9, 0, 0, 0, time.UTC), email.ReceivedAt)
+	assert.Equal(t, "Thanks, I loved this issue!", email.Reply())
+}
+
+func TestParse_HTMLOnly(t *testing.T) {
+	raw := crlf(
+		"From: reader@example.com",
+		"Subject: Re: Issue",
+		"Content-Type: text/html; charset=iso-8859-1",
+		"Content-Transfer-Encoding: base64",
+		"",
+		"PHA+R3L8J2UhPC9wPg==",
+	)
+
+	email, err := Parse(raw)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Grü'e!", email.Text)
+	assert.Empty(t, email.References)
+}
+
+func TestParse_SkipsAttachments(t *testing.T) {
+	raw := crlf(
+		"From: reader@example.com",
+		`Content-Type: multipart/mixed; boundary="m"`,
+		"",
+		"--m",
+		"Content-Type: text/plain",
+		"Content-Disposition: attachment; filename=notes.txt",
+		"",
+		"attached notes",
+		"--m",
+		"Content-Type: text/plain",
+		"",
+		"See my notes.",
+		"> quoted",
+		"-- ",
+		"Reader",
+		"--m--",
+	)
+
+	email, err := Parse(raw)
+
+	require.NoError(t, err)
+	assert.Equal(t, "See my notes.", email.Reply())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte("not a message"))
+	assert.Error(t, err)
+
+	_, err = Parse(crlf("Subject: no sender", "", "body"))
+	assert.Error(t, err)
+}
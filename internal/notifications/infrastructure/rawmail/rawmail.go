@@ -0,0 +1,192 @@
+// Package rawmail builds the MIME messages of emails, for the providers
+// that are given complete messages rather than their parts: SMTP servers
+// and the raw sending API of AWS SES.
+package rawmail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"newsletter/internal/notifications/domain"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Recipients returns the addresses email is delivered to: its recipient
+// and its Cc and Bcc copies.
+func Recipients(email *domain.Email) ([]string, error) {
+	addresses := append(append([]string{email.To}, email.Cc...), email.Bcc...)
+	recipients := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", address, err)
+		}
+		recipients = append(recipients, parsed.Address)
+	}
+	return recipients, nil
+}
+
+// addressList formats addresses as the value of an address header.
+func addressList(addresses []string) (string, error) {
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return "", fmt.Errorf("invalid address %q: %w", address, err)
+		}
+		formatted = append(formatted, parsed.String())
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+// Compose returns the MIME message of email sent by sender, with a plain
+// text and an HTML alternative, followed by its attachments if any. Its
+// Reply-To, Cc and custom headers are written; Bcc copies are not, as they
+// must stay hidden. The Message-ID header is left out when messageID is
+// empty, for providers assigning their own.
+func Compose(sender *mail.Address, messageID string, email *domain.Email) ([]byte, error) {
+	body, contentType, err := alternatives(email)
+	if err != nil {
+		return nil, err
+	}
+	if len(email.Attachments) > 0 {
+		if body, contentType, err = mixed(body, contentType, email.Attachments); err != nil {
+			return nil, err
+		}
+	}
+
+	to, err := addressList([]string{email.To})
+	if err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", sender)
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	if len(email.Cc) > 0 {
+		cc, err := addressList(email.Cc)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&message, "Cc: %s\r\n", cc)
+	}
+	if email.ReplyTo != "" {
+		replyTo, err := addressList([]string{email.ReplyTo})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&message, "Reply-To: %s\r\n", replyTo)
+	}
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if messageID != "" {
+		fmt.Fprintf(&message, "Message-ID: %s\r\n", messageID)
+	}
+
+	names := make([]string, 0, len(email.Headers))
+	for name := range email.Headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for name := range slices.Values(names) {
+		value := email.Headers[name]
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("header %s spans several lines", name)
+		}
+		fmt.Fprintf(&message, "%s: %s\r\n", textproto.CanonicalMIMEHeaderKey(name), mime.QEncoding.Encode("utf-8", value))
+	}
+
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: %s\r\n\r\n", contentType)
+	message.Write(body)
+
+	return message.Bytes(), nil
+}
+
+// alternatives returns the plain text and HTML bodies of email as a
+// multipart/alternative body, with its content type.
+func alternatives(email *domain.Email) ([]byte, string, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, alternative := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	} {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		encoder := quotedprintable.NewWriter(part)
+		if _, err := encoder.Write([]byte(alternative.content)); err != nil {
+			return nil, "", err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return body.Bytes(), mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": parts.Boundary()}), nil
+}
+
+// mixed returns a multipart/mixed body made of the body of contentType
+// followed by attachments, encoded in base64, with its content type.
+func mixed(body []byte, contentType string, attachments []domain.Attachment) ([]byte, string, error) {
+	var mixedBody bytes.Buffer
+	parts := multipart.NewWriter(&mixedBody)
+
+	part, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(body); err != nil {
+		return nil, "", err
+	}
+
+	for _, attachment := range attachments {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(attachment.Type(), map[string]string{"name": attachment.Filename})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		if err := writeBase64(part, attachment.Data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return mixedBody.Bytes(), mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": parts.Boundary()}), nil
+}
+
+// writeBase64 writes data encoded in base64, in lines of 76 characters as
+// required by RFC 2045.
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		line := encoded[:min(76, len(encoded))]
+		encoded = encoded[len(line):]
+		if _, err := io.WriteString(w, line+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
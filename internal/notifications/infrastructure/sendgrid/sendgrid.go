@@ -0,0 +1,159 @@
+package sendgrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"newsletter/internal/notifications/domain"
+	"time"
+)
+
+// DefaultBaseURL is the SendGrid v3 API endpoint.
+const DefaultBaseURL = "https://api.sendgrid.com"
+
+// Provider sends emails using the SendGrid v3 Mail Send API.
+type Provider struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	from    string
+}
+
+func NewProvider(baseURL, apiKey, from string) *Provider {
+	return &Provider{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		from:    from,
+	}
+}
+
+type address struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// parseAddress splits an RFC 5322 address such as "Weekly <news@example.com>"
+// into the separate fields expected by SendGrid.
+func parseAddress(value string) address {
+	parsed, err := mail.ParseAddress(value)
+	if err != nil {
+		return address{Email: value}
+	}
+	return address{Email: parsed.Address, Name: parsed.Name}
+}
+
+// addresses parses a list of addresses, see parseAddress.
+func addresses(values []string) []address {
+	if len(values) == 0 {
+		return nil
+	}
+	parsed := make([]address, 0, len(values))
+	for _, value := range values {
+		parsed = append(parsed, parseAddress(value))
+	}
+	return parsed
+}
+
+type personalization struct {
+	To  []address `json:"to"`
+	Cc  []address `json:"cc,omitempty"`
+	Bcc []address `json:"bcc,omitempty"`
+}
+
+type content struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type attachment struct {
+	Content     string `json:"content"` // Base64 encoded
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+}
+
+type mailSendRequest struct {
+	Personalizations []personalization `json:"personalizations"`
+	From             address           `json:"from"`
+	ReplyTo          *address          `json:"reply_to,omitempty"`
+	Subject          string            `json:"subject"`
+	Content          []content         `json:"content"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Attachments      []attachment      `json:"attachments,omitempty"`
+}
+
+// Name returns the provider identifier.
+func (p *Provider) Name() string {
+	return "sendgrid"
+}
+
+// Send sends an email to a recipient with both plain text and HTML bodies,
+// its reply-to address, copies, custom headers and attachments.
+//
+// Returns:
+//   - An error wrapping domain.ErrRetryable for rate limiting (429), server
+//     errors (5xx) and network failures, domain.ErrPermanent for any other
+//     rejected request; otherwise nil.
+func (p *Provider) Send(ctx context.Context, email *domain.Email) error {
+	request := mailSendRequest{
+		Personalizations: []personalization{{
+			To:  []address{{Email: email.To}},
+			Cc:  addresses(email.Cc),
+			Bcc: addresses(email.Bcc),
+		}},
+		From:    parseAddress(email.Sender(p.from)),
+		Subject: email.Subject,
+		Content: []content{
+			{Type: "text/plain", Value: email.Text},
+			{Type: "text/html", Value: email.HTML},
+		},
+		Headers: email.Headers,
+	}
+	if email.ReplyTo != "" {
+		replyTo := parseAddress(email.ReplyTo)
+		request.ReplyTo = &replyTo
+	}
+	for _, file := range email.Attachments {
+		request.Attachments = append(request.Attachments, attachment{
+			Content:     base64.StdEncoding.EncodeToString(file.Data),
+			Type:        file.Type(),
+			Filename:    file.Filename,
+			Disposition: "attachment",
+		})
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("%w: sendgrid: %v", domain.ErrPermanent, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v3/mail/send", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%w: sendgrid: %v", domain.ErrPermanent, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: sendgrid: %v", domain.ErrRetryable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return domain.ClassifyHTTPStatus(p.Name(), resp.StatusCode, string(body))
+	}
+
+	email.MessageID = resp.Header.Get("X-Message-Id")
+	slog.Info("SendGrid accepted message", "message", email.MessageID)
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package ses
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// maxInboundMessage is the size of the largest received email read from S3.
+// Replies are small; larger emails carry attachments that are not kept.
+const maxInboundMessage = 10 << 20
+
+// s3API is the subset of the S3 client used by MailStore.
+type s3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// MailStore reads the emails that the S3 action of SES receipt rules
+// stores, for emails too large to be carried by SNS notifications.
+type MailStore struct {
+	client s3API
+}
+
+func NewMailStore(client s3API) *MailStore {
+	return &MailStore{client: client}
+}
+
+// Fetch returns the raw message stored as key in bucket. Messages larger
+// than 10 MiB are refused.
+func (ms *MailStore) Fetch(ctx context.Context, bucket, key string) ([]byte, error) {
+	object, err := ms.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get received email %s/%s: %w", bucket, key, err)
+	}
+	defer object.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(object.Body, maxInboundMessage+1))
+	if err != nil {
+		return nil, fmt.Errorf("read received email %s/%s: %w", bucket, key, err)
+	}
+	if len(raw) > maxInboundMessage {
+		return nil, fmt.Errorf("received email %s/%s is larger than %d bytes", bucket, key, maxInboundMessage)
+	}
+	return raw, nil
+}
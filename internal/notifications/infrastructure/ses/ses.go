@@ -0,0 +1,206 @@
+package ses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"newsletter/internal/notifications/domain"
+	"newsletter/internal/notifications/infrastructure/rawmail"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ses"
+	"github.com/aws/aws-sdk-go-v2/service/ses/types"
+	"github.com/aws/smithy-go"
+)
+
+// retryableCodes lists SES error codes caused by throttling or temporary
+// unavailability of the service.
+var retryableCodes = map[string]bool{
+	"Throttling":          true,
+	"ThrottlingException": true,
+	"ServiceUnavailable":  true,
+	"RequestTimeout":      true,
+}
+
+// Provider sends emails using AWS SES.
+type Provider struct {
+	client *ses.Client
+	from   string
+}
+
+func NewProvider(client *ses.Client, from string) *Provider {
+	return &Provider{client: client, from: from}
+}
+
+// Name returns the provider identifier.
+func (p *Provider) Name() string {
+	return "ses"
+}
+
+// Send sends an email to a recipient.
+//
+// Behavior:
+//   - Constructs both HTML and plain text versions of the email.
+//   - Sends the email via AWS SES, with its reply-to address and copies.
+//   - Emails with custom headers or attachments are sent as raw MIME
+//     messages, as the simple SES API supports neither.
+//
+// Notes:
+//   - The "from" address (email.From or the default sender) must be verified
+//     in AWS SES (sandbox or production).
+//   - In the SES sandbox, recipient addresses must also be verified.
+//
+// Returns:
+//   - An error wrapping domain.ErrRetryable or domain.ErrPermanent if sending fails; otherwise nil,
+//     with email.MessageID set to the SES MessageId.
+func (p *Provider) Send(ctx context.Context, email *domain.Email) error {
+	if len(email.Headers) > 0 || len(email.Attachments) > 0 {
+		return p.sendRaw(ctx, email)
+	}
+
+	input := &ses.SendEmailInput{
+		Destination: &types.Destination{
+			ToAddresses:  []string{email.To},
+			CcAddresses:  email.Cc,
+			BccAddresses: email.Bcc,
+		},
+		Message: &types.Message{
+			Body: &types.Body{
+				Html: &types.Content{
+					Data: aws.String(email.HTML),
+				},
+				Text: &types.Content{
+					Data: aws.String(email.Text),
+				},
+			},
+			Subject: &types.Content{
+				Data: aws.String(email.Subject),
+			},
+		},
+		Source: aws.String(email.Sender(p.from)),
+	}
+	if email.ReplyTo != "" {
+		input.ReplyToAddresses = []string{email.ReplyTo}
+	}
+
+	response, err := p.client.SendEmail(ctx, input)
+	if err != nil {
+		return classify(err)
+	}
+
+	email.MessageID = aws.ToString(response.MessageId)
+	slog.Info("SES accepted message", "message", email.MessageID)
+
+	return nil
+}
+
+// sendRaw sends email as a MIME message built by the service. SES assigns
+// the Message-ID header.
+func (p *Provider) sendRaw(ctx context.Context, email *domain.Email) error {
+	sender, err := mail.ParseAddress(email.Sender(p.from))
+	if err != nil {
+		return fmt.Errorf("%w: ses: invalid sender: %v", domain.ErrPermanent, err)
+	}
+	recipients, err := rawmail.Recipients(email)
+	if err != nil {
+		return fmt.Errorf("%w: ses: %v", domain.ErrPermanent, err)
+	}
+	message, err := rawmail.Compose(sender, "", email)
+	if err != nil {
+		return fmt.Errorf("%w: ses: %v", domain.ErrPermanent, err)
+	}
+
+	response, err := p.client.SendRawEmail(ctx, &ses.SendRawEmailInput{
+		Source:       aws.String(sender.String()),
+		Destinations: recipients,
+		RawMessage:   &types.RawMessage{Data: message},
+	})
+	if err != nil {
+		return classify(err)
+	}
+
+	email.MessageID = aws.ToString(response.MessageId)
+	slog.Info("SES accepted raw message", "message", email.MessageID)
+
+	return nil
+}
+
+// StartVerification asks SES to send a verification email to address.
+// The address can be used as sender once its owner follows the link in it.
+func (p *Provider) StartVerification(address string) error {
+	_, err := p.client.VerifyEmailIdentity(context.TODO(), &ses.VerifyEmailIdentityInput{
+		EmailAddress: aws.String(address),
+	})
+	if err != nil {
+		return classify(err)
+	}
+
+	slog.Info("SES verification email requested", "address", address)
+
+	return nil
+}
+
+// VerificationStatus returns the SES verification state of address.
+// Addresses unknown to SES are reported as domain.VerificationNotStarted.
+func (p *Provider) VerificationStatus(address string) (domain.VerificationStatus, error) {
+	response, err := p.client.GetIdentityVerificationAttributes(context.TODO(), &ses.GetIdentityVerificationAttributesInput{
+		Identities: []string{address},
+	})
+	if err != nil {
+		return "", classify(err)
+	}
+
+	attributes, ok := response.VerificationAttributes[address]
+	if !ok {
+		return domain.VerificationNotStarted, nil
+	}
+
+	switch attributes.VerificationStatus {
+	case types.VerificationStatusSuccess:
+		return domain.VerificationSuccess, nil
+	case types.VerificationStatusPending:
+		return domain.VerificationPending, nil
+	case types.VerificationStatusNotStarted:
+		return domain.VerificationNotStarted, nil
+	default:
+		// Failed and TemporaryFailure both require a new verification email.
+		return domain.VerificationFailed, nil
+	}
+}
+
+// DKIMTokens returns the Easy DKIM tokens of domain, or none if the domain
+// is not an SES identity with DKIM.
+func (p *Provider) DKIMTokens(ctx context.Context, domainName string) ([]string, error) {
+	response, err := p.client.GetIdentityDkimAttributes(ctx, &ses.GetIdentityDkimAttributesInput{
+		Identities: []string{domainName},
+	})
+	if err != nil {
+		return nil, classify(err)
+	}
+
+	return response.DkimAttributes[domainName].DkimTokens, nil
+}
+
+// DKIMTarget returns the host the CNAME record of an Easy DKIM token points to.
+func (p *Provider) DKIMTarget(token string) string {
+	return token + ".dkim.amazonses.com"
+}
+
+// classify maps an SES error to a retryable or permanent delivery error.
+//
+// Errors that are not SES API errors (for example network failures) are
+// considered retryable.
+func classify(err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return fmt.Errorf("%w: ses: %v", domain.ErrRetryable, err)
+	}
+
+	if retryableCodes[apiErr.ErrorCode()] || apiErr.ErrorFault() == smithy.FaultServer {
+		return fmt.Errorf("%w: ses: %v", domain.ErrRetryable, err)
+	}
+
+	return fmt.Errorf("%w: ses: %v", domain.ErrPermanent, err)
+}
@@ -0,0 +1,123 @@
+// Package smtp delivers emails to a plain SMTP server, such as a MailHog or
+// Mailpit instance capturing the emails of a development environment.
+package smtp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	netsmtp "net/smtp"
+	"net/textproto"
+	"newsletter/internal/notifications/domain"
+	"newsletter/internal/notifications/infrastructure/rawmail"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Provider sends emails over SMTP without authentication or TLS, which is
+// what local capture servers expect. It is not meant for production.
+type Provider struct {
+	addr string
+	from string
+}
+
+// NewProvider creates a provider delivering to the SMTP server at addr,
+// such as "localhost:1025".
+func NewProvider(addr, from string) *Provider {
+	return &Provider{addr: addr, from: from}
+}
+
+// Name returns the provider identifier.
+func (p *Provider) Name() string {
+	return "smtp"
+}
+
+// Send delivers an email with both plain text and HTML bodies to its
+// recipient and its copies.
+//
+// Returns:
+//   - An error wrapping domain.ErrRetryable for connection failures and
+//     transient (4xx) replies, domain.ErrPermanent for invalid addresses and
+//     permanent (5xx) replies; otherwise nil.
+func (p *Provider) Send(ctx context.Context, email *domain.Email) error {
+	sender, err := mail.ParseAddress(email.Sender(p.from))
+	if err != nil {
+		return fmt.Errorf("%w: smtp: invalid sender: %v", domain.ErrPermanent, err)
+	}
+	recipients, err := rawmail.Recipients(email)
+	if err != nil {
+		return fmt.Errorf("%w: smtp: %v", domain.ErrPermanent, err)
+	}
+
+	messageID := fmt.Sprintf("<%s@%s>", uuid.NewString(), domainOf(sender.Address))
+	message, err := rawmail.Compose(sender, messageID, email)
+	if err != nil {
+		return fmt.Errorf("%w: smtp: %v", domain.ErrPermanent, err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return fmt.Errorf("%w: smtp: %v", domain.ErrRetryable, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(p.addr)
+	client, err := netsmtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return classify(err)
+	}
+	defer client.Close()
+
+	if err := client.Mail(sender.Address); err != nil {
+		return classify(err)
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return classify(err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return classify(err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return classify(err)
+	}
+	if err := w.Close(); err != nil {
+		return classify(err)
+	}
+	if err := client.Quit(); err != nil {
+		slog.Warn("failed to close SMTP session", "error", err)
+	}
+
+	email.MessageID = messageID
+	slog.Info("SMTP server accepted message", "message", email.MessageID)
+
+	return nil
+}
+
+// classify wraps an SMTP error: permanent (5xx) replies are permanent
+// failures, everything else may succeed later.
+func classify(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return fmt.Errorf("%w: smtp: %v", domain.ErrPermanent, err)
+	}
+	return fmt.Errorf("%w: smtp: %v", domain.ErrRetryable, err)
+}
+
+// domainOf returns the domain of an email address, used in message IDs.
+func domainOf(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return address[at+1:]
+	}
+	return "localhost"
+}
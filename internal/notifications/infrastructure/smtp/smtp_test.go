@@ -0,0 +1,161 @@
+package smtp
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"newsletter/internal/notifications/domain"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer accepts one SMTP session on a local port and returns its
+// address and a channel receiving the DATA of the message.
+func fakeServer(t *testing.T, rcptReply string) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "RCPT"):
+				reply(rcptReply)
+			case command == "DATA":
+				reply("354 go ahead")
+				var message strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					message.WriteString(line)
+				}
+				data <- message.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), data
+}
+
+func TestSend(t *testing.T) {
+	addr, data := fakeServer(t, "250 ok")
+	provider := NewProvider(addr, "News <news@example.com>")
+	email := &domain.Email{To: "reader@example.com", Subject: "Grüße", Text: "Hello", HTML: "<p>Hello</p>"}
+
+	require.NoError(t, provider.Send(context.Background(), email))
+
+	message, err := mail.ReadMessage(strings.NewReader(<-data))
+	require.NoError(t, err)
+	assert.Equal(t, `"News" <news@example.com>`, message.Header.Get("From"))
+	assert.Equal(t, "<reader@example.com>", message.Header.Get("To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Grüße", subject)
+	assert.Contains(t, message.Header.Get("Content-Type"), "multipart/alternative")
+	assert.Equal(t, email.MessageID, message.Header.Get("Message-ID"))
+	assert.True(t, strings.HasSuffix(email.MessageID, "@example.com>"))
+}
+
+func TestSend_Envelope(t *testing.T) {
+	addr, data := fakeServer(t, "250 ok")
+	provider := NewProvider(addr, "news@example.com")
+	email := &domain.Email{To: "reader@example.com", Subject: "Hi", Envelope: domain.Envelope{
+		ReplyTo: "Editor <editor@example.com>",
+		Cc:      []string{"archive@example.com"},
+		Bcc:     []string{"audit@example.com"},
+		Headers: map[string]string{"List-Id": "Weekly <weekly.example.com>", "X-Campaign": "Été"},
+	}}
+
+	require.NoError(t, provider.Send(context.Background(), email))
+
+	message, err := mail.ReadMessage(strings.NewReader(<-data))
+	require.NoError(t, err)
+	assert.Equal(t, `"Editor" <editor@example.com>`, message.Header.Get("Reply-To"))
+	assert.Equal(t, "<archive@example.com>", message.Header.Get("Cc"))
+	assert.Empty(t, message.Header.Get("Bcc"), "bcc copies stay hidden")
+	assert.Equal(t, "Weekly <weekly.example.com>", message.Header.Get("List-Id"))
+	campaign, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("X-Campaign"))
+	require.NoError(t, err)
+	assert.Equal(t, "Été", campaign)
+}
+
+func TestSend_Attachments(t *testing.T) {
+	addr, data := fakeServer(t, "250 ok")
+	provider := NewProvider(addr, "news@example.com")
+	email := &domain.Email{To: "reader@example.com", Subject: "Invoice", Text: "Attached", HTML: "<p>Attached</p>",
+		Attachments: []domain.Attachment{{Filename: "invoice.pdf", Data: []byte("%PDF-1.7 invoice")}}}
+
+	require.NoError(t, provider.Send(context.Background(), email))
+
+	message, err := mail.ReadMessage(strings.NewReader(<-data))
+	require.NoError(t, err)
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	body, err := parts.NextPart()
+	require.NoError(t, err)
+	assert.Contains(t, body.Header.Get("Content-Type"), "multipart/alternative")
+
+	attachment, err := parts.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "invoice.pdf", attachment.FileName())
+	assert.Equal(t, "application/pdf; name=invoice.pdf", attachment.Header.Get("Content-Type"))
+	content, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, attachment))
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.7 invoice", string(content))
+}
+
+func TestSend_RejectedRecipient(t *testing.T) {
+	addr, _ := fakeServer(t, "550 no such user")
+	provider := NewProvider(addr, "news@example.com")
+
+	err := provider.Send(context.Background(), &domain.Email{To: "reader@example.com", Subject: "Hi"})
+
+	assert.ErrorIs(t, err, domain.ErrPermanent)
+}
+
+func TestSend_ServerUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	err = NewProvider(addr, "news@example.com").Send(context.Background(), &domain.Email{To: "reader@example.com"})
+
+	assert.ErrorIs(t, err, domain.ErrRetryable)
+}
@@ -0,0 +1,172 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"newsletter/internal/infrastructure/sanitize"
+	"newsletter/internal/posts/domain"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// PostService provides application-level operations related to posts
+// and it orchestrates domain logic and persistence concerns.
+type PostService struct {
+	pr domain.PostRepository
+
+	maxBodyLength int
+}
+
+func NewPostService(pr domain.PostRepository) *PostService {
+	return &PostService{pr: pr, maxBodyLength: domain.DefaultMaxBodyLength}
+}
+
+// SetMaxBodyLength sets the maximum number of characters of the body of a
+// post, replacing domain.DefaultMaxBodyLength.
+func (ps *PostService) SetMaxBodyLength(n int) {
+	ps.maxBodyLength = n
+}
+
+// Create saves a new draft post. Its content is sanitized and validated
+// first, see prepare.
+func (ps *PostService) Create(post *domain.Post) (*domain.Post, error) {
+	if err := ps.prepare(post); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	post.Status = domain.StatusDraft
+	newPost, err := ps.pr.Create(ctx, post)
+	if err != nil {
+		slog.Error("failed to create post", "newsletter_id", post.NewsletterID, "error", err)
+		return nil, err
+	}
+
+	return newPost, nil
+}
+
+// prepare sanitizes the content of post before it is stored: the title is
+// the plain-text subject of emails and the body keeps only safe HTML, as
+// both end up in emails and on public pages. The cleaned post must then be
+// valid and its body fit the length limit, otherwise domain.ErrInvalidPost
+// is returned.
+func (ps *PostService) prepare(post *domain.Post) error {
+	post.Title = sanitize.Text(post.Title)
+	post.Body = sanitize.HTML(post.Body)
+	if err := post.Validate(); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(post.Body) > ps.maxBodyLength {
+		return fmt.Errorf("%w: body must be at most %d characters", domain.ErrInvalidPost, ps.maxBodyLength)
+	}
+	return nil
+}
+
+// Prepare sanitizes and validates post as Create does, see prepare, without
+// storing it.
+func (ps *PostService) Prepare(post *domain.Post) error {
+	return ps.prepare(post)
+}
+
+// Get returns a post of a newsletter.
+func (ps *PostService) Get(newsletterID, id uuid.UUID) (*domain.Post, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	return ps.pr.Get(ctx, newsletterID, id)
+}
+
+// List returns the posts of a newsletter, newest first, optionally only
+// those with the given status.
+func (ps *PostService) List(newsletterID uuid.UUID, status string) ([]*domain.Post, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	posts, err := ps.pr.List(ctx, newsletterID, status)
+	if err != nil {
+		slog.Error("failed to list posts", "newsletter_id", newsletterID, "error", err)
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// Update replaces the title and body of a draft post, sanitized and
+// validated as by Create. Published and archived posts are frozen and fail
+// with domain.ErrPostNotEditable.
+func (ps *PostService) Update(post *domain.Post) (*domain.Post, error) {
+	if err := ps.prepare(post); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	updated, err := ps.pr.UpdateContent(ctx, post)
+	if err != nil {
+		slog.Warn("failed to update post", "post_id", post.ID, "error", err)
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// Publish freezes a draft post and makes it sendable.
+func (ps *PostService) Publish(newsletterID, id uuid.UUID) (*domain.Post, error) {
+	return ps.transition(newsletterID, id, (*domain.Post).Publish)
+}
+
+// Archive retires a published post.
+func (ps *PostService) Archive(newsletterID, id uuid.UUID) (*domain.Post, error) {
+	return ps.transition(newsletterID, id, (*domain.Post).Archive)
+}
+
+// transition applies a lifecycle change to a post and persists it, failing
+// with domain.ErrInvalidTransition if the post changed status in between.
+func (ps *PostService) transition(newsletterID, id uuid.UUID, apply func(*domain.Post, time.Time) error) (*domain.Post, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	post, err := ps.pr.Get(ctx, newsletterID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from := post.Status
+	if err := apply(post, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	if err := ps.pr.UpdateStatus(ctx, post, from); err != nil {
+		slog.Error("failed to change post status", "post_id", id, "from", from, "to", post.Status, "error", err)
+		return nil, err
+	}
+
+	slog.Info("post status changed", "post_id", id, "from", from, "to", post.Status)
+	return post, nil
+}
+
+// MarkSent checks that a post can be sent, and that its merge tags can be
+// expanded, and records its sent time.
+func (ps *PostService) MarkSent(newsletterID, id uuid.UUID) (*domain.Post, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	post, err := ps.pr.Get(ctx, newsletterID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := post.CanSend(); err != nil {
+		return nil, err
+	}
+	if err := post.ValidateMergeTags(); err != nil {
+		return nil, err
+	}
+
+	return ps.pr.MarkSent(ctx, newsletterID, id, time.Now().UTC())
+}
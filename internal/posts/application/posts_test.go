@@ -0,0 +1,197 @@
+package application_test
+
+import (
+	"context"
+	"newsletter/internal/posts/application"
+	"newsletter/internal/posts/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mock Post Repository ---
+type MockPostRepository struct {
+	mock.Mock
+}
+
+func (m *MockPostRepository) Create(ctx context.Context, post *domain.Post) (*domain.Post, error) {
+	args := m.Called(ctx, post)
+	p := args.Get(0)
+	if p == nil {
+		return nil, args.Error(1)
+	}
+	return p.(*domain.Post), args.Error(1)
+}
+
+func (m *MockPostRepository) Get(ctx context.Context, newsletterID, id uuid.UUID) (*domain.Post, error) {
+	args := m.Called(ctx, newsletterID, id)
+	p := args.Get(0)
+	if p == nil {
+		return nil, args.Error(1)
+	}
+	return p.(*domain.Post), args.Error(1)
+}
+
+func (m *MockPostRepository) List(ctx context.Context, newsletterID uuid.UUID, status string) ([]*domain.Post, error) {
+	args := m.Called(ctx, newsletterID, status)
+	p := args.Get(0)
+	if p == nil {
+		return nil, args.Error(1)
+	}
+	return p.([]*domain.Post), args.Error(1)
+}
+
+func (m *MockPostRepository) UpdateContent(ctx context.Context, post *domain.Post) (*domain.Post, error) {
+	args := m.Called(ctx, post)
+	p := args.Get(0)
+	if p == nil {
+		return nil, args.Error(1)
+	}
+	return p.(*domain.Post), args.Error(1)
+}
+
+func (m *MockPostRepository) UpdateStatus(ctx context.Context, post *domain.Post, from string) error {
+	args := m.Called(ctx, post, from)
+	return args.Error(0)
+}
+
+func (m *MockPostRepository) MarkSent(ctx context.Context, newsletterID, id uuid.UUID, sentAt time.Time) (*domain.Post, error) {
+	args := m.Called(ctx, newsletterID, id, sentAt)
+	p := args.Get(0)
+	if p == nil {
+		return nil, args.Error(1)
+	}
+	return p.(*domain.Post), args.Error(1)
+}
+
+// --- Tests ---
+
+func TestCreatePost_StartsAsDraft(t *testing.T) {
+	mockRepo := new(MockPostRepository)
+	ps := application.NewPostService(mockRepo)
+
+	post := &domain.Post{NewsletterID: uuid.New(), Title: "Issue #1"}
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p *domain.Post) bool {
+		return p.Status == domain.StatusDraft
+	})).Return(post, nil)
+
+	created, err := ps.Create(post)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusDraft, created.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreatePost_RequiresTitle(t *testing.T) {
+	mockRepo := new(MockPostRepository)
+	ps := application.NewPostService(mockRepo)
+
+	_, err := ps.Create(&domain.Post{NewsletterID: uuid.New()})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidPost)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCreatePost_SanitizesContent(t *testing.T) {
+	mockRepo := new(MockPostRepository)
+	ps := application.NewPostService(mockRepo)
+
+	post := &domain.Post{
+		NewsletterID: uuid.New(),
+		Title:        `Issue <script>alert(1)</script>#1`,
+		Body:         `<p onclick="alert(1)">Hello <strong>world</strong></p><script>alert(1)</script>`,
+	}
+	mockRepo.On("Create", mock.Anything, post).Return(post, nil)
+
+	created, err := ps.Create(post)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Issue #1", created.Title)
+	assert.Equal(t, "<p>Hello <strong>world</strong></p>", created.Body)
+}
+
+func TestUpdatePost_BodyLengthLimit(t *testing.T) {
+	mockRepo := new(MockPostRepository)
+	ps := application.NewPostService(mockRepo)
+	ps.SetMaxBodyLength(10)
+
+	_, err := ps.Update(&domain.Post{NewsletterID: uuid.New(), Title: "Issue #1", Body: "<p>Too long</p>"})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidPost)
+	mockRepo.AssertNotCalled(t, "UpdateContent", mock.Anything, mock.Anything)
+}
+
+func TestPublishPost_Success(t *testing.T) {
+	mockRepo := new(MockPostRepository)
+	ps := application.NewPostService(mockRepo)
+
+	post := &domain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Status: domain.StatusDraft}
+	mockRepo.On("Get", mock.Anything, post.NewsletterID, post.ID).Return(post, nil)
+	mockRepo.On("UpdateStatus", mock.Anything, post, domain.StatusDraft).Return(nil)
+
+	published, err := ps.Publish(post.NewsletterID, post.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusPublished, published.Status)
+	assert.NotNil(t, published.PublishedAt)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestArchivePost_RejectsDraft(t *testing.T) {
+	mockRepo := new(MockPostRepository)
+	ps := application.NewPostService(mockRepo)
+
+	post := &domain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Status: domain.StatusDraft}
+	mockRepo.On("Get", mock.Anything, post.NewsletterID, post.ID).Return(post, nil)
+
+	_, err := ps.Archive(post.NewsletterID, post.ID)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidTransition)
+	mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMarkSent_Guards(t *testing.T) {
+	sentAt := time.Now()
+	tests := []struct {
+		name string
+		post *domain.Post
+		err  error
+	}{
+		{"draft", &domain.Post{Status: domain.StatusDraft}, domain.ErrPostNotSendable},
+		{"archived", &domain.Post{Status: domain.StatusArchived}, domain.ErrPostNotSendable},
+		{"already sent", &domain.Post{Status: domain.StatusPublished, SentAt: &sentAt}, domain.ErrPostAlreadySent},
+		{"unknown merge tag", &domain.Post{Status: domain.StatusPublished, Title: "Hi {{ first_name }}"}, domain.ErrUnknownMergeTag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockPostRepository)
+			ps := application.NewPostService(mockRepo)
+
+			tt.post.ID, tt.post.NewsletterID = uuid.New(), uuid.New()
+			mockRepo.On("Get", mock.Anything, tt.post.NewsletterID, tt.post.ID).Return(tt.post, nil)
+
+			_, err := ps.MarkSent(tt.post.NewsletterID, tt.post.ID)
+
+			assert.ErrorIs(t, err, tt.err)
+			mockRepo.AssertNotCalled(t, "MarkSent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestMarkSent_Published(t *testing.T) {
+	mockRepo := new(MockPostRepository)
+	ps := application.NewPostService(mockRepo)
+
+	post := &domain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Status: domain.StatusPublished}
+	mockRepo.On("Get", mock.Anything, post.NewsletterID, post.ID).Return(post, nil)
+	mockRepo.On("MarkSent", mock.Anything, post.NewsletterID, post.ID, mock.Anything).Return(post, nil)
+
+	_, err := ps.MarkSent(post.NewsletterID, post.ID)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
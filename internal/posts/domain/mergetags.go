@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"fmt"
+	"html"
+	apperrors "newsletter/internal/errors"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	"regexp"
+	"strings"
+)
+
+// ErrUnknownMergeTag is returned when sending a post whose title or body
+// uses a merge tag that cannot be expanded.
+var ErrUnknownMergeTag = apperrors.New(apperrors.Validation, "unknown merge tag")
+
+// Merge tags expanded for each recipient. A tag may give a fallback used
+// when the recipient has no value, as in {{ attributes.first_name | there }}.
+const (
+	TagEmail             = "email"               // Address of the recipient
+	TagUnsubscribeURL    = "unsubscribe_url"     // Link unsubscribing the recipient
+	TagUnsubscribeAllURL = "unsubscribe_all_url" // Link unsubscribing the recipient from every newsletter
+	TagNewsletterName    = "newsletter_name"     // Name of the newsletter
+
+	// AttributeTagPrefix starts the tags of custom subscriber attributes,
+	// such as {{ attributes.first_name }}.
+	AttributeTagPrefix = "attributes."
+)
+
+// mergeTag matches a merge tag and captures its name and fallback.
+var mergeTag = regexp.MustCompile(`\{\{\s*([^\s{}|<>"]+)\s*(?:\|\s*([^{}<>"]*?)\s*)?\}\}`)
+
+// KnownMergeTag reports whether name is a merge tag that can be expanded.
+func KnownMergeTag(name string) bool {
+	switch name {
+	case TagEmail, TagUnsubscribeURL, TagUnsubscribeAllURL, TagNewsletterName:
+		return true
+	}
+	key, ok := strings.CutPrefix(name, AttributeTagPrefix)
+	return ok && subscriptiondomain.ValidAttributeKey(key)
+}
+
+// UnknownMergeTags returns the names of the merge tags of s that cannot be
+// expanded, once each, in order of appearance.
+func UnknownMergeTags(s string) []string {
+	var unknown []string
+	seen := map[string]bool{}
+	for _, match := range mergeTag.FindAllStringSubmatch(s, -1) {
+		if name := match[1]; !KnownMergeTag(name) && !seen[name] {
+			seen[name] = true
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// ValidateMergeTags returns ErrUnknownMergeTag, naming the tags, if one of
+// texts uses a merge tag that cannot be expanded.
+func ValidateMergeTags(texts ...string) error {
+	if unknown := UnknownMergeTags(strings.Join(texts, "\n")); len(unknown) > 0 {
+		return fmt.Errorf("%w: {{%s}}", ErrUnknownMergeTag, strings.Join(unknown, "}}, {{"))
+	}
+	return nil
+}
+
+// ValidateMergeTags returns ErrUnknownMergeTag if the title or the body of
+// the post uses a merge tag that cannot be expanded.
+func (p *Post) ValidateMergeTags() error {
+	return ValidateMergeTags(p.Title, p.Body)
+}
+
+// MergeFields are the values of the merge tags for one recipient.
+type MergeFields struct {
+	Email             string
+	UnsubscribeURL    string
+	UnsubscribeAllURL string // Empty when unsubscribe-all links cannot be signed
+	NewsletterName    string
+	Attributes        map[string]string // Custom attributes of the subscriber, by key
+}
+
+// value returns the value of the merge tag name, empty if unknown.
+func (f MergeFields) value(name string) string {
+	switch name {
+	case TagEmail:
+		return f.Email
+	case TagUnsubscribeURL:
+		return f.UnsubscribeURL
+	case TagUnsubscribeAllURL:
+		return f.UnsubscribeAllURL
+	case TagNewsletterName:
+		return f.NewsletterName
+	}
+	if key, ok := strings.CutPrefix(name, AttributeTagPrefix); ok {
+		return f.Attributes[key]
+	}
+	return ""
+}
+
+// Expand replaces the merge tags of the plain text s, such as a subject.
+func (f MergeFields) Expand(s string) string {
+	return f.expand(s, func(value string) string { return value })
+}
+
+// ExpandHTML replaces the merge tags of the HTML s, escaping their values.
+func (f MergeFields) ExpandHTML(s string) string {
+	return f.expand(s, html.EscapeString)
+}
+
+func (f MergeFields) expand(s string, escape func(string) string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return mergeTag.ReplaceAllStringFunc(s, func(tag string) string {
+		match := mergeTag.FindStringSubmatch(tag)
+		if value := f.value(match[1]); value != "" {
+			return escape(value)
+		}
+		// The fallback is part of s, and thus already escaped in HTML.
+		return match[2]
+	})
+}
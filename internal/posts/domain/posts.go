@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	apperrors "newsletter/internal/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Post statuses. A post starts as a draft, is frozen when published and is
+// finally archived:
+//
+//	draft -> published -> archived
+//
+// Only drafts can be edited and only published posts can be sent.
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+var (
+	// ErrPostNotFound is returned when a post does not exist in the newsletter.
+	ErrPostNotFound = apperrors.New(apperrors.NotFound, "post not found")
+	// ErrInvalidPost is returned when a post is missing required content.
+	ErrInvalidPost = apperrors.New(apperrors.Validation, "invalid post")
+	// ErrPostNotEditable is returned when editing a post that is no longer a draft.
+	ErrPostNotEditable = apperrors.New(apperrors.Conflict, "only draft posts can be edited")
+	// ErrInvalidTransition is returned when a status change is not allowed from the current status.
+	ErrInvalidTransition = apperrors.New(apperrors.Conflict, "invalid status transition")
+	// ErrPostNotSendable is returned when sending a post that is not published.
+	ErrPostNotSendable = apperrors.New(apperrors.Conflict, "only published posts can be sent")
+	// ErrPostAlreadySent is returned when sending a post that has already been sent.
+	ErrPostAlreadySent = apperrors.New(apperrors.Conflict, "post already sent")
+)
+
+// DefaultMaxBodyLength is the default maximum number of characters of the
+// body of a post. It can be changed with PostService.SetMaxBodyLength.
+const DefaultMaxBodyLength = 1_000_000
+
+// Post represents an issue of a newsletter.
+type Post struct {
+	ID           uuid.UUID  `json:"id"`                     // ID of the post
+	NewsletterID uuid.UUID  `json:"newsletter_id"`          // Newsletter the post belongs to
+	Title        string     `json:"title"`                  // Title, used as email subject
+	Body         string     `json:"body"`                   // HTML content of the post
+	Status       string     `json:"status"`                 // Lifecycle status of the post
+	Version      int        `json:"version"`                // Incremented on every edit, frozen on publish
+	PublishedAt  *time.Time `json:"published_at,omitempty"` // Time of publication, if any
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`  // Time of archival, if any
+	SentAt       *time.Time `json:"sent_at,omitempty"`      // Time the post was sent to subscribers, if any
+	CreatedAt    time.Time  `json:"created_at"`             // Creation time of the post
+	UpdatedAt    time.Time  `json:"updated_at"`             // Time of the last change
+}
+
+// Validate checks that the post has the content required to be saved.
+func (p *Post) Validate() error {
+	if p.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidPost)
+	}
+	return nil
+}
+
+// Publish freezes a draft and makes it sendable.
+func (p *Post) Publish(now time.Time) error {
+	if p.Status != StatusDraft {
+		return ErrInvalidTransition
+	}
+	p.Status = StatusPublished
+	p.PublishedAt = &now
+	p.UpdatedAt = now
+	return nil
+}
+
+// Archive retires a published post. Archived posts can no longer be sent.
+func (p *Post) Archive(now time.Time) error {
+	if p.Status != StatusPublished {
+		return ErrInvalidTransition
+	}
+	p.Status = StatusArchived
+	p.ArchivedAt = &now
+	p.UpdatedAt = now
+	return nil
+}
+
+// CanSend returns an error if the post must not be sent to subscribers.
+func (p *Post) CanSend() error {
+	if p.Status != StatusPublished {
+		return ErrPostNotSendable
+	}
+	if p.SentAt != nil {
+		return ErrPostAlreadySent
+	}
+	return nil
+}
+
+// PostService is an interface that contains a collection of method signatures
+// which will be implemented in application level and are responsible for
+// writing posts and moving them through their lifecycle.
+type PostService interface {
+	Create(post *Post) (*Post, error)
+	Get(newsletterID, id uuid.UUID) (*Post, error)
+	List(newsletterID uuid.UUID, status string) ([]*Post, error)
+	Update(post *Post) (*Post, error)
+	// Prepare sanitizes and validates post as Create does, without storing
+	// it, so that unsaved content can be previewed.
+	Prepare(post *Post) error
+	Publish(newsletterID, id uuid.UUID) (*Post, error)
+	Archive(newsletterID, id uuid.UUID) (*Post, error)
+	// MarkSent records that a published post is being sent. It fails with
+	// ErrPostNotSendable or ErrPostAlreadySent, so that a post is sent once,
+	// and with ErrUnknownMergeTag if its merge tags cannot be expanded.
+	MarkSent(newsletterID, id uuid.UUID) (*Post, error)
+}
+
+// PostRepository is an interface that contains a collection of method signatures
+// which will be implemented in persistence level.
+type PostRepository interface {
+	Create(ctx context.Context, post *Post) (*Post, error)
+	Get(ctx context.Context, newsletterID, id uuid.UUID) (*Post, error)
+	List(ctx context.Context, newsletterID uuid.UUID, status string) ([]*Post, error)
+	// UpdateContent replaces the title and body of a draft. It returns
+	// ErrPostNotEditable if the post is no longer a draft.
+	UpdateContent(ctx context.Context, post *Post) (*Post, error)
+	// UpdateStatus persists a status transition of post, provided the stored
+	// status is still from. It returns ErrInvalidTransition otherwise.
+	UpdateStatus(ctx context.Context, post *Post, from string) error
+	// MarkSent sets the sent time of a published, unsent post. It returns
+	// ErrPostNotSendable if the post was changed concurrently.
+	MarkSent(ctx context.Context, newsletterID, id uuid.UUID, sentAt time.Time) (*Post, error)
+}
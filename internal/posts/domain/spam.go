@@ -0,0 +1,30 @@
+package domain
+
+import apperrors "newsletter/internal/errors"
+
+// ErrSpammyContent is returned when sending a post whose email scores at or
+// above the spam threshold, unless the send is forced.
+var ErrSpammyContent = apperrors.New(apperrors.Validation, "content looks like spam")
+
+// SpamRule is a rule of a content check that matched an email.
+type SpamRule struct {
+	Name        string  `json:"name"`        // Identifier of the rule, such as "IMAGE_RATIO"
+	Score       float64 `json:"score"`       // Points added to the score of the email
+	Description string  `json:"description"` // What the rule found
+}
+
+// SpamReport is the outcome of checking the content of an email before it
+// is sent. Like the scores of SpamAssassin, higher scores look more like
+// spam.
+type SpamReport struct {
+	Score     float64    `json:"score"`     // Sum of the scores of the rules that matched
+	Threshold float64    `json:"threshold"` // Score from which sending is refused
+	Passed    bool       `json:"passed"`    // Whether the score is below the threshold
+	Rules     []SpamRule `json:"rules"`     // Rules that matched, by decreasing score
+}
+
+// ContentChecker scores the content of emails before they are sent.
+type ContentChecker interface {
+	// Check scores an email with the given subject and HTML and text parts.
+	Check(subject, html, text string) *SpamReport
+}
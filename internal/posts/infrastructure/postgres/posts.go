@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/posts/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type PostRepository struct {
+	db database.DB
+}
+
+func NewPostRepository(db database.DB) *PostRepository {
+	return &PostRepository{db: db}
+}
+
+// postColumns lists the columns scanned by scanPost, in order.
+const postColumns = `id, newsletter_id, title, body, status, version, published_at, archived_at, sent_at, created_at, updated_at`
+
+// scanner is implemented by both pgx.Row and pgx.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPost scans a row selected with postColumns into a domain.Post.
+func scanPost(row scanner) (*domain.Post, error) {
+	var post domain.Post
+
+	err := row.Scan(
+		&post.ID,
+		&post.NewsletterID,
+		&post.Title,
+		&post.Body,
+		&post.Status,
+		&post.Version,
+		&post.PublishedAt,
+		&post.ArchivedAt,
+		&post.SentAt,
+		&post.CreatedAt,
+		&post.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &post, nil
+}
+
+// Create inserts a new post.
+func (pr *PostRepository) Create(ctx context.Context, post *domain.Post) (*domain.Post, error) {
+	now := time.Now()
+	query := `insert into posts (newsletter_id, title, body, status, created_at, updated_at) values ($1, $2, $3, $4, $5, $5) returning ` + postColumns
+
+	return scanPost(pr.db.QueryRow(ctx, query, post.NewsletterID, post.Title, post.Body, post.Status, now))
+}
+
+// Get retrieves a post of a newsletter.
+//
+// If no such post exists, Get returns domain.ErrPostNotFound.
+func (pr *PostRepository) Get(ctx context.Context, newsletterID, id uuid.UUID) (*domain.Post, error) {
+	query := `select ` + postColumns + ` from posts where id = $1 and newsletter_id = $2`
+
+	post, err := scanPost(pr.db.QueryRow(ctx, query, id, newsletterID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrPostNotFound
+	}
+
+	return post, err
+}
+
+// List retrieves the posts of a newsletter, newest first. An empty status
+// returns posts of every status.
+func (pr *PostRepository) List(ctx context.Context, newsletterID uuid.UUID, status string) ([]*domain.Post, error) {
+	query := `select ` + postColumns + ` from posts where newsletter_id = $1 and ($2 = '' or status = $2) order by created_at desc`
+
+	rows, err := pr.db.Query(ctx, query, newsletterID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []*domain.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// UpdateContent replaces the title and body of a draft and bumps its version.
+//
+// It returns domain.ErrPostNotEditable if the post is no longer a draft,
+// and domain.ErrPostNotFound if it does not exist.
+func (pr *PostRepository) UpdateContent(ctx context.Context, post *domain.Post) (*domain.Post, error) {
+	query := `update posts
+		set title = $1, body = $2, version = version + 1, updated_at = $3
+		where id = $4 and newsletter_id = $5 and status = $6
+		returning ` + postColumns
+
+	updated, err := scanPost(pr.db.QueryRow(ctx, query, post.Title, post.Body, time.Now(), post.ID, post.NewsletterID, domain.StatusDraft))
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := pr.Get(ctx, post.NewsletterID, post.ID); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrPostNotEditable
+	}
+
+	return updated, err
+}
+
+// UpdateStatus persists the lifecycle fields of post, provided its stored
+// status is still from. It returns domain.ErrInvalidTransition otherwise.
+func (pr *PostRepository) UpdateStatus(ctx context.Context, post *domain.Post, from string) error {
+	query := `update posts
+		set status = $1, published_at = $2, archived_at = $3, updated_at = $4
+		where id = $5 and newsletter_id = $6 and status = $7`
+
+	result, err := pr.db.Exec(ctx, query, post.Status, post.PublishedAt, post.ArchivedAt, post.UpdatedAt, post.ID, post.NewsletterID, from)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrInvalidTransition
+	}
+
+	return nil
+}
+
+// MarkSent sets the sent time of a published post that has not been sent yet.
+//
+// The check and the update happen in one statement, so concurrent sends of
+// the same post cannot both succeed; the loser gets domain.ErrPostNotSendable.
+func (pr *PostRepository) MarkSent(ctx context.Context, newsletterID, id uuid.UUID, sentAt time.Time) (*domain.Post, error) {
+	query := `update posts
+		set sent_at = $1, updated_at = $1
+		where id = $2 and newsletter_id = $3 and status = $4 and sent_at is null
+		returning ` + postColumns
+
+	post, err := scanPost(pr.db.QueryRow(ctx, query, sentAt, id, newsletterID, domain.StatusPublished))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrPostNotSendable
+	}
+
+	return post, err
+}
@@ -0,0 +1,116 @@
+package postgres_test
+
+import (
+	"context"
+	"newsletter/internal/infrastructure/fixtures"
+	"newsletter/internal/posts/domain"
+	"newsletter/internal/posts/infrastructure/postgres"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMock returns a mocked database whose expectations are checked at the
+// end of the test.
+func newMock(t *testing.T) pgxmock.PgxPoolIface {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		mock.Close()
+	})
+	return mock
+}
+
+// postRows returns the rows selected with the post columns.
+func postRows(posts ...*domain.Post) *pgxmock.Rows {
+	rows := pgxmock.NewRows([]string{
+		"id", "newsletter_id", "title", "body", "status", "version", "published_at", "archived_at", "sent_at", "created_at", "updated_at",
+	})
+	for _, p := range posts {
+		rows.AddRow(p.ID, p.NewsletterID, p.Title, p.Body, p.Status, p.Version, p.PublishedAt, p.ArchivedAt, p.SentAt, p.CreatedAt, p.UpdatedAt)
+	}
+	return rows
+}
+
+func TestPostRepository_List(t *testing.T) {
+	mock := newMock(t)
+	newsletter := fixtures.Newsletter(fixtures.User(1), 1)
+	older, newer := fixtures.Post(newsletter, 1), fixtures.Post(newsletter, 2)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`from posts where newsletter_id = $1 and ($2 = '' or status = $2) order by created_at desc`)).
+		WithArgs(newsletter.ID, domain.StatusDraft).
+		WillReturnRows(postRows(newer, older))
+
+	posts, err := postgres.NewPostRepository(mock).List(context.Background(), newsletter.ID, domain.StatusDraft)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*domain.Post{newer, older}, posts)
+}
+
+func TestPostRepository_Get_NotFound(t *testing.T) {
+	mock := newMock(t)
+	newsletter := fixtures.Newsletter(fixtures.User(1), 1)
+	id := fixtures.ID("post", 404)
+
+	mock.ExpectQuery(`from posts where id = \$1 and newsletter_id = \$2`).
+		WithArgs(id, newsletter.ID).
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err := postgres.NewPostRepository(mock).Get(context.Background(), newsletter.ID, id)
+
+	assert.ErrorIs(t, err, domain.ErrPostNotFound)
+}
+
+func TestPostRepository_UpdateContent_NotEditable(t *testing.T) {
+	mock := newMock(t)
+	post := fixtures.Post(fixtures.Newsletter(fixtures.User(1), 1), 1)
+	published := *post
+	published.Status = domain.StatusPublished
+
+	// The update matches no draft, and the post is found: it was published.
+	mock.ExpectQuery(`update posts`).
+		WithArgs(post.Title, post.Body, pgxmock.AnyArg(), post.ID, post.NewsletterID, domain.StatusDraft).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectQuery(`from posts where id = \$1`).
+		WithArgs(post.ID, post.NewsletterID).
+		WillReturnRows(postRows(&published))
+
+	_, err := postgres.NewPostRepository(mock).UpdateContent(context.Background(), post)
+
+	assert.ErrorIs(t, err, domain.ErrPostNotEditable)
+}
+
+func TestPostRepository_UpdateStatus_InvalidTransition(t *testing.T) {
+	mock := newMock(t)
+	post := fixtures.Post(fixtures.Newsletter(fixtures.User(1), 1), 1)
+	post.Status = domain.StatusPublished
+
+	mock.ExpectExec(`update posts`).
+		WithArgs(post.Status, post.PublishedAt, post.ArchivedAt, post.UpdatedAt, post.ID, post.NewsletterID, domain.StatusDraft).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	err := postgres.NewPostRepository(mock).UpdateStatus(context.Background(), post, domain.StatusDraft)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidTransition)
+}
+
+func TestPostRepository_MarkSent_AlreadySent(t *testing.T) {
+	mock := newMock(t)
+	post := fixtures.Post(fixtures.Newsletter(fixtures.User(1), 1), 1)
+	sentAt := fixtures.Epoch.Add(time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`where id = $2 and newsletter_id = $3 and status = $4 and sent_at is null`)).
+		WithArgs(sentAt, post.ID, post.NewsletterID, domain.StatusPublished).
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err := postgres.NewPostRepository(mock).MarkSent(context.Background(), post.NewsletterID, post.ID, sentAt)
+
+	assert.ErrorIs(t, err, domain.ErrPostNotSendable)
+}
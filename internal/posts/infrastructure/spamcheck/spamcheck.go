@@ -0,0 +1,168 @@
+// Package spamcheck scores the content of emails with heuristic rules in the
+// spirit of SpamAssassin, so that authors learn what could send their posts
+// to the spam folder before subscribers receive them.
+package spamcheck
+
+import (
+	"cmp"
+	"fmt"
+	"html"
+	"net/url"
+	"newsletter/internal/infrastructure/sanitize"
+	"newsletter/internal/posts/domain"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultThreshold is the score from which sending is refused, as the
+// default required score of SpamAssassin.
+const DefaultThreshold = 5.0
+
+// Scores of the rules. Rules matching several times, such as spammy
+// phrases, add up to a cap.
+const (
+	noTextScore         = 2.5
+	missingTextScore    = 1.5
+	imageRatioScore     = 2.0
+	spammyPhraseScore   = 0.5
+	maxSpammyScore      = 2.5
+	capsSubjectScore    = 1.5
+	exclamationScore    = 1.0
+	brokenLinkScore     = 1.0
+	maxBrokenLinksScore = 3.0
+)
+
+// textPerImage is the number of characters of text expected for each image.
+const textPerImage = 200
+
+// minCapsLetters is the number of letters a subject needs before its case is
+// judged, so that short subjects such as "FAQ" are not flagged.
+const minCapsLetters = 10
+
+// spammyPhrases are phrases common in spam, matched case-insensitively.
+var spammyPhrases = []string{
+	"100% free", "act now", "apply now", "buy now", "cash bonus", "click here",
+	"double your", "earn money", "free money", "guaranteed", "limited time",
+	"make money", "no credit check", "order now", "risk-free", "special promotion",
+	"this is not spam", "urgent", "winner", "you have been selected", "$$$",
+}
+
+var (
+	imageTag = regexp.MustCompile(`(?i)<img\b`)
+	linkHref = regexp.MustCompile(`(?i)<a\b[^>]*?\bhref="([^"]*)"`)
+)
+
+// Checker scores emails against heuristic rules. It implements
+// domain.ContentChecker.
+type Checker struct {
+	threshold float64
+}
+
+// NewChecker creates a Checker refusing emails scoring threshold or more.
+func NewChecker(threshold float64) *Checker {
+	return &Checker{threshold: threshold}
+}
+
+// Check scores an email with the given subject and HTML and text parts:
+//   - NO_TEXT: the HTML part has no text, only images or links
+//   - MISSING_TEXT_PART: the email has no plain text part
+//   - IMAGE_RATIO: the HTML part has little text for its images
+//   - SPAMMY_WORDS: phrases common in spam, such as "act now"
+//   - SUBJECT_ALL_CAPS: the subject is mostly in capital letters
+//   - EXCESSIVE_EXCLAMATION: repeated exclamation marks
+//   - BROKEN_LINKS: links without a valid http, https or mailto address
+//
+// Links are checked for their form only; they are not requested.
+func (c *Checker) Check(subject, body, text string) *domain.SpamReport {
+	var rules []domain.SpamRule
+	match := func(name string, score float64, format string, args ...any) {
+		rules = append(rules, domain.SpamRule{Name: name, Score: score, Description: fmt.Sprintf(format, args...)})
+	}
+
+	content := sanitize.Text(body)
+	length := len([]rune(content))
+	if length == 0 {
+		match("NO_TEXT", noTextScore, "the email has no text besides its images and links")
+	}
+	if strings.TrimSpace(text) == "" {
+		match("MISSING_TEXT_PART", missingTextScore, "the email has no plain text version")
+	}
+	if images := len(imageTag.FindAllString(body, -1)); images > 0 && length < images*textPerImage {
+		match("IMAGE_RATIO", imageRatioScore, "%d images for %d characters of text", images, length)
+	}
+
+	lower := strings.ToLower(subject + "\n" + content)
+	var phrases []string
+	for _, phrase := range spammyPhrases {
+		if strings.Contains(lower, phrase) {
+			phrases = append(phrases, phrase)
+		}
+	}
+	if len(phrases) > 0 {
+		match("SPAMMY_WORDS", min(maxSpammyScore, float64(len(phrases))*spammyPhraseScore), "phrases common in spam: %s", strings.Join(phrases, ", "))
+	}
+
+	if mostlyCapitals(subject) {
+		match("SUBJECT_ALL_CAPS", capsSubjectScore, "the subject is mostly in capital letters")
+	}
+	if strings.Contains(subject, "!!") || strings.Contains(content, "!!!") {
+		match("EXCESSIVE_EXCLAMATION", exclamationScore, "repeated exclamation marks")
+	}
+
+	var broken []string
+	for _, href := range linkHref.FindAllStringSubmatch(body, -1) {
+		if link := html.UnescapeString(href[1]); !validLink(link) {
+			broken = append(broken, strconv.Quote(link))
+		}
+	}
+	if len(broken) > 0 {
+		match("BROKEN_LINKS", min(maxBrokenLinksScore, float64(len(broken))*brokenLinkScore), "links without a valid address: %s", strings.Join(broken, ", "))
+	}
+
+	slices.SortStableFunc(rules, func(a, b domain.SpamRule) int { return cmp.Compare(b.Score, a.Score) })
+
+	report := &domain.SpamReport{Threshold: c.threshold, Rules: rules}
+	for _, rule := range rules {
+		report.Score += rule.Score
+	}
+	report.Passed = report.Score < c.threshold
+	if report.Rules == nil {
+		report.Rules = []domain.SpamRule{}
+	}
+	return report
+}
+
+// mostlyCapitals reports whether at least three quarters of the letters of
+// s, which has at least minCapsLetters of them, are capital letters.
+func mostlyCapitals(s string) bool {
+	letters, upper := 0, 0
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= minCapsLetters && upper*4 >= letters*3
+}
+
+// validLink reports whether link is an absolute http or https URL with a
+// host, or a mailto URL with an address.
+func validLink(link string) bool {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	default:
+		return false
+	}
+}
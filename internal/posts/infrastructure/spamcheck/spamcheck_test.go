@@ -0,0 +1,75 @@
+package spamcheck_test
+
+import (
+	"newsletter/internal/posts/domain"
+	"newsletter/internal/posts/infrastructure/spamcheck"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ruleNames(report *domain.SpamReport) []string {
+	names := make([]string, 0, len(report.Rules))
+	for _, rule := range report.Rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+func TestCheck_Clean(t *testing.T) {
+	body := `<p>This week we look back at the release of version 2 and what comes next.</p>
+<p>Read the <a href="https://example.com/blog/v2">announcement</a> or <a href="mailto:editor@example.com">write to us</a>.</p>`
+
+	report := spamcheck.NewChecker(spamcheck.DefaultThreshold).Check("Issue #12: version 2 is out", body, "This week we look back...")
+
+	assert.True(t, report.Passed)
+	assert.Zero(t, report.Score)
+	assert.Equal(t, spamcheck.DefaultThreshold, report.Threshold)
+	assert.NotNil(t, report.Rules)
+	assert.Empty(t, report.Rules)
+}
+
+func TestCheck_Spammy(t *testing.T) {
+	body := `<img src="https://example.com/a.png"><img src="https://example.com/b.png">
+<p>You are a WINNER!!! Act now and click here, this is not spam.</p>`
+
+	report := spamcheck.NewChecker(spamcheck.DefaultThreshold).Check("FREE MONEY FOR YOU TODAY!!", body, "")
+
+	assert.False(t, report.Passed)
+	assert.GreaterOrEqual(t, report.Score, spamcheck.DefaultThreshold)
+	assert.ElementsMatch(t, []string{"SPAMMY_WORDS", "IMAGE_RATIO", "MISSING_TEXT_PART", "SUBJECT_ALL_CAPS", "EXCESSIVE_EXCLAMATION"}, ruleNames(report))
+	for i := 1; i < len(report.Rules); i++ {
+		assert.GreaterOrEqual(t, report.Rules[i-1].Score, report.Rules[i].Score, "rules by decreasing score")
+	}
+}
+
+func TestCheck_ImagesOnly(t *testing.T) {
+	report := spamcheck.NewChecker(spamcheck.DefaultThreshold).Check("Our catalog", `<a href="https://example.com"><img src="https://example.com/catalog.png"></a>`, "")
+
+	assert.Equal(t, []string{"NO_TEXT", "IMAGE_RATIO", "MISSING_TEXT_PART"}, ruleNames(report))
+	assert.Equal(t, 6.0, report.Score)
+	assert.False(t, report.Passed)
+}
+
+func TestCheck_BrokenLinks(t *testing.T) {
+	body := `<p>` + strings.Repeat("Plenty of text about the topic of the week. ", 10) + `</p>
+<a href="">empty</a> <a href="javascript:alert(1)">script</a> <a href="/relative">relative</a>
+<a href="http://">no host</a> <a href="https://example.com/ok?a=1&amp;b=2">fine</a>`
+
+	report := spamcheck.NewChecker(spamcheck.DefaultThreshold).Check("Links", body, "text")
+
+	require.Len(t, report.Rules, 1)
+	assert.Equal(t, "BROKEN_LINKS", report.Rules[0].Name)
+	assert.Equal(t, 3.0, report.Rules[0].Score, "capped")
+	assert.Contains(t, report.Rules[0].Description, `"javascript:alert(1)"`)
+	assert.NotContains(t, report.Rules[0].Description, "example.com/ok")
+	assert.True(t, report.Passed)
+}
+
+func TestCheck_ShortCapitalSubject(t *testing.T) {
+	report := spamcheck.NewChecker(spamcheck.DefaultThreshold).Check("FAQ", "<p>Answers to your questions.</p>", "Answers")
+
+	assert.Empty(t, report.Rules)
+}
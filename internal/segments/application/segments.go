@@ -0,0 +1,156 @@
+package application
+
+import (
+	"context"
+	"log/slog"
+	"newsletter/internal/segments/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SegmentService provides application-level operations related to segments
+// and it orchestrates domain logic and persistence concerns.
+type SegmentService struct {
+	sr domain.SegmentRepository
+	sl domain.SubscriberLister
+	er domain.EngagementReader
+}
+
+func NewSegmentService(sr domain.SegmentRepository, sl domain.SubscriberLister, er domain.EngagementReader) *SegmentService {
+	return &SegmentService{sr: sr, sl: sl, er: er}
+}
+
+// Create saves a new segment after validating its filter.
+func (ss *SegmentService) Create(segment *domain.Segment) (*domain.Segment, error) {
+	if err := segment.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	created, err := ss.sr.Create(ctx, segment)
+	if err != nil {
+		slog.Error("failed to create segment", "newsletter_id", segment.NewsletterID, "error", err)
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// Get returns a segment of a newsletter.
+func (ss *SegmentService) Get(newsletterID, id uuid.UUID) (*domain.Segment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	return ss.sr.Get(ctx, newsletterID, id)
+}
+
+// List returns the segments of a newsletter, by name.
+func (ss *SegmentService) List(newsletterID uuid.UUID) ([]*domain.Segment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	segments, err := ss.sr.List(ctx, newsletterID)
+	if err != nil {
+		slog.Error("failed to list segments", "newsletter_id", newsletterID, "error", err)
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+// Update replaces the name and filter of a segment, validated as by Create.
+// Campaigns sent to the segment and not dispatched yet use the new filter.
+func (ss *SegmentService) Update(segment *domain.Segment) (*domain.Segment, error) {
+	if err := segment.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	updated, err := ss.sr.Update(ctx, segment)
+	if err != nil {
+		slog.Warn("failed to update segment", "segment_id", segment.ID, "error", err)
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// Delete removes a segment that no unfinished campaign is sent to.
+func (ss *SegmentService) Delete(newsletterID, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := ss.sr.Delete(ctx, newsletterID, id); err != nil {
+		slog.Warn("failed to delete segment", "segment_id", id, "error", err)
+		return err
+	}
+
+	slog.Info("segment deleted", "newsletter_id", newsletterID, "segment_id", id)
+	return nil
+}
+
+// Count resolves filter and counts the active subscribers it selects,
+// going through every active subscriber of the newsletter. It previews the
+// recipients of a campaign sent to the segment now.
+func (ss *SegmentService) Count(newsletterID uuid.UUID, filter domain.Filter) (int, error) {
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	audience, err := ss.audience(ctx, newsletterID, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = ss.sl.GetAllForSend(ctx, newsletterID, func(subscription *subscriptiondomain.Subscription) error {
+		if audience.Includes(subscription) {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("failed to list subscribers", "newsletter_id", newsletterID, "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// Resolve loads a segment with the engagement of the subscribers of the
+// newsletter, if its filter needs it.
+func (ss *SegmentService) Resolve(newsletterID, id uuid.UUID) (*domain.Audience, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	segment, err := ss.sr.Get(ctx, newsletterID, id)
+	if err != nil {
+		return nil, err
+	}
+	return ss.audience(ctx, newsletterID, segment.Filter)
+}
+
+// audience resolves filter, reading the engagement of the subscribers only
+// when the filter has an engagement condition.
+func (ss *SegmentService) audience(ctx context.Context, newsletterID uuid.UUID, filter domain.Filter) (*domain.Audience, error) {
+	audience := &domain.Audience{Filter: filter}
+	if filter.Engagement == "" {
+		return audience, nil
+	}
+
+	engagement, err := ss.er.Engagement(ctx, newsletterID, time.Now().Add(-domain.EngagementWindow))
+	if err != nil {
+		slog.Error("failed to read subscriber engagement", "newsletter_id", newsletterID, "error", err)
+		return nil, err
+	}
+	audience.Engagement = engagement
+	return audience, nil
+}
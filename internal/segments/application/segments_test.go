@@ -0,0 +1,207 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/segments/application"
+	"newsletter/internal/segments/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Segment Repository ---
+type MockSegmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockSegmentRepository) Create(ctx context.Context, segment *domain.Segment) (*domain.Segment, error) {
+	args := m.Called(segment)
+	s := args.Get(0)
+	if s == nil {
+		return nil, args.Error(1)
+	}
+	return s.(*domain.Segment), args.Error(1)
+}
+
+func (m *MockSegmentRepository) Get(ctx context.Context, newsletterID, id uuid.UUID) (*domain.Segment, error) {
+	args := m.Called(newsletterID, id)
+	s := args.Get(0)
+	if s == nil {
+		return nil, args.Error(1)
+	}
+	return s.(*domain.Segment), args.Error(1)
+}
+
+func (m *MockSegmentRepository) List(ctx context.Context, newsletterID uuid.UUID) ([]*domain.Segment, error) {
+	args := m.Called(newsletterID)
+	return args.Get(0).([]*domain.Segment), args.Error(1)
+}
+
+func (m *MockSegmentRepository) Update(ctx context.Context, segment *domain.Segment) (*domain.Segment, error) {
+	args := m.Called(segment)
+	s := args.Get(0)
+	if s == nil {
+		return nil, args.Error(1)
+	}
+	return s.(*domain.Segment), args.Error(1)
+}
+
+func (m *MockSegmentRepository) Delete(ctx context.Context, newsletterID, id uuid.UUID) error {
+	return m.Called(newsletterID, id).Error(0)
+}
+
+// --- Mock Subscriber Lister ---
+type MockSubscriberLister struct {
+	mock.Mock
+}
+
+// GetAllForSend calls fn with the subscriptions the mock returns.
+func (m *MockSubscriberLister) GetAllForSend(ctx context.Context, newsletterID uuid.UUID, fn func(*subscriptiondomain.Subscription) error) error {
+	args := m.Called(newsletterID)
+	subscriptions, _ := args.Get(0).([]*subscriptiondomain.Subscription)
+	for _, subscription := range subscriptions {
+		if err := fn(subscription); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+// --- Mock Engagement Reader ---
+type MockEngagementReader struct {
+	mock.Mock
+}
+
+func (m *MockEngagementReader) Engagement(ctx context.Context, newsletterID uuid.UUID, since time.Time) (map[string]domain.Engagement, error) {
+	args := m.Called(newsletterID, since)
+	engagement := args.Get(0)
+	if engagement == nil {
+		return nil, args.Error(1)
+	}
+	return engagement.(map[string]domain.Engagement), args.Error(1)
+}
+
+// --- Tests ---
+
+func TestCreateSegment_Invalid(t *testing.T) {
+	after := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		segment domain.Segment
+	}{
+		{"missing name", domain.Segment{Filter: domain.Filter{Tags: []string{"beta"}}}},
+		{"no condition", domain.Segment{Name: "Everyone"}},
+		{"invalid attribute key", domain.Segment{Name: "Berlin", Filter: domain.Filter{Attributes: map[string]string{"City": "Berlin"}}}},
+		{"empty tag", domain.Segment{Name: "Tagged", Filter: domain.Filter{Tags: []string{""}}}},
+		{"empty date range", domain.Segment{Name: "January", Filter: domain.Filter{SubscribedAfter: &after, SubscribedBefore: &before}}},
+		{"unknown engagement", domain.Segment{Name: "Fans", Filter: domain.Filter{Engagement: "fans"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockSegmentRepository)
+			ss := application.NewSegmentService(repo, nil, nil)
+
+			_, err := ss.Create(&tt.segment)
+
+			assert.ErrorIs(t, err, domain.ErrInvalidSegment)
+			repo.AssertNotCalled(t, "Create", mock.Anything)
+		})
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	joined := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	subscription := &subscriptiondomain.Subscription{
+		Email:      "reader@example.com",
+		CreatedAt:  joined,
+		Tags:       []string{"beta", "vip"},
+		Attributes: map[string]string{"city": "Berlin"},
+	}
+	engaged := domain.Engagement{Received: 3, Opened: 1}
+
+	tests := []struct {
+		name   string
+		filter domain.Filter
+		want   bool
+	}{
+		{"attribute equals", domain.Filter{Attributes: map[string]string{"city": "Berlin"}}, true},
+		{"attribute differs", domain.Filter{Attributes: map[string]string{"city": "Paris"}}, false},
+		{"attribute missing", domain.Filter{Attributes: map[string]string{"plan": ""}}, false},
+		{"all tags", domain.Filter{Tags: []string{"vip", "beta"}}, true},
+		{"missing tag", domain.Filter{Tags: []string{"beta", "press"}}, false},
+		{"subscribed after", domain.Filter{SubscribedAfter: &joined}, true},
+		{"subscribed before", domain.Filter{SubscribedBefore: &joined}, false},
+		{"engaged", domain.Filter{Engagement: domain.EngagementEngaged}, true},
+		{"unengaged", domain.Filter{Engagement: domain.EngagementUnengaged}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Matches(subscription, engaged))
+		})
+	}
+
+	assert.Equal(t, domain.EngagementUnengaged, domain.Engagement{Received: 2}.Level())
+	assert.Equal(t, domain.EngagementNew, domain.Engagement{}.Level())
+}
+
+func TestCount_GoesThroughSubscribers(t *testing.T) {
+	newsletterID := uuid.New()
+	now := time.Now()
+	lister := new(MockSubscriberLister)
+	engagement := new(MockEngagementReader)
+	ss := application.NewSegmentService(new(MockSegmentRepository), lister, engagement)
+
+	engagement.On("Engagement", newsletterID, mock.MatchedBy(func(since time.Time) bool {
+		return since.Before(now.Add(-domain.EngagementWindow).Add(time.Minute))
+	})).Return(map[string]domain.Engagement{
+		"opened@example.com":   {Received: 2, Opened: 1},
+		"received@example.com": {Received: 2},
+	}, nil)
+
+	lister.On("GetAllForSend", newsletterID).Return([]*subscriptiondomain.Subscription{
+		{Email: "opened@example.com"},
+		{Email: "received@example.com"},
+		{Email: "new@example.com"},
+		{Email: "left@example.com", Status: subscriptiondomain.StatusUnsubscribed},
+	}, nil)
+
+	count, err := ss.Count(newsletterID, domain.Filter{Engagement: domain.EngagementNew})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "only new@example.com is active and never received an email")
+	lister.AssertExpectations(t)
+}
+
+func TestResolve(t *testing.T) {
+	newsletterID, id := uuid.New(), uuid.New()
+	repo := new(MockSegmentRepository)
+	engagement := new(MockEngagementReader)
+	ss := application.NewSegmentService(repo, nil, engagement)
+
+	repo.On("Get", newsletterID, id).Return(&domain.Segment{ID: id, Filter: domain.Filter{Tags: []string{"beta"}}}, nil).Once()
+
+	audience, err := ss.Resolve(newsletterID, id)
+
+	require.NoError(t, err)
+	assert.Nil(t, audience.Engagement, "engagement is only read for engagement conditions")
+	assert.True(t, audience.Includes(&subscriptiondomain.Subscription{Tags: []string{"beta"}}))
+	assert.False(t, audience.Includes(&subscriptiondomain.Subscription{Tags: []string{"beta"}, Status: subscriptiondomain.StatusUnsubscribed}))
+	engagement.AssertNotCalled(t, "Engagement", mock.Anything, mock.Anything)
+
+	repo.On("Get", newsletterID, id).Return(&domain.Segment{ID: id, Filter: domain.Filter{Engagement: domain.EngagementEngaged}}, nil)
+	engagement.On("Engagement", newsletterID, mock.Anything).Return(nil, errors.New("connection refused"))
+
+	_, err = ss.Resolve(newsletterID, id)
+
+	assert.Error(t, err)
+}
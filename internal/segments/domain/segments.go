@@ -0,0 +1,203 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	apperrors "newsletter/internal/errors"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrSegmentNotFound is returned when a segment does not exist in the newsletter.
+	ErrSegmentNotFound = apperrors.New(apperrors.NotFound, "segment not found")
+	// ErrInvalidSegment is returned when a segment has no name or an invalid filter.
+	ErrInvalidSegment = apperrors.New(apperrors.Validation, "invalid segment")
+	// ErrSegmentInUse is returned when deleting a segment an unfinished campaign is sent to.
+	ErrSegmentInUse = apperrors.New(apperrors.Conflict, "segment used by an unfinished campaign")
+)
+
+// MaxNameLength is the maximum number of characters of the name of a segment.
+const MaxNameLength = 100
+
+// Engagement levels of subscribers, from the campaigns of the newsletter
+// sent to them during the last EngagementWindow. Opens are only known for
+// tracked deliveries, that is the samples of A/B tests, so subscribers who
+// never received a tracked email count as unengaged.
+const (
+	EngagementEngaged   = "engaged"   // Opened at least one email
+	EngagementUnengaged = "unengaged" // Received emails, but opened none
+	EngagementNew       = "new"       // Received no email
+)
+
+// EngagementWindow is how far back the emails counted in engagement levels go.
+const EngagementWindow = 90 * 24 * time.Hour
+
+// Engagement counts the campaign emails of a newsletter a subscriber received
+// and opened during the last EngagementWindow.
+type Engagement struct {
+	Received int
+	Opened   int
+}
+
+// Level returns the engagement level of the subscriber.
+func (e Engagement) Level() string {
+	switch {
+	case e.Opened > 0:
+		return EngagementEngaged
+	case e.Received > 0:
+		return EngagementUnengaged
+	default:
+		return EngagementNew
+	}
+}
+
+// Filter selects the subscribers of a segment. A subscriber must meet every
+// condition set; conditions left empty select everyone.
+type Filter struct {
+	Attributes       map[string]string `json:"attributes,omitempty"`        // Custom attributes the subscriber must have, with these values
+	Tags             []string          `json:"tags,omitempty"`              // Tags the subscriber must all carry
+	SubscribedAfter  *time.Time        `json:"subscribed_after,omitempty"`  // Only subscriptions created at or after this time
+	SubscribedBefore *time.Time        `json:"subscribed_before,omitempty"` // Only subscriptions created before this time
+	Engagement       string            `json:"engagement,omitempty"`        // Engagement level of the subscriber, see EngagementEngaged
+}
+
+// IsEmpty reports whether the filter has no condition.
+func (f Filter) IsEmpty() bool {
+	return len(f.Attributes) == 0 && len(f.Tags) == 0 && f.SubscribedAfter == nil && f.SubscribedBefore == nil && f.Engagement == ""
+}
+
+// Validate checks the conditions of the filter, returning an error wrapping
+// ErrInvalidSegment.
+func (f Filter) Validate() error {
+	if f.IsEmpty() {
+		return fmt.Errorf("%w: the filter needs at least one condition", ErrInvalidSegment)
+	}
+	for key := range f.Attributes {
+		if !subscriptiondomain.ValidAttributeKey(key) {
+			return fmt.Errorf("%w: invalid attribute key %q", ErrInvalidSegment, key)
+		}
+	}
+	for _, tag := range f.Tags {
+		if tag == "" {
+			return fmt.Errorf("%w: tags cannot be empty", ErrInvalidSegment)
+		}
+	}
+	if f.SubscribedAfter != nil && f.SubscribedBefore != nil && !f.SubscribedAfter.Before(*f.SubscribedBefore) {
+		return fmt.Errorf("%w: subscribed_after must be before subscribed_before", ErrInvalidSegment)
+	}
+	switch f.Engagement {
+	case "", EngagementEngaged, EngagementUnengaged, EngagementNew:
+	default:
+		return fmt.Errorf("%w: engagement must be %q, %q or %q", ErrInvalidSegment, EngagementEngaged, EngagementUnengaged, EngagementNew)
+	}
+	return nil
+}
+
+// Matches reports whether a subscriber with the given engagement meets the
+// conditions of the filter.
+func (f Filter) Matches(subscription *subscriptiondomain.Subscription, engagement Engagement) bool {
+	for key, value := range f.Attributes {
+		if actual, ok := subscription.Attributes[key]; !ok || actual != value {
+			return false
+		}
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(subscription.Tags, tag) {
+			return false
+		}
+	}
+	if f.SubscribedAfter != nil && subscription.CreatedAt.Before(*f.SubscribedAfter) {
+		return false
+	}
+	if f.SubscribedBefore != nil && !subscription.CreatedAt.Before(*f.SubscribedBefore) {
+		return false
+	}
+	return f.Engagement == "" || engagement.Level() == f.Engagement
+}
+
+// Segment is a named filter over the subscribers of a newsletter. Campaigns
+// sent to a segment resolve it when they are dispatched, so subscribers
+// joining or leaving it before then are taken into account.
+type Segment struct {
+	ID           uuid.UUID `json:"id"`            // ID of the segment
+	NewsletterID uuid.UUID `json:"newsletter_id"` // Newsletter whose subscribers are filtered
+	Name         string    `json:"name"`          // Name shown to the owner
+	Filter       Filter    `json:"filter"`        // Conditions subscribers of the segment meet
+	CreatedAt    time.Time `json:"created_at"`    // Creation time of the segment
+	UpdatedAt    time.Time `json:"updated_at"`    // Time of the last change
+}
+
+// Validate checks that the segment has a name and a valid filter.
+func (s *Segment) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSegment)
+	}
+	if utf8.RuneCountInString(s.Name) > MaxNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidSegment, MaxNameLength)
+	}
+	return s.Filter.Validate()
+}
+
+// Audience is a filter resolved at a point in time: it holds the engagement
+// of the subscribers when the filter needs it.
+type Audience struct {
+	Filter     Filter
+	Engagement map[string]Engagement // By email address; nil without an engagement condition
+}
+
+// Includes reports whether the subscription is active and meets the filter.
+func (a *Audience) Includes(subscription *subscriptiondomain.Subscription) bool {
+	return subscription.IsActive() && a.Filter.Matches(subscription, a.Engagement[subscription.Email])
+}
+
+// SegmentService is an interface that contains a collection of method signatures
+// which will be implemented in application level and are responsible for
+// defining segments and resolving them to subscribers.
+type SegmentService interface {
+	Create(segment *Segment) (*Segment, error)
+	Get(newsletterID, id uuid.UUID) (*Segment, error)
+	List(newsletterID uuid.UUID) ([]*Segment, error)
+	Update(segment *Segment) (*Segment, error)
+	// Delete removes a segment. It fails with ErrSegmentInUse while an
+	// unfinished campaign is sent to it.
+	Delete(newsletterID, id uuid.UUID) error
+	// Count returns the number of active subscribers of the newsletter that
+	// the filter selects now.
+	Count(newsletterID uuid.UUID, filter Filter) (int, error)
+	// Resolve returns the audience of a segment, to select the recipients of
+	// a campaign at dispatch time.
+	Resolve(newsletterID, id uuid.UUID) (*Audience, error)
+}
+
+// SegmentRepository is an interface that contains a collection of method signatures
+// which will be implemented in persistence level.
+type SegmentRepository interface {
+	Create(ctx context.Context, segment *Segment) (*Segment, error)
+	Get(ctx context.Context, newsletterID, id uuid.UUID) (*Segment, error)
+	List(ctx context.Context, newsletterID uuid.UUID) ([]*Segment, error)
+	// Update replaces the name and filter of a segment. It returns
+	// ErrSegmentNotFound if the newsletter has no such segment.
+	Update(ctx context.Context, segment *Segment) (*Segment, error)
+	// Delete removes a segment unless a campaign that is not completed is
+	// sent to it, returning ErrSegmentInUse then.
+	Delete(ctx context.Context, newsletterID, id uuid.UUID) error
+}
+
+// EngagementReader reads the engagement of the subscribers of a newsletter.
+type EngagementReader interface {
+	// Engagement returns the campaign emails of the newsletter received and
+	// opened since the given time, by email address. Subscribers who
+	// received none are left out.
+	Engagement(ctx context.Context, newsletterID uuid.UUID, since time.Time) (map[string]Engagement, error)
+}
+
+// SubscriberLister goes through the active subscribers of a newsletter. It
+// is implemented by the subscription repositories.
+type SubscriberLister interface {
+	GetAllForSend(ctx context.Context, newsletterID uuid.UUID, fn func(*subscriptiondomain.Subscription) error) error
+}
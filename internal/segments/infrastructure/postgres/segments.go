@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/segments/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type SegmentRepository struct {
+	db database.DB
+}
+
+func NewSegmentRepository(db database.DB) *SegmentRepository {
+	return &SegmentRepository{db: db}
+}
+
+// segmentColumns lists the columns scanned by scanSegment, in order.
+const segmentColumns = `id, newsletter_id, name, filter, created_at, updated_at`
+
+// scanner is implemented by both pgx.Row and pgx.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSegment scans a row selected with segmentColumns into a
+// domain.Segment. The filter is stored as JSON.
+func scanSegment(row scanner) (*domain.Segment, error) {
+	var segment domain.Segment
+	var filter []byte
+
+	err := row.Scan(
+		&segment.ID,
+		&segment.NewsletterID,
+		&segment.Name,
+		&filter,
+		&segment.CreatedAt,
+		&segment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filter, &segment.Filter); err != nil {
+		return nil, err
+	}
+
+	return &segment, nil
+}
+
+// Create inserts a new segment.
+func (sr *SegmentRepository) Create(ctx context.Context, segment *domain.Segment) (*domain.Segment, error) {
+	filter, err := json.Marshal(segment.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `insert into segments (newsletter_id, name, filter, created_at, updated_at) values ($1, $2, $3, $4, $4) returning ` + segmentColumns
+
+	return scanSegment(sr.db.QueryRow(ctx, query, segment.NewsletterID, segment.Name, filter, time.Now()))
+}
+
+// Get retrieves a segment of a newsletter.
+//
+// If no such segment exists, Get returns domain.ErrSegmentNotFound.
+func (sr *SegmentRepository) Get(ctx context.Context, newsletterID, id uuid.UUID) (*domain.Segment, error) {
+	query := `select ` + segmentColumns + ` from segments where id = $1 and newsletter_id = $2`
+
+	segment, err := scanSegment(sr.db.QueryRow(ctx, query, id, newsletterID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrSegmentNotFound
+	}
+
+	return segment, err
+}
+
+// List retrieves the segments of a newsletter, by name.
+func (sr *SegmentRepository) List(ctx context.Context, newsletterID uuid.UUID) ([]*domain.Segment, error) {
+	query := `select ` + segmentColumns + ` from segments where newsletter_id = $1 order by name, created_at`
+
+	rows, err := sr.db.Query(ctx, query, newsletterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []*domain.Segment{}
+	for rows.Next() {
+		segment, err := scanSegment(rows)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments, rows.Err()
+}
+
+// Update replaces the name and filter of a segment.
+//
+// If no such segment exists, Update returns domain.ErrSegmentNotFound.
+func (sr *SegmentRepository) Update(ctx context.Context, segment *domain.Segment) (*domain.Segment, error) {
+	filter, err := json.Marshal(segment.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `update segments
+		set name = $1, filter = $2, updated_at = $3
+		where id = $4 and newsletter_id = $5
+		returning ` + segmentColumns
+
+	updated, err := scanSegment(sr.db.QueryRow(ctx, query, segment.Name, filter, time.Now(), segment.ID, segment.NewsletterID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrSegmentNotFound
+	}
+
+	return updated, err
+}
+
+// Delete removes a segment that no campaign is sent to, unless completed:
+// failed campaigns can still be resumed. Completed campaigns forget the
+// segment.
+//
+// It returns domain.ErrSegmentInUse if such a campaign exists, and
+// domain.ErrSegmentNotFound if the segment does not.
+func (sr *SegmentRepository) Delete(ctx context.Context, newsletterID, id uuid.UUID) error {
+	query := `delete from segments
+		where id = $1 and newsletter_id = $2
+		and not exists (select 1 from campaigns c where c.segment_id = segments.id and c.status <> 'completed')`
+
+	result, err := sr.db.Exec(ctx, query, id, newsletterID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		if _, err := sr.Get(ctx, newsletterID, id); err != nil {
+			return err
+		}
+		return domain.ErrSegmentInUse
+	}
+
+	return nil
+}
+
+// Engagement counts, by recipient, the campaign emails of the newsletter
+// the provider accepted since the given time, and those that were opened.
+func (sr *SegmentRepository) Engagement(ctx context.Context, newsletterID uuid.UUID, since time.Time) (map[string]domain.Engagement, error) {
+	query := `select d.email, count(*), count(d.opened_at) from campaign_deliveries d
+		join campaigns c on c.id = d.campaign_id
+		where c.newsletter_id = $1 and d.sent_at >= $2
+		group by d.email`
+
+	rows, err := sr.db.Query(ctx, query, newsletterID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	engagement := map[string]domain.Engagement{}
+	for rows.Next() {
+		var email string
+		var counts domain.Engagement
+		if err := rows.Scan(&email, &counts.Received, &counts.Opened); err != nil {
+			return nil, err
+		}
+		engagement[email] = counts
+	}
+
+	return engagement, rows.Err()
+}
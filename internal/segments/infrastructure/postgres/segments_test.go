@@ -0,0 +1,79 @@
+package postgres_test
+
+import (
+	"context"
+	"newsletter/internal/segments/domain"
+	"newsletter/internal/segments/infrastructure/postgres"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMock returns a mocked database whose expectations are checked at the
+// end of the test.
+func newMock(t *testing.T) pgxmock.PgxPoolIface {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		mock.Close()
+	})
+	return mock
+}
+
+func TestSegmentRepository_Get_DecodesFilter(t *testing.T) {
+	mock := newMock(t)
+	newsletterID, id := uuid.New(), uuid.New()
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(`from segments where id = \$1 and newsletter_id = \$2`).
+		WithArgs(id, newsletterID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "newsletter_id", "name", "filter", "created_at", "updated_at"}).
+			AddRow(id, newsletterID, "Berlin", []byte(`{"attributes":{"city":"Berlin"},"engagement":"engaged"}`), now, now))
+
+	segment, err := postgres.NewSegmentRepository(mock).Get(context.Background(), newsletterID, id)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.Filter{Attributes: map[string]string{"city": "Berlin"}, Engagement: domain.EngagementEngaged}, segment.Filter)
+}
+
+func TestSegmentRepository_Delete_InUse(t *testing.T) {
+	mock := newMock(t)
+	newsletterID, id := uuid.New(), uuid.New()
+	now := time.Now().UTC()
+
+	// Nothing is deleted, and the segment exists: a campaign uses it.
+	mock.ExpectExec(`delete from segments`).
+		WithArgs(id, newsletterID).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectQuery(`from segments where id = \$1`).
+		WithArgs(id, newsletterID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "newsletter_id", "name", "filter", "created_at", "updated_at"}).
+			AddRow(id, newsletterID, "Beta", []byte(`{"tags":["beta"]}`), now, now))
+
+	err := postgres.NewSegmentRepository(mock).Delete(context.Background(), newsletterID, id)
+
+	assert.ErrorIs(t, err, domain.ErrSegmentInUse)
+}
+
+func TestSegmentRepository_Delete_NotFound(t *testing.T) {
+	mock := newMock(t)
+	newsletterID, id := uuid.New(), uuid.New()
+
+	mock.ExpectExec(`delete from segments`).
+		WithArgs(id, newsletterID).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectQuery(`from segments where id = \$1`).
+		WithArgs(id, newsletterID).
+		WillReturnError(pgx.ErrNoRows)
+
+	err := postgres.NewSegmentRepository(mock).Delete(context.Background(), newsletterID, id)
+
+	assert.ErrorIs(t, err, domain.ErrSegmentNotFound)
+}
@@ -2,17 +2,67 @@ package application
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"newsletter/internal/infrastructure/pagination"
+	limitsdomain "newsletter/internal/limits/domain"
 	"newsletter/internal/subscriptions/domain"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type SubscriptionService struct {
-	sr domain.SubscriptionRepository
+	sr        domain.SubscriptionRepository
+	validator domain.EmailValidator // nil skips email validation
+	limits    limitsdomain.Enforcer // nil skips plan limits
+	cooldown  time.Duration         // 0 lets an address subscribe again at once
+
+	// unsubscribeSecrets are the key signing unsubscribe tokens followed by
+	// the keys it replaced, whose tokens are still accepted; nil when tokens
+	// are not signed.
+	unsubscribeSecrets []string
+	unsubscribeTTL     time.Duration
+	legacyTokens       bool // Accept the random tokens stored with subscriptions
 }
 
 func NewSubscriptionService(sr domain.SubscriptionRepository) *SubscriptionService {
-	return &SubscriptionService{sr: sr}
+	return &SubscriptionService{sr: sr, unsubscribeTTL: DefaultUnsubscribeTokenTTL, legacyTokens: true}
+}
+
+// SetEmailValidator sets the validator that subscriber addresses must pass.
+// A nil validator disables validation.
+func (ss *SubscriptionService) SetEmailValidator(validator domain.EmailValidator) {
+	ss.validator = validator
+}
+
+// SetLimits sets the enforcer of the subscriber limits of newsletters. A nil
+// enforcer disables them.
+func (ss *SubscriptionService) SetLimits(limits limitsdomain.Enforcer) {
+	ss.limits = limits
+}
+
+// SetSubscribeCooldown sets the minimum time before the same address can
+// subscribe to the same newsletter again. Zero disables the cooldown.
+func (ss *SubscriptionService) SetSubscribeCooldown(cooldown time.Duration) {
+	ss.cooldown = cooldown
+}
+
+// SetUnsubscribeTokens signs unsubscribe tokens with secret, valid for ttl,
+// and still accepts the tokens signed with the previous secrets. An empty
+// secret leaves tokens unsigned. legacyTokens accepts the random tokens of
+// the emails sent before tokens were signed.
+func (ss *SubscriptionService) SetUnsubscribeTokens(secret string, previous []string, ttl time.Duration, legacyTokens bool) {
+	ss.unsubscribeSecrets = nil
+	if secret != "" {
+		ss.unsubscribeSecrets = append([]string{secret}, previous...)
+	}
+	ss.unsubscribeTTL = ttl
+	ss.legacyTokens = legacyTokens
 }
 
 // Subscribe creates a new subscription for a given newsletter.
@@ -27,13 +77,72 @@ func NewSubscriptionService(sr domain.SubscriptionRepository) *SubscriptionServi
 //
 // Behavior:
 //   - Uses a context with a 5-second timeout to ensure the operation does not hang.
-//   - Delegates the actual persistence to the subscription repository.
+//   - Rejects an address refused by the email validator, if one is set, with
+//     a *domain.EmailError wrapping domain.ErrInvalidEmail.
+//   - Rejects a timezone that is not an IANA timezone name with
+//     domain.ErrInvalidTimezone, and invalid custom attributes with
+//     domain.ErrInvalidAttributes.
+//   - When a cooldown is set (see SetSubscribeCooldown), rejects the subscription with
+//     domain.ErrSubscribeCooldown if the same email subscribed to the same
+//     newsletter within that period. This prevents the public endpoint from
+//     being used to flood an inbox with confirmation emails.
+//   - Rejects the subscription with limitsdomain.ErrSubscriberLimit when the
+//     newsletter has as many active subscribers as the plan of its owner
+//     allows, if limits are set.
+//   - Delegates the actual persistence to the subscription repository. An
+//     email that unsubscribed from the newsletter before gets its
+//     subscription back with domain.StatusPending, until it is confirmed
+//     with a token from ConfirmToken.
 func (ss *SubscriptionService) Subscribe(subscription *domain.Subscription) (*domain.Subscription, error) {
+	if subscription.Timezone != "" {
+		if _, err := time.LoadLocation(subscription.Timezone); err != nil || subscription.Timezone == "Local" {
+			return nil, fmt.Errorf("%w: %q", domain.ErrInvalidTimezone, subscription.Timezone)
+		}
+	}
+	if err := domain.ValidateAttributes(subscription.Attributes); err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if ss.validator != nil {
+		if err := ss.validator.Validate(ctx, subscription.Email); err != nil {
+			slog.Warn("Subscription rejected by email validation", "newsletter_id", subscription.NewsletterID, "email", subscription.Email, "error", err)
+			return nil, err
+		}
+	}
+
 	slog.Info("Creating subscription", "newsletter_id", subscription.NewsletterID, "email", subscription.Email)
 
+	if ss.cooldown > 0 {
+		last, err := ss.sr.LastSubscribedAt(ctx, subscription.NewsletterID, subscription.Email)
+		if err != nil {
+			slog.Error(
+				"Failed to check subscription cooldown",
+				"newsletter_id", subscription.NewsletterID,
+				"email", subscription.Email,
+				"error", err,
+			)
+			return nil, err
+		}
+		if !last.IsZero() && time.Since(last) < ss.cooldown {
+			slog.Warn(
+				"Subscription rejected by cooldown",
+				"newsletter_id", subscription.NewsletterID,
+				"email", subscription.Email,
+				"last_subscribed_at", last,
+			)
+			return nil, domain.ErrSubscribeCooldown
+		}
+	}
+
+	if ss.limits != nil {
+		if err := ss.limits.CheckSubscribers(subscription.NewsletterID); err != nil {
+			return nil, err
+		}
+	}
+
 	newSubscription, err := ss.sr.Subscribe(ctx, subscription)
 	if err != nil {
 		slog.Error(
@@ -55,31 +164,520 @@ func (ss *SubscriptionService) Subscribe(subscription *domain.Subscription) (*do
 	return newSubscription, nil
 }
 
-// Unsubscribe removes a subscription associated with the given unsubscribe token.
+// UnsubscribeToken returns the token of the unsubscribe links of the emails
+// sent to subscription: the subscription ID and the current time signed with
+// the secret of SetUnsubscribeTokens, valid for its TTL (default one year)
+// and only until the subscription is renewed. Without a secret, the random
+// token stored with the subscription, which Resubscribe renews, is returned
+// instead.
+func (ss *SubscriptionService) UnsubscribeToken(subscription *domain.Subscription) string {
+	if ss.unsubscribeSecrets == nil {
+		slog.Warn("unsubscribe secret key not set, using the stored unsubscribe token", "subscription_id", subscription.ID)
+		return subscription.UnsubscribeToken
+	}
+
+	now := time.Now()
+	return signSubscriptionToken(unsubscribeTokenPurpose, subscription.ID, now, now.Add(ss.unsubscribeTTL), ss.unsubscribeSecrets[0])
+}
+
+// GetByToken returns the subscription identified by an unsubscribe token,
+// active or not, so that the newsletter it belongs to can be found.
+//
+// Signed tokens are verified before any lookup, and refused once the
+// subscription was renewed after they were issued. The random tokens of the
+// emails sent before tokens were signed are looked up in the repository,
+// unless SetUnsubscribeTokens refuses them.
+//
+// Returns domain.ErrSubscriptionNotFound if no subscription holds the token,
+// or domain.ErrTokenExpired if the token is authentic but expired.
+func (ss *SubscriptionService) GetByToken(unsubscribeToken string) (*domain.Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subscription, err := ss.byToken(ctx, unsubscribeToken)
+	if err != nil {
+		if !errors.Is(err, domain.ErrSubscriptionNotFound) && !errors.Is(err, domain.ErrTokenExpired) {
+			slog.Error("Failed to get subscription", "error", err)
+		}
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// Unsubscribe deactivates a subscription associated with the given unsubscribe token.
 //
 // This method is part of the SubscriptionService and acts as the application-level
-// logic for handling unsubscription requests. It delegates the deletion to the
-// underlying repository while enforcing a timeout.
+// logic for handling unsubscription requests. The subscription is not deleted: the
+// repository marks it as unsubscribed so that its history is preserved.
 //
 // Parameters:
-//   - unsubscribeToken: A unique token identifying the subscription to remove.
+//   - unsubscribeToken: A signed token from UnsubscribeToken, or a random
+//     token of an email sent before tokens were signed.
 //
 // Behavior:
-//   - Creates a context with a 5-second timeout for the repository operation.
-//   - Calls the SubscriptionRepository's Unsubscribe method to delete the subscription.
-//   - Returns any error encountered during the deletion, or nil if successful.
+//   - Creates a context with a 5-second timeout for the repository operations.
+//   - Verifies signed tokens and looks random tokens up as GetByToken does,
+//     refusing the tokens issued before the subscription was renewed.
+//   - Calls the SubscriptionRepository's Unsubscribe method to deactivate the subscription.
+//   - Returns domain.ErrTokenExpired for expired tokens, any error encountered
+//     during the update, or nil if successful.
 func (ss *SubscriptionService) Unsubscribe(unsubscribeToken string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	slog.Info("Attempting to unsubscribe", "token", unsubscribeToken)
+	subscription, err := ss.byToken(ctx, unsubscribeToken)
+	if err != nil {
+		if errors.Is(err, domain.ErrSubscriptionNotFound) || errors.Is(err, domain.ErrTokenExpired) {
+			slog.Warn("Rejected unsubscribe token", "error", err)
+		} else {
+			slog.Error("Failed to unsubscribe", "error", err)
+		}
+		return err
+	}
+	id := subscription.ID
 
-	err := ss.sr.Unsubscribe(ctx, unsubscribeToken)
+	slog.Info("Attempting to unsubscribe", "subscription_id", id)
+
+	err = ss.sr.Unsubscribe(ctx, id)
 	if err != nil {
-		slog.Error("Failed to unsubscribe", "token", unsubscribeToken, "error", err)
+		slog.Error("Failed to unsubscribe", "subscription_id", id, "error", err)
 		return err
 	}
 
-	slog.Info("Unsubscribed successfully", "token", unsubscribeToken)
+	slog.Info("Unsubscribed successfully", "subscription_id", id)
 	return nil
 }
+
+// byToken returns the subscription of a signed or random unsubscribe token.
+func (ss *SubscriptionService) byToken(ctx context.Context, unsubscribeToken string) (*domain.Subscription, error) {
+	if !isSignedToken(unsubscribeToken) {
+		if !ss.legacyTokens {
+			return nil, domain.ErrSubscriptionNotFound
+		}
+		return ss.sr.GetByToken(ctx, unsubscribeToken)
+	}
+
+	return ss.signedSubscription(ctx, unsubscribeTokenPurpose, unsubscribeToken)
+}
+
+// signedSubscription returns the subscription of a token signed for purpose,
+// verified with the configured keys. Tokens issued before the subscription
+// was renewed are reported as domain.ErrSubscriptionNotFound.
+func (ss *SubscriptionService) signedSubscription(ctx context.Context, purpose, token string) (*domain.Subscription, error) {
+	id, issuedAt, err := ss.signedTokenID(purpose, token)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := ss.sr.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !issuedFor(subscription, issuedAt) {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// signedTokenID returns the subscription ID of a token signed for purpose,
+// verified with the configured keys, and the time it was issued.
+func (ss *SubscriptionService) signedTokenID(purpose, token string) (string, time.Time, error) {
+	if ss.unsubscribeSecrets == nil {
+		slog.Error("unsubscribe secret key not set")
+		return "", time.Time{}, errNoUnsubscribeSecret
+	}
+	return parseSubscriptionToken(purpose, token, ss.unsubscribeSecrets, time.Now())
+}
+
+// ConfirmToken returns the token of the link confirming subscription, pending
+// since it was renewed: the subscription ID and the current time signed with
+// the secret of SetUnsubscribeTokens, valid for a week. Without a secret,
+// the random token stored with the subscription, which Resubscribe renews,
+// is returned instead.
+func (ss *SubscriptionService) ConfirmToken(subscription *domain.Subscription) string {
+	if ss.unsubscribeSecrets == nil {
+		slog.Warn("unsubscribe secret key not set, using the stored unsubscribe token", "subscription_id", subscription.ID)
+		return subscription.UnsubscribeToken
+	}
+
+	now := time.Now()
+	return signSubscriptionToken(confirmTokenPurpose, subscription.ID, now, now.Add(confirmTokenTTL), ss.unsubscribeSecrets[0])
+}
+
+// GetByConfirmToken returns the subscription identified by a confirm token,
+// whatever its status, so that the confirmation page can show it.
+//
+// Returns domain.ErrSubscriptionNotFound if no subscription matches the
+// token, or domain.ErrTokenExpired if the token is authentic but expired.
+func (ss *SubscriptionService) GetByConfirmToken(confirmToken string) (*domain.Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, _, err := ss.confirmTokenID(confirmToken)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := ss.sr.Get(ctx, id)
+	if err != nil {
+		if !errors.Is(err, domain.ErrSubscriptionNotFound) {
+			slog.Error("Failed to get subscription", "subscription_id", id, "error", err)
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Confirm activates the subscription of a confirm token, pending since the
+// unsubscribed email subscribed again, and records the consent in its
+// history. Confirming twice is not an error.
+//
+// Returns:
+//   - the confirmed subscription
+//   - domain.ErrSubscriptionNotFound if the token is not valid, the
+//     subscription is unsubscribed, or the token was issued before the
+//     latest re-subscription
+//   - domain.ErrTokenExpired if the token is authentic but expired
+func (ss *SubscriptionService) Confirm(confirmToken string) (*domain.Subscription, error) {
+	id, issuedAt, err := ss.confirmTokenID(confirmToken)
+	if err != nil {
+		slog.Warn("Rejected confirm token", "error", err)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subscription, err := ss.sr.Confirm(ctx, id, issuedAt)
+	if err != nil {
+		if !errors.Is(err, domain.ErrSubscriptionNotFound) {
+			slog.Error("Failed to confirm subscription", "subscription_id", id, "error", err)
+		}
+		return nil, err
+	}
+
+	slog.Info("Subscription confirmed", "subscription_id", id, "newsletter_id", subscription.NewsletterID)
+	return subscription, nil
+}
+
+// confirmTokenID returns the subscription ID of a confirm token and the time
+// it was issued. Without a secret, the token is the random token stored with
+// the subscription, renewed by each re-subscription, so it is issued now.
+func (ss *SubscriptionService) confirmTokenID(confirmToken string) (string, time.Time, error) {
+	if ss.unsubscribeSecrets != nil {
+		return ss.signedTokenID(confirmTokenPurpose, confirmToken)
+	}
+	if confirmToken == "" || isSignedToken(confirmToken) {
+		return "", time.Time{}, domain.ErrSubscriptionNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subscription, err := ss.sr.GetByToken(ctx, confirmToken)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return subscription.ID, time.Now(), nil
+}
+
+// UnsubscribeAll deactivates every subscription of a subscriber across all newsletters.
+//
+// Parameters:
+//   - globalToken: A signed token produced by GlobalUnsubscribeToken that
+//     identifies a subscription of the subscriber's email address.
+//
+// Behavior:
+//   - Verifies the token signature using the unsubscribe secret, or one of
+//     the previous secrets, and its expiry; tokens issued before their
+//     subscription was renewed are refused.
+//   - Accepts the tokens encoding the email address that were issued before
+//     global tokens expired, unless SetUnsubscribeTokens refuses legacy
+//     tokens.
+//   - Creates a context with a 5-second timeout for the repository operations.
+//   - Marks all active subscriptions of the email address as unsubscribed.
+//
+// Returns:
+//   - the number of subscriptions that were deactivated
+//   - domain.ErrInvalidToken if the token cannot be verified,
+//     domain.ErrTokenExpired if it expired, or any repository error
+func (ss *SubscriptionService) UnsubscribeAll(globalToken string) (int, error) {
+	if ss.unsubscribeSecrets == nil {
+		slog.Error("unsubscribe secret key not set")
+		return 0, errNoUnsubscribeSecret
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var email string
+	if strings.Count(globalToken, ".") == 1 {
+		if !ss.legacyTokens {
+			slog.Warn("Invalid global unsubscribe token", "error", "legacy tokens are disabled")
+			return 0, domain.ErrInvalidToken
+		}
+		var err error
+		if email, err = parseLegacyGlobalToken(globalToken, ss.unsubscribeSecrets); err != nil {
+			slog.Warn("Invalid global unsubscribe token", "error", err)
+			return 0, err
+		}
+	} else {
+		subscription, err := ss.signedSubscription(ctx, globalTokenPurpose, globalToken)
+		if errors.Is(err, domain.ErrSubscriptionNotFound) {
+			err = domain.ErrInvalidToken
+		}
+		if err != nil {
+			slog.Warn("Invalid global unsubscribe token", "error", err)
+			return 0, err
+		}
+		email = subscription.Email
+	}
+
+	slog.Info("Attempting to unsubscribe from all newsletters")
+
+	count, err := ss.sr.UnsubscribeAll(ctx, email)
+	if err != nil {
+		slog.Error("Failed to unsubscribe from all newsletters", "error", err)
+		return 0, err
+	}
+
+	slog.Info("Unsubscribed from all newsletters", "count", count)
+	return count, nil
+}
+
+// GlobalUnsubscribeToken returns a signed token that identifies the email
+// address of subscription across all newsletters, without including it. The
+// token is accepted by UnsubscribeAll and is valid as long as the
+// unsubscribe tokens of the subscription are.
+func (ss *SubscriptionService) GlobalUnsubscribeToken(subscription *domain.Subscription) (string, error) {
+	if ss.unsubscribeSecrets == nil {
+		slog.Error("unsubscribe secret key not set")
+		return "", errNoUnsubscribeSecret
+	}
+
+	now := time.Now()
+	return signSubscriptionToken(globalTokenPurpose, subscription.ID, now, now.Add(ss.unsubscribeTTL), ss.unsubscribeSecrets[0]), nil
+}
+
+// List returns a page of the subscribers of a newsletter matching filter.
+//
+// Parameters:
+//   - newsletterID: the newsletter whose subscribers are listed
+//   - filter: optional status, tag and subscription date range filters
+//   - limit: page size, clamped to [1, pagination.MaxLimit] (default pagination.DefaultLimit)
+//   - cursor: the NextCursor of the previous page, or empty for the first page
+//
+// Returns:
+//   - the page of subscribers, newest first, with the cursor of the next page
+//   - pagination.ErrInvalidCursor if the cursor cannot be decoded, or any repository error
+func (ss *SubscriptionService) List(newsletterID uuid.UUID, filter domain.SubscriberFilter, limit int, cursor string) (*domain.SubscriberPage, error) {
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	page, err := ss.sr.List(ctx, newsletterID, domain.SubscriberQuery{
+		Filter: filter,
+		Limit:  pagination.Limit(limit),
+		After:  after,
+	})
+	if err != nil {
+		slog.Error("Failed to list subscribers", "newsletter_id", newsletterID, "error", err)
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// UpdateAttributes sets the custom attributes of changes on a subscription of
+// a newsletter, and removes the ones whose value is nil. Attributes not in
+// changes are kept.
+//
+// Returns:
+//   - the updated subscription
+//   - domain.ErrInvalidAttributes if a key or value is invalid, or the
+//     subscription would have more than domain.MaxAttributes attributes
+//   - domain.ErrSubscriptionNotFound if the newsletter has no such subscription
+func (ss *SubscriptionService) UpdateAttributes(newsletterID uuid.UUID, id string, changes map[string]*string) (*domain.Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subscription, err := ss.sr.UpdateAttributes(ctx, newsletterID, id, changes)
+	if err != nil {
+		if !errors.Is(err, domain.ErrSubscriptionNotFound) && !errors.Is(err, domain.ErrInvalidAttributes) {
+			slog.Error("Failed to update subscriber attributes", "newsletter_id", newsletterID, "subscription_id", id, "error", err)
+		}
+		return nil, err
+	}
+
+	slog.Info("Subscriber attributes updated", "newsletter_id", newsletterID, "subscription_id", id, "changed", len(changes))
+	return subscription, nil
+}
+
+// bulkUnsubscribeTimeout bounds a bulk unsubscription, which may go through
+// every subscriber of a newsletter.
+const bulkUnsubscribeTimeout = 2 * time.Minute
+
+// BulkUnsubscribe marks subscribers of a newsletter as unsubscribed,
+// recording reason, for owners removing bounced addresses or honouring
+// removal requests. Subscribers are selected either by email address or by
+// filter, whose matching subscribers are unsubscribed a page at a time.
+//
+// Returns:
+//   - the number of subscriptions that were deactivated, which excludes
+//     addresses that were not subscribed
+//   - domain.ErrInvalidUnsubscribeReason if reason is not an
+//     UnsubscribeReason constant
+//   - domain.ErrInvalidBulkUnsubscribe if neither or both of emails and
+//     filter are given, filter is empty or more than
+//     domain.MaxBulkUnsubscribeEmails addresses are given
+//   - any repository error, with the number of subscriptions deactivated
+//     before it
+func (ss *SubscriptionService) BulkUnsubscribe(newsletterID uuid.UUID, emails []string, filter *domain.SubscriberFilter, reason string) (int, error) {
+	if !domain.ValidUnsubscribeReason(reason) {
+		return 0, fmt.Errorf("%w: %q", domain.ErrInvalidUnsubscribeReason, reason)
+	}
+	if (len(emails) == 0) == (filter == nil) || len(emails) > domain.MaxBulkUnsubscribeEmails {
+		return 0, domain.ErrInvalidBulkUnsubscribe
+	}
+	if filter != nil {
+		// Only active subscribers are unsubscribed: a status alone does not
+		// narrow the selection.
+		criteria := *filter
+		criteria.Status = ""
+		if criteria == (domain.SubscriberFilter{}) {
+			return 0, domain.ErrInvalidBulkUnsubscribe
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), bulkUnsubscribeTimeout)
+	defer cancel()
+
+	slog.Info("Unsubscribing subscribers in bulk", "newsletter_id", newsletterID, "emails", len(emails), "filter", filter != nil, "reason", reason)
+
+	var count int
+	var err error
+	if filter != nil {
+		count, err = ss.unsubscribeMatching(ctx, newsletterID, *filter, reason)
+	} else {
+		count, err = ss.sr.UnsubscribeEmails(ctx, newsletterID, emails, reason)
+	}
+	if err != nil {
+		slog.Error("Failed to unsubscribe subscribers in bulk", "newsletter_id", newsletterID, "unsubscribed", count, "error", err)
+		return count, err
+	}
+
+	slog.Info("Unsubscribed subscribers in bulk", "newsletter_id", newsletterID, "count", count, "reason", reason)
+	return count, nil
+}
+
+// unsubscribeMatching unsubscribes the active subscribers of a newsletter
+// matching filter, a page at a time.
+func (ss *SubscriptionService) unsubscribeMatching(ctx context.Context, newsletterID uuid.UUID, filter domain.SubscriberFilter, reason string) (int, error) {
+	filter.Status = domain.StatusActive
+
+	var count int
+	query := domain.SubscriberQuery{Filter: filter, Limit: pagination.MaxLimit}
+	for {
+		page, err := ss.sr.List(ctx, newsletterID, query)
+		if err != nil {
+			return count, err
+		}
+
+		emails := make([]string, len(page.Subscriptions))
+		for i, subscription := range page.Subscriptions {
+			emails[i] = subscription.Email
+		}
+		if len(emails) > 0 {
+			unsubscribed, err := ss.sr.UnsubscribeEmails(ctx, newsletterID, emails, reason)
+			count += unsubscribed
+			if err != nil {
+				return count, err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return count, nil
+		}
+		if query.After, err = pagination.Decode(page.NextCursor); err != nil {
+			return count, err
+		}
+	}
+}
+
+// copySubscribersTimeout bounds the copy of the subscribers of a newsletter.
+const copySubscribersTimeout = 2 * time.Minute
+
+// copyBatchSize is the number of subscriptions stored at a time while
+// copying subscribers.
+const copyBatchSize = 500
+
+// CopySubscribers subscribes the active subscribers of the newsletter
+// fromNewsletterID to the newsletter toNewsletterID, with their tags,
+// language, timezone and attributes, such as when duplicating a newsletter.
+// The copies record a consent of their own, at the time of the copy: owners
+// should only copy subscribers who agreed to receive the other newsletter.
+// The newsletter toNewsletterID is expected to be new: as with Subscribe,
+// addresses that unsubscribed from it would be subscribed again.
+//
+// Subscriptions are stored in batches of copyBatchSize with
+// SubscribeBatch. If limits are set, the subscriber limit of the plan of the
+// owner is checked before each batch.
+//
+// Returns:
+//   - the number of subscribers copied
+//   - limitsdomain.ErrSubscriberLimit or any repository error, with the
+//     number of subscribers copied before it
+func (ss *SubscriptionService) CopySubscribers(fromNewsletterID, toNewsletterID uuid.UUID) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), copySubscribersTimeout)
+	defer cancel()
+
+	slog.Info("Copying subscribers", "from_newsletter_id", fromNewsletterID, "to_newsletter_id", toNewsletterID)
+
+	var count int
+	batch := make([]*domain.Subscription, 0, copyBatchSize)
+	store := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if ss.limits != nil {
+			if err := ss.limits.CheckSubscribers(toNewsletterID); err != nil {
+				return err
+			}
+		}
+		if _, err := ss.sr.SubscribeBatch(ctx, batch); err != nil {
+			return err
+		}
+		count += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err := ss.sr.GetAllForSend(ctx, fromNewsletterID, func(subscription *domain.Subscription) error {
+		batch = append(batch, &domain.Subscription{
+			NewsletterID: toNewsletterID,
+			Email:        subscription.Email,
+			Tags:         slices.Clone(subscription.Tags),
+			Language:     subscription.Language,
+			Timezone:     subscription.Timezone,
+			Attributes:   maps.Clone(subscription.Attributes),
+		})
+		if len(batch) < copyBatchSize {
+			return nil
+		}
+		return store()
+	})
+	if err == nil {
+		err = store()
+	}
+	if err != nil {
+		slog.Error("Failed to copy subscribers", "from_newsletter_id", fromNewsletterID, "to_newsletter_id", toNewsletterID, "copied", count, "error", err)
+		return count, err
+	}
+
+	slog.Info("Copied subscribers", "from_newsletter_id", fromNewsletterID, "to_newsletter_id", toNewsletterID, "count", count)
+	return count, nil
+}
@@ -2,16 +2,27 @@ package application_test
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"newsletter/internal/infrastructure/pagination"
 	"newsletter/internal/subscriptions/application"
 	"newsletter/internal/subscriptions/domain"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// testNewsletterID is the newsletter subscribed to by the tests.
+var testNewsletterID = uuid.MustParse("7b0c6a4e-3f0d-4f7a-9a39-2f1d5c8e6b21")
+
 // --- Mock Repository ---
 type MockSubscriptionRepository struct {
 	mock.Mock
@@ -26,11 +37,82 @@ func (m *MockSubscriptionRepository) Subscribe(ctx context.Context, s *domain.Su
 	return sub.(*domain.Subscription), args.Error(1)
 }
 
-func (m *MockSubscriptionRepository) Unsubscribe(ctx context.Context, token string) error {
+func (m *MockSubscriptionRepository) GetAllForSend(ctx context.Context, newsletterID uuid.UUID, fn func(*domain.Subscription) error) error {
+	args := m.Called(ctx, newsletterID, fn)
+	return args.Error(0)
+}
+
+func (m *MockSubscriptionRepository) SubscribeBatch(ctx context.Context, subscriptions []*domain.Subscription) ([]*domain.Subscription, error) {
+	args := m.Called(ctx, subscriptions)
+	stored, _ := args.Get(0).([]*domain.Subscription)
+	return stored, args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) Get(ctx context.Context, id string) (*domain.Subscription, error) {
+	args := m.Called(ctx, id)
+	sub := args.Get(0)
+	if sub == nil {
+		return nil, args.Error(1)
+	}
+	return sub.(*domain.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) GetByToken(ctx context.Context, token string) (*domain.Subscription, error) {
 	args := m.Called(ctx, token)
+	sub := args.Get(0)
+	if sub == nil {
+		return nil, args.Error(1)
+	}
+	return sub.(*domain.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) Unsubscribe(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockSubscriptionRepository) Confirm(ctx context.Context, id string, issuedAt time.Time) (*domain.Subscription, error) {
+	args := m.Called(ctx, id, issuedAt)
+	sub := args.Get(0)
+	if sub == nil {
+		return nil, args.Error(1)
+	}
+	return sub.(*domain.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) UnsubscribeAll(ctx context.Context, email string) (int, error) {
+	args := m.Called(ctx, email)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) LastSubscribedAt(ctx context.Context, newsletterID uuid.UUID, email string) (time.Time, error) {
+	args := m.Called(ctx, newsletterID, email)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) List(ctx context.Context, newsletterID uuid.UUID, query domain.SubscriberQuery) (*domain.SubscriberPage, error) {
+	args := m.Called(ctx, newsletterID, query)
+	page := args.Get(0)
+	if page == nil {
+		return nil, args.Error(1)
+	}
+	return page.(*domain.SubscriberPage), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) UpdateAttributes(ctx context.Context, newsletterID uuid.UUID, id string, changes map[string]*string) (*domain.Subscription, error) {
+	args := m.Called(ctx, newsletterID, id, changes)
+	sub := args.Get(0)
+	if sub == nil {
+		return nil, args.Error(1)
+	}
+	return sub.(*domain.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) UnsubscribeEmails(ctx context.Context, newsletterID uuid.UUID, emails []string, reason string) (int, error) {
+	args := m.Called(ctx, newsletterID, emails, reason)
+	return args.Int(0), args.Error(1)
+}
+
 // --- Tests for Subscribe ---
 
 func TestSubscribe_Success(t *testing.T) {
@@ -38,7 +120,7 @@ func TestSubscribe_Success(t *testing.T) {
 	ss := application.NewSubscriptionService(mockRepo)
 
 	subscription := &domain.Subscription{
-		NewsletterID: "newsletter1",
+		NewsletterID: testNewsletterID,
 		Email:        "test@example.com",
 	}
 
@@ -64,7 +146,7 @@ func TestSubscribe_Failure(t *testing.T) {
 	ss := application.NewSubscriptionService(mockRepo)
 
 	subscription := &domain.Subscription{
-		NewsletterID: "newsletter1",
+		NewsletterID: testNewsletterID,
 		Email:        "fail@example.com",
 	}
 
@@ -79,29 +161,188 @@ func TestSubscribe_Failure(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestSubscribe_InvalidTimezone(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+
+	for _, timezone := range []string{"Europe/Atlantis", "Local", "+02:00"} {
+		_, err := ss.Subscribe(&domain.Subscription{NewsletterID: testNewsletterID, Email: "test@example.com", Timezone: timezone})
+		assert.ErrorIs(t, err, domain.ErrInvalidTimezone, timezone)
+	}
+	mockRepo.AssertNotCalled(t, "Subscribe", mock.Anything, mock.Anything)
+}
+
+func TestSubscribe_InvalidAttributes(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+
+	tooMany := map[string]string{}
+	for i := range domain.MaxAttributes + 1 {
+		tooMany[fmt.Sprintf("key_%d", i)] = "value"
+	}
+	for _, attributes := range []map[string]string{
+		{"First Name": "Ada"},
+		{"1st": "Ada"},
+		{"bio": strings.Repeat("a", domain.MaxAttributeValueLength+1)},
+		tooMany,
+	} {
+		_, err := ss.Subscribe(&domain.Subscription{NewsletterID: testNewsletterID, Email: "test@example.com", Attributes: attributes})
+		assert.ErrorIs(t, err, domain.ErrInvalidAttributes)
+	}
+	mockRepo.AssertNotCalled(t, "Subscribe", mock.Anything, mock.Anything)
+}
+
+func TestApplyAttributes(t *testing.T) {
+	name := "Grace"
+	subscription := &domain.Subscription{Attributes: map[string]string{"first_name": "Ada", "source": "import"}}
+
+	assert.NoError(t, subscription.ApplyAttributes(map[string]*string{"first_name": &name, "source": nil}))
+	assert.Equal(t, map[string]string{"first_name": "Grace"}, subscription.Attributes)
+
+	assert.ErrorIs(t, subscription.ApplyAttributes(map[string]*string{"Bad Key": &name}), domain.ErrInvalidAttributes)
+	assert.Equal(t, map[string]string{"first_name": "Grace"}, subscription.Attributes, "unchanged on error")
+
+	assert.NoError(t, subscription.ApplyAttributes(map[string]*string{"first_name": nil}))
+	assert.Nil(t, subscription.Attributes)
+}
+
+// rejectingValidator refuses every address.
+type rejectingValidator struct{}
+
+func (rejectingValidator) Validate(ctx context.Context, email string) error {
+	return &domain.EmailError{Email: email, Reason: domain.EmailReasonDisposable, Detail: "mailinator.com is a disposable email provider"}
+}
+
+func TestSubscribe_RejectedEmail(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetEmailValidator(rejectingValidator{})
+
+	_, err := ss.Subscribe(&domain.Subscription{NewsletterID: testNewsletterID, Email: "test@mailinator.com"})
+
+	var emailErr *domain.EmailError
+	assert.ErrorIs(t, err, domain.ErrInvalidEmail)
+	assert.ErrorAs(t, err, &emailErr)
+	assert.Equal(t, domain.EmailReasonDisposable, emailErr.Reason)
+	mockRepo.AssertNotCalled(t, "Subscribe", mock.Anything, mock.Anything)
+}
+
+func TestSubscribe_Cooldown(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetSubscribeCooldown(10 * time.Minute)
+
+	subscription := &domain.Subscription{
+		NewsletterID: testNewsletterID,
+		Email:        "test@example.com",
+	}
+
+	mockRepo.On("LastSubscribedAt", mock.Anything, testNewsletterID, "test@example.com").Return(time.Now().Add(-time.Minute), nil)
+
+	result, err := ss.Subscribe(subscription)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, domain.ErrSubscribeCooldown)
+	mockRepo.AssertNotCalled(t, "Subscribe", mock.Anything, mock.Anything)
+}
+
+func TestSubscribe_CooldownElapsed(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetSubscribeCooldown(10 * time.Minute)
+
+	subscription := &domain.Subscription{
+		NewsletterID: testNewsletterID,
+		Email:        "test@example.com",
+	}
+	createdSub := &domain.Subscription{ID: "sub123", NewsletterID: testNewsletterID, Email: "test@example.com"}
+
+	mockRepo.On("LastSubscribedAt", mock.Anything, testNewsletterID, "test@example.com").Return(time.Now().Add(-time.Hour), nil)
+	mockRepo.On("Subscribe", mock.Anything, subscription).Return(createdSub, nil)
+
+	result, err := ss.Subscribe(subscription)
+
+	assert.NoError(t, err)
+	assert.Equal(t, createdSub, result)
+	mockRepo.AssertExpectations(t)
+}
+
 // --- Tests for Unsubscribe ---
 
 func TestUnsubscribe_Success(t *testing.T) {
 	mockRepo := new(MockSubscriptionRepository)
 	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
 
-	token := "token123"
+	subscription := &domain.Subscription{ID: "sub123", UnsubscribeToken: "stored-token", CreatedAt: time.Now()}
+	token := ss.UnsubscribeToken(subscription)
+	assert.NotContains(t, token, "stored-token")
 
-	mockRepo.On("Unsubscribe", mock.Anything, token).Return(nil)
+	mockRepo.On("Get", mock.Anything, "sub123").Return(subscription, nil)
+	mockRepo.On("Unsubscribe", mock.Anything, "sub123").Return(nil)
 
 	err := ss.Unsubscribe(token)
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetByToken", mock.Anything, mock.Anything)
+}
+
+func TestUnsubscribe_TokenOfEarlierSubscription(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	token := ss.UnsubscribeToken(&domain.Subscription{ID: "sub123"})
+	mockRepo.On("Get", mock.Anything, "sub123").Return(&domain.Subscription{
+		ID: "sub123", Status: domain.StatusActive, CreatedAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	err := ss.Unsubscribe(token)
+
+	assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	mockRepo.AssertNotCalled(t, "Unsubscribe", mock.Anything, mock.Anything)
+}
+
+func TestUnsubscribe_TokenWithoutIssueTime(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	// base64url("sub123").base36(expiry).base64url(mac), as signed before
+	// tokens recorded their time of issue.
+	expiry := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 36)
+	mac := hmac.New(sha256.New, []byte("secret123"))
+	mac.Write([]byte("unsubscribe:sub123." + expiry))
+	token := base64.RawURLEncoding.EncodeToString([]byte("sub123")) + "." + expiry + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	created := time.Now().Add(-48 * time.Hour)
+	unsubscribed := created.Add(time.Hour)
+	renewed := &domain.Subscription{ID: "sub123", Status: domain.StatusActive, CreatedAt: time.Now(), History: []domain.ConsentEvent{
+		{Type: domain.ConsentSubscribed, At: created},
+		{Type: domain.ConsentUnsubscribed, At: unsubscribed},
+		{Type: domain.ConsentSubscribed, At: time.Now()},
+	}}
+	mockRepo.On("Get", mock.Anything, "sub123").Return(renewed, nil).Once()
+
+	assert.ErrorIs(t, ss.Unsubscribe(token), domain.ErrSubscriptionNotFound, "renewed subscriptions refuse the tokens of earlier emails")
+
+	mockRepo.On("Get", mock.Anything, "sub123").Return(&domain.Subscription{ID: "sub123", Status: domain.StatusActive, CreatedAt: created}, nil)
+	mockRepo.On("Unsubscribe", mock.Anything, "sub123").Return(nil)
+
+	assert.NoError(t, ss.Unsubscribe(token))
+	mockRepo.AssertExpectations(t)
 }
 
 func TestUnsubscribe_Failure(t *testing.T) {
 	mockRepo := new(MockSubscriptionRepository)
 	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
 
-	token := "token123"
+	token := ss.UnsubscribeToken(&domain.Subscription{ID: "sub123"})
 
-	mockRepo.On("Unsubscribe", mock.Anything, token).Return(errors.New("not found"))
+	mockRepo.On("Get", mock.Anything, "sub123").Return(&domain.Subscription{ID: "sub123"}, nil)
+	mockRepo.On("Unsubscribe", mock.Anything, "sub123").Return(errors.New("not found"))
 
 	err := ss.Unsubscribe(token)
 
@@ -110,13 +351,185 @@ func TestUnsubscribe_Failure(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestUnsubscribe_ForgedToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	token := ss.UnsubscribeToken(&domain.Subscription{ID: "sub123"})
+	ss.SetUnsubscribeTokens("other-secret", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	err := ss.Unsubscribe(token)
+
+	assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	mockRepo.AssertNotCalled(t, "Unsubscribe", mock.Anything, mock.Anything)
+}
+
+func TestUnsubscribe_ExpiredToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, time.Nanosecond, true)
+
+	err := ss.Unsubscribe(ss.UnsubscribeToken(&domain.Subscription{ID: "sub123"}))
+
+	assert.ErrorIs(t, err, domain.ErrTokenExpired)
+	mockRepo.AssertNotCalled(t, "Unsubscribe", mock.Anything, mock.Anything)
+}
+
+func TestUnsubscribe_RotatedSecret(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("old-secret", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	token := ss.UnsubscribeToken(&domain.Subscription{ID: "sub123"})
+	ss.SetUnsubscribeTokens("new-secret", []string{"older-secret", "old-secret"}, application.DefaultUnsubscribeTokenTTL, true)
+
+	mockRepo.On("Get", mock.Anything, "sub123").Return(&domain.Subscription{ID: "sub123"}, nil)
+	mockRepo.On("Unsubscribe", mock.Anything, "sub123").Return(nil)
+
+	assert.NoError(t, ss.Unsubscribe(token))
+	mockRepo.AssertExpectations(t)
+
+	// Removing the old key invalidates its tokens.
+	ss.SetUnsubscribeTokens("new-secret", nil, application.DefaultUnsubscribeTokenTTL, true)
+	assert.ErrorIs(t, ss.Unsubscribe(token), domain.ErrSubscriptionNotFound)
+}
+
+func TestUnsubscribe_LegacyToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+
+	mockRepo.On("GetByToken", mock.Anything, "token123").Return(&domain.Subscription{ID: "sub123"}, nil)
+	mockRepo.On("Unsubscribe", mock.Anything, "sub123").Return(nil)
+
+	err := ss.Unsubscribe("token123")
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUnsubscribe_LegacyTokensDisabled(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("", nil, application.DefaultUnsubscribeTokenTTL, false)
+
+	err := ss.Unsubscribe("token123")
+
+	assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	mockRepo.AssertNotCalled(t, "GetByToken", mock.Anything, mock.Anything)
+}
+
+func TestGetByToken_SignedToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	subscription := &domain.Subscription{ID: "sub123", Email: "test@example.com"}
+	mockRepo.On("Get", mock.Anything, "sub123").Return(subscription, nil)
+
+	found, err := ss.GetByToken(ss.UnsubscribeToken(subscription))
+
+	assert.NoError(t, err)
+	assert.Equal(t, subscription, found)
+}
+
+// --- Tests for Confirm ---
+
+func TestConfirm_SignedToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	before := time.Now().Truncate(time.Second)
+	token := ss.ConfirmToken(&domain.Subscription{ID: "sub123", UnsubscribeToken: "stored-token"})
+	assert.NotContains(t, token, "stored-token")
+
+	confirmed := &domain.Subscription{ID: "sub123", Status: domain.StatusActive}
+	mockRepo.On("Confirm", mock.Anything, "sub123", mock.MatchedBy(func(issuedAt time.Time) bool {
+		return !issuedAt.Before(before) && !issuedAt.After(time.Now())
+	})).Return(confirmed, nil)
+
+	found, err := ss.Confirm(token)
+
+	assert.NoError(t, err)
+	assert.Equal(t, confirmed, found)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestConfirm_UnsubscribeTokenRefused(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	_, err := ss.Confirm(ss.UnsubscribeToken(&domain.Subscription{ID: "sub123"}))
+
+	assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	mockRepo.AssertNotCalled(t, "Confirm", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfirm_ForgedToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	token := ss.ConfirmToken(&domain.Subscription{ID: "sub123"})
+	ss.SetUnsubscribeTokens("other-secret", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	_, err := ss.Confirm(token)
+
+	assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	mockRepo.AssertNotCalled(t, "Confirm", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfirm_StoredToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+
+	token := ss.ConfirmToken(&domain.Subscription{ID: "sub123", UnsubscribeToken: "stored-token"})
+	assert.Equal(t, "stored-token", token)
+
+	mockRepo.On("GetByToken", mock.Anything, "stored-token").Return(&domain.Subscription{ID: "sub123"}, nil)
+	mockRepo.On("Confirm", mock.Anything, "sub123", mock.Anything).Return(&domain.Subscription{ID: "sub123"}, nil)
+
+	_, err := ss.Confirm(token)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSubscription_Confirm(t *testing.T) {
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	unsubscribed := created.Add(time.Hour)
+	renewed := created.Add(48 * time.Hour)
+
+	subscription := &domain.Subscription{Status: domain.StatusUnsubscribed, CreatedAt: created, UnsubscribedAt: &unsubscribed}
+	assert.NoError(t, subscription.Resubscribe(&domain.Subscription{UnsubscribeToken: "new-token"}, renewed))
+	assert.Equal(t, domain.StatusPending, subscription.Status)
+	assert.False(t, subscription.IsActive())
+	assert.Len(t, subscription.Periods(), 1, "the re-subscription is not a consent until confirmed")
+
+	assert.ErrorIs(t, subscription.Confirm(renewed.Add(-time.Minute), renewed.Add(time.Hour)), domain.ErrSubscriptionNotFound)
+	assert.NoError(t, subscription.Confirm(renewed, renewed.Add(time.Hour)))
+	assert.True(t, subscription.IsActive())
+	assert.Nil(t, subscription.UnsubscribedAt)
+	assert.Len(t, subscription.Periods(), 2)
+}
+
+func TestUnsubscribeToken_MissingSecret(t *testing.T) {
+	ss := application.NewSubscriptionService(new(MockSubscriptionRepository))
+
+	token := ss.UnsubscribeToken(&domain.Subscription{ID: "sub123", UnsubscribeToken: "stored-token"})
+
+	assert.Equal(t, "stored-token", token)
+}
+
 // --- Timeout / context test (optional, ensures context is used) ---
 func TestSubscribe_ContextTimeout(t *testing.T) {
 	mockRepo := new(MockSubscriptionRepository)
 	ss := application.NewSubscriptionService(mockRepo)
 
 	subscription := &domain.Subscription{
-		NewsletterID: "newsletter1",
+		NewsletterID: testNewsletterID,
 		Email:        "timeout@example.com",
 	}
 
@@ -138,10 +551,12 @@ func TestSubscribe_ContextTimeout(t *testing.T) {
 func TestUnsubscribe_ContextTimeout(t *testing.T) {
 	mockRepo := new(MockSubscriptionRepository)
 	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
 
-	token := "timeouttoken"
+	token := ss.UnsubscribeToken(&domain.Subscription{ID: "sub123"})
 
-	mockRepo.On("Unsubscribe", mock.Anything, token).Run(func(args mock.Arguments) {
+	mockRepo.On("Get", mock.Anything, "sub123").Return(&domain.Subscription{ID: "sub123"}, nil)
+	mockRepo.On("Unsubscribe", mock.Anything, "sub123").Run(func(args mock.Arguments) {
 		ctx := args.Get(0).(context.Context)
 		<-ctx.Done() // block until context is cancelled
 	}).Return(context.DeadlineExceeded)
@@ -154,3 +569,253 @@ func TestUnsubscribe_ContextTimeout(t *testing.T) {
 	assert.LessOrEqual(t, elapsed.Milliseconds(), int64(6000))
 	mockRepo.AssertExpectations(t)
 }
+
+// --- Tests for UnsubscribeAll ---
+
+func TestUnsubscribeAll_Success(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	subscription := &domain.Subscription{ID: "sub123", Email: "test@example.com", CreatedAt: time.Now()}
+	token, err := ss.GlobalUnsubscribeToken(subscription)
+	assert.NoError(t, err)
+	assert.NotContains(t, token, base64.RawURLEncoding.EncodeToString([]byte("test@example.com")))
+
+	mockRepo.On("Get", mock.Anything, "sub123").Return(subscription, nil)
+	mockRepo.On("UnsubscribeAll", mock.Anything, "test@example.com").Return(2, nil)
+
+	count, err := ss.UnsubscribeAll(token)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUnsubscribeAll_ExpiredToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, time.Nanosecond, true)
+
+	token, err := ss.GlobalUnsubscribeToken(&domain.Subscription{ID: "sub123"})
+	assert.NoError(t, err)
+
+	_, err = ss.UnsubscribeAll(token)
+
+	assert.ErrorIs(t, err, domain.ErrTokenExpired)
+	mockRepo.AssertNotCalled(t, "UnsubscribeAll", mock.Anything, mock.Anything)
+}
+
+func TestUnsubscribeAll_TokenOfEarlierSubscription(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	token, err := ss.GlobalUnsubscribeToken(&domain.Subscription{ID: "sub123"})
+	assert.NoError(t, err)
+	mockRepo.On("Get", mock.Anything, "sub123").Return(&domain.Subscription{
+		ID: "sub123", Email: "test@example.com", CreatedAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	_, err = ss.UnsubscribeAll(token)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+	mockRepo.AssertNotCalled(t, "UnsubscribeAll", mock.Anything, mock.Anything)
+}
+
+func TestUnsubscribeAll_LegacyToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	// base64url(email).base64url(mac), as signed before global tokens
+	// identified a subscription.
+	mac := hmac.New(sha256.New, []byte("secret123"))
+	mac.Write([]byte("unsubscribe-all:test@example.com"))
+	token := base64.RawURLEncoding.EncodeToString([]byte("test@example.com")) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	mockRepo.On("UnsubscribeAll", mock.Anything, "test@example.com").Return(1, nil)
+
+	count, err := ss.UnsubscribeAll(token)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, false)
+	_, err = ss.UnsubscribeAll(token)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+	mockRepo.AssertNumberOfCalls(t, "UnsubscribeAll", 1)
+}
+
+func TestUnsubscribeAll_TamperedToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	token, err := ss.GlobalUnsubscribeToken(&domain.Subscription{ID: "sub123", Email: "test@example.com"})
+	assert.NoError(t, err)
+
+	// Verify with a different key to simulate a forged token
+	ss.SetUnsubscribeTokens("other-secret", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	count, err := ss.UnsubscribeAll(token)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+	assert.Equal(t, 0, count)
+	mockRepo.AssertNotCalled(t, "UnsubscribeAll", mock.Anything, mock.Anything)
+}
+
+func TestUnsubscribeAll_MalformedToken(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+	ss.SetUnsubscribeTokens("secret123", nil, application.DefaultUnsubscribeTokenTTL, true)
+
+	_, err := ss.UnsubscribeAll("not-a-token")
+
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+	mockRepo.AssertNotCalled(t, "UnsubscribeAll", mock.Anything, mock.Anything)
+}
+
+func TestGlobalUnsubscribeToken_MissingSecret(t *testing.T) {
+	ss := application.NewSubscriptionService(new(MockSubscriptionRepository))
+
+	token, err := ss.GlobalUnsubscribeToken(&domain.Subscription{ID: "sub123", Email: "test@example.com"})
+
+	assert.Error(t, err)
+	assert.Equal(t, "", token)
+}
+
+// --- Tests for List ---
+
+func TestList_DecodesCursorAndClampsLimit(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+
+	cursor := pagination.Cursor{CreatedAt: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), ID: "sub123"}
+	filter := domain.SubscriberFilter{Status: domain.StatusActive}
+	page := &domain.SubscriberPage{Subscriptions: []*domain.Subscription{}}
+
+	mockRepo.On("List", mock.Anything, testNewsletterID, mock.MatchedBy(func(query domain.SubscriberQuery) bool {
+		return query.Limit == pagination.MaxLimit &&
+			query.Filter == filter &&
+			query.After != nil && query.After.ID == cursor.ID && query.After.CreatedAt.Equal(cursor.CreatedAt)
+	})).Return(page, nil)
+
+	result, err := ss.List(testNewsletterID, filter, 1000, cursor.Encode())
+
+	assert.NoError(t, err)
+	assert.Equal(t, page, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestList_InvalidCursor(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+
+	result, err := ss.List(testNewsletterID, domain.SubscriberFilter{}, 10, "not-a-cursor")
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+	mockRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// --- Tests for BulkUnsubscribe ---
+
+func TestBulkUnsubscribe_Emails(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+
+	emails := []string{"a@example.com", "b@example.com"}
+	mockRepo.On("UnsubscribeEmails", mock.Anything, testNewsletterID, emails, domain.UnsubscribeReasonBounced).Return(1, nil)
+
+	count, err := ss.BulkUnsubscribe(testNewsletterID, emails, nil, domain.UnsubscribeReasonBounced)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBulkUnsubscribe_FilterPages(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+
+	filter := domain.SubscriberFilter{Tag: "imported"}
+	last := &domain.Subscription{ID: "sub-2", Email: "b@example.com", CreatedAt: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)}
+	first := &domain.SubscriberPage{
+		Subscriptions: []*domain.Subscription{{ID: "sub-1", Email: "a@example.com"}, last},
+		NextCursor:    pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode(),
+	}
+	second := &domain.SubscriberPage{Subscriptions: []*domain.Subscription{{ID: "sub-3", Email: "c@example.com"}}}
+
+	activeImported := func(query domain.SubscriberQuery) bool {
+		return query.Filter.Tag == "imported" && query.Filter.Status == domain.StatusActive
+	}
+	mockRepo.On("List", mock.Anything, testNewsletterID, mock.MatchedBy(func(query domain.SubscriberQuery) bool {
+		return activeImported(query) && query.After == nil
+	})).Return(first, nil)
+	mockRepo.On("List", mock.Anything, testNewsletterID, mock.MatchedBy(func(query domain.SubscriberQuery) bool {
+		return activeImported(query) && query.After != nil && query.After.ID == "sub-2"
+	})).Return(second, nil)
+	mockRepo.On("UnsubscribeEmails", mock.Anything, testNewsletterID, []string{"a@example.com", "b@example.com"}, domain.UnsubscribeReasonCleanup).Return(2, nil)
+	mockRepo.On("UnsubscribeEmails", mock.Anything, testNewsletterID, []string{"c@example.com"}, domain.UnsubscribeReasonCleanup).Return(1, nil)
+
+	count, err := ss.BulkUnsubscribe(testNewsletterID, nil, &filter, domain.UnsubscribeReasonCleanup)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBulkUnsubscribe_Invalid(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+
+	emails := []string{"a@example.com"}
+	tests := []struct {
+		name   string
+		emails []string
+		filter *domain.SubscriberFilter
+		reason string
+		err    error
+	}{
+		{"no reason", emails, nil, "", domain.ErrInvalidUnsubscribeReason},
+		{"unknown reason", emails, nil, "boredom", domain.ErrInvalidUnsubscribeReason},
+		{"no selection", nil, nil, domain.UnsubscribeReasonLegal, domain.ErrInvalidBulkUnsubscribe},
+		{"both selections", emails, &domain.SubscriberFilter{Tag: "x"}, domain.UnsubscribeReasonLegal, domain.ErrInvalidBulkUnsubscribe},
+		{"empty filter", nil, &domain.SubscriberFilter{Status: domain.StatusActive}, domain.UnsubscribeReasonCleanup, domain.ErrInvalidBulkUnsubscribe},
+		{"too many emails", make([]string, domain.MaxBulkUnsubscribeEmails+1), nil, domain.UnsubscribeReasonBounced, domain.ErrInvalidBulkUnsubscribe},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ss.BulkUnsubscribe(testNewsletterID, tt.emails, tt.filter, tt.reason)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+	mockRepo.AssertNotCalled(t, "UnsubscribeEmails", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCopySubscribers(t *testing.T) {
+	mockRepo := new(MockSubscriptionRepository)
+	ss := application.NewSubscriptionService(mockRepo)
+
+	toNewsletterID := uuid.New()
+	subscribers := []*domain.Subscription{
+		{ID: "sub-1", NewsletterID: testNewsletterID, Email: "a@example.com", Tags: []string{"vip"}, Language: "de", Attributes: map[string]string{"first_name": "Ada"}},
+		{ID: "sub-2", NewsletterID: testNewsletterID, Email: "b@example.com", Timezone: "Europe/Paris"},
+	}
+	mockRepo.On("GetAllForSend", mock.Anything, testNewsletterID, mock.Anything).Run(func(args mock.Arguments) {
+		fn := args.Get(2).(func(*domain.Subscription) error)
+		for _, subscriber := range subscribers {
+			assert.NoError(t, fn(subscriber))
+		}
+	}).Return(nil)
+	mockRepo.On("SubscribeBatch", mock.Anything, []*domain.Subscription{
+		{NewsletterID: toNewsletterID, Email: "a@example.com", Tags: []string{"vip"}, Language: "de", Attributes: map[string]string{"first_name": "Ada"}},
+		{NewsletterID: toNewsletterID, Email: "b@example.com", Timezone: "Europe/Paris"},
+	}).Return([]*domain.Subscription{}, nil)
+
+	count, err := ss.CopySubscribers(testNewsletterID, toNewsletterID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,145 @@
+package application
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"newsletter/internal/subscriptions/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Purposes binding the signatures of tokens to their use, so that a token
+// signed for one use with the same key is never accepted for another.
+const (
+	unsubscribeTokenPurpose = "unsubscribe:"     // Unsubscription of a single subscription
+	globalTokenPurpose      = "unsubscribe-all:" // Unsubscription of an email address from every newsletter
+	confirmTokenPurpose     = "confirm:"         // Confirmation of a pending subscription
+)
+
+// DefaultUnsubscribeTokenTTL is how long unsubscribe tokens are valid unless
+// configured otherwise, one year.
+const DefaultUnsubscribeTokenTTL = 365 * 24 * time.Hour
+
+// confirmTokenTTL is how long the links confirming a re-subscription are
+// valid.
+const confirmTokenTTL = 7 * 24 * time.Hour
+
+// isSignedToken tells the tokens of signSubscriptionToken from the random
+// tokens stored with subscriptions, which are UUIDs.
+func isSignedToken(token string) bool {
+	return strings.Contains(token, ".")
+}
+
+// signSubscriptionToken builds a token of the form
+// base64url(id).base36(issued).base36(expiry).base64url(mac), where mac is an
+// HMAC-SHA256 over purpose, the subscription ID and the Unix times of issue
+// and expiry. The time of issue tells the tokens of the current subscription
+// period from those issued before a re-subscription; see issuedFor.
+func signSubscriptionToken(purpose, id string, issuedAt, expiresAt time.Time, secret string) string {
+	issued := strconv.FormatInt(issuedAt.Unix(), 36)
+	expiry := strconv.FormatInt(expiresAt.Unix(), 36)
+	signature := base64.RawURLEncoding.EncodeToString(subscriptionTokenMAC(purpose, id+"."+issued+"."+expiry, secret))
+	return base64.RawURLEncoding.EncodeToString([]byte(id)) + "." + issued + "." + expiry + "." + signature
+}
+
+// parseSubscriptionToken verifies a token produced by signSubscriptionToken
+// for purpose with any of secrets and returns the subscription ID it encodes
+// and the time it was issued. Tokens of the form
+// base64url(id).base36(expiry).base64url(mac), signed before the time of
+// issue was recorded, are accepted with a zero time of issue.
+//
+// Tokens that are malformed or signed with another key are reported as
+// domain.ErrSubscriptionNotFound, like unknown random tokens; authentic
+// tokens expired at now as domain.ErrTokenExpired.
+func parseSubscriptionToken(purpose, token string, secrets []string, now time.Time) (string, time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 && len(parts) != 4 {
+		return "", time.Time{}, domain.ErrSubscriptionNotFound
+	}
+
+	id, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(id) == 0 {
+		return "", time.Time{}, domain.ErrSubscriptionNotFound
+	}
+	var issuedAt time.Time
+	if len(parts) == 4 {
+		issued, err := strconv.ParseInt(parts[1], 36, 64)
+		if err != nil {
+			return "", time.Time{}, domain.ErrSubscriptionNotFound
+		}
+		issuedAt = time.Unix(issued, 0)
+	}
+	expiry, err := strconv.ParseInt(parts[len(parts)-2], 36, 64)
+	if err != nil {
+		return "", time.Time{}, domain.ErrSubscriptionNotFound
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[len(parts)-1])
+	if err != nil {
+		return "", time.Time{}, domain.ErrSubscriptionNotFound
+	}
+
+	payload := strings.Join(append([]string{string(id)}, parts[1:len(parts)-1]...), ".")
+	for _, secret := range secrets {
+		if !hmac.Equal(mac, subscriptionTokenMAC(purpose, payload, secret)) {
+			continue
+		}
+		if !now.Before(time.Unix(expiry, 0)) {
+			return "", time.Time{}, domain.ErrTokenExpired
+		}
+		return string(id), issuedAt, nil
+	}
+	return "", time.Time{}, domain.ErrSubscriptionNotFound
+}
+
+func subscriptionTokenMAC(purpose, payload, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose + payload))
+	return mac.Sum(nil)
+}
+
+// issuedFor reports whether a token issued at issuedAt belongs to the
+// current period of subscription, so that the links of the emails sent
+// before it was unsubscribed and subscribed again stop working. Tokens
+// issued before the time of issue was recorded, with a zero issuedAt, are
+// accepted as long as the subscription was never renewed.
+func issuedFor(subscription *domain.Subscription, issuedAt time.Time) bool {
+	if issuedAt.IsZero() {
+		return len(subscription.Periods()) <= 1 && subscription.Status != domain.StatusPending
+	}
+	return !issuedAt.Before(subscription.CreatedAt.Truncate(time.Second))
+}
+
+// parseLegacyGlobalToken verifies a global token of the form
+// base64url(email).base64url(mac), issued before global tokens identified a
+// subscription, with any of secrets and returns the email address it
+// encodes. Such tokens do not expire.
+func parseLegacyGlobalToken(token string, secrets []string) (string, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", domain.ErrInvalidToken
+	}
+
+	email, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || len(email) == 0 {
+		return "", domain.ErrInvalidToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", domain.ErrInvalidToken
+	}
+
+	for _, secret := range secrets {
+		if hmac.Equal(mac, subscriptionTokenMAC(globalTokenPurpose, string(email), secret)) {
+			return string(email), nil
+		}
+	}
+	return "", domain.ErrInvalidToken
+}
+
+// errNoUnsubscribeSecret is returned when unsubscribe tokens cannot be
+// signed or verified.
+var errNoUnsubscribeSecret = errors.New("unsubscribe secret key is missing")
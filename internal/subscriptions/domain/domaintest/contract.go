@@ -0,0 +1,403 @@
+// Package domaintest provides the conformance tests that every
+// implementation of domain.SubscriptionRepository must pass, so that the
+// services behave the same whichever backend stores subscriptions.
+package domaintest
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/subscriptions/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SubscriptionRepositoryContract runs the conformance tests against
+// repository. legacy stores a subscription to the newsletter as written
+// before statuses existed, without a status, and returns its ID; it is nil
+// for backends that never stored such subscriptions. Every test works on
+// newsletters and emails of its own, so the repository may be shared with
+// other tests and need not be empty.
+func SubscriptionRepositoryContract(t *testing.T, repository domain.SubscriptionRepository, legacy func(t *testing.T, newsletterID uuid.UUID) string) {
+	ctx := context.Background()
+
+	subscribe := func(t *testing.T, newsletterID uuid.UUID, email string) *domain.Subscription {
+		t.Helper()
+		subscription, err := repository.Subscribe(ctx, &domain.Subscription{NewsletterID: newsletterID, Email: email, Tags: []string{"contract"}})
+		require.NoError(t, err)
+		return subscription
+	}
+
+	t.Run("Subscribe assigns an ID, a token and an active status", func(t *testing.T) {
+		newsletterID, email := uuid.New(), uniqueEmail()
+		before := time.Now()
+
+		subscription := subscribe(t, newsletterID, email)
+
+		assert.NotEmpty(t, subscription.ID)
+		assert.NotEmpty(t, subscription.UnsubscribeToken)
+		assert.Equal(t, domain.StatusActive, subscription.Status)
+		assert.False(t, subscription.CreatedAt.Before(before.Add(-time.Second)))
+		assert.Nil(t, subscription.UnsubscribedAt)
+
+		other := subscribe(t, newsletterID, uniqueEmail())
+		assert.NotEqual(t, subscription.ID, other.ID)
+		assert.NotEqual(t, subscription.UnsubscribeToken, other.UnsubscribeToken)
+	})
+
+	t.Run("GetByToken returns the subscription", func(t *testing.T) {
+		subscription := subscribe(t, uuid.New(), uniqueEmail())
+
+		found, err := repository.GetByToken(ctx, subscription.UnsubscribeToken)
+
+		require.NoError(t, err)
+		assert.Equal(t, subscription.ID, found.ID)
+		assert.Equal(t, subscription.NewsletterID, found.NewsletterID)
+		assert.Equal(t, subscription.Email, found.Email)
+		assert.Equal(t, []string{"contract"}, found.Tags)
+	})
+
+	t.Run("GetByToken of an unknown token returns not found", func(t *testing.T) {
+		_, err := repository.GetByToken(ctx, uuid.NewString())
+		assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	})
+
+	t.Run("Get returns the subscription", func(t *testing.T) {
+		subscription := subscribe(t, uuid.New(), uniqueEmail())
+
+		found, err := repository.Get(ctx, subscription.ID)
+
+		require.NoError(t, err)
+		assert.Equal(t, subscription.NewsletterID, found.NewsletterID)
+		assert.Equal(t, subscription.Email, found.Email)
+		assert.Equal(t, []string{"contract"}, found.Tags)
+	})
+
+	t.Run("Get of an unknown ID returns not found", func(t *testing.T) {
+		_, err := repository.Get(ctx, uuid.NewString())
+		assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	})
+
+	t.Run("Unsubscribe of an unknown ID returns not found", func(t *testing.T) {
+		err := repository.Unsubscribe(ctx, uuid.NewString())
+		assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	})
+
+	t.Run("Unsubscribe keeps the subscription, once", func(t *testing.T) {
+		subscription := subscribe(t, uuid.New(), uniqueEmail())
+
+		require.NoError(t, repository.Unsubscribe(ctx, subscription.ID))
+
+		found, err := repository.GetByToken(ctx, subscription.UnsubscribeToken)
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusUnsubscribed, found.Status)
+		assert.NotNil(t, found.UnsubscribedAt)
+
+		err = repository.Unsubscribe(ctx, subscription.ID)
+		assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	})
+
+	t.Run("Subscribe of an active email returns its subscription", func(t *testing.T) {
+		newsletterID, email := uuid.New(), uniqueEmail()
+		subscription := subscribe(t, newsletterID, email)
+
+		again := subscribe(t, newsletterID, email)
+
+		assert.Equal(t, subscription.ID, again.ID)
+		assert.Equal(t, subscription.UnsubscribeToken, again.UnsubscribeToken)
+		assert.Len(t, again.History, 1)
+	})
+
+	t.Run("Subscribe after Unsubscribe renews the subscription pending confirmation", func(t *testing.T) {
+		newsletterID, email := uuid.New(), uniqueEmail()
+		subscription := subscribe(t, newsletterID, email)
+		require.NoError(t, repository.Unsubscribe(ctx, subscription.ID))
+		time.Sleep(time.Millisecond) // Distinct consent times
+
+		again, err := repository.Subscribe(ctx, &domain.Subscription{NewsletterID: newsletterID, Email: email, Tags: []string{"returning"}})
+		require.NoError(t, err)
+
+		assert.Equal(t, subscription.ID, again.ID)
+		assert.NotEqual(t, subscription.UnsubscribeToken, again.UnsubscribeToken)
+		assert.Equal(t, domain.StatusPending, again.Status)
+		assert.False(t, again.IsActive())
+		assert.True(t, again.CreatedAt.After(subscription.CreatedAt))
+		assert.ElementsMatch(t, []string{"contract", "returning"}, again.Tags)
+
+		err = repository.GetAllForSend(ctx, newsletterID, func(*domain.Subscription) error {
+			return errors.New("pending subscription listed for sending")
+		})
+		require.NoError(t, err)
+
+		confirmed, err := repository.Confirm(ctx, subscription.ID, time.Now())
+		require.NoError(t, err)
+		assert.True(t, confirmed.IsActive())
+		assert.Nil(t, confirmed.UnsubscribedAt)
+
+		found, err := repository.Get(ctx, subscription.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusActive, found.Status)
+		require.Len(t, found.History, 3)
+		assert.Equal(t, []string{domain.ConsentSubscribed, domain.ConsentUnsubscribed, domain.ConsentSubscribed},
+			[]string{found.History[0].Type, found.History[1].Type, found.History[2].Type})
+		assert.Len(t, found.Periods(), 2)
+
+		again, err = repository.Confirm(ctx, subscription.ID, time.Now())
+		require.NoError(t, err, "confirming twice is not an error")
+		assert.Len(t, again.History, 3)
+
+		_, err = repository.GetByToken(ctx, subscription.UnsubscribeToken)
+		assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+
+		page, err := repository.List(ctx, newsletterID, domain.SubscriberQuery{Limit: 10})
+		require.NoError(t, err)
+		assert.Len(t, page.Subscriptions, 1)
+	})
+
+	t.Run("Confirm refuses stale links and unsubscribed subscriptions", func(t *testing.T) {
+		newsletterID, email := uuid.New(), uniqueEmail()
+		subscription := subscribe(t, newsletterID, email)
+		require.NoError(t, repository.Unsubscribe(ctx, subscription.ID))
+
+		_, err := repository.Confirm(ctx, subscription.ID, time.Now())
+		assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+
+		issuedBefore := time.Now().Add(-time.Minute)
+		subscribe(t, newsletterID, email)
+		_, err = repository.Confirm(ctx, subscription.ID, issuedBefore)
+		assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+
+		found, err := repository.Get(ctx, subscription.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusPending, found.Status)
+
+		_, err = repository.Confirm(ctx, uuid.NewString(), time.Now())
+		assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	})
+
+	t.Run("SubscribeBatch stores the subscriptions as Subscribe does", func(t *testing.T) {
+		newsletterID, otherID := uuid.New(), uuid.New()
+		active := subscribe(t, newsletterID, uniqueEmail())
+		gone := subscribe(t, newsletterID, uniqueEmail())
+		require.NoError(t, repository.Unsubscribe(ctx, gone.ID))
+		fresh := uniqueEmail()
+
+		stored, err := repository.SubscribeBatch(ctx, []*domain.Subscription{
+			{NewsletterID: newsletterID, Email: fresh},
+			{NewsletterID: newsletterID, Email: active.Email},
+			{NewsletterID: newsletterID, Email: gone.Email},
+			{NewsletterID: otherID, Email: fresh},
+			{NewsletterID: newsletterID, Email: fresh},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, stored, 5)
+		for i, subscription := range stored {
+			assert.Equal(t, i != 2, subscription.IsActive(), "only the unsubscribed email is pending")
+			assert.NotEmpty(t, subscription.UnsubscribeToken)
+		}
+		assert.Equal(t, domain.StatusPending, stored[2].Status)
+		assert.Equal(t, active.ID, stored[1].ID)
+		assert.Equal(t, gone.ID, stored[2].ID)
+		assert.NotEqual(t, gone.UnsubscribeToken, stored[2].UnsubscribeToken)
+		assert.NotEqual(t, stored[0].ID, stored[3].ID)
+		assert.Equal(t, stored[0].ID, stored[4].ID)
+
+		found, err := repository.GetByToken(ctx, stored[0].UnsubscribeToken)
+		require.NoError(t, err)
+		assert.Equal(t, fresh, found.Email)
+
+		page, err := repository.List(ctx, newsletterID, domain.SubscriberQuery{Limit: 10})
+		require.NoError(t, err)
+		assert.Len(t, page.Subscriptions, 3)
+	})
+
+	t.Run("UnsubscribeAll counts the active subscriptions of the email", func(t *testing.T) {
+		email := uniqueEmail()
+		subscribe(t, uuid.New(), email)
+		subscribe(t, uuid.New(), email)
+		gone := subscribe(t, uuid.New(), email)
+		require.NoError(t, repository.Unsubscribe(ctx, gone.ID))
+		kept := subscribe(t, uuid.New(), uniqueEmail())
+
+		count, err := repository.UnsubscribeAll(ctx, email)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		count, err = repository.UnsubscribeAll(ctx, email)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+
+		found, err := repository.GetByToken(ctx, kept.UnsubscribeToken)
+		require.NoError(t, err)
+		assert.True(t, found.IsActive())
+	})
+
+	t.Run("LastSubscribedAt returns the latest subscription", func(t *testing.T) {
+		newsletterID, email := uuid.New(), uniqueEmail()
+
+		last, err := repository.LastSubscribedAt(ctx, newsletterID, email)
+		require.NoError(t, err)
+		assert.True(t, last.IsZero())
+
+		first := subscribe(t, newsletterID, email)
+		require.NoError(t, repository.Unsubscribe(ctx, first.ID))
+		time.Sleep(time.Millisecond) // Distinct consent times
+		latest := subscribe(t, newsletterID, email)
+		subscribe(t, uuid.New(), email)
+
+		last, err = repository.LastSubscribedAt(ctx, newsletterID, email)
+		require.NoError(t, err)
+		assert.WithinDuration(t, latest.CreatedAt, last, time.Millisecond)
+	})
+
+	t.Run("List pages through the subscribers of a newsletter, newest first", func(t *testing.T) {
+		newsletterID := uuid.New()
+		var ids []string
+		for range 3 {
+			ids = append([]string{subscribe(t, newsletterID, uniqueEmail()).ID}, ids...)
+			time.Sleep(time.Millisecond) // Distinct creation times
+		}
+		subscribe(t, uuid.New(), uniqueEmail())
+
+		first, err := repository.List(ctx, newsletterID, domain.SubscriberQuery{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, first.Subscriptions, 2)
+		assert.NotEmpty(t, first.NextCursor)
+
+		last := first.Subscriptions[1]
+		second, err := repository.List(ctx, newsletterID, domain.SubscriberQuery{
+			Limit: 2,
+			After: &pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID},
+		})
+		require.NoError(t, err)
+		require.Len(t, second.Subscriptions, 1)
+		assert.Empty(t, second.NextCursor)
+
+		assert.Equal(t, ids, []string{first.Subscriptions[0].ID, first.Subscriptions[1].ID, second.Subscriptions[0].ID})
+	})
+
+	t.Run("List filters by status", func(t *testing.T) {
+		newsletterID := uuid.New()
+		active := subscribe(t, newsletterID, uniqueEmail())
+		gone := subscribe(t, newsletterID, uniqueEmail())
+		require.NoError(t, repository.Unsubscribe(ctx, gone.ID))
+
+		page, err := repository.List(ctx, newsletterID, domain.SubscriberQuery{Filter: domain.SubscriberFilter{Status: domain.StatusActive}, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page.Subscriptions, 1)
+		assert.Equal(t, active.ID, page.Subscriptions[0].ID)
+	})
+
+	t.Run("GetAllForSend visits the active subscribers of a newsletter", func(t *testing.T) {
+		newsletterID := uuid.New()
+		want := []string{subscribe(t, newsletterID, uniqueEmail()).ID, subscribe(t, newsletterID, uniqueEmail()).ID}
+		gone := subscribe(t, newsletterID, uniqueEmail())
+		require.NoError(t, repository.Unsubscribe(ctx, gone.ID))
+		subscribe(t, uuid.New(), uniqueEmail())
+
+		var visited []string
+		err := repository.GetAllForSend(ctx, newsletterID, func(subscription *domain.Subscription) error {
+			visited = append(visited, subscription.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, want, visited)
+
+		stop := errors.New("stop")
+		calls := 0
+		err = repository.GetAllForSend(ctx, newsletterID, func(*domain.Subscription) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("GetAllForSend visits subscriptions without a status", func(t *testing.T) {
+		if legacy == nil {
+			t.Skip("the backend has no subscriptions without a status")
+		}
+		newsletterID := uuid.New()
+		want := []string{legacy(t, newsletterID), subscribe(t, newsletterID, uniqueEmail()).ID}
+
+		var visited []string
+		err := repository.GetAllForSend(ctx, newsletterID, func(subscription *domain.Subscription) error {
+			visited = append(visited, subscription.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, want, visited)
+	})
+
+	t.Run("UpdateAttributes sets and removes attributes", func(t *testing.T) {
+		newsletterID := uuid.New()
+		subscription, err := repository.Subscribe(ctx, &domain.Subscription{
+			NewsletterID: newsletterID,
+			Email:        uniqueEmail(),
+			Attributes:   map[string]string{"first_name": "Ada", "source": "import"},
+		})
+		require.NoError(t, err)
+
+		name, plan := "Grace", "pro"
+		updated, err := repository.UpdateAttributes(ctx, newsletterID, subscription.ID, map[string]*string{"first_name": &name, "plan": &plan, "source": nil})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"first_name": "Grace", "plan": "pro"}, updated.Attributes)
+
+		found, err := repository.GetByToken(ctx, subscription.UnsubscribeToken)
+		require.NoError(t, err)
+		assert.Equal(t, updated.Attributes, found.Attributes)
+	})
+
+	t.Run("UpdateAttributes of another newsletter returns not found", func(t *testing.T) {
+		subscription := subscribe(t, uuid.New(), uniqueEmail())
+		value := "Ada"
+
+		_, err := repository.UpdateAttributes(ctx, uuid.New(), subscription.ID, map[string]*string{"first_name": &value})
+		assert.ErrorIs(t, err, domain.ErrSubscriptionNotFound)
+	})
+
+	t.Run("UnsubscribeEmails records the reason", func(t *testing.T) {
+		newsletterID := uuid.New()
+		bounced := subscribe(t, newsletterID, uniqueEmail())
+		kept := subscribe(t, newsletterID, uniqueEmail())
+		other := subscribe(t, uuid.New(), bounced.Email)
+
+		count, err := repository.UnsubscribeEmails(ctx, newsletterID, []string{bounced.Email, uniqueEmail()}, domain.UnsubscribeReasonBounced)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		found, err := repository.Get(ctx, bounced.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusUnsubscribed, found.Status)
+		assert.Equal(t, domain.UnsubscribeReasonBounced, found.UnsubscribeReason)
+		assert.NotNil(t, found.UnsubscribedAt)
+
+		for _, id := range []string{kept.ID, other.ID} {
+			found, err := repository.Get(ctx, id)
+			require.NoError(t, err)
+			assert.True(t, found.IsActive())
+		}
+
+		count, err = repository.UnsubscribeEmails(ctx, newsletterID, []string{bounced.Email}, domain.UnsubscribeReasonBounced)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("List of a newsletter without subscribers is empty", func(t *testing.T) {
+		page, err := repository.List(ctx, uuid.New(), domain.SubscriberQuery{Limit: 10})
+		require.NoError(t, err)
+		assert.NotNil(t, page.Subscriptions)
+		assert.Empty(t, page.Subscriptions)
+		assert.Empty(t, page.NextCursor)
+	})
+}
+
+// uniqueEmail returns an address no other test uses.
+func uniqueEmail() string {
+	return "contract-" + uuid.NewString() + "@example.com"
+}
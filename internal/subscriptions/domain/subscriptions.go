@@ -2,16 +2,336 @@ package domain
 
 import (
 	"context"
+	"fmt"
+	apperrors "newsletter/internal/errors"
+	"newsletter/internal/infrastructure/pagination"
+	"regexp"
+	"slices"
 	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Subscription statuses. A subscription is never removed from the store on
+// unsubscribe; it transitions to StatusUnsubscribed so its history is kept
+// for analytics and re-subscription protection. An unsubscribed email that
+// subscribes again is StatusPending until it confirms, so that nobody can
+// subscribe someone else's address back.
+const (
+	StatusActive       = "active"
+	StatusPending      = "pending"
+	StatusUnsubscribed = "unsubscribed"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned when no active subscription matches the lookup.
+	ErrSubscriptionNotFound = apperrors.New(apperrors.NotFound, "subscription not found")
+	// ErrInvalidToken is returned when a signed token is malformed or its signature does not match.
+	ErrInvalidToken = apperrors.New(apperrors.Validation, "invalid token")
+	// ErrTokenExpired is returned when a signed unsubscribe or confirm token is authentic but too old.
+	ErrTokenExpired = apperrors.New(apperrors.Unauthorized, "unsubscribe token expired")
+	// ErrCaptchaFailed is returned when a CAPTCHA token is missing or rejected by the provider.
+	ErrCaptchaFailed = apperrors.New(apperrors.Validation, "captcha verification failed")
+	// ErrSubscribeCooldown is returned when the same email subscribed to the same
+	// newsletter too recently.
+	ErrSubscribeCooldown = apperrors.New(apperrors.Conflict, "subscribed too recently, try again later")
+	// ErrInvalidTimezone is returned when a subscription timezone is not an IANA timezone name.
+	ErrInvalidTimezone = apperrors.New(apperrors.Validation, "invalid timezone")
+	// ErrInvalidEmail is returned, wrapped in an EmailError, when a subscriber
+	// address is rejected by the EmailValidator.
+	ErrInvalidEmail = apperrors.New(apperrors.Validation, "invalid email address")
+	// ErrInvalidAttributes is returned when custom subscriber attributes
+	// have an invalid key, a value too long, or are too many.
+	ErrInvalidAttributes = apperrors.New(apperrors.Validation, "invalid subscriber attributes")
+	// ErrEmailSearchUnavailable is returned when searching subscribers by
+	// email prefix while email addresses are encrypted at rest.
+	ErrEmailSearchUnavailable = apperrors.New(apperrors.Validation, "searching by email is unavailable while email addresses are encrypted")
+	// ErrInvalidUnsubscribeReason is returned when a bulk unsubscription has
+	// no reason or one that is not an UnsubscribeReason constant.
+	ErrInvalidUnsubscribeReason = apperrors.New(apperrors.Validation, "invalid unsubscribe reason")
+	// ErrInvalidBulkUnsubscribe is returned when a bulk unsubscription does
+	// not select subscribers with either email addresses or a filter, or
+	// lists too many addresses.
+	ErrInvalidBulkUnsubscribe = apperrors.New(apperrors.Validation, "select subscribers with either emails or a filter")
 )
 
+// Reasons an owner unsubscribes subscribers in bulk for, recorded with the
+// subscriptions.
+const (
+	UnsubscribeReasonBounced   = "bounced"       // The address bounces
+	UnsubscribeReasonComplaint = "complaint"     // The subscriber reported the emails as spam
+	UnsubscribeReasonLegal     = "legal_request" // The subscriber asked to be removed, such as under the GDPR
+	UnsubscribeReasonCleanup   = "cleanup"       // The owner removes inactive or unwanted subscribers
+)
+
+// ValidUnsubscribeReason reports whether reason is an UnsubscribeReason
+// constant.
+func ValidUnsubscribeReason(reason string) bool {
+	switch reason {
+	case UnsubscribeReasonBounced, UnsubscribeReasonComplaint, UnsubscribeReasonLegal, UnsubscribeReasonCleanup:
+		return true
+	}
+	return false
+}
+
+// MaxBulkUnsubscribeEmails caps the email addresses of a bulk unsubscription.
+const MaxBulkUnsubscribeEmails = 1000
+
+// Limits of the custom attributes of a subscription.
+const (
+	MaxAttributes           = 50   // Attributes per subscription
+	MaxAttributeKeyLength   = 64   // Characters of a key
+	MaxAttributeValueLength = 1000 // Characters of a value
+)
+
+// attributeKey matches the keys of custom attributes, such as "first_name".
+var attributeKey = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ValidAttributeKey reports whether key can name a custom attribute: lower
+// case letters, digits and underscores, starting with a letter.
+func ValidAttributeKey(key string) bool {
+	return len(key) <= MaxAttributeKeyLength && attributeKey.MatchString(key)
+}
+
+// ValidateAttributes returns ErrInvalidAttributes if attributes cannot be
+// stored on a subscription.
+func ValidateAttributes(attributes map[string]string) error {
+	if len(attributes) > MaxAttributes {
+		return fmt.Errorf("%w: at most %d attributes are allowed", ErrInvalidAttributes, MaxAttributes)
+	}
+	for key, value := range attributes {
+		if !ValidAttributeKey(key) {
+			return fmt.Errorf("%w: invalid key %q", ErrInvalidAttributes, key)
+		}
+		if utf8.RuneCountInString(value) > MaxAttributeValueLength {
+			return fmt.Errorf("%w: value of %q longer than %d characters", ErrInvalidAttributes, key, MaxAttributeValueLength)
+		}
+	}
+	return nil
+}
+
+// ApplyAttributes sets the attributes of changes on the subscription and
+// removes the ones whose value is nil. It returns ErrInvalidAttributes,
+// leaving the subscription unchanged, if the result is invalid.
+func (s *Subscription) ApplyAttributes(changes map[string]*string) error {
+	attributes := make(map[string]string, len(s.Attributes)+len(changes))
+	for key, value := range s.Attributes {
+		attributes[key] = value
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(attributes, key)
+			continue
+		}
+		attributes[key] = *value
+	}
+	if err := ValidateAttributes(attributes); err != nil {
+		return err
+	}
+
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+	s.Attributes = attributes
+	return nil
+}
+
+// Reasons an EmailValidator rejects an address for.
+const (
+	EmailReasonSyntax     = "syntax"     // Not a plain address such as "user@example.com"
+	EmailReasonNoMX       = "no_mx"      // The domain does not accept email
+	EmailReasonDisposable = "disposable" // The domain is a blocked disposable email provider
+)
+
+// EmailError explains why an address was rejected. It wraps ErrInvalidEmail.
+type EmailError struct {
+	Email  string // Rejected address
+	Reason string // One of the EmailReason constants
+	Detail string // Human readable explanation
+}
+
+func (e *EmailError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidEmail, e.Detail)
+}
+
+func (e *EmailError) Unwrap() error {
+	return ErrInvalidEmail
+}
+
 // Subscription represents a newsletter subscription.
 type Subscription struct {
-	ID               string    `firestore:"-" json:"id"`                       // Firestore document ID
-	NewsletterID     string    `firestore:"newsletterId" json:"newsletter_id"` // Newsletter ID
-	Email            string    `firestore:"email" json:"email"`                // Email of the subscriber
-	UnsubscribeToken string    `firestore:"unsubscribeToken" json:"-"`         // Token to unsubscribe
-	CreatedAt        time.Time `firestore:"createdAt" json:"created_at"`       // Creation time
+	ID               string     `firestore:"-" json:"id"`                                     // Firestore document ID
+	NewsletterID     uuid.UUID  `firestore:"-" json:"newsletter_id"`                          // Newsletter ID, stored as its canonical string
+	Email            string     `firestore:"email" json:"email"`                              // Email of the subscriber
+	UnsubscribeToken string     `firestore:"unsubscribeToken" json:"-"`                       // Random token of the emails sent before unsubscribe tokens were signed
+	Status           string     `firestore:"status" json:"status"`                            // Status of the subscription
+	CreatedAt        time.Time  `firestore:"createdAt" json:"created_at"`                     // Time of the latest consent, or of the re-subscription pending confirmation
+	UnsubscribedAt   *time.Time `firestore:"unsubscribedAt" json:"unsubscribed_at,omitempty"` // Time of unsubscription, if any
+	Tags             []string   `firestore:"tags,omitempty" json:"tags,omitempty"`            // Tags assigned to the subscriber
+	Language         string     `firestore:"language,omitempty" json:"language,omitempty"`    // Language of the emails sent to the subscriber, such as "de"
+	Timezone         string     `firestore:"timezone,omitempty" json:"timezone,omitempty"`    // IANA timezone of the subscriber, such as "America/New_York", for send windows
+	// UnsubscribeReason is why the owner unsubscribed the subscriber, one of
+	// the UnsubscribeReason constants; it is empty when subscribers
+	// unsubscribed themselves.
+	UnsubscribeReason string `firestore:"unsubscribeReason,omitempty" json:"unsubscribe_reason,omitempty"`
+	// Attributes are custom data about the subscriber, such as "first_name"
+	// or "source", used by merge tags and segments.
+	Attributes map[string]string `firestore:"attributes,omitempty" json:"attributes,omitempty"`
+	// History records every time the email subscribed to the newsletter and
+	// was unsubscribed, oldest first, as proof of consent. It is incomplete
+	// for subscriptions created before it was recorded; see ConsentHistory.
+	History []ConsentEvent `firestore:"history,omitempty" json:"history,omitempty"`
+}
+
+// Types of the consent events of the history of a subscription.
+const (
+	ConsentSubscribed   = "subscribed"
+	ConsentUnsubscribed = "unsubscribed"
+)
+
+// ConsentEvent is an entry of the history of a subscription.
+type ConsentEvent struct {
+	Type   string    `firestore:"type" json:"type"`                         // ConsentSubscribed or ConsentUnsubscribed
+	At     time.Time `firestore:"at" json:"at"`                             // Time of the event
+	Reason string    `firestore:"reason,omitempty" json:"reason,omitempty"` // UnsubscribeReason of an unsubscription by the owner
+}
+
+// Unsubscribed returns the consent event of an unsubscription at at, for
+// reason if the owner unsubscribed the subscriber.
+func Unsubscribed(at time.Time, reason string) ConsentEvent {
+	return ConsentEvent{Type: ConsentUnsubscribed, At: at, Reason: reason}
+}
+
+// ConsentHistory returns the history of the subscription. The history of a
+// subscription created before histories were recorded lacks its
+// subscription, timed by CreatedAt, and its unsubscription if that was not
+// recorded either, timed by UnsubscribedAt: both are added back.
+func (s *Subscription) ConsentHistory() []ConsentEvent {
+	if len(s.History) > 0 && s.History[0].Type == ConsentSubscribed {
+		return s.History
+	}
+
+	history := []ConsentEvent{{Type: ConsentSubscribed, At: s.CreatedAt}}
+	if len(s.History) > 0 {
+		return append(history, s.History...)
+	}
+	if s.UnsubscribedAt != nil {
+		history = append(history, Unsubscribed(*s.UnsubscribedAt, s.UnsubscribeReason))
+	}
+	return history
+}
+
+// Period is a time a subscription was active, until To if it ended.
+type Period struct {
+	From time.Time
+	To   *time.Time
+}
+
+// Periods returns the times the subscription was active, from its history,
+// oldest first.
+func (s *Subscription) Periods() []Period {
+	var periods []Period
+	for _, event := range s.ConsentHistory() {
+		switch {
+		case event.Type == ConsentSubscribed && (len(periods) == 0 || periods[len(periods)-1].To != nil):
+			periods = append(periods, Period{From: event.At})
+		case event.Type == ConsentUnsubscribed && len(periods) > 0 && periods[len(periods)-1].To == nil:
+			at := event.At
+			periods[len(periods)-1].To = &at
+		}
+	}
+	return periods
+}
+
+// Resubscribe renews s, an unsubscribed subscription, with next, a new
+// subscription of the same email to the same newsletter, so that an email
+// has a single subscription per newsletter. The renewed subscription is
+// StatusPending until Confirm: it takes the unsubscribe token of next and is
+// timed at now, and its tags and attributes are kept, updated by those of
+// next. It returns ErrInvalidAttributes, leaving s unchanged, if the
+// attributes would be too many.
+func (s *Subscription) Resubscribe(next *Subscription, now time.Time) error {
+	changes := make(map[string]*string, len(next.Attributes))
+	for key, value := range next.Attributes {
+		changes[key] = &value
+	}
+	if err := s.ApplyAttributes(changes); err != nil {
+		return err
+	}
+
+	// The history falls back on CreatedAt, which is about to change.
+	s.History = slices.Clone(s.ConsentHistory())
+	s.Status = StatusPending
+	s.CreatedAt = now
+	s.UnsubscribeToken = next.UnsubscribeToken
+
+	for _, tag := range next.Tags {
+		if !slices.Contains(s.Tags, tag) {
+			s.Tags = append(s.Tags, tag)
+		}
+	}
+	if next.Language != "" {
+		s.Language = next.Language
+	}
+	if next.Timezone != "" {
+		s.Timezone = next.Timezone
+	}
+	return nil
+}
+
+// Confirm activates s, pending since Resubscribe, with the consent given at
+// now through a confirmation link issued at issuedAt, and adds the consent
+// to its history. Confirming an active subscription changes nothing. It
+// returns ErrSubscriptionNotFound if s is unsubscribed or the link was
+// issued before the latest re-subscription.
+func (s *Subscription) Confirm(issuedAt, now time.Time) error {
+	if s.IsActive() {
+		return nil
+	}
+	if s.Status != StatusPending || issuedAt.Before(s.CreatedAt.Truncate(time.Second)) {
+		return ErrSubscriptionNotFound
+	}
+
+	s.History = append(slices.Clone(s.ConsentHistory()), ConsentEvent{Type: ConsentSubscribed, At: now})
+	s.Status = StatusActive
+	s.CreatedAt = now
+	s.UnsubscribedAt = nil
+	s.UnsubscribeReason = ""
+	return nil
+}
+
+// SubscriberFilter narrows a subscriber listing. Zero values disable a filter.
+type SubscriberFilter struct {
+	Status           string    // Only subscriptions with this status
+	Tag              string    // Only subscribers carrying this tag
+	SubscribedAfter  time.Time // Only subscriptions created at or after this time
+	SubscribedBefore time.Time // Only subscriptions created before this time
+	// EmailPrefix only keeps the subscribers whose email address starts with
+	// it (case-sensitive). Matches are listed by email address instead of
+	// newest first, so it cannot be combined with the date range.
+	EmailPrefix string
+}
+
+// SubscriberQuery selects one page of subscribers, newest first.
+type SubscriberQuery struct {
+	Filter SubscriberFilter
+	Limit  int
+	After  *pagination.Cursor // Last item of the previous page, nil for the first page
+}
+
+// SubscriberPage is one page of a subscriber listing.
+type SubscriberPage struct {
+	Subscriptions []*Subscription `json:"subscriptions"`
+	NextCursor    string          `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// IsActive reports whether the subscription should receive emails and appear
+// in listings. Documents written before statuses existed have no status and
+// are treated as active; pending subscriptions are not active until
+// confirmed.
+func (s *Subscription) IsActive() bool {
+	return s.Status == StatusActive || s.Status == ""
 }
 
 // SubscriptionService is an interface that contains a collection of method signatures
@@ -20,13 +340,118 @@ type SubscriptionService interface {
 	// Subscribe adds a new subscription for a newsletter
 	Subscribe(subscription *Subscription) (*Subscription, error)
 
-	// Unsubscribe removes a subscription
+	// UnsubscribeToken returns the token of the unsubscribe links of the
+	// emails sent to a subscription
+	UnsubscribeToken(subscription *Subscription) string
+
+	// GetByToken returns the subscription identified by an unsubscribe token
+	GetByToken(unsubscribeToken string) (*Subscription, error)
+
+	// ConfirmToken returns the token of the link confirming a subscription
+	// pending since it was renewed
+	ConfirmToken(subscription *Subscription) string
+
+	// GetByConfirmToken returns the subscription identified by a confirm token
+	GetByConfirmToken(confirmToken string) (*Subscription, error)
+
+	// Confirm activates the pending subscription identified by a confirm token
+	Confirm(confirmToken string) (*Subscription, error)
+
+	// Unsubscribe marks a subscription as unsubscribed
 	Unsubscribe(unsubscribeToken string) error
+
+	// UnsubscribeAll marks every subscription of the email identified by the
+	// signed global token as unsubscribed
+	UnsubscribeAll(globalToken string) (int, error)
+
+	// GlobalUnsubscribeToken returns a signed token identifying the email
+	// address of a subscription across all newsletters
+	GlobalUnsubscribeToken(subscription *Subscription) (string, error)
+
+	// List returns a page of the subscribers of a newsletter matching filter,
+	// starting after the opaque cursor returned with the previous page
+	List(newsletterID uuid.UUID, filter SubscriberFilter, limit int, cursor string) (*SubscriberPage, error)
+
+	// UpdateAttributes sets or, with a nil value, removes custom attributes
+	// of a subscription of the newsletter
+	UpdateAttributes(newsletterID uuid.UUID, id string, changes map[string]*string) (*Subscription, error)
+
+	// BulkUnsubscribe marks the subscribers of a newsletter with the given
+	// email addresses, or matching filter, as unsubscribed for reason
+	BulkUnsubscribe(newsletterID uuid.UUID, emails []string, filter *SubscriberFilter, reason string) (int, error)
+
+	// CopySubscribers subscribes the active subscribers of a newsletter to
+	// another one, such as a duplicate, and returns their number
+	CopySubscribers(fromNewsletterID, toNewsletterID uuid.UUID) (int, error)
 }
 
 // SubscriptionRepository is an interface that contains a collection of method signatures
 // which will be implemented in persistence level.
 type SubscriptionRepository interface {
+	// Subscribe stores a new active subscription, with a new unsubscribe
+	// token and the consent in its history. An email has one subscription
+	// per newsletter: its active subscription is returned unchanged, and
+	// its latest other one is renewed with Resubscribe, pending
+	// confirmation.
 	Subscribe(ctx context.Context, subscription *Subscription) (*Subscription, error)
-	Unsubscribe(ctx context.Context, unsubscribeToken string) error
+	// SubscribeBatch stores subscriptions as Subscribe does, with far fewer
+	// writes, for imports. It returns the stored subscriptions in the order
+	// of subscriptions; an email listed twice for a newsletter gets a
+	// single subscription.
+	SubscribeBatch(ctx context.Context, subscriptions []*Subscription) ([]*Subscription, error)
+	// GetAllForSend calls fn with every active subscription of the
+	// newsletter, in no particular order, reading them a page at a time so
+	// that memory use does not grow with the number of subscribers. It
+	// stops at the first error of fn, or of ctx, and returns it.
+	GetAllForSend(ctx context.Context, newsletterID uuid.UUID, fn func(*Subscription) error) error
+	// Get returns the subscription id, whatever its status, or
+	// ErrSubscriptionNotFound.
+	Get(ctx context.Context, id string) (*Subscription, error)
+	// GetByToken returns the subscription holding a random unsubscribe
+	// token, whatever its status, or ErrSubscriptionNotFound.
+	GetByToken(ctx context.Context, unsubscribeToken string) (*Subscription, error)
+	// Unsubscribe marks the subscription id as unsubscribed. It returns
+	// ErrSubscriptionNotFound if there is no such active subscription.
+	Unsubscribe(ctx context.Context, id string) error
+	// Confirm activates the subscription id with Subscription.Confirm,
+	// atomically, for a confirmation link issued at issuedAt. It returns
+	// ErrSubscriptionNotFound if there is no such subscription or it cannot
+	// be confirmed.
+	Confirm(ctx context.Context, id string, issuedAt time.Time) (*Subscription, error)
+	UnsubscribeAll(ctx context.Context, email string) (int, error)
+	// LastSubscribedAt returns the creation time of the most recent subscription
+	// of email to the newsletter, or the zero time if there is none.
+	LastSubscribedAt(ctx context.Context, newsletterID uuid.UUID, email string) (time.Time, error)
+	List(ctx context.Context, newsletterID uuid.UUID, query SubscriberQuery) (*SubscriberPage, error)
+	// UpdateAttributes applies changes to the attributes of the subscription
+	// id of the newsletter with Subscription.ApplyAttributes, atomically. It
+	// returns ErrSubscriptionNotFound if the newsletter has no such
+	// subscription.
+	UpdateAttributes(ctx context.Context, newsletterID uuid.UUID, id string, changes map[string]*string) (*Subscription, error)
+	// UnsubscribeEmails marks the active subscriptions of emails to the
+	// newsletter as unsubscribed, recording reason, and returns their
+	// number. Addresses without an active subscription are skipped.
+	UnsubscribeEmails(ctx context.Context, newsletterID uuid.UUID, emails []string, reason string) (int, error)
+}
+
+// NormalizationReport summarizes a pass of the maintenance command
+// rewriting the newsletter IDs of stored subscriptions in canonical form.
+type NormalizationReport struct {
+	Scanned int      `json:"scanned"` // Number of subscriptions read
+	Updated int      `json:"updated"` // Number of newsletter IDs rewritten, or to rewrite on a dry run
+	Invalid []string `json:"invalid"` // IDs of the subscriptions whose newsletter ID is not a UUID
+}
+
+// EmailValidator checks subscriber addresses before they are stored.
+type EmailValidator interface {
+	// Validate returns an *EmailError if email must be rejected. Failures to
+	// check the address, such as DNS timeouts, do not reject it.
+	Validate(ctx context.Context, email string) error
+}
+
+// CaptchaVerifier validates CAPTCHA tokens submitted with public subscribe
+// requests against a provider such as hCaptcha or reCAPTCHA.
+type CaptchaVerifier interface {
+	// Verify returns ErrCaptchaFailed if the token is not valid.
+	Verify(ctx context.Context, token, remoteIP string) error
 }
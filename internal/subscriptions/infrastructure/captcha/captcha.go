@@ -0,0 +1,115 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"newsletter/internal/subscriptions/domain"
+	"strings"
+	"time"
+)
+
+// Verification endpoints of the supported providers. Both implement the same
+// "siteverify" protocol.
+const (
+	HCaptchaURL  = "https://api.hcaptcha.com/siteverify"
+	ReCaptchaURL = "https://www.google.com/recaptcha/api/siteverify"
+)
+
+// Verifier validates CAPTCHA tokens with a siteverify endpoint.
+type Verifier struct {
+	client    *http.Client
+	verifyURL string
+	secret    string
+}
+
+func NewVerifier(verifyURL, secret string) *Verifier {
+	return &Verifier{
+		client:    &http.Client{Timeout: 5 * time.Second},
+		verifyURL: verifyURL,
+		secret:    secret,
+	}
+}
+
+// siteverifyResponse is the subset of the provider response used here.
+type siteverifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify checks token with the provider.
+//
+// Returns:
+//   - domain.ErrCaptchaFailed if the token is empty or rejected
+//   - an error if the provider cannot be reached
+//   - nil if the token is valid
+func (v *Verifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return fmt.Errorf("%w: missing token", domain.ErrCaptchaFailed)
+	}
+
+	form := url.Values{}
+	form.Set("secret", v.secret)
+	form.Set("response", token)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha: provider responded with status %d", resp.StatusCode)
+	}
+
+	var result siteverifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("captcha: %w", err)
+	}
+	if !result.Success {
+		slog.Warn("captcha token rejected", "error_codes", result.ErrorCodes)
+		return fmt.Errorf("%w: %s", domain.ErrCaptchaFailed, strings.Join(result.ErrorCodes, ", "))
+	}
+
+	return nil
+}
+
+// Supported providers.
+const (
+	ProviderHCaptcha  = "hcaptcha"
+	ProviderReCaptcha = "recaptcha"
+)
+
+// Config configures CAPTCHA verification.
+type Config struct {
+	Provider  string // ProviderHCaptcha or ProviderReCaptcha; verification is disabled when empty
+	SecretKey string // Secret key issued by the provider, verifying tokens
+	SiteKey   string // Site key issued by the provider, rendered by the subscribe forms
+}
+
+// NewVerifierFromConfig creates the verifier of the provider of cfg. It
+// returns a nil verifier when CAPTCHA verification is disabled.
+func NewVerifierFromConfig(cfg Config) (domain.CaptchaVerifier, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case ProviderHCaptcha:
+		return NewVerifier(HCaptchaURL, cfg.SecretKey), nil
+	case ProviderReCaptcha:
+		return NewVerifier(ReCaptchaURL, cfg.SecretKey), nil
+	default:
+		return nil, fmt.Errorf("unsupported CAPTCHA provider %q", cfg.Provider)
+	}
+}
@@ -0,0 +1,34 @@
+package captcha_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/subscriptions/domain"
+	"newsletter/internal/subscriptions/infrastructure/captcha"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "secret", r.PostForm.Get("secret"))
+		assert.Equal(t, "192.0.2.1", r.PostForm.Get("remoteip"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("response") == "valid" {
+			w.Write([]byte(`{"success": true}`))
+			return
+		}
+		w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+	}))
+	defer server.Close()
+
+	verifier := captcha.NewVerifier(server.URL, "secret")
+
+	assert.NoError(t, verifier.Verify(context.Background(), "valid", "192.0.2.1"))
+	assert.ErrorIs(t, verifier.Verify(context.Background(), "invalid", "192.0.2.1"), domain.ErrCaptchaFailed)
+	assert.ErrorIs(t, verifier.Verify(context.Background(), "", "192.0.2.1"), domain.ErrCaptchaFailed)
+}
@@ -0,0 +1,193 @@
+package emailcheck
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/mail"
+	"newsletter/internal/subscriptions/domain"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxAddressLength is the longest address that can be delivered (RFC 5321).
+const maxAddressLength = 254
+
+// Resolver looks up the mail exchangers and addresses of a domain. It is
+// implemented by *net.Resolver.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Validator checks the syntax of addresses and, optionally, that their
+// domain accepts email and is not a blocked disposable email provider.
+type Validator struct {
+	resolver  Resolver // nil disables MX lookups
+	cacheTTL  time.Duration
+	blocklist map[string]bool
+
+	mu    sync.Mutex
+	cache map[string]lookup
+}
+
+// lookup is the cached outcome of the MX lookup of a domain.
+type lookup struct {
+	accepts bool
+	expires time.Time
+}
+
+// NewValidator creates a Validator. resolver may be nil to skip MX lookups;
+// their outcome is cached for cacheTTL per domain. blocklist lists the
+// disposable domains to reject, along with their subdomains.
+func NewValidator(resolver Resolver, cacheTTL time.Duration, blocklist []string) *Validator {
+	blocked := make(map[string]bool, len(blocklist))
+	for _, domain := range blocklist {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			blocked[domain] = true
+		}
+	}
+
+	return &Validator{resolver: resolver, cacheTTL: cacheTTL, blocklist: blocked, cache: map[string]lookup{}}
+}
+
+// Config configures a Validator.
+type Config struct {
+	CheckMX    bool          // Reject domains without mail exchanger
+	MXCacheTTL time.Duration // How long the outcome of an MX lookup is cached per domain
+	Blocklist  []string      // Disposable domains rejected, along with their subdomains
+}
+
+// NewValidatorFromConfig creates the Validator configured by cfg, looking up
+// MX records with the default resolver when cfg.CheckMX is set.
+func NewValidatorFromConfig(cfg Config) *Validator {
+	var resolver Resolver
+	if cfg.CheckMX {
+		resolver = net.DefaultResolver
+	}
+	return NewValidator(resolver, cfg.MXCacheTTL, cfg.Blocklist)
+}
+
+// ReadBlocklist reads the domains listed in the file at path, one per line.
+// Empty lines and lines starting with "#" are ignored.
+func ReadBlocklist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			domains = append(domains, line)
+		}
+	}
+	return domains, scanner.Err()
+}
+
+// Validate checks email, returning a *domain.EmailError with the reason it
+// is rejected for:
+//   - domain.EmailReasonSyntax when it is not a plain address
+//   - domain.EmailReasonDisposable when its domain, or a parent domain, is blocked
+//   - domain.EmailReasonNoMX when MX lookups are enabled and the domain has
+//     neither mail exchanger nor address, or a null MX (RFC 7505)
+//
+// Lookups failing for another reason than a missing domain accept the
+// address, so that a DNS outage does not stop subscriptions.
+func (v *Validator) Validate(ctx context.Context, email string) error {
+	reject := func(reason, detail string) error {
+		return &domain.EmailError{Email: email, Reason: reason, Detail: detail}
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" || len(email) > maxAddressLength {
+		return reject(domain.EmailReasonSyntax, "not a valid email address")
+	}
+
+	host := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+	if !strings.Contains(host, ".") {
+		return reject(domain.EmailReasonSyntax, "the domain must be fully qualified")
+	}
+
+	if blocked := v.blocked(host); blocked != "" {
+		return reject(domain.EmailReasonDisposable, blocked+" is a disposable email provider")
+	}
+
+	if v.resolver != nil && !v.acceptsEmail(ctx, host) {
+		return reject(domain.EmailReasonNoMX, host+" does not accept email")
+	}
+
+	return nil
+}
+
+// blocked returns the blocked domain host belongs to, or "".
+func (v *Validator) blocked(host string) string {
+	for {
+		if v.blocklist[host] {
+			return host
+		}
+		dot := strings.IndexByte(host, '.')
+		if dot < 0 {
+			return ""
+		}
+		host = host[dot+1:]
+	}
+}
+
+// acceptsEmail reports whether host has a mail exchanger, or an address
+// used as implicit one (RFC 5321 section 5.1), caching the answer.
+func (v *Validator) acceptsEmail(ctx context.Context, host string) bool {
+	v.mu.Lock()
+	cached, ok := v.cache[host]
+	v.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.accepts
+	}
+
+	accepts, err := v.lookup(ctx, host)
+	if err != nil {
+		slog.Warn("MX lookup failed, accepting address", "domain", host, "error", err)
+		return true
+	}
+
+	v.mu.Lock()
+	v.cache[host] = lookup{accepts: accepts, expires: time.Now().Add(v.cacheTTL)}
+	v.mu.Unlock()
+
+	return accepts
+}
+
+// lookup resolves host. It returns an error only when the answer is unknown.
+func (v *Validator) lookup(ctx context.Context, host string) (bool, error) {
+	records, err := v.resolver.LookupMX(ctx, host)
+	if err == nil && len(records) > 0 {
+		// A single "." exchanger is a null MX: the domain accepts no email.
+		return !(len(records) == 1 && strings.TrimSuffix(records[0].Host, ".") == ""), nil
+	}
+	if err != nil && !notFound(err) {
+		return false, err
+	}
+
+	addresses, err := v.resolver.LookupHost(ctx, host)
+	if err != nil {
+		if notFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(addresses) > 0, nil
+}
+
+// notFound reports whether err is a DNS answer that the name does not exist
+// or has no records of the requested type.
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,130 @@
+package emailcheck_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"newsletter/internal/subscriptions/domain"
+	"newsletter/internal/subscriptions/infrastructure/emailcheck"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver answers from fixed records and counts the MX lookups.
+type fakeResolver struct {
+	mx      map[string][]*net.MX
+	hosts   map[string][]string
+	err     error // returned by every lookup when set
+	lookups int
+}
+
+func (f *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	f.lookups++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if records, ok := f.mx[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (f *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if addresses, ok := f.hosts[host]; ok {
+		return addresses, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// reason returns the reason err rejects an address for, or "".
+func reason(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var emailErr *domain.EmailError
+	require.ErrorAs(t, err, &emailErr)
+	assert.ErrorIs(t, err, domain.ErrInvalidEmail)
+	return emailErr.Reason
+}
+
+func TestValidate_Syntax(t *testing.T) {
+	validator := emailcheck.NewValidator(nil, time.Hour, nil)
+
+	for _, email := range []string{"", "user", "user@", "@example.com", "User <user@example.com>", "user@localhost", "a b@example.com"} {
+		assert.Equal(t, domain.EmailReasonSyntax, reason(t, validator.Validate(context.Background(), email)), email)
+	}
+	assert.NoError(t, validator.Validate(context.Background(), "first.last+tag@example.co.uk"))
+}
+
+func TestValidate_Blocklist(t *testing.T) {
+	validator := emailcheck.NewValidator(nil, time.Hour, []string{" Mailinator.com ", ""})
+
+	assert.Equal(t, domain.EmailReasonDisposable, reason(t, validator.Validate(context.Background(), "user@mailinator.com")))
+	assert.Equal(t, domain.EmailReasonDisposable, reason(t, validator.Validate(context.Background(), "user@eu.MAILINATOR.com")))
+	assert.NoError(t, validator.Validate(context.Background(), "user@notmailinator.com"))
+}
+
+func TestValidate_MX(t *testing.T) {
+	resolver := &fakeResolver{
+		mx: map[string][]*net.MX{
+			"example.com": {{Host: "mx.example.com.", Pref: 10}},
+			"null.test":   {{Host: ".", Pref: 0}},
+		},
+		hosts: map[string][]string{"implicit.test": {"192.0.2.1"}},
+	}
+	validator := emailcheck.NewValidator(resolver, time.Hour, nil)
+
+	assert.NoError(t, validator.Validate(context.Background(), "user@example.com"))
+	assert.NoError(t, validator.Validate(context.Background(), "user@implicit.test"))
+	assert.Equal(t, domain.EmailReasonNoMX, reason(t, validator.Validate(context.Background(), "user@null.test")))
+	assert.Equal(t, domain.EmailReasonNoMX, reason(t, validator.Validate(context.Background(), "user@missing.test")))
+}
+
+func TestValidate_MXCached(t *testing.T) {
+	resolver := &fakeResolver{mx: map[string][]*net.MX{"example.com": {{Host: "mx.example.com."}}}}
+	validator := emailcheck.NewValidator(resolver, time.Hour, nil)
+
+	assert.NoError(t, validator.Validate(context.Background(), "a@example.com"))
+	assert.NoError(t, validator.Validate(context.Background(), "b@EXAMPLE.com"))
+	assert.Equal(t, 1, resolver.lookups)
+}
+
+func TestValidate_LookupFailureAccepts(t *testing.T) {
+	resolver := &fakeResolver{err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}
+	validator := emailcheck.NewValidator(resolver, time.Hour, nil)
+
+	assert.NoError(t, validator.Validate(context.Background(), "user@example.com"))
+	assert.NoError(t, validator.Validate(context.Background(), "user@example.com"))
+	assert.Equal(t, 2, resolver.lookups, "failed lookups must not be cached")
+
+	resolver.err = errors.New("connection refused")
+	assert.NoError(t, validator.Validate(context.Background(), "user@example.com"))
+}
+
+func TestReadBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# disposable providers\n\nyopmail.com\n  trashmail.com  \n"), 0o600))
+
+	domains, err := emailcheck.ReadBlocklist(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"yopmail.com", "trashmail.com"}, domains)
+
+	_, err = emailcheck.ReadBlocklist(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestNewValidatorFromConfig(t *testing.T) {
+	validator := emailcheck.NewValidatorFromConfig(emailcheck.Config{MXCacheTTL: time.Hour, Blocklist: []string{"mailinator.com", " Guerrillamail.com"}})
+
+	assert.Equal(t, domain.EmailReasonDisposable, reason(t, validator.Validate(context.Background(), "user@guerrillamail.com")))
+	assert.NoError(t, validator.Validate(context.Background(), "user@example.com"), "MX not checked")
+}
@@ -3,23 +3,177 @@ package firebase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/infrastructure/secretbox"
 	"newsletter/internal/subscriptions/domain"
+	"slices"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	"github.com/google/uuid"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type SubscriptionRepository struct {
-	db *firestore.Client
+	db  *firestore.Client
+	pii *secretbox.PII // nil stores email addresses in clear
+}
+
+// document is a subscription as stored in the "subscriptions" collection.
+// The newsletter ID is stored as the canonical string form of the UUID,
+// which queries by newsletter compare against.
+//
+// With encryption, Email holds the sealed address and EmailHash its blind
+// index, which lookups by email address compare against.
+type document struct {
+	NewsletterID      string            `firestore:"newsletterId"`
+	Email             string            `firestore:"email"`
+	EmailHash         string            `firestore:"emailHash,omitempty"`
+	UnsubscribeToken  string            `firestore:"unsubscribeToken"`
+	Status            string            `firestore:"status"`
+	CreatedAt         time.Time         `firestore:"createdAt"`
+	UnsubscribedAt    *time.Time        `firestore:"unsubscribedAt"`
+	UnsubscribeReason string            `firestore:"unsubscribeReason,omitempty"`
+	Tags              []string          `firestore:"tags,omitempty"`
+	Language          string            `firestore:"language,omitempty"`
+	Timezone          string            `firestore:"timezone,omitempty"`
+	Attributes        map[string]string `firestore:"attributes,omitempty"`
+
+	// History is appended to with firestore.ArrayUnion on unsubscription.
+	History []domain.ConsentEvent `firestore:"history,omitempty"`
+}
+
+// toDocument returns the stored form of subscription, its email address
+// sealed by pii.
+func toDocument(subscription *domain.Subscription, pii *secretbox.PII) (*document, error) {
+	email, err := pii.Seal(subscription.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	return &document{
+		NewsletterID:      subscription.NewsletterID.String(),
+		Email:             email,
+		EmailHash:         pii.Index(subscription.Email),
+		UnsubscribeToken:  subscription.UnsubscribeToken,
+		Status:            subscription.Status,
+		CreatedAt:         subscription.CreatedAt,
+		UnsubscribedAt:    subscription.UnsubscribedAt,
+		UnsubscribeReason: subscription.UnsubscribeReason,
+		Tags:              subscription.Tags,
+		Language:          subscription.Language,
+		Timezone:          subscription.Timezone,
+		Attributes:        subscription.Attributes,
+		History:           subscription.History,
+	}, nil
+}
+
+// toSubscription returns the subscription of the stored document id.
+// Newsletter IDs that are not UUIDs, written before IDs were validated, are
+// reported as uuid.Nil; they are listed by NormalizeNewsletterIDs.
+func (d *document) toSubscription(id string) *domain.Subscription {
+	newsletterID, err := uuid.Parse(d.NewsletterID)
+	if err != nil {
+		slog.Warn("subscription has an invalid newsletter ID", "subscription_id", id, "newsletter_id", d.NewsletterID)
+	}
+
+	return &domain.Subscription{
+		ID:                id,
+		NewsletterID:      newsletterID,
+		Email:             d.Email,
+		UnsubscribeToken:  d.UnsubscribeToken,
+		Status:            d.Status,
+		CreatedAt:         d.CreatedAt,
+		UnsubscribedAt:    d.UnsubscribedAt,
+		UnsubscribeReason: d.UnsubscribeReason,
+		Tags:              d.Tags,
+		Language:          d.Language,
+		Timezone:          d.Timezone,
+		Attributes:        d.Attributes,
+		History:           d.History,
+	}
+}
+
+// decode returns the subscription stored in doc, its email address opened
+// by pii.
+func decode(doc *firestore.DocumentSnapshot, pii *secretbox.PII) (*domain.Subscription, error) {
+	var stored document
+	if err := doc.DataTo(&stored); err != nil {
+		return nil, err
+	}
+
+	email, err := pii.Open(stored.Email)
+	if err != nil {
+		return nil, fmt.Errorf("subscription %s: decrypt email: %w", doc.Ref.ID, err)
+	}
+	stored.Email = email
+	return stored.toSubscription(doc.Ref.ID), nil
 }
 
 func NewSubscriptionRepository(db *firestore.Client) *SubscriptionRepository {
 	return &SubscriptionRepository{db: db}
 }
 
-// Subscribe persists a new subscription in the database.
+// SetEncryption encrypts the email addresses of the subscriptions written
+// from now on with pii, and looks subscriptions up by their blind index.
+// Addresses stored in clear are still read; EncryptEmails encrypts them.
+//
+// Email addresses cannot be searched by prefix once encrypted: List returns
+// domain.ErrEmailSearchUnavailable instead.
+func (sr *SubscriptionRepository) SetEncryption(pii *secretbox.PII) {
+	sr.pii = pii
+}
+
+// byEmail returns the documents of q whose email address is email, read in
+// tx unless it is nil. With encryption, documents are matched by blind
+// index, and by address for those not yet encrypted.
+func (sr *SubscriptionRepository) byEmail(ctx context.Context, tx *firestore.Transaction, q firestore.Query, email string) ([]*firestore.DocumentSnapshot, error) {
+	documents := func(q firestore.Query) ([]*firestore.DocumentSnapshot, error) {
+		if tx != nil {
+			return tx.Documents(q).GetAll()
+		}
+		return q.Documents(ctx).GetAll()
+	}
+
+	docs, err := documents(q.Where("email", "==", email))
+	if err != nil || sr.pii == nil {
+		return docs, err
+	}
+
+	encrypted, err := documents(q.Where("emailHash", "==", sr.pii.Index(email)))
+	if err != nil {
+		return nil, err
+	}
+	return append(docs, encrypted...), nil
+}
+
+// byEmails returns the documents of q whose email address is one of emails,
+// at most inLimit of them, matched as byEmail does.
+func (sr *SubscriptionRepository) byEmails(ctx context.Context, q firestore.Query, emails []string) ([]*firestore.DocumentSnapshot, error) {
+	docs, err := q.Where("email", "in", emails).Documents(ctx).GetAll()
+	if err != nil || sr.pii == nil {
+		return docs, err
+	}
+
+	hashes := make([]string, len(emails))
+	for i, email := range emails {
+		hashes[i] = sr.pii.Index(email)
+	}
+	encrypted, err := q.Where("emailHash", "in", hashes).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return append(docs, encrypted...), nil
+}
+
+// Subscribe persists a new subscription in the database, or renews the
+// subscription of the email to the newsletter.
 //
 // Parameters:
 //   - ctx: context for managing cancellation and timeouts
@@ -28,59 +182,818 @@ func NewSubscriptionRepository(db *firestore.Client) *SubscriptionRepository {
 //     will be populated by this method.
 //
 // Behavior:
-//   - Generates a new unsubscribe token for the subscription.
-//   - Sets the CreatedAt timestamp to the current time.
-//   - Adds the subscription to the "subscriptions" collection in the database.
-//   - Populates the subscription.ID field with the database-generated document ID.
+//   - Looks up the subscriptions of the email to the newsletter in a
+//     transaction, so that concurrent requests cannot create duplicates.
+//   - Returns the active subscription unchanged, if any.
+//   - Otherwise renews the latest subscription with
+//     domain.Subscription.Resubscribe, which gives it a new unsubscribe
+//     token and leaves it pending confirmation.
+//   - Otherwise adds a new active document to the "subscriptions"
+//     collection, with a new unsubscribe token, the current time and the
+//     consent in its history.
 //
 // Returns:
-//   - pointer to the created Subscription object with ID and unsubscribe token set
-//   - error if the operation fails
+//   - pointer to the stored Subscription object with ID and unsubscribe token set
+//   - domain.ErrInvalidAttributes if the attributes of a renewed
+//     subscription would be too many, or the error of the Firestore operation
 func (sr *SubscriptionRepository) Subscribe(ctx context.Context, subscription *domain.Subscription) (*domain.Subscription, error) {
-	subscription.UnsubscribeToken = uuid.NewString()
-	subscription.CreatedAt = time.Now()
+	collection := sr.db.Collection("subscriptions")
+	q := collection.Where("newsletterId", "==", subscription.NewsletterID.String())
+
+	var stored *domain.Subscription
+	err := sr.db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := sr.byEmail(ctx, tx, q, subscription.Email)
+		if err != nil {
+			return err
+		}
+
+		var latest *domain.Subscription
+		for _, doc := range docs {
+			existing, err := decode(doc, sr.pii)
+			if err != nil {
+				return err
+			}
+			if existing.IsActive() {
+				stored = existing
+				return nil
+			}
+			if latest == nil || existing.CreatedAt.After(latest.CreatedAt) {
+				latest = existing
+			}
+		}
 
-	docRef, _, err := sr.db.Collection("subscriptions").Add(ctx, subscription)
+		next, ref, err := renew(collection, subscription, latest, time.Now())
+		if err != nil {
+			return err
+		}
+		doc, err := toDocument(next, sr.pii)
+		if err != nil {
+			return err
+		}
+		stored = next
+		return tx.Set(ref, doc)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	subscription.ID = docRef.ID
-	return subscription, nil
+	return stored, nil
 }
 
-// Unsubscribe removes a subscription from Firestore based on the unsubscribe token.
-//
-// It searches the "subscriptions" collection for a document whose "unsubscribeToken"
-// field matches the provided token. If a matching document is found, it is deleted.
-//
-// Parameters:
-//   - ctx: Context for controlling cancellation and deadlines for the Firestore operation.
-//   - token: The unique unsubscribe token associated with the subscription to be removed.
+// renew returns the subscription to store for subscription at now, and the
+// document to store it in: latest renewed by Resubscribe, unless it is nil,
+// or a new active subscription.
+func renew(collection *firestore.CollectionRef, subscription, latest *domain.Subscription, now time.Time) (*domain.Subscription, *firestore.DocumentRef, error) {
+	next := *subscription
+	next.UnsubscribeToken = uuid.NewString()
+	ref := collection.NewDoc()
+	if latest != nil {
+		if err := latest.Resubscribe(&next, now); err != nil {
+			return nil, nil, err
+		}
+		next, ref = *latest, collection.Doc(latest.ID)
+	} else {
+		next.Status = domain.StatusActive
+		next.CreatedAt = now
+		next.History = []domain.ConsentEvent{{Type: domain.ConsentSubscribed, At: now}}
+	}
+	next.ID = ref.ID
+	return &next, ref, nil
+}
+
+// SubscribeBatch stores subscriptions as Subscribe does, for imports: the
+// existing subscriptions of their emails are read with a few queries and
+// the subscriptions written with a BulkWriter, instead of a transaction
+// each. An email listed twice for a newsletter gets a single subscription.
 //
-// Returns:
-//   - error: Returns an error if no matching subscription is found, or if the Firestore
-//     operation fails for any reason.
-//
-// Notes:
-//   - This function only deletes the first subscription found with the given token.
-//   - The unsubscribe token should be unique to avoid accidental deletion of multiple subscriptions.
-//   - The Firestore field name used in the query is "unsubscribeToken", matching the struct tag in the Subscription entity.
-func (sr *SubscriptionRepository) Unsubscribe(ctx context.Context, unsubscribeToken string) error {
-	iter := sr.db.
+// It returns the stored subscriptions, in the order of subscriptions. Unlike
+// Subscribe, it does not guard against concurrent subscriptions of the same
+// emails, and the subscriptions written before an error are kept.
+func (sr *SubscriptionRepository) SubscribeBatch(ctx context.Context, subscriptions []*domain.Subscription) ([]*domain.Subscription, error) {
+	collection := sr.db.Collection("subscriptions")
+	now := time.Now()
+
+	byNewsletter := make(map[uuid.UUID][]int)
+	for i, subscription := range subscriptions {
+		byNewsletter[subscription.NewsletterID] = append(byNewsletter[subscription.NewsletterID], i)
+	}
+
+	stored := make([]*domain.Subscription, len(subscriptions))
+	for newsletterID, indexes := range byNewsletter {
+		q := collection.Where("newsletterId", "==", newsletterID.String())
+
+		for batch := range slices.Chunk(indexes, inLimit) {
+			var emails []string
+			for _, index := range batch {
+				if !slices.Contains(emails, subscriptions[index].Email) {
+					emails = append(emails, subscriptions[index].Email)
+				}
+			}
+			docs, err := sr.byEmails(ctx, q, emails)
+			if err != nil {
+				return nil, err
+			}
+
+			// The active subscription of each email, or else its latest one
+			existing := make(map[string]*domain.Subscription, len(docs))
+			for _, doc := range docs {
+				subscription, err := decode(doc, sr.pii)
+				if err != nil {
+					return nil, err
+				}
+				current := existing[subscription.Email]
+				if current == nil || (!current.IsActive() && (subscription.IsActive() || subscription.CreatedAt.After(current.CreatedAt))) {
+					existing[subscription.Email] = subscription
+				}
+			}
+
+			bw := sr.db.BulkWriter(ctx)
+			var jobs []*firestore.BulkWriterJob
+			for _, index := range batch {
+				subscription := subscriptions[index]
+				current := existing[subscription.Email]
+				if current != nil && current.IsActive() {
+					stored[index] = current
+					continue
+				}
+
+				next, ref, err := renew(collection, subscription, current, now)
+				if err != nil {
+					bw.End()
+					return nil, err
+				}
+				doc, err := toDocument(next, sr.pii)
+				if err != nil {
+					bw.End()
+					return nil, err
+				}
+				job, err := bw.Set(ref, doc)
+				if err != nil {
+					bw.End()
+					return nil, err
+				}
+				jobs = append(jobs, job)
+				stored[index], existing[subscription.Email] = next, next
+			}
+			bw.End()
+
+			for _, job := range jobs {
+				if _, err := job.Results(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return stored, nil
+}
+
+// Get returns the subscription id, whatever its status. It returns
+// domain.ErrSubscriptionNotFound if there is none.
+func (sr *SubscriptionRepository) Get(ctx context.Context, id string) (*domain.Subscription, error) {
+	doc, err := sr.db.Collection("subscriptions").Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(doc, sr.pii)
+}
+
+// GetByToken returns the subscription holding unsubscribeToken, whatever its
+// status. It returns domain.ErrSubscriptionNotFound if there is none.
+func (sr *SubscriptionRepository) GetByToken(ctx context.Context, unsubscribeToken string) (*domain.Subscription, error) {
+	doc, err := sr.db.
 		Collection("subscriptions").
 		Where("unsubscribeToken", "==", unsubscribeToken).
 		Limit(1).
-		Documents(ctx)
-
-	doc, err := iter.Next()
+		Documents(ctx).
+		Next()
 	if err != nil {
 		if err == iterator.Done {
-			return errors.New("subscription not found")
+			return nil, domain.ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+
+	return decode(doc, sr.pii)
+}
+
+// Unsubscribe marks the subscription id as unsubscribed.
+//
+// The document is kept for history: its status is set to
+// domain.StatusUnsubscribed and the "unsubscribedAt" timestamp is recorded,
+// in a transaction so that concurrent unsubscriptions record it once.
+//
+// Returns:
+//   - error: Returns domain.ErrSubscriptionNotFound if no matching active subscription
+//     exists, or the underlying error if the Firestore operation fails.
+func (sr *SubscriptionRepository) Unsubscribe(ctx context.Context, id string) error {
+	ref := sr.db.Collection("subscriptions").Doc(id)
+
+	return sr.db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return domain.ErrSubscriptionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		subscription, err := decode(doc, sr.pii)
+		if err != nil {
+			return err
+		}
+		if !subscription.IsActive() {
+			return domain.ErrSubscriptionNotFound
+		}
+
+		now := time.Now()
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: domain.StatusUnsubscribed},
+			{Path: "unsubscribedAt", Value: now},
+			{Path: "history", Value: firestore.ArrayUnion(domain.Unsubscribed(now, ""))},
+		})
+	})
+}
+
+// Confirm activates the subscription id with domain.Subscription.Confirm,
+// for a confirmation link issued at issuedAt, in a transaction so that
+// concurrent confirmations record the consent once.
+//
+// Returns:
+//   - the confirmed subscription
+//   - domain.ErrSubscriptionNotFound if there is no such subscription or it
+//     cannot be confirmed, or the error of the Firestore operation
+func (sr *SubscriptionRepository) Confirm(ctx context.Context, id string, issuedAt time.Time) (*domain.Subscription, error) {
+	ref := sr.db.Collection("subscriptions").Doc(id)
+
+	var confirmed *domain.Subscription
+	err := sr.db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return domain.ErrSubscriptionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		subscription, err := decode(doc, sr.pii)
+		if err != nil {
+			return err
+		}
+		wasActive := subscription.IsActive()
+		if err := subscription.Confirm(issuedAt, time.Now()); err != nil {
+			return err
+		}
+
+		confirmed = subscription
+		if wasActive {
+			return nil
+		}
+		stored, err := toDocument(subscription, sr.pii)
+		if err != nil {
+			return err
+		}
+		return tx.Set(ref, stored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return confirmed, nil
+}
+
+// UnsubscribeAll marks every active subscription of the given email address as
+// unsubscribed, across all newsletters.
+//
+// Matching documents are updated through a Firestore BulkWriter so that
+// subscribers with many subscriptions do not cause sequential round trips.
+//
+// Returns:
+//   - the number of subscriptions that were deactivated
+//   - error if querying or any of the updates fail
+func (sr *SubscriptionRepository) UnsubscribeAll(ctx context.Context, email string) (int, error) {
+	docs, err := sr.byEmail(ctx, nil, sr.db.Collection("subscriptions").Query, email)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	bw := sr.db.BulkWriter(ctx)
+
+	var jobs []*firestore.BulkWriterJob
+	for _, doc := range docs {
+		subscription, err := decode(doc, sr.pii)
+		if err != nil {
+			bw.End()
+			return 0, err
+		}
+		if !subscription.IsActive() {
+			continue
+		}
+
+		job, err := bw.Update(doc.Ref, []firestore.Update{
+			{Path: "status", Value: domain.StatusUnsubscribed},
+			{Path: "unsubscribedAt", Value: now},
+			{Path: "history", Value: firestore.ArrayUnion(domain.Unsubscribed(now, ""))},
+		})
+		if err != nil {
+			bw.End()
+			return 0, err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(jobs), nil
+}
+
+// inLimit is the number of values Firestore compares a field with in one
+// "in" filter.
+const inLimit = 30
+
+// sendPageSize is the number of subscriptions GetAllForSend reads at once.
+const sendPageSize = 500
+
+// UnsubscribeEmails marks the active subscriptions of emails to the
+// newsletter as unsubscribed, recording reason, and returns their number.
+//
+// Subscriptions are looked up with "in" filters of up to inLimit addresses,
+// by blind index too with encryption, and updated through a Firestore
+// BulkWriter a batch at a time.
+func (sr *SubscriptionRepository) UnsubscribeEmails(ctx context.Context, newsletterID uuid.UUID, emails []string, reason string) (int, error) {
+	q := sr.db.Collection("subscriptions").Where("newsletterId", "==", newsletterID.String())
+	now := time.Now()
+
+	var count int
+	for batch := range slices.Chunk(emails, inLimit) {
+		docs, err := sr.byEmails(ctx, q, batch)
+		if err != nil {
+			return count, err
+		}
+
+		bw := sr.db.BulkWriter(ctx)
+		var jobs []*firestore.BulkWriterJob
+		for _, doc := range docs {
+			subscription, err := decode(doc, sr.pii)
+			if err != nil {
+				bw.End()
+				return count, err
+			}
+			if !subscription.IsActive() {
+				continue
+			}
+
+			job, err := bw.Update(doc.Ref, []firestore.Update{
+				{Path: "status", Value: domain.StatusUnsubscribed},
+				{Path: "unsubscribedAt", Value: now},
+				{Path: "unsubscribeReason", Value: reason},
+				{Path: "history", Value: firestore.ArrayUnion(domain.Unsubscribed(now, reason))},
+			})
+			if err != nil {
+				bw.End()
+				return count, err
+			}
+			jobs = append(jobs, job)
+		}
+		bw.End()
+
+		for _, job := range jobs {
+			if _, err := job.Results(); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// LastSubscribedAt returns the creation time of the most recent subscription of
+// email to the newsletter, regardless of its status, or the zero time if the
+// email never subscribed.
+//
+// The latest document is picked in memory: an email has few subscriptions to a
+// single newsletter and ordering in the query would require a composite index.
+func (sr *SubscriptionRepository) LastSubscribedAt(ctx context.Context, newsletterID uuid.UUID, email string) (time.Time, error) {
+	docs, err := sr.byEmail(ctx, nil, sr.db.Collection("subscriptions").Where("newsletterId", "==", newsletterID.String()), email)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var last time.Time
+	for _, doc := range docs {
+		subscription, err := decode(doc, sr.pii)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if subscription.CreatedAt.After(last) {
+			last = subscription.CreatedAt
+		}
+	}
+
+	return last, nil
+}
+
+// List returns a page of the subscriptions of a newsletter, newest first, or
+// by email address when searching by email prefix.
+//
+// Subscriptions are ordered by creation time (or email) and document ID, so
+// that pages are stable and can be resumed with StartAfter from the cursor of
+// the last item. One more document than requested is fetched to detect the
+// last page.
+//
+// Filtering by status only matches documents that have a status field;
+// documents written before statuses existed are treated as active elsewhere
+// but are not returned when filtering on "active".
+//
+// The query combinations require the composite indexes declared in
+// firestore.indexes.json. Searching by email prefix returns
+// domain.ErrEmailSearchUnavailable when email addresses are encrypted.
+func (sr *SubscriptionRepository) List(ctx context.Context, newsletterID uuid.UUID, query domain.SubscriberQuery) (*domain.SubscriberPage, error) {
+	if query.Filter.EmailPrefix != "" && sr.pii != nil {
+		return nil, domain.ErrEmailSearchUnavailable
+	}
+
+	q := sr.db.Collection("subscriptions").Where("newsletterId", "==", newsletterID.String())
+
+	filter := query.Filter
+	if filter.Status != "" {
+		q = q.Where("status", "==", filter.Status)
+	}
+	if filter.Tag != "" {
+		q = q.Where("tags", "array-contains", filter.Tag)
+	}
+	if !filter.SubscribedAfter.IsZero() {
+		q = q.Where("createdAt", ">=", filter.SubscribedAfter)
+	}
+	if !filter.SubscribedBefore.IsZero() {
+		q = q.Where("createdAt", "<", filter.SubscribedBefore)
+	}
+
+	if filter.EmailPrefix != "" {
+		// Firestore has no prefix operator: a prefix is the range of the
+		// strings between it and it followed by the highest code point.
+		q = q.Where("email", ">=", filter.EmailPrefix).Where("email", "<", filter.EmailPrefix+"\uf8ff")
+		q = q.OrderBy("email", firestore.Asc).OrderBy(firestore.DocumentID, firestore.Asc)
+		if query.After != nil {
+			q = q.StartAfter(query.After.Key, query.After.ID)
+		}
+	} else {
+		q = q.OrderBy("createdAt", firestore.Desc).OrderBy(firestore.DocumentID, firestore.Desc)
+		if query.After != nil {
+			q = q.StartAfter(query.After.CreatedAt, query.After.ID)
 		}
-		return err
 	}
 
-	_, err = doc.Ref.Delete(ctx)
-	return err
+	docs, err := q.Limit(query.Limit + 1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.SubscriberPage{Subscriptions: []*domain.Subscription{}}
+	for i, doc := range docs {
+		if i == query.Limit {
+			last := page.Subscriptions[len(page.Subscriptions)-1]
+			cursor := pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+			if filter.EmailPrefix != "" {
+				cursor = pagination.Cursor{Key: last.Email, ID: last.ID}
+			}
+			page.NextCursor = cursor.Encode()
+			break
+		}
+
+		subscription, err := decode(doc, sr.pii)
+		if err != nil {
+			return nil, err
+		}
+		page.Subscriptions = append(page.Subscriptions, subscription)
+	}
+
+	return page, nil
+}
+
+// GetAllForSend calls fn with the active subscriptions of the newsletter,
+// sendPageSize at a time. Pages are ordered by document ID, so that the
+// query needs no composite index and a page starts after the last document
+// of the previous one.
+//
+// Unsubscribed documents are skipped here rather than by the query, as
+// documents written before statuses existed have no status field and would
+// not match one; like CountActive, they are active.
+func (sr *SubscriptionRepository) GetAllForSend(ctx context.Context, newsletterID uuid.UUID, fn func(*domain.Subscription) error) error {
+	q := sr.db.Collection("subscriptions").
+		Where("newsletterId", "==", newsletterID.String()).
+		OrderBy(firestore.DocumentID, firestore.Asc).
+		Limit(sendPageSize)
+
+	page := q
+	for {
+		docs, err := page.Documents(ctx).GetAll()
+		if err != nil {
+			return err
+		}
+
+		for _, doc := range docs {
+			subscription, err := decode(doc, sr.pii)
+			if err != nil {
+				return err
+			}
+			if !subscription.IsActive() {
+				continue
+			}
+			if err := fn(subscription); err != nil {
+				return err
+			}
+		}
+
+		if len(docs) < sendPageSize {
+			return nil
+		}
+		page = q.StartAfter(docs[len(docs)-1])
+	}
+}
+
+// UpdateAttributes applies changes to the attributes of the subscription id
+// of the newsletter in a transaction, so that concurrent updates of other
+// attributes are not lost and the number of attributes stays within
+// domain.MaxAttributes. It returns domain.ErrSubscriptionNotFound if the
+// document does not exist or belongs to another newsletter.
+func (sr *SubscriptionRepository) UpdateAttributes(ctx context.Context, newsletterID uuid.UUID, id string, changes map[string]*string) (*domain.Subscription, error) {
+	ref := sr.db.Collection("subscriptions").Doc(id)
+
+	var updated *domain.Subscription
+	err := sr.db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return domain.ErrSubscriptionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		subscription, err := decode(doc, sr.pii)
+		if err != nil {
+			return err
+		}
+		if subscription.NewsletterID != newsletterID {
+			return domain.ErrSubscriptionNotFound
+		}
+		if err := subscription.ApplyAttributes(changes); err != nil {
+			return err
+		}
+
+		updated = subscription
+		if subscription.Attributes == nil {
+			return tx.Update(ref, []firestore.Update{{Path: "attributes", Value: firestore.Delete}})
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "attributes", Value: subscription.Attributes}})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// NormalizeNewsletterIDs rewrites the newsletter IDs of the stored
+// subscriptions in canonical form (lowercase, hyphenated), so that queries
+// by newsletter find them. IDs written in another form accepted by
+// uuid.Parse, such as uppercase or braced, are rewritten; IDs that are not
+// UUIDs are reported and left unchanged. With dryRun, nothing is written.
+//
+// Documents are read in pages and rewritten through a BulkWriter, so that
+// the command can run against large collections.
+func (sr *SubscriptionRepository) NormalizeNewsletterIDs(ctx context.Context, dryRun bool) (*domain.NormalizationReport, error) {
+	report := &domain.NormalizationReport{Invalid: []string{}}
+
+	var bw *firestore.BulkWriter
+	if !dryRun {
+		bw = sr.db.BulkWriter(ctx)
+		defer bw.End()
+	}
+
+	var jobs []*firestore.BulkWriterJob
+	var last *firestore.DocumentSnapshot
+	for {
+		q := sr.db.Collection("subscriptions").Select("newsletterId").OrderBy(firestore.DocumentID, firestore.Asc).Limit(normalizePageSize)
+		if last != nil {
+			q = q.StartAfter(last)
+		}
+		docs, err := q.Documents(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range docs {
+			report.Scanned++
+
+			stored, _ := doc.Data()["newsletterId"].(string)
+			id, err := uuid.Parse(stored)
+			if err != nil {
+				report.Invalid = append(report.Invalid, doc.Ref.ID)
+				continue
+			}
+			if id.String() == stored {
+				continue
+			}
+
+			report.Updated++
+			if dryRun {
+				continue
+			}
+			job, err := bw.Update(doc.Ref, []firestore.Update{{Path: "newsletterId", Value: id.String()}})
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, job)
+		}
+
+		if len(docs) < normalizePageSize {
+			break
+		}
+		last = docs[len(docs)-1]
+	}
+
+	if bw != nil {
+		bw.End()
+	}
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// normalizePageSize is the number of documents read per page by
+// NormalizeNewsletterIDs and EncryptEmails.
+const normalizePageSize = 500
+
+// EncryptEmails encrypts the email addresses of the subscriptions stored in
+// clear, such as those written before SetEncryption was called, and records
+// their blind index. Encrypted addresses are left unchanged. With dryRun,
+// nothing is written. It returns the number of documents scanned and of
+// documents encrypted, or to encrypt.
+//
+// Documents are read in pages and rewritten through a BulkWriter, so that
+// the command can run against large collections.
+func (sr *SubscriptionRepository) EncryptEmails(ctx context.Context, dryRun bool) (scanned, encrypted int, err error) {
+	if sr.pii == nil {
+		return 0, 0, errors.New("encryption is not configured")
+	}
+
+	var bw *firestore.BulkWriter
+	if !dryRun {
+		bw = sr.db.BulkWriter(ctx)
+		defer bw.End()
+	}
+
+	var jobs []*firestore.BulkWriterJob
+	var last *firestore.DocumentSnapshot
+	for {
+		q := sr.db.Collection("subscriptions").Select("email").OrderBy(firestore.DocumentID, firestore.Asc).Limit(normalizePageSize)
+		if last != nil {
+			q = q.StartAfter(last)
+		}
+		docs, err := q.Documents(ctx).GetAll()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		for _, doc := range docs {
+			scanned++
+
+			email, _ := doc.Data()["email"].(string)
+			if email == "" || secretbox.Sealed(email) {
+				continue
+			}
+
+			encrypted++
+			if dryRun {
+				continue
+			}
+			sealed, err := sr.pii.Seal(email)
+			if err != nil {
+				return 0, 0, err
+			}
+			job, err := bw.Update(doc.Ref, []firestore.Update{
+				{Path: "email", Value: sealed},
+				{Path: "emailHash", Value: sr.pii.Index(email)},
+			})
+			if err != nil {
+				return 0, 0, err
+			}
+			jobs = append(jobs, job)
+		}
+
+		if len(docs) < normalizePageSize {
+			break
+		}
+		last = docs[len(docs)-1]
+	}
+
+	if bw != nil {
+		bw.End()
+	}
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return scanned, encrypted, nil
+}
+
+// inactiveStatuses are the statuses of the subscriptions that are not
+// counted as active.
+var inactiveStatuses = []string{domain.StatusUnsubscribed, domain.StatusPending}
+
+// countConcurrency is the number of newsletters counted at the same time by
+// CountActive.
+const countConcurrency = 10
+
+// CountActive returns the number of active subscribers of each newsletter.
+//
+// Each count is the number of subscriptions minus the unsubscribed and
+// pending ones, computed with aggregation queries so that no document is
+// read. Counting this way includes the documents written before statuses
+// existed, which are active.
+func (sr *SubscriptionRepository) CountActive(ctx context.Context, newsletterIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	counts := make(map[uuid.UUID]int, len(newsletterIDs))
+
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, countConcurrency)
+
+	for _, id := range newsletterIDs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			q := sr.db.Collection("subscriptions").Where("newsletterId", "==", id.String())
+			total, err := count(ctx, q)
+			if err == nil {
+				var inactive int
+				inactive, err = count(ctx, q.Where("status", "in", inactiveStatuses))
+				total -= inactive
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			counts[id] = total
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return counts, nil
+}
+
+// CountAllActive returns the number of subscriptions of every newsletter
+// that are neither unsubscribed nor pending.
+func (sr *SubscriptionRepository) CountAllActive(ctx context.Context) (int, error) {
+	q := sr.db.Collection("subscriptions").Query
+	total, err := count(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	inactive, err := count(ctx, q.Where("status", "in", inactiveStatuses))
+	if err != nil {
+		return 0, err
+	}
+	return total - inactive, nil
+}
+
+// count returns the number of documents matching q.
+func count(ctx context.Context, q firestore.Query) (int, error) {
+	result, err := q.NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := result["count"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count result %T", result["count"])
+	}
+	return int(value.GetIntegerValue()), nil
 }
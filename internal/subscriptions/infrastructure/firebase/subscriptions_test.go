@@ -0,0 +1,68 @@
+package firebase
+
+import (
+	"bytes"
+	"newsletter/internal/infrastructure/secretbox"
+	"newsletter/internal/subscriptions/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocument_RoundTrip(t *testing.T) {
+	unsubscribedAt := time.Now()
+	subscription := &domain.Subscription{
+		ID:               "sub-1",
+		NewsletterID:     uuid.New(),
+		Email:            "reader@example.com",
+		UnsubscribeToken: "token-1",
+		Status:           domain.StatusUnsubscribed,
+		CreatedAt:        time.Now().Add(-time.Hour),
+		UnsubscribedAt:   &unsubscribedAt,
+		Tags:             []string{"vip"},
+		Language:         "de",
+		Timezone:         "Europe/Berlin",
+		Attributes:       map[string]string{"first_name": "Ada"},
+		History: []domain.ConsentEvent{
+			{Type: domain.ConsentSubscribed, At: time.Now().Add(-time.Hour)},
+			domain.Unsubscribed(unsubscribedAt, ""),
+		},
+	}
+
+	stored, err := toDocument(subscription, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, subscription.NewsletterID.String(), stored.NewsletterID)
+	assert.Equal(t, "reader@example.com", stored.Email)
+	assert.Empty(t, stored.EmailHash)
+	assert.Equal(t, subscription, stored.toSubscription("sub-1"))
+}
+
+func TestDocument_EncryptedEmail(t *testing.T) {
+	pii, err := secretbox.NewPII(bytes.Repeat([]byte{7}, secretbox.KeySize))
+	require.NoError(t, err)
+	subscription := &domain.Subscription{NewsletterID: uuid.New(), Email: "reader@example.com"}
+
+	stored, err := toDocument(subscription, pii)
+
+	require.NoError(t, err)
+	assert.True(t, secretbox.Sealed(stored.Email))
+	assert.Equal(t, pii.Index("reader@example.com"), stored.EmailHash)
+
+	email, err := pii.Open(stored.Email)
+	require.NoError(t, err)
+	assert.Equal(t, "reader@example.com", email)
+}
+
+func TestDocument_LegacyNewsletterIDs(t *testing.T) {
+	id := uuid.MustParse("7b0c6a4e-3f0d-4f7a-9a39-2f1d5c8e6b21")
+
+	upper := &document{NewsletterID: "7B0C6A4E-3F0D-4F7A-9A39-2F1D5C8E6B21"}
+	assert.Equal(t, id, upper.toSubscription("sub-1").NewsletterID)
+
+	invalid := &document{NewsletterID: "news-1"}
+	assert.Equal(t, uuid.Nil, invalid.toSubscription("sub-2").NewsletterID)
+}
@@ -0,0 +1,425 @@
+// Package memory stores subscriptions in memory, for local development
+// without Firestore. Everything is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"maps"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/subscriptions/domain"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	activitydomain "newsletter/internal/activity/domain"
+	analyticsdomain "newsletter/internal/analytics/domain"
+)
+
+// SubscriptionRepository implements domain.SubscriptionRepository in memory.
+// It also counts subscribers and provides the subscription spans of the
+// analytics and the subscriber events of the activity feed, which the
+// Firestore backend does with separate repositories.
+type SubscriptionRepository struct {
+	mu            sync.RWMutex
+	subscriptions []*domain.Subscription
+}
+
+func NewSubscriptionRepository() *SubscriptionRepository {
+	return &SubscriptionRepository{}
+}
+
+// clone returns a copy of subscription that callers may modify.
+func clone(subscription *domain.Subscription) *domain.Subscription {
+	copied := *subscription
+	copied.Tags = slices.Clone(subscription.Tags)
+	copied.Attributes = maps.Clone(subscription.Attributes)
+	copied.History = slices.Clone(subscription.History)
+	if subscription.UnsubscribedAt != nil {
+		unsubscribedAt := *subscription.UnsubscribedAt
+		copied.UnsubscribedAt = &unsubscribedAt
+	}
+	return &copied
+}
+
+// byToken returns the stored subscription holding unsubscribeToken, or nil.
+// The caller holds mu.
+func (sr *SubscriptionRepository) byToken(unsubscribeToken string) *domain.Subscription {
+	for _, subscription := range sr.subscriptions {
+		if subscription.UnsubscribeToken == unsubscribeToken {
+			return subscription
+		}
+	}
+	return nil
+}
+
+// byID returns the stored subscription id, or nil. The caller holds mu.
+func (sr *SubscriptionRepository) byID(id string) *domain.Subscription {
+	for _, subscription := range sr.subscriptions {
+		if subscription.ID == id {
+			return subscription
+		}
+	}
+	return nil
+}
+
+// Subscribe stores a new active subscription with a new ID and unsubscribe
+// token, or returns the active subscription of the email to the newsletter,
+// or renews its latest one, pending confirmation.
+func (sr *SubscriptionRepository) Subscribe(ctx context.Context, subscription *domain.Subscription) (*domain.Subscription, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	now := time.Now()
+	subscription.UnsubscribeToken = uuid.NewString()
+
+	var latest *domain.Subscription
+	for _, stored := range sr.subscriptions {
+		if stored.NewsletterID == subscription.NewsletterID && stored.Email == subscription.Email &&
+			(latest == nil || stored.CreatedAt.After(latest.CreatedAt)) {
+			latest = stored
+		}
+	}
+	if latest != nil {
+		if !latest.IsActive() {
+			renewed := clone(latest)
+			if err := renewed.Resubscribe(subscription, now); err != nil {
+				return nil, err
+			}
+			*latest = *renewed
+		}
+		return clone(latest), nil
+	}
+
+	subscription.ID = uuid.NewString()
+	subscription.Status = domain.StatusActive
+	subscription.CreatedAt = now
+	subscription.History = []domain.ConsentEvent{{Type: domain.ConsentSubscribed, At: now}}
+	sr.subscriptions = append(sr.subscriptions, clone(subscription))
+
+	return subscription, nil
+}
+
+// SubscribeBatch stores subscriptions one by one with Subscribe, leaving
+// them unchanged, and returns the stored subscriptions in their order.
+func (sr *SubscriptionRepository) SubscribeBatch(ctx context.Context, subscriptions []*domain.Subscription) ([]*domain.Subscription, error) {
+	stored := make([]*domain.Subscription, len(subscriptions))
+	for i, subscription := range subscriptions {
+		next := *subscription
+		var err error
+		if stored[i], err = sr.Subscribe(ctx, &next); err != nil {
+			return nil, err
+		}
+	}
+	return stored, nil
+}
+
+// GetAllForSend calls fn with copies of the active subscriptions of the
+// newsletter, in the order they were created. The subscriptions are copied
+// first, so that fn may use the repository.
+func (sr *SubscriptionRepository) GetAllForSend(ctx context.Context, newsletterID uuid.UUID, fn func(*domain.Subscription) error) error {
+	sr.mu.RLock()
+	var active []*domain.Subscription
+	for _, subscription := range sr.subscriptions {
+		if subscription.NewsletterID == newsletterID && subscription.IsActive() {
+			active = append(active, clone(subscription))
+		}
+	}
+	sr.mu.RUnlock()
+
+	for _, subscription := range active {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(subscription); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the subscription id, whatever its status. It returns
+// domain.ErrSubscriptionNotFound if there is none.
+func (sr *SubscriptionRepository) Get(ctx context.Context, id string) (*domain.Subscription, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	subscription := sr.byID(id)
+	if subscription == nil {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	return clone(subscription), nil
+}
+
+// GetByToken returns the subscription holding unsubscribeToken, whatever its
+// status. It returns domain.ErrSubscriptionNotFound if there is none.
+func (sr *SubscriptionRepository) GetByToken(ctx context.Context, unsubscribeToken string) (*domain.Subscription, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	subscription := sr.byToken(unsubscribeToken)
+	if subscription == nil {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	return clone(subscription), nil
+}
+
+// Unsubscribe marks the subscription id as unsubscribed. It returns
+// domain.ErrSubscriptionNotFound if there is no such active subscription.
+func (sr *SubscriptionRepository) Unsubscribe(ctx context.Context, id string) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	subscription := sr.byID(id)
+	if subscription == nil || !subscription.IsActive() {
+		return domain.ErrSubscriptionNotFound
+	}
+
+	now := time.Now()
+	subscription.Status = domain.StatusUnsubscribed
+	subscription.UnsubscribedAt = &now
+	subscription.History = append(subscription.History, domain.Unsubscribed(now, ""))
+	return nil
+}
+
+// Confirm activates the subscription id with Subscription.Confirm for a
+// confirmation link issued at issuedAt. It returns
+// domain.ErrSubscriptionNotFound if there is none or it cannot be confirmed.
+func (sr *SubscriptionRepository) Confirm(ctx context.Context, id string, issuedAt time.Time) (*domain.Subscription, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	subscription := sr.byID(id)
+	if subscription == nil {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	confirmed := clone(subscription)
+	if err := confirmed.Confirm(issuedAt, time.Now()); err != nil {
+		return nil, err
+	}
+	*subscription = *confirmed
+	return clone(subscription), nil
+}
+
+// UnsubscribeAll marks every active subscription of email as unsubscribed,
+// across all newsletters, and returns their number.
+func (sr *SubscriptionRepository) UnsubscribeAll(ctx context.Context, email string) (int, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	now := time.Now()
+	var count int
+	for _, subscription := range sr.subscriptions {
+		if subscription.Email == email && subscription.IsActive() {
+			subscription.Status = domain.StatusUnsubscribed
+			subscription.UnsubscribedAt = &now
+			subscription.History = append(subscription.History, domain.Unsubscribed(now, ""))
+			count++
+		}
+	}
+	return count, nil
+}
+
+// UnsubscribeEmails marks the active subscriptions of emails to the
+// newsletter as unsubscribed for reason, and returns their number.
+func (sr *SubscriptionRepository) UnsubscribeEmails(ctx context.Context, newsletterID uuid.UUID, emails []string, reason string) (int, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	now := time.Now()
+	var count int
+	for _, subscription := range sr.subscriptions {
+		if subscription.NewsletterID == newsletterID && subscription.IsActive() && slices.Contains(emails, subscription.Email) {
+			subscription.Status = domain.StatusUnsubscribed
+			subscription.UnsubscribedAt = &now
+			subscription.UnsubscribeReason = reason
+			subscription.History = append(subscription.History, domain.Unsubscribed(now, reason))
+			count++
+		}
+	}
+	return count, nil
+}
+
+// LastSubscribedAt returns the creation time of the most recent subscription
+// of email to the newsletter, or the zero time if there is none.
+func (sr *SubscriptionRepository) LastSubscribedAt(ctx context.Context, newsletterID uuid.UUID, email string) (time.Time, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	var last time.Time
+	for _, subscription := range sr.subscriptions {
+		if subscription.NewsletterID == newsletterID && subscription.Email == email && subscription.CreatedAt.After(last) {
+			last = subscription.CreatedAt
+		}
+	}
+	return last, nil
+}
+
+// List returns a page of the subscriptions of a newsletter in the order of
+// the Firestore repository: newest first, or by email address when
+// searching by email prefix, ties being broken by ID.
+func (sr *SubscriptionRepository) List(ctx context.Context, newsletterID uuid.UUID, query domain.SubscriberQuery) (*domain.SubscriberPage, error) {
+	filter := query.Filter
+	byEmail := filter.EmailPrefix != ""
+
+	sr.mu.RLock()
+	var matches []*domain.Subscription
+	for _, subscription := range sr.subscriptions {
+		switch {
+		case subscription.NewsletterID != newsletterID:
+		case filter.Status != "" && subscription.Status != filter.Status:
+		case filter.Tag != "" && !slices.Contains(subscription.Tags, filter.Tag):
+		case !filter.SubscribedAfter.IsZero() && subscription.CreatedAt.Before(filter.SubscribedAfter):
+		case !filter.SubscribedBefore.IsZero() && !subscription.CreatedAt.Before(filter.SubscribedBefore):
+		case !strings.HasPrefix(subscription.Email, filter.EmailPrefix):
+		default:
+			matches = append(matches, clone(subscription))
+		}
+	}
+	sr.mu.RUnlock()
+
+	slices.SortFunc(matches, func(a, b *domain.Subscription) int {
+		if byEmail {
+			return compare(a.Email, a.ID, b.Email, b.ID)
+		}
+		return -compareTime(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+
+	if after := query.After; after != nil {
+		matches = slices.DeleteFunc(matches, func(subscription *domain.Subscription) bool {
+			if byEmail {
+				return compare(subscription.Email, subscription.ID, after.Key, after.ID) <= 0
+			}
+			return compareTime(subscription.CreatedAt, subscription.ID, after.CreatedAt, after.ID) >= 0
+		})
+	}
+
+	page := &domain.SubscriberPage{Subscriptions: []*domain.Subscription{}}
+	if len(matches) > query.Limit {
+		matches = matches[:query.Limit]
+		last := matches[len(matches)-1]
+		cursor := pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		if byEmail {
+			cursor = pagination.Cursor{Key: last.Email, ID: last.ID}
+		}
+		page.NextCursor = cursor.Encode()
+	}
+	page.Subscriptions = append(page.Subscriptions, matches...)
+
+	return page, nil
+}
+
+// UpdateAttributes applies changes to the attributes of the subscription id
+// of the newsletter. It returns domain.ErrSubscriptionNotFound if there is
+// no such subscription.
+func (sr *SubscriptionRepository) UpdateAttributes(ctx context.Context, newsletterID uuid.UUID, id string, changes map[string]*string) (*domain.Subscription, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	for _, subscription := range sr.subscriptions {
+		if subscription.ID != id || subscription.NewsletterID != newsletterID {
+			continue
+		}
+		if err := subscription.ApplyAttributes(changes); err != nil {
+			return nil, err
+		}
+		return clone(subscription), nil
+	}
+	return nil, domain.ErrSubscriptionNotFound
+}
+
+// compare orders subscriptions by key, then ID.
+func compare(keyA, idA, keyB, idB string) int {
+	if result := strings.Compare(keyA, keyB); result != 0 {
+		return result
+	}
+	return strings.Compare(idA, idB)
+}
+
+// compareTime orders subscriptions by time, then ID.
+func compareTime(timeA time.Time, idA string, timeB time.Time, idB string) int {
+	if result := timeA.Compare(timeB); result != 0 {
+		return result
+	}
+	return strings.Compare(idA, idB)
+}
+
+// CountActive returns the number of active subscribers of each newsletter.
+func (sr *SubscriptionRepository) CountActive(ctx context.Context, newsletterIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	counts := make(map[uuid.UUID]int, len(newsletterIDs))
+	for _, id := range newsletterIDs {
+		counts[id] = 0
+	}
+	for _, subscription := range sr.subscriptions {
+		if _, ok := counts[subscription.NewsletterID]; ok && subscription.IsActive() {
+			counts[subscription.NewsletterID]++
+		}
+	}
+	return counts, nil
+}
+
+// CountAllActive returns the number of active subscriptions of every
+// newsletter.
+func (sr *SubscriptionRepository) CountAllActive(ctx context.Context) (int, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	var count int
+	for _, subscription := range sr.subscriptions {
+		if subscription.IsActive() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Spans returns every period the subscriptions of a newsletter were active.
+func (sr *SubscriptionRepository) Spans(ctx context.Context, newsletterID uuid.UUID) ([]analyticsdomain.Span, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	spans := []analyticsdomain.Span{}
+	for _, subscription := range sr.subscriptions {
+		if subscription.NewsletterID == newsletterID {
+			for _, period := range clone(subscription).Periods() {
+				spans = append(spans, analyticsdomain.Span{SubscribedAt: period.From, UnsubscribedAt: period.To})
+			}
+		}
+	}
+	return spans, nil
+}
+
+// Events returns the subscriptions and unsubscriptions of a newsletter
+// following after, in feed order, as the Firestore event repository does.
+func (sr *SubscriptionRepository) Events(ctx context.Context, newsletterID uuid.UUID, after *pagination.Cursor, limit int) ([]*activitydomain.Event, error) {
+	sr.mu.RLock()
+	var events []*activitydomain.Event
+	for _, subscription := range sr.subscriptions {
+		if subscription.NewsletterID != newsletterID {
+			continue
+		}
+		events = append(events, &activitydomain.Event{
+			Type: activitydomain.EventSubscribed, ID: subscription.ID, OccurredAt: subscription.CreatedAt, Email: subscription.Email,
+		})
+		if subscription.UnsubscribedAt != nil {
+			events = append(events, &activitydomain.Event{
+				Type: activitydomain.EventUnsubscribed, ID: subscription.ID, OccurredAt: *subscription.UnsubscribedAt, Email: subscription.Email,
+				Detail: subscription.UnsubscribeReason,
+			})
+		}
+	}
+	sr.mu.RUnlock()
+
+	if after != nil {
+		last := &activitydomain.Event{Type: after.Key, ID: after.ID, OccurredAt: after.CreatedAt}
+		events = slices.DeleteFunc(events, func(event *activitydomain.Event) bool {
+			return !activitydomain.Before(last, event)
+		})
+	}
+	return activitydomain.Merge(limit, events), nil
+}
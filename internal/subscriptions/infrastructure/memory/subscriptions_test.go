@@ -0,0 +1,11 @@
+package memory_test
+
+import (
+	"newsletter/internal/subscriptions/domain/domaintest"
+	"newsletter/internal/subscriptions/infrastructure/memory"
+	"testing"
+)
+
+func TestSubscriptionRepositoryContract(t *testing.T) {
+	domaintest.SubscriptionRepositoryContract(t, memory.NewSubscriptionRepository(), nil)
+}
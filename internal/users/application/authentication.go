@@ -0,0 +1,151 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"newsletter/internal/users/domain"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultAccessTokenTTL is the lifetime of access tokens when TokenConfig
+// does not set one.
+const DefaultAccessTokenTTL = 15 * time.Minute
+
+// TokenConfig configures the signing of access tokens.
+type TokenConfig struct {
+	Keys *domain.Keyset // Keys signing the access tokens, with the current one
+	TTL  time.Duration  // Lifetime of the access tokens, DefaultAccessTokenTTL when zero
+}
+
+// AuthenticationService signs users in: it verifies their credentials and
+// issues their access tokens.
+type AuthenticationService struct {
+	ur     domain.UserRepository
+	ph     domain.PasswordHasher
+	tokens TokenConfig
+}
+
+// NewAuthenticationService creates an AuthenticationService looking users
+// up in ur, verifying their passwords with ph and signing access tokens as
+// tokens configures.
+func NewAuthenticationService(ur domain.UserRepository, ph domain.PasswordHasher, tokens TokenConfig) *AuthenticationService {
+	if tokens.TTL <= 0 {
+		tokens.TTL = DefaultAccessTokenTTL
+	}
+	return &AuthenticationService{ur: ur, ph: ph, tokens: tokens}
+}
+
+// Authenticate verifies a user's credentials by email and password.
+//
+// It returns the authenticated user if credentials are valid, or
+// domain.ErrInvalidCredentials if no account has the email or the password
+// does not match.
+// When the stored hash uses an outdated algorithm or cost, it is replaced by
+// a fresh hash of the password; a failure to do so does not fail the sign in.
+func (as *AuthenticationService) Authenticate(email, password string) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	user, err := as.ur.Get(ctx, email)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		slog.Warn("sign in attempt for unknown account", "email", email)
+		return nil, domain.ErrInvalidCredentials
+	}
+	if err != nil {
+		slog.Error("failed to find user",
+			"email", email,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	match, err := as.ph.Verify(user.Password, password)
+	if err != nil {
+		slog.Error("failed to verify password",
+			"email", email,
+			"error", err,
+		)
+		return nil, err
+	}
+	if !match {
+		slog.Warn("invalid password attempt",
+			"email", email,
+		)
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if as.ph.NeedsRehash(user.Password) {
+		as.rehash(ctx, user, password)
+	}
+
+	slog.Info("user authenticated successfully",
+		"user_id", user.ID.String(),
+		"email", user.Email,
+	)
+
+	return user, nil
+}
+
+// rehash replaces the stored password hash of user with one produced by the
+// current hasher configuration.
+func (as *AuthenticationService) rehash(ctx context.Context, user *domain.User, password string) {
+	hash, err := as.ph.Hash(password)
+	if err != nil {
+		slog.Error("failed to rehash password", "user_id", user.ID.String(), "error", err)
+		return
+	}
+
+	if err := as.ur.UpdatePassword(ctx, user.ID, hash); err != nil {
+		slog.Error("failed to store rehashed password", "user_id", user.ID.String(), "error", err)
+		return
+	}
+
+	user.Password = hash
+	slog.Info("password rehashed", "user_id", user.ID.String())
+}
+
+// GenerateAccessToken generates a JWT access token for an authenticated user.
+// The token is short-lived (TokenConfig.TTL, 15 minutes by default) and
+// includes the user's email, ID and
+// the scopes it grants. Tokens issued to account owners carry all scopes,
+// and administrators are also granted domain.ScopeAdmin.
+func (as *AuthenticationService) GenerateAccessToken(user *domain.User) (string, error) {
+	slog.Info("generating access token",
+		"user_id",
+		user.ID.String(),
+		"email", user.Email,
+	)
+
+	secret := as.tokens.Keys.Current()
+	if secret == "" {
+		slog.Error("JWT secret key not set", "user_id", user.ID.String())
+		return "", errors.New("JWT secret key is missing")
+	}
+
+	claims := &domain.Claims{
+		Email:  user.Email,
+		Scopes: domain.ScopesFor(user),
+		RegisteredClaims: &jwt.RegisteredClaims{
+			Subject:   user.ID.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(as.tokens.TTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	access := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	accessToken, err := access.SignedString([]byte(secret))
+	if err != nil {
+		slog.Error("failed to sign access token", "user_id", user.ID.String(), "error", err)
+		return "", err
+	}
+
+	slog.Info("access token generated successfully",
+		"user_id", user.ID.String(),
+	)
+
+	return accessToken, nil
+}
@@ -0,0 +1,150 @@
+package application
+
+import (
+	"newsletter/internal/users/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ------------------- Authenticate -------------------
+
+func TestAuthenticationService_Authenticate_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	as := NewAuthenticationService(mockRepo, newTestHasher(t), TokenConfig{Keys: domain.NewKeyset("secret123")})
+
+	password := "password123"
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	storedUser := &domain.User{ID: uuid.New(), Email: "test@example.com", Password: string(hashed)}
+
+	mockRepo.On("Get", mock.Anything, "test@example.com").Return(storedUser, nil)
+
+	user, err := as.Authenticate("test@example.com", password)
+
+	assert.NoError(t, err)
+	assert.Equal(t, storedUser.ID, user.ID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthenticationService_Authenticate_WrongPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	as := NewAuthenticationService(mockRepo, newTestHasher(t), TokenConfig{Keys: domain.NewKeyset("secret123")})
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("correct"), bcrypt.DefaultCost)
+	storedUser := &domain.User{ID: uuid.New(), Email: "test@example.com", Password: string(hashed)}
+
+	mockRepo.On("Get", mock.Anything, "test@example.com").Return(storedUser, nil)
+
+	user, err := as.Authenticate("test@example.com", "wrongpass")
+
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	assert.Nil(t, user)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthenticationService_Authenticate_RehashesLegacyHash(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	ph, err := NewPasswordHasher(AlgorithmArgon2id, bcrypt.DefaultCost, testArgon2Params)
+	assert.NoError(t, err)
+	as := NewAuthenticationService(mockRepo, ph, TokenConfig{Keys: domain.NewKeyset("secret123")})
+
+	password := "password123"
+	legacy, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	storedUser := &domain.User{ID: uuid.New(), Email: "test@example.com", Password: string(legacy)}
+
+	mockRepo.On("Get", mock.Anything, "test@example.com").Return(storedUser, nil)
+	mockRepo.On("UpdatePassword", mock.Anything, storedUser.ID, mock.MatchedBy(func(hash string) bool {
+		return strings.HasPrefix(hash, "$argon2id$")
+	})).Return(nil)
+
+	user, err := as.Authenticate("test@example.com", password)
+
+	assert.NoError(t, err)
+	assert.Equal(t, storedUser.ID, user.ID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthenticationService_Authenticate_UserNotFound(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	as := NewAuthenticationService(mockRepo, newTestHasher(t), TokenConfig{Keys: domain.NewKeyset("secret123")})
+
+	mockRepo.On("Get", mock.Anything, "missing@example.com").Return((*domain.User)(nil), domain.ErrUserNotFound)
+
+	user, err := as.Authenticate("missing@example.com", "any")
+
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	assert.Nil(t, user)
+	mockRepo.AssertExpectations(t)
+}
+
+// ------------------- GenerateAccessToken -------------------
+
+func TestAuthenticationService_GenerateAccessToken_Success(t *testing.T) {
+	as := NewAuthenticationService(nil, nil, TokenConfig{Keys: domain.NewKeyset("secret123")})
+	user := &domain.User{
+		ID:    uuid.New(),
+		Email: "test@example.com",
+	}
+
+	token, err := as.GenerateAccessToken(user)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	claims := &domain.Claims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte("secret123"), nil
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, domain.AllScopes, claims.Scopes)
+	assert.WithinDuration(t, time.Now().Add(DefaultAccessTokenTTL), claims.ExpiresAt.Time, time.Minute)
+}
+
+func TestAuthenticationService_GenerateAccessToken_TTL(t *testing.T) {
+	as := NewAuthenticationService(nil, nil, TokenConfig{Keys: domain.NewKeyset("secret123"), TTL: time.Hour})
+
+	token, err := as.GenerateAccessToken(&domain.User{ID: uuid.New(), Email: "test@example.com"})
+	assert.NoError(t, err)
+
+	claims := &domain.Claims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte("secret123"), nil
+	})
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt.Time, time.Minute)
+}
+
+func TestAuthenticationService_GenerateAccessToken_Admin(t *testing.T) {
+	as := NewAuthenticationService(nil, nil, TokenConfig{Keys: domain.NewKeyset("secret123")})
+	user := &domain.User{ID: uuid.New(), Email: "admin@example.com", Role: domain.RoleAdmin}
+
+	token, err := as.GenerateAccessToken(user)
+	assert.NoError(t, err)
+
+	claims := &domain.Claims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return []byte("secret123"), nil
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, claims.Scopes, domain.ScopeAdmin)
+	assert.NotContains(t, domain.AllScopes, domain.ScopeAdmin)
+}
+
+func TestAuthenticationService_GenerateAccessToken_Failure(t *testing.T) {
+	as := NewAuthenticationService(nil, nil, TokenConfig{Keys: domain.NewKeyset("")}) // no signing secret
+	user := &domain.User{
+		ID:    uuid.Nil, // invalid ID still works, but we'll test secret missing
+		Email: "test@example.com",
+	}
+
+	token, err := as.GenerateAccessToken(user)
+
+	assert.Error(t, err)
+	assert.Equal(t, "", token)
+}
@@ -0,0 +1,78 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"newsletter/internal/users/domain"
+	"time"
+)
+
+// MagicLinkService issues the single-use tokens of passwordless sign in
+// links and exchanges them for the account they belong to.
+type MagicLinkService struct {
+	ur domain.UserRepository
+	lr domain.LoginTokenRepository
+}
+
+func NewMagicLinkService(ur domain.UserRepository, lr domain.LoginTokenRepository) *MagicLinkService {
+	return &MagicLinkService{ur: ur, lr: lr}
+}
+
+// hashLoginToken returns the hex encoded SHA-256 of token, as stored.
+func hashLoginToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue creates a random login token valid for domain.LoginTokenTTL for the
+// account of email. The token is returned to be sent to the account owner;
+// only its hash is stored.
+func (ms *MagicLinkService) Issue(email string) (string, *domain.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	user, err := ms.ur.Get(ctx, email)
+	if err != nil {
+		slog.Warn("magic link requested for unknown account", "email", email, "error", err)
+		return "", nil, err
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+
+	if err := ms.lr.Create(ctx, user.ID, hashLoginToken(token), time.Now().Add(domain.LoginTokenTTL)); err != nil {
+		slog.Error("failed to store login token", "user_id", user.ID.String(), "error", err)
+		return "", nil, err
+	}
+
+	user.Password = ""
+	return token, user, nil
+}
+
+// Redeem consumes token and returns the account it signs in. Tokens work
+// once: a second attempt fails with domain.ErrInvalidLoginToken, like
+// unknown and expired tokens.
+func (ms *MagicLinkService) Redeem(token string) (*domain.User, error) {
+	if token == "" {
+		return nil, domain.ErrInvalidLoginToken
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	user, err := ms.lr.Consume(ctx, hashLoginToken(token), time.Now())
+	if err != nil {
+		slog.Warn("failed to redeem login token", "error", err)
+		return nil, err
+	}
+
+	slog.Info("user authenticated with magic link", "user_id", user.ID.String(), "email", user.Email)
+	return user, nil
+}
@@ -0,0 +1,99 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/users/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLoginTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginTokenRepository) Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockLoginTokenRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (*domain.User, error) {
+	args := m.Called(ctx, tokenHash, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func TestMagicLinkService_Issue_StoresTokenHash(t *testing.T) {
+	mockUsers := new(MockUserRepository)
+	mockTokens := new(MockLoginTokenRepository)
+	ms := NewMagicLinkService(mockUsers, mockTokens)
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", Password: "hash"}
+	mockUsers.On("Get", mock.Anything, "test@example.com").Return(user, nil)
+
+	var storedHash string
+	var expiresAt time.Time
+	mockTokens.On("Create", mock.Anything, user.ID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			storedHash = args.String(2)
+			expiresAt = args.Get(3).(time.Time)
+		}).
+		Return(nil)
+
+	token, issuedTo, err := ms.Issue("test@example.com")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, hashLoginToken(token), storedHash)
+	assert.NotEqual(t, token, storedHash)
+	assert.WithinDuration(t, time.Now().Add(domain.LoginTokenTTL), expiresAt, time.Minute)
+	assert.Equal(t, user.ID, issuedTo.ID)
+	assert.Empty(t, issuedTo.Password)
+	mockTokens.AssertExpectations(t)
+}
+
+func TestMagicLinkService_Issue_UnknownAccount(t *testing.T) {
+	mockUsers := new(MockUserRepository)
+	mockTokens := new(MockLoginTokenRepository)
+	ms := NewMagicLinkService(mockUsers, mockTokens)
+
+	mockUsers.On("Get", mock.Anything, "missing@example.com").Return((*domain.User)(nil), errors.New("not found"))
+
+	token, user, err := ms.Issue("missing@example.com")
+
+	assert.Error(t, err)
+	assert.Empty(t, token)
+	assert.Nil(t, user)
+	mockTokens.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMagicLinkService_Redeem(t *testing.T) {
+	mockTokens := new(MockLoginTokenRepository)
+	ms := NewMagicLinkService(new(MockUserRepository), mockTokens)
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com"}
+	mockTokens.On("Consume", mock.Anything, hashLoginToken("token"), mock.Anything).Return(user, nil)
+
+	redeemed, err := ms.Redeem("token")
+
+	assert.NoError(t, err)
+	assert.Equal(t, user, redeemed)
+	mockTokens.AssertExpectations(t)
+}
+
+func TestMagicLinkService_Redeem_EmptyToken(t *testing.T) {
+	mockTokens := new(MockLoginTokenRepository)
+	ms := NewMagicLinkService(new(MockUserRepository), mockTokens)
+
+	user, err := ms.Redeem("")
+
+	assert.ErrorIs(t, err, domain.ErrInvalidLoginToken)
+	assert.Nil(t, user)
+	mockTokens.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything, mock.Anything)
+}
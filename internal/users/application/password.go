@@ -0,0 +1,165 @@
+package application
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported password hashing algorithms.
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Argon2Params configures the argon2id key derivation.
+type Argon2Params struct {
+	Memory      uint32 // memory in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follows the OWASP recommendation for argon2id.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var errInvalidHash = errors.New("invalid password hash")
+
+// PasswordHasher hashes passwords with the configured algorithm and verifies
+// passwords against hashes produced by any supported algorithm, so that the
+// algorithm or its cost can be changed without invalidating existing accounts.
+type PasswordHasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     Argon2Params
+}
+
+// NewPasswordHasher creates a PasswordHasher producing hashes with algorithm.
+func NewPasswordHasher(algorithm string, bcryptCost int, argon2Params Argon2Params) (*PasswordHasher, error) {
+	switch algorithm {
+	case AlgorithmBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case AlgorithmArgon2id:
+		if argon2Params.Memory == 0 || argon2Params.Iterations == 0 || argon2Params.Parallelism == 0 {
+			return nil, errors.New("argon2id memory, iterations and parallelism must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hashing algorithm %q", algorithm)
+	}
+
+	return &PasswordHasher{algorithm: algorithm, bcryptCost: bcryptCost, argon2: argon2Params}, nil
+}
+
+// Hash returns the hash of password using the configured algorithm.
+func (ph *PasswordHasher) Hash(password string) (string, error) {
+	if ph.algorithm == AlgorithmArgon2id {
+		return ph.hashArgon2id(password)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), ph.bcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify reports whether password matches hash. The algorithm is detected
+// from the hash, so hashes produced with a previous configuration still verify.
+func (ph *PasswordHasher) Verify(hash, password string) (bool, error) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, candidate) == 1, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NeedsRehash reports whether hash was produced with another algorithm or
+// other parameters than the configured ones.
+func (ph *PasswordHasher) NeedsRehash(hash string) bool {
+	if ph.algorithm == AlgorithmArgon2id {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return params.Memory != ph.argon2.Memory ||
+			params.Iterations != ph.argon2.Iterations ||
+			params.Parallelism != ph.argon2.Parallelism ||
+			uint32(len(salt)) != ph.argon2.SaltLength ||
+			uint32(len(key)) != ph.argon2.KeyLength
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != ph.bcryptCost
+}
+
+// hashArgon2id hashes password into the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func (ph *PasswordHasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, ph.argon2.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, ph.argon2.Iterations, ph.argon2.Memory, ph.argon2.Parallelism, ph.argon2.KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		ph.argon2.Memory, ph.argon2.Iterations, ph.argon2.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// decodeArgon2id parses a hash produced by hashArgon2id.
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, errInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, errInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errInvalidHash
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,63 @@
+package application
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Params keeps argon2id fast in tests.
+var testArgon2Params = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHasher_Argon2id(t *testing.T) {
+	ph, err := NewPasswordHasher(AlgorithmArgon2id, bcrypt.DefaultCost, testArgon2Params)
+	assert.NoError(t, err)
+
+	hash, err := ph.Hash("password123")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	match, err := ph.Verify(hash, "password123")
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	match, err = ph.Verify(hash, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, match)
+
+	assert.False(t, ph.NeedsRehash(hash))
+}
+
+func TestPasswordHasher_VerifiesOtherAlgorithms(t *testing.T) {
+	bcryptHasher, _ := NewPasswordHasher(AlgorithmBcrypt, bcrypt.MinCost, testArgon2Params)
+	argonHasher, _ := NewPasswordHasher(AlgorithmArgon2id, bcrypt.MinCost, testArgon2Params)
+
+	legacy, err := bcryptHasher.Hash("password123")
+	assert.NoError(t, err)
+
+	match, err := argonHasher.Verify(legacy, "password123")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.True(t, argonHasher.NeedsRehash(legacy))
+}
+
+func TestPasswordHasher_NeedsRehashOnCostChange(t *testing.T) {
+	cheap, _ := NewPasswordHasher(AlgorithmBcrypt, bcrypt.MinCost, testArgon2Params)
+	stronger, _ := NewPasswordHasher(AlgorithmBcrypt, bcrypt.MinCost+1, testArgon2Params)
+
+	hash, err := cheap.Hash("password123")
+	assert.NoError(t, err)
+
+	assert.False(t, cheap.NeedsRehash(hash))
+	assert.True(t, stronger.NeedsRehash(hash))
+}
+
+func TestNewPasswordHasher_Invalid(t *testing.T) {
+	_, err := NewPasswordHasher("md5", bcrypt.DefaultCost, testArgon2Params)
+	assert.Error(t, err)
+
+	_, err = NewPasswordHasher(AlgorithmBcrypt, bcrypt.MaxCost+1, testArgon2Params)
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package application
+
+import (
+	"context"
+	"log/slog"
+	"newsletter/internal/users/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecurityEventService records account activity such as sign ups and sign
+// ins, and lets users review it.
+type SecurityEventService struct {
+	sr domain.SecurityEventRepository
+}
+
+func NewSecurityEventService(sr domain.SecurityEventRepository) *SecurityEventService {
+	return &SecurityEventService{sr: sr}
+}
+
+// Record stores a security event.
+//
+// Recording is best effort: a failure is logged but never returned, so that
+// an unavailable audit log does not prevent users from signing in.
+func (ss *SecurityEventService) Record(event *domain.SecurityEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	if err := ss.sr.Create(ctx, event); err != nil {
+		slog.Error(
+			"failed to record security event",
+			"type", event.Type,
+			"email", event.Email,
+			"error", err,
+		)
+	}
+}
+
+// List returns the most recent security events of a user, newest first.
+func (ss *SecurityEventService) List(userID uuid.UUID, limit int) ([]*domain.SecurityEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	events, err := ss.sr.List(ctx, userID, limit)
+	if err != nil {
+		slog.Error(
+			"failed to list security events",
+			"user_id", userID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,55 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/users/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSecurityEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockSecurityEventRepository) Create(ctx context.Context, event *domain.SecurityEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockSecurityEventRepository) List(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.SecurityEvent, error) {
+	args := m.Called(ctx, userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.SecurityEvent), args.Error(1)
+}
+
+func TestSecurityEventService_Record_IgnoresFailures(t *testing.T) {
+	mockRepo := new(MockSecurityEventRepository)
+	ss := NewSecurityEventService(mockRepo)
+
+	event := &domain.SecurityEvent{Email: "test@example.com", Type: domain.SecurityEventSigninFailed}
+	mockRepo.On("Create", mock.Anything, event).Return(errors.New("db down"))
+
+	assert.NotPanics(t, func() { ss.Record(event) })
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSecurityEventService_List(t *testing.T) {
+	mockRepo := new(MockSecurityEventRepository)
+	ss := NewSecurityEventService(mockRepo)
+
+	userID := uuid.New()
+	events := []*domain.SecurityEvent{{ID: uuid.New(), UserID: userID, Type: domain.SecurityEventSignup}}
+	mockRepo.On("List", mock.Anything, userID, 10).Return(events, nil)
+
+	result, err := ss.List(userID, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, events, result)
+	mockRepo.AssertExpectations(t)
+}
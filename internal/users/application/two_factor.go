@@ -0,0 +1,424 @@
+package application
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"newsletter/internal/infrastructure/abuse"
+	"newsletter/internal/users/domain"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// totpIssuer names the service in authenticator apps.
+	totpIssuer = "Newsletter"
+	// totpPeriod is the validity of a TOTP code, as expected by authenticator apps.
+	totpPeriod = 30 * time.Second
+	// totpDigits is the length of TOTP codes.
+	totpDigits = 6
+	// totpSkew is the number of periods before and after the current one
+	// whose codes are accepted, to tolerate clock drift.
+	totpSkew = 1
+	// recoveryCodeCount is the number of recovery codes generated on enrollment.
+	recoveryCodeCount = 10
+	// challengeTTL is how long the second step of a sign in may take.
+	challengeTTL = 5 * time.Minute
+	// maxCodeFailures is the number of invalid codes tolerated for an
+	// account, or a challenge, within challengeTTL before codeLockout.
+	maxCodeFailures = 5
+	// codeLockout is how long codes are refused after maxCodeFailures.
+	codeLockout = 15 * time.Minute
+)
+
+// base32NoPadding encodes TOTP secrets and recovery codes.
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorService implements TOTP two-factor authentication (RFC 6238)
+// with recovery codes. TOTP secrets are stored encrypted by cipher and
+// recovery codes hashed.
+type TwoFactorService struct {
+	tr     domain.TwoFactorRepository
+	cipher domain.SecretCipher
+	keys   *domain.Keyset // Keys signing sign in challenges
+
+	failures *abuse.Detector // Invalid codes of accounts and challenges
+}
+
+// NewTwoFactorService creates a TwoFactorService signing sign in challenges
+// with keys. cipher may be nil when no encryption key is configured, in
+// which case every operation fails with domain.ErrTwoFactorNotConfigured.
+//
+// After maxCodeFailures invalid codes for an account or a challenge, codes
+// are refused with domain.ErrTwoFactorLocked for codeLockout, so that the
+// 6 digits of TOTP codes cannot be guessed. Failures are counted in memory,
+// per instance.
+func NewTwoFactorService(tr domain.TwoFactorRepository, cipher domain.SecretCipher, keys *domain.Keyset) *TwoFactorService {
+	return &TwoFactorService{
+		tr: tr, cipher: cipher, keys: keys,
+		failures: abuse.NewDetector(abuse.Limits{Threshold: maxCodeFailures, Window: challengeTTL, Block: codeLockout}),
+	}
+}
+
+// Enroll generates a TOTP secret and recovery codes for an account, replacing
+// any pending enrollment. Accounts with two-factor authentication enabled
+// must disable it first (domain.ErrTwoFactorEnabled).
+func (ts *TwoFactorService) Enroll(userID uuid.UUID, email string) (*domain.TwoFactorEnrollment, error) {
+	if ts.cipher == nil {
+		return nil, domain.ErrTwoFactorNotConfigured
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, current, err := ts.tr.Get(ctx, userID)
+	if err != nil {
+		slog.Error("failed to get two-factor state", "user_id", userID, "error", err)
+		return nil, err
+	}
+	if current.Enabled {
+		return nil, domain.ErrTwoFactorEnabled
+	}
+
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	encrypted, err := ts.cipher.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err := ts.tr.Save(ctx, userID, &domain.TwoFactor{Secret: encrypted, RecoveryCodes: hashes}); err != nil {
+		slog.Error("failed to save two-factor enrollment", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	encoded := base32NoPadding.EncodeToString(secret)
+	return &domain.TwoFactorEnrollment{
+		Secret:          encoded,
+		ProvisioningURI: provisioningURI(email, encoded),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+// Verify enables two-factor authentication of an enrolled account when code
+// is its current TOTP code.
+func (ts *TwoFactorService) Verify(userID uuid.UUID, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, current, err := ts.load(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if current.Enabled {
+		return domain.ErrTwoFactorEnabled
+	}
+
+	var counter int64
+	if err := ts.attempt(func() (err error) {
+		counter, err = ts.checkTOTP(current, code)
+		return err
+	}, userKey(userID)); err != nil {
+		return err
+	}
+
+	current.Enabled = true
+	current.LastCounter = counter
+	if err := ts.tr.Save(ctx, userID, current); err != nil {
+		slog.Error("failed to enable two-factor authentication", "user_id", userID, "error", err)
+		return err
+	}
+
+	slog.Info("two-factor authentication enabled", "user_id", userID)
+	return nil
+}
+
+// Disable turns two-factor authentication off and forgets the TOTP secret
+// and recovery codes, when code is a TOTP or recovery code of the account.
+func (ts *TwoFactorService) Disable(userID uuid.UUID, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, current, err := ts.load(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !current.Enabled {
+		return domain.ErrTwoFactorNotEnrolled
+	}
+
+	if err := ts.attempt(func() error {
+		return ts.checkCode(ctx, userID, current, code)
+	}, userKey(userID)); err != nil {
+		return err
+	}
+
+	if err := ts.tr.Save(ctx, userID, &domain.TwoFactor{}); err != nil {
+		slog.Error("failed to disable two-factor authentication", "user_id", userID, "error", err)
+		return err
+	}
+
+	slog.Info("two-factor authentication disabled", "user_id", userID)
+	return nil
+}
+
+// Challenge returns a token, valid for 5 minutes, proving that user passed
+// the password or magic link step of a sign in. It is not an access token:
+// it is only accepted by Complete.
+func (ts *TwoFactorService) Challenge(user *domain.User) (string, error) {
+	if ts.cipher == nil {
+		return "", domain.ErrTwoFactorNotConfigured
+	}
+
+	payload := user.ID.String() + "." + strconv.FormatInt(time.Now().Add(challengeTTL).Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(sign(ts.keys.Current(), payload)), nil
+}
+
+// Complete finishes the sign in of challenge when code is a TOTP or recovery
+// code of the account. Codes only work once: a TOTP code is rejected once it,
+// or a later one, was accepted.
+func (ts *TwoFactorService) Complete(challenge, code string) (*domain.User, error) {
+	userID, err := ts.parseChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	user, current, err := ts.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !current.Enabled {
+		return nil, domain.ErrInvalidChallenge
+	}
+
+	if err := ts.attempt(func() error {
+		return ts.checkCode(ctx, userID, current, code)
+	}, userKey(userID), "challenge:"+challenge); err != nil {
+		slog.Warn("invalid two-factor code", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	slog.Info("user completed two-factor authentication", "user_id", userID)
+	return user, nil
+}
+
+// load returns an account and its two-factor state, failing with
+// domain.ErrTwoFactorNotEnrolled when it has no TOTP secret.
+func (ts *TwoFactorService) load(ctx context.Context, userID uuid.UUID) (*domain.User, *domain.TwoFactor, error) {
+	if ts.cipher == nil {
+		return nil, nil, domain.ErrTwoFactorNotConfigured
+	}
+
+	user, current, err := ts.tr.Get(ctx, userID)
+	if err != nil {
+		slog.Error("failed to get two-factor state", "user_id", userID, "error", err)
+		return nil, nil, err
+	}
+	if current.Secret == nil {
+		return nil, nil, domain.ErrTwoFactorNotEnrolled
+	}
+	return user, current, nil
+}
+
+// userKey identifies an account among the clients of the failure detector.
+func userKey(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+// attempt runs check, which verifies a code, unless any of keys is locked
+// out, and records a failure of keys when the code is invalid.
+func (ts *TwoFactorService) attempt(check func() error, keys ...string) error {
+	if _, locked := ts.failures.Blocked(time.Now(), keys...); locked {
+		return domain.ErrTwoFactorLocked
+	}
+
+	err := check()
+	if errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		for _, key := range ts.failures.Fail(time.Now(), keys...) {
+			slog.Warn("two-factor codes locked after too many failures", "key", key, "duration", codeLockout)
+		}
+	}
+	return err
+}
+
+// checkTOTP verifies code against the TOTP secret of current and returns
+// the period counter it matched. Codes of periods at or before the last
+// accepted one are rejected, so that a code cannot be used twice.
+func (ts *TwoFactorService) checkTOTP(current *domain.TwoFactor, code string) (int64, error) {
+	secret, err := ts.cipher.Decrypt(current.Secret)
+	if err != nil {
+		return 0, fmt.Errorf("decrypt TOTP secret: %w", err)
+	}
+	counter, ok := matchTOTP(secret, code, time.Now())
+	if !ok || counter <= current.LastCounter {
+		return 0, domain.ErrInvalidTwoFactorCode
+	}
+	return counter, nil
+}
+
+// checkCode verifies code as a TOTP code, whose counter is recorded, then as
+// a recovery code, which is consumed.
+func (ts *TwoFactorService) checkCode(ctx context.Context, userID uuid.UUID, current *domain.TwoFactor, code string) error {
+	counter, err := ts.checkTOTP(current, code)
+	if err == nil {
+		accepted, err := ts.tr.AcceptCounter(ctx, userID, counter)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return domain.ErrInvalidTwoFactorCode
+		}
+		current.LastCounter = counter
+		return nil
+	}
+	if !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		return err
+	}
+
+	used, err := ts.tr.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.ErrInvalidTwoFactorCode
+	}
+
+	slog.Info("recovery code used", "user_id", userID, "remaining", len(current.RecoveryCodes)-1)
+	return nil
+}
+
+// sign returns the MAC of a challenge payload with key.
+func sign(key, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("two-factor challenge:" + payload))
+	return mac.Sum(nil)
+}
+
+// signed reports whether mac is the MAC of payload with any of the keys.
+func (ts *TwoFactorService) signed(payload string, mac []byte) bool {
+	for _, key := range ts.keys.Verifying() {
+		if hmac.Equal(mac, sign(key, payload)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseChallenge returns the account of a valid, unexpired challenge.
+func (ts *TwoFactorService) parseChallenge(challenge string) (uuid.UUID, error) {
+	encodedPayload, encodedMAC, ok := strings.Cut(challenge, ".")
+	if !ok {
+		return uuid.Nil, domain.ErrInvalidChallenge
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return uuid.Nil, domain.ErrInvalidChallenge
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !ts.signed(string(payload), mac) {
+		return uuid.Nil, domain.ErrInvalidChallenge
+	}
+
+	id, expiry, ok := strings.Cut(string(payload), ".")
+	if !ok {
+		return uuid.Nil, domain.ErrInvalidChallenge
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return uuid.Nil, domain.ErrInvalidChallenge
+	}
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, domain.ErrInvalidChallenge
+	}
+	return userID, nil
+}
+
+// provisioningURI returns the otpauth:// URI registering the base32 secret
+// of the account of email in authenticator apps.
+func provisioningURI(email, secret string) string {
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {totpIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {strconv.Itoa(totpDigits)},
+		"period":    {strconv.Itoa(int(totpPeriod.Seconds()))},
+	}
+	return "otpauth://totp/" + url.PathEscape(totpIssuer+":"+email) + "?" + query.Encode()
+}
+
+// totpCode returns the TOTP code of secret for the period counter.
+func totpCode(secret []byte, counter uint64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// matchTOTP returns the period counter whose TOTP code of secret is code,
+// among the period of now and the totpSkew periods around it, and false
+// when none matches.
+func matchTOTP(secret []byte, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	counter := now.Unix() / int64(totpPeriod.Seconds())
+	for skew := -totpSkew; skew <= totpSkew; skew++ {
+		if hmac.Equal([]byte(totpCode(secret, uint64(counter+int64(skew)))), []byte(code)) {
+			return counter + int64(skew), true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCode returns a random recovery code, such as "abcd-efgh".
+func newRecoveryCode() (string, error) {
+	random := make([]byte, 5)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32NoPadding.EncodeToString(random))
+	return code[:4] + "-" + code[4:], nil
+}
+
+// hashRecoveryCode returns the stored hash of a recovery code, ignoring case,
+// spaces and dashes.
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return fmt.Sprintf("%x", sum)
+}
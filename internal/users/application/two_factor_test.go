@@ -0,0 +1,243 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"newsletter/internal/infrastructure/secretbox"
+	"newsletter/internal/users/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTwoFactorRepository struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorRepository) Get(ctx context.Context, userID uuid.UUID) (*domain.User, *domain.TwoFactor, error) {
+	args := m.Called(ctx, userID)
+	user, _ := args.Get(0).(*domain.User)
+	twoFactor, _ := args.Get(1).(*domain.TwoFactor)
+	return user, twoFactor, args.Error(2)
+}
+
+func (m *MockTwoFactorRepository) Save(ctx context.Context, userID uuid.UUID, twoFactor *domain.TwoFactor) error {
+	args := m.Called(ctx, userID, twoFactor)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) AcceptCounter(ctx context.Context, userID uuid.UUID, counter int64) (bool, error) {
+	args := m.Called(ctx, userID, counter)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+// newTestCipher returns a cipher with a fixed key.
+func newTestCipher(t *testing.T) *secretbox.Box {
+	t.Helper()
+	box, err := secretbox.New(bytes.Repeat([]byte{1}, secretbox.KeySize))
+	require.NoError(t, err)
+	return box
+}
+
+func TestTOTPCode_RFC6238(t *testing.T) {
+	// Test vectors of RFC 6238, appendix B, truncated to 6 digits.
+	secret := []byte("12345678901234567890")
+
+	assert.Equal(t, "287082", totpCode(secret, 59/30))
+	assert.Equal(t, "081804", totpCode(secret, 1111111109/30))
+	counter, ok := matchTOTP(secret, "287 082", time.Unix(59+30, 0))
+	assert.True(t, ok)
+	assert.Equal(t, int64(59/30), counter)
+	_, ok = matchTOTP(secret, "287082", time.Unix(59+90, 0))
+	assert.False(t, ok)
+}
+
+func TestTwoFactorService_EnrollAndVerify(t *testing.T) {
+	mockRepo := new(MockTwoFactorRepository)
+	cipher := newTestCipher(t)
+	ts := NewTwoFactorService(mockRepo, cipher, domain.NewKeyset("secret123"))
+
+	userID := uuid.New()
+	mockRepo.On("Get", mock.Anything, userID).Return(&domain.User{ID: userID}, &domain.TwoFactor{}, nil).Once()
+
+	var saved *domain.TwoFactor
+	mockRepo.On("Save", mock.Anything, userID, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(2).(*domain.TwoFactor)
+	}).Return(nil)
+
+	enrollment, err := ts.Enroll(userID, "user@example.com")
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrollment.ProvisioningURI, "otpauth://totp/Newsletter:user@example.com?"))
+	assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+	assert.Len(t, enrollment.RecoveryCodes, recoveryCodeCount)
+	assert.False(t, saved.Enabled)
+	assert.NotContains(t, string(saved.Secret), enrollment.Secret)
+	assert.Equal(t, hashRecoveryCode(enrollment.RecoveryCodes[0]), saved.RecoveryCodes[0])
+
+	secret, err := base32NoPadding.DecodeString(enrollment.Secret)
+	require.NoError(t, err)
+
+	mockRepo.On("Get", mock.Anything, userID).Return(&domain.User{ID: userID}, saved, nil)
+	assert.ErrorIs(t, ts.Verify(userID, "000000x"), domain.ErrInvalidTwoFactorCode)
+
+	counter := time.Now().Unix() / 30
+	require.NoError(t, ts.Verify(userID, totpCode(secret, uint64(counter))))
+	assert.True(t, saved.Enabled)
+	assert.Equal(t, counter, saved.LastCounter)
+}
+
+func TestTwoFactorService_Enroll_AlreadyEnabled(t *testing.T) {
+	mockRepo := new(MockTwoFactorRepository)
+	ts := NewTwoFactorService(mockRepo, newTestCipher(t), domain.NewKeyset("secret123"))
+
+	userID := uuid.New()
+	mockRepo.On("Get", mock.Anything, userID).Return(&domain.User{ID: userID}, &domain.TwoFactor{Secret: []byte("x"), Enabled: true}, nil)
+
+	_, err := ts.Enroll(userID, "user@example.com")
+
+	assert.ErrorIs(t, err, domain.ErrTwoFactorEnabled)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTwoFactorService_NotConfigured(t *testing.T) {
+	ts := NewTwoFactorService(new(MockTwoFactorRepository), nil, domain.NewKeyset("secret123"))
+
+	_, err := ts.Enroll(uuid.New(), "user@example.com")
+	assert.ErrorIs(t, err, domain.ErrTwoFactorNotConfigured)
+
+	_, err = ts.Challenge(&domain.User{ID: uuid.New()})
+	assert.ErrorIs(t, err, domain.ErrTwoFactorNotConfigured)
+}
+
+func TestTwoFactorService_Complete_WithRecoveryCode(t *testing.T) {
+	mockRepo := new(MockTwoFactorRepository)
+	cipher := newTestCipher(t)
+	ts := NewTwoFactorService(mockRepo, cipher, domain.NewKeyset("secret123"))
+
+	user := &domain.User{ID: uuid.New(), Email: "user@example.com", TOTPEnabled: true}
+	encrypted, err := cipher.Encrypt([]byte("12345678901234567890"))
+	require.NoError(t, err)
+	mockRepo.On("Get", mock.Anything, user.ID).Return(user, &domain.TwoFactor{Secret: encrypted, Enabled: true, RecoveryCodes: []string{hashRecoveryCode("abcd-efgh")}}, nil)
+	mockRepo.On("UseRecoveryCode", mock.Anything, user.ID, hashRecoveryCode("abcd-efgh")).Return(true, nil)
+	mockRepo.On("UseRecoveryCode", mock.Anything, user.ID, mock.Anything).Return(false, nil)
+
+	challenge, err := ts.Challenge(user)
+	require.NoError(t, err)
+
+	signedIn, err := ts.Complete(challenge, "ABCD EFGH")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, signedIn.ID)
+
+	_, err = ts.Complete(challenge, "wrong")
+	assert.ErrorIs(t, err, domain.ErrInvalidTwoFactorCode)
+}
+
+func TestTwoFactorService_Complete_RejectsForgedChallenge(t *testing.T) {
+	mockRepo := new(MockTwoFactorRepository)
+	ts := NewTwoFactorService(mockRepo, newTestCipher(t), domain.NewKeyset("secret123"))
+	other := NewTwoFactorService(mockRepo, newTestCipher(t), domain.NewKeyset("other-secret"))
+
+	challenge, err := other.Challenge(&domain.User{ID: uuid.New()})
+	require.NoError(t, err)
+
+	_, err = ts.Complete(challenge, "123456")
+	assert.ErrorIs(t, err, domain.ErrInvalidChallenge)
+
+	_, err = ts.Complete("not-a-challenge", "123456")
+	assert.ErrorIs(t, err, domain.ErrInvalidChallenge)
+	mockRepo.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+func TestTwoFactorService_Challenge_RotatedKeys(t *testing.T) {
+	keys := domain.NewKeyset("old-secret")
+	ts := NewTwoFactorService(new(MockTwoFactorRepository), newTestCipher(t), keys)
+	user := &domain.User{ID: uuid.New()}
+
+	challenge, err := ts.Challenge(user)
+	require.NoError(t, err)
+
+	keys.Set("new-secret", "old-secret")
+	id, err := ts.parseChallenge(challenge)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, id)
+
+	keys.Set("new-secret")
+	_, err = ts.parseChallenge(challenge)
+	assert.ErrorIs(t, err, domain.ErrInvalidChallenge)
+}
+
+func TestTwoFactorService_Complete_RejectsReplayedCode(t *testing.T) {
+	mockRepo := new(MockTwoFactorRepository)
+	cipher := newTestCipher(t)
+	ts := NewTwoFactorService(mockRepo, cipher, domain.NewKeyset("secret123"))
+
+	secret := []byte("12345678901234567890")
+	encrypted, err := cipher.Encrypt(secret)
+	require.NoError(t, err)
+	counter := time.Now().Unix() / 30
+	code := totpCode(secret, uint64(counter))
+
+	user := &domain.User{ID: uuid.New(), TOTPEnabled: true}
+	mockRepo.On("Get", mock.Anything, user.ID).Return(user, &domain.TwoFactor{Secret: encrypted, Enabled: true, LastCounter: counter - 1}, nil).Once()
+	mockRepo.On("AcceptCounter", mock.Anything, user.ID, counter).Return(true, nil).Once()
+	challenge, err := ts.Challenge(user)
+	require.NoError(t, err)
+
+	_, err = ts.Complete(challenge, code)
+	require.NoError(t, err)
+
+	// The code was accepted: its period is now the last accepted one.
+	mockRepo.On("Get", mock.Anything, user.ID).Return(user, &domain.TwoFactor{Secret: encrypted, Enabled: true, LastCounter: counter}, nil).Once()
+	mockRepo.On("UseRecoveryCode", mock.Anything, user.ID, mock.Anything).Return(false, nil)
+	_, err = ts.Complete(challenge, code)
+	assert.ErrorIs(t, err, domain.ErrInvalidTwoFactorCode)
+
+	// A concurrent sign in accepted the code first.
+	mockRepo.On("Get", mock.Anything, user.ID).Return(user, &domain.TwoFactor{Secret: encrypted, Enabled: true, LastCounter: counter - 1}, nil).Once()
+	mockRepo.On("AcceptCounter", mock.Anything, user.ID, counter).Return(false, nil).Once()
+	_, err = ts.Complete(challenge, code)
+	assert.ErrorIs(t, err, domain.ErrInvalidTwoFactorCode)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTwoFactorService_Complete_LocksOutAfterFailures(t *testing.T) {
+	mockRepo := new(MockTwoFactorRepository)
+	cipher := newTestCipher(t)
+	ts := NewTwoFactorService(mockRepo, cipher, domain.NewKeyset("secret123"))
+
+	secret := []byte("12345678901234567890")
+	encrypted, err := cipher.Encrypt(secret)
+	require.NoError(t, err)
+
+	user := &domain.User{ID: uuid.New(), TOTPEnabled: true}
+	mockRepo.On("Get", mock.Anything, user.ID).Return(user, &domain.TwoFactor{Secret: encrypted, Enabled: true}, nil)
+	mockRepo.On("UseRecoveryCode", mock.Anything, user.ID, mock.Anything).Return(false, nil)
+	challenge, err := ts.Challenge(user)
+	require.NoError(t, err)
+
+	for range maxCodeFailures {
+		_, err = ts.Complete(challenge, "000000x")
+		assert.ErrorIs(t, err, domain.ErrInvalidTwoFactorCode)
+	}
+
+	// Even the right code is refused, with a new challenge too.
+	_, err = ts.Complete(challenge, totpCode(secret, uint64(time.Now().Unix()/30)))
+	assert.ErrorIs(t, err, domain.ErrTwoFactorLocked)
+	other, err := ts.Challenge(user)
+	require.NoError(t, err)
+	_, err = ts.Complete(other, totpCode(secret, uint64(time.Now().Unix()/30)))
+	assert.ErrorIs(t, err, domain.ErrTwoFactorLocked)
+	assert.ErrorIs(t, ts.Disable(user.ID, "abcd-efgh"), domain.ErrTwoFactorLocked)
+	mockRepo.AssertNotCalled(t, "AcceptCounter", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -2,24 +2,23 @@ package application
 
 import (
 	"context"
-	"errors"
 	"log/slog"
-	"newsletter/config"
 	"newsletter/internal/users/domain"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
 // UserService provides application-level operations related to users
-// and it orchestrates domain logic and persistence concerns.
+// and it orchestrates domain logic and persistence concerns. It handles the
+// lifecycle of accounts; signing in is up to AuthenticationService.
 type UserService struct {
 	ur domain.UserRepository
+	ph domain.PasswordHasher
 }
 
-func NewUserService(ur domain.UserRepository) *UserService {
-	return &UserService{ur: ur}
+func NewUserService(ur domain.UserRepository, ph domain.PasswordHasher) *UserService {
+	return &UserService{ur: ur, ph: ph}
 }
 
 // Create registers a new user in the system.
@@ -27,17 +26,33 @@ func NewUserService(ur domain.UserRepository) *UserService {
 // A timeout is applied to the operation to prevent long-running database
 // calls from blocking the request lifecycle.
 //
+// The plaintext password of user is checked against the password policy
+// (domain.ErrWeakPassword) and replaced by its hash before the user is
+// persisted. Registering an email twice fails with domain.ErrEmailAlreadyExists.
+//
 // On success, Create returns the newly created user entity.
 // On failure, the error is logged and returned to the caller.
 func (us *UserService) Create(user *domain.User) (*domain.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-
 	slog.Info(
 		"creating user",
 		"email", user.Email,
 	)
 
+	if err := domain.ValidatePassword(user.Password); err != nil {
+		slog.Warn("rejected password", "email", user.Email, "error", err)
+		return nil, err
+	}
+
+	hash, err := us.ph.Hash(user.Password)
+	if err != nil {
+		slog.Error("failed to hash password", "email", user.Email, "error", err)
+		return nil, err
+	}
+	user.Password = hash
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
 	newUser, err := us.ur.Create(ctx, user)
 	if err != nil {
 		slog.Error(
@@ -51,82 +66,11 @@ func (us *UserService) Create(user *domain.User) (*domain.User, error) {
 	return newUser, nil
 }
 
-type AuthenticationService struct {
-	ur domain.UserRepository
-}
-
-func NewAuthenticationService(ur domain.UserRepository) *AuthenticationService {
-	return &AuthenticationService{ur: ur}
-}
-
-// Authenticate verifies a user's credentials by email and password.
-//
-// It returns the authenticated user if credentials are valid.
-// The user's password hash is cleared before returning to prevent accidental exposure.
-func (us *AuthenticationService) Authenticate(email, password string) (*domain.User, error) {
+// Get returns the user with id, without its password hash. Unknown users
+// fail with domain.ErrUserNotFound.
+func (us *UserService) Get(id uuid.UUID) (*domain.User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancel()
 
-	user, err := us.ur.Get(ctx, email)
-	if err != nil {
-		slog.Error("failed to find user",
-			"email", email,
-			"error", err,
-		)
-		return nil, err
-	}
-
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
-		slog.Warn("invalid password attempt",
-			"email", email,
-		)
-		return nil, err
-	}
-
-	slog.Info("user authenticated successfully",
-		"user_id", user.ID.String(),
-		"email", user.Email,
-	)
-
-	return user, nil
-}
-
-// GenerateAccessToken generates a JWT access token for an authenticated user.
-// The token is short-lived (15 minutes) and includes the user's email and ID.
-func (us *AuthenticationService) GenerateAccessToken(user *domain.User) (string, error) {
-	slog.Info("generating access token",
-		"user_id",
-		user.ID.String(),
-		"email", user.Email,
-	)
-
-	secret := config.GetEnv("JWT_SECRET_KEY", "")
-	if secret == "" {
-		slog.Error("JWT secret key not set", "user_id", user.ID.String())
-		return "", errors.New("JWT secret key is missing")
-	}
-
-	claims := &domain.Claims{
-		Email: user.Email,
-		RegisteredClaims: &jwt.RegisteredClaims{
-			Subject:   user.ID.String(),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
-
-	access := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	accessToken, err := access.SignedString([]byte(secret))
-	if err != nil {
-		slog.Error("failed to sign access token", "user_id", user.ID.String(), "error", err)
-		return "", err
-	}
-
-	slog.Info("access token generated successfully",
-		"user_id", user.ID.String(),
-	)
-
-	return accessToken, nil
+	return us.ur.GetByID(ctx, id)
 }
@@ -34,13 +34,36 @@ func (m *MockUserRepository) Get(ctx context.Context, email string) (*domain.Use
 	return nil, args.Error(1)
 }
 
+func (m *MockUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	args := m.Called(ctx, id, passwordHash)
+	return args.Error(0)
+}
+
+// newTestHasher returns a bcrypt hasher matching the cost used by the tests.
+func newTestHasher(t *testing.T) *PasswordHasher {
+	t.Helper()
+	ph, err := NewPasswordHasher(AlgorithmBcrypt, bcrypt.DefaultCost, DefaultArgon2Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ph
+}
+
 // ------------------- Tests -------------------
 
 func TestUserService_Create_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	us := NewUserService(mockRepo)
+	us := NewUserService(mockRepo, newTestHasher(t))
 
-	inputUser := &domain.User{Email: "test@example.com", Password: "hashed"}
+	inputUser := &domain.User{Email: "test@example.com", Password: "password123"}
 	createdUser := &domain.User{ID: uuid.New(), Email: "test@example.com"}
 
 	mockRepo.On("Create", mock.Anything, inputUser).Return(createdUser, nil)
@@ -54,9 +77,9 @@ func TestUserService_Create_Success(t *testing.T) {
 
 func TestUserService_Create_Failure(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	us := NewUserService(mockRepo)
+	us := NewUserService(mockRepo, newTestHasher(t))
 
-	inputUser := &domain.User{Email: "fail@example.com", Password: "hashed"}
+	inputUser := &domain.User{Email: "fail@example.com", Password: "password123"}
 
 	mockRepo.On("Create", mock.Anything, inputUser).Return((*domain.User)(nil), errors.New("create failed"))
 
@@ -67,84 +90,40 @@ func TestUserService_Create_Failure(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-// ------------------- Authenticate -------------------
-
-func TestAuthenticationService_Authenticate_Success(t *testing.T) {
+func TestUserService_Create_WeakPassword(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	as := NewAuthenticationService(mockRepo)
-
-	password := "password123"
-	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	storedUser := &domain.User{ID: uuid.New(), Email: "test@example.com", Password: string(hashed)}
+	us := NewUserService(mockRepo, newTestHasher(t))
 
-	mockRepo.On("Get", mock.Anything, "test@example.com").Return(storedUser, nil)
+	result, err := us.Create(&domain.User{Email: "test@example.com", Password: "short"})
 
-	user, err := as.Authenticate("test@example.com", password)
-
-	assert.NoError(t, err)
-	assert.Equal(t, storedUser.ID, user.ID)
-	mockRepo.AssertExpectations(t)
+	assert.ErrorIs(t, err, domain.ErrWeakPassword)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
 }
 
-func TestAuthenticationService_Authenticate_WrongPassword(t *testing.T) {
+func TestUserService_Create_EmailAlreadyExists(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	as := NewAuthenticationService(mockRepo)
+	us := NewUserService(mockRepo, newTestHasher(t))
 
-	hashed, _ := bcrypt.GenerateFromPassword([]byte("correct"), bcrypt.DefaultCost)
-	storedUser := &domain.User{ID: uuid.New(), Email: "test@example.com", Password: string(hashed)}
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return((*domain.User)(nil), domain.ErrEmailAlreadyExists)
 
-	mockRepo.On("Get", mock.Anything, "test@example.com").Return(storedUser, nil)
+	result, err := us.Create(&domain.User{Email: "taken@example.com", Password: "password123"})
 
-	user, err := as.Authenticate("test@example.com", "wrongpass")
-
-	assert.Error(t, err)
-	assert.Nil(t, user)
+	assert.ErrorIs(t, err, domain.ErrEmailAlreadyExists)
+	assert.Nil(t, result)
 	mockRepo.AssertExpectations(t)
 }
 
-func TestAuthenticationService_Authenticate_UserNotFound(t *testing.T) {
+func TestUserService_Get(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	as := NewAuthenticationService(mockRepo)
-
-	mockRepo.On("Get", mock.Anything, "missing@example.com").Return((*domain.User)(nil), errors.New("not found"))
-
-	user, err := as.Authenticate("missing@example.com", "any")
-
-	assert.Error(t, err)
-	assert.Nil(t, user)
-	mockRepo.AssertExpectations(t)
-}
-
-// ------------------- GenerateAccessToken -------------------
-
-func TestAuthenticationService_GenerateAccessToken_Success(t *testing.T) {
-	as := &AuthenticationService{}
-	user := &domain.User{
-		ID:    uuid.New(),
-		Email: "test@example.com",
-	}
+	us := NewUserService(mockRepo, newTestHasher(t))
 
-	// Set a temporary JWT_SECRET_KEY for test
-	t.Setenv("JWT_SECRET_KEY", "secret123")
+	user := &domain.User{ID: uuid.New(), Email: "owner@example.com"}
+	mockRepo.On("GetByID", mock.Anything, user.ID).Return(user, nil)
 
-	token, err := as.GenerateAccessToken(user)
+	result, err := us.Get(user.ID)
 
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
-}
-
-func TestAuthenticationService_GenerateAccessToken_Failure(t *testing.T) {
-	as := &AuthenticationService{}
-	user := &domain.User{
-		ID:    uuid.Nil, // invalid ID still works, but we'll test secret missing
-		Email: "test@example.com",
-	}
-
-	// Unset JWT_SECRET_KEY to simulate signing failure
-	t.Setenv("JWT_SECRET_KEY", "")
-
-	token, err := as.GenerateAccessToken(user)
-
-	assert.Error(t, err)
-	assert.Equal(t, "", token)
+	assert.Equal(t, user, result)
+	mockRepo.AssertExpectations(t)
 }
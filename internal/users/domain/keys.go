@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"slices"
+	"sync"
+)
+
+// Keyset holds the keys signing access tokens and two-factor challenges.
+// Keys can be replaced while the API runs, when secrets are refreshed: new
+// tokens are signed with the current key, and tokens signed with a previous
+// key stay valid until they expire, so that keys rotate without signing
+// everyone out.
+type Keyset struct {
+	mu       sync.RWMutex
+	current  string
+	previous []string
+}
+
+// NewKeyset returns a Keyset signing with current and also accepting
+// previous.
+func NewKeyset(current string, previous ...string) *Keyset {
+	k := &Keyset{}
+	k.Set(current, previous...)
+	return k
+}
+
+// Set replaces the keys. Empty previous keys are ignored.
+func (k *Keyset) Set(current string, previous ...string) {
+	previous = slices.DeleteFunc(slices.Clone(previous), func(key string) bool {
+		return key == "" || key == current
+	})
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.current, k.previous = current, previous
+}
+
+// Current returns the key signing new tokens, or an empty string when none
+// is configured.
+func (k *Keyset) Current() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current
+}
+
+// Verifying returns the keys tokens are accepted with, the current one
+// first.
+func (k *Keyset) Verifying() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.current == "" {
+		return nil
+	}
+	return append([]string{k.current}, k.previous...)
+}
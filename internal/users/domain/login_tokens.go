@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"context"
+	apperrors "newsletter/internal/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginTokenTTL is how long a magic sign in link stays valid.
+const LoginTokenTTL = 15 * time.Minute
+
+// ErrInvalidLoginToken is returned when a magic sign in link is unknown,
+// expired or was already used.
+var ErrInvalidLoginToken = apperrors.New(apperrors.Unauthorized, "invalid or expired login link")
+
+// MagicLinkService issues and redeems the single-use tokens of passwordless
+// sign in links.
+type MagicLinkService interface {
+	// Issue creates a login token for the account of email and returns it
+	// together with the account. It fails when no account uses email.
+	Issue(email string) (string, *User, error)
+	// Redeem consumes a login token and returns the account it signs in. It
+	// fails with ErrInvalidLoginToken when the token cannot be used.
+	Redeem(token string) (*User, error)
+}
+
+// LoginTokenRepository persists login tokens. Only the hash of a token is
+// stored, so that a leaked table cannot be used to sign in.
+type LoginTokenRepository interface {
+	Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	// Consume marks the unused and unexpired token of tokenHash as used and
+	// returns its account, or ErrInvalidLoginToken.
+	Consume(ctx context.Context, tokenHash string, now time.Time) (*User, error)
+}
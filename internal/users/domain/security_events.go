@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecurityEventType identifies an account activity recorded for auditing.
+type SecurityEventType string
+
+const (
+	SecurityEventSignup          SecurityEventType = "signup"
+	SecurityEventSignin          SecurityEventType = "signin"
+	SecurityEventSigninFailed    SecurityEventType = "signin_failed"
+	SecurityEventPasswordChanged SecurityEventType = "password_changed"
+	SecurityEventTokenRefreshed  SecurityEventType = "token_refreshed"
+	SecurityEventTwoFactorOn     SecurityEventType = "two_factor_enabled"
+	SecurityEventTwoFactorOff    SecurityEventType = "two_factor_disabled"
+)
+
+// SecurityEvent is an entry of the account activity log.
+type SecurityEvent struct {
+	ID        uuid.UUID         `json:"id"`         // ID of the event
+	UserID    uuid.UUID         `json:"-"`          // Account the event belongs to, uuid.Nil if unknown
+	Email     string            `json:"email"`      // Email used in the request
+	Type      SecurityEventType `json:"type"`       // Kind of activity
+	IP        string            `json:"ip"`         // IP address of the client
+	UserAgent string            `json:"user_agent"` // User agent of the client
+	CreatedAt time.Time         `json:"created_at"` // Time of the event
+}
+
+// SecurityEventService records account activity and lets users review it.
+type SecurityEventService interface {
+	// Record stores an event. Failures are logged and never interrupt the
+	// request that triggered the event.
+	Record(event *SecurityEvent)
+	// List returns the most recent events of a user, newest first.
+	List(userID uuid.UUID, limit int) ([]*SecurityEvent, error)
+}
+
+// SecurityEventRepository persists security events.
+type SecurityEventRepository interface {
+	Create(ctx context.Context, event *SecurityEvent) error
+	List(ctx context.Context, userID uuid.UUID, limit int) ([]*SecurityEvent, error)
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	apperrors "newsletter/internal/errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTwoFactorNotConfigured is returned when no key to encrypt TOTP
+	// secrets is configured.
+	ErrTwoFactorNotConfigured = apperrors.New(apperrors.Internal, "two-factor authentication is not configured")
+	// ErrTwoFactorEnabled is returned when enrolling an account that already uses two-factor authentication.
+	ErrTwoFactorEnabled = apperrors.New(apperrors.Conflict, "two-factor authentication already enabled")
+	// ErrTwoFactorNotEnrolled is returned when verifying or disabling two-factor authentication before enrolling.
+	ErrTwoFactorNotEnrolled = apperrors.New(apperrors.Conflict, "two-factor authentication not enrolled")
+	// ErrInvalidTwoFactorCode is returned when a TOTP or recovery code does not match.
+	ErrInvalidTwoFactorCode = apperrors.New(apperrors.Unauthorized, "invalid two-factor code")
+	// ErrInvalidChallenge is returned when a sign in challenge is malformed, forged or expired.
+	ErrInvalidChallenge = apperrors.New(apperrors.Unauthorized, "invalid or expired sign in challenge")
+	// ErrTwoFactorLocked is returned when codes are checked for an account,
+	// or a challenge, after too many invalid codes.
+	ErrTwoFactorLocked = apperrors.New(apperrors.Unauthorized, "too many invalid two-factor codes, try again later")
+)
+
+// TwoFactor is the two-factor authentication state of an account.
+type TwoFactor struct {
+	Secret        []byte   // Encrypted TOTP secret, nil when not enrolled
+	Enabled       bool     // Whether sign ins require a second step
+	RecoveryCodes []string // Hashes of the unused recovery codes
+	LastCounter   int64    // Period counter of the last accepted TOTP code, 0 if none
+}
+
+// TwoFactorEnrollment is returned when enrolling, to be shown once to the
+// user.
+type TwoFactorEnrollment struct {
+	Secret          string   `json:"secret"`           // Base32 TOTP secret, for manual entry
+	ProvisioningURI string   `json:"provisioning_uri"` // otpauth:// URI, to be rendered as a QR code
+	RecoveryCodes   []string `json:"recovery_codes"`   // Single-use codes replacing a TOTP code
+}
+
+// TwoFactorService manages TOTP two-factor authentication: enrollment, the
+// second step of sign ins and recovery codes.
+type TwoFactorService interface {
+	// Enroll generates a new TOTP secret and recovery codes for an account.
+	// Two-factor authentication is enabled once a code is verified.
+	Enroll(userID uuid.UUID, email string) (*TwoFactorEnrollment, error)
+	// Verify enables two-factor authentication when code matches the
+	// enrolled secret.
+	Verify(userID uuid.UUID, code string) error
+	// Disable turns two-factor authentication off when code, a TOTP or
+	// recovery code, matches.
+	Disable(userID uuid.UUID, code string) error
+	// Challenge returns a short-lived token proving that user passed the
+	// first sign in step.
+	Challenge(user *User) (string, error)
+	// Complete checks the TOTP or recovery code of a challenge and returns
+	// the account signing in.
+	Complete(challenge, code string) (*User, error)
+}
+
+// TwoFactorRepository persists the two-factor authentication state of
+// accounts.
+type TwoFactorRepository interface {
+	// Get returns an account, without its password, and its two-factor state.
+	Get(ctx context.Context, userID uuid.UUID) (*User, *TwoFactor, error)
+	Save(ctx context.Context, userID uuid.UUID, twoFactor *TwoFactor) error
+	// AcceptCounter records counter as the period of the last accepted TOTP
+	// code of the account and reports whether it is later than the
+	// previous one, so that a code is accepted once.
+	AcceptCounter(ctx context.Context, userID uuid.UUID, counter int64) (bool, error)
+	// UseRecoveryCode removes the recovery code of codeHash from the account
+	// and reports whether it was unused.
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+}
+
+// SecretCipher encrypts the TOTP secrets stored in the database.
+type SecretCipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
@@ -2,6 +2,8 @@ package domain
 
 import (
 	"context"
+	"fmt"
+	apperrors "newsletter/internal/errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,7 +14,81 @@ import (
 type ContextKey string
 
 const (
-	UserID ContextKey = "userID"
+	UserID    ContextKey = "userID"
+	UserEmail ContextKey = "userEmail"
+	Scopes    ContextKey = "scopes"
+)
+
+// Scope is a permission granted to an access token. Routes declare the scope
+// they require and tokens carry the scopes they were issued with.
+type Scope string
+
+const (
+	ScopeNewslettersRead  Scope = "newsletters:read"
+	ScopeNewslettersWrite Scope = "newsletters:write"
+	ScopeSubscribersWrite Scope = "subscribers:write"
+	ScopeIssuesSend       Scope = "issues:send"
+	ScopeAnalyticsRead    Scope = "analytics:read"
+
+	// ScopeAdmin grants access to the system-wide administration API. It is
+	// not part of AllScopes and is only granted to users with RoleAdmin.
+	ScopeAdmin Scope = "admin"
+)
+
+// AllScopes lists every scope known to the system. Tokens issued on sign up
+// and sign in are granted all of them.
+var AllScopes = []Scope{
+	ScopeNewslettersRead,
+	ScopeNewslettersWrite,
+	ScopeSubscribersWrite,
+	ScopeIssuesSend,
+	ScopeAnalyticsRead,
+}
+
+// HasScope reports whether scope is contained in granted.
+func HasScope(granted []Scope, scope Scope) bool {
+	for _, s := range granted {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// ErrInvalidCredentials is returned when a password does not match the stored hash.
+	ErrInvalidCredentials = apperrors.New(apperrors.Unauthorized, "invalid credentials")
+	// ErrEmailAlreadyExists is returned when signing up with an email that is already registered.
+	ErrEmailAlreadyExists = apperrors.New(apperrors.Conflict, "email already registered")
+	// ErrUserNotFound is returned when no account matches the lookup.
+	ErrUserNotFound = apperrors.New(apperrors.NotFound, "user not found")
+	// ErrWeakPassword is returned when a new password does not meet the password policy.
+	ErrWeakPassword = apperrors.New(apperrors.Validation, "password too weak")
+)
+
+// Password policy. The upper bound is the longest input bcrypt accepts.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// ValidatePassword checks a plaintext password against the password policy
+// and returns an error wrapping ErrWeakPassword if it is not met.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrWeakPassword, MinPasswordLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("%w: must be at most %d bytes", ErrWeakPassword, MaxPasswordLength)
+	}
+	return nil
+}
+
+// Roles of a user account. Every account is created with RoleUser; operators
+// promote accounts to RoleAdmin in the database.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
 )
 
 // User represents the user account.
@@ -20,29 +96,61 @@ type User struct {
 	ID        uuid.UUID // ID of the user
 	Password  string    // Hashed password of the user
 	Email     string    // Email of the user
+	Role      string    // RoleUser or RoleAdmin
 	CreatedAt time.Time // Creation time of the user
+
+	TOTPEnabled bool // Whether sign ins require a TOTP code
+}
+
+// ScopesFor returns the scopes granted to the access tokens of user: all
+// scopes, and ScopeAdmin for administrators.
+func ScopesFor(user *User) []Scope {
+	scopes := append([]Scope{}, AllScopes...)
+	if user.Role == RoleAdmin {
+		scopes = append(scopes, ScopeAdmin)
+	}
+	return scopes
 }
 
 // UserService is an interface that contains a collection of method signatures
-// which will be implemented in application level and are responsible for creating a user.
+// which will be implemented in application level and are responsible for creating
+// and getting a user.
 type UserService interface {
 	Create(user *User) (*User, error)
+	// Get returns the user with id, without its password hash, or
+	// ErrUserNotFound.
+	Get(id uuid.UUID) (*User, error)
 }
 
 // UserRepository is an interface that contains a collection of method signatures
 // which will be implemented in persistence level and are responsible for creating
-// and getting a user.
+// and getting a user, and replacing its password hash.
 type UserRepository interface {
 	Create(ctx context.Context, user *User) (*User, error)
 	Get(ctx context.Context, email string) (*User, error)
+	// GetByID returns the user with id, without its password hash, or
+	// ErrUserNotFound.
+	GetByID(ctx context.Context, id uuid.UUID) (*User, error)
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+}
+
+// PasswordHasher hashes passwords and verifies them against stored hashes.
+type PasswordHasher interface {
+	// Hash returns the hash of password using the configured algorithm and cost.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash.
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash reports whether hash was produced with an outdated algorithm or cost.
+	NeedsRehash(hash string) bool
 }
 
 type Claims struct {
-	Email string
+	Email  string
+	Scopes []Scope `json:"scopes,omitempty"`
 	*jwt.RegisteredClaims
 }
 
-// AuthService is an interface that contains a collection of method signatures
+// AuthenticationService is an interface that contains a collection of method signatures
 // which will be implemented in application level and are responsible for authenticating a user
 // and generating a token on sign up/sign in.
 type AuthenticationService interface {
@@ -0,0 +1,90 @@
+// Package memory stores users in memory, for local development without
+// a database. Everything is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"newsletter/internal/users/domain"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserRepository implements domain.UserRepository in memory.
+type UserRepository struct {
+	mu    sync.RWMutex
+	users map[string]*domain.User // By email
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{users: map[string]*domain.User{}}
+}
+
+// Create stores a new user, as the PostgreSQL repository does: it returns
+// domain.ErrEmailAlreadyExists if the email is registered, and the user
+// without its password hash.
+func (ur *UserRepository) Create(ctx context.Context, user *domain.User) (*domain.User, error) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	if _, ok := ur.users[user.Email]; ok {
+		return nil, domain.ErrEmailAlreadyExists
+	}
+
+	stored := &domain.User{
+		ID:        uuid.New(),
+		Password:  user.Password,
+		Email:     user.Email,
+		Role:      domain.RoleUser,
+		CreatedAt: time.Now(),
+	}
+	ur.users[user.Email] = stored
+
+	return &domain.User{ID: stored.ID, Email: stored.Email, Role: stored.Role, CreatedAt: stored.CreatedAt}, nil
+}
+
+// Get returns a copy of the user registered with email, including its
+// password hash, or domain.ErrUserNotFound.
+func (ur *UserRepository) Get(ctx context.Context, email string) (*domain.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	user, ok := ur.users[email]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+
+	found := *user
+	return &found, nil
+}
+
+// GetByID returns a copy of the user with id, without its password hash,
+// or domain.ErrUserNotFound.
+func (ur *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	for _, user := range ur.users {
+		if user.ID == id {
+			found := *user
+			found.Password = ""
+			return &found, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+// UpdatePassword replaces the password hash of a user. Unknown users are
+// ignored.
+func (ur *UserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	for _, user := range ur.users {
+		if user.ID == id {
+			user.Password = passwordHash
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"newsletter/internal/users/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepository(t *testing.T) {
+	ctx := context.Background()
+	repository := NewUserRepository()
+
+	created, err := repository.Create(ctx, &domain.User{Email: "writer@example.com", Password: "hash"})
+	require.NoError(t, err)
+	assert.Empty(t, created.Password)
+	assert.Equal(t, domain.RoleUser, created.Role)
+
+	_, err = repository.Create(ctx, &domain.User{Email: "writer@example.com", Password: "other"})
+	assert.ErrorIs(t, err, domain.ErrEmailAlreadyExists)
+
+	require.NoError(t, repository.UpdatePassword(ctx, created.ID, "new hash"))
+	found, err := repository.Get(ctx, "writer@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+	assert.Equal(t, "new hash", found.Password)
+
+	byID, err := repository.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "writer@example.com", byID.Email)
+	assert.Empty(t, byID.Password)
+
+	_, err = repository.Get(ctx, "unknown@example.com")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	_, err = repository.GetByID(ctx, uuid.New())
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/users/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// LoginTokenRepository implements persistence operations for the tokens of
+// magic sign in links using a PostgreSQL database.
+type LoginTokenRepository struct {
+	db database.DB
+}
+
+func NewLoginTokenRepository(db database.DB) *LoginTokenRepository {
+	return &LoginTokenRepository{db: db}
+}
+
+// Create stores the hash of a login token of a user.
+func (lr *LoginTokenRepository) Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	query := `insert into login_tokens (user_id, token_hash, expires_at, created_at) values ($1, $2, $3, $4)`
+
+	_, err := lr.db.Exec(ctx, query, userID, tokenHash, expiresAt, time.Now())
+	return err
+}
+
+// Consume marks the login token of tokenHash as used and returns its user.
+//
+// The token is claimed by a single conditional update, so that concurrent
+// requests with the same link cannot both sign in. It returns
+// domain.ErrInvalidLoginToken when the token does not exist, has expired or
+// was already used.
+func (lr *LoginTokenRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (*domain.User, error) {
+	query := `with consumed as (
+			update login_tokens set used_at = $2
+			where token_hash = $1 and used_at is null and expires_at > $2
+			returning user_id
+		)
+		select u.id, u.email, u.role, u.created_at, u.totp_enabled from users u join consumed c on c.user_id = u.id`
+
+	var user domain.User
+	err := lr.db.QueryRow(ctx, query, tokenHash, now).Scan(&user.ID, &user.Email, &user.Role, &user.CreatedAt, &user.TOTPEnabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrInvalidLoginToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/users/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecurityEventRepository implements persistence operations for
+// domain.SecurityEvent entities using a PostgreSQL database.
+type SecurityEventRepository struct {
+	db database.DB
+}
+
+func NewSecurityEventRepository(db database.DB) *SecurityEventRepository {
+	return &SecurityEventRepository{db: db}
+}
+
+// Create stores a security event.
+//
+// When the event has no user ID, the account is looked up by email so that
+// failed sign ins on existing accounts show up in their activity log. Events
+// for unknown emails are stored without a user.
+func (sr *SecurityEventRepository) Create(ctx context.Context, event *domain.SecurityEvent) error {
+	var userID any
+	if event.UserID != uuid.Nil {
+		userID = event.UserID
+	}
+
+	query := `insert into security_events (user_id, email, type, ip, user_agent, created_at)
+		values (coalesce($1, (select id from users where email = $2)), $2, $3, $4, $5, $6)
+		returning id, created_at`
+
+	return sr.db.QueryRow(
+		ctx,
+		query,
+		userID,
+		event.Email,
+		event.Type,
+		event.IP,
+		event.UserAgent,
+		time.Now(),
+	).Scan(&event.ID, &event.CreatedAt)
+}
+
+// List retrieves the most recent security events of a user, newest first.
+func (sr *SecurityEventRepository) List(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.SecurityEvent, error) {
+	query := `select id, user_id, email, type, ip, user_agent, created_at
+		from security_events where user_id = $1 order by created_at desc limit $2`
+
+	rows, err := sr.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*domain.SecurityEvent{}
+	for rows.Next() {
+		var event domain.SecurityEvent
+		err := rows.Scan(&event.ID, &event.UserID, &event.Email, &event.Type, &event.IP, &event.UserAgent, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
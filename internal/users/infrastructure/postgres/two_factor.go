@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/users/domain"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// TwoFactorRepository implements persistence operations for the two-factor
+// authentication state stored on the users table.
+type TwoFactorRepository struct {
+	db database.DB
+}
+
+func NewTwoFactorRepository(db database.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// Get retrieves a user, without its password hash, and its two-factor
+// authentication state.
+//
+// If no user exists with the given ID, Get returns domain.ErrUserNotFound.
+func (tr *TwoFactorRepository) Get(ctx context.Context, userID uuid.UUID) (*domain.User, *domain.TwoFactor, error) {
+	query := `select id, email, role, created_at, totp_enabled, totp_secret, totp_recovery_codes, totp_last_counter from users where id = $1`
+
+	var user domain.User
+	var twoFactor domain.TwoFactor
+	err := tr.db.QueryRow(ctx, query, userID).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Role,
+		&user.CreatedAt,
+		&user.TOTPEnabled,
+		&twoFactor.Secret,
+		&twoFactor.RecoveryCodes,
+		&twoFactor.LastCounter,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	twoFactor.Enabled = user.TOTPEnabled
+
+	return &user, &twoFactor, nil
+}
+
+// Save replaces the two-factor authentication state of a user.
+func (tr *TwoFactorRepository) Save(ctx context.Context, userID uuid.UUID, twoFactor *domain.TwoFactor) error {
+	query := `update users set totp_secret = $1, totp_enabled = $2, totp_recovery_codes = $3, totp_last_counter = $4 where id = $5`
+
+	codes := twoFactor.RecoveryCodes
+	if codes == nil {
+		codes = []string{}
+	}
+
+	_, err := tr.db.Exec(ctx, query, twoFactor.Secret, twoFactor.Enabled, codes, twoFactor.LastCounter, userID)
+	return err
+}
+
+// AcceptCounter moves the last accepted TOTP counter of a user forward in a
+// single update, so that concurrent sign ins cannot use the same code twice.
+func (tr *TwoFactorRepository) AcceptCounter(ctx context.Context, userID uuid.UUID, counter int64) (bool, error) {
+	query := `update users set totp_last_counter = $1 where id = $2 and totp_last_counter < $1`
+
+	tag, err := tr.db.Exec(ctx, query, counter, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// UseRecoveryCode removes a recovery code of a user in a single update, so
+// that concurrent sign ins cannot use the same code twice.
+func (tr *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `update users set totp_recovery_codes = array_remove(totp_recovery_codes, $1)
+		where id = $2 and $1 = any(totp_recovery_codes)`
+
+	tag, err := tr.db.Exec(ctx, query, codeHash, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
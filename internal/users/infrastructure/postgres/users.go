@@ -2,52 +2,56 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
+	"errors"
+	"newsletter/internal/infrastructure/database"
 	"newsletter/internal/users/domain"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 )
 
+// uniqueViolation is the PostgreSQL error code of unique constraint violations.
+const uniqueViolation = "23505"
+
 // UserRepository implements persistence operations for domain.User entities
 // using a PostgreSQL database.
 type UserRepository struct {
-	db *sql.DB
+	db database.DB
 }
 
-func NewUserRepository(db *sql.DB) *UserRepository {
+func NewUserRepository(db database.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
 // Create persists a new user in the database.
 //
-// The user's plaintext password is hashed using bcrypt before storage.
+// The user's password must already be hashed (see domain.PasswordHasher).
 // On success, Create returns a fully initialized domain.User containing
-// the generated ID, email, and creation timestamp.
+// the generated ID, email, role, and creation timestamp.
 //
 // The returned user will never contain a password or password hash.
 //
 // Possible errors include:
-//   - bcrypt hashing failures
-//   - database constraint violations (e.g. duplicate email)
+//   - domain.ErrEmailAlreadyExists if the email is already registered
 //   - database connectivity errors
 func (ur *UserRepository) Create(ctx context.Context, user *domain.User) (*domain.User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, err
-	}
-
 	var userDB *domain.User = &domain.User{}
-	query := `insert into users (password, email, created_at) values ($1, $2, $3) returning id, email, created_at`
+	query := `insert into users (password, email, created_at) values ($1, $2, $3) returning id, email, role, created_at`
 
-	err = ur.db.QueryRowContext(
+	err := ur.db.QueryRow(
 		ctx,
 		query,
-		string(hashedPassword),
+		user.Password,
 		user.Email,
 		time.Now(),
-	).Scan(&userDB.ID, &userDB.Email, &userDB.CreatedAt)
+	).Scan(&userDB.ID, &userDB.Email, &userDB.Role, &userDB.CreatedAt)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, domain.ErrEmailAlreadyExists
+		}
 		return nil, err
 	}
 
@@ -59,15 +63,45 @@ func (ur *UserRepository) Create(ctx context.Context, user *domain.User) (*domai
 // The returned user includes the stored password hash, making this method
 // suitable for authentication-related use cases.
 //
-// If no user exists with the given email, Get returns an error (typically sql.ErrNoRows).
+// If no user exists with the given email, Get returns domain.ErrUserNotFound.
 func (ur *UserRepository) Get(ctx context.Context, email string) (*domain.User, error) {
-	query := `select id, password, email, created_at from users where email = $1`
+	query := `select id, password, email, role, created_at, totp_enabled from users where email = $1`
+
+	var user *domain.User = &domain.User{}
+	err := ur.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.Password, &user.Email, &user.Role, &user.CreatedAt, &user.TOTPEnabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetByID retrieves a user by ID, without its password hash, e.g. to email
+// the owner of a newsletter.
+//
+// If no user exists with the given ID, GetByID returns domain.ErrUserNotFound.
+func (ur *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	query := `select id, email, role, created_at, totp_enabled from users where id = $1`
 
 	var user *domain.User = &domain.User{}
-	err := ur.db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Password, &user.Email, &user.CreatedAt)
+	err := ur.db.QueryRow(ctx, query, id).Scan(&user.ID, &user.Email, &user.Role, &user.CreatedAt, &user.TOTPEnabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
+
+// UpdatePassword replaces the password hash of a user.
+func (ur *UserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	query := `update users set password = $1 where id = $2`
+
+	_, err := ur.db.Exec(ctx, query, passwordHash, id)
+	return err
+}
@@ -0,0 +1,110 @@
+package postgres_test
+
+import (
+	"context"
+	"newsletter/internal/infrastructure/fixtures"
+	"newsletter/internal/users/domain"
+	"newsletter/internal/users/infrastructure/postgres"
+	"regexp"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMock returns a mocked database whose expectations are checked at the
+// end of the test.
+func newMock(t *testing.T) pgxmock.PgxPoolIface {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		mock.Close()
+	})
+	return mock
+}
+
+func TestUserRepository_Create(t *testing.T) {
+	mock := newMock(t)
+	user := fixtures.User(1)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`insert into users (password, email, created_at) values ($1, $2, $3) returning id, email, role, created_at`)).
+		WithArgs(user.Password, user.Email, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "email", "role", "created_at"}).
+			AddRow(user.ID, user.Email, user.Role, user.CreatedAt))
+
+	created, err := postgres.NewUserRepository(mock).Create(context.Background(), user)
+
+	require.NoError(t, err)
+	assert.Equal(t, &domain.User{ID: user.ID, Email: user.Email, Role: user.Role, CreatedAt: user.CreatedAt}, created)
+}
+
+func TestUserRepository_Create_EmailTaken(t *testing.T) {
+	mock := newMock(t)
+	user := fixtures.User(1)
+
+	mock.ExpectQuery(`insert into users`).
+		WithArgs(user.Password, user.Email, pgxmock.AnyArg()).
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"})
+
+	_, err := postgres.NewUserRepository(mock).Create(context.Background(), user)
+
+	assert.ErrorIs(t, err, domain.ErrEmailAlreadyExists)
+}
+
+func TestUserRepository_Get(t *testing.T) {
+	mock := newMock(t)
+	user := fixtures.User(2)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`select id, password, email, role, created_at, totp_enabled from users where email = $1`)).
+		WithArgs(user.Email).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "password", "email", "role", "created_at", "totp_enabled"}).
+			AddRow(user.ID, user.Password, user.Email, user.Role, user.CreatedAt, false))
+
+	found, err := postgres.NewUserRepository(mock).Get(context.Background(), user.Email)
+
+	require.NoError(t, err)
+	assert.Equal(t, user, found)
+}
+
+func TestUserRepository_Get_NotFound(t *testing.T) {
+	mock := newMock(t)
+
+	mock.ExpectQuery(`select .* from users where email = \$1`).
+		WithArgs("nobody@example.com").
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err := postgres.NewUserRepository(mock).Get(context.Background(), "nobody@example.com")
+
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+}
+
+func TestUserRepository_GetByID(t *testing.T) {
+	mock := newMock(t)
+	user := fixtures.User(4)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`select id, email, role, created_at, totp_enabled from users where id = $1`)).
+		WithArgs(user.ID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "email", "role", "created_at", "totp_enabled"}).
+			AddRow(user.ID, user.Email, user.Role, user.CreatedAt, false))
+
+	found, err := postgres.NewUserRepository(mock).GetByID(context.Background(), user.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, &domain.User{ID: user.ID, Email: user.Email, Role: user.Role, CreatedAt: user.CreatedAt}, found)
+}
+
+func TestUserRepository_UpdatePassword(t *testing.T) {
+	mock := newMock(t)
+	user := fixtures.User(3)
+
+	mock.ExpectExec(regexp.QuoteMeta(`update users set password = $1 where id = $2`)).
+		WithArgs("new-hash", user.ID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	assert.NoError(t, postgres.NewUserRepository(mock).UpdatePassword(context.Background(), user.ID, "new-hash"))
+}
@@ -0,0 +1,2 @@
+DROP TABLE campaign_deliveries;
+DROP TABLE campaigns;
\ No newline at end of file
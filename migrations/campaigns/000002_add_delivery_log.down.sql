@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_campaign_deliveries_message_id;
+
+UPDATE campaign_deliveries SET status = 'sent' WHERE status IN ('delivered', 'bounced', 'complained');
+ALTER TABLE campaign_deliveries DROP CONSTRAINT campaign_deliveries_status_check;
+ALTER TABLE campaign_deliveries ADD CONSTRAINT campaign_deliveries_status_check
+    CHECK (status IN ('pending', 'sent', 'failed'));
+
+ALTER TABLE campaign_deliveries
+    DROP COLUMN sent_at,
+    DROP COLUMN message_id;
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE campaign_deliveries
+    ADD COLUMN message_id TEXT,
+    ADD COLUMN sent_at TIMESTAMPTZ;
+
+ALTER TABLE campaign_deliveries DROP CONSTRAINT campaign_deliveries_status_check;
+ALTER TABLE campaign_deliveries ADD CONSTRAINT campaign_deliveries_status_check
+    CHECK (status IN ('pending', 'sent', 'failed', 'delivered', 'bounced', 'complained'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_campaign_deliveries_message_id ON campaign_deliveries(message_id) WHERE message_id IS NOT NULL;
\ No newline at end of file
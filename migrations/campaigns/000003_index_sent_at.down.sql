@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_campaign_deliveries_sent_at;
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_campaign_deliveries_sent_at ON campaign_deliveries(sent_at) WHERE sent_at IS NOT NULL;
\ No newline at end of file
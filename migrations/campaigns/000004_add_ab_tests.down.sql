@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS idx_campaign_deliveries_tracking_id;
+
+ALTER TABLE campaign_deliveries
+    DROP COLUMN opened_at,
+    DROP COLUMN tracking_id,
+    DROP COLUMN variant;
+
+UPDATE campaigns SET status = 'paused' WHERE status = 'testing';
+ALTER TABLE campaigns DROP CONSTRAINT campaigns_status_check;
+ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+    CHECK (status IN ('queued', 'sending', 'paused', 'completed', 'failed'));
+
+ALTER TABLE campaigns
+    DROP COLUMN ab_winner,
+    DROP COLUMN ab_ends_at,
+    DROP COLUMN ab_window_minutes,
+    DROP COLUMN ab_sample_percent,
+    DROP COLUMN ab_subject_b,
+    DROP COLUMN ab_subject_a;
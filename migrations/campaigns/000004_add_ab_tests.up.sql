@@ -0,0 +1,18 @@
+ALTER TABLE campaigns
+    ADD COLUMN ab_subject_a TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ab_subject_b TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ab_sample_percent INT CHECK (ab_sample_percent BETWEEN 1 AND 50),
+    ADD COLUMN ab_window_minutes INT NOT NULL DEFAULT 0,
+    ADD COLUMN ab_ends_at TIMESTAMPTZ,
+    ADD COLUMN ab_winner TEXT NOT NULL DEFAULT '' CHECK (ab_winner IN ('', 'a', 'b'));
+
+ALTER TABLE campaigns DROP CONSTRAINT campaigns_status_check;
+ALTER TABLE campaigns ADD CONSTRAINT campaigns_status_check
+    CHECK (status IN ('queued', 'sending', 'testing', 'paused', 'completed', 'failed'));
+
+ALTER TABLE campaign_deliveries
+    ADD COLUMN variant TEXT NOT NULL DEFAULT '' CHECK (variant IN ('', 'a', 'b')),
+    ADD COLUMN tracking_id UUID NOT NULL DEFAULT gen_random_uuid(),
+    ADD COLUMN opened_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_campaign_deliveries_tracking_id ON campaign_deliveries(tracking_id);
@@ -0,0 +1,5 @@
+ALTER TABLE campaigns
+    DROP COLUMN next_batch_at,
+    DROP COLUMN send_window_timezone,
+    DROP COLUMN send_window_end,
+    DROP COLUMN send_window_start;
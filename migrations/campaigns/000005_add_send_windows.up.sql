@@ -0,0 +1,5 @@
+ALTER TABLE campaigns
+    ADD COLUMN send_window_start TEXT NOT NULL DEFAULT '',
+    ADD COLUMN send_window_end TEXT NOT NULL DEFAULT '',
+    ADD COLUMN send_window_timezone TEXT NOT NULL DEFAULT '',
+    ADD COLUMN next_batch_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- Campaigns without a segment are sent to every active subscriber. Segments
+-- cannot be deleted while a campaign sent to them is not completed.
+ALTER TABLE campaigns
+    ADD COLUMN segment_id UUID REFERENCES segments(id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+-- Reply-To, copies and custom headers overriding those of the newsletter;
+-- NULL for campaigns using the settings of the newsletter.
+ALTER TABLE campaigns
+    ADD COLUMN envelope JSONB;
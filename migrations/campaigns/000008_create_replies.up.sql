@@ -0,0 +1,14 @@
+-- Emails recipients sent in answer to campaign emails, received through the
+-- inbound webhook. Redeliveries are identified by their Message-ID header.
+CREATE TABLE campaign_replies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    text TEXT NOT NULL DEFAULT '',
+    message_id TEXT,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_replies_campaign ON campaign_replies(campaign_id, received_at, id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_campaign_replies_message_id ON campaign_replies(message_id) WHERE message_id IS NOT NULL;
@@ -0,0 +1,18 @@
+CREATE TABLE idempotency_keys (
+    -- Who sent the key, such as "user:<id>"; keys of different scopes are independent
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    -- SHA-256 of the method, path and body of the request
+    request_hash CHAR(64) NOT NULL,
+    -- Response, set once the request completed
+    status INTEGER,
+    content_type TEXT,
+    body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    PRIMARY KEY (scope, key)
+);
+
+-- Keys expire after a day; prune them with
+-- DELETE FROM idempotency_keys WHERE created_at < NOW() - INTERVAL '1 day'
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
@@ -0,0 +1 @@
+DROP TABLE short_links;
@@ -0,0 +1,11 @@
+-- Short links of the URLs of campaign emails, /r/{code}, counting their
+-- clicks. Each URL of a campaign has a single link.
+CREATE TABLE short_links (
+    code TEXT PRIMARY KEY,
+    url TEXT NOT NULL,
+    campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    clicks INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_short_links_campaign_url ON short_links(campaign_id, url);
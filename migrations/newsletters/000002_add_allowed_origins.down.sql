@@ -0,0 +1 @@
+ALTER TABLE newsletters DROP COLUMN IF EXISTS allowed_origins;
\ No newline at end of file
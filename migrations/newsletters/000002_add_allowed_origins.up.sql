@@ -0,0 +1 @@
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS allowed_origins TEXT[] NOT NULL DEFAULT '{}';
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE newsletters DROP COLUMN IF EXISTS from_email_verified;
+ALTER TABLE newsletters DROP COLUMN IF EXISTS from_email;
+ALTER TABLE newsletters DROP COLUMN IF EXISTS from_name;
\ No newline at end of file
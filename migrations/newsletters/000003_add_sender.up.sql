@@ -0,0 +1,3 @@
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS from_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS from_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS from_email_verified BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE newsletters DROP COLUMN IF EXISTS unsubscribe_redirect_url;
+ALTER TABLE newsletters DROP COLUMN IF EXISTS logo_url;
+ALTER TABLE newsletters DROP COLUMN IF EXISTS brand_color;
+ALTER TABLE newsletters DROP COLUMN IF EXISTS footer_text;
\ No newline at end of file
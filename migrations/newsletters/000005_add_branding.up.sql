@@ -0,0 +1,4 @@
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS unsubscribe_redirect_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS logo_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS brand_color TEXT NOT NULL DEFAULT '';
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS footer_text TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_newsletters_search;
+ALTER TABLE newsletters DROP COLUMN IF EXISTS search;
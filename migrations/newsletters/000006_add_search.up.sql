@@ -0,0 +1,4 @@
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS search TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || description)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_newsletters_search ON newsletters USING GIN (search);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_newsletters_slug;
+ALTER TABLE newsletters DROP COLUMN IF EXISTS slug;
@@ -0,0 +1,13 @@
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS slug VARCHAR(64);
+
+-- Existing newsletters get a slug derived from their name, made unique by
+-- the start of their ID. Owners can change it afterwards.
+UPDATE newsletters
+SET slug = concat_ws('-',
+    nullif(trim(both '-' from left(regexp_replace(lower(name), '[^a-z0-9]+', '-', 'g'), 48)), ''),
+    left(id::text, 8))
+WHERE slug IS NULL;
+
+ALTER TABLE newsletters ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_newsletters_slug ON newsletters(slug);
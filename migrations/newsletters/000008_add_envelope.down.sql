@@ -0,0 +1 @@
+ALTER TABLE newsletters DROP COLUMN IF EXISTS envelope;
@@ -0,0 +1 @@
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS envelope JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_newsletters_owner_created;
@@ -0,0 +1,2 @@
+-- Keyset pagination of the newsletters of an owner, by creation time then ID.
+CREATE INDEX IF NOT EXISTS idx_newsletters_owner_created ON newsletters(owner_id, created_at, id);
@@ -0,0 +1 @@
+DROP TABLE posts;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE segments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    -- Conditions subscribers of the segment meet, see the segments domain Filter
+    filter JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_segments_newsletter_id ON segments(newsletter_id, name);
@@ -0,0 +1,2 @@
+-- Fails if argon2id hashes longer than 60 characters are stored.
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR(60);
\ No newline at end of file
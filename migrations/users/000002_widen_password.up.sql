@@ -0,0 +1 @@
+ALTER TABLE users ALTER COLUMN password TYPE TEXT;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE login_tokens;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    -- TOTP secret encrypted with TOTP_ENCRYPTION_KEY, NULL when not enrolled
+    ADD COLUMN totp_secret BYTEA,
+    ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    -- SHA-256 of the unused recovery codes
+    ADD COLUMN totp_recovery_codes TEXT[] NOT NULL DEFAULT '{}';
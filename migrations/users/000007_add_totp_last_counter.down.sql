@@ -0,0 +1,2 @@
+ALTER TABLE users
+    DROP COLUMN totp_last_counter;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    -- Period counter of the last accepted TOTP code, so that codes cannot be replayed
+    ADD COLUMN totp_last_counter BIGINT NOT NULL DEFAULT 0;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    -- Name of the plan limiting the user, one of the plans configured with PLANS
+    ADD COLUMN plan VARCHAR(32) NOT NULL DEFAULT 'free';
\ No newline at end of file
@@ -0,0 +1,255 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// brotliLevel trades the ratio of Brotli for speed, as responses are
+// compressed as they are sent.
+const brotliLevel = 4
+
+// Content encodings of compressed responses, by order of preference.
+var compressionEncodings = []string{"br", "gzip"}
+
+// Compress is a middleware that compresses responses with Brotli or gzip,
+// whichever the client prefers in its Accept-Encoding header, Brotli on a
+// tie.
+//
+// Only successful and error responses of compressible types are compressed:
+// JSON, such as the lists of subscribers, HTML, such as the public archive
+// pages, and XML, such as the feeds. Responses are buffered until minSize
+// bytes are written, and sent uncompressed when they end before. Responses
+// already encoded, partial content and responses to HEAD requests are not
+// compressed. Compressed responses carry a weak ETag, as their bytes differ
+// from the uncompressed ones. Flushing a response, such as a stream of
+// Server-Sent Events, sends the buffered bytes right away.
+//
+// Usage:
+//
+//	http.ListenAndServe(":8001", Compress(1024)(router))
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Not deferred: when the handler panics, the buffered response is
+			// dropped for the one of Recover.
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+			next.ServeHTTP(cw, r)
+			if err := cw.Close(); err != nil {
+				slog.Debug("failed to send compressed response", "path", r.URL.Path, "error", err)
+			}
+		})
+	}
+}
+
+// acceptedEncoding returns the encoding of compressionEncodings with the
+// highest quality in the Accept-Encoding header, or an empty string when the
+// client accepts none of them.
+func acceptedEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		qualities[name] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range compressionEncodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// compressible reports whether responses of contentType are compressed.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch mediaType {
+	case "application/json", "text/html", "application/xml", "text/xml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// compressWriter compresses a response once it knows whether to: when
+// minSize bytes were written, the response is flushed or the handler
+// returns.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	encoder io.WriteCloser // Nil when the response is sent uncompressed
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if status < http.StatusOK {
+		// Informational responses, such as 103 Early Hints, precede the response.
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.minSize {
+			return len(p), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends the bytes written so far, compressing them if the response is
+// compressible whatever its size.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.decide(true); err != nil {
+			return
+		}
+	}
+
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close sends the rest of the response once the handler returned.
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			// Nothing was written: net/http answers 200 OK with no body.
+			return nil
+		}
+		if err := cw.decide(len(cw.buf) >= cw.minSize); err != nil {
+			return err
+		}
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
+
+// decide writes the header of the response, compressed when compress is
+// true and the response can be, followed by the bytes buffered so far.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	header := cw.Header()
+
+	if compress {
+		contentType := header.Get("Content-Type")
+		if contentType == "" && len(cw.buf) > 0 {
+			// Sniffed now, as net/http would sniff the compressed bytes.
+			contentType = http.DetectContentType(cw.buf)
+			header.Set("Content-Type", contentType)
+		}
+		compress = cw.status != http.StatusNoContent &&
+			cw.status != http.StatusNotModified &&
+			cw.status != http.StatusPartialContent &&
+			header.Get("Content-Encoding") == "" &&
+			header.Get("Content-Range") == "" &&
+			compressible(contentType)
+	}
+
+	if compress {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		switch cw.encoding {
+		case "br":
+			cw.encoder = brotli.NewWriterLevel(cw.ResponseWriter, brotliLevel)
+		default:
+			cw.encoder = gzip.NewWriter(cw.ResponseWriter)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	large := `[` + strings.Repeat(`{"email":"subscriber@example.com"},`, 100) + `{}]`
+	handler := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"v1"`)
+			io.WriteString(w, large)
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `[]`)
+		case "/image":
+			w.Header().Set("Content-Type", "image/gif")
+			io.WriteString(w, large)
+		}
+	}))
+
+	serve := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		rec := serve("/large", "gzip, deflate")
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		assert.Equal(t, `W/"v1"`, rec.Header().Get("ETag"))
+
+		reader, err := gzip.NewReader(rec.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, large, string(body))
+	})
+
+	t.Run("brotli preferred", func(t *testing.T) {
+		rec := serve("/large", "gzip, br")
+		assert.Equal(t, "br", rec.Header().Get("Content-Encoding"))
+
+		body, err := io.ReadAll(brotli.NewReader(rec.Body))
+		require.NoError(t, err)
+		assert.Equal(t, large, string(body))
+	})
+
+	t.Run("quality", func(t *testing.T) {
+		assert.Equal(t, "gzip", serve("/large", "br;q=0.5, gzip").Header().Get("Content-Encoding"))
+		assert.Empty(t, serve("/large", "br;q=0, gzip;q=0").Header().Get("Content-Encoding"))
+		assert.Empty(t, serve("/large", "identity").Header().Get("Content-Encoding"))
+	})
+
+	t.Run("below threshold", func(t *testing.T) {
+		rec := serve("/small", "gzip")
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, `[]`, rec.Body.String())
+	})
+
+	t.Run("not compressible", func(t *testing.T) {
+		rec := serve("/image", "gzip")
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, rec.Body.String())
+	})
+}
@@ -0,0 +1,54 @@
+//go:build integration
+
+package http_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/require"
+
+	newslettertest "newsletter/internal/newsletters/domain/domaintest"
+	newsletterpostgres "newsletter/internal/newsletters/infrastructure/postgres"
+	subscriptiontest "newsletter/internal/subscriptions/domain/domaintest"
+	subscriptionfirebase "newsletter/internal/subscriptions/infrastructure/firebase"
+)
+
+// The repository contracts run against the backends started by TestMain.
+
+func TestSubscriptionRepositoryContract_Firestore(t *testing.T) {
+	legacy := func(t *testing.T, newsletterID uuid.UUID) string {
+		ref, _, err := firestoreClient.Collection("subscriptions").Add(context.Background(), map[string]any{
+			"newsletterId":     newsletterID.String(),
+			"email":            "legacy-" + uuid.NewString() + "@example.com",
+			"unsubscribeToken": uuid.NewString(),
+			"createdAt":        time.Now(),
+		})
+		require.NoError(t, err)
+		return ref.ID
+	}
+
+	subscriptiontest.SubscriptionRepositoryContract(t, subscriptionfirebase.NewSubscriptionRepository(firestoreClient), legacy)
+}
+
+func TestNewsletterRepositoryContract_Postgres(t *testing.T) {
+	pool, err := pgxpool.Connect(context.Background(), os.Getenv("DSN"))
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	newOwner := func(t *testing.T) uuid.UUID {
+		var id uuid.UUID
+		err := pool.QueryRow(context.Background(),
+			`insert into users (password, email) values ('contract', $1) returning id`,
+			"contract-"+uuid.NewString()+"@example.com",
+		).Scan(&id)
+		require.NoError(t, err)
+		return id
+	}
+
+	newslettertest.NewsletterRepositoryContract(t, newsletterpostgres.NewNewsletterRepository(pool), newOwner)
+}
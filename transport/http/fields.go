@@ -0,0 +1,205 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"newsletter/transport/http/handler"
+	"slices"
+	"strings"
+)
+
+// SelectFields is a middleware that trims the JSON responses of listings to
+// the fields named by the "fields" query parameter, such as
+// ?fields=id,name, so that dashboards only download what they show.
+//
+// The fields are the top-level fields of the listed items: those of every
+// object of a JSON array, such as the newsletters of GET /newsletters, or of
+// the objects of the arrays of an envelope, such as the subscriptions of a
+// page of subscribers. The other fields of an envelope, such as next_cursor,
+// are kept. Unknown fields are ignored, and an empty parameter selects every
+// field.
+//
+// Only 200 OK JSON responses are trimmed. Handlers still load and cache
+// whole items; their ETag is derived into one naming the selected fields,
+// which If-None-Match is compared to.
+func SelectFields(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields := requestedFields(r.URL.Query().Get("fields"))
+		if len(fields) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// The handler does not know the ETag of the trimmed response.
+		ifNoneMatch := r.Header.Get("If-None-Match")
+		if ifNoneMatch != "" {
+			r = r.Clone(r.Context())
+			r.Header.Del("If-None-Match")
+		}
+
+		fw := &fieldsWriter{ResponseWriter: w}
+		next.ServeHTTP(fw, r)
+
+		body := fw.buf.Bytes()
+		header := w.Header()
+		if fw.status == http.StatusOK && isJSON(header.Get("Content-Type")) {
+			trimmed, err := selectFields(body, fields)
+			if err != nil {
+				slog.Warn("failed to select response fields", "path", r.URL.Path, "error", err)
+			} else {
+				body = trimmed
+				header.Del("Content-Length")
+				if etag := header.Get("ETag"); etag != "" {
+					etag = fieldsETag(etag, fields)
+					header.Set("ETag", etag)
+					if handler.ETagMatches(ifNoneMatch, etag) {
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+				}
+			}
+		}
+
+		if fw.status != 0 {
+			w.WriteHeader(fw.status)
+		}
+		if _, err := w.Write(body); err != nil {
+			slog.Debug("failed to write response", "path", r.URL.Path, "error", err)
+		}
+	})
+}
+
+// requestedFields returns the sorted, distinct field names of a fields
+// query parameter.
+func requestedFields(value string) []string {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+	return slices.Compact(fields)
+}
+
+// isJSON reports whether contentType is a JSON media type.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// selectFields returns body, a JSON array of items or an envelope of arrays
+// of items, keeping only fields in the items. Other values are returned as
+// they are.
+func selectFields(body []byte, fields []string) ([]byte, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return body, nil
+	}
+
+	switch trimmed[0] {
+	case '[':
+		items, err := selectItemFields(trimmed, fields)
+		if err != nil {
+			return nil, err
+		}
+		return append(items, '\n'), nil
+	case '{':
+		var envelope map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &envelope); err != nil {
+			return nil, err
+		}
+		for key, value := range envelope {
+			if len(value) == 0 || value[0] != '[' {
+				continue
+			}
+			items, err := selectItemFields(value, fields)
+			if err != nil {
+				return nil, err
+			}
+			envelope[key] = items
+		}
+		result, err := json.Marshal(envelope)
+		if err != nil {
+			return nil, err
+		}
+		return append(result, '\n'), nil
+	default:
+		return body, nil
+	}
+}
+
+// selectItemFields returns the JSON array items, keeping only fields in
+// its objects.
+func selectItemFields(array json.RawMessage, fields []string) (json.RawMessage, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(array, &items); err != nil {
+		return nil, err
+	}
+
+	for i, item := range items {
+		if len(item) == 0 || item[0] != '{' {
+			continue
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(item, &object); err != nil {
+			return nil, err
+		}
+		kept := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := object[field]; ok {
+				kept[field] = value
+			}
+		}
+		selected, err := json.Marshal(kept)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = selected
+	}
+
+	return json.Marshal(items)
+}
+
+// fieldsETag derives the entity tag of the response trimmed to fields from
+// the tag etag of the whole response.
+func fieldsETag(etag string, fields []string) string {
+	weak := strings.HasPrefix(etag, "W/")
+	opaque := strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	sum := sha256.Sum256([]byte(strings.Join(fields, ",")))
+	derived := `"` + opaque + "-" + base64.RawURLEncoding.EncodeToString(sum[:6]) + `"`
+	if weak {
+		return "W/" + derived
+	}
+	return derived
+}
+
+// fieldsWriter buffers a response for SelectFields.
+type fieldsWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (fw *fieldsWriter) WriteHeader(status int) {
+	if status < http.StatusOK {
+		// Informational responses, such as 103 Early Hints, precede the response.
+		fw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if fw.status == 0 {
+		fw.status = status
+	}
+}
+
+func (fw *fieldsWriter) Write(p []byte) (int, error) {
+	if fw.status == 0 {
+		fw.status = http.StatusOK
+	}
+	return fw.buf.Write(p)
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectFields(t *testing.T) {
+	handler := SelectFields(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/list":
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") != "" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			io.WriteString(w, `[{"id":"1","name":"Tech","description":"Tech news"},{"id":"2","name":"Science"}]`+"\n")
+		case "/page":
+			io.WriteString(w, `{"subscriptions":[{"id":"1","email":"a@example.com","status":"active"}],"next_cursor":"abc"}`+"\n")
+		case "/error":
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":"invalid cursor"}`)
+		}
+	}))
+
+	serve := func(target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("array", func(t *testing.T) {
+		rec := serve("/list?fields=name,id", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[{"id":"1","name":"Tech"},{"id":"2","name":"Science"}]`, rec.Body.String())
+		assert.NotEqual(t, `"v1"`, rec.Header().Get("ETag"))
+	})
+
+	t.Run("envelope", func(t *testing.T) {
+		rec := serve("/page?fields=email,unknown", "")
+		assert.JSONEq(t, `{"subscriptions":[{"email":"a@example.com"}],"next_cursor":"abc"}`, rec.Body.String())
+	})
+
+	t.Run("every field", func(t *testing.T) {
+		rec := serve("/list?fields=", "")
+		assert.Equal(t, `"v1"`, rec.Header().Get("ETag"))
+		assert.Contains(t, rec.Body.String(), "Tech news")
+	})
+
+	t.Run("errors are not trimmed", func(t *testing.T) {
+		rec := serve("/error?fields=id", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"invalid cursor"}`, rec.Body.String())
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		etag := serve("/list?fields=id,name", "").Header().Get("ETag")
+		assert.Equal(t, etag, serve("/list?fields=name,id", "").Header().Get("ETag"), "same fields in another order")
+		assert.NotEqual(t, etag, serve("/list?fields=id", "").Header().Get("ETag"))
+
+		rec := serve("/list?fields=id,name", etag)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+
+		// The tag of the whole listing does not match the trimmed one.
+		assert.Equal(t, http.StatusOK, serve("/list?fields=id,name", `"v1"`).Code)
+	})
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/activity/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"strconv"
+)
+
+// ActivityHandler handles HTTP requests related to the activity feed of
+// newsletters.
+type ActivityHandler struct {
+	as domain.ActivityService
+	ns newsletterdomain.NewsletterService
+}
+
+func NewActivityHandler(as domain.ActivityService, ns newsletterdomain.NewsletterService) *ActivityHandler {
+	return &ActivityHandler{as: as, ns: ns}
+}
+
+// Feed handles reading the activity feed of a newsletter.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/activity
+//
+// Description:
+//
+//	Returns a page of the events of a newsletter owned by the authenticated
+//	user, newest first: new subscribers, unsubscriptions, bounced campaign
+//	emails and completed campaigns. Pages are cursor based: pass the
+//	next_cursor of a response as the cursor of the next request.
+//
+// Query Parameters:
+//
+//	limit  (int, optional)     - Page size (default 50, max 100)
+//	cursor (string, optional)  - Cursor returned with the previous page
+//	fields (string, optional)  - Comma-separated fields of the items to return, such as id,name
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "events": [
+//	      {"type": "campaign_sent", "occurred_at": "2026-01-10T12:00:00Z", "campaign_id": "uuid", "post_id": "uuid"},
+//	      {"type": "bounced", "occurred_at": "2026-01-10T11:58:00Z", "email": "user@example.com", "campaign_id": "uuid", "detail": "mailbox full"},
+//	      {"type": "subscribed", "occurred_at": "2026-01-09T08:00:00Z", "email": "new@example.com"}
+//	    ],
+//	    "next_cursor": "opaque cursor, omitted on the last page"
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//	  - Invalid limit or cursor
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	500 Internal Server Error
+//	  - Failure reading the events
+func (ah *ActivityHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, ah.ns)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := ah.as.Feed(newsletter.ID, limit, query.Get("cursor"))
+	if err != nil {
+		WriteError(w, r, err, "failed to read activity")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		slog.Error("failed to encode activity response", "newsletter_id", newsletter.ID, "error", err)
+	}
+}
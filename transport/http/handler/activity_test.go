@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/activity/domain"
+	"newsletter/internal/infrastructure/pagination"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mock Activity Service ---
+type MockActivityService struct {
+	mock.Mock
+}
+
+func (m *MockActivityService) Feed(newsletterID uuid.UUID, limit int, cursor string) (*domain.EventPage, error) {
+	args := m.Called(newsletterID, limit, cursor)
+	page := args.Get(0)
+	if page == nil {
+		return nil, args.Error(1)
+	}
+	return page.(*domain.EventPage), args.Error(1)
+}
+
+// activityRequest builds a feed request on newsletter, authenticated as its owner.
+func activityRequest(newsletter *newsletterdomain.Newsletter, query string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/newsletters/"+newsletter.ID.String()+"/activity?"+query, nil)
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletter.ID.String()})
+	return req.WithContext(contextWithUserID(req.Context(), newsletter.OwnerID.String()))
+}
+
+func TestActivityFeed_Success(t *testing.T) {
+	mockAS, mockNS := new(MockActivityService), new(MockNewsletterService)
+	h := NewActivityHandler(mockAS, mockNS)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	at := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockAS.On("Feed", newsletter.ID, 1, "").Return(&domain.EventPage{
+		Events:     []*domain.Event{{Type: domain.EventSubscribed, ID: "sub-1", OccurredAt: at, Email: "a@example.com"}},
+		NextCursor: "next",
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.Feed(rec, activityRequest(newsletter, "limit=1"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"events":[{"type":"subscribed","occurred_at":"2026-01-10T12:00:00Z","email":"a@example.com"}],"next_cursor":"next"}`, rec.Body.String())
+}
+
+func TestActivityFeed_InvalidRequest(t *testing.T) {
+	mockAS, mockNS := new(MockActivityService), new(MockNewsletterService)
+	h := NewActivityHandler(mockAS, mockNS)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockAS.On("Feed", newsletter.ID, 0, "garbage").Return(nil, pagination.ErrInvalidCursor)
+
+	rec := httptest.NewRecorder()
+	h.Feed(rec, activityRequest(newsletter, "limit=-1"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.Feed(rec, activityRequest(newsletter, "cursor=garbage"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,372 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	admindomain "newsletter/internal/admin/domain"
+	campaigndomain "newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/errorlog"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxRecentErrors is the maximum number of errors returned by Errors.
+const maxRecentErrors = 100
+
+// RecentErrors is implemented by *errorlog.Recorder.
+type RecentErrors interface {
+	Recent(limit int) []errorlog.Entry
+}
+
+// RuntimeSettings are the settings of an instance that can change while it
+// runs, as applied by a configuration reload.
+type RuntimeSettings struct {
+	LogLevel               string   `json:"log_level"`
+	Workers                int      `json:"workers"`
+	CampaignsPerNewsletter int      `json:"campaigns_per_newsletter"`
+	AbuseThreshold         int      `json:"abuse_threshold"`
+	AbuseWindow            string   `json:"abuse_window"`
+	AbuseBlock             string   `json:"abuse_block"`
+	Changed                []string `json:"changed"` // Settings read again from the .env file that changed
+}
+
+// Reloader reloads the configuration of the instance.
+type Reloader interface {
+	Reload() (*RuntimeSettings, error)
+}
+
+// AdminHandler handles the system-wide administration API. Its routes are
+// restricted to administrators.
+type AdminHandler struct {
+	as       admindomain.AdminService
+	wp       PoolStatser
+	errors   RecentErrors
+	throttle campaigndomain.Throttle
+	reloader Reloader
+}
+
+// NewAdminHandler creates a new AdminHandler. errors may be nil when errors
+// are not recorded. throttle is the delivery throttling of campaigns.
+func NewAdminHandler(as admindomain.AdminService, wp PoolStatser, errors RecentErrors, throttle campaigndomain.Throttle) *AdminHandler {
+	return &AdminHandler{as: as, wp: wp, errors: errors, throttle: throttle}
+}
+
+// SetReloader sets what reloads the configuration on POST /admin/reload.
+func (ah *AdminHandler) SetReloader(reloader Reloader) {
+	ah.reloader = reloader
+}
+
+// adminQueueStats is the state of the job queues reported by Stats.
+type adminQueueStats struct {
+	Depth     int    `json:"depth"`
+	Capacity  int    `json:"capacity"`
+	Scheduled int    `json:"scheduled"`
+	Processed uint64 `json:"processed"`
+	Failed    uint64 `json:"failed"`
+	Rejected  uint64 `json:"rejected"`
+	Dropped   uint64 `json:"dropped"`
+}
+
+// Stats handles retrieving the system-wide statistics.
+//
+// Route:
+//
+//	GET /admin/stats
+//
+// Description:
+//
+//	Returns the number of users, newsletters and active subscriptions of the
+//	whole system, the campaign emails sent since midnight UTC and the state
+//	of the job queues of this instance. Job counters are cumulative since
+//	the instance started.
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "users": 120,
+//	    "newsletters": 45,
+//	    "subscriptions": 9800,
+//	    "emails_sent_today": 3100,
+//	    "queue": {
+//	      "depth": 4,
+//	      "capacity": 300,
+//	      "scheduled": 1,
+//	      "processed": 5230,
+//	      "failed": 12,
+//	      "rejected": 0,
+//	      "dropped": 0
+//	    },
+//	    "generated_at": "2026-01-10T12:00:00Z"
+//	  }
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	403 Forbidden
+//	  - The user is not an administrator
+//
+//	500 Internal Server Error
+//	  - Failed to count the totals
+func (ah *AdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	totals, err := ah.as.Totals()
+	if err != nil {
+		WriteError(w, r, err, "failed to compute statistics")
+		return
+	}
+
+	jobs := ah.wp.Stats()
+	response := struct {
+		*admindomain.Totals
+		Queue       adminQueueStats `json:"queue"`
+		GeneratedAt time.Time       `json:"generated_at"`
+	}{
+		Totals: totals,
+		Queue: adminQueueStats{
+			Depth:     jobs.QueueDepth,
+			Capacity:  jobs.Capacity,
+			Scheduled: jobs.Scheduled,
+			Processed: jobs.Processed,
+			Failed:    jobs.Failed,
+			Rejected:  jobs.Rejected,
+			Dropped:   jobs.Dropped,
+		},
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode admin stats response", "error", err)
+	}
+}
+
+// Errors handles listing the errors recently logged by the API.
+//
+// Route:
+//
+//	GET /admin/errors
+//
+// Description:
+//
+//	Returns the most recent errors logged by this instance, newest first,
+//	with their structured attributes. Errors are kept in memory and lost
+//	when the instance restarts.
+//
+// Query Parameters:
+//
+//	limit  (int, optional)  - Maximum number of errors (default and max 100)
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "errors": [
+//	      {
+//	        "time": "2026-01-10T12:00:00Z",
+//	        "message": "failed to send campaign email",
+//	        "attrs": {"campaign_id": "uuid", "error": "throttled"}
+//	      }
+//	    ]
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid limit
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	403 Forbidden
+//	  - The user is not an administrator
+func (ah *AdminHandler) Errors(w http.ResponseWriter, r *http.Request) {
+	limit := maxRecentErrors
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxRecentErrors)
+	}
+
+	entries := []errorlog.Entry{}
+	if ah.errors != nil {
+		entries = append(entries, ah.errors.Recent(limit)...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"errors": entries}); err != nil {
+		slog.Error("failed to encode admin errors response", "error", err)
+	}
+}
+
+// writeThrottling writes the throttling limits and the campaigns being sent
+// by newsletter.
+func (ah *AdminHandler) writeThrottling(w http.ResponseWriter) {
+	response := struct {
+		campaigndomain.ThrottleLimits
+		Running map[uuid.UUID]int `json:"running"`
+	}{
+		ThrottleLimits: ah.throttle.Limits(),
+		Running:        ah.throttle.Running(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode throttling response", "error", err)
+	}
+}
+
+// Throttling handles retrieving the delivery throttling of campaigns.
+//
+// Route:
+//
+//	GET /admin/throttling
+//
+// Description:
+//
+//	Returns how many campaigns of a newsletter this instance sends at once,
+//	by default and for the newsletters with their own cap, and how many
+//	campaigns each newsletter is sending right now. Campaigns take turns on
+//	the workers page by page; a campaign whose newsletter is at its cap
+//	waits until one of its other campaigns finishes a page.
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "per_newsletter": 1,
+//	    "overrides": {"uuid": 3},
+//	    "running": {"uuid": 1}
+//	  }
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	403 Forbidden
+//	  - The user is not an administrator
+func (ah *AdminHandler) Throttling(w http.ResponseWriter, r *http.Request) {
+	ah.writeThrottling(w)
+}
+
+// UpdateThrottling handles changing the delivery throttling of campaigns.
+//
+// Route:
+//
+//	PUT /admin/throttling
+//
+// Description:
+//
+//	Replaces the caps on the campaigns of a newsletter sent at once. Caps
+//	apply to this instance only and until it restarts, when
+//	CAMPAIGN_CONCURRENCY_PER_NEWSLETTER applies again; reloading the
+//	configuration also applies it again as the default cap, keeping the
+//	caps of single newsletters. Campaigns above a lowered cap finish their
+//	current page first.
+//
+// Request Body:
+//
+//	{
+//	  "per_newsletter": 2,               // Required, at least 1
+//	  "overrides": {"uuid": 4}           // Optional, caps of single newsletters, at least 1
+//	}
+//
+// Responses:
+//
+//	200 OK
+//	  - The new limits, as returned by GET /admin/throttling
+//
+//	400 Bad Request
+//	  - Invalid request body or limits
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	403 Forbidden
+//	  - The user is not an administrator
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not application/json
+//
+// Side Effects:
+//
+//   - Campaigns waiting for a slot of their newsletter may start sending
+func (ah *AdminHandler) UpdateThrottling(w http.ResponseWriter, r *http.Request) {
+	var limits campaigndomain.ThrottleLimits
+	if !decodeJSON(w, r, &limits, maxBodyBytes) {
+		return
+	}
+
+	if err := ah.throttle.SetLimits(limits); err != nil {
+		WriteError(w, r, err, "failed to update throttling")
+		return
+	}
+
+	slog.Info("campaign throttling updated", "per_newsletter", limits.PerNewsletter, "overrides", len(limits.Overrides))
+	ah.writeThrottling(w)
+}
+
+// Reload handles reloading the configuration of the instance.
+//
+// Route:
+//
+//	POST /admin/reload
+//
+// Description:
+//
+//	Reads the reloadable settings again, as on SIGHUP, and applies them
+//	without a restart: the log level (LOG_LEVEL), the number of workers
+//	(WORKERS), the default number of campaigns of a newsletter sent at
+//	once (CAMPAIGN_CONCURRENCY_PER_NEWSLETTER) and the limits on clients
+//	guessing tokens (ABUSE_THRESHOLD, ABUSE_WINDOW and ABUSE_BLOCK).
+//	Settings are read from the .env file, except those set by the process
+//	environment. Nothing is applied when a setting is invalid. Settings
+//	apply to this instance only.
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "log_level": "INFO",
+//	    "workers": 8,
+//	    "campaigns_per_newsletter": 2,
+//	    "abuse_threshold": 20,
+//	    "abuse_window": "1m0s",
+//	    "abuse_block": "15m0s",
+//	    "changed": ["WORKERS"]
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid setting; the previous settings are kept
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	403 Forbidden
+//	  - The user is not an administrator
+//
+//	500 Internal Server Error
+//	  - The .env file cannot be read
+//
+// Side Effects:
+//
+//   - Removed workers stop after their current job, so that campaign sends
+//     in flight are not interrupted
+func (ah *AdminHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	settings, err := ah.reloader.Reload()
+	if err != nil {
+		WriteError(w, r, err, "failed to reload the configuration")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(settings); err != nil {
+		slog.Error("failed to encode reload response", "error", err)
+	}
+}
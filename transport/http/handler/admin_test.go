@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/config"
+	admindomain "newsletter/internal/admin/domain"
+	campaignapp "newsletter/internal/campaigns/application"
+	"newsletter/internal/infrastructure/errorlog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mock Admin Service ---
+type MockAdminService struct {
+	mock.Mock
+}
+
+func (m *MockAdminService) Totals() (*admindomain.Totals, error) {
+	args := m.Called()
+	totals := args.Get(0)
+	if totals == nil {
+		return nil, args.Error(1)
+	}
+	return totals.(*admindomain.Totals), args.Error(1)
+}
+
+type fakeRecentErrors []errorlog.Entry
+
+func (f fakeRecentErrors) Recent(limit int) []errorlog.Entry {
+	return f[:min(limit, len(f))]
+}
+
+func TestAdminStats_Success(t *testing.T) {
+	mockAS := new(MockAdminService)
+	mockAS.On("Totals").Return(&admindomain.Totals{Users: 3, Newsletters: 5, Subscriptions: 42, EmailsSentToday: 7}, nil)
+	h := NewAdminHandler(mockAS, fakePoolStats{QueueDepth: 4, Capacity: 300, Failed: 2}, nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.Stats(rec, httptest.NewRequest(http.MethodGet, "/admin/stats", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `"users":3,"newsletters":5,"subscriptions":42,"emails_sent_today":7`)
+	assert.Contains(t, body, `"queue":{"depth":4,"capacity":300,"scheduled":0,"processed":0,"failed":2`)
+}
+
+func TestAdminStats_Failure(t *testing.T) {
+	mockAS := new(MockAdminService)
+	mockAS.On("Totals").Return(nil, errors.New("db down"))
+	h := NewAdminHandler(mockAS, fakePoolStats{}, nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.Stats(rec, httptest.NewRequest(http.MethodGet, "/admin/stats", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestAdminErrors(t *testing.T) {
+	recorded := fakeRecentErrors{
+		{Time: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), Message: "failed to send campaign email", Attrs: map[string]string{"error": "throttled"}},
+		{Time: time.Date(2026, 1, 10, 11, 0, 0, 0, time.UTC), Message: "older"},
+	}
+	h := NewAdminHandler(new(MockAdminService), fakePoolStats{}, recorded, nil)
+
+	rec := httptest.NewRecorder()
+	h.Errors(rec, httptest.NewRequest(http.MethodGet, "/admin/errors?limit=1", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"errors":[{"time":"2026-01-10T12:00:00Z","message":"failed to send campaign email","attrs":{"error":"throttled"}}]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h.Errors(rec, httptest.NewRequest(http.MethodGet, "/admin/errors?limit=zero", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAdminThrottling(t *testing.T) {
+	throttle := campaignapp.NewThrottle(1)
+	newsletterID := uuid.New()
+	throttle.Acquire(newsletterID)
+	h := NewAdminHandler(new(MockAdminService), fakePoolStats{}, nil, throttle)
+
+	rec := httptest.NewRecorder()
+	h.Throttling(rec, httptest.NewRequest(http.MethodGet, "/admin/throttling", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"per_newsletter":1,"running":{"`+newsletterID.String()+`":1}}`, rec.Body.String())
+
+	body := `{"per_newsletter":2,"overrides":{"` + newsletterID.String() + `":4}}`
+	rec = httptest.NewRecorder()
+	h.UpdateThrottling(rec, httptest.NewRequest(http.MethodPut, "/admin/throttling", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"per_newsletter":2,"overrides":{"`+newsletterID.String()+`":4},"running":{"`+newsletterID.String()+`":1}}`, rec.Body.String())
+	assert.Equal(t, 4, throttle.Limits().Limit(newsletterID))
+
+	rec = httptest.NewRecorder()
+	h.UpdateThrottling(rec, httptest.NewRequest(http.MethodPut, "/admin/throttling", strings.NewReader(`{"per_newsletter":0}`)))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, 2, throttle.Limits().PerNewsletter)
+}
+
+type fakeReloader struct {
+	settings *RuntimeSettings
+	err      error
+}
+
+func (f fakeReloader) Reload() (*RuntimeSettings, error) {
+	return f.settings, f.err
+}
+
+func TestAdminReload(t *testing.T) {
+	h := NewAdminHandler(new(MockAdminService), fakePoolStats{}, nil, nil)
+	h.SetReloader(fakeReloader{settings: &RuntimeSettings{
+		LogLevel: "DEBUG", Workers: 4, CampaignsPerNewsletter: 2,
+		AbuseThreshold: 20, AbuseWindow: "1m0s", AbuseBlock: "15m0s", Changed: []string{"LOG_LEVEL"},
+	}})
+
+	rec := httptest.NewRecorder()
+	h.Reload(rec, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"log_level":"DEBUG","workers":4,"campaigns_per_newsletter":2,"abuse_threshold":20,"abuse_window":"1m0s","abuse_block":"15m0s","changed":["LOG_LEVEL"]}`, rec.Body.String())
+
+	h.SetReloader(fakeReloader{err: fmt.Errorf("%w: WORKERS must be at least 1, got 0", config.ErrInvalidSettings)})
+	rec = httptest.NewRecorder()
+	h.Reload(rec, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "WORKERS must be at least 1")
+}
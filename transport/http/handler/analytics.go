@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/analytics/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"time"
+)
+
+// defaultAnalyticsDays is the number of days of the growth series returned
+// when no range is requested.
+const defaultAnalyticsDays = 30
+
+// AnalyticsHandler handles HTTP requests related to newsletter analytics.
+type AnalyticsHandler struct {
+	as domain.AnalyticsService
+	ns newsletterdomain.NewsletterService
+}
+
+func NewAnalyticsHandler(as domain.AnalyticsService, ns newsletterdomain.NewsletterService) *AnalyticsHandler {
+	return &AnalyticsHandler{as: as, ns: ns}
+}
+
+// Growth handles reading the subscriber growth of a newsletter.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/analytics[?from=2026-01-01&to=2026-01-31&granularity=day]
+//
+// Description:
+//
+//	Returns a time series of the subscribers of the newsletter, suitable
+//	for charts. Each point covers a day, an ISO week (starting on Monday) or
+//	a month, in UTC, and holds the number of active subscribers at its end
+//	and the subscriptions and unsubscriptions that happened during it. The
+//	first and last points only count the days within the range.
+//
+// Query Parameters:
+//
+//	from        (date, optional)    - First day, YYYY-MM-DD; defaults to 29 days before to
+//	to          (date, optional)    - Last day, YYYY-MM-DD; defaults to today
+//	granularity (string, optional)  - "day" (default), "week" or "month"
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "newsletter_id": "uuid",
+//	    "from": "2026-01-01T00:00:00Z",
+//	    "to": "2026-01-31T00:00:00Z",
+//	    "granularity": "day",
+//	    "points": [
+//	      {"date": "2026-01-01T00:00:00Z", "subscribers": 120, "subscribed": 4, "unsubscribed": 1}
+//	    ]
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//	  - Invalid date, granularity or range (at most 1000 points)
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	500 Internal Server Error
+//	  - Failure reading the subscription history
+func (ah *AnalyticsHandler) Growth(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, ah.ns)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	to := time.Now().UTC()
+	if value := query.Get("to"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			http.Error(w, "invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -(defaultAnalyticsDays - 1))
+	if value := query.Get("from"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			http.Error(w, "invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+
+	granularity := domain.Granularity(query.Get("granularity"))
+	if granularity == "" {
+		granularity = domain.GranularityDay
+	}
+
+	series, err := ah.as.Growth(newsletter.ID, from, to, granularity)
+	if err != nil {
+		WriteError(w, r, err, "failed to compute analytics")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(series); err != nil {
+		slog.Error("failed to encode analytics response", "error", err)
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/analytics/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mock Analytics Service ---
+type MockAnalyticsService struct {
+	mock.Mock
+}
+
+func (m *MockAnalyticsService) Growth(newsletterID uuid.UUID, from, to time.Time, granularity domain.Granularity) (*domain.Series, error) {
+	args := m.Called(newsletterID, from, to, granularity)
+	series := args.Get(0)
+	if series == nil {
+		return nil, args.Error(1)
+	}
+	return series.(*domain.Series), args.Error(1)
+}
+
+// analyticsRequest builds a growth request on newsletter, authenticated as its owner.
+func analyticsRequest(newsletter *newsletterdomain.Newsletter, query string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/newsletters/"+newsletter.ID.String()+"/analytics?"+query, nil)
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletter.ID.String()})
+	return req.WithContext(contextWithUserID(req.Context(), newsletter.OwnerID.String()))
+}
+
+func TestAnalyticsGrowth_Success(t *testing.T) {
+	mockAS, mockNS := new(MockAnalyticsService), new(MockNewsletterService)
+	h := NewAnalyticsHandler(mockAS, mockNS)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockAS.On("Growth", newsletter.ID, from, to, domain.GranularityMonth).Return(&domain.Series{
+		NewsletterID: newsletter.ID, From: from, To: to, Granularity: domain.GranularityMonth,
+		Points: []domain.Point{{Date: from, Subscribers: 3, Subscribed: 3}},
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.Growth(rec, analyticsRequest(newsletter, "from=2026-01-01&to=2026-03-31&granularity=month"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"points":[{"date":"2026-01-01T00:00:00Z","subscribers":3,"subscribed":3,"unsubscribed":0}]`)
+}
+
+func TestAnalyticsGrowth_InvalidRequest(t *testing.T) {
+	mockAS, mockNS := new(MockAnalyticsService), new(MockNewsletterService)
+	h := NewAnalyticsHandler(mockAS, mockNS)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockAS.On("Growth", newsletter.ID, mock.Anything, mock.Anything, domain.Granularity("hour")).Return(nil, domain.ErrInvalidGranularity)
+
+	rec := httptest.NewRecorder()
+	h.Growth(rec, analyticsRequest(newsletter, "from=01/02/2026"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.Growth(rec, analyticsRequest(newsletter, "granularity=hour"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
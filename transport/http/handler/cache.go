@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxCachedResponses bounds the number of entries of a responseCache.
+const maxCachedResponses = 1000
+
+// responseCache keeps recently rendered responses in memory so that clients
+// polling a listing do not hit the database on every request. Entries expire
+// after ttl and are dropped early when the data of their owner changes
+// through the same process; other instances may serve them until they expire.
+//
+// A nil or zero-TTL cache caches nothing.
+type responseCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+// cachedResponse is a rendered response body, its entity tag and the
+// headers written along with them.
+type cachedResponse struct {
+	owner   uuid.UUID
+	body    []byte
+	etag    string
+	header  http.Header
+	expires time.Time
+}
+
+// newResponseCache returns a cache keeping responses for ttl.
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: map[string]cachedResponse{}}
+}
+
+// get returns the unexpired response stored under key.
+func (c *responseCache) get(key string) (cachedResponse, bool) {
+	if c == nil || c.ttl <= 0 {
+		return cachedResponse{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return cachedResponse{}, false
+	}
+	return entry, true
+}
+
+// put stores the response of owner under key.
+func (c *responseCache) put(owner uuid.UUID, key string, body []byte, etag string, header http.Header) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxCachedResponses {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCachedResponses {
+			c.entries = map[string]cachedResponse{}
+		}
+	}
+
+	c.entries[key] = cachedResponse{owner: owner, body: body, etag: etag, header: header, expires: now.Add(c.ttl)}
+}
+
+// invalidate drops every response of owner.
+func (c *responseCache) invalidate(owner uuid.UUID) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, entry := range c.entries {
+		if entry.owner == owner {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// entityTag returns a strong entity tag identifying body.
+func entityTag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// ETagMatches reports whether the If-None-Match header value names etag.
+// As required for If-None-Match, weak tags match their strong counterpart.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeWithETag writes body as a 200 OK response with its entity tag, or an
+// empty 304 Not Modified response when the client already has it.
+func writeWithETag(w http.ResponseWriter, r *http.Request, contentType string, body []byte, etag string) {
+	w.Header().Set("ETag", etag)
+	if ETagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		slog.Error("failed to write response", "path", r.URL.Path, "error", err)
+	}
+}
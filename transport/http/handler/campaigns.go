@@ -0,0 +1,944 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/i18n"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/internal/infrastructure/workerpool/jobs"
+	linkdomain "newsletter/internal/links/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	notifications "newsletter/internal/notifications/domain"
+	postdomain "newsletter/internal/posts/domain"
+	segmentdomain "newsletter/internal/segments/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// CampaignHandler handles HTTP requests related to the progress of campaigns
+// and pausing or resuming them.
+type CampaignHandler struct {
+	cs domain.CampaignService
+	ns newsletterdomain.NewsletterService
+
+	campaigns *campaignRunner
+
+	// pollInterval is how often Events checks a campaign for progress.
+	pollInterval time.Duration
+}
+
+// campaignHeartbeat is how long Events stays silent before it writes a
+// comment, so that proxies do not close an idle stream.
+const campaignHeartbeat = 15 * time.Second
+
+// NewCampaignHandler creates a new CampaignHandler. links builds the
+// unsubscribe links and open tracking pixels of campaign emails; throttle,
+// which may be nil, caps the campaigns sent at once by newsletter; segments
+// resolves the segments campaigns are sent to.
+func NewCampaignHandler(cs domain.CampaignService, ps postdomain.PostService, ns newsletterdomain.NewsletterService, ss subscriptiondomain.SubscriptionService, es notifications.EmailService, wp workerpool.JobQueue, links *LinkBuilder, throttle domain.Throttle, segments segmentdomain.SegmentService) *CampaignHandler {
+	return &CampaignHandler{
+		cs: cs,
+		ns: ns,
+
+		campaigns: &campaignRunner{cs: cs, ps: ps, ns: ns, ss: ss, es: es, wp: wp, links: links, throttle: throttle, segments: segments},
+
+		pollInterval: time.Second,
+	}
+}
+
+// SetClickTracking makes the campaigns started by the handler replace the
+// links of their emails with short links counting clicks; nil disables it.
+func (ch *CampaignHandler) SetClickTracking(shortener linkdomain.LinkService) {
+	ch.campaigns.shortener = shortener
+}
+
+// writeCampaign writes campaign as a JSON response with the given status code.
+func writeCampaign(w http.ResponseWriter, status int, campaign *domain.Campaign) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(campaign); err != nil {
+		slog.Error("failed to encode campaign response", "campaign_id", campaign.ID, "error", err)
+	}
+}
+
+// ownedCampaign loads the campaign from the path and checks that its
+// newsletter belongs to the authenticated user. It writes an error response
+// and returns false otherwise.
+func (ch *CampaignHandler) ownedCampaign(w http.ResponseWriter, r *http.Request) (*domain.Campaign, bool) {
+	ownerID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["campaign_id"])
+	if err != nil {
+		http.Error(w, "invalid campaign ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	campaign, err := ch.cs.Get(id)
+	if err != nil {
+		WriteError(w, r, err, "failed to get campaign")
+		return nil, false
+	}
+
+	newsletter, err := ch.ns.Get(campaign.NewsletterID)
+	if err != nil || newsletter.OwnerID != ownerID {
+		WriteError(w, r, domain.ErrCampaignNotFound, "failed to get campaign")
+		return nil, false
+	}
+
+	return campaign, true
+}
+
+// Get handles retrieving the progress of a campaign.
+//
+// Route:
+//
+//	GET /campaigns/{campaign_id}
+//
+// Description:
+//
+//	Returns the status of a campaign and the number of recipients the post
+//	was sent to, could not be sent to, or whose delivery is in progress.
+//	Campaigns testing two subject lines also report how many recipients of
+//	each subject opened the email, and the winner once it was chosen.
+//	Campaigns with a send window report when the subscribers waiting for
+//	their window are emailed next.
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "id": "uuid",
+//	    "newsletter_id": "uuid",
+//	    "post_id": "uuid",
+//	    "status": "queued" | "sending" | "testing" | "paused" | "completed" | "failed",
+//	    "sent": 120,
+//	    "failed": 2,
+//	    "pending": 1,
+//	    "ab_test": {
+//	      "subject_a": "Issue #1",
+//	      "subject_b": "You will not believe issue #1",
+//	      "sample_percent": 10,
+//	      "window_minutes": 240,
+//	      "ends_at": "2026-01-10T16:00:30Z",
+//	      "winner": "b",
+//	      "a": {"sent": 50, "opened": 12},
+//	      "b": {"sent": 48, "opened": 20}
+//	    },
+//	    "send_window": {"start": "09:00", "end": "17:00", "timezone": "Europe/Berlin"},
+//	    "next_batch_at": "2026-01-11T08:00:00Z",
+//	    "error": "reason of a failed campaign",
+//	    "created_at": "2026-01-10T12:00:00Z",
+//	    "started_at": "2026-01-10T12:00:01Z",
+//	    "completed_at": "2026-01-10T12:05:00Z",
+//	    "updated_at": "2026-01-10T12:05:00Z"
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid campaign ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Campaign does not exist or belongs to another user's newsletter
+func (ch *CampaignHandler) Get(w http.ResponseWriter, r *http.Request) {
+	campaign, ok := ch.ownedCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	writeCampaign(w, http.StatusOK, campaign)
+}
+
+// Events handles streaming the progress of a campaign.
+//
+// Route:
+//
+//	GET /campaigns/{campaign_id}/events
+//
+// Description:
+//
+//	Streams the progress of a campaign as Server-Sent Events, so that a
+//	dashboard can follow a large send without polling. A "progress" event
+//	carrying the campaign is sent when the stream opens and whenever its
+//	status or delivery counters change. The stream ends with a "completed"
+//	or "failed" event once the campaign finishes; paused campaigns keep the
+//	stream open until they are resumed or the client disconnects.
+//
+// Responses:
+//
+//	200 OK (text/event-stream)
+//	  event: progress
+//	  data: {"id": "uuid", "status": "sending", "sent": 120, "failed": 2, "pending": 1, ...}
+//
+//	  event: completed
+//	  data: {"id": "uuid", "status": "completed", "sent": 5000, "failed": 3, "pending": 0, ...}
+//
+//	400 Bad Request
+//	  - Invalid campaign ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Campaign does not exist or belongs to another user's newsletter
+//
+//	500 Internal Server Error
+//	  - The server does not support streaming responses
+func (ch *CampaignHandler) Events(w http.ResponseWriter, r *http.Request) {
+	campaign, ok := ch.ownedCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(ch.pollInterval)
+	defer ticker.Stop()
+
+	var last *domain.Campaign
+	lastWrite := time.Now()
+	for {
+		if last == nil || campaignChanged(last, campaign) {
+			event := "progress"
+			switch campaign.Status {
+			case domain.StatusCompleted, domain.StatusFailed:
+				event = campaign.Status
+			}
+			if err := writeCampaignEvent(w, event, campaign); err != nil {
+				slog.Warn("failed to write campaign event", "campaign_id", campaign.ID, "error", err)
+				return
+			}
+			flusher.Flush()
+			if event != "progress" {
+				return
+			}
+			last, lastWrite = campaign, time.Now()
+		} else if time.Since(lastWrite) >= campaignHeartbeat {
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			lastWrite = time.Now()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := ch.cs.Get(campaign.ID)
+		if err != nil {
+			slog.Error("failed to poll campaign", "campaign_id", campaign.ID, "error", err)
+			continue
+		}
+		campaign = current
+	}
+}
+
+// campaignChanged reports whether the status or counters of a campaign
+// differ between two reads.
+func campaignChanged(before, after *domain.Campaign) bool {
+	return before.Status != after.Status ||
+		before.Sent != after.Sent ||
+		before.Failed != after.Failed ||
+		before.Pending != after.Pending
+}
+
+// writeCampaignEvent writes campaign as a Server-Sent Event of the given type.
+func writeCampaignEvent(w http.ResponseWriter, event string, campaign *domain.Campaign) error {
+	data, err := json.Marshal(campaign)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// Pause handles pausing a campaign.
+//
+// Route:
+//
+//	POST /campaigns/{campaign_id}/pause
+//
+// Description:
+//
+//	Stops a queued or sending campaign. Deliveries in progress finish, and
+//	the campaign stops before the next page of recipients.
+//
+// Responses:
+//
+//	200 OK
+//	  - The paused campaign
+//
+//	400 Bad Request
+//	  - Invalid campaign ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Campaign does not exist or belongs to another user's newsletter
+//
+//	409 Conflict
+//	  - The campaign is not queued or sending
+func (ch *CampaignHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	campaign, ok := ch.ownedCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	paused, err := ch.cs.Pause(campaign.ID)
+	if err != nil {
+		WriteError(w, r, err, "failed to pause campaign")
+		return
+	}
+
+	writeCampaign(w, http.StatusOK, paused)
+}
+
+// Resume handles resuming a paused or failed campaign.
+//
+// Route:
+//
+//	POST /campaigns/{campaign_id}/resume
+//
+// Description:
+//
+//	Queues the campaign again. Sending continues with the recipients that
+//	have not been emailed yet; nobody receives the post twice.
+//
+// Responses:
+//
+//	202 Accepted
+//	  - The queued campaign
+//
+//	400 Bad Request
+//	  - Invalid campaign ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Campaign does not exist or belongs to another user's newsletter
+//
+//	409 Conflict
+//	  - The campaign is not paused or failed
+//
+// Side Effects:
+//   - Sends the remaining emails in the background
+func (ch *CampaignHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	campaign, ok := ch.ownedCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	resumed, err := ch.cs.Resume(campaign.ID)
+	if err != nil {
+		WriteError(w, r, err, "failed to resume campaign")
+		return
+	}
+	ch.campaigns.enqueue(resumed)
+
+	writeCampaign(w, http.StatusAccepted, resumed)
+}
+
+// Deliveries handles listing the per-recipient delivery log of a campaign.
+//
+// Route:
+//
+//	GET /campaigns/{campaign_id}/deliveries
+//
+// Description:
+//
+//	Returns the deliveries of a campaign in the order they were made, to
+//	troubleshoot why a recipient did not receive a post. Deliveries are
+//	updated with the delivery, bounce and complaint events reported by SES.
+//
+// Query Parameters:
+//
+//	email   (string, optional)  - Only the delivery to this address
+//	status  (string, optional)  - pending | sent | failed | delivered | bounced | complained
+//	limit   (int, optional)     - Page size (default 50, max 100)
+//	cursor  (string, optional)  - Cursor returned with the previous page
+//	fields  (string, optional)  - Comma-separated fields of the items to return, such as id,name
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "deliveries": [
+//	      {
+//	        "campaign_id": "uuid",
+//	        "email": "reader@example.com",
+//	        "status": "bounced",
+//	        "variant": "a",
+//	        "message_id": "0100018c...",
+//	        "error": "Permanent/General",
+//	        "created_at": "2026-01-10T12:00:01Z",
+//	        "sent_at": "2026-01-10T12:00:02Z",
+//	        "opened_at": "2026-01-10T12:03:40Z",
+//	        "updated_at": "2026-01-10T12:00:09Z"
+//	      }
+//	    ],
+//	    "next_cursor": "opaque"
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid campaign ID, status, limit or cursor
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Campaign does not exist or belongs to another user's newsletter
+func (ch *CampaignHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	campaign, ok := ch.ownedCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter := domain.DeliveryFilter{Email: query.Get("email"), Status: query.Get("status")}
+	page, err := ch.cs.Deliveries(campaign.ID, filter, limit, query.Get("cursor"))
+	if err != nil {
+		WriteError(w, r, err, "failed to list deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		slog.Error("failed to encode deliveries response", "campaign_id", campaign.ID, "error", err)
+	}
+}
+
+// Replies handles listing the replies to a campaign.
+//
+// Route:
+//
+//	GET /campaigns/{campaign_id}/replies
+//
+// Description:
+//
+//	Returns the emails recipients sent in answer to the emails of a
+//	campaign, in the order they were received, without the quoted campaign
+//	email. Replies are received by the SES inbound webhook; the owner is
+//	emailed each of them as well.
+//
+// Query Parameters:
+//
+//	limit   (int, optional)     - Page size (default 50, max 100)
+//	cursor  (string, optional)  - Cursor returned with the previous page
+//	fields  (string, optional)  - Comma-separated fields of the items to return, such as id,name
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "replies": [
+//	      {
+//	        "id": "uuid",
+//	        "campaign_id": "uuid",
+//	        "email": "reader@example.com",
+//	        "subject": "Re: Issue #1",
+//	        "text": "Thanks, I loved this issue!",
+//	        "received_at": "2026-01-10T12:30:00Z"
+//	      }
+//	    ],
+//	    "next_cursor": "opaque"
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid campaign ID, limit or cursor
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Campaign does not exist or belongs to another user's newsletter
+func (ch *CampaignHandler) Replies(w http.ResponseWriter, r *http.Request) {
+	campaign, ok := ch.ownedCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := ch.cs.Replies(campaign.ID, limit, query.Get("cursor"))
+	if err != nil {
+		WriteError(w, r, err, "failed to list replies")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		slog.Error("failed to encode replies response", "campaign_id", campaign.ID, "error", err)
+	}
+}
+
+// transparentGIF is the 1x1 transparent image served as open tracking pixel.
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// TrackOpen handles the open tracking pixel of campaign emails.
+//
+// Route:
+//
+//	GET /track/open/{tracking_id}
+//
+// Description:
+//
+//	Records that the email of a delivery was opened, when the mail client
+//	loads the pixel embedded in it. Only the first open is kept. Emails of
+//	A/B tests carry the pixel, so that the subject with the most opens can
+//	be chosen. The image is served for unknown tracking IDs as well, so
+//	that emails never show a broken image.
+//
+// Path Parameters:
+//
+//	tracking_id (UUID) - Tracking ID of the delivery
+//
+// Responses:
+//
+//	200 OK
+//	  - A 1x1 transparent GIF, never cached
+//
+// Side Effects:
+//   - Stores the open time of the delivery
+func (ch *CampaignHandler) TrackOpen(w http.ResponseWriter, r *http.Request) {
+	if id, err := uuid.Parse(mux.Vars(r)["tracking_id"]); err == nil {
+		if err := ch.cs.RecordOpen(id); err != nil {
+			slog.Error("failed to record open", "tracking_id", id, "error", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/gif")
+	w.Header().Set("Cache-Control", "no-store, private")
+	if _, err := w.Write(transparentGIF); err != nil {
+		slog.Warn("failed to write tracking pixel", "error", err)
+	}
+}
+
+// ResumeUnfinished queues again the campaigns that were queued or sending
+// when the process last stopped, and schedules the end of the A/B tests
+// that were running. It is called once at startup.
+func (ch *CampaignHandler) ResumeUnfinished() error {
+	campaigns, err := ch.cs.Unfinished()
+	if err != nil {
+		return err
+	}
+
+	for _, campaign := range campaigns {
+		slog.Info("resuming unfinished campaign", "campaign_id", campaign.ID, "status", campaign.Status)
+		ch.campaigns.enqueue(campaign)
+	}
+	return nil
+}
+
+// campaignRunner sends campaigns in the background. It is shared by the
+// handlers that start campaigns.
+type campaignRunner struct {
+	cs domain.CampaignService
+	ps postdomain.PostService
+	ns newsletterdomain.NewsletterService
+	ss subscriptiondomain.SubscriptionService
+	es notifications.EmailService
+	wp workerpool.JobQueue
+
+	links *LinkBuilder
+
+	// throttle caps the campaigns sent at once by newsletter; nil means no cap.
+	throttle domain.Throttle
+
+	// segments resolves the segments campaigns are sent to.
+	segments segmentdomain.SegmentService
+
+	// shortener replaces the links of emails with short links counting
+	// clicks; nil disables click tracking.
+	shortener linkdomain.LinkService
+}
+
+// trackedLink matches the href attributes of the http and https links of
+// sanitized post bodies, which are double quoted.
+var trackedLink = regexp.MustCompile(`href="(https?://[^"]*)"`)
+
+// trackClicks returns body, the HTML of the post of a campaign, with its
+// links replaced with short links counting their clicks. Links with merge
+// tags, which differ by recipient, are kept, as are links that cannot be
+// shortened: emails are sent without tracking rather than not at all.
+func (cr *campaignRunner) trackClicks(campaignID uuid.UUID, body string) string {
+	shortened := make(map[string]string) // By URL, empty when it is kept
+	return trackedLink.ReplaceAllStringFunc(body, func(attribute string) string {
+		target := html.UnescapeString(trackedLink.FindStringSubmatch(attribute)[1])
+		if strings.Contains(target, "{{") {
+			return attribute
+		}
+
+		short, ok := shortened[target]
+		if !ok {
+			if link, err := cr.shortener.Shorten(campaignID, target); err != nil {
+				slog.Warn("sending link without click tracking", "campaign_id", campaignID, "url", target, "error", err)
+			} else {
+				short = cr.links.ShortLink(link.Code)
+			}
+			shortened[target] = short
+		}
+		if short == "" {
+			return attribute
+		}
+		return `href="` + html.EscapeString(short) + `"`
+	})
+}
+
+// campaignThrottleDelay is how long a campaign waits before trying again
+// when the other campaigns of its newsletter hold all its sending slots.
+const campaignThrottleDelay = time.Second
+
+// enqueue submits the sending of campaign to the worker pool. Campaigns are
+// persisted before they are queued, so they wait for room in the queue
+// rather than being rejected by the overflow policy. Campaigns testing two
+// subjects are sent to the remainder of the subscribers when the test ends.
+func (cr *campaignRunner) enqueue(campaign *domain.Campaign) {
+	if campaign.Status == domain.StatusTesting && campaign.ABTest != nil && campaign.ABTest.EndsAt != nil {
+		cr.wp.SubmitAt(&abTestJob{campaign: campaign, runner: cr}, *campaign.ABTest.EndsAt)
+		return
+	}
+	if campaign.Status == domain.StatusSending && campaign.NextBatchAt != nil {
+		// Waiting for the send window of its remaining subscribers.
+		cr.wp.SubmitAt(&campaignJob{campaign: campaign, runner: cr}, *campaign.NextBatchAt)
+		return
+	}
+	cr.wp.Submit(&campaignJob{campaign: campaign, runner: cr})
+}
+
+// campaignJob delivers the post of a campaign to every active subscriber of
+// its newsletter, or of its segment, that has not received it yet.
+type campaignJob struct {
+	campaign *domain.Campaign
+	runner   *campaignRunner
+
+	// cursor is the page of subscribers the job continues from, and
+	// nextBatch the earliest send window found on the pages before it.
+	cursor    string
+	nextBatch time.Time
+}
+
+// Priority queues campaigns behind transactional emails and exports.
+func (job *campaignJob) Priority() workerpool.Priority {
+	return workerpool.PriorityLow
+}
+
+// Timeout lifts the job timeout of the pool: campaigns run until every
+// subscriber was emailed, however large the newsletter.
+func (job *campaignJob) Timeout() time.Duration {
+	return 0
+}
+
+// Process sends the campaign page by page. Each recipient is reserved
+// before being emailed, so a resumed campaign skips everyone already
+// handled, including deliveries interrupted by a crash. Delivery failures of
+// single recipients are recorded and do not stop the campaign. The campaign
+// status is checked after every page so that pausing takes effect quickly.
+//
+// Each run sends a single page, then queues the job again for the next one
+// behind the jobs already waiting, so that concurrent campaigns take turns
+// on the workers instead of the largest one holding them until it is done.
+// With a throttle, a run first takes a sending slot of the newsletter, and
+// waits for campaignThrottleDelay when all its slots are taken.
+//
+// Campaigns with an A/B test are first sent to the sample only, with the
+// subject of each recipient's variant and an open tracking pixel; they then
+// wait in testing until abTestJob picks the winner and sends it again.
+//
+// With a send window, subscribers outside the window in their timezone are
+// skipped, and the job is scheduled again for when the window opens for the
+// earliest of them. Each run thus sends one batch of subscribers.
+//
+// Campaigns sent to a segment resolve it at every run, so that the
+// subscribers of each page are matched against the current filter and
+// engagement.
+//
+// Emails get the envelope of the newsletter, overridden by the one of the
+// campaign, a List-Id header unless a custom one is set, and the
+// List-Unsubscribe headers of their recipient. With click tracking, their
+// links are replaced with short links, see trackClicks.
+func (job *campaignJob) Process(ctx context.Context) error {
+	cr := job.runner
+	id := job.campaign.ID
+
+	if cr.throttle != nil {
+		if !cr.throttle.Acquire(job.campaign.NewsletterID) {
+			cr.wp.SubmitAfter(job, campaignThrottleDelay)
+			return nil
+		}
+		defer cr.throttle.Release(job.campaign.NewsletterID)
+	}
+
+	started, err := cr.cs.Start(id)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			// Paused or finished while waiting in the queue.
+			return nil
+		}
+		return err
+	}
+
+	post, err := cr.ps.Get(job.campaign.NewsletterID, job.campaign.PostID)
+	if err != nil {
+		return job.fail(fmt.Errorf("load post: %w", err))
+	}
+	if cr.shortener != nil {
+		tracked := *post
+		tracked.Body = cr.trackClicks(id, post.Body)
+		post = &tracked
+	}
+
+	// The default sender, language and branding are used if the newsletter is unavailable.
+	newsletter, err := cr.ns.Get(job.campaign.NewsletterID)
+	if err != nil {
+		newsletter = &newsletterdomain.Newsletter{ID: job.campaign.NewsletterID}
+	}
+
+	var audience *segmentdomain.Audience
+	if started.SegmentID != nil {
+		if audience, err = cr.segments.Resolve(job.campaign.NewsletterID, *started.SegmentID); err != nil {
+			return job.fail(fmt.Errorf("resolve segment: %w", err))
+		}
+	}
+
+	// The campaign overrides the reply-to address, copies and headers of the newsletter.
+	envelope := newsletter.Envelope
+	if started.Envelope != nil {
+		envelope = envelope.Merge(*started.Envelope)
+	}
+	listID := cr.links.ListID(newsletter.ID, newsletter.Name)
+
+	test := started.ABTest
+	sampling := test != nil && test.Winner == ""
+
+	window := started.SendWindow
+	nextBatch := job.nextBatch
+	locations := map[string]*time.Location{} // By subscriber timezone, as loading them reads the tz database
+
+	page, err := cr.ss.List(job.campaign.NewsletterID, subscriptiondomain.SubscriberFilter{}, pagination.MaxLimit, job.cursor)
+	if err != nil {
+		return job.fail(fmt.Errorf("list subscribers: %w", err))
+	}
+
+	for _, subscription := range page.Subscriptions {
+		if ctx.Err() != nil {
+			// Left sending, so it is resumed at the next start.
+			slog.Warn("campaign interrupted", "campaign_id", id, "error", ctx.Err())
+			return ctx.Err()
+		}
+		if !subscription.IsActive() || (audience != nil && !audience.Includes(subscription)) {
+			continue
+		}
+
+		delivery := &domain.Delivery{CampaignID: id, Email: subscription.Email}
+		if sampling {
+			if delivery.Variant = test.VariantOf(id, subscription.Email); delivery.Variant == "" {
+				// Sent the winner once the test ends.
+				continue
+			}
+			delivery.TrackingID = uuid.New()
+		}
+
+		if window != nil {
+			loc, ok := locations[subscription.Timezone]
+			if !ok {
+				loc = window.Location(subscription.Timezone)
+				locations[subscription.Timezone] = loc
+			}
+			now := time.Now()
+			if opens := window.Next(now, loc); opens.After(now) {
+				// Recipients already emailed must not hold the campaign back.
+				delivered, err := cr.cs.Delivered(id, subscription.Email)
+				if err != nil {
+					return job.fail(fmt.Errorf("check delivery: %w", err))
+				}
+				if !delivered && (nextBatch.IsZero() || opens.Before(nextBatch)) {
+					nextBatch = opens
+				}
+				continue
+			}
+		}
+
+		reserved, err := cr.cs.Reserve(delivery)
+		if err != nil {
+			return job.fail(fmt.Errorf("reserve delivery: %w", err))
+		}
+		if !reserved {
+			continue
+		}
+
+		fields := postdomain.MergeFields{
+			Email:          subscription.Email,
+			UnsubscribeURL: cr.links.Unsubscribe(cr.ss.UnsubscribeToken(subscription)),
+			NewsletterName: newsletter.Name,
+			Attributes:     subscription.Attributes,
+		}
+		// Without a secret key there is no unsubscribe-all link, as in
+		// confirmation emails.
+		if globalToken, err := cr.ss.GlobalUnsubscribeToken(subscription); err == nil {
+			fields.UnsubscribeAllURL = cr.links.UnsubscribeAll(globalToken)
+		}
+		email := jobs.SendEmailJob{
+			Email:   renderPost(post, newsletter, fields, i18n.New(i18n.Match(subscription.Language, newsletter.Language))),
+			Service: cr.es,
+		}
+		// One-click unsubscription (RFC 8058) posts to the unsubscribe link.
+		email.Email.Envelope = envelope.Merge(notifications.Envelope{Headers: map[string]string{
+			"List-Unsubscribe":      "<" + fields.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}})
+		email.Email.SetHeader("List-Id", listID)
+		if test != nil {
+			email.Email.Subject = fields.Expand(test.Subject(delivery.Variant))
+		}
+		if delivery.TrackingID != uuid.Nil {
+			email.Email.HTML += `<img src="` + html.EscapeString(cr.links.OpenPixel(delivery.TrackingID)) + `" width="1" height="1" alt="" style="display:none">`
+		}
+		sendErr := email.Process(ctx)
+		if sendErr != nil {
+			slog.Warn("failed to send campaign email", "campaign_id", id, "to", subscription.Email, "error", sendErr)
+		}
+		if err := cr.cs.Record(id, subscription.Email, email.Email.MessageID, sendErr); err != nil {
+			slog.Error("failed to record delivery", "campaign_id", id, "to", subscription.Email, "error", err)
+		}
+	}
+
+	if page.NextCursor != "" {
+		current, err := cr.cs.Get(id)
+		if err == nil && current.Status != domain.StatusSending {
+			slog.Info("campaign stopped", "campaign_id", id, "status", current.Status)
+			return nil
+		}
+		// Queued through the scheduler, as a worker blocking on a full queue
+		// could never free room in it.
+		cr.wp.SubmitAt(&campaignJob{campaign: started, runner: cr, cursor: page.NextCursor, nextBatch: nextBatch}, time.Now())
+		return nil
+	}
+
+	if !nextBatch.IsZero() {
+		if err := cr.cs.ScheduleBatch(id, nextBatch); err != nil {
+			slog.Error("failed to record next campaign batch", "campaign_id", id, "error", err)
+		}
+		slog.Info("campaign waiting for send window", "campaign_id", id, "next_batch_at", nextBatch)
+		cr.wp.SubmitAt(&campaignJob{campaign: started, runner: cr}, nextBatch)
+		return nil
+	}
+
+	if sampling {
+		testing, err := cr.cs.EndSample(id)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidTransition) {
+				// Paused while the last page was being sent.
+				return nil
+			}
+			return err
+		}
+		cr.enqueue(testing)
+		return nil
+	}
+
+	campaign, err := cr.cs.Complete(id)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			// Paused while the last page was being sent.
+			return nil
+		}
+		return err
+	}
+
+	slog.Info("campaign completed", "campaign_id", id, "sent", campaign.Sent, "failed", campaign.Failed)
+	return nil
+}
+
+// fail marks the campaign as failed and returns cause.
+func (job *campaignJob) fail(cause error) error {
+	if _, err := job.runner.cs.Fail(job.campaign.ID, cause); err != nil {
+		slog.Error("failed to mark campaign as failed", "campaign_id", job.campaign.ID, "error", err)
+	}
+	return fmt.Errorf("campaign %s: %w", job.campaign.ID, cause)
+}
+
+// abTestJob ends the A/B test of a campaign: it picks the subject with the
+// higher open rate and sends it to the subscribers outside the sample.
+type abTestJob struct {
+	campaign *domain.Campaign
+	runner   *campaignRunner
+}
+
+// Priority queues the end of tests with the campaigns.
+func (job *abTestJob) Priority() workerpool.Priority {
+	return workerpool.PriorityLow
+}
+
+// Timeout lifts the job timeout of the pool, as the winner is sent by the
+// job itself.
+func (job *abTestJob) Timeout() time.Duration {
+	return 0
+}
+
+// Process picks the winner and sends the remainder of the campaign, without
+// waiting for room in the queue again.
+func (job *abTestJob) Process(ctx context.Context) error {
+	campaign, err := job.runner.cs.PickWinner(job.campaign.ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			// The winner was already picked, e.g. before a restart.
+			return nil
+		}
+		return fmt.Errorf("campaign %s: pick A/B test winner: %w", job.campaign.ID, err)
+	}
+
+	return (&campaignJob{campaign: campaign, runner: job.runner}).Process(ctx)
+}
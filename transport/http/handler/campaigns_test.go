@@ -0,0 +1,702 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	campaignapp "newsletter/internal/campaigns/application"
+	"newsletter/internal/campaigns/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	notifications "newsletter/internal/notifications/domain"
+	postdomain "newsletter/internal/posts/domain"
+	segmentdomain "newsletter/internal/segments/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mock Campaign Service ---
+type MockCampaignService struct {
+	mock.Mock
+}
+
+func (m *MockCampaignService) campaign(args mock.Arguments) (*domain.Campaign, error) {
+	c := args.Get(0)
+	if c == nil {
+		return nil, args.Error(1)
+	}
+	return c.(*domain.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignService) Create(newsletterID, postID uuid.UUID, options domain.SendOptions) (*domain.Campaign, error) {
+	return m.campaign(m.Called(newsletterID, postID, options))
+}
+
+func (m *MockCampaignService) CheckLimits(newsletterID uuid.UUID) error {
+	return m.Called(newsletterID).Error(0)
+}
+
+func (m *MockCampaignService) Get(id uuid.UUID) (*domain.Campaign, error) {
+	return m.campaign(m.Called(id))
+}
+
+func (m *MockCampaignService) Start(id uuid.UUID) (*domain.Campaign, error) {
+	return m.campaign(m.Called(id))
+}
+
+func (m *MockCampaignService) Pause(id uuid.UUID) (*domain.Campaign, error) {
+	return m.campaign(m.Called(id))
+}
+
+func (m *MockCampaignService) Resume(id uuid.UUID) (*domain.Campaign, error) {
+	return m.campaign(m.Called(id))
+}
+
+func (m *MockCampaignService) Complete(id uuid.UUID) (*domain.Campaign, error) {
+	return m.campaign(m.Called(id))
+}
+
+func (m *MockCampaignService) Fail(id uuid.UUID, cause error) (*domain.Campaign, error) {
+	return m.campaign(m.Called(id, cause))
+}
+
+func (m *MockCampaignService) EndSample(id uuid.UUID) (*domain.Campaign, error) {
+	return m.campaign(m.Called(id))
+}
+
+func (m *MockCampaignService) PickWinner(id uuid.UUID) (*domain.Campaign, error) {
+	return m.campaign(m.Called(id))
+}
+
+func (m *MockCampaignService) ScheduleBatch(id uuid.UUID, at time.Time) error {
+	return m.Called(id, at).Error(0)
+}
+
+func (m *MockCampaignService) Unfinished() ([]*domain.Campaign, error) {
+	args := m.Called()
+	return args.Get(0).([]*domain.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignService) Reserve(delivery *domain.Delivery) (bool, error) {
+	args := m.Called(delivery.CampaignID, delivery.Email, delivery.Variant)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCampaignService) Delivered(campaignID uuid.UUID, email string) (bool, error) {
+	args := m.Called(campaignID, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCampaignService) Record(campaignID uuid.UUID, email, messageID string, sendErr error) error {
+	return m.Called(campaignID, email, messageID, sendErr).Error(0)
+}
+
+func (m *MockCampaignService) Deliveries(campaignID uuid.UUID, filter domain.DeliveryFilter, limit int, cursor string) (*domain.DeliveryPage, error) {
+	args := m.Called(campaignID, filter, limit, cursor)
+	p := args.Get(0)
+	if p == nil {
+		return nil, args.Error(1)
+	}
+	return p.(*domain.DeliveryPage), args.Error(1)
+}
+
+func (m *MockCampaignService) Report(campaignID uuid.UUID, fn func(*domain.Delivery) error) error {
+	args := m.Called(campaignID)
+	if deliveries, ok := args.Get(0).([]*domain.Delivery); ok {
+		for _, delivery := range deliveries {
+			if err := fn(delivery); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockCampaignService) RecordEvent(messageID, status, detail string) error {
+	return m.Called(messageID, status, detail).Error(0)
+}
+
+func (m *MockCampaignService) RecordOpen(trackingID uuid.UUID) error {
+	return m.Called(trackingID).Error(0)
+}
+
+func (m *MockCampaignService) RecordReply(reply *domain.Reply, references []string) (bool, error) {
+	args := m.Called(reply, references)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCampaignService) Replies(campaignID uuid.UUID, limit int, cursor string) (*domain.ReplyPage, error) {
+	args := m.Called(campaignID, limit, cursor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ReplyPage), args.Error(1)
+}
+
+// campaignRequest builds a request on campaign, authenticated as ownerID.
+func campaignRequest(method string, campaign *domain.Campaign, ownerID uuid.UUID) *http.Request {
+	req := httptest.NewRequest(method, "/campaigns/"+campaign.ID.String(), nil)
+	req = mux.SetURLVars(req, map[string]string{"campaign_id": campaign.ID.String()})
+	return req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+}
+
+func TestGetCampaign_OtherOwner(t *testing.T) {
+	mockCS, mockNS := new(MockCampaignService), new(MockNewsletterService)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID}
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+
+	rec := httptest.NewRecorder()
+	h.Get(rec, campaignRequest(http.MethodGet, campaign, uuid.New()))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestResumeCampaign_NotPaused(t *testing.T) {
+	mockCS, mockNS, mockWP := new(MockCampaignService), new(MockNewsletterService), new(MockWorkerPool)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, mockWP, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID, Status: domain.StatusCompleted}
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockCS.On("Resume", campaign.ID).Return(nil, domain.ErrInvalidTransition)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+
+	rec := httptest.NewRecorder()
+	h.Resume(rec, campaignRequest(http.MethodPost, campaign, newsletter.OwnerID))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	mockWP.AssertNotCalled(t, "Submit", mock.Anything)
+}
+
+func TestResumeCampaign_Success(t *testing.T) {
+	mockCS, mockNS, mockWP := new(MockCampaignService), new(MockNewsletterService), new(MockWorkerPool)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, mockWP, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID, Status: domain.StatusPaused}
+	queued := &domain.Campaign{ID: campaign.ID, NewsletterID: newsletter.ID, Status: domain.StatusQueued}
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockCS.On("Resume", campaign.ID).Return(queued, nil)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockWP.On("Submit", mock.AnythingOfType("*handler.campaignJob")).Return()
+
+	rec := httptest.NewRecorder()
+	h.Resume(rec, campaignRequest(http.MethodPost, campaign, newsletter.OwnerID))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	mockWP.AssertExpectations(t)
+}
+
+func TestCampaignJob_SkipsUnsubscribedAndDelivered(t *testing.T) {
+	mockCS, mockPS, mockNS := new(MockCampaignService), new(MockPostService), new(MockNewsletterService)
+	mockSS, mockES := new(MockSubscriptionService), new(MockEmailService)
+
+	post := &postdomain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Title: "Issue #1", Status: postdomain.StatusPublished}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: post.NewsletterID, PostID: post.ID, Status: domain.StatusQueued}
+
+	mockCS.On("Start", campaign.ID).Return(campaign, nil)
+	mockPS.On("Get", post.NewsletterID, post.ID).Return(post, nil)
+	mockNS.On("Get", post.NewsletterID).Return(&newsletterdomain.Newsletter{ID: post.NewsletterID}, nil)
+	mockSS.On("UnsubscribeToken", mock.Anything).Return("token-1")
+	mockSS.On("GlobalUnsubscribeToken", mock.Anything).Return("global-1", nil)
+	mockSS.On("List", post.NewsletterID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{
+			{Email: "active@example.com", Status: subscriptiondomain.StatusActive},
+			{Email: "delivered@example.com", Status: subscriptiondomain.StatusActive},
+			{Email: "gone@example.com", Status: subscriptiondomain.StatusUnsubscribed},
+		},
+	}, nil)
+	mockCS.On("Reserve", campaign.ID, "active@example.com", "").Return(true, nil)
+	mockCS.On("Reserve", campaign.ID, "delivered@example.com", "").Return(false, nil)
+	mockES.On("Send", mock.MatchedBy(func(email *notifications.Email) bool {
+		return email.To == "active@example.com" && email.Subject == "Issue #1" &&
+			strings.Contains(email.Text, testLinks.UnsubscribeAll("global-1")) &&
+			strings.Contains(email.HTML, testLinks.UnsubscribeAll("global-1"))
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*notifications.Email).MessageID = "msg-1"
+	}).Return(nil).Once()
+	mockCS.On("Record", campaign.ID, "active@example.com", "msg-1", nil).Return(nil)
+	mockCS.On("Complete", campaign.ID).Return(campaign, nil)
+
+	runner := &campaignRunner{cs: mockCS, ps: mockPS, ns: mockNS, ss: mockSS, es: mockES, links: testLinks}
+	job := &campaignJob{campaign: campaign, runner: runner}
+
+	assert.NoError(t, job.Process(context.Background()))
+	mockES.AssertExpectations(t)
+	mockCS.AssertExpectations(t)
+}
+
+func TestCampaignJob_WithoutUnsubscribeAllLink(t *testing.T) {
+	mockCS, mockPS, mockNS := new(MockCampaignService), new(MockPostService), new(MockNewsletterService)
+	mockSS, mockES := new(MockSubscriptionService), new(MockEmailService)
+
+	post := &postdomain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Title: "Issue #1", Body: "{{unsubscribe_all_url | Reply to stop}}", Status: postdomain.StatusPublished}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: post.NewsletterID, PostID: post.ID, Status: domain.StatusQueued}
+
+	mockCS.On("Start", campaign.ID).Return(campaign, nil)
+	mockPS.On("Get", post.NewsletterID, post.ID).Return(post, nil)
+	mockNS.On("Get", post.NewsletterID).Return(&newsletterdomain.Newsletter{ID: post.NewsletterID}, nil)
+	mockSS.On("UnsubscribeToken", mock.Anything).Return("token-1")
+	mockSS.On("GlobalUnsubscribeToken", mock.Anything).Return("", errors.New("unsubscribe secret key is missing"))
+	mockSS.On("List", post.NewsletterID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{{Email: "active@example.com", Status: subscriptiondomain.StatusActive}},
+	}, nil)
+	mockCS.On("Reserve", campaign.ID, "active@example.com", "").Return(true, nil)
+	mockES.On("Send", mock.MatchedBy(func(email *notifications.Email) bool {
+		return strings.HasPrefix(email.HTML, "Reply to stop") && !strings.Contains(email.Text, "/subscriptions/unsubscribe-all")
+	})).Return(nil).Once()
+	mockCS.On("Record", campaign.ID, "active@example.com", "", nil).Return(nil)
+	mockCS.On("Complete", campaign.ID).Return(campaign, nil)
+
+	runner := &campaignRunner{cs: mockCS, ps: mockPS, ns: mockNS, ss: mockSS, es: mockES, links: testLinks}
+	job := &campaignJob{campaign: campaign, runner: runner}
+
+	assert.NoError(t, job.Process(context.Background()))
+	mockES.AssertExpectations(t)
+}
+
+func TestCampaignJob_SendsToSegment(t *testing.T) {
+	mockCS, mockPS, mockNS := new(MockCampaignService), new(MockPostService), new(MockNewsletterService)
+	mockSS, mockES, mockSeg := new(MockSubscriptionService), new(MockEmailService), new(MockSegmentService)
+
+	segmentID := uuid.New()
+	post := &postdomain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Title: "Issue #1", Status: postdomain.StatusPublished}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: post.NewsletterID, PostID: post.ID, Status: domain.StatusQueued, SegmentID: &segmentID}
+
+	mockCS.On("Start", campaign.ID).Return(campaign, nil)
+	mockPS.On("Get", post.NewsletterID, post.ID).Return(post, nil)
+	mockNS.On("Get", post.NewsletterID).Return(&newsletterdomain.Newsletter{ID: post.NewsletterID}, nil)
+	mockSeg.On("Resolve", post.NewsletterID, segmentID).Return(&segmentdomain.Audience{
+		Filter: segmentdomain.Filter{Attributes: map[string]string{"city": "Berlin"}},
+	}, nil)
+	mockSS.On("UnsubscribeToken", mock.Anything).Return("token-1")
+	mockSS.On("GlobalUnsubscribeToken", mock.Anything).Return("global-1", nil)
+	mockSS.On("List", post.NewsletterID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{
+			{Email: "berlin@example.com", Status: subscriptiondomain.StatusActive, Attributes: map[string]string{"city": "Berlin"}},
+			{Email: "paris@example.com", Status: subscriptiondomain.StatusActive, Attributes: map[string]string{"city": "Paris"}},
+		},
+	}, nil)
+	mockCS.On("Reserve", campaign.ID, "berlin@example.com", "").Return(true, nil)
+	mockES.On("Send", mock.Anything).Return(nil).Once()
+	mockCS.On("Record", campaign.ID, "berlin@example.com", "", nil).Return(nil)
+	mockCS.On("Complete", campaign.ID).Return(campaign, nil)
+
+	runner := &campaignRunner{cs: mockCS, ps: mockPS, ns: mockNS, ss: mockSS, es: mockES, links: testLinks, segments: mockSeg}
+	job := &campaignJob{campaign: campaign, runner: runner}
+
+	assert.NoError(t, job.Process(context.Background()))
+	mockES.AssertExpectations(t)
+	mockCS.AssertNotCalled(t, "Reserve", campaign.ID, "paris@example.com", "")
+}
+
+func TestCampaignJob_SetsEnvelope(t *testing.T) {
+	mockCS, mockPS, mockNS := new(MockCampaignService), new(MockPostService), new(MockNewsletterService)
+	mockSS, mockES := new(MockSubscriptionService), new(MockEmailService)
+
+	post := &postdomain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Title: "Issue #1", Status: postdomain.StatusPublished}
+	newsletter := &newsletterdomain.Newsletter{ID: post.NewsletterID, Name: "Weekly", Settings: newsletterdomain.Settings{
+		Envelope: notifications.Envelope{
+			ReplyTo: "editor@example.com",
+			Bcc:     []string{"archive@example.com"},
+			Headers: map[string]string{"x-newsletter": "weekly", "X-Campaign": "default"},
+		},
+	}}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: post.NewsletterID, PostID: post.ID, Status: domain.StatusQueued,
+		Envelope: &notifications.Envelope{ReplyTo: "sales@example.com", Headers: map[string]string{"X-Campaign": "spring"}}}
+
+	mockCS.On("Start", campaign.ID).Return(campaign, nil)
+	mockPS.On("Get", post.NewsletterID, post.ID).Return(post, nil)
+	mockNS.On("Get", post.NewsletterID).Return(newsletter, nil)
+	mockSS.On("UnsubscribeToken", mock.Anything).Return("token-1")
+	mockSS.On("GlobalUnsubscribeToken", mock.Anything).Return("global-1", nil)
+	mockSS.On("List", post.NewsletterID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{
+			{Email: "reader@example.com", Status: subscriptiondomain.StatusActive, UnsubscribeToken: "token-1"},
+		},
+	}, nil)
+	mockCS.On("Reserve", campaign.ID, "reader@example.com", "").Return(true, nil)
+	mockES.On("Send", mock.Anything).Return(nil).Once()
+	mockCS.On("Record", campaign.ID, "reader@example.com", "", nil).Return(nil)
+	mockCS.On("Complete", campaign.ID).Return(campaign, nil)
+
+	runner := &campaignRunner{cs: mockCS, ps: mockPS, ns: mockNS, ss: mockSS, es: mockES, links: testLinks}
+	job := &campaignJob{campaign: campaign, runner: runner}
+
+	assert.NoError(t, job.Process(context.Background()))
+	email := mockES.Calls[0].Arguments.Get(0).(*notifications.Email)
+	assert.Equal(t, "sales@example.com", email.ReplyTo)
+	assert.Equal(t, []string{"archive@example.com"}, email.Bcc)
+	assert.Equal(t, map[string]string{
+		"X-Newsletter":          "weekly",
+		"X-Campaign":            "spring",
+		"List-Id":               `"Weekly" <` + newsletter.ID.String() + `.lists.api.example.com>`,
+		"List-Unsubscribe":      "<https://api.example.com/v1/subscriptions/unsubscribe?token=token-1>",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}, email.Headers)
+}
+
+func TestCampaignJob_StopsWhenPaused(t *testing.T) {
+	mockCS, mockPS, mockNS := new(MockCampaignService), new(MockPostService), new(MockNewsletterService)
+	mockSS, mockES := new(MockSubscriptionService), new(MockEmailService)
+
+	post := &postdomain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Status: postdomain.StatusPublished}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: post.NewsletterID, PostID: post.ID, Status: domain.StatusSending}
+
+	mockCS.On("Start", campaign.ID).Return(campaign, nil)
+	mockPS.On("Get", post.NewsletterID, post.ID).Return(post, nil)
+	mockNS.On("Get", post.NewsletterID).Return(&newsletterdomain.Newsletter{ID: post.NewsletterID}, nil)
+	mockSS.On("UnsubscribeToken", mock.Anything).Return("token-1")
+	mockSS.On("GlobalUnsubscribeToken", mock.Anything).Return("global-1", nil)
+	mockSS.On("List", post.NewsletterID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{{Email: "a@example.com", Status: subscriptiondomain.StatusActive}},
+		NextCursor:    "next",
+	}, nil)
+	mockCS.On("Reserve", campaign.ID, "a@example.com", "").Return(true, nil)
+	mockES.On("Send", mock.Anything).Return(nil)
+	mockCS.On("Record", campaign.ID, "a@example.com", "", nil).Return(nil)
+	mockCS.On("Get", campaign.ID).Return(&domain.Campaign{ID: campaign.ID, Status: domain.StatusPaused}, nil)
+
+	runner := &campaignRunner{cs: mockCS, ps: mockPS, ns: mockNS, ss: mockSS, es: mockES, links: testLinks}
+	job := &campaignJob{campaign: campaign, runner: runner}
+
+	assert.NoError(t, job.Process(context.Background()))
+	mockSS.AssertNumberOfCalls(t, "List", 1)
+	mockCS.AssertNotCalled(t, "Complete", mock.Anything)
+}
+
+func TestCampaignJob_YieldsAfterEachPage(t *testing.T) {
+	mockCS, mockPS, mockNS := new(MockCampaignService), new(MockPostService), new(MockNewsletterService)
+	mockSS, mockES, mockWP := new(MockSubscriptionService), new(MockEmailService), new(MockWorkerPool)
+
+	post := &postdomain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Status: postdomain.StatusPublished}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: post.NewsletterID, PostID: post.ID, Status: domain.StatusSending}
+
+	mockCS.On("Start", campaign.ID).Return(campaign, nil)
+	mockPS.On("Get", post.NewsletterID, post.ID).Return(post, nil)
+	mockNS.On("Get", post.NewsletterID).Return(&newsletterdomain.Newsletter{ID: post.NewsletterID}, nil)
+	mockSS.On("UnsubscribeToken", mock.Anything).Return("token-1")
+	mockSS.On("GlobalUnsubscribeToken", mock.Anything).Return("global-1", nil)
+	mockSS.On("List", post.NewsletterID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{{Email: "a@example.com", Status: subscriptiondomain.StatusActive}},
+		NextCursor:    "next",
+	}, nil)
+	mockSS.On("List", post.NewsletterID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "next").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{{Email: "b@example.com", Status: subscriptiondomain.StatusActive}},
+	}, nil)
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		mockCS.On("Reserve", campaign.ID, email, "").Return(true, nil)
+		mockCS.On("Record", campaign.ID, email, "", nil).Return(nil)
+	}
+	mockES.On("Send", mock.Anything).Return(nil)
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockCS.On("Complete", campaign.ID).Return(campaign, nil)
+
+	var next *campaignJob
+	mockWP.On("SubmitAt", mock.AnythingOfType("*handler.campaignJob"), mock.Anything).Run(func(args mock.Arguments) {
+		next = args.Get(0).(*campaignJob)
+	}).Return().Once()
+
+	runner := &campaignRunner{cs: mockCS, ps: mockPS, ns: mockNS, ss: mockSS, es: mockES, wp: mockWP, links: testLinks}
+	job := &campaignJob{campaign: campaign, runner: runner}
+
+	assert.NoError(t, job.Process(context.Background()))
+	mockSS.AssertNumberOfCalls(t, "List", 1)
+	mockCS.AssertNotCalled(t, "Complete", mock.Anything)
+	if assert.NotNil(t, next) {
+		assert.Equal(t, "next", next.cursor)
+		assert.NoError(t, next.Process(context.Background()))
+	}
+	mockES.AssertNumberOfCalls(t, "Send", 2)
+	mockCS.AssertExpectations(t)
+	mockWP.AssertExpectations(t)
+}
+
+func TestCampaignJob_WaitsForThrottle(t *testing.T) {
+	mockCS, mockWP := new(MockCampaignService), new(MockWorkerPool)
+	throttle := campaignapp.NewThrottle(1)
+
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: uuid.New(), Status: domain.StatusQueued}
+	assert.True(t, throttle.Acquire(campaign.NewsletterID), "another campaign of the newsletter is sending")
+
+	runner := &campaignRunner{cs: mockCS, wp: mockWP, links: testLinks, throttle: throttle}
+	job := &campaignJob{campaign: campaign, runner: runner}
+	mockWP.On("SubmitAfter", job, campaignThrottleDelay).Return().Once()
+
+	assert.NoError(t, job.Process(context.Background()))
+	mockWP.AssertExpectations(t)
+	mockCS.AssertNotCalled(t, "Start", mock.Anything)
+	assert.Equal(t, 1, throttle.Running()[campaign.NewsletterID])
+}
+
+func TestCampaignJob_ABTestSendsSampleThenWaits(t *testing.T) {
+	mockCS, mockPS, mockNS := new(MockCampaignService), new(MockPostService), new(MockNewsletterService)
+	mockSS, mockES, mockWP := new(MockSubscriptionService), new(MockEmailService), new(MockWorkerPool)
+
+	post := &postdomain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Title: "Issue #1", Status: postdomain.StatusPublished}
+	test := &domain.ABTest{SubjectA: "Issue #1", SubjectB: "Do not miss issue #1", SamplePercent: 25, WindowMinutes: 60}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: post.NewsletterID, PostID: post.ID, Status: domain.StatusQueued, ABTest: test}
+
+	subscriptions := []*subscriptiondomain.Subscription{}
+	variants := map[string]string{}
+	for i := 0; i < 40; i++ {
+		email := fmt.Sprintf("reader%d@example.com", i)
+		subscriptions = append(subscriptions, &subscriptiondomain.Subscription{Email: email, Status: subscriptiondomain.StatusActive})
+		if variant := test.VariantOf(campaign.ID, email); variant != "" {
+			variants[email] = variant
+			mockCS.On("Reserve", campaign.ID, email, variant).Return(true, nil)
+			mockCS.On("Record", campaign.ID, email, "", nil).Return(nil)
+		}
+	}
+	assert.NotEmpty(t, variants)
+	assert.Less(t, len(variants), len(subscriptions))
+
+	endsAt := time.Now().Add(time.Hour)
+	waiting := &domain.Campaign{ID: campaign.ID, Status: domain.StatusTesting, ABTest: &domain.ABTest{EndsAt: &endsAt}}
+	mockCS.On("Start", campaign.ID).Return(campaign, nil)
+	mockPS.On("Get", post.NewsletterID, post.ID).Return(post, nil)
+	mockNS.On("Get", post.NewsletterID).Return(&newsletterdomain.Newsletter{ID: post.NewsletterID}, nil)
+	mockSS.On("UnsubscribeToken", mock.Anything).Return("token-1")
+	mockSS.On("GlobalUnsubscribeToken", mock.Anything).Return("global-1", nil)
+	mockSS.On("List", post.NewsletterID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{Subscriptions: subscriptions}, nil)
+	mockES.On("Send", mock.MatchedBy(func(email *notifications.Email) bool {
+		return email.Subject == test.Subject(variants[email.To]) && strings.Contains(email.HTML, testLinks.URL("/track/open/", nil))
+	})).Return(nil).Times(len(variants))
+	mockCS.On("EndSample", campaign.ID).Return(waiting, nil)
+	mockWP.On("SubmitAt", mock.AnythingOfType("*handler.abTestJob"), endsAt).Return()
+
+	runner := &campaignRunner{cs: mockCS, ps: mockPS, ns: mockNS, ss: mockSS, es: mockES, wp: mockWP, links: testLinks}
+	job := &campaignJob{campaign: campaign, runner: runner}
+
+	assert.NoError(t, job.Process(context.Background()))
+	mockES.AssertExpectations(t)
+	mockCS.AssertExpectations(t)
+	mockWP.AssertExpectations(t)
+	mockCS.AssertNotCalled(t, "Complete", mock.Anything)
+}
+
+func TestABTestJob_SendsWinnerToRemainder(t *testing.T) {
+	mockCS, mockPS, mockNS := new(MockCampaignService), new(MockPostService), new(MockNewsletterService)
+	mockSS, mockES := new(MockSubscriptionService), new(MockEmailService)
+
+	post := &postdomain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Title: "Issue #1", Status: postdomain.StatusPublished}
+	test := &domain.ABTest{SubjectA: "Issue #1", SubjectB: "Do not miss issue #1", SamplePercent: 10, WindowMinutes: 60, Winner: domain.VariantB}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: post.NewsletterID, PostID: post.ID, Status: domain.StatusSending, ABTest: test}
+
+	mockCS.On("PickWinner", campaign.ID).Return(campaign, nil)
+	mockCS.On("Start", campaign.ID).Return(campaign, nil)
+	mockPS.On("Get", post.NewsletterID, post.ID).Return(post, nil)
+	mockNS.On("Get", post.NewsletterID).Return(&newsletterdomain.Newsletter{ID: post.NewsletterID}, nil)
+	mockSS.On("UnsubscribeToken", mock.Anything).Return("token-1")
+	mockSS.On("GlobalUnsubscribeToken", mock.Anything).Return("global-1", nil)
+	mockSS.On("List", post.NewsletterID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{
+			{Email: "sampled@example.com", Status: subscriptiondomain.StatusActive},
+			{Email: "rest@example.com", Status: subscriptiondomain.StatusActive},
+		},
+	}, nil)
+	mockCS.On("Reserve", campaign.ID, "sampled@example.com", "").Return(false, nil)
+	mockCS.On("Reserve", campaign.ID, "rest@example.com", "").Return(true, nil)
+	mockES.On("Send", mock.MatchedBy(func(email *notifications.Email) bool {
+		return email.To == "rest@example.com" && email.Subject == "Do not miss issue #1" && !strings.Contains(email.HTML, "/track/open/")
+	})).Return(nil).Once()
+	mockCS.On("Record", campaign.ID, "rest@example.com", "", nil).Return(nil)
+	mockCS.On("Complete", campaign.ID).Return(campaign, nil)
+
+	runner := &campaignRunner{cs: mockCS, ps: mockPS, ns: mockNS, ss: mockSS, es: mockES, links: testLinks}
+	job := &abTestJob{campaign: &domain.Campaign{ID: campaign.ID, Status: domain.StatusTesting}, runner: runner}
+
+	assert.NoError(t, job.Process(context.Background()))
+	mockES.AssertExpectations(t)
+	mockCS.AssertExpectations(t)
+}
+
+func TestTrackOpen_RecordsAndServesPixel(t *testing.T) {
+	mockCS := new(MockCampaignService)
+	h := NewCampaignHandler(mockCS, nil, nil, nil, nil, nil, testLinks, nil, nil)
+
+	trackingID := uuid.New()
+	mockCS.On("RecordOpen", trackingID).Return(nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/track/open/"+trackingID.String(), nil)
+	req = mux.SetURLVars(req, map[string]string{"tracking_id": trackingID.String()})
+	rec := httptest.NewRecorder()
+	h.TrackOpen(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/gif", rec.Header().Get("Content-Type"))
+	assert.Equal(t, transparentGIF, rec.Body.Bytes())
+	mockCS.AssertExpectations(t)
+}
+
+func TestTrackOpen_InvalidIDStillServesPixel(t *testing.T) {
+	mockCS := new(MockCampaignService)
+	h := NewCampaignHandler(mockCS, nil, nil, nil, nil, nil, testLinks, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/track/open/nope", nil)
+	req = mux.SetURLVars(req, map[string]string{"tracking_id": "nope"})
+	rec := httptest.NewRecorder()
+	h.TrackOpen(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, transparentGIF, rec.Body.Bytes())
+	mockCS.AssertNotCalled(t, "RecordOpen", mock.Anything)
+}
+
+func TestCampaignJob_SendWindowDefersOtherTimezones(t *testing.T) {
+	mockCS, mockPS, mockNS := new(MockCampaignService), new(MockPostService), new(MockNewsletterService)
+	mockSS, mockES, mockWP := new(MockSubscriptionService), new(MockEmailService), new(MockWorkerPool)
+
+	// Open for an hour around now in UTC, closed twelve hours away.
+	now := time.Now().UTC()
+	window := &domain.SendWindow{Start: now.Add(-time.Hour).Format("15:04"), End: now.Add(time.Hour).Format("15:04")}
+	post := &postdomain.Post{ID: uuid.New(), NewsletterID: uuid.New(), Title: "Issue #1", Status: postdomain.StatusPublished}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: post.NewsletterID, PostID: post.ID, Status: domain.StatusQueued, SendWindow: window}
+
+	mockCS.On("Start", campaign.ID).Return(campaign, nil)
+	mockPS.On("Get", post.NewsletterID, post.ID).Return(post, nil)
+	mockNS.On("Get", post.NewsletterID).Return(&newsletterdomain.Newsletter{ID: post.NewsletterID}, nil)
+	mockSS.On("UnsubscribeToken", mock.Anything).Return("token-1")
+	mockSS.On("GlobalUnsubscribeToken", mock.Anything).Return("global-1", nil)
+	mockSS.On("List", post.NewsletterID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{
+			{Email: "london@example.com", Status: subscriptiondomain.StatusActive, Timezone: "UTC"},
+			{Email: "default@example.com", Status: subscriptiondomain.StatusActive},
+			{Email: "auckland@example.com", Status: subscriptiondomain.StatusActive, Timezone: "Etc/GMT-12"},
+			{Email: "done@example.com", Status: subscriptiondomain.StatusActive, Timezone: "Etc/GMT+12"},
+		},
+	}, nil)
+	for _, email := range []string{"london@example.com", "default@example.com"} {
+		mockCS.On("Reserve", campaign.ID, email, "").Return(true, nil)
+		mockCS.On("Record", campaign.ID, email, "", nil).Return(nil)
+	}
+	mockCS.On("Delivered", campaign.ID, "auckland@example.com").Return(false, nil)
+	mockCS.On("Delivered", campaign.ID, "done@example.com").Return(true, nil)
+	mockES.On("Send", mock.Anything).Return(nil).Twice()
+	inElevenHours := mock.MatchedBy(func(at time.Time) bool {
+		return at.After(now.Add(10*time.Hour)) && at.Before(now.Add(12*time.Hour))
+	})
+	mockCS.On("ScheduleBatch", campaign.ID, inElevenHours).Return(nil)
+	mockWP.On("SubmitAt", mock.AnythingOfType("*handler.campaignJob"), inElevenHours).Return()
+
+	runner := &campaignRunner{cs: mockCS, ps: mockPS, ns: mockNS, ss: mockSS, es: mockES, wp: mockWP, links: testLinks}
+	job := &campaignJob{campaign: campaign, runner: runner}
+
+	assert.NoError(t, job.Process(context.Background()))
+	mockES.AssertExpectations(t)
+	mockCS.AssertExpectations(t)
+	mockWP.AssertExpectations(t)
+	mockCS.AssertNotCalled(t, "Complete", mock.Anything)
+}
+
+func TestEnqueue_WaitsForNextBatch(t *testing.T) {
+	mockWP := new(MockWorkerPool)
+	runner := &campaignRunner{wp: mockWP}
+
+	at := time.Now().Add(time.Hour)
+	mockWP.On("SubmitAt", mock.AnythingOfType("*handler.campaignJob"), at).Return()
+
+	runner.enqueue(&domain.Campaign{ID: uuid.New(), Status: domain.StatusSending, NextBatchAt: &at})
+
+	mockWP.AssertExpectations(t)
+	mockWP.AssertNotCalled(t, "Submit", mock.Anything)
+}
+
+func TestCampaignDeliveries_FilterByEmail(t *testing.T) {
+	mockCS, mockNS := new(MockCampaignService), new(MockNewsletterService)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID}
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("Deliveries", campaign.ID, domain.DeliveryFilter{Email: "x@example.com"}, 0, "").Return(&domain.DeliveryPage{
+		Deliveries: []*domain.Delivery{{CampaignID: campaign.ID, Email: "x@example.com", Status: domain.DeliveryBounced, MessageID: "msg-1"}},
+	}, nil)
+
+	req := campaignRequest(http.MethodGet, campaign, newsletter.OwnerID)
+	req.URL.RawQuery = "email=x@example.com"
+	rec := httptest.NewRecorder()
+	h.Deliveries(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"message_id":"msg-1"`)
+}
+
+func TestCampaignReplies(t *testing.T) {
+	mockCS, mockNS := new(MockCampaignService), new(MockNewsletterService)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID}
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("Replies", campaign.ID, 10, "").Return(&domain.ReplyPage{
+		Replies: []*domain.Reply{{CampaignID: campaign.ID, Email: "x@example.com", Text: "Thanks!", MessageID: "<r@example.com>"}},
+	}, nil)
+
+	req := campaignRequest(http.MethodGet, campaign, newsletter.OwnerID)
+	req.URL.RawQuery = "limit=10"
+	rec := httptest.NewRecorder()
+	h.Replies(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"text":"Thanks!"`)
+	assert.NotContains(t, rec.Body.String(), "r@example.com")
+}
+
+func TestCampaignEvents_StreamsUntilCompleted(t *testing.T) {
+	mockCS, mockNS := new(MockCampaignService), new(MockNewsletterService)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, nil, testLinks, nil, nil)
+	h.pollInterval = time.Millisecond
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID, Status: domain.StatusSending, Pending: 2}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("Get", campaign.ID).Return(campaign, nil).Twice()
+	mockCS.On("Get", campaign.ID).Return(&domain.Campaign{ID: campaign.ID, Status: domain.StatusSending, Sent: 1, Pending: 1}, nil).Once()
+	mockCS.On("Get", campaign.ID).Return(&domain.Campaign{ID: campaign.ID, Status: domain.StatusCompleted, Sent: 2}, nil)
+
+	rec := httptest.NewRecorder()
+	h.Events(rec, campaignRequest(http.MethodGet, campaign, newsletter.OwnerID))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.Equal(t, 2, strings.Count(body, "event: progress\n"))
+	assert.Contains(t, body, `"sent":1`)
+	assert.True(t, strings.HasSuffix(body, "\n\n"))
+	assert.Contains(t, body, "event: completed\ndata: ")
+}
+
+func TestCampaignEvents_StopsWhenClientDisconnects(t *testing.T) {
+	mockCS, mockNS := new(MockCampaignService), new(MockNewsletterService)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, nil, testLinks, nil, nil)
+	h.pollInterval = time.Millisecond
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID, Status: domain.StatusPaused}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+
+	req := campaignRequest(http.MethodGet, campaign, newsletter.OwnerID)
+	ctx, cancel := context.WithTimeout(req.Context(), 20*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	h.Events(rec, req.WithContext(ctx))
+
+	assert.Equal(t, 1, strings.Count(rec.Body.String(), "event: progress\n"))
+	assert.NotContains(t, rec.Body.String(), "event: completed")
+}
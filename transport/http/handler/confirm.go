@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/infrastructure/i18n"
+	"newsletter/internal/subscriptions/domain"
+)
+
+// confirmMessages are the messages of the pages confirming a re-subscription.
+var confirmMessages = tokenPageMessages{Title: "ConfirmTitle", Invalid: "ConfirmInvalid", Expired: "ConfirmExpired"}
+
+// ConfirmPage shows the branded page confirming a re-subscription.
+//
+// Route:
+//
+//	GET /subscriptions/confirm?token=<confirm_token>
+//
+// Description:
+//
+//	Landing page of the link of the email sent when an address that
+//	unsubscribed from a newsletter subscribes again. It asks the subscriber
+//	to confirm with a button posting to ConfirmSubscription, so that link
+//	scanners following the link do not subscribe anyone. The page shows the
+//	logo and brand color of the newsletter, in the language of the
+//	subscription.
+//
+// Responses:
+//
+//	200 OK
+//	  - HTML confirmation page, or a notice if already confirmed
+//
+//	400 Bad Request
+//	  - Missing token
+//
+//	404 Not Found
+//	  - No subscription matches the token
+//
+//	410 Gone
+//	  - The token has expired
+func (sh *SubscriptionHandler) ConfirmPage(w http.ResponseWriter, r *http.Request) {
+	subscription, page, ok := sh.tokenTarget(w, r, sh.ss.GetByConfirmToken, confirmMessages)
+	if !ok {
+		return
+	}
+
+	data := map[string]any{"Email": subscription.Email, "Newsletter": page.Newsletter}
+	localizer := i18n.New(page.Language)
+	switch {
+	case subscription.IsActive():
+		page.Message = localizer.T("ConfirmDone", data)
+	case subscription.Status == domain.StatusPending:
+		page.Message = localizer.T("ConfirmPrompt", data)
+		page.Button = localizer.T("ConfirmButton", nil)
+	default:
+		page.Message = localizer.T("ConfirmInvalid", nil)
+		renderUnsubscribePage(w, http.StatusNotFound, page)
+		return
+	}
+
+	renderUnsubscribePage(w, http.StatusOK, page)
+}
+
+// ConfirmSubscription confirms a re-subscription through its branded page.
+//
+// Route:
+//
+//	POST /subscriptions/confirm?token=<confirm_token>
+//
+// Description:
+//
+//	Submitted by the button of ConfirmPage. The pending subscription is
+//	activated, with the consent recorded in its history, and a branded page
+//	tells the subscriber. Confirming twice is not an error. Links issued
+//	before the latest re-subscription, or for a subscription unsubscribed
+//	since, are refused.
+//
+// Responses:
+//
+//	200 OK
+//	  - HTML confirmation page
+//
+//	400 Bad Request
+//	  - Missing token
+//
+//	404 Not Found
+//	  - No pending subscription matches the token
+//
+//	410 Gone
+//	  - The token has expired
+//
+//	500 Internal Server Error
+//	  - Confirmation failure
+//
+// Side Effects:
+//   - Marks the subscription as active, so that it receives campaigns again
+func (sh *SubscriptionHandler) ConfirmSubscription(w http.ResponseWriter, r *http.Request) {
+	subscription, page, ok := sh.tokenTarget(w, r, sh.ss.GetByConfirmToken, confirmMessages)
+	if !ok {
+		return
+	}
+
+	localizer := i18n.New(page.Language)
+	if _, err := sh.ss.Confirm(r.URL.Query().Get("token")); err != nil {
+		status, message := http.StatusInternalServerError, "ConfirmFailed"
+		switch {
+		case errors.Is(err, domain.ErrSubscriptionNotFound):
+			status, message = http.StatusNotFound, "ConfirmInvalid"
+		case errors.Is(err, domain.ErrTokenExpired):
+			status, message = http.StatusGone, "ConfirmExpired"
+		default:
+			slog.Error("failed to confirm subscription", "newsletter_id", subscription.NewsletterID, "error", err)
+		}
+		page.Message = localizer.T(message, nil)
+		renderUnsubscribePage(w, status, page)
+		return
+	}
+
+	page.Message = localizer.T("ConfirmDone", map[string]any{"Email": subscription.Email, "Newsletter": page.Newsletter})
+	renderUnsubscribePage(w, http.StatusOK, page)
+}
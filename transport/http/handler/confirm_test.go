@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/infrastructure/workerpool/jobs"
+	"newsletter/internal/subscriptions/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSubscribe_PendingSendsConfirmLink(t *testing.T) {
+	ss, wp := new(MockSubscriptionService), new(MockWorkerPool)
+	h := NewSubscriptionHandler(ss, unknownNewsletters(), new(MockEmailService), wp, nil, testLinks)
+
+	ss.On("Subscribe", mock.AnythingOfType("*domain.Subscription")).Return(&domain.Subscription{
+		ID: "sub-1", NewsletterID: testNewsletterID, Email: "user@test.com", Status: domain.StatusPending,
+	}, nil)
+	ss.On("ConfirmToken", mock.Anything).Return("confirm-1")
+
+	var job *jobs.SendEmailJob
+	wp.On("TrySubmit", mock.AnythingOfType("*jobs.SendEmailJob")).Run(func(args mock.Arguments) {
+		job = args.Get(0).(*jobs.SendEmailJob)
+	}).Return(nil)
+
+	payload, _ := json.Marshal(map[string]string{"email": "user@test.com"})
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/"+testNewsletterID.String(), bytes.NewReader(payload))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": testNewsletterID.String()})
+	rec := httptest.NewRecorder()
+
+	h.Subscribe(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var resp SubscribeResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, domain.StatusPending, resp.Status)
+	if assert.NotNil(t, job) {
+		assert.Contains(t, job.Email.Text, testLinks.Confirm("confirm-1"))
+		assert.NotContains(t, job.Email.Text, "/subscriptions/unsubscribe")
+	}
+	ss.AssertNotCalled(t, "UnsubscribeToken", mock.Anything)
+}
+
+func TestConfirmPage_Pending(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	h := NewSubscriptionHandler(ss, unknownNewsletters(), nil, nil, nil, testLinks)
+
+	ss.On("GetByConfirmToken", "confirm-1").Return(&domain.Subscription{
+		NewsletterID: uuid.New(), Email: "user@test.com", Status: domain.StatusPending,
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.ConfirmPage(rec, httptest.NewRequest(http.MethodGet, "/subscriptions/confirm?token=confirm-1", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<form method="post">`)
+	ss.AssertNotCalled(t, "Confirm", mock.Anything)
+}
+
+func TestConfirmPage_ExpiredToken(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), nil, nil, nil, testLinks)
+
+	ss.On("GetByConfirmToken", "old").Return(nil, domain.ErrTokenExpired)
+
+	rec := httptest.NewRecorder()
+	h.ConfirmPage(rec, httptest.NewRequest(http.MethodGet, "/subscriptions/confirm?token=old", nil))
+
+	assert.Equal(t, http.StatusGone, rec.Code)
+	assert.Contains(t, rec.Body.String(), "This confirmation link has expired.")
+}
+
+func TestConfirmSubscription(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	h := NewSubscriptionHandler(ss, unknownNewsletters(), nil, nil, nil, testLinks)
+
+	subscription := &domain.Subscription{NewsletterID: uuid.New(), Email: "user@test.com", Status: domain.StatusPending}
+	ss.On("GetByConfirmToken", "confirm-1").Return(subscription, nil)
+	ss.On("Confirm", "confirm-1").Return(subscription, nil)
+
+	rec := httptest.NewRecorder()
+	h.ConfirmSubscription(rec, httptest.NewRequest(http.MethodPost, "/subscriptions/confirm?token=confirm-1", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "user@test.com is subscribed to this newsletter again.")
+	ss.AssertExpectations(t)
+}
+
+func TestConfirmSubscription_StaleToken(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	h := NewSubscriptionHandler(ss, unknownNewsletters(), nil, nil, nil, testLinks)
+
+	ss.On("GetByConfirmToken", "confirm-1").Return(&domain.Subscription{
+		NewsletterID: uuid.New(), Email: "user@test.com", Status: domain.StatusUnsubscribed,
+	}, nil)
+	ss.On("Confirm", "confirm-1").Return(nil, domain.ErrSubscriptionNotFound)
+
+	rec := httptest.NewRecorder()
+	h.ConfirmSubscription(rec, httptest.NewRequest(http.MethodPost, "/subscriptions/confirm?token=confirm-1", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "This confirmation link is not valid.")
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Limits of JSON request bodies. Posts carry the full content of an issue
+// and bulk unsubscriptions up to 1000 email addresses; every other request
+// is a few fields.
+const (
+	maxBodyBytes     = 64 << 10  // 64 KiB
+	maxPostBodyBytes = 4 << 20   // 4 MiB
+	maxBulkBodyBytes = 512 << 10 // 512 KiB
+)
+
+// decodeJSON decodes the JSON body of r into dst, reading at most limit
+// bytes. Otherwise it answers the request and returns false:
+//
+//   - 415 Unsupported Media Type if the Content-Type is not JSON; requests
+//     without a Content-Type are accepted, as older clients omit it
+//   - 413 Request Entity Too Large if the body exceeds limit
+//   - 400 Bad Request if the body is empty, malformed, has fields dst does
+//     not have or anything after the JSON value
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any, limit int64) bool {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return false
+		}
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(dst)
+	if err == nil && decoder.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("unexpected data after the JSON value")
+	}
+	if err == nil {
+		return true
+	}
+
+	slog.Warn("failed to decode request body", "path", r.URL.Path, "error", err)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, io.EOF):
+		http.Error(w, "invalid request body: empty", http.StatusBadRequest)
+	default:
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		ok          bool
+		status      int
+	}{
+		{"valid", "application/json", `{"email":"user@example.com"}`, true, http.StatusOK},
+		{"charset", "application/json; charset=utf-8", `{"email":"user@example.com"}`, true, http.StatusOK},
+		{"json suffix", "application/merge-patch+json", `{"email":"user@example.com"}`, true, http.StatusOK},
+		{"no content type", "", `{"email":"user@example.com"}`, true, http.StatusOK},
+		{"unknown field", "application/json", `{"email":"user@example.com","admin":true}`, false, http.StatusBadRequest},
+		{"trailing data", "application/json", `{"email":"user@example.com"} {}`, false, http.StatusBadRequest},
+		{"malformed", "application/json", `{"email":`, false, http.StatusBadRequest},
+		{"empty", "application/json", ``, false, http.StatusBadRequest},
+		{"too large", "application/json", `{"email":"` + strings.Repeat("a", 100) + `"}`, false, http.StatusRequestEntityTooLarge},
+		{"form", "application/x-www-form-urlencoded", `email=user@example.com`, false, http.StatusUnsupportedMediaType},
+		{"text", "text/plain", `{"email":"user@example.com"}`, false, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+
+			var dst struct {
+				Email string `json:"email"`
+			}
+			ok := decodeJSON(rec, req, &dst, 64)
+
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.status, rec.Code)
+			if ok {
+				assert.Equal(t, "user@example.com", dst.Email)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"newsletter/internal/infrastructure/artifacts"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// DownloadHandler serves generated files, such as exports, through expiring
+// signed links.
+type DownloadHandler struct {
+	store *artifacts.Store
+}
+
+// NewDownloadHandler creates a new DownloadHandler. store may be nil when no
+// artifact storage is configured.
+func NewDownloadHandler(store *artifacts.Store) *DownloadHandler {
+	return &DownloadHandler{store: store}
+}
+
+// downloadTypes maps the extensions of generated files to their content
+// type, as the types known to package mime depend on the host.
+var downloadTypes = map[string]string{
+	".csv": "text/csv; charset=utf-8",
+	".zip": "application/zip",
+}
+
+// Download handles downloading a generated file through a signed link.
+//
+// Route:
+//
+//	GET /downloads/{name}?expires={unix}&signature={signature}[&once=1]
+//	GET /exports/{name}?expires={unix}&signature={signature}
+//
+// Description:
+//
+//	Serves a file produced by an export. The link is authorized by its
+//	signature alone and stops working once it expires. Single-use links
+//	(once=1) also stop working after the first download, which deletes the
+//	file. The /exports route serves links emailed before /downloads existed.
+//
+// Responses:
+//
+//	200 OK
+//	  - The file, with a Content-Type derived from its extension
+//
+//	403 Forbidden
+//	  - Invalid signature
+//
+//	404 Not Found
+//	  - The file does not exist
+//
+//	410 Gone
+//	  - The link has expired or, if single-use, was already used
+//
+//	501 Not Implemented
+//	  - Artifact storage is not configured
+func (dh *DownloadHandler) Download(w http.ResponseWriter, r *http.Request) {
+	if dh.store == nil {
+		http.Error(w, "downloads are not configured", http.StatusNotImplemented)
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	singleUse, err := dh.store.Verify(name, r.URL.Query(), time.Now())
+	if err != nil {
+		WriteError(w, r, err, "failed to verify download link")
+		return
+	}
+
+	object, err := dh.store.Open(r.Context(), name)
+	if err != nil {
+		if singleUse && err == artifacts.ErrNotFound {
+			// Single-use artifacts are deleted by their first download.
+			err = artifacts.ErrLinkUsed
+		}
+		WriteError(w, r, err, "failed to open file")
+		return
+	}
+	defer object.Close()
+
+	if singleUse {
+		if err := dh.store.Consume(r.Context(), name); err != nil {
+			WriteError(w, r, err, "failed to consume download link")
+			return
+		}
+	}
+
+	contentType, ok := downloadTypes[filepath.Ext(name)]
+	if !ok {
+		contentType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	if !object.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", object.ModTime.UTC().Format(http.TimeFormat))
+	}
+
+	if _, err := io.Copy(w, object); err != nil {
+		slog.Error("failed to write download", "artifact", name, "error", err)
+	}
+}
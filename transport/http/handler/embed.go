@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/newsletters/domain"
+	"text/template"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// embedScript renders a subscribe form right after the <script> tag that loads
+// it and posts the entered email to the public subscribe endpoint.
+//
+// Values are injected as JSON literals, which are safe to embed in JavaScript.
+var embedScript = template.Must(template.New("embed").Parse(`(function () {
+  var config = {{.}};
+  var script = document.currentScript;
+
+  var form = document.createElement("form");
+  form.className = "newsletter-subscribe";
+
+  var title = document.createElement("strong");
+  title.textContent = config.name;
+
+  var input = document.createElement("input");
+  input.type = "email";
+  input.name = "email";
+  input.required = true;
+  input.placeholder = "you@example.com";
+
+  var button = document.createElement("button");
+  button.type = "submit";
+  button.textContent = "Subscribe";
+
+  // Honeypot field, hidden from humans and left empty by them.
+  var honeypot = document.createElement("input");
+  honeypot.type = "text";
+  honeypot.name = "website";
+  honeypot.tabIndex = -1;
+  honeypot.autocomplete = "off";
+  honeypot.setAttribute("aria-hidden", "true");
+  honeypot.style.position = "absolute";
+  honeypot.style.left = "-10000px";
+
+  var captcha = document.createElement("div");
+  var widget = null;
+
+  var message = document.createElement("p");
+
+  form.appendChild(title);
+  form.appendChild(input);
+  form.appendChild(honeypot);
+  form.appendChild(captcha);
+  form.appendChild(button);
+  form.appendChild(message);
+
+  // The hCaptcha and reCAPTCHA APIs are compatible for explicit rendering.
+  function captchaAPI() {
+    return config.captcha.provider === "hcaptcha" ? window.hcaptcha : window.grecaptcha;
+  }
+
+  if (config.captcha) {
+    var callback = "newsletterCaptcha" + Math.random().toString(36).slice(2);
+    window[callback] = function () {
+      widget = captchaAPI().render(captcha, { sitekey: config.captcha.site_key });
+    };
+    var api = document.createElement("script");
+    api.src = config.captcha.script + "?render=explicit&onload=" + callback;
+    api.async = true;
+    document.head.appendChild(api);
+  }
+
+  form.addEventListener("submit", function (event) {
+    event.preventDefault();
+    button.disabled = true;
+
+    var payload = { email: input.value, website: honeypot.value };
+    if (config.captcha && widget !== null) {
+      payload.captcha_token = captchaAPI().getResponse(widget);
+    }
+
+    fetch(config.endpoint, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(payload)
+    })
+      .then(function (response) {
+        if (!response.ok) {
+          throw new Error(response.statusText);
+        }
+        message.textContent = "Thanks for subscribing!";
+        form.reset();
+      })
+      .catch(function () {
+        message.textContent = "Subscription failed, please try again.";
+      })
+      .then(function () {
+        button.disabled = false;
+        if (config.captcha && widget !== null) {
+          captchaAPI().reset(widget);
+        }
+      });
+  });
+
+  script.parentNode.insertBefore(form, script.nextSibling);
+})();
+`))
+
+// embedConfig is the configuration passed to the embeddable script.
+type embedConfig struct {
+	Name     string        `json:"name"`
+	Endpoint string        `json:"endpoint"`
+	Captcha  *embedCaptcha `json:"captcha,omitempty"`
+}
+
+// embedCaptcha configures the CAPTCHA widget rendered by the embeddable script.
+type embedCaptcha struct {
+	Provider string `json:"provider"`
+	SiteKey  string `json:"site_key"`
+	Script   string `json:"script"`
+}
+
+// captchaScripts maps CAPTCHA providers to the URL of their JavaScript API.
+var captchaScripts = map[string]string{
+	"hcaptcha":  "https://js.hcaptcha.com/1/api.js",
+	"recaptcha": "https://www.google.com/recaptcha/api.js",
+}
+
+// newEmbedCaptcha returns the configuration of the CAPTCHA widget of
+// provider, or nil when provider is not one of captchaScripts.
+func newEmbedCaptcha(provider, siteKey string) *embedCaptcha {
+	script, ok := captchaScripts[provider]
+	if !ok {
+		return nil
+	}
+
+	return &embedCaptcha{Provider: provider, SiteKey: siteKey, Script: script}
+}
+
+// EmbedScript serves a small JavaScript snippet that renders a subscribe form.
+//
+// Route:
+//
+//	GET /embed/{slug}.js
+//
+// Description:
+//
+//	Returns a script that website owners can include with
+//	<script src=".../embed/{slug}.js"></script>. Scripts included by
+//	newsletter ID, before newsletters had slugs, are still served. The script renders
+//	a subscribe form in place and submits it to the public subscribe endpoint.
+//	The embedding website's origin must be listed in the newsletter's
+//	allowed_origins setting for the browser to accept the subscribe call.
+//	The form includes a hidden honeypot field and, when CAPTCHA_PROVIDER is
+//	set, renders the provider's CAPTCHA widget. Browsers may cache the
+//	script for five minutes, then revalidate it with its ETag.
+//
+// Responses:
+//
+//	200 OK
+//	  - JavaScript snippet (application/javascript)
+//
+//	304 Not Modified
+//	  - If-None-Match names the ETag of the current script
+//
+//	404 Not Found
+//	  - No newsletter has the slug or ID
+//
+//	500 Internal Server Error
+//	  - Newsletter retrieval or rendering failure
+func (nh *NewsletterHandler) EmbedScript(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	newsletter, err := nh.ns.GetBySlug(slug)
+	if errors.Is(err, domain.ErrNewsletterNotFound) {
+		if newsletterID, parseErr := uuid.Parse(slug); parseErr == nil {
+			newsletter, err = nh.ns.Get(newsletterID)
+		}
+	}
+	if err != nil {
+		WriteError(w, r, err, "failed to retrieve newsletter")
+		return
+	}
+
+	cfg, err := json.Marshal(embedConfig{
+		Name:     newsletter.Name,
+		Endpoint: nh.links.Subscribe(newsletter.ID),
+		Captcha:  nh.captcha,
+	})
+	if err != nil {
+		http.Error(w, "failed to render embed script", http.StatusInternalServerError)
+		return
+	}
+
+	var script bytes.Buffer
+	if err := embedScript.Execute(&script, string(cfg)); err != nil {
+		slog.Error("failed to render embed script", "newsletter_id", newsletter.ID, "error", err)
+		http.Error(w, "failed to render embed script", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeWithETag(w, r, "application/javascript; charset=utf-8", script.Bytes(), entityTag(script.Bytes()))
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	apperrors "newsletter/internal/errors"
+	"newsletter/internal/infrastructure/artifacts"
+	"newsletter/internal/infrastructure/idempotency"
+	"newsletter/internal/infrastructure/workerpool"
+	limitsdomain "newsletter/internal/limits/domain"
+	postdomain "newsletter/internal/posts/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	userdomain "newsletter/internal/users/domain"
+)
+
+// kindStatuses maps the kinds of errors (see package newsletter/internal/errors)
+// to the HTTP status code they are reported with.
+var kindStatuses = map[error]int{
+	apperrors.NotFound:     http.StatusNotFound,
+	apperrors.Conflict:     http.StatusConflict,
+	apperrors.Unauthorized: http.StatusUnauthorized,
+	apperrors.Validation:   http.StatusBadRequest,
+	apperrors.Internal:     http.StatusInternalServerError,
+}
+
+// statusOverrides maps the errors reported with a more specific status code
+// than the one of their kind.
+var statusOverrides = map[error]int{
+	userdomain.ErrWeakPassword:              http.StatusUnprocessableEntity,
+	userdomain.ErrTwoFactorNotConfigured:    http.StatusNotImplemented,
+	userdomain.ErrTwoFactorLocked:           http.StatusTooManyRequests,
+	subscriptiondomain.ErrInvalidEmail:      http.StatusUnprocessableEntity,
+	subscriptiondomain.ErrSubscribeCooldown: http.StatusTooManyRequests,
+	artifacts.ErrInvalidLink:                http.StatusForbidden,
+	artifacts.ErrLinkExpired:                http.StatusGone,
+	artifacts.ErrLinkUsed:                   http.StatusGone,
+	workerpool.ErrQueueFull:                 http.StatusServiceUnavailable,
+	workerpool.ErrShutdown:                  http.StatusServiceUnavailable,
+	idempotency.ErrKeyReused:                http.StatusUnprocessableEntity,
+	limitsdomain.ErrNewsletterLimit:         http.StatusPaymentRequired,
+	limitsdomain.ErrEmailLimit:              http.StatusPaymentRequired,
+	limitsdomain.ErrSubscriberLimit:         http.StatusForbidden,
+	limitsdomain.ErrPlansNotStored:          http.StatusNotImplemented,
+	postdomain.ErrSpammyContent:             http.StatusUnprocessableEntity,
+}
+
+// domainError returns the sentinel error of a module matched by err and the
+// HTTP status code it maps to, and false when err is not such an error.
+// Errors are matched through any wrapping.
+func domainError(err error) (error, int, bool) {
+	known, ok := apperrors.As(err)
+	if !ok {
+		return nil, 0, false
+	}
+	if status, ok := statusOverrides[known]; ok {
+		return known, status, true
+	}
+	return known, kindStatuses[known.Kind()], true
+}
+
+// WriteError writes err as an error response; it is the single translation
+// of errors to HTTP responses. Sentinel errors of the modules are reported
+// with the status code of their kind, or their override, and a message in
+// the language negotiated from the Accept-Language header of r (see
+// localize); any other error is reported as 500 Internal Server Error
+// prefixed with message.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if known, status, ok := domainError(err); ok {
+		text, lang := localize(r, known, err)
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+		http.Error(w, text, status)
+		return
+	}
+	http.Error(w, message+": "+err.Error(), http.StatusInternalServerError)
+}
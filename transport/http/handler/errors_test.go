@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/infrastructure/artifacts"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	postdomain "newsletter/internal/posts/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	userdomain "newsletter/internal/users/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteError_Statuses(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("get newsletter: %w", newsletterdomain.ErrNewsletterNotFound), http.StatusNotFound},
+		{newsletterdomain.ErrSlugTaken, http.StatusConflict},
+		{userdomain.ErrInvalidCredentials, http.StatusUnauthorized},
+		{postdomain.ErrInvalidPost, http.StatusBadRequest},
+		{&subscriptiondomain.EmailError{Email: "a@example.invalid", Reason: subscriptiondomain.EmailReasonNoMX}, http.StatusUnprocessableEntity},
+		{artifacts.ErrLinkExpired, http.StatusGone},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			WriteError(rec, httptest.NewRequest(http.MethodGet, "/", nil), tt.err, "failed")
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
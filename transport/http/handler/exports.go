@@ -0,0 +1,520 @@
+package handler
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/infrastructure/artifacts"
+	"newsletter/internal/infrastructure/i18n"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/internal/infrastructure/workerpool/jobs"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	notifications "newsletter/internal/notifications/domain"
+	postdomain "newsletter/internal/posts/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	userdomain "newsletter/internal/users/domain"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// exportPageSize is the number of newsletters read per page while exporting.
+const exportPageSize = 100
+
+// ExportHandler handles HTTP requests related to the export of account and
+// newsletter data. Exports are built in the background and delivered by
+// email as expiring signed download links, and as attachments when they are
+// small enough.
+type ExportHandler struct {
+	ns    newsletterdomain.NewsletterService
+	ps    postdomain.PostService
+	ss    subscriptiondomain.SubscriptionService
+	es    notifications.EmailService
+	wp    workerpool.JobSubmiter
+	store *artifacts.Store
+	links *LinkBuilder
+
+	maxAttachment int // Size of the largest export attached to its email, in bytes; 0 never attaches them
+}
+
+// NewExportHandler creates a new ExportHandler. store may be nil when no
+// artifact storage is configured, which disables exports. links builds the
+// download links of the exports.
+func NewExportHandler(ns newsletterdomain.NewsletterService, ps postdomain.PostService, ss subscriptiondomain.SubscriptionService, es notifications.EmailService, wp workerpool.JobSubmiter, store *artifacts.Store, links *LinkBuilder) *ExportHandler {
+	return &ExportHandler{ns: ns, ps: ps, ss: ss, es: es, wp: wp, store: store, links: links}
+}
+
+// SetAttachmentLimit attaches the exports of at most size bytes to the email
+// with their download link, except for single-use links, whose files must
+// only be retrieved once. size should not exceed the attachment cap of the
+// email service; 0 disables attachments.
+func (eh *ExportHandler) SetAttachmentLimit(size int) {
+	eh.maxAttachment = size
+}
+
+// ExportResponse acknowledges an export request.
+type ExportResponse struct {
+	Status string `json:"status"`
+	Email  string `json:"email"` // Address the download link is sent to
+}
+
+// submit queues job and acknowledges the export request. It responds with
+// 501 Not Implemented when no artifact storage is configured and 503
+// Service Unavailable when the job queue is full.
+func (eh *ExportHandler) submit(w http.ResponseWriter, r *http.Request, newJob func(email string, singleUse bool) workerpool.Job) {
+	if eh.store == nil {
+		http.Error(w, "exports are not configured", http.StatusNotImplemented)
+		return
+	}
+
+	singleUse, _ := strconv.ParseBool(r.URL.Query().Get("single_use"))
+	email, _ := r.Context().Value(userdomain.UserEmail).(string)
+	if err := eh.wp.TrySubmit(newJob(email, singleUse)); err != nil {
+		WriteError(w, r, err, "failed to queue export")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(ExportResponse{Status: "processing", Email: email}); err != nil {
+		slog.Error("failed to encode export response", "error", err)
+	}
+}
+
+// Export handles requesting an archive of the authenticated user's account.
+//
+// Route:
+//
+//	GET /users/me/export[?single_use=true]
+//
+// Description:
+//
+//	Starts building a ZIP archive of the account in the background and
+//	emails an expiring signed download link to the user once it is ready.
+//	With single_use=true the link only works once. The archive contains
+//	JSON files:
+//	  - profile.json: the account
+//	  - newsletters.json: owned newsletters and their settings
+//	  - posts.json: the posts of every newsletter
+//	  - subscribers.json: the subscribers of every newsletter
+//	  - analytics.json: subscriber and post counts per newsletter
+//
+// Responses:
+//
+//	202 Accepted
+//	  {
+//	    "status": "processing",
+//	    "email": "user@example.com"
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid user ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	501 Not Implemented
+//	  - Artifact storage is not configured
+//
+//	503 Service Unavailable
+//	  - The job queue is full
+//
+// Side Effects:
+//   - Stores the archive and emails a download link to the user
+func (eh *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	eh.submit(w, r, func(email string, singleUse bool) workerpool.Job {
+		slog.Info("account export requested", "user_id", ownerID)
+		return &exportJob{ownerID: ownerID, email: email, language: i18n.Match(r.Header.Get("Accept-Language")), singleUse: singleUse, handler: eh}
+	})
+}
+
+// ExportSubscribers handles requesting a CSV file of the subscribers of a newsletter.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/subscribers/export[?single_use=true]
+//
+// Description:
+//
+//	Starts building a CSV file of every subscriber of the newsletter,
+//	including unsubscribed ones, and emails an expiring signed download link
+//	to the owner once it is ready. Columns: email, status, tags (separated
+//	by ";"), created_at, unsubscribed_at.
+//
+// Responses:
+//
+//	202 Accepted
+//	  - Same body as GET /users/me/export
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist
+//
+//	501 Not Implemented
+//	  - Artifact storage is not configured
+//
+//	503 Service Unavailable
+//	  - The job queue is full
+//
+// Side Effects:
+//   - Stores the file and emails a download link to the owner
+func (eh *ExportHandler) ExportSubscribers(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, eh.ns)
+	if !ok {
+		return
+	}
+
+	eh.submit(w, r, func(email string, singleUse bool) workerpool.Job {
+		slog.Info("subscriber export requested", "newsletter_id", newsletter.ID)
+		return &newsletterExportJob{newsletter: newsletter, kind: "subscribers", email: email, language: i18n.Match(r.Header.Get("Accept-Language"), newsletter.Language), singleUse: singleUse, handler: eh}
+	})
+}
+
+// ExportStats handles requesting a CSV file of the statistics of a newsletter.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/stats/export[?single_use=true]
+//
+// Description:
+//
+//	Starts building a CSV file of metric,value rows (active and
+//	unsubscribed subscribers, posts by status, sent posts) and emails an
+//	expiring signed download link to the owner once it is ready.
+//
+// Responses:
+//
+//	202 Accepted
+//	  - Same body as GET /users/me/export
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist
+//
+//	501 Not Implemented
+//	  - Artifact storage is not configured
+//
+//	503 Service Unavailable
+//	  - The job queue is full
+//
+// Side Effects:
+//   - Stores the file and emails a download link to the owner
+func (eh *ExportHandler) ExportStats(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, eh.ns)
+	if !ok {
+		return
+	}
+
+	eh.submit(w, r, func(email string, singleUse bool) workerpool.Job {
+		slog.Info("stats export requested", "newsletter_id", newsletter.ID)
+		return &newsletterExportJob{newsletter: newsletter, kind: "stats", email: email, language: i18n.Match(r.Header.Get("Accept-Language"), newsletter.Language), singleUse: singleUse, handler: eh}
+	})
+}
+
+// exportProfile is the account section of an archive.
+type exportProfile struct {
+	ID         uuid.UUID `json:"id"`
+	Email      string    `json:"email"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// exportSubscriber is a subscriber of one of the exported newsletters.
+type exportSubscriber struct {
+	NewsletterID   uuid.UUID         `json:"newsletter_id"`
+	Email          string            `json:"email"`
+	Status         string            `json:"status"`
+	Tags           []string          `json:"tags,omitempty"`
+	Attributes     map[string]string `json:"attributes,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UnsubscribedAt *time.Time        `json:"unsubscribed_at,omitempty"`
+}
+
+// exportAnalytics summarizes the audience and posts of a newsletter.
+type exportAnalytics struct {
+	NewsletterID      uuid.UUID      `json:"newsletter_id"`
+	ActiveSubscribers int            `json:"active_subscribers"`
+	Unsubscribed      int            `json:"unsubscribed"`
+	Posts             map[string]int `json:"posts"` // Number of posts by status
+	SentPosts         int            `json:"sent_posts"`
+}
+
+// exportJob builds the archive of an account and emails its download link.
+type exportJob struct {
+	ownerID   uuid.UUID
+	email     string
+	language  string // Language of the email with the download link
+	singleUse bool
+	handler   *ExportHandler
+}
+
+// Process collects the account data, stores the archive and emails the
+// download link to the account owner.
+func (job *exportJob) Process(ctx context.Context) error {
+	eh := job.handler
+
+	newsletters, err := job.newsletters()
+	if err != nil {
+		return fmt.Errorf("export newsletters of user %s: %w", job.ownerID, err)
+	}
+
+	posts := []*postdomain.Post{}
+	subscribers := []exportSubscriber{}
+	analytics := make([]exportAnalytics, 0, len(newsletters))
+	for _, newsletter := range newsletters {
+		newsletterPosts, newsletterSubscribers, summary, err := eh.collect(newsletter.ID)
+		if err != nil {
+			return err
+		}
+		posts = append(posts, newsletterPosts...)
+		subscribers = append(subscribers, newsletterSubscribers...)
+		analytics = append(analytics, summary)
+	}
+
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"profile.json", exportProfile{ID: job.ownerID, Email: job.email, ExportedAt: time.Now().UTC()}},
+		{"newsletters.json", newsletters},
+		{"posts.json", posts},
+		{"subscribers.json", subscribers},
+		{"analytics.json", analytics},
+	}
+
+	name, err := eh.store.Create(ctx, "export", "zip", func(out io.Writer) error {
+		archive := zip.NewWriter(out)
+		for _, file := range files {
+			entry, err := archive.Create(file.name)
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(entry)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(file.content); err != nil {
+				return err
+			}
+		}
+		return archive.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("store export of user %s: %w", job.ownerID, err)
+	}
+
+	slog.Info("account export ready", "user_id", job.ownerID, "artifact", name)
+	localizer := i18n.New(job.language)
+	return eh.sendLink(ctx, localizer, job.email, localizer.T("ExportAccountSubject", nil), name, job.singleUse)
+}
+
+// newsletters returns every newsletter owned by the exported account.
+func (job *exportJob) newsletters() ([]*newsletterdomain.Newsletter, error) {
+	all := []*newsletterdomain.Newsletter{}
+	for page := 1; ; page++ {
+		newsletters, _, err := job.handler.ns.GetAll(job.ownerID, newsletterdomain.NewsletterFilter{}, newsletterdomain.NewsletterSort{}, exportPageSize, page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, newsletters...)
+		if len(newsletters) < exportPageSize {
+			return all, nil
+		}
+	}
+}
+
+// newsletterExportJob builds a CSV file of the subscribers or the statistics
+// of a newsletter and emails its download link.
+type newsletterExportJob struct {
+	newsletter *newsletterdomain.Newsletter
+	kind       string // "subscribers" or "stats"
+	email      string
+	language   string // Language of the email with the download link
+	singleUse  bool
+	handler    *ExportHandler
+}
+
+// Process collects the newsletter data, stores the CSV file and emails the
+// download link to the owner.
+func (job *newsletterExportJob) Process(ctx context.Context) error {
+	eh := job.handler
+
+	_, subscribers, summary, err := eh.collect(job.newsletter.ID)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	switch job.kind {
+	case "subscribers":
+		rows = [][]string{{"email", "status", "tags", "created_at", "unsubscribed_at"}}
+		for _, subscriber := range subscribers {
+			unsubscribedAt := ""
+			if subscriber.UnsubscribedAt != nil {
+				unsubscribedAt = subscriber.UnsubscribedAt.UTC().Format(time.RFC3339)
+			}
+			rows = append(rows, []string{
+				subscriber.Email,
+				subscriber.Status,
+				strings.Join(subscriber.Tags, ";"),
+				subscriber.CreatedAt.UTC().Format(time.RFC3339),
+				unsubscribedAt,
+			})
+		}
+	default:
+		rows = [][]string{
+			{"metric", "value"},
+			{"active_subscribers", strconv.Itoa(summary.ActiveSubscribers)},
+			{"unsubscribed", strconv.Itoa(summary.Unsubscribed)},
+		}
+		for _, status := range []string{postdomain.StatusDraft, postdomain.StatusPublished, postdomain.StatusArchived} {
+			rows = append(rows, []string{"posts_" + status, strconv.Itoa(summary.Posts[status])})
+		}
+		rows = append(rows, []string{"sent_posts", strconv.Itoa(summary.SentPosts)})
+	}
+
+	name, err := eh.store.Create(ctx, job.kind, "csv", func(out io.Writer) error {
+		writer := csv.NewWriter(out)
+		if err := writer.WriteAll(rows); err != nil {
+			return err
+		}
+		return writer.Error()
+	})
+	if err != nil {
+		return fmt.Errorf("store %s export of newsletter %s: %w", job.kind, job.newsletter.ID, err)
+	}
+
+	slog.Info("newsletter export ready", "newsletter_id", job.newsletter.ID, "kind", job.kind, "artifact", name)
+	subject := "ExportSubscribersSubject"
+	if job.kind == "stats" {
+		subject = "ExportStatsSubject"
+	}
+	localizer := i18n.New(job.language)
+	return eh.sendLink(ctx, localizer, job.email, localizer.T(subject, map[string]any{"Newsletter": job.newsletter.Name}), name, job.singleUse)
+}
+
+// collect reads the posts and subscribers of a newsletter and summarizes them.
+func (eh *ExportHandler) collect(newsletterID uuid.UUID) ([]*postdomain.Post, []exportSubscriber, exportAnalytics, error) {
+	summary := exportAnalytics{NewsletterID: newsletterID, Posts: map[string]int{}}
+
+	posts, err := eh.ps.List(newsletterID, "")
+	if err != nil {
+		return nil, nil, summary, fmt.Errorf("export posts of newsletter %s: %w", newsletterID, err)
+	}
+	for _, post := range posts {
+		summary.Posts[post.Status]++
+		if post.SentAt != nil {
+			summary.SentPosts++
+		}
+	}
+
+	subscribers := []exportSubscriber{}
+	cursor := ""
+	for {
+		page, err := eh.ss.List(newsletterID, subscriptiondomain.SubscriberFilter{}, pagination.MaxLimit, cursor)
+		if err != nil {
+			return nil, nil, summary, fmt.Errorf("export subscribers of newsletter %s: %w", newsletterID, err)
+		}
+		for _, subscription := range page.Subscriptions {
+			if subscription.IsActive() {
+				summary.ActiveSubscribers++
+			} else {
+				summary.Unsubscribed++
+			}
+			subscribers = append(subscribers, exportSubscriber{
+				NewsletterID:   subscription.NewsletterID,
+				Email:          subscription.Email,
+				Status:         subscription.Status,
+				Tags:           subscription.Tags,
+				Attributes:     subscription.Attributes,
+				CreatedAt:      subscription.CreatedAt,
+				UnsubscribedAt: subscription.UnsubscribedAt,
+			})
+		}
+		if page.NextCursor == "" {
+			return posts, subscribers, summary, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// attachment returns the artifact name as an attachment named after its
+// kind, such as "subscribers.csv", or nil if it is larger than the
+// attachment limit.
+func (eh *ExportHandler) attachment(ctx context.Context, name string) (*notifications.Attachment, error) {
+	object, err := eh.store.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	if object.Size > int64(eh.maxAttachment) {
+		return nil, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(object, int64(eh.maxAttachment)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > eh.maxAttachment {
+		return nil, nil
+	}
+
+	kind, _, _ := strings.Cut(name, "-")
+	ext := path.Ext(name)
+	return &notifications.Attachment{Filename: kind + ext, ContentType: downloadTypes[ext], Data: data}, nil
+}
+
+// sendLink emails to a signed download link of the artifact name, in the
+// language of localizer, with the artifact attached if it is small enough
+// (see SetAttachmentLimit). subject is the localized subject of the email,
+// e.g. "Your account export is ready".
+func (eh *ExportHandler) sendLink(ctx context.Context, localizer *i18n.Localizer, to, subject, name string, singleUse bool) error {
+	link := eh.links.Download(name, eh.store.Sign(name, time.Now(), singleUse))
+	download, downloadHTML := "DownloadText", "DownloadHTML"
+	if singleUse {
+		download, downloadHTML = "DownloadOnceText", "DownloadOnceHTML"
+	}
+	ttl := eh.store.TTL().String()
+
+	email := jobs.SendEmailJob{
+		Email: notifications.Email{
+			To:      to,
+			Subject: subject,
+			Text:    subject + "\n\n" + localizer.T(download, map[string]any{"TTL": ttl, "Link": link}),
+			HTML:    "<p>" + html.EscapeString(subject) + "</p><p>" + localizer.T(downloadHTML, map[string]any{"TTL": ttl, "Link": html.EscapeString(link)}) + "</p>",
+		},
+		Service: eh.es,
+	}
+	if eh.maxAttachment > 0 && !singleUse {
+		// The link still works, so the export is only attached when it can be.
+		attachment, err := eh.attachment(ctx, name)
+		if err != nil {
+			slog.Warn("failed to attach export", "artifact", name, "error", err)
+		} else if attachment != nil {
+			email.Email.Attachments = []notifications.Attachment{*attachment}
+		}
+	}
+	return email.Process(ctx)
+}
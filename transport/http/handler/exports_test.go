@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"newsletter/internal/infrastructure/artifacts"
+	"newsletter/internal/infrastructure/workerpool"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	notifications "newsletter/internal/notifications/domain"
+	postdomain "newsletter/internal/posts/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	userdomain "newsletter/internal/users/domain"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestArtifactStore(t *testing.T, ttl time.Duration) *artifacts.Store {
+	t.Helper()
+
+	backend, err := artifacts.NewDiskBackend(t.TempDir())
+	require.NoError(t, err)
+	return artifacts.NewStore(backend, "secret", ttl)
+}
+
+// exportRequest builds an export request authenticated as userID.
+func exportRequest(userID uuid.UUID) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/users/me/export", nil)
+	ctx := contextWithUserID(req.Context(), userID.String())
+	return req.WithContext(context.WithValue(ctx, userdomain.UserEmail, "owner@example.com"))
+}
+
+func TestExport_NotConfigured(t *testing.T) {
+	mockWP := new(MockWorkerPool)
+	h := NewExportHandler(nil, nil, nil, nil, mockWP, nil, testLinks)
+
+	rec := httptest.NewRecorder()
+	h.Export(rec, exportRequest(uuid.New()))
+
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	mockWP.AssertNotCalled(t, "TrySubmit", mock.Anything)
+}
+
+func TestExport_Accepted(t *testing.T) {
+	mockWP := new(MockWorkerPool)
+	h := NewExportHandler(nil, nil, nil, nil, mockWP, newTestArtifactStore(t, time.Hour), testLinks)
+
+	mockWP.On("TrySubmit", mock.AnythingOfType("*handler.exportJob")).Return(nil)
+
+	rec := httptest.NewRecorder()
+	h.Export(rec, exportRequest(uuid.New()))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Contains(t, rec.Body.String(), "owner@example.com")
+	mockWP.AssertExpectations(t)
+}
+
+func TestExport_QueueFull(t *testing.T) {
+	mockWP := new(MockWorkerPool)
+	h := NewExportHandler(nil, nil, nil, nil, mockWP, newTestArtifactStore(t, time.Hour), testLinks)
+
+	mockWP.On("TrySubmit", mock.AnythingOfType("*handler.exportJob")).Return(workerpool.ErrQueueFull)
+
+	rec := httptest.NewRecorder()
+	h.Export(rec, exportRequest(uuid.New()))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestExportJob_BuildsArchiveAndEmailsLink(t *testing.T) {
+	mockNS, mockPS, mockSS, mockES := new(MockNewsletterService), new(MockPostService), new(MockSubscriptionService), new(MockEmailService)
+	store := newTestArtifactStore(t, time.Hour)
+	h := NewExportHandler(mockNS, mockPS, mockSS, mockES, nil, store, testLinks)
+
+	ownerID := uuid.New()
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: ownerID, Name: "Weekly"}
+	mockNS.On("GetAll", ownerID, newsletterdomain.NewsletterFilter{}, newsletterdomain.NewsletterSort{}, exportPageSize, 1).Return([]*newsletterdomain.Newsletter{newsletter}, 1, nil)
+	mockPS.On("List", newsletter.ID, "").Return([]*postdomain.Post{{ID: uuid.New(), NewsletterID: newsletter.ID, Status: postdomain.StatusDraft}}, nil)
+	mockSS.On("List", newsletter.ID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{{NewsletterID: newsletter.ID, Email: "reader@example.com", Status: subscriptiondomain.StatusActive}},
+	}, nil)
+
+	var sent *notifications.Email
+	mockES.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(*notifications.Email)
+	}).Return(nil)
+
+	job := &exportJob{ownerID: ownerID, email: "owner@example.com", handler: h}
+	require.NoError(t, job.Process(context.Background()))
+	require.NotNil(t, sent)
+	assert.Equal(t, "owner@example.com", sent.To)
+
+	// Follow the emailed link
+	rec := followDownloadLink(t, NewDownloadHandler(store), sent.Text)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+
+	body, _ := io.ReadAll(rec.Body)
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.ElementsMatch(t, []string{"profile.json", "newsletters.json", "posts.json", "subscribers.json", "analytics.json"}, names)
+}
+
+// followDownloadLink requests the download link found in text.
+func followDownloadLink(t *testing.T, h *DownloadHandler, text string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	match := regexp.MustCompile(`/downloads/(\S+)\?(\S+)`).FindStringSubmatch(text)
+	require.Len(t, match, 3)
+	query, err := url.ParseQuery(match[2])
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/downloads/"+match[1]+"?"+query.Encode(), nil)
+	req = mux.SetURLVars(req, map[string]string{"name": match[1]})
+	rec := httptest.NewRecorder()
+	h.Download(rec, req)
+	return rec
+}
+
+func TestNewsletterExportJob_SubscribersSingleUse(t *testing.T) {
+	mockPS, mockSS, mockES := new(MockPostService), new(MockSubscriptionService), new(MockEmailService)
+	store := newTestArtifactStore(t, time.Hour)
+	h := NewExportHandler(nil, mockPS, mockSS, mockES, nil, store, testLinks)
+	h.SetAttachmentLimit(1 << 20)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), Name: "Weekly"}
+	mockPS.On("List", newsletter.ID, "").Return([]*postdomain.Post{}, nil)
+	mockSS.On("List", newsletter.ID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{
+		Subscriptions: []*subscriptiondomain.Subscription{{Email: "reader@example.com", Status: subscriptiondomain.StatusActive, Tags: []string{"a", "b"}}},
+	}, nil)
+
+	var sent *notifications.Email
+	mockES.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(*notifications.Email)
+	}).Return(nil)
+
+	job := &newsletterExportJob{newsletter: newsletter, kind: "subscribers", email: "owner@example.com", singleUse: true, handler: h}
+	require.NoError(t, job.Process(context.Background()))
+	require.NotNil(t, sent)
+	assert.Empty(t, sent.Attachments, "single-use exports are not attached")
+
+	downloads := NewDownloadHandler(store)
+	rec := followDownloadLink(t, downloads, sent.Text)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "reader@example.com,active,a;b,")
+
+	// Single-use links stop working after the first download
+	rec = followDownloadLink(t, downloads, sent.Text)
+	assert.Equal(t, http.StatusGone, rec.Code)
+}
+
+func TestNewsletterExportJob_AttachesSmallExports(t *testing.T) {
+	for _, tc := range []struct {
+		limit    int
+		attached bool
+	}{{1024, true}, {10, false}, {0, false}} {
+		mockPS, mockSS, mockES := new(MockPostService), new(MockSubscriptionService), new(MockEmailService)
+		h := NewExportHandler(nil, mockPS, mockSS, mockES, nil, newTestArtifactStore(t, time.Hour), testLinks)
+		h.SetAttachmentLimit(tc.limit)
+
+		newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), Name: "Weekly"}
+		mockPS.On("List", newsletter.ID, "").Return([]*postdomain.Post{}, nil)
+		mockSS.On("List", newsletter.ID, subscriptiondomain.SubscriberFilter{}, mock.Anything, "").Return(&subscriptiondomain.SubscriberPage{}, nil)
+
+		var sent *notifications.Email
+		mockES.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+			sent = args.Get(0).(*notifications.Email)
+		}).Return(nil)
+
+		job := &newsletterExportJob{newsletter: newsletter, kind: "stats", email: "owner@example.com", handler: h}
+		require.NoError(t, job.Process(context.Background()))
+		require.NotNil(t, sent)
+		assert.Contains(t, sent.Text, "/downloads/", "the link is always sent")
+
+		if !tc.attached {
+			assert.Empty(t, sent.Attachments, tc.limit)
+			continue
+		}
+		require.Len(t, sent.Attachments, 1)
+		assert.Equal(t, "stats.csv", sent.Attachments[0].Filename)
+		assert.Equal(t, "text/csv; charset=utf-8", sent.Attachments[0].ContentType)
+		assert.Contains(t, string(sent.Attachments[0].Data), "active_subscribers,0")
+	}
+}
+
+func TestExportSubscribers_OtherOwner(t *testing.T) {
+	mockNS, mockWP := new(MockNewsletterService), new(MockWorkerPool)
+	h := NewExportHandler(mockNS, nil, nil, nil, mockWP, newTestArtifactStore(t, time.Hour), testLinks)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/newsletters/"+newsletter.ID.String()+"/subscribers/export", nil)
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletter.ID.String()})
+	req = req.WithContext(contextWithUserID(req.Context(), uuid.New().String()))
+	rec := httptest.NewRecorder()
+	h.ExportSubscribers(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockWP.AssertNotCalled(t, "TrySubmit", mock.Anything)
+}
+
+func TestDownload_ExpiredLink(t *testing.T) {
+	store := newTestArtifactStore(t, time.Hour)
+	h := NewDownloadHandler(store)
+
+	name, err := store.Create(context.Background(), "export", "zip", func(w io.Writer) error { return nil })
+	require.NoError(t, err)
+	query := store.Sign(name, time.Now().Add(-2*time.Hour), false)
+
+	req := httptest.NewRequest(http.MethodGet, "/exports/"+name+"?"+query.Encode(), nil)
+	req = mux.SetURLVars(req, map[string]string{"name": name})
+	rec := httptest.NewRecorder()
+	h.Download(rec, req)
+
+	assert.Equal(t, http.StatusGone, rec.Code)
+}
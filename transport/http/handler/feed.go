@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	postdomain "newsletter/internal/posts/domain"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// feedSize is the number of most recent posts included in feeds.
+const feedSize = 20
+
+// rssFeed is an RSS 2.0 document.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atomFeed is an Atom (RFC 4287) document.
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Language string      `xml:"xml:lang,attr,omitempty"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Feed serves the feed of the published posts of a newsletter.
+//
+// Route:
+//
+//	GET /public/{slug}/feed.xml
+//
+// Description:
+//
+//	Returns the most recent published posts of a newsletter, newest first,
+//	as an RSS 2.0 feed, or as an Atom feed with format=atom, so that readers
+//	can follow the newsletter in a feed reader. Entries link to the posts on
+//	the public archive page and carry their full HTML content.
+//
+//	Feeds may be cached for five minutes. They carry an ETag and a
+//	Last-Modified time, the publication time of the newest post, so that
+//	feed readers polling them get an empty 304 while nothing was published.
+//
+// Path Parameters:
+//
+//	slug (string) - Slug of the newsletter
+//
+// Query Parameters:
+//
+//	format (string, optional) - rss (default) or atom
+//
+// Responses:
+//
+//	200 OK
+//	  - RSS 2.0 (application/rss+xml) or Atom (application/atom+xml) feed
+//
+//	304 Not Modified
+//	  - If-None-Match or If-Modified-Since match the current feed
+//
+//	400 Bad Request
+//	  - Unknown format
+//
+//	404 Not Found
+//	  - No newsletter has the slug
+//
+//	500 Internal Server Error
+//	  - Newsletter or post retrieval failure
+func (ph *PublicHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "rss" && format != "atom" {
+		http.Error(w, "invalid format: must be rss or atom", http.StatusBadRequest)
+		return
+	}
+
+	newsletter, err := ph.ns.GetBySlug(mux.Vars(r)["slug"])
+	if err != nil {
+		WriteError(w, r, err, "failed to get newsletter")
+		return
+	}
+
+	posts, err := ph.ps.List(newsletter.ID, postdomain.StatusPublished)
+	if err != nil {
+		WriteError(w, r, err, "failed to list posts")
+		return
+	}
+	posts = feedPosts(posts)
+
+	// Feeds without posts change when the newsletter is renamed: they are
+	// dated at its creation and revalidated with their ETag.
+	updated := newsletter.CreatedAt
+	if len(posts) > 0 {
+		updated = *posts[0].PublishedAt
+	}
+
+	contentType, document := "application/rss+xml; charset=utf-8", any(ph.rss(newsletter, posts, updated))
+	if format == "atom" {
+		contentType, document = "application/atom+xml; charset=utf-8", ph.atom(newsletter, posts, updated)
+	}
+
+	body := bytes.NewBufferString(xml.Header)
+	if err := xml.NewEncoder(body).Encode(document); err != nil {
+		slog.Error("failed to render feed", "newsletter_id", newsletter.ID, "error", err)
+		http.Error(w, "failed to render feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("ETag", entityTag(body.Bytes()))
+	http.ServeContent(w, r, "", updated, bytes.NewReader(body.Bytes()))
+}
+
+// feedPosts returns the feedSize most recently published posts, newest first.
+func feedPosts(posts []*postdomain.Post) []*postdomain.Post {
+	published := make([]*postdomain.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.PublishedAt != nil {
+			published = append(published, post)
+		}
+	}
+	sort.SliceStable(published, func(i, j int) bool {
+		return published[i].PublishedAt.After(*published[j].PublishedAt)
+	})
+	return published[:min(len(published), feedSize)]
+}
+
+// rss returns the RSS 2.0 feed of posts.
+func (ph *PublicHandler) rss(newsletter *newsletterdomain.Newsletter, posts []*postdomain.Post, updated time.Time) rssFeed {
+	archive := ph.links.Archive(newsletter.Slug)
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         newsletter.Name,
+			Link:          archive,
+			Description:   newsletter.Description,
+			Language:      newsletter.Language,
+			LastBuildDate: updated.UTC().Format(time.RFC1123Z),
+			Self:          atomLink{Href: ph.links.Feed(newsletter.Slug, ""), Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	reader := postdomain.MergeFields{NewsletterName: newsletter.Name}
+	for _, post := range posts {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       reader.Expand(post.Title),
+			Link:        archive + "#" + post.ID.String(),
+			Description: reader.ExpandHTML(post.Body),
+			GUID:        rssGUID{Value: "urn:uuid:" + post.ID.String()},
+			PubDate:     post.PublishedAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return feed
+}
+
+// atom returns the Atom feed of posts.
+func (ph *PublicHandler) atom(newsletter *newsletterdomain.Newsletter, posts []*postdomain.Post, updated time.Time) atomFeed {
+	archive := ph.links.Archive(newsletter.Slug)
+	feed := atomFeed{
+		Language: newsletter.Language,
+		ID:       "urn:uuid:" + newsletter.ID.String(),
+		Title:    newsletter.Name,
+		Subtitle: newsletter.Description,
+		Updated:  updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: archive, Rel: "alternate", Type: "text/html"},
+			{Href: ph.links.Feed(newsletter.Slug, "atom"), Rel: "self", Type: "application/atom+xml"},
+		},
+		Author: atomAuthor{Name: newsletter.Name},
+	}
+	reader := postdomain.MergeFields{NewsletterName: newsletter.Name}
+	for _, post := range posts {
+		published := post.PublishedAt.UTC().Format(time.RFC3339)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        "urn:uuid:" + post.ID.String(),
+			Title:     reader.Expand(post.Title),
+			Link:      atomLink{Href: archive + "#" + post.ID.String(), Rel: "alternate", Type: "text/html"},
+			Published: published,
+			Updated:   published, // Published posts are frozen
+			Content:   atomContent{Type: "html", Value: reader.ExpandHTML(post.Body)},
+		})
+	}
+	return feed
+}
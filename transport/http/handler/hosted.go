@@ -0,0 +1,403 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/infrastructure/i18n"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"newsletter/internal/subscriptions/domain"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// hostedSubscribePage renders the subscribe form hosted for newsletters whose
+// owners have no website of their own. The form posts back to the same URL;
+// the page can also be shown in an iframe of the owner's website.
+var hostedSubscribePage = template.Must(template.New("subscribe").Parse(`<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 32rem; margin: 2rem auto; padding: 0 1rem; text-align: center; color: #222; }
+img { max-height: 4rem; margin-bottom: 1rem; }
+h1 { color: {{.Color}}; font-size: 1.5rem; }
+input[type=email] { width: 100%; box-sizing: border-box; padding: .75rem; margin: .5rem 0; font-size: 1rem; }
+.website { position: absolute; left: -10000px; }
+button { background: {{.Color}}; color: #fff; border: 0; border-radius: 4px; padding: .75rem 1.5rem; font-size: 1rem; cursor: pointer; }
+</style>
+{{with .Captcha}}<script src="{{.Script}}" async defer></script>{{end}}
+</head>
+<body>
+{{if .LogoURL}}<img src="{{.LogoURL}}" alt="{{.Newsletter}}">{{end}}
+<h1>{{.Title}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if .Message}}<p role="status">{{.Message}}</p>{{end}}
+{{if not .Done}}<form method="post">
+<label for="email">{{.EmailLabel}}</label>
+<input type="email" id="email" name="email" value="{{.Email}}" required placeholder="you@example.com">
+<input type="text" class="website" name="website" tabindex="-1" autocomplete="off" aria-hidden="true">
+<input type="hidden" id="timezone" name="timezone">
+<script>try { document.getElementById("timezone").value = Intl.DateTimeFormat().resolvedOptions().timeZone || ""; } catch (e) {}</script>
+{{with .Captcha}}<div class="{{.Class}}" data-sitekey="{{.SiteKey}}"></div>{{end}}
+<button type="submit">{{.Button}}</button>
+</form>{{end}}
+</body>
+</html>
+`))
+
+// hostedCaptcha configures the CAPTCHA widget of the hosted subscribe form,
+// rendered implicitly by the provider's script.
+type hostedCaptcha struct {
+	Class   string // Class of the element replaced by the widget
+	Field   string // Form field receiving the response token
+	SiteKey string
+	Script  string
+}
+
+// hostedCaptchaFields maps CAPTCHA providers to the widget class and response
+// field of their implicit rendering.
+var hostedCaptchaFields = map[string]struct{ class, field string }{
+	"hcaptcha":  {"h-captcha", "h-captcha-response"},
+	"recaptcha": {"g-recaptcha", "g-recaptcha-response"},
+}
+
+// newHostedCaptcha returns the configuration of the CAPTCHA widget of
+// provider on the hosted subscribe form, or nil when provider is not one of
+// captchaScripts.
+func newHostedCaptcha(provider, siteKey string) *hostedCaptcha {
+	captcha := newEmbedCaptcha(provider, siteKey)
+	if captcha == nil {
+		return nil
+	}
+
+	fields := hostedCaptchaFields[captcha.Provider]
+	return &hostedCaptcha{Class: fields.class, Field: fields.field, SiteKey: captcha.SiteKey, Script: captcha.Script}
+}
+
+// hostedSubscribePageData is the content of the hosted subscribe page.
+type hostedSubscribePageData struct {
+	Language    string
+	Title       string
+	Newsletter  string
+	Description string
+	LogoURL     string
+	Color       string
+	EmailLabel  string
+	Button      string
+	Captcha     *hostedCaptcha
+	Email       string // Address entered, kept when the form is shown again
+	Message     string
+	Done        bool // Whether the subscription succeeded, hiding the form
+}
+
+// SubscribePage shows the hosted subscribe form of a newsletter.
+//
+// Route:
+//
+//	GET /public/{slug}/subscribe
+//
+// Description:
+//
+//	Public HTML page letting readers subscribe to a newsletter without any
+//	website of the owner. Owners can link to it or show it in an iframe:
+//	when the newsletter has allowed origins, only those may frame it. The
+//	form has a hidden honeypot field and, when CAPTCHA_PROVIDER is set,
+//	renders the provider's CAPTCHA widget. It is submitted to
+//	SubscribeForm. The page carries the logo and brand color of the
+//	newsletter, in the language negotiated from the Accept-Language header,
+//	falling back to the language of the newsletter.
+//
+// Path Parameters:
+//
+//	slug (string) - Slug of the newsletter
+//
+// Responses:
+//
+//	200 OK
+//	  - HTML subscribe page
+//
+//	404 Not Found
+//	  - No newsletter has the slug
+//
+//	500 Internal Server Error
+//	  - Newsletter retrieval failure
+func (sh *SubscriptionHandler) SubscribePage(w http.ResponseWriter, r *http.Request) {
+	newsletter, err := sh.ns.GetBySlug(mux.Vars(r)["slug"])
+	if err != nil {
+		WriteError(w, r, err, "failed to get newsletter")
+		return
+	}
+
+	renderHostedSubscribePage(w, http.StatusOK, newsletter, newHostedSubscribePage(r, newsletter, sh.captcha))
+}
+
+// SubscribeForm subscribes to a newsletter from its hosted subscribe form.
+//
+// Route:
+//
+//	POST /public/{slug}/subscribe
+//
+// Description:
+//
+//	Submitted by the form of SubscribePage. Subscribes the email address
+//	like the public subscribe endpoint, confirmation email included, then
+//	shows a confirmation page. On failure the form is shown again with the
+//	reason.
+//
+// Request Body (application/x-www-form-urlencoded):
+//
+//	email=user@example.com&website=&timezone=Europe/Berlin&h-captcha-response=...
+//
+//	The timezone of the browser is filled in by the form, so that send
+//	windows apply in the subscriber's local time. The CAPTCHA response field is h-captcha-response or g-recaptcha-response,
+//	depending on CAPTCHA_PROVIDER. Requests filling in the "website" honeypot
+//	are answered as if successful but no subscription is created.
+//
+// Responses:
+//
+//	200 OK
+//	  - HTML confirmation page, asking to confirm through the email when
+//	    the address unsubscribed before
+//
+//	400 Bad Request
+//	  - Invalid form or failed CAPTCHA verification, with the form
+//
+//	403 Forbidden
+//	  - The newsletter does not accept more subscribers, with the form
+//
+//	404 Not Found
+//	  - No newsletter has the slug
+//
+//	422 Unprocessable Entity
+//	  - The email address was rejected by validation, with the form
+//
+//	429 Too Many Requests
+//	  - The email subscribed to this newsletter too recently, with the form
+//
+//	500 Internal Server Error
+//	  - Subscription creation failure, with the form
+//
+// Side Effects:
+//   - Sends a confirmation email, as the public subscribe endpoint does
+func (sh *SubscriptionHandler) SubscribeForm(w http.ResponseWriter, r *http.Request) {
+	newsletter, err := sh.ns.GetBySlug(mux.Vars(r)["slug"])
+	if err != nil {
+		WriteError(w, r, err, "failed to get newsletter")
+		return
+	}
+
+	page := newHostedSubscribePage(r, newsletter, sh.captcha)
+	localizer := i18n.New(page.Language)
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		page.Message = localizer.T("SubscribeFailed", nil)
+		renderHostedSubscribePage(w, http.StatusBadRequest, newsletter, page)
+		return
+	}
+
+	request := SubscribeRequest{
+		Email:    strings.TrimSpace(r.PostForm.Get("email")),
+		Website:  r.PostForm.Get("website"),
+		Language: page.Language,
+		Timezone: r.PostForm.Get("timezone"),
+	}
+	if page.Captcha != nil {
+		request.CaptchaToken = r.PostForm.Get(page.Captcha.Field)
+	}
+	page.Email = request.Email
+
+	subscription, err := sh.subscribe(r, newsletter.ID, newsletter, request)
+	if err != nil {
+		status := http.StatusInternalServerError
+		page.Message = localizer.T("SubscribeFailed", nil)
+		if known, code, ok := domainError(err); ok {
+			status = code
+			page.Message, _ = localize(r, known, err)
+		}
+		renderHostedSubscribePage(w, status, newsletter, page)
+		return
+	}
+
+	page.Done = true
+	message := "SubscribeDone"
+	if subscription.Status == domain.StatusPending {
+		message = "SubscribePending"
+	}
+	page.Message = localizer.T(message, map[string]any{"Email": request.Email, "Newsletter": newsletter.Name})
+	renderHostedSubscribePage(w, http.StatusOK, newsletter, page)
+}
+
+// jsonpCallback matches the JavaScript function names accepted as JSONP
+// callbacks, such as "handleSubscribe" or "widgets.newsletter.done".
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+// jsonpResult is the outcome of a subscription through SubscribeJSONP.
+type jsonpResult struct {
+	Status  string `json:"status"`            // "subscribed", "pending" until the subscriber confirms, or "error"
+	Message string `json:"message,omitempty"` // Localized reason of the error
+	Reason  string `json:"reason,omitempty"`  // Why the email was rejected, see EmailErrorResponse
+}
+
+// SubscribeJSONP subscribes to a newsletter from a script or an iframe.
+//
+// Route:
+//
+//	GET /public/{slug}/subscribe/jsonp?email=<email>&callback=<function>
+//
+// Description:
+//
+//	Subscription endpoint for embedding on websites that cannot call the
+//	public subscribe endpoint with CORS, such as static pages loading it
+//	with a <script> tag. With callback, the result is returned as a call of
+//	that function (JSONP); without, as JSON. Because scripts cannot read
+//	the status code, failures are reported with 200 OK and an "error"
+//	status when a callback is given. Requests filling in the "website"
+//	honeypot are answered as if successful but no subscription is created.
+//
+// Query Parameters:
+//
+//	email         (string)           - Email address to subscribe
+//	callback      (string, optional) - JavaScript function receiving the result
+//	captcha_token (string, optional) - CAPTCHA response token, required when CAPTCHA is enabled
+//	website       (string, optional) - Honeypot, left empty by humans
+//	language      (string, optional) - Preferred language of the emails, such as "de"
+//	timezone      (string, optional) - IANA timezone of the subscriber, such as "Europe/Berlin"
+//
+// Responses:
+//
+//	200 OK
+//	  handleSubscribe({"status":"subscribed"})
+//	  handleSubscribe({"status":"pending"})
+//	  handleSubscribe({"status":"error","message":"..."})
+//	  handleSubscribe({"status":"error","message":"...","reason":"disposable"})
+//
+//	400 Bad Request
+//	  - Invalid callback name
+//	  - Missing email or failed CAPTCHA verification (without callback)
+//
+//	403 Forbidden
+//	  - The newsletter does not accept more subscribers (without callback)
+//
+//	404 Not Found
+//	  - No newsletter has the slug (without callback)
+//
+//	422 Unprocessable Entity
+//	  - The email address was rejected by validation (without callback)
+//
+//	429 Too Many Requests
+//	  - The email subscribed to this newsletter too recently (without callback)
+//
+//	500 Internal Server Error
+//	  - Subscription creation failure (without callback)
+//
+// Side Effects:
+//   - Sends a confirmation email, as the public subscribe endpoint does
+func (sh *SubscriptionHandler) SubscribeJSONP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	callback := query.Get("callback")
+	if callback != "" && !jsonpCallback.MatchString(callback) {
+		http.Error(w, "invalid callback", http.StatusBadRequest)
+		return
+	}
+
+	status, result := http.StatusOK, jsonpResult{Status: "subscribed"}
+	fail := func(code int, err error, message string) {
+		status, result = code, jsonpResult{Status: "error", Message: message}
+		if known, mapped, ok := domainError(err); ok {
+			status = mapped
+			result.Message, _ = localize(r, known, err)
+		}
+	}
+
+	request := SubscribeRequest{
+		Email:        strings.TrimSpace(query.Get("email")),
+		CaptchaToken: query.Get("captcha_token"),
+		Website:      query.Get("website"),
+		Language:     query.Get("language"),
+		Timezone:     query.Get("timezone"),
+	}
+	newsletter, err := sh.ns.GetBySlug(mux.Vars(r)["slug"])
+	switch {
+	case err != nil:
+		fail(http.StatusInternalServerError, err, "failed to get newsletter")
+	case request.Email == "":
+		fail(http.StatusBadRequest, nil, "email is required")
+	default:
+		subscription, err := sh.subscribe(r, newsletter.ID, newsletter, request)
+		if err != nil {
+			fail(http.StatusInternalServerError, err, "failed to create subscription")
+			var emailErr *domain.EmailError
+			if errors.As(err, &emailErr) {
+				result.Reason = emailErr.Reason
+			}
+		} else if subscription.Status == domain.StatusPending {
+			result.Status = "pending"
+		}
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, "failed to encode result", http.StatusInternalServerError)
+		return
+	}
+
+	// Results depend on the request and must never be reused.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if callback == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, err = w.Write(append(body, '\n'))
+	} else {
+		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		// The comment keeps the response from being read as another file
+		// type when the callback is controlled by an attacker.
+		_, err = w.Write([]byte("/**/" + callback + "(" + string(body) + ");\n"))
+	}
+	if err != nil {
+		slog.Error("failed to write subscription result", "error", err)
+	}
+}
+
+// newHostedSubscribePage returns the hosted subscribe page of newsletter,
+// in the language of the request, with the CAPTCHA widget captcha if not nil.
+func newHostedSubscribePage(r *http.Request, newsletter *newsletterdomain.Newsletter, captcha *hostedCaptcha) hostedSubscribePageData {
+	localizer := i18n.New(i18n.Match(r.Header.Get("Accept-Language"), newsletter.Language))
+	page := hostedSubscribePageData{
+		Language:    localizer.Language(),
+		Title:       localizer.T("SubscribeTitle", map[string]any{"Newsletter": newsletter.Name}),
+		Newsletter:  newsletter.Name,
+		Description: newsletter.Description,
+		LogoURL:     newsletter.LogoURL,
+		Color:       defaultBrandColor,
+		EmailLabel:  localizer.T("SubscribeEmail", nil),
+		Button:      localizer.T("SubscribeButton", nil),
+		Captcha:     captcha,
+	}
+	if newsletter.BrandColor != "" {
+		page.Color = newsletter.BrandColor
+	}
+	return page
+}
+
+// renderHostedSubscribePage writes page with the given status code. Only
+// the allowed origins of newsletter may frame it, when it has any.
+func renderHostedSubscribePage(w http.ResponseWriter, status int, newsletter *newsletterdomain.Newsletter, page hostedSubscribePageData) {
+	if origins := newsletter.AllowedOrigins; len(origins) > 0 && !newsletter.AllowsOrigin("*") {
+		w.Header().Set("Content-Security-Policy", "frame-ancestors 'self' "+strings.Join(origins, " "))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", page.Language)
+	w.WriteHeader(status)
+	if err := hostedSubscribePage.Execute(w, page); err != nil {
+		slog.Error("failed to render subscribe page", "newsletter_id", newsletter.ID, "error", err)
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"newsletter/internal/subscriptions/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// hostedNewsletter returns a newsletter service knowing one newsletter, with
+// the slug "tech-news".
+func hostedNewsletter() (*MockNewsletterService, *newsletterdomain.Newsletter) {
+	newsletter := &newsletterdomain.Newsletter{ID: testNewsletterID, Name: "Tech News", Slug: "tech-news"}
+	ns := new(MockNewsletterService)
+	ns.On("GetBySlug", "tech-news").Return(newsletter, nil)
+	ns.On("GetBySlug", mock.Anything).Return((*newsletterdomain.Newsletter)(nil), newsletterdomain.ErrNewsletterNotFound)
+	return ns, newsletter
+}
+
+// expectSubscription sets up the services to subscribe email.
+func expectSubscription(ss *MockSubscriptionService, wp *MockWorkerPool, email string) {
+	ss.On("Subscribe", mock.MatchedBy(func(s *domain.Subscription) bool {
+		return s.NewsletterID == testNewsletterID && s.Email == email
+	})).Return(&domain.Subscription{ID: "sub-1", NewsletterID: testNewsletterID, Email: email, UnsubscribeToken: "token-1", CreatedAt: time.Now()}, nil)
+	ss.On("UnsubscribeToken", mock.Anything).Return("token-1")
+	ss.On("GlobalUnsubscribeToken", mock.Anything).Return("global-token", nil)
+	wp.On("TrySubmit", mock.AnythingOfType("*jobs.SendEmailJob")).Return(nil)
+}
+
+func TestSubscribePage(t *testing.T) {
+	ns, newsletter := hostedNewsletter()
+	newsletter.AllowedOrigins = []string{"https://example.com"}
+	h := NewSubscriptionHandler(new(MockSubscriptionService), ns, new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+
+	req := httptest.NewRequest(http.MethodGet, "/public/tech-news/subscribe", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	req.Header.Set("Accept-Language", "fr")
+	rec := httptest.NewRecorder()
+
+	h.SubscribePage(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "frame-ancestors 'self' https://example.com", rec.Header().Get("Content-Security-Policy"))
+	assert.Contains(t, rec.Body.String(), `<form method="post">`)
+	assert.Contains(t, rec.Body.String(), "S&#39;abonner à Tech News")
+}
+
+func TestSubscribePage_NotFound(t *testing.T) {
+	ns, _ := hostedNewsletter()
+	h := NewSubscriptionHandler(new(MockSubscriptionService), ns, new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+
+	req := httptest.NewRequest(http.MethodGet, "/public/unknown/subscribe", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "unknown"})
+	rec := httptest.NewRecorder()
+
+	h.SubscribePage(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSubscribeForm_Success(t *testing.T) {
+	ns, _ := hostedNewsletter()
+	ss, wp := new(MockSubscriptionService), new(MockWorkerPool)
+	h := NewSubscriptionHandler(ss, ns, new(MockEmailService), wp, nil, testLinks)
+	expectSubscription(ss, wp, "reader@example.com")
+
+	form := url.Values{"email": {" reader@example.com "}, "website": {""}}
+	req := httptest.NewRequest(http.MethodPost, "/public/tech-news/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	rec := httptest.NewRecorder()
+
+	h.SubscribeForm(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "reader@example.com is now subscribed to Tech News.")
+	assert.NotContains(t, rec.Body.String(), "<form")
+	ss.AssertExpectations(t)
+	wp.AssertExpectations(t)
+}
+
+func TestSubscribeForm_Cooldown(t *testing.T) {
+	ns, _ := hostedNewsletter()
+	ss := new(MockSubscriptionService)
+	h := NewSubscriptionHandler(ss, ns, new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+	ss.On("Subscribe", mock.Anything).Return((*domain.Subscription)(nil), domain.ErrSubscribeCooldown)
+
+	form := url.Values{"email": {"reader@example.com"}}
+	req := httptest.NewRequest(http.MethodPost, "/public/tech-news/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	rec := httptest.NewRecorder()
+
+	h.SubscribeForm(rec, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), domain.ErrSubscribeCooldown.Error())
+	assert.Contains(t, rec.Body.String(), `value="reader@example.com"`, "the form keeps the address")
+}
+
+func TestSubscribeJSONP(t *testing.T) {
+	ns, _ := hostedNewsletter()
+	ss, wp := new(MockSubscriptionService), new(MockWorkerPool)
+	h := NewSubscriptionHandler(ss, ns, new(MockEmailService), wp, nil, testLinks)
+	expectSubscription(ss, wp, "reader@example.com")
+
+	req := httptest.NewRequest(http.MethodGet, "/public/tech-news/subscribe/jsonp?email=reader@example.com&callback=widgets.done", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	rec := httptest.NewRecorder()
+
+	h.SubscribeJSONP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/javascript; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "/**/widgets.done({\"status\":\"subscribed\"});\n", rec.Body.String())
+	ss.AssertExpectations(t)
+}
+
+func TestSubscribeJSONP_Errors(t *testing.T) {
+	ns, _ := hostedNewsletter()
+	h := NewSubscriptionHandler(new(MockSubscriptionService), ns, new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+
+	tests := []struct {
+		name, path string
+		status     int
+		body       string
+	}{
+		{"invalid callback", "/public/tech-news/subscribe/jsonp?email=a@example.com&callback=alert(1)", http.StatusBadRequest, "invalid callback\n"},
+		{"missing email with callback", "/public/tech-news/subscribe/jsonp?callback=done", http.StatusOK, "/**/done({\"status\":\"error\",\"message\":\"email is required\"});\n"},
+		{"unknown newsletter as JSON", "/public/unknown/subscribe/jsonp?email=a@example.com", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req = mux.SetURLVars(req, map[string]string{"slug": strings.Split(test.path, "/")[2]})
+			rec := httptest.NewRecorder()
+
+			h.SubscribeJSONP(rec, req)
+
+			assert.Equal(t, test.status, rec.Code)
+			if test.body != "" {
+				assert.Equal(t, test.body, rec.Body.String())
+				return
+			}
+			var result jsonpResult
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+			assert.Equal(t, "error", result.Status)
+		})
+	}
+}
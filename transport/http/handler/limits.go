@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/limits/domain"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// LimitHandler handles HTTP requests related to the plan limits of users.
+type LimitHandler struct {
+	ls domain.LimitService
+}
+
+func NewLimitHandler(ls domain.LimitService) *LimitHandler {
+	return &LimitHandler{ls: ls}
+}
+
+// Get handles retrieving the plan limits of the authenticated user.
+//
+// Route:
+//
+//	GET /users/me/limits
+//
+// Description:
+//
+//	Returns the plan of the authenticated user, its limits and how much of
+//	them the user consumes. A limit of 0 means unlimited. Monthly emails are
+//	counted from the start of the calendar month in UTC.
+//
+//	Reaching a limit refuses, until usage drops or the plan changes:
+//	  - creating a newsletter with 402 Payment Required
+//	  - sending a campaign that would exceed the monthly emails with 402
+//	    Payment Required
+//	  - subscribing to a full newsletter with 403 Forbidden
+//	  - requests beyond the requests per minute with 429 Too Many Requests,
+//	    until the next minute
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "plan": "free",
+//	    "limits": {
+//	      "newsletters": 3,
+//	      "subscribers_per_newsletter": 1000,
+//	      "emails_per_month": 10000,
+//	      "requests_per_minute": 60
+//	    },
+//	    "usage": {
+//	      "newsletters": 2,
+//	      "subscribers": {"uuid": 120, "uuid": 0},
+//	      "emails_this_month": 340
+//	    },
+//	    "period_start": "2026-01-01T00:00:00Z"
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid user ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	500 Internal Server Error
+//	  - Failed to count the usage
+func (lh *LimitHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := lh.ls.Report(userID)
+	if err != nil {
+		WriteError(w, r, err, "failed to compute limits")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("failed to encode limits response", "user_id", userID, "error", err)
+	}
+}
+
+// PlanRequest represents the payload for putting a user on a plan.
+type PlanRequest struct {
+	Plan string `json:"plan"` // Name of the plan, "free" or one of PLANS
+}
+
+// SetPlan handles putting a user on a plan.
+//
+// Route:
+//
+//	PUT /admin/users/{user_id}/plan
+//
+// Description:
+//
+//	Puts a user on the default plan, "free", or one of the plans configured
+//	with PLANS. The limits of the plan apply to the next actions of the
+//	user; other instances apply them within a minute. Usage above the new
+//	limits is kept, further actions are refused.
+//
+// Path Parameters:
+//
+//	user_id (uuid) - User to put on the plan
+//
+// Request Body (application/json):
+//
+//	{
+//	  "plan": "pro"
+//	}
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "name": "pro",
+//	    "limits": {
+//	      "newsletters": 10,
+//	      "subscribers_per_newsletter": 0,
+//	      "emails_per_month": 100000,
+//	      "requests_per_minute": 600
+//	    }
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid user ID or JSON body
+//	  - Unknown plan
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	403 Forbidden
+//	  - The user is not an administrator
+//
+//	404 Not Found
+//	  - User does not exist
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	501 Not Implemented
+//	  - Plans are not stored, as with STORE=memory
+func (lh *LimitHandler) SetPlan(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var request PlanRequest
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
+		return
+	}
+
+	plan, err := lh.ls.SetPlan(userID, request.Plan)
+	if err != nil {
+		WriteError(w, r, err, "failed to set plan")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		slog.Error("failed to encode plan response", "user_id", userID, "error", err)
+	}
+}
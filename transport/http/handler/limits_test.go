@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/limits/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Limit Service ---
+type MockLimitService struct {
+	mock.Mock
+}
+
+func (m *MockLimitService) CheckNewsletters(ownerID uuid.UUID) error {
+	return m.Called(ownerID).Error(0)
+}
+
+func (m *MockLimitService) CheckSubscribers(newsletterID uuid.UUID) error {
+	return m.Called(newsletterID).Error(0)
+}
+
+func (m *MockLimitService) CheckEmails(newsletterID uuid.UUID) error {
+	return m.Called(newsletterID).Error(0)
+}
+
+func (m *MockLimitService) PlanOf(userID uuid.UUID) (domain.Plan, error) {
+	args := m.Called(userID)
+	return args.Get(0).(domain.Plan), args.Error(1)
+}
+
+func (m *MockLimitService) SetPlan(userID uuid.UUID, name string) (domain.Plan, error) {
+	args := m.Called(userID, name)
+	return args.Get(0).(domain.Plan), args.Error(1)
+}
+
+func (m *MockLimitService) Report(userID uuid.UUID) (*domain.Report, error) {
+	args := m.Called(userID)
+	report := args.Get(0)
+	if report == nil {
+		return nil, args.Error(1)
+	}
+	return report.(*domain.Report), args.Error(1)
+}
+
+func TestGetLimits_Success(t *testing.T) {
+	mockLS := new(MockLimitService)
+	h := NewLimitHandler(mockLS)
+	userID, newsletterID := uuid.New(), uuid.New()
+
+	mockLS.On("Report", userID).Return(&domain.Report{
+		Plan:        domain.DefaultPlanName,
+		Limits:      domain.Limits{Newsletters: 3, EmailsPerMonth: 10000},
+		Usage:       domain.Usage{Newsletters: 1, Subscribers: map[uuid.UUID]int{newsletterID: 120}, EmailsThisMonth: 340},
+		PeriodStart: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me/limits", nil)
+	req = req.WithContext(contextWithUserID(req.Context(), userID.String()))
+	rec := httptest.NewRecorder()
+	h.Get(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"plan": "free",
+		"limits": {"newsletters": 3, "subscribers_per_newsletter": 0, "emails_per_month": 10000, "requests_per_minute": 0},
+		"usage": {"newsletters": 1, "subscribers": {"`+newsletterID.String()+`": 120}, "emails_this_month": 340},
+		"period_start": "2026-01-01T00:00:00Z"
+	}`, rec.Body.String())
+}
+
+func TestGetLimits_Failure(t *testing.T) {
+	mockLS := new(MockLimitService)
+	h := NewLimitHandler(mockLS)
+	userID := uuid.New()
+
+	mockLS.On("Report", userID).Return(nil, errors.New("db down"))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me/limits", nil)
+	req = req.WithContext(contextWithUserID(req.Context(), userID.String()))
+	rec := httptest.NewRecorder()
+	h.Get(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestWriteError_PlanLimits(t *testing.T) {
+	for err, status := range map[error]int{
+		domain.ErrNewsletterLimit: http.StatusPaymentRequired,
+		domain.ErrEmailLimit:      http.StatusPaymentRequired,
+		domain.ErrSubscriberLimit: http.StatusForbidden,
+	} {
+		rec := httptest.NewRecorder()
+		WriteError(rec, httptest.NewRequest(http.MethodPost, "/", nil), err, "failed")
+		assert.Equal(t, status, rec.Code, err.Error())
+	}
+}
+
+func TestSetPlan(t *testing.T) {
+	mockLS := new(MockLimitService)
+	h := NewLimitHandler(mockLS)
+	userID := uuid.New()
+	pro := domain.Plan{Name: "pro", Limits: domain.Limits{Newsletters: 10}}
+
+	mockLS.On("SetPlan", userID, "pro").Return(pro, nil)
+	mockLS.On("SetPlan", userID, "gold").Return(domain.Plan{}, domain.ErrUnknownPlan)
+
+	put := func(id, plan string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/admin/users/"+id+"/plan", strings.NewReader(`{"plan":"`+plan+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req = mux.SetURLVars(req, map[string]string{"user_id": id})
+		rec := httptest.NewRecorder()
+		h.SetPlan(rec, req)
+		return rec
+	}
+
+	rec := put(userID.String(), "pro")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got domain.Plan
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, pro, got)
+
+	assert.Equal(t, http.StatusBadRequest, put(userID.String(), "gold").Code)
+	assert.Equal(t, http.StatusBadRequest, put("not-a-uuid", "pro").Code)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"mime"
+	"net/url"
+	"newsletter/config"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// LinkBuilder builds the absolute links to the API that leave it, in emails,
+// embed scripts and download notifications. Links point to the current API
+// version.
+type LinkBuilder struct {
+	base string // scheme, host and path of the API, with APIPrefix, without trailing slash
+	host string // host name of the API, without port
+}
+
+// NewLinkBuilder returns a LinkBuilder for the API served at baseURL, such as
+// "https://api.example.com". It fails when baseURL is not a valid public URL
+// (see config.CheckBaseURL), so that the API does not send broken links. A
+// path is kept, for APIs served behind a reverse proxy under a prefix.
+func NewLinkBuilder(baseURL string) (*LinkBuilder, error) {
+	if err := config.CheckBaseURL(baseURL); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LinkBuilder{
+		base: strings.TrimRight(u.Scheme+"://"+u.Host+u.EscapedPath(), "/") + APIPrefix,
+		host: u.Hostname(),
+	}, nil
+}
+
+// URL returns the link to path, relative to the API version, with query.
+func (lb *LinkBuilder) URL(path string, query url.Values) string {
+	link := lb.base + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
+
+// Unsubscribe returns the link that unsubscribes the holder of an
+// unsubscribe token from a newsletter.
+func (lb *LinkBuilder) Unsubscribe(token string) string {
+	return lb.URL("/subscriptions/unsubscribe", url.Values{"token": {token}})
+}
+
+// UnsubscribeAll returns the link that unsubscribes the holder of a global
+// unsubscribe token from every newsletter.
+func (lb *LinkBuilder) UnsubscribeAll(token string) string {
+	return lb.URL("/subscriptions/unsubscribe-all", url.Values{"token": {token}})
+}
+
+// Confirm returns the link that confirms the pending subscription of a
+// confirm token.
+func (lb *LinkBuilder) Confirm(token string) string {
+	return lb.URL("/subscriptions/confirm", url.Values{"token": {token}})
+}
+
+// Subscribe returns the endpoint subscribing to a newsletter.
+func (lb *LinkBuilder) Subscribe(newsletterID uuid.UUID) string {
+	return lb.URL("/subscriptions/"+newsletterID.String(), nil)
+}
+
+// Download returns the link to the generated file name, signed by query.
+func (lb *LinkBuilder) Download(name string, query url.Values) string {
+	return lb.URL("/downloads/"+url.PathEscape(name), query)
+}
+
+// MagicLogin returns the passwordless sign in link of a login token.
+func (lb *LinkBuilder) MagicLogin(token string) string {
+	return lb.URL("/users/magic-login", url.Values{"token": {token}})
+}
+
+// OpenPixel returns the open tracking pixel of the campaign delivery with
+// trackingID.
+func (lb *LinkBuilder) OpenPixel(trackingID uuid.UUID) string {
+	return lb.URL("/track/open/"+trackingID.String(), nil)
+}
+
+// ShortLink returns the short link with code, redirecting to the URL it
+// stands for.
+func (lb *LinkBuilder) ShortLink(code string) string {
+	return lb.URL("/r/"+url.PathEscape(code), nil)
+}
+
+// Archive returns the public archive page of the newsletter with slug.
+func (lb *LinkBuilder) Archive(slug string) string {
+	return lb.URL("/public/"+url.PathEscape(slug), nil)
+}
+
+// Feed returns the feed of the newsletter with slug: RSS 2.0 by default, or
+// Atom when format is "atom".
+func (lb *LinkBuilder) Feed(slug, format string) string {
+	var query url.Values
+	if format != "" {
+		query = url.Values{"format": {format}}
+	}
+	return lb.URL("/public/"+url.PathEscape(slug)+"/feed.xml", query)
+}
+
+// ListID returns the List-Id header of the emails of a newsletter (RFC
+// 2919): its name and an identifier under the host of the API, such as
+// `"Weekly" <{newsletter_id}.lists.api.example.com>`, which mail clients use
+// to filter and group them.
+func (lb *LinkBuilder) ListID(newsletterID uuid.UUID, name string) string {
+	id := "<" + newsletterID.String() + ".lists." + lb.host + ">"
+	if name == "" {
+		return id
+	}
+	// Non-ASCII names are encoded words, others quoted strings.
+	phrase := mime.QEncoding.Encode("utf-8", name)
+	if phrase == name {
+		phrase = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+	}
+	return phrase + " " + id
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLinks builds the links of the handlers under test.
+var testLinks, _ = NewLinkBuilder("https://api.example.com")
+
+func TestNewLinkBuilder_Invalid(t *testing.T) {
+	_, err := NewLinkBuilder("api.example.com")
+	assert.Error(t, err)
+}
+
+func TestLinkBuilder(t *testing.T) {
+	links, err := NewLinkBuilder("https://example.com/api/")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://example.com/api/v1/subscriptions/unsubscribe?token=a%2Bb", links.Unsubscribe("a+b"))
+	assert.Equal(t, "https://example.com/api/v1/subscriptions/unsubscribe-all?token=t", links.UnsubscribeAll("t"))
+	id := uuid.New()
+	assert.Equal(t, "https://example.com/api/v1/subscriptions/"+id.String(), links.Subscribe(id))
+	assert.Equal(t, "https://example.com/api/v1/downloads/export.zip?sig=x", links.Download("export.zip", url.Values{"sig": {"x"}}))
+	assert.Equal(t, "https://example.com/api/v1/public/tech-news", links.Archive("tech-news"))
+	assert.Equal(t, "https://example.com/api/v1/public/tech-news/feed.xml", links.Feed("tech-news", ""))
+	assert.Equal(t, "https://example.com/api/v1/public/tech-news/feed.xml?format=atom", links.Feed("tech-news", "atom"))
+	assert.Equal(t, `"Tech \"News\"" <`+id.String()+`.lists.example.com>`, links.ListID(id, `Tech "News"`))
+	assert.Equal(t, "=?utf-8?q?Gr=C3=BC=C3=9Fe?= <"+id.String()+".lists.example.com>", links.ListID(id, "Grüße"))
+}
@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	analyticsdomain "newsletter/internal/analytics/domain"
+	campaigndomain "newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/artifacts"
+	"newsletter/internal/infrastructure/idempotency"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/infrastructure/workerpool"
+	limitsdomain "newsletter/internal/limits/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	notificationdomain "newsletter/internal/notifications/domain"
+	postdomain "newsletter/internal/posts/domain"
+	segmentdomain "newsletter/internal/segments/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	userdomain "newsletter/internal/users/domain"
+
+	"golang.org/x/text/language"
+)
+
+// supportedLanguages lists the languages error messages are translated to.
+// The first one is the default, used when no language of the request matches.
+var supportedLanguages = []language.Tag{
+	language.English,
+	language.German,
+	language.Spanish,
+	language.French,
+}
+
+var languageMatcher = language.NewMatcher(supportedLanguages)
+
+// errorCatalog holds the translations of domain error messages, keyed by
+// base language. English messages are the errors' own text and are not listed.
+var errorCatalog = map[string]map[error]string{
+	"de": {
+		userdomain.ErrEmailAlreadyExists:           "Diese E-Mail-Adresse ist bereits registriert.",
+		userdomain.ErrWeakPassword:                 fmt.Sprintf("Das Passwort muss zwischen %d und %d Zeichen lang sein.", userdomain.MinPasswordLength, userdomain.MaxPasswordLength),
+		userdomain.ErrInvalidCredentials:           "Ungültige Anmeldedaten.",
+		userdomain.ErrInvalidLoginToken:            "Der Anmeldelink ist ungültig oder abgelaufen.",
+		userdomain.ErrTwoFactorNotConfigured:       "Die Zwei-Faktor-Authentifizierung ist nicht konfiguriert.",
+		userdomain.ErrTwoFactorEnabled:             "Die Zwei-Faktor-Authentifizierung ist bereits aktiviert.",
+		userdomain.ErrTwoFactorNotEnrolled:         "Die Zwei-Faktor-Authentifizierung wurde nicht eingerichtet.",
+		userdomain.ErrInvalidTwoFactorCode:         "Ungültiger Bestätigungscode.",
+		userdomain.ErrInvalidChallenge:             "Die Anmeldeanfrage ist ungültig oder abgelaufen.",
+		userdomain.ErrTwoFactorLocked:              "Zu viele ungültige Bestätigungscodes. Bitte versuchen Sie es später erneut.",
+		newsletterdomain.ErrNewsletterNotFound:     "Newsletter nicht gefunden.",
+		newsletterdomain.ErrInvalidOrigin:          "Ungültige Herkunft (Origin).",
+		newsletterdomain.ErrInvalidSender:          "Ungültiger Absender.",
+		newsletterdomain.ErrInvalidLanguage:        "Nicht unterstützte Sprache.",
+		newsletterdomain.ErrInvalidBranding:        "Ungültiges Branding.",
+		newsletterdomain.ErrInvalidSort:            "Ungültige Sortierung.",
+		newsletterdomain.ErrInvalidSlug:            "Ungültige Kurzadresse (Slug).",
+		newsletterdomain.ErrSlugTaken:              "Diese Kurzadresse (Slug) ist bereits vergeben.",
+		newsletterdomain.ErrInvalidNewsletter:      "Name oder Beschreibung des Newsletters ist zu lang.",
+		analyticsdomain.ErrInvalidGranularity:      "Ungültige Granularität.",
+		analyticsdomain.ErrInvalidRange:            "Ungültiger Zeitraum.",
+		notificationdomain.ErrInvalidDomain:        "Ungültige Versanddomain.",
+		notificationdomain.ErrInvalidEnvelope:      "Ungültige E-Mail-Kopfzeilen.",
+		postdomain.ErrPostNotFound:                 "Beitrag nicht gefunden.",
+		postdomain.ErrInvalidPost:                  "Der Beitrag benötigt einen Titel und darf nicht zu lang sein.",
+		postdomain.ErrPostNotEditable:              "Nur Entwürfe können bearbeitet werden.",
+		postdomain.ErrInvalidTransition:            "Diese Statusänderung ist nicht erlaubt.",
+		postdomain.ErrPostNotSendable:              "Nur veröffentlichte Beiträge können versendet werden.",
+		postdomain.ErrPostAlreadySent:              "Der Beitrag wurde bereits versendet.",
+		postdomain.ErrUnknownMergeTag:              "Der Beitrag enthält einen unbekannten Platzhalter.",
+		postdomain.ErrSpammyContent:                "Der Inhalt wirkt wie Spam.",
+		segmentdomain.ErrSegmentNotFound:           "Segment nicht gefunden.",
+		segmentdomain.ErrInvalidSegment:            "Ungültiges Segment.",
+		segmentdomain.ErrSegmentInUse:              "Das Segment wird von einer nicht abgeschlossenen Kampagne verwendet.",
+		subscriptiondomain.ErrSubscriptionNotFound: "Abonnement nicht gefunden.",
+		subscriptiondomain.ErrInvalidToken:         "Ungültiges Token.",
+		subscriptiondomain.ErrCaptchaFailed:        "Die CAPTCHA-Prüfung ist fehlgeschlagen.",
+		subscriptiondomain.ErrSubscribeCooldown:    "Zu viele Anmeldungen, bitte später erneut versuchen.",
+		subscriptiondomain.ErrInvalidTimezone:      "Ungültige Zeitzone.",
+		subscriptiondomain.ErrInvalidAttributes:    "Ungültige Abonnentenattribute.",
+		subscriptiondomain.ErrInvalidEmail:         "Ungültige E-Mail-Adresse.",
+		campaigndomain.ErrCampaignNotFound:         "Kampagne nicht gefunden.",
+		campaigndomain.ErrInvalidTransition:        "Diese Statusänderung der Kampagne ist nicht erlaubt.",
+		campaigndomain.ErrInvalidDeliveryFilter:    "Ungültiger Zustellungsfilter.",
+		campaigndomain.ErrInvalidABTest:            "Ungültiger A/B-Test.",
+		campaigndomain.ErrInvalidSendWindow:        "Ungültiges Versandfenster.",
+		campaigndomain.ErrInvalidThrottle:          "Ungültige Drosselungsgrenzen.",
+		campaigndomain.ErrDeliveryNotFound:         "Zustellung nicht gefunden.",
+		pagination.ErrInvalidCursor:                "Ungültiger Cursor.",
+		artifacts.ErrNotFound:                      "Datei nicht gefunden.",
+		artifacts.ErrInvalidLink:                   "Ungültiger Download-Link.",
+		artifacts.ErrLinkExpired:                   "Der Download-Link ist abgelaufen.",
+		artifacts.ErrLinkUsed:                      "Der Download-Link wurde bereits verwendet.",
+		workerpool.ErrQueueFull:                    "Der Dienst ist ausgelastet. Bitte versuchen Sie es später erneut.",
+		workerpool.ErrShutdown:                     "Der Dienst wird beendet. Bitte versuchen Sie es später erneut.",
+		idempotency.ErrInProgress:                  "Eine Anfrage mit diesem Idempotency-Key wird noch verarbeitet.",
+		idempotency.ErrKeyReused:                   "Dieser Idempotency-Key wurde bereits für eine andere Anfrage verwendet.",
+		limitsdomain.ErrNewsletterLimit:            "Ihr Tarif erlaubt keine weiteren Newsletter.",
+		limitsdomain.ErrSubscriberLimit:            "Dieser Newsletter nimmt keine weiteren Abonnenten an.",
+		limitsdomain.ErrEmailLimit:                 "Das monatliche E-Mail-Kontingent Ihres Tarifs ist erreicht.",
+		limitsdomain.ErrPlansNotStored:             "Tarife können mit diesem Speicher nicht zugewiesen werden.",
+	},
+	"es": {
+		userdomain.ErrEmailAlreadyExists:           "Este correo electrónico ya está registrado.",
+		userdomain.ErrWeakPassword:                 fmt.Sprintf("La contraseña debe tener entre %d y %d caracteres.", userdomain.MinPasswordLength, userdomain.MaxPasswordLength),
+		userdomain.ErrInvalidCredentials:           "Credenciales no válidas.",
+		userdomain.ErrInvalidLoginToken:            "El enlace de inicio de sesión no es válido o ha caducado.",
+		userdomain.ErrTwoFactorNotConfigured:       "La autenticación en dos pasos no está configurada.",
+		userdomain.ErrTwoFactorEnabled:             "La autenticación en dos pasos ya está activada.",
+		userdomain.ErrTwoFactorNotEnrolled:         "La autenticación en dos pasos no se ha configurado.",
+		userdomain.ErrInvalidTwoFactorCode:         "Código de verificación no válido.",
+		userdomain.ErrInvalidChallenge:             "La solicitud de inicio de sesión no es válida o ha caducado.",
+		userdomain.ErrTwoFactorLocked:              "Demasiados códigos de verificación no válidos. Inténtalo de nuevo más tarde.",
+		newsletterdomain.ErrNewsletterNotFound:     "Boletín no encontrado.",
+		newsletterdomain.ErrInvalidOrigin:          "Origen no válido.",
+		newsletterdomain.ErrInvalidSender:          "Remitente no válido.",
+		newsletterdomain.ErrInvalidLanguage:        "Idioma no admitido.",
+		newsletterdomain.ErrInvalidBranding:        "Personalización de marca no válida.",
+		newsletterdomain.ErrInvalidSort:            "Orden no válido.",
+		newsletterdomain.ErrInvalidSlug:            "Identificador de URL (slug) no válido.",
+		newsletterdomain.ErrSlugTaken:              "Este identificador de URL (slug) ya está en uso.",
+		newsletterdomain.ErrInvalidNewsletter:      "El nombre o la descripción del boletín es demasiado largo.",
+		analyticsdomain.ErrInvalidGranularity:      "Granularidad no válida.",
+		analyticsdomain.ErrInvalidRange:            "Intervalo de fechas no válido.",
+		notificationdomain.ErrInvalidDomain:        "Dominio de envío no válido.",
+		notificationdomain.ErrInvalidEnvelope:      "Encabezados de correo no válidos.",
+		postdomain.ErrPostNotFound:                 "Publicación no encontrada.",
+		postdomain.ErrInvalidPost:                  "La publicación necesita un título y no puede ser demasiado larga.",
+		postdomain.ErrPostNotEditable:              "Solo se pueden editar los borradores.",
+		postdomain.ErrInvalidTransition:            "Este cambio de estado no está permitido.",
+		postdomain.ErrPostNotSendable:              "Solo se pueden enviar publicaciones publicadas.",
+		postdomain.ErrPostAlreadySent:              "La publicación ya ha sido enviada.",
+		postdomain.ErrUnknownMergeTag:              "La publicación contiene una etiqueta de combinación desconocida.",
+		postdomain.ErrSpammyContent:                "El contenido parece spam.",
+		segmentdomain.ErrSegmentNotFound:           "Segmento no encontrado.",
+		segmentdomain.ErrInvalidSegment:            "Segmento no válido.",
+		segmentdomain.ErrSegmentInUse:              "El segmento lo usa una campaña no finalizada.",
+		subscriptiondomain.ErrSubscriptionNotFound: "Suscripción no encontrada.",
+		subscriptiondomain.ErrInvalidToken:         "Token no válido.",
+		subscriptiondomain.ErrCaptchaFailed:        "La verificación CAPTCHA ha fallado.",
+		subscriptiondomain.ErrSubscribeCooldown:    "Demasiadas suscripciones, inténtalo más tarde.",
+		subscriptiondomain.ErrInvalidTimezone:      "Zona horaria no válida.",
+		subscriptiondomain.ErrInvalidAttributes:    "Atributos de suscriptor no válidos.",
+		subscriptiondomain.ErrInvalidEmail:         "Dirección de correo electrónico no válida.",
+		campaigndomain.ErrCampaignNotFound:         "Campaña no encontrada.",
+		campaigndomain.ErrInvalidTransition:        "Este cambio de estado de la campaña no está permitido.",
+		campaigndomain.ErrInvalidDeliveryFilter:    "Filtro de entregas no válido.",
+		campaigndomain.ErrInvalidABTest:            "Prueba A/B no válida.",
+		campaigndomain.ErrInvalidSendWindow:        "Ventana de envío no válida.",
+		campaigndomain.ErrInvalidThrottle:          "Límites de limitación de envío no válidos.",
+		campaigndomain.ErrDeliveryNotFound:         "Entrega no encontrada.",
+		pagination.ErrInvalidCursor:                "Cursor no válido.",
+		artifacts.ErrNotFound:                      "Archivo no encontrado.",
+		artifacts.ErrInvalidLink:                   "Enlace de descarga no válido.",
+		artifacts.ErrLinkExpired:                   "El enlace de descarga ha caducado.",
+		artifacts.ErrLinkUsed:                      "El enlace de descarga ya se ha utilizado.",
+		workerpool.ErrQueueFull:                    "El servicio está saturado. Inténtalo de nuevo más tarde.",
+		workerpool.ErrShutdown:                     "El servicio se está deteniendo. Inténtalo de nuevo más tarde.",
+		idempotency.ErrInProgress:                  "Una solicitud con esta Idempotency-Key todavía se está procesando.",
+		idempotency.ErrKeyReused:                   "Esta Idempotency-Key ya se usó para otra solicitud.",
+		limitsdomain.ErrNewsletterLimit:            "Tu plan no permite más boletines.",
+		limitsdomain.ErrSubscriberLimit:            "Este boletín no acepta más suscriptores.",
+		limitsdomain.ErrEmailLimit:                 "Se alcanzó el límite mensual de correos de tu plan.",
+		limitsdomain.ErrPlansNotStored:             "No se pueden asignar planes con este almacenamiento.",
+	},
+	"fr": {
+		userdomain.ErrEmailAlreadyExists:           "Cette adresse e-mail est déjà enregistrée.",
+		userdomain.ErrWeakPassword:                 fmt.Sprintf("Le mot de passe doit contenir entre %d et %d caractères.", userdomain.MinPasswordLength, userdomain.MaxPasswordLength),
+		userdomain.ErrInvalidCredentials:           "Identifiants invalides.",
+		userdomain.ErrInvalidLoginToken:            "Le lien de connexion est invalide ou a expiré.",
+		userdomain.ErrTwoFactorNotConfigured:       "L'authentification à deux facteurs n'est pas configurée.",
+		userdomain.ErrTwoFactorEnabled:             "L'authentification à deux facteurs est déjà activée.",
+		userdomain.ErrTwoFactorNotEnrolled:         "L'authentification à deux facteurs n'a pas été configurée.",
+		userdomain.ErrInvalidTwoFactorCode:         "Code de vérification invalide.",
+		userdomain.ErrInvalidChallenge:             "La demande de connexion est invalide ou a expiré.",
+		userdomain.ErrTwoFactorLocked:              "Trop de codes de vérification invalides. Veuillez réessayer plus tard.",
+		newsletterdomain.ErrNewsletterNotFound:     "Newsletter introuvable.",
+		newsletterdomain.ErrInvalidOrigin:          "Origine invalide.",
+		newsletterdomain.ErrInvalidSender:          "Expéditeur invalide.",
+		newsletterdomain.ErrInvalidLanguage:        "Langue non prise en charge.",
+		newsletterdomain.ErrInvalidBranding:        "Personnalisation de marque invalide.",
+		newsletterdomain.ErrInvalidSort:            "Tri invalide.",
+		newsletterdomain.ErrInvalidSlug:            "Identifiant d'URL (slug) invalide.",
+		newsletterdomain.ErrSlugTaken:              "Cet identifiant d'URL (slug) est déjà utilisé.",
+		newsletterdomain.ErrInvalidNewsletter:      "Le nom ou la description de la newsletter est trop long.",
+		analyticsdomain.ErrInvalidGranularity:      "Granularité invalide.",
+		analyticsdomain.ErrInvalidRange:            "Plage de dates invalide.",
+		notificationdomain.ErrInvalidDomain:        "Domaine d'envoi invalide.",
+		notificationdomain.ErrInvalidEnvelope:      "En-têtes d'e-mail invalides.",
+		postdomain.ErrPostNotFound:                 "Article introuvable.",
+		postdomain.ErrInvalidPost:                  "L'article doit avoir un titre et ne pas être trop long.",
+		postdomain.ErrPostNotEditable:              "Seuls les brouillons peuvent être modifiés.",
+		postdomain.ErrInvalidTransition:            "Ce changement de statut n'est pas autorisé.",
+		postdomain.ErrPostNotSendable:              "Seuls les articles publiés peuvent être envoyés.",
+		postdomain.ErrPostAlreadySent:              "L'article a déjà été envoyé.",
+		postdomain.ErrUnknownMergeTag:              "L'article contient une balise de fusion inconnue.",
+		postdomain.ErrSpammyContent:                "Le contenu ressemble à du spam.",
+		segmentdomain.ErrSegmentNotFound:           "Segment introuvable.",
+		segmentdomain.ErrInvalidSegment:            "Segment invalide.",
+		segmentdomain.ErrSegmentInUse:              "Le segment est utilisé par une campagne non terminée.",
+		subscriptiondomain.ErrSubscriptionNotFound: "Abonnement introuvable.",
+		subscriptiondomain.ErrInvalidToken:         "Jeton invalide.",
+		subscriptiondomain.ErrCaptchaFailed:        "La vérification CAPTCHA a échoué.",
+		subscriptiondomain.ErrSubscribeCooldown:    "Trop d'inscriptions, veuillez réessayer plus tard.",
+		subscriptiondomain.ErrInvalidTimezone:      "Fuseau horaire invalide.",
+		subscriptiondomain.ErrInvalidAttributes:    "Attributs d'abonné invalides.",
+		subscriptiondomain.ErrInvalidEmail:         "Adresse e-mail invalide.",
+		campaigndomain.ErrCampaignNotFound:         "Campagne introuvable.",
+		campaigndomain.ErrInvalidTransition:        "Ce changement de statut de la campagne n'est pas autorisé.",
+		campaigndomain.ErrInvalidDeliveryFilter:    "Filtre de livraisons invalide.",
+		campaigndomain.ErrInvalidABTest:            "Test A/B invalide.",
+		campaigndomain.ErrInvalidSendWindow:        "Fenêtre d'envoi invalide.",
+		campaigndomain.ErrInvalidThrottle:          "Limites de régulation d'envoi invalides.",
+		campaigndomain.ErrDeliveryNotFound:         "Livraison introuvable.",
+		pagination.ErrInvalidCursor:                "Curseur invalide.",
+		artifacts.ErrNotFound:                      "Fichier introuvable.",
+		artifacts.ErrInvalidLink:                   "Lien de téléchargement invalide.",
+		artifacts.ErrLinkExpired:                   "Le lien de téléchargement a expiré.",
+		artifacts.ErrLinkUsed:                      "Le lien de téléchargement a déjà été utilisé.",
+		workerpool.ErrQueueFull:                    "Le service est surchargé. Veuillez réessayer plus tard.",
+		workerpool.ErrShutdown:                     "Le service est en cours d'arrêt. Veuillez réessayer plus tard.",
+		idempotency.ErrInProgress:                  "Une requête avec cette Idempotency-Key est encore en cours de traitement.",
+		idempotency.ErrKeyReused:                   "Cette Idempotency-Key a déjà été utilisée pour une autre requête.",
+		limitsdomain.ErrNewsletterLimit:            "Votre offre ne permet pas de créer d'autres newsletters.",
+		limitsdomain.ErrSubscriberLimit:            "Cette newsletter n'accepte plus de nouveaux abonnés.",
+		limitsdomain.ErrEmailLimit:                 "La limite mensuelle d'e-mails de votre offre est atteinte.",
+		limitsdomain.ErrPlansNotStored:             "Les offres ne peuvent pas être attribuées avec ce stockage.",
+	},
+}
+
+// requestLanguage returns the base language, such as "de", negotiated from
+// the Accept-Language header of r among supportedLanguages.
+func requestLanguage(r *http.Request) string {
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return supportedLanguages[0].String()
+	}
+
+	_, index, _ := languageMatcher.Match(tags...)
+	return supportedLanguages[index].String()
+}
+
+// localize returns the message of err in the language of r, together with
+// that language. known is the domain error err matches, used to look up the
+// translation. Errors without a translation keep their English text, which
+// may carry more detail than the translated message.
+func localize(r *http.Request, known, err error) (string, string) {
+	lang := requestLanguage(r)
+	if message, ok := errorCatalog[lang][known]; ok {
+		return message, lang
+	}
+	return err.Error(), supportedLanguages[0].String()
+}
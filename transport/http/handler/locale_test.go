@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	userdomain "newsletter/internal/users/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLanguage(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},
+		{"de-DE,de;q=0.9,en;q=0.8", "de"},
+		{"fr-CH, en;q=0.5", "fr"},
+		{"ja", "en"},
+		{"not a language", "en"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", tt.acceptLanguage)
+
+		assert.Equal(t, tt.want, requestLanguage(req), tt.acceptLanguage)
+	}
+}
+
+func TestWriteError_Localized(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/users/signup", nil)
+	req.Header.Set("Accept-Language", "es")
+	rec := httptest.NewRecorder()
+
+	WriteError(rec, req, userdomain.ErrEmailAlreadyExists, "failed to create user")
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "es", rec.Header().Get("Content-Language"))
+	assert.Contains(t, rec.Body.String(), "ya está registrado")
+}
+
+func TestWriteError_EnglishKeepsDetails(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/users/signup", nil)
+	rec := httptest.NewRecorder()
+
+	err := userdomain.ValidatePassword("short")
+	WriteError(rec, req, err, "failed to create user")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "en", rec.Header().Get("Content-Language"))
+	assert.Contains(t, rec.Body.String(), err.Error())
+}
+
+func TestErrorCatalog_Complete(t *testing.T) {
+	reference := errorCatalog["de"]
+	for known := range statusOverrides {
+		assert.Contains(t, reference, known, "de translation of %q", known)
+	}
+	for lang, messages := range errorCatalog {
+		for known := range reference {
+			assert.Contains(t, messages, known, "%s translation of %q", lang, known)
+		}
+		assert.Len(t, messages, len(reference), "%s translations", lang)
+	}
+}
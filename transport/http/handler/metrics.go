@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/infrastructure/abuse"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/infrastructure/workerpool"
+	linkdomain "newsletter/internal/links/domain"
+	"strings"
+)
+
+// PoolStatser is implemented by *workerpool.WorkerPool.
+type PoolStatser interface {
+	Stats() workerpool.Stats
+}
+
+// AbuseStatser is implemented by *abuse.Detector.
+type AbuseStatser interface {
+	Stats() abuse.Stats
+}
+
+// LinkStatser is implemented by the short link service.
+type LinkStatser interface {
+	Stats() linkdomain.Stats
+}
+
+// MetricsHandler exposes operational metrics to monitoring systems.
+type MetricsHandler struct {
+	db    func() database.Stats
+	wp    PoolStatser
+	abuse AbuseStatser
+	links LinkStatser // nil until SetLinks
+	token string
+}
+
+// NewMetricsHandler creates a new MetricsHandler reporting the database pool
+// statistics returned by db and the counters of the detection of token
+// guessing. Requests must carry token as a bearer token; an
+// empty token disables the endpoint.
+func NewMetricsHandler(db func() database.Stats, wp PoolStatser, abuse AbuseStatser, token string) *MetricsHandler {
+	return &MetricsHandler{db: db, wp: wp, abuse: abuse, token: token}
+}
+
+// SetLinks adds the counters of the redirects of short links to the metrics.
+func (mh *MetricsHandler) SetLinks(links LinkStatser) {
+	mh.links = links
+}
+
+// Metrics reports the database connection pool and job queue statistics.
+//
+// Route:
+//
+//	GET /metrics
+//
+// Description:
+//
+//	Returns the statistics in the Prometheus text exposition format, so
+//	that operators can diagnose pool saturation: connections in use, idle
+//	and open, and how often and how long requests waited for one. The
+//	redirects of short links are counted as well. Counters are cumulative
+//	since the API started.
+//
+// Responses:
+//
+//	200 OK
+//	  # TYPE newsletter_db_open_connections gauge
+//	  newsletter_db_open_connections 4
+//	  ...
+//
+//	403 Forbidden
+//	  - Missing or invalid bearer token
+//
+//	501 Not Implemented
+//	  - No metrics token is configured
+func (mh *MetricsHandler) Metrics(w http.ResponseWriter, r *http.Request) {
+	if mh.token == "" {
+		http.Error(w, "metrics are not configured", http.StatusNotImplemented)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(mh.token)) != 1 {
+		http.Error(w, "invalid metrics token", http.StatusForbidden)
+		return
+	}
+
+	var body bytes.Buffer
+	metric := func(name, kind, help string, value any) {
+		fmt.Fprintf(&body, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+
+	db := mh.db()
+	metric("newsletter_db_max_open_connections", "gauge", "Maximum number of open connections to the database.", db.MaxConns)
+	metric("newsletter_db_open_connections", "gauge", "Number of established connections, in use or idle.", db.TotalConns)
+	metric("newsletter_db_in_use_connections", "gauge", "Number of connections currently in use.", db.AcquiredConns)
+	metric("newsletter_db_idle_connections", "gauge", "Number of idle connections.", db.IdleConns)
+	metric("newsletter_db_acquire_count_total", "counter", "Number of connections acquired.", db.AcquireCount)
+	metric("newsletter_db_wait_count_total", "counter", "Number of times a connection had to be waited for.", db.EmptyAcquireCount)
+	metric("newsletter_db_acquire_duration_seconds_total", "counter", "Time spent acquiring connections.", db.AcquireDuration.Seconds())
+	metric("newsletter_db_max_idle_time_closed_total", "counter", "Connections closed because they were idle for too long.", db.IdleClosed)
+	metric("newsletter_db_max_lifetime_closed_total", "counter", "Connections closed because they reached their maximum lifetime.", db.LifetimeClosed)
+
+	jobs := mh.wp.Stats()
+	metric("newsletter_jobs_queued", "gauge", "Number of jobs waiting in the queues.", jobs.QueueDepth)
+	metric("newsletter_jobs_queue_capacity", "gauge", "Size of the job queues.", jobs.Capacity)
+	metric("newsletter_jobs_scheduled", "gauge", "Number of delayed jobs waiting for their time.", jobs.Scheduled)
+	metric("newsletter_jobs_processed_total", "counter", "Number of jobs processed.", jobs.Processed)
+	metric("newsletter_jobs_failed_total", "counter", "Number of jobs that failed, including panics.", jobs.Failed)
+	metric("newsletter_jobs_rejected_total", "counter", "Number of jobs refused because the queue was full.", jobs.Rejected)
+	metric("newsletter_jobs_dropped_total", "counter", "Number of queued jobs discarded to make room.", jobs.Dropped)
+	metric("newsletter_jobs_last_wait_seconds", "gauge", "Time the most recently started job spent in the queue.", jobs.LastWait.Seconds())
+
+	abuse := mh.abuse.Stats()
+	metric("newsletter_invalid_tokens_total", "counter", "Number of requests with unknown unsubscribe tokens, by IP address and by user.", abuse.Failures)
+	metric("newsletter_blocked_clients", "gauge", "Number of clients blocked after a burst of unknown tokens.", abuse.Blocked)
+	metric("newsletter_client_blocks_total", "counter", "Number of times a client was blocked.", abuse.Blocks)
+	metric("newsletter_blocked_requests_total", "counter", "Number of requests refused because their client was blocked.", abuse.Refused)
+
+	if mh.links != nil {
+		links := mh.links.Stats()
+		metric("newsletter_link_redirects_total", "counter", "Number of short links followed.", links.Redirects)
+		metric("newsletter_link_not_found_total", "counter", "Number of requests for unknown short link codes.", links.NotFound)
+		metric("newsletter_link_code_collisions_total", "counter", "Number of short link codes generated again because they were taken.", links.Collisions)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		slog.Error("failed to write metrics", "error", err)
+	}
+}
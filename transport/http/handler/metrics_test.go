@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/infrastructure/abuse"
+	"newsletter/internal/infrastructure/database"
+	"newsletter/internal/infrastructure/workerpool"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePoolStats workerpool.Stats
+
+func (f fakePoolStats) Stats() workerpool.Stats { return workerpool.Stats(f) }
+
+type fakeAbuseStats abuse.Stats
+
+func (f fakeAbuseStats) Stats() abuse.Stats { return abuse.Stats(f) }
+
+func TestMetrics_Success(t *testing.T) {
+	h := NewMetricsHandler(
+		func() database.Stats {
+			return database.Stats{MaxConns: 25, TotalConns: 7, AcquiredConns: 5, IdleConns: 2, EmptyAcquireCount: 3, AcquireDuration: 1500 * time.Millisecond}
+		},
+		fakePoolStats{QueueDepth: 4, Processed: 10},
+		fakeAbuseStats{Failures: 42, Blocked: 1},
+		"secret",
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+
+	h.Metrics(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE newsletter_db_in_use_connections gauge\nnewsletter_db_in_use_connections 5\n")
+	assert.Contains(t, body, "newsletter_db_max_open_connections 25\n")
+	assert.Contains(t, body, "newsletter_db_wait_count_total 3\n")
+	assert.Contains(t, body, "newsletter_db_acquire_duration_seconds_total 1.5\n")
+	assert.Contains(t, body, "newsletter_jobs_queued 4\n")
+	assert.Contains(t, body, "newsletter_jobs_processed_total 10\n")
+	assert.Contains(t, body, "newsletter_invalid_tokens_total 42\n")
+	assert.Contains(t, body, "newsletter_blocked_clients 1\n")
+}
+
+func TestMetrics_Token(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+	}{
+		{"not configured", "", "Bearer ", http.StatusNotImplemented},
+		{"missing token", "secret", "", http.StatusForbidden},
+		{"wrong token", "secret", "Bearer other", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewMetricsHandler(func() database.Stats { return database.Stats{} }, fakePoolStats{}, fakeAbuseStats{}, tt.token)
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			rec := httptest.NewRecorder()
+
+			h.Metrics(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
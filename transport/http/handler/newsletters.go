@@ -2,24 +2,58 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"newsletter/internal/infrastructure/pagination"
 	"newsletter/internal/newsletters/domain"
 	userdomain "newsletter/internal/users/domain"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 // NewsletterHandler handles HTTP requests related to newsletters,
 // including creation and retrieval.
 type NewsletterHandler struct {
-	ns domain.NewsletterService
+	ns          domain.NewsletterService
+	cache       *responseCache   // Rendered newsletter listings, see SetCacheTTL
+	links       *LinkBuilder     // Builds the subscribe endpoint of embed scripts
+	subscribers SubscriberCopier // Copies the subscribers of duplicated newsletters; nil when unavailable
+	captcha     *embedCaptcha    // CAPTCHA widget of embed scripts; nil when CAPTCHA is disabled
 }
 
-// NewNewsletterHandler creates a new NewsletterHandler.
-func NewNewsletterHandler(ns domain.NewsletterService) *NewsletterHandler {
-	return &NewsletterHandler{ns: ns}
+// SubscriberCopier copies the active subscribers of a newsletter to another
+// one, such as subscriptiondomain.SubscriptionService.
+type SubscriberCopier interface {
+	CopySubscribers(fromNewsletterID, toNewsletterID uuid.UUID) (int, error)
+}
+
+// NewNewsletterHandler creates a new NewsletterHandler. Newsletter listings
+// are not cached until SetCacheTTL is called.
+func NewNewsletterHandler(ns domain.NewsletterService, links *LinkBuilder) *NewsletterHandler {
+	return &NewsletterHandler{ns: ns, cache: newResponseCache(0), links: links}
+}
+
+// SetCacheTTL caches newsletter listings for ttl. Zero disables caching.
+func (nh *NewsletterHandler) SetCacheTTL(ttl time.Duration) {
+	nh.cache = newResponseCache(ttl)
+}
+
+// SetCaptchaWidget renders the CAPTCHA widget of provider, such as
+// "hcaptcha", with siteKey in embed scripts. An empty or unknown provider
+// renders none.
+func (nh *NewsletterHandler) SetCaptchaWidget(provider, siteKey string) {
+	nh.captcha = newEmbedCaptcha(provider, siteKey)
+}
+
+// SetSubscriberCopier sets what copies the subscribers of a newsletter
+// duplicated with "subscribers": true.
+func (nh *NewsletterHandler) SetSubscriberCopier(copier SubscriberCopier) {
+	nh.subscribers = copier
 }
 
 // Create handles creating a new newsletter.
@@ -37,19 +71,14 @@ func NewNewsletterHandler(ns domain.NewsletterService) *NewsletterHandler {
 //
 //	{
 //	  "name": "My Newsletter",
+//	  "slug": "my-newsletter",
 //	  "description": "Weekly updates about tech"
 //	}
 //
-// Responses:
-//
-//	201 Created
-//	  {
-//	    "id": "uuid",
-//	    "name": "My Newsletter",
-//	    "description": "Weekly updates about tech",
-//	    "owner_id": "uuid",
-//	    "created_at": "2026-01-10T12:00:00Z"
-//	  }
+//	The name and description are plain text: HTML is removed from them.
+//	The slug identifies the newsletter in its public URLs. It is optional:
+//	by default it is derived from the name, with a random suffix when the
+//	name is taken.
 //
 // Responses:
 //
@@ -57,6 +86,7 @@ func NewNewsletterHandler(ns domain.NewsletterService) *NewsletterHandler {
 //	  {
 //	    "id": "uuid",
 //	    "name": "My Newsletter",
+//	    "slug": "my-newsletter",
 //	    "description": "Weekly updates about tech",
 //	    "owner_id": "uuid",
 //	    "created_at": "2026-01-10T12:00:00Z"
@@ -65,35 +95,38 @@ func NewNewsletterHandler(ns domain.NewsletterService) *NewsletterHandler {
 //	400 Bad Request
 //	  - Invalid JSON body
 //	  - Invalid owner ID
+//	  - Invalid slug
+//	  - Name or description longer than the configured limits
+//
+//	409 Conflict
+//	  - The chosen slug is used by another newsletter
 //
 //	401 Unauthorized
 //	  - Missing or invalid authentication context
 //
+//	402 Payment Required
+//	  - The user has as many newsletters as their plan allows
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
 //	500 Internal Server Error
 //	  - Newsletter creation failure
 //
 // Side Effects:
 //   - Persists a new newsletter owned by the authenticated user
+//   - Drops the cached newsletter listings of the user
 func (nh *NewsletterHandler) Create(w http.ResponseWriter, r *http.Request) {
-	value := r.Context().Value(userdomain.UserID)
-	ownerIDStr, ok := value.(string)
+	ownerID, ok := ownerIDFromContext(w, r)
 	if !ok {
-		slog.Warn("owner ID not found in context")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	ownerID, err := uuid.Parse(ownerIDStr)
-	if err != nil {
-		slog.Warn("invalid owner ID", "ownerID", ownerIDStr, "error", err)
-		http.Error(w, "invalid identification", http.StatusBadRequest)
 		return
 	}
 
 	var newsletter domain.Newsletter
-	if err := json.NewDecoder(r.Body).Decode(&newsletter); err != nil {
-		slog.Warn("failed to decode request body", "error", err)
-		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+	if !decodeJSON(w, r, &newsletter, maxBodyBytes) {
 		return
 	}
 
@@ -102,9 +135,10 @@ func (nh *NewsletterHandler) Create(w http.ResponseWriter, r *http.Request) {
 	newNewsletter, err := nh.ns.Create(&newsletter)
 	if err != nil {
 		slog.Error("failed to create newsletter", "owner_id", newsletter.OwnerID, "name", newsletter.Name, "error", err)
-		http.Error(w, "failed to create newsletter: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err, "failed to create newsletter")
 		return
 	}
+	nh.cache.invalidate(ownerID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -122,16 +156,42 @@ func (nh *NewsletterHandler) Create(w http.ResponseWriter, r *http.Request) {
 // Description:
 //
 //	Returns a paginated list of newsletters owned by the authenticated user.
-//	Pagination is controlled via optional query parameters.
+//	Pagination is controlled via optional query parameters. With q, only
+//	the newsletters whose name or description match the full-text search
+//	are returned, most relevant first unless sort is given. Otherwise
+//	newsletters are listed oldest first by default. The X-Total-Count header
+//	gives the number of matching newsletters across all pages, and the Link
+//	header the URLs of the first, previous, next and last pages.
+//
+//	Offset pages get slower the further they are and shift when newsletters
+//	are created. With cursor, which may be empty for the first page, the
+//	listing is paginated by keyset instead: newsletters are ordered by
+//	creation time, q only filters them, and the response is an envelope
+//	whose next_cursor selects the next page, also linked by the Link
+//	header. There is no X-Total-Count, and page is ignored.
+//
+//	Responses carry an ETag: clients polling the listing send it back in
+//	If-None-Match and get an empty 304 while it is unchanged. Listings are
+//	cached per user and query for NEWSLETTER_CACHE_TTL, so changes made
+//	through other instances or to subscriber counts may show up that late.
 //
 // Query Parameters:
 //
-//	limit (int, optional) - Number of newsletters per page (default: 10)
-//	page  (int, optional) - Page number (default: 1)
+//	q              (string, optional)    - Search terms; supports "quoted phrases", or, and -excluded words
+//	sort           (string, optional)    - created_at, name or subscriber_count (active subscribers); only created_at with cursor
+//	order          (string, optional)    - asc (default) or desc
+//	created_after  (RFC 3339, optional)  - Only newsletters created at or after this time
+//	created_before (RFC 3339, optional)  - Only newsletters created before this time
+//	limit          (int, optional)       - Number of newsletters per page (default: 10, max: 100)
+//	page           (int, optional)       - Page number (default: 1)
+//	cursor         (string, optional)    - next_cursor of the previous page, empty for the first page
+//	fields         (string, optional)    - Comma-separated fields of the items to return, such as id,name
 //
 // Responses:
 //
 //	200 OK
+//	  X-Total-Count: 42
+//	  Link: </newsletters?limit=10&page=1>; rel="first", </newsletters?limit=10&page=2>; rel="next", ...
 //	  [
 //	    {
 //	      "id": "uuid",
@@ -142,8 +202,20 @@ func (nh *NewsletterHandler) Create(w http.ResponseWriter, r *http.Request) {
 //	    }
 //	  ]
 //
+//	200 OK (with cursor)
+//	  Link: </newsletters?cursor=eyJj...&limit=10>; rel="next"
+//	  {
+//	    "newsletters": [ ... ],
+//	    "next_cursor": "eyJj..."
+//	  }
+//
 //	400 Bad Request
 //	  - Invalid owner ID
+//	  - Unknown sort field or order, or invalid creation time
+//	  - Invalid cursor, or cursor with a sort other than created_at
+//
+//	304 Not Modified
+//	  - If-None-Match names the ETag of the current listing
 //
 //	401 Unauthorized
 //	  - Missing or invalid authentication context
@@ -152,44 +224,520 @@ func (nh *NewsletterHandler) Create(w http.ResponseWriter, r *http.Request) {
 //	  - Newsletter retrieval failure
 //
 // Side Effects:
-//   - None
+//   - Caches the listing
 func (nh *NewsletterHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	limit = pagination.Limit(limit)
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	query := r.URL.Query()
+	filter := domain.NewsletterFilter{Query: query.Get("q")}
+	if value := query.Get("created_after"); value != "" {
+		if filter.CreatedAfter, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, "invalid created_after: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("created_before"); value != "" {
+		if filter.CreatedBefore, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, "invalid created_before: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	sort := domain.NewsletterSort{Field: query.Get("sort")}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		sort.Descending = true
+	default:
+		http.Error(w, "invalid order: must be asc or desc", http.StatusBadRequest)
+		return
+	}
+
+	byCursor := query.Has("cursor")
+	if byCursor && sort.Field != "" && sort.Field != domain.SortCreatedAt {
+		http.Error(w, "invalid sort: cursor pagination is by created_at", http.StatusBadRequest)
+		return
+	}
+
+	// The listing may be private: it must be revalidated on every use.
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	// The path is part of the key as the Link header is built from it, and
+	// the listing is served under /v1 and the deprecated unversioned route.
+	key := fmt.Sprintf("%s|%s|%q|%v|%s|%s|%d|%d", ownerID, r.URL.Path, filter.Query, sort,
+		filter.CreatedAfter.Format(time.RFC3339Nano), filter.CreatedBefore.Format(time.RFC3339Nano), limit, page)
+	if byCursor {
+		key = fmt.Sprintf("%s|%s|%q|%v|%s|%s|%d|cursor=%q", ownerID, r.URL.Path, filter.Query, sort.Descending,
+			filter.CreatedAfter.Format(time.RFC3339Nano), filter.CreatedBefore.Format(time.RFC3339Nano), limit, query.Get("cursor"))
+	}
+	if cached, ok := nh.cache.get(key); ok {
+		copyHeader(w.Header(), cached.header)
+		writeWithETag(w, r, "application/json", cached.body, cached.etag)
+		return
+	}
+
+	var response any
+	header := http.Header{}
+	if byCursor {
+		newsletters, err := nh.ns.List(ownerID, filter, sort.Descending, limit, query.Get("cursor"))
+		if err != nil {
+			slog.Error("service failure during newsletter retrieval", "owner_id", ownerID, "error", err)
+			WriteError(w, r, err, "failed to retrieve newsletters")
+			return
+		}
+		response = newsletters
+
+		if newsletters.NextCursor != "" {
+			next := r.URL.Query()
+			next.Set("cursor", newsletters.NextCursor)
+			header.Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+		}
+	} else {
+		newsletters, total, err := nh.ns.GetAll(ownerID, filter, sort, limit, page)
+		if err != nil {
+			slog.Error("service failure during newsletter retrieval", "owner_id", ownerID, "error", err)
+			WriteError(w, r, err, "failed to retrieve newsletters")
+			return
+		}
+		response = newsletters
+		header = paginationHeader(r, limit, page, total)
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		slog.Error("failed to encode newsletters response", "owner_id", ownerID, "error", err)
+		http.Error(w, "failed to encode newsletters", http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+
+	etag := entityTag(body)
+	nh.cache.put(ownerID, key, body, etag, header)
+	copyHeader(w.Header(), header)
+	writeWithETag(w, r, "application/json", body, etag)
+}
+
+// paginationHeader returns the headers describing the page of a listing of
+// total items: X-Total-Count, and a Link header (RFC 8288) to the first,
+// previous, next and last pages, relative to the URL of r.
+func paginationHeader(r *http.Request, limit, page, total int) http.Header {
+	header := http.Header{}
+	header.Set("X-Total-Count", strconv.Itoa(total))
+
+	last := max(1, (total+limit-1)/limit)
+	link := func(page int, rel string) string {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(limit))
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), rel)
+	}
+
+	links := []string{link(1, "first")}
+	if page > 1 {
+		links = append(links, link(min(page-1, last), "prev"))
+	}
+	if page < last {
+		links = append(links, link(page+1, "next"))
+	}
+	links = append(links, link(last, "last"))
+	header.Set("Link", strings.Join(links, ", "))
+
+	return header
+}
+
+// copyHeader adds the values of src to dst.
+func copyHeader(dst, src http.Header) {
+	for key, values := range src {
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
+
+// ownerIDFromContext extracts the authenticated user ID stored in the request
+// context by the authentication middleware.
+//
+// On failure it writes the error response (401 when the ID is missing,
+// 400 when it is not a valid UUID) and returns false.
+func ownerIDFromContext(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
 	value := r.Context().Value(userdomain.UserID)
 	ownerIDStr, ok := value.(string)
 	if !ok {
 		slog.Warn("owner ID not found in context")
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
+		return uuid.Nil, false
 	}
 
 	ownerID, err := uuid.Parse(ownerIDStr)
 	if err != nil {
 		slog.Warn("invalid owner ID", "ownerID", ownerIDStr, "error", err)
 		http.Error(w, "invalid identification", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	return ownerID, true
+}
+
+// ownedNewsletter loads the newsletter from the path and checks that it
+// belongs to the authenticated user. It writes an error response and
+// returns false otherwise.
+func ownedNewsletter(w http.ResponseWriter, r *http.Request, ns domain.NewsletterService) (*domain.Newsletter, bool) {
+	ownerID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	newsletterID, err := uuid.Parse(mux.Vars(r)["newsletter_id"])
+	if err != nil {
+		http.Error(w, "invalid newsletter ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return newsletterOwnedBy(w, r, ns, newsletterID, ownerID)
+}
+
+// newsletterOwnedBy returns the newsletter newsletterID, provided it is
+// owned by ownerID. Otherwise it writes an error response and returns false.
+func newsletterOwnedBy(w http.ResponseWriter, r *http.Request, ns domain.NewsletterService, newsletterID, ownerID uuid.UUID) (*domain.Newsletter, bool) {
+	newsletter, err := ns.Get(newsletterID)
+	if err != nil {
+		WriteError(w, r, err, "failed to get newsletter")
+		return nil, false
+	}
+	if newsletter.OwnerID != ownerID {
+		http.Error(w, domain.ErrNewsletterNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+
+	return newsletter, true
+}
+
+// UpdateSettings handles replacing the settings of a newsletter.
+//
+// Route:
+//
+//	PUT /newsletters/{newsletter_id}/settings
+//
+// Description:
+//
+//	Replaces the owner-configurable settings of a newsletter owned by the
+//	authenticated user. Allowed origins control which websites may call the
+//	public subscribe endpoint from a browser (CORS). The sender name and
+//	address are used as "from" of outgoing emails once the address has been
+//	verified (see SenderHandler); changing the address resets its verification.
+//	The language is the default language of system emails sent to subscribers
+//	whose language is unknown: "en", "de", "es" or "fr". The reply-to
+//	address, cc and bcc copies and custom headers apply to the emails of
+//	every campaign, which may override them; headers set by the service,
+//	such as Subject or List-Unsubscribe, cannot be customized.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "allowed_origins": ["https://example.com"],
+//	  "from_name": "My Newsletter",
+//	  "from_email": "news@example.com",
+//	  "language": "de",
+//	  "reply_to": "editor@example.com",
+//	  "bcc": ["archive@example.com"],
+//	  "headers": {"List-Id": "My Newsletter <news.example.com>"}
+//	}
+//
+// Responses:
+//
+//	200 OK
+//	  - The updated newsletter
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//	  - Invalid JSON body
+//	  - Invalid origin
+//	  - Invalid sender name or address
+//	  - Unsupported language
+//	  - Invalid reply-to or copy address, more than 10 copies or 20
+//	    headers, or a header set by the service
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	500 Internal Server Error
+//	  - Settings update failure
+//
+// Side Effects:
+//   - Drops the cached newsletter listings of the user
+func (nh *NewsletterHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := ownerIDFromContext(w, r)
+	if !ok {
 		return
 	}
 
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10
+	newsletterID, err := uuid.Parse(mux.Vars(r)["newsletter_id"])
+	if err != nil {
+		http.Error(w, "invalid newsletter ID", http.StatusBadRequest)
+		return
 	}
 
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil || page <= 0 {
-		page = 1
+	var settings domain.Settings
+	if !decodeJSON(w, r, &settings, maxBodyBytes) {
+		return
 	}
 
-	newsletters, err := nh.ns.GetAll(ownerID, limit, page)
+	newsletter, err := nh.ns.UpdateSettings(newsletterID, ownerID, settings)
 	if err != nil {
-		slog.Error("service failure during newsletter retrieval", "owner_id", ownerID, "error", err)
-		http.Error(w, "failed to retrieve newsletters: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err, "failed to update newsletter settings")
 		return
 	}
+	nh.cache.invalidate(ownerID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(newsletter); err != nil {
+		slog.Error("failed to encode newsletter response", "newsletter_id", newsletterID, "error", err)
+	}
+}
 
-	if err := json.NewEncoder(w).Encode(newsletters); err != nil {
-		slog.Error("failed to encode newsletters response", "owner_id", ownerID, "error", err)
+// DuplicateRequest is the body of a request duplicating a newsletter.
+type DuplicateRequest struct {
+	Name        string `json:"name"`        // Name of the duplicate; defaults to the name of the newsletter followed by " (copy)"
+	Slug        string `json:"slug"`        // Slug of the duplicate; derived from its name by default
+	Subscribers bool   `json:"subscribers"` // Whether to copy the active subscribers
+}
+
+// DuplicateResponse is a duplicated newsletter.
+type DuplicateResponse struct {
+	*domain.Newsletter
+	SubscribersCopied int `json:"subscribers_copied"` // Number of subscribers copied
+}
+
+// Duplicate handles creating a newsletter from an existing one.
+//
+// Route:
+//
+//	POST /newsletters/{newsletter_id}/duplicate
+//
+// Description:
+//
+//	Creates a newsletter owned by the authenticated user with the
+//	description and settings of one of their newsletters, for seasonal or
+//	spin-off publications: CORS origins, sender and its verification,
+//	language, branding and email envelope. Posts are not copied. With
+//	"subscribers": true, the active subscribers are subscribed to the
+//	duplicate too, with their tags, language, timezone and attributes; only
+//	copy them when they agreed to receive the new publication.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "name": "Tech (summer edition)",
+//	  "slug": "tech-summer",
+//	  "subscribers": true
+//	}
+//
+//	Every field is optional: send {} for a copy named after the newsletter.
+//
+// Responses:
+//
+//	201 Created
+//	  {
+//	    "id": "uuid",
+//	    "name": "Tech (summer edition)",
+//	    "slug": "tech-summer",
+//	    ...,
+//	    "subscribers_copied": 1250
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//	  - Invalid JSON body
+//	  - Invalid slug, or name longer than the configured limit
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	402 Payment Required
+//	  - The user has as many newsletters as their plan allows, or the
+//	    duplicate reached the subscriber limit while copying subscribers
+//
+//	403 Forbidden
+//	  - Copying subscribers without the subscribers:write scope
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	409 Conflict
+//	  - The slug is used by another newsletter
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	500 Internal Server Error
+//	  - Duplication failure
+//
+//	501 Not Implemented
+//	  - Copying subscribers is not available
+//
+// Side Effects:
+//   - Persists a new newsletter owned by the authenticated user, which is
+//     kept when copying its settings or subscribers fails
+//   - Subscribes the active subscribers of the newsletter to it, if requested
+//   - Drops the cached newsletter listings of the user
+func (nh *NewsletterHandler) Duplicate(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	newsletterID, err := uuid.Parse(mux.Vars(r)["newsletter_id"])
+	if err != nil {
+		http.Error(w, "invalid newsletter ID", http.StatusBadRequest)
+		return
+	}
+
+	var req DuplicateRequest
+	if !decodeJSON(w, r, &req, maxBodyBytes) {
+		return
+	}
+	if req.Subscribers {
+		if nh.subscribers == nil {
+			http.Error(w, "copying subscribers is not available", http.StatusNotImplemented)
+			return
+		}
+		scopes, _ := r.Context().Value(userdomain.Scopes).([]userdomain.Scope)
+		if !userdomain.HasScope(scopes, userdomain.ScopeSubscribersWrite) {
+			http.Error(w, "copying subscribers requires the "+string(userdomain.ScopeSubscribersWrite)+" scope", http.StatusForbidden)
+			return
+		}
+	}
+
+	duplicate, err := nh.ns.Duplicate(newsletterID, ownerID, req.Name, req.Slug)
+	if err != nil {
+		slog.Error("failed to duplicate newsletter", "newsletter_id", newsletterID, "owner_id", ownerID, "error", err)
+		WriteError(w, r, err, "failed to duplicate newsletter")
+		return
+	}
+	nh.cache.invalidate(ownerID)
+
+	response := DuplicateResponse{Newsletter: duplicate}
+	if req.Subscribers {
+		response.SubscribersCopied, err = nh.subscribers.CopySubscribers(newsletterID, duplicate.ID)
+		if err != nil {
+			slog.Error("failed to copy subscribers of duplicated newsletter", "newsletter_id", duplicate.ID, "copied", response.SubscribersCopied, "error", err)
+			WriteError(w, r, err, "newsletter duplicated, but failed to copy its subscribers")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode newsletter response", "newsletter_id", duplicate.ID, "error", err)
+	}
+}
+
+// UpdateSlug handles changing the slug of a newsletter.
+//
+// Route:
+//
+//	PUT /newsletters/{newsletter_id}/slug
+//
+// Description:
+//
+//	Changes the slug identifying the newsletter in its public URLs, such as
+//	/public/{slug}. Slugs are 3 to 64 lowercase letters and digits, in words
+//	separated by single hyphens, and unique across newsletters. The previous
+//	slug is released: links using it stop working.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "slug": "weekly-tech"
+//	}
+//
+// Responses:
+//
+//	200 OK
+//	  - The updated newsletter
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//	  - Invalid JSON body
+//	  - Invalid slug
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	409 Conflict
+//	  - The slug is used by another newsletter
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	500 Internal Server Error
+//	  - Slug update failure
+//
+// Side Effects:
+//   - Drops the cached newsletter listings of the user
+func (nh *NewsletterHandler) UpdateSlug(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	newsletterID, err := uuid.Parse(mux.Vars(r)["newsletter_id"])
+	if err != nil {
+		http.Error(w, "invalid newsletter ID", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Slug string `json:"slug"`
+	}
+	if !decodeJSON(w, r, &body, maxBodyBytes) {
+		return
+	}
+
+	newsletter, err := nh.ns.UpdateSlug(newsletterID, ownerID, body.Slug)
+	if err != nil {
+		WriteError(w, r, err, "failed to update newsletter slug")
+		return
+	}
+	nh.cache.invalidate(ownerID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(newsletter); err != nil {
+		slog.Error("failed to encode newsletter response", "newsletter_id", newsletterID, "error", err)
 	}
 }
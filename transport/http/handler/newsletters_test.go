@@ -4,13 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"newsletter/internal/infrastructure/pagination"
 	"newsletter/internal/newsletters/domain"
 	userdomain "newsletter/internal/users/domain"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -25,9 +31,48 @@ func (m *MockNewsletterService) Create(n *domain.Newsletter) (*domain.Newsletter
 	return args.Get(0).(*domain.Newsletter), args.Error(1)
 }
 
-func (m *MockNewsletterService) GetAll(ownerID uuid.UUID, limit, page int) ([]*domain.Newsletter, error) {
-	args := m.Called(ownerID, limit, page)
-	return args.Get(0).([]*domain.Newsletter), args.Error(1)
+func (m *MockNewsletterService) GetAll(ownerID uuid.UUID, filter domain.NewsletterFilter, sort domain.NewsletterSort, limit, page int) ([]*domain.Newsletter, int, error) {
+	args := m.Called(ownerID, filter, sort, limit, page)
+	return args.Get(0).([]*domain.Newsletter), args.Int(1), args.Error(2)
+}
+
+func (m *MockNewsletterService) List(ownerID uuid.UUID, filter domain.NewsletterFilter, descending bool, limit int, cursor string) (*domain.NewsletterPage, error) {
+	args := m.Called(ownerID, filter, descending, limit, cursor)
+	page := args.Get(0)
+	if page == nil {
+		return nil, args.Error(1)
+	}
+	return page.(*domain.NewsletterPage), args.Error(1)
+}
+
+func (m *MockNewsletterService) Get(id uuid.UUID) (*domain.Newsletter, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.Newsletter), args.Error(1)
+}
+
+func (m *MockNewsletterService) GetBySlug(slug string) (*domain.Newsletter, error) {
+	args := m.Called(slug)
+	return args.Get(0).(*domain.Newsletter), args.Error(1)
+}
+
+func (m *MockNewsletterService) UpdateSlug(id, ownerID uuid.UUID, slug string) (*domain.Newsletter, error) {
+	args := m.Called(id, ownerID, slug)
+	return args.Get(0).(*domain.Newsletter), args.Error(1)
+}
+
+func (m *MockNewsletterService) UpdateSettings(id, ownerID uuid.UUID, settings domain.Settings) (*domain.Newsletter, error) {
+	args := m.Called(id, ownerID, settings)
+	return args.Get(0).(*domain.Newsletter), args.Error(1)
+}
+
+func (m *MockNewsletterService) SetSenderVerified(id uuid.UUID, fromEmail string, verified bool) error {
+	args := m.Called(id, fromEmail, verified)
+	return args.Error(0)
+}
+
+func (m *MockNewsletterService) Duplicate(id, ownerID uuid.UUID, name, slug string) (*domain.Newsletter, error) {
+	args := m.Called(id, ownerID, name, slug)
+	return args.Get(0).(*domain.Newsletter), args.Error(1)
 }
 
 // --- helper function to set user ID in context ---
@@ -39,7 +84,7 @@ func contextWithUserID(ctx context.Context, userID string) context.Context {
 
 func TestCreateNewsletter_Success(t *testing.T) {
 	mockSvc := new(MockNewsletterService)
-	h := NewNewsletterHandler(mockSvc)
+	h := NewNewsletterHandler(mockSvc, testLinks)
 
 	ownerID := uuid.New()
 	body := domain.Newsletter{Name: "Tech Newsletter"}
@@ -65,7 +110,7 @@ func TestCreateNewsletter_Success(t *testing.T) {
 
 func TestCreateNewsletter_Unauthorized(t *testing.T) {
 	mockSvc := new(MockNewsletterService)
-	h := NewNewsletterHandler(mockSvc)
+	h := NewNewsletterHandler(mockSvc, testLinks)
 
 	req := httptest.NewRequest(http.MethodPost, "/newsletters", nil)
 	rec := httptest.NewRecorder()
@@ -77,7 +122,7 @@ func TestCreateNewsletter_Unauthorized(t *testing.T) {
 
 func TestGetAllNewsletters_Success(t *testing.T) {
 	mockSvc := new(MockNewsletterService)
-	h := NewNewsletterHandler(mockSvc)
+	h := NewNewsletterHandler(mockSvc, testLinks)
 
 	ownerID := uuid.New()
 	req := httptest.NewRequest(http.MethodGet, "/newsletters?limit=2&page=1", nil)
@@ -89,11 +134,13 @@ func TestGetAllNewsletters_Success(t *testing.T) {
 		{ID: uuid.New(), OwnerID: ownerID, Name: "Science"},
 	}
 
-	mockSvc.On("GetAll", ownerID, 2, 1).Return(newsletters, nil)
+	mockSvc.On("GetAll", ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 2, 1).Return(newsletters, 5, nil)
 
 	h.GetAll(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</newsletters?limit=2&page=1>; rel="first", </newsletters?limit=2&page=2>; rel="next", </newsletters?limit=2&page=3>; rel="last"`, rec.Header().Get("Link"))
 	var resp []*domain.Newsletter
 	err := json.NewDecoder(rec.Body).Decode(&resp)
 	assert.NoError(t, err)
@@ -101,3 +148,459 @@ func TestGetAllNewsletters_Success(t *testing.T) {
 
 	mockSvc.AssertExpectations(t)
 }
+
+func TestGetAllNewsletters_Search(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	ownerID := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/newsletters?q="+url.QueryEscape(`"machine learning" -crypto`), nil)
+	req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+	rec := httptest.NewRecorder()
+
+	mockSvc.On("GetAll", ownerID, domain.NewsletterFilter{Query: `"machine learning" -crypto`}, domain.NewsletterSort{}, 10, 1).Return([]*domain.Newsletter{}, 0, nil)
+
+	h.GetAll(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestGetAllNewsletters_ETag(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	ownerID := uuid.New()
+	newsletters := []*domain.Newsletter{{ID: uuid.New(), OwnerID: ownerID, Name: "Tech"}}
+	mockSvc.On("GetAll", ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 10, 1).Return(newsletters, 0, nil)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/newsletters", nil)
+		req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.GetAll(rec, req)
+		return rec
+	}
+
+	first := get("")
+	assert.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "private, no-cache", first.Header().Get("Cache-Control"))
+
+	notModified := get(`"other", W/` + etag)
+	assert.Equal(t, http.StatusNotModified, notModified.Code)
+	assert.Empty(t, notModified.Body.String())
+	assert.Equal(t, etag, notModified.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusOK, get(`"other"`).Code)
+}
+
+func TestGetAllNewsletters_Cache(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+	h.SetCacheTTL(time.Minute)
+
+	ownerID := uuid.New()
+	mockSvc.On("GetAll", ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 10, 1).
+		Return([]*domain.Newsletter{{ID: uuid.New(), OwnerID: ownerID, Name: "Tech"}}, 1, nil)
+	mockSvc.On("GetAll", ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 10, 2).
+		Return([]*domain.Newsletter{}, 1, nil)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+		rec := httptest.NewRecorder()
+		h.GetAll(rec, req)
+		return rec
+	}
+
+	first := get("/newsletters")
+	second := get("/newsletters?page=1")
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+	mockSvc.AssertNumberOfCalls(t, "GetAll", 1)
+
+	// Pages are cached separately.
+	get("/newsletters?page=2")
+	mockSvc.AssertNumberOfCalls(t, "GetAll", 2)
+
+	// Creating a newsletter drops the cached listings of its owner.
+	created := &domain.Newsletter{ID: uuid.New(), OwnerID: ownerID, Name: "Science"}
+	mockSvc.On("Create", &domain.Newsletter{OwnerID: ownerID, Name: "Science"}).Return(created, nil)
+	req := httptest.NewRequest(http.MethodPost, "/newsletters", bytes.NewReader([]byte(`{"name":"Science"}`)))
+	req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+	h.Create(httptest.NewRecorder(), req)
+
+	get("/newsletters")
+	mockSvc.AssertNumberOfCalls(t, "GetAll", 3)
+}
+
+func TestGetAllNewsletters_CacheByPath(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+	h.SetCacheTTL(time.Minute)
+
+	ownerID := uuid.New()
+	mockSvc.On("GetAll", ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, 1, 1).
+		Return([]*domain.Newsletter{{ID: uuid.New(), OwnerID: ownerID, Name: "Tech"}}, 2, nil)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+		rec := httptest.NewRecorder()
+		h.GetAll(rec, req)
+		return rec
+	}
+
+	legacy := get("/newsletters?limit=1")
+	versioned := get("/v1/newsletters?limit=1")
+
+	assert.Contains(t, legacy.Header().Get("Link"), "</newsletters?")
+	assert.Contains(t, versioned.Header().Get("Link"), "</v1/newsletters?")
+	assert.NotContains(t, versioned.Header().Get("Link"), "</newsletters?")
+	mockSvc.AssertNumberOfCalls(t, "GetAll", 2)
+}
+
+func TestGetAllNewsletters_MaxLimit(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	ownerID := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/newsletters?limit=100000", nil)
+	req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+	rec := httptest.NewRecorder()
+
+	mockSvc.On("GetAll", ownerID, domain.NewsletterFilter{}, domain.NewsletterSort{}, pagination.MaxLimit, 1).Return([]*domain.Newsletter{}, 0, nil)
+
+	h.GetAll(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestGetAllNewsletters_SortAndCreationRange(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	ownerID := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/newsletters?sort=subscriber_count&order=desc&created_after=2026-01-01T00:00:00Z&created_before=2026-02-01T00:00:00Z", nil)
+	req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+	rec := httptest.NewRecorder()
+
+	filter := domain.NewsletterFilter{
+		CreatedAfter:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedBefore: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	sort := domain.NewsletterSort{Field: domain.SortSubscriberCount, Descending: true}
+	mockSvc.On("GetAll", ownerID, filter, sort, 10, 1).Return([]*domain.Newsletter{}, 0, nil)
+
+	h.GetAll(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestGetAllNewsletters_InvalidParameters(t *testing.T) {
+	for _, query := range []string{"order=up", "created_after=yesterday", "created_before=2026-01-01"} {
+		t.Run(query, func(t *testing.T) {
+			mockSvc := new(MockNewsletterService)
+			h := NewNewsletterHandler(mockSvc, testLinks)
+
+			req := httptest.NewRequest(http.MethodGet, "/newsletters?"+query, nil)
+			req = req.WithContext(contextWithUserID(req.Context(), uuid.NewString()))
+			rec := httptest.NewRecorder()
+
+			h.GetAll(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			mockSvc.AssertNotCalled(t, "GetAll")
+		})
+	}
+}
+
+func TestGetAllNewsletters_Cursor(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	ownerID := uuid.New()
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+		rec := httptest.NewRecorder()
+		h.GetAll(rec, req)
+		return rec
+	}
+
+	page := &domain.NewsletterPage{
+		Newsletters: []*domain.Newsletter{{ID: uuid.New(), OwnerID: ownerID, Name: "Tech"}},
+		NextCursor:  "next",
+	}
+	mockSvc.On("List", ownerID, domain.NewsletterFilter{Query: "tech"}, true, 1, "").Return(page, nil)
+
+	rec := get("/newsletters?q=tech&order=desc&limit=1&cursor=")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</newsletters?cursor=next&limit=1&order=desc&q=tech>; rel="next"`, rec.Header().Get("Link"))
+	var resp domain.NewsletterPage
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "next", resp.NextCursor)
+	assert.Len(t, resp.Newsletters, 1)
+
+	mockSvc.On("List", ownerID, domain.NewsletterFilter{}, false, 10, "bad").Return(nil, pagination.ErrInvalidCursor)
+	assert.Equal(t, http.StatusBadRequest, get("/newsletters?cursor=bad").Code)
+
+	// Keyset pagination follows the creation order only.
+	assert.Equal(t, http.StatusBadRequest, get("/newsletters?cursor=&sort=name").Code)
+
+	mockSvc.AssertExpectations(t)
+	mockSvc.AssertNotCalled(t, "GetAll")
+}
+
+func TestGetAllNewsletters_InvalidSort(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	ownerID := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/newsletters?sort=popularity", nil)
+	req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+	rec := httptest.NewRecorder()
+
+	sort := domain.NewsletterSort{Field: "popularity"}
+	mockSvc.On("GetAll", ownerID, domain.NewsletterFilter{}, sort, 10, 1).
+		Return([]*domain.Newsletter(nil), 0, fmt.Errorf("%w: %q", domain.ErrInvalidSort, sort.Field))
+
+	h.GetAll(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestUpdateSettings_Success(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	settings := domain.Settings{AllowedOrigins: []string{"https://example.com"}}
+	jsonBody, _ := json.Marshal(settings)
+
+	req := httptest.NewRequest(http.MethodPut, "/newsletters/"+newsletterID.String()+"/settings", bytes.NewReader(jsonBody))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String()})
+	req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+	rec := httptest.NewRecorder()
+
+	updated := &domain.Newsletter{ID: newsletterID, OwnerID: ownerID, Settings: settings}
+	mockSvc.On("UpdateSettings", newsletterID, ownerID, settings).Return(updated, nil)
+
+	h.UpdateSettings(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp domain.Newsletter
+	err := json.NewDecoder(rec.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Equal(t, settings.AllowedOrigins, resp.AllowedOrigins)
+
+	mockSvc.AssertExpectations(t)
+}
+
+func TestUpdateSettings_NotFound(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	settings := domain.Settings{AllowedOrigins: []string{}}
+	jsonBody, _ := json.Marshal(settings)
+
+	req := httptest.NewRequest(http.MethodPut, "/newsletters/"+newsletterID.String()+"/settings", bytes.NewReader(jsonBody))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String()})
+	req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+	rec := httptest.NewRecorder()
+
+	mockSvc.On("UpdateSettings", newsletterID, ownerID, settings).Return((*domain.Newsletter)(nil), domain.ErrNewsletterNotFound)
+
+	h.UpdateSettings(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestUpdateSlug_Success(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+
+	req := httptest.NewRequest(http.MethodPut, "/newsletters/"+newsletterID.String()+"/slug", strings.NewReader(`{"slug":"weekly-tech"}`))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String()})
+	req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+	rec := httptest.NewRecorder()
+
+	updated := &domain.Newsletter{ID: newsletterID, OwnerID: ownerID, Slug: "weekly-tech"}
+	mockSvc.On("UpdateSlug", newsletterID, ownerID, "weekly-tech").Return(updated, nil)
+
+	h.UpdateSlug(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp domain.Newsletter
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "weekly-tech", resp.Slug)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestUpdateSlug_Errors(t *testing.T) {
+	tests := map[error]int{
+		domain.ErrInvalidSlug:        http.StatusBadRequest,
+		domain.ErrSlugTaken:          http.StatusConflict,
+		domain.ErrNewsletterNotFound: http.StatusNotFound,
+	}
+	for err, status := range tests {
+		mockSvc := new(MockNewsletterService)
+		h := NewNewsletterHandler(mockSvc, testLinks)
+
+		ownerID, newsletterID := uuid.New(), uuid.New()
+		req := httptest.NewRequest(http.MethodPut, "/newsletters/"+newsletterID.String()+"/slug", strings.NewReader(`{"slug":"taken"}`))
+		req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String()})
+		req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+		rec := httptest.NewRecorder()
+
+		mockSvc.On("UpdateSlug", newsletterID, ownerID, "taken").Return((*domain.Newsletter)(nil), err)
+
+		h.UpdateSlug(rec, req)
+
+		assert.Equal(t, status, rec.Code, err.Error())
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	duplicate := &domain.Newsletter{ID: uuid.New(), OwnerID: ownerID, Name: "Tech (copy)", Slug: "tech-copy"}
+
+	serve := func(h *NewsletterHandler, body string, scopes ...userdomain.Scope) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/newsletters/"+newsletterID.String()+"/duplicate", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String()})
+		ctx := contextWithUserID(req.Context(), ownerID.String())
+		req = req.WithContext(context.WithValue(ctx, userdomain.Scopes, scopes))
+		rec := httptest.NewRecorder()
+		h.Duplicate(rec, req)
+		return rec
+	}
+
+	t.Run("settings only", func(t *testing.T) {
+		mockSvc := new(MockNewsletterService)
+		h := NewNewsletterHandler(mockSvc, testLinks)
+		mockSvc.On("Duplicate", newsletterID, ownerID, "", "").Return(duplicate, nil)
+
+		rec := serve(h, `{}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		var resp map[string]any
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "tech-copy", resp["slug"])
+		assert.Equal(t, float64(0), resp["subscribers_copied"])
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("with subscribers", func(t *testing.T) {
+		mockSvc := new(MockNewsletterService)
+		mockSubs := new(MockSubscriptionService)
+		h := NewNewsletterHandler(mockSvc, testLinks)
+		h.SetSubscriberCopier(mockSubs)
+		mockSvc.On("Duplicate", newsletterID, ownerID, "Tech summer", "tech-summer").Return(duplicate, nil)
+		mockSubs.On("CopySubscribers", newsletterID, duplicate.ID).Return(42, nil)
+
+		rec := serve(h, `{"name":"Tech summer","slug":"tech-summer","subscribers":true}`, userdomain.ScopeNewslettersWrite, userdomain.ScopeSubscribersWrite)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		var resp DuplicateResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, 42, resp.SubscribersCopied)
+		mockSvc.AssertExpectations(t)
+		mockSubs.AssertExpectations(t)
+	})
+
+	t.Run("subscribers without scope", func(t *testing.T) {
+		mockSvc := new(MockNewsletterService)
+		h := NewNewsletterHandler(mockSvc, testLinks)
+		h.SetSubscriberCopier(new(MockSubscriptionService))
+
+		rec := serve(h, `{"subscribers":true}`, userdomain.ScopeNewslettersWrite)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		mockSvc.AssertNotCalled(t, "Duplicate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockSvc := new(MockNewsletterService)
+		h := NewNewsletterHandler(mockSvc, testLinks)
+		mockSvc.On("Duplicate", newsletterID, ownerID, "", "").Return((*domain.Newsletter)(nil), domain.ErrNewsletterNotFound)
+
+		assert.Equal(t, http.StatusNotFound, serve(h, `{}`).Code)
+	})
+}
+
+func TestEmbedScript_Success(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	newsletter := &domain.Newsletter{ID: uuid.New(), Name: "Tech </script> News", Slug: "tech-news"}
+
+	req := httptest.NewRequest(http.MethodGet, "/embed/tech-news.js", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	rec := httptest.NewRecorder()
+
+	mockSvc.On("GetBySlug", "tech-news").Return(newsletter, nil)
+
+	h.EmbedScript(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "application/javascript")
+	assert.Contains(t, rec.Body.String(), "https://api.example.com/v1/subscriptions/"+newsletter.ID.String())
+	assert.NotContains(t, rec.Body.String(), "</script>")
+
+	// Revalidating an unchanged script
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	h.EmbedScript(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	mockSvc.AssertExpectations(t)
+}
+
+func TestEmbedScript_ByID(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	newsletter := &domain.Newsletter{ID: uuid.New(), Name: "Tech News", Slug: "tech-news"}
+
+	req := httptest.NewRequest(http.MethodGet, "/embed/"+newsletter.ID.String()+".js", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": newsletter.ID.String()})
+	rec := httptest.NewRecorder()
+
+	mockSvc.On("GetBySlug", newsletter.ID.String()).Return((*domain.Newsletter)(nil), domain.ErrNewsletterNotFound)
+	mockSvc.On("Get", newsletter.ID).Return(newsletter, nil)
+
+	h.EmbedScript(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "https://api.example.com/v1/subscriptions/"+newsletter.ID.String())
+	mockSvc.AssertExpectations(t)
+}
+
+func TestEmbedScript_UnknownSlug(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewNewsletterHandler(mockSvc, testLinks)
+
+	req := httptest.NewRequest(http.MethodGet, "/embed/unknown.js", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "unknown"})
+	rec := httptest.NewRecorder()
+
+	mockSvc.On("GetBySlug", "unknown").Return((*domain.Newsletter)(nil), domain.ErrNewsletterNotFound)
+
+	h.EmbedScript(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockSvc.AssertNotCalled(t, "Get", mock.Anything)
+}
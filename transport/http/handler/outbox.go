@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/notifications/infrastructure/outbox"
+	"strings"
+)
+
+// Outbox is implemented by *outbox.Provider.
+type Outbox interface {
+	Messages(to string) []outbox.Message
+}
+
+// OutboxHandler shows the emails recorded instead of being sent when
+// EMAIL_DRY_RUN is enabled.
+type OutboxHandler struct {
+	outbox Outbox
+	token  string // Bearer token required by Outbox, the metrics token
+}
+
+// NewOutboxHandler creates a new OutboxHandler listing the emails of
+// outbox to the clients presenting token.
+func NewOutboxHandler(outbox Outbox, token string) *OutboxHandler {
+	return &OutboxHandler{outbox: outbox, token: token}
+}
+
+// Outbox handles listing the recorded emails.
+//
+// Route:
+//
+//	GET /debug/outbox
+//
+// Description:
+//
+//	Returns the most recent emails recorded by the dry-run email provider,
+//	newest first, so that sign up, confirmation and campaign emails can be
+//	inspected in development. The route only exists when EMAIL_DRY_RUN and
+//	DEBUG_OUTBOX are enabled. The recorded emails hold magic sign-in,
+//	confirmation and unsubscribe links of real accounts, so the route
+//	requires the metrics token like /metrics, as
+//	"Authorization: Bearer <METRICS_TOKEN>".
+//
+// Query Parameters:
+//
+//	to  (string, optional)  - Only the emails sent to this address
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "emails": [
+//	      {
+//	        "id": "outbox-uuid",
+//	        "from": "news@example.com",
+//	        "to": "reader@example.com",
+//	        "subject": "Confirm your subscription",
+//	        "text": "...",
+//	        "html": "...",
+//	        "sent_at": "2026-01-10T12:00:00Z"
+//	      }
+//	    ]
+//	  }
+//
+//	403 Forbidden
+//	  - Missing or invalid bearer token
+//
+//	501 Not Implemented
+//	  - No metrics token is configured
+func (oh *OutboxHandler) Outbox(w http.ResponseWriter, r *http.Request) {
+	if oh.token == "" {
+		http.Error(w, "outbox is not configured", http.StatusNotImplemented)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(oh.token)) != 1 {
+		http.Error(w, "invalid outbox token", http.StatusForbidden)
+		return
+	}
+
+	messages := oh.outbox.Messages(r.URL.Query().Get("to"))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"emails": messages}); err != nil {
+		slog.Error("failed to encode outbox response", "error", err)
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/notifications/domain"
+	"newsletter/internal/notifications/infrastructure/outbox"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox(t *testing.T) {
+	provider := outbox.NewProvider("news@example.com", nil)
+	require.NoError(t, provider.Send(context.Background(), &domain.Email{To: "a@example.com", Subject: "Welcome"}))
+	require.NoError(t, provider.Send(context.Background(), &domain.Email{To: "b@example.com", Subject: "Confirm"}))
+	h := NewOutboxHandler(provider, "metrics-secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/outbox?to=b@example.com", nil)
+	req.Header.Set("Authorization", "Bearer metrics-secret")
+	rec := httptest.NewRecorder()
+
+	h.Outbox(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response struct {
+		Emails []outbox.Message `json:"emails"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Len(t, response.Emails, 1)
+	assert.Equal(t, "Confirm", response.Emails[0].Subject)
+	assert.Equal(t, "news@example.com", response.Emails[0].From)
+}
+
+func TestOutbox_Unauthorized(t *testing.T) {
+	provider := outbox.NewProvider("news@example.com", nil)
+	require.NoError(t, provider.Send(context.Background(), &domain.Email{To: "admin@example.com", Subject: "Sign in"}))
+
+	for name, test := range map[string]struct {
+		token, header string
+		status        int
+	}{
+		"missing token":    {token: "metrics-secret", status: http.StatusForbidden},
+		"invalid token":    {token: "metrics-secret", header: "Bearer guess", status: http.StatusForbidden},
+		"token not set up": {header: "Bearer ", status: http.StatusNotImplemented},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/outbox?to=admin@example.com", nil)
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+			rec := httptest.NewRecorder()
+
+			NewOutboxHandler(provider, test.token).Outbox(rec, req)
+
+			assert.Equal(t, test.status, rec.Code)
+			assert.NotContains(t, rec.Body.String(), "Sign in")
+		})
+	}
+}
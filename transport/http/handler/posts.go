@@ -0,0 +1,1006 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	campaigndomain "newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/i18n"
+	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/internal/infrastructure/workerpool/jobs"
+	linkdomain "newsletter/internal/links/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	notifications "newsletter/internal/notifications/domain"
+	"newsletter/internal/posts/domain"
+	segmentdomain "newsletter/internal/segments/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	userdomain "newsletter/internal/users/domain"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// PostHandler handles HTTP requests related to the posts of a newsletter,
+// including their draft / published / archived lifecycle and sending.
+type PostHandler struct {
+	ps domain.PostService
+	ns newsletterdomain.NewsletterService
+	ss subscriptiondomain.SubscriptionService
+	es notifications.EmailService
+	wp workerpool.JobSubmiter
+
+	campaigns *campaignRunner
+	checker   domain.ContentChecker
+}
+
+// NewPostHandler creates a new PostHandler. links builds the unsubscribe
+// links of the campaigns it starts; throttle, which may be nil, caps the
+// campaigns sent at once by newsletter; segments resolves the segments
+// posts are sent to.
+func NewPostHandler(ps domain.PostService, ns newsletterdomain.NewsletterService, ss subscriptiondomain.SubscriptionService, es notifications.EmailService, wp workerpool.JobQueue, cs campaigndomain.CampaignService, links *LinkBuilder, throttle campaigndomain.Throttle, segments segmentdomain.SegmentService) *PostHandler {
+	return &PostHandler{
+		ps: ps, ns: ns, ss: ss, es: es, wp: wp,
+		campaigns: &campaignRunner{cs: cs, ps: ps, ns: ns, ss: ss, es: es, wp: wp, links: links, throttle: throttle, segments: segments},
+	}
+}
+
+// SetClickTracking makes the campaigns started by the handler replace the
+// links of their emails with short links counting clicks; nil disables it.
+func (ph *PostHandler) SetClickTracking(shortener linkdomain.LinkService) {
+	ph.campaigns.shortener = shortener
+}
+
+// SetContentChecker makes Send score the content of posts and refuse those
+// looking like spam unless forced; nil disables the check.
+func (ph *PostHandler) SetContentChecker(checker domain.ContentChecker) {
+	ph.checker = checker
+}
+
+// PostRequest represents the payload for creating or editing a post.
+type PostRequest struct {
+	Title string `json:"title"` // Title, used as email subject
+	Body  string `json:"body"`  // HTML content
+}
+
+// postID parses the post ID from the request path. It writes a 400 response
+// and returns false when the ID is not a valid UUID.
+func postID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["post_id"])
+	if err != nil {
+		http.Error(w, "invalid post ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writePost writes post as a JSON response with the given status code.
+func writePost(w http.ResponseWriter, status int, post *domain.Post) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(post); err != nil {
+		slog.Error("failed to encode post response", "post_id", post.ID, "error", err)
+	}
+}
+
+// Create handles writing a new draft post.
+//
+// Route:
+//
+//	POST /newsletters/{newsletter_id}/posts
+//
+// Description:
+//
+//	Creates a draft post in a newsletter owned by the authenticated user.
+//	Drafts are editable and are never sent to subscribers. The title is
+//	stored as plain text and the body keeps only safe HTML: scripts, event
+//	handlers and other unsafe markup are removed.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "title": "Issue #1",
+//	  "body": "<p>Hello</p>"
+//	}
+//
+// Responses:
+//
+//	201 Created
+//	  - The created post, with status "draft"
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//	  - Invalid JSON body or missing title
+//	  - Body longer than the configured limit
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 4 MiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	500 Internal Server Error
+//	  - Post creation failure
+func (ph *PostHandler) Create(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, ph.ns)
+	if !ok {
+		return
+	}
+
+	var request PostRequest
+	if !decodeJSON(w, r, &request, maxPostBodyBytes) {
+		return
+	}
+
+	post, err := ph.ps.Create(&domain.Post{NewsletterID: newsletter.ID, Title: request.Title, Body: request.Body})
+	if err != nil {
+		WriteError(w, r, err, "failed to create post")
+		return
+	}
+
+	writePost(w, http.StatusCreated, post)
+}
+
+// List handles listing the posts of a newsletter.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/posts
+//
+// Description:
+//
+//	Returns the posts of a newsletter owned by the authenticated user,
+//	newest first.
+//
+// Query Parameters:
+//
+//	status (string, optional) - "draft", "published" or "archived"
+//	fields (string, optional) - Comma-separated fields of the items to return, such as id,name
+//
+// Responses:
+//
+//	200 OK
+//	  - List of posts
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID or status
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	500 Internal Server Error
+//	  - Post retrieval failure
+func (ph *PostHandler) List(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, ph.ns)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", domain.StatusDraft, domain.StatusPublished, domain.StatusArchived:
+	default:
+		http.Error(w, "invalid status: "+status, http.StatusBadRequest)
+		return
+	}
+
+	posts, err := ph.ps.List(newsletter.ID, status)
+	if err != nil {
+		WriteError(w, r, err, "failed to list posts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(posts); err != nil {
+		slog.Error("failed to encode posts response", "newsletter_id", newsletter.ID, "error", err)
+	}
+}
+
+// Get handles retrieving a single post.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/posts/{post_id}
+//
+// Responses:
+//
+//	200 OK
+//	  - The post
+//
+//	400 Bad Request
+//	  - Invalid newsletter or post ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter or post does not exist
+func (ph *PostHandler) Get(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, ph.ns)
+	if !ok {
+		return
+	}
+	id, ok := postID(w, r)
+	if !ok {
+		return
+	}
+
+	post, err := ph.ps.Get(newsletter.ID, id)
+	if err != nil {
+		WriteError(w, r, err, "failed to get post")
+		return
+	}
+
+	writePost(w, http.StatusOK, post)
+}
+
+// Update handles editing a draft post.
+//
+// Route:
+//
+//	PUT /newsletters/{newsletter_id}/posts/{post_id}
+//
+// Description:
+//
+//	Replaces the title and body of a draft, sanitized as on creation.
+//	Published and archived posts are frozen and cannot be edited.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "title": "Issue #1",
+//	  "body": "<p>Hello again</p>"
+//	}
+//
+// Responses:
+//
+//	200 OK
+//	  - The updated post
+//
+//	400 Bad Request
+//	  - Invalid newsletter or post ID
+//	  - Invalid JSON body or missing title
+//	  - Body longer than the configured limit
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter or post does not exist
+//
+//	409 Conflict
+//	  - The post is not a draft
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 4 MiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+func (ph *PostHandler) Update(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, ph.ns)
+	if !ok {
+		return
+	}
+	id, ok := postID(w, r)
+	if !ok {
+		return
+	}
+
+	var request PostRequest
+	if !decodeJSON(w, r, &request, maxPostBodyBytes) {
+		return
+	}
+
+	post, err := ph.ps.Update(&domain.Post{ID: id, NewsletterID: newsletter.ID, Title: request.Title, Body: request.Body})
+	if err != nil {
+		WriteError(w, r, err, "failed to update post")
+		return
+	}
+
+	writePost(w, http.StatusOK, post)
+}
+
+// Publish handles publishing a draft post.
+//
+// Route:
+//
+//	POST /newsletters/{newsletter_id}/posts/{post_id}/publish
+//
+// Description:
+//
+//	Freezes the current version of a draft and makes it sendable and
+//	archivable.
+//
+// Responses:
+//
+//	200 OK
+//	  - The published post
+//
+//	400 Bad Request
+//	  - Invalid newsletter or post ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter or post does not exist
+//
+//	409 Conflict
+//	  - The post is not a draft
+func (ph *PostHandler) Publish(w http.ResponseWriter, r *http.Request) {
+	ph.transition(w, r, ph.ps.Publish)
+}
+
+// Archive handles archiving a published post.
+//
+// Route:
+//
+//	POST /newsletters/{newsletter_id}/posts/{post_id}/archive
+//
+// Description:
+//
+//	Retires a published post. Archived posts can no longer be sent.
+//
+// Responses:
+//
+//	200 OK
+//	  - The archived post
+//
+//	400 Bad Request
+//	  - Invalid newsletter or post ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter or post does not exist
+//
+//	409 Conflict
+//	  - The post is not published
+func (ph *PostHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	ph.transition(w, r, ph.ps.Archive)
+}
+
+// transition applies a lifecycle change to the post of the request.
+func (ph *PostHandler) transition(w http.ResponseWriter, r *http.Request, apply func(newsletterID, id uuid.UUID) (*domain.Post, error)) {
+	newsletter, ok := ownedNewsletter(w, r, ph.ns)
+	if !ok {
+		return
+	}
+	id, ok := postID(w, r)
+	if !ok {
+		return
+	}
+
+	post, err := apply(newsletter.ID, id)
+	if err != nil {
+		WriteError(w, r, err, "failed to change post status")
+		return
+	}
+
+	writePost(w, http.StatusOK, post)
+}
+
+// SendRequest represents the optional payload for sending a post.
+type SendRequest struct {
+	ABTest     *campaigndomain.ABTest     `json:"ab_test"`     // Subject lines to test before sending to everybody
+	SendWindow *campaigndomain.SendWindow `json:"send_window"` // Local times at which subscribers are emailed
+	SegmentID  *uuid.UUID                 `json:"segment_id"`  // Segment of the newsletter to send to, instead of every subscriber
+	// Reply-To, copies and custom headers overriding those of the newsletter
+	Envelope *notifications.Envelope `json:"envelope"`
+	Force    bool                    `json:"force"` // Send even when the content check fails
+}
+
+// SendResponse is the campaign started by sending a post, with the report
+// of the content check when it ran.
+type SendResponse struct {
+	*campaigndomain.Campaign
+	SpamCheck *domain.SpamReport `json:"spam_check,omitempty"`
+}
+
+// SpamErrorResponse reports why the content of a post was refused.
+type SpamErrorResponse struct {
+	Error     string             `json:"error"` // Localized message
+	SpamCheck *domain.SpamReport `json:"spam_check"`
+}
+
+// Send handles sending a published post to the subscribers of its newsletter.
+//
+// Route:
+//
+//	POST /newsletters/{newsletter_id}/posts/{post_id}/send
+//
+// Description:
+//
+//	Queues a campaign delivering a published post to every active
+//	subscriber. Drafts cannot be sent, and a post is sent at most once, so
+//	archived or already sent posts are rejected. The progress of the
+//	campaign is available at GET /campaigns/{campaign_id}.
+//
+//	Clients may send an Idempotency-Key header: a retry with the same key
+//	is answered with the original response instead of queuing another
+//	campaign.
+//
+//	With an A/B test, subject_a (the post title by default) and subject_b
+//	are each sent to sample_percent of the subscribers first. Opens are
+//	tracked for window_minutes, then the subject with the higher open rate
+//	is sent to the remaining subscribers.
+//
+//	With a send window, subscribers are only emailed between start and end
+//	in their own timezone, or in the timezone of the window (UTC by default)
+//	when they have none. The others are emailed in batches, each starting
+//	when the window opens for the next of them, so that a global audience
+//	receives the post during the day.
+//
+//	Unless SPAM_SCORE_THRESHOLD is 0, the email of the post is scored
+//	against heuristic rules before anything is sent: text missing or
+//	outweighed by images, phrases common in spam, a subject in capital
+//	letters, repeated exclamation marks and broken links. Each subject of
+//	an A/B test is scored and the worst report is kept. Posts scoring the
+//	threshold or more are rejected with the report, so that authors can fix
+//	them, unless force is set.
+//
+//	With a segment, only its active subscribers are emailed. The segment is
+//	resolved when the campaign is dispatched, so subscribers joining or
+//	leaving it until then are taken into account.
+//
+//	The envelope overrides the reply-to address, copies and custom headers
+//	set in the newsletter settings: reply_to, cc and bcc replace those of
+//	the newsletter when set, headers are merged by name. Every email gets
+//	a List-ID header, unless a custom one is set, and List-Unsubscribe
+//	headers for the one-click unsubscription of the recipient.
+//
+//	The title, the body and the A/B test subjects may contain merge tags,
+//	expanded for each subscriber: {{email}}, {{unsubscribe_url}},
+//	{{unsubscribe_all_url}}, {{newsletter_name}} and {{attributes.<key>}}
+//	for custom subscriber attributes. A tag may give a fallback for subscribers without a value,
+//	as in {{attributes.first_name | there}}. Posts using any other tag are
+//	rejected before anything is sent.
+//
+// Request Body (application/json, optional):
+//
+//	{
+//	  "ab_test": {
+//	    "subject_a": "Issue #1",
+//	    "subject_b": "You will not believe issue #1",
+//	    "sample_percent": 10,
+//	    "window_minutes": 240
+//	  },
+//	  "send_window": {
+//	    "start": "09:00",
+//	    "end": "17:00",
+//	    "timezone": "Europe/Berlin"
+//	  },
+//	  "segment_id": "uuid",
+//	  "envelope": {
+//	    "reply_to": "editor@example.com",
+//	    "cc": ["archive@example.com"],
+//	    "headers": {"X-Campaign": "spring-sale"}
+//	  },
+//	  "force": false
+//	}
+//
+// Responses:
+//
+//	202 Accepted
+//	  {
+//	    "id": "uuid",
+//	    "newsletter_id": "uuid",
+//	    "post_id": "uuid",
+//	    "status": "queued",
+//	    "sent": 0,
+//	    "failed": 0,
+//	    "pending": 0,
+//	    "created_at": "2026-01-10T12:00:00Z",
+//	    "updated_at": "2026-01-10T12:00:00Z",
+//	    "spam_check": {
+//	      "score": 1.5,
+//	      "threshold": 5,
+//	      "passed": true,
+//	      "rules": [
+//	        {"name": "SUBJECT_ALL_CAPS", "score": 1.5, "description": "the subject is mostly in capital letters"}
+//	      ]
+//	    }
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter or post ID
+//	  - Invalid JSON body
+//	  - Invalid A/B test: subject_b missing, subjects equal or longer than
+//	    200 characters, sample_percent outside 1-50 or window_minutes
+//	    outside 1-10080
+//	  - Invalid send window: start or end not in HH:MM form, equal, or
+//	    unknown timezone
+//	  - Invalid envelope: malformed address, more than 10 copies or 20
+//	    headers, or a header set by the service such as Subject
+//	  - Unknown merge tag in the post or the A/B test subjects
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	402 Payment Required
+//	  - Emailing every active subscriber would exceed the monthly emails of
+//	    the plan (see GET /users/me/limits)
+//
+//	404 Not Found
+//	  - Newsletter, post or segment does not exist
+//
+//	409 Conflict
+//	  - The post is not published or has already been sent
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	422 Unprocessable Entity
+//	  - The content scores the spam threshold or more and force is not set
+//	  {
+//	    "error": "content looks like spam",
+//	    "spam_check": {"score": 6.5, "threshold": 5, "passed": false, "rules": [...]}
+//	  }
+//
+//	500 Internal Server Error
+//	  - Campaign creation failure
+//
+// Side Effects:
+//   - Marks the post as sent
+//   - Sends one email per active subscriber, or per subscriber of the
+//     segment, in the background
+//   - With an A/B test, tracks the opens of the sample with a pixel
+func (ph *PostHandler) Send(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, ph.ns)
+	if !ok {
+		return
+	}
+	id, ok := postID(w, r)
+	if !ok {
+		return
+	}
+
+	var request SendRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &request, maxBodyBytes) {
+		return
+	}
+
+	// The options are checked before the post is marked as sent, which
+	// cannot be undone.
+	options := campaigndomain.SendOptions{
+		ABTest:     request.ABTest,
+		SendWindow: request.SendWindow,
+		SegmentID:  request.SegmentID,
+		Envelope:   request.Envelope,
+	}
+	if test := options.ABTest; test != nil && test.SubjectA == "" {
+		post, err := ph.ps.Get(newsletter.ID, id)
+		if err != nil {
+			WriteError(w, r, err, "failed to get post")
+			return
+		}
+		test.SubjectA = post.Title
+	}
+	if err := options.Validate(); err != nil {
+		WriteError(w, r, err, "invalid send options")
+		return
+	}
+	if test := options.ABTest; test != nil {
+		if err := domain.ValidateMergeTags(test.SubjectA, test.SubjectB); err != nil {
+			WriteError(w, r, err, "invalid A/B test subjects")
+			return
+		}
+	}
+	if options.SegmentID != nil {
+		if _, err := ph.campaigns.segments.Get(newsletter.ID, *options.SegmentID); err != nil {
+			WriteError(w, r, err, "failed to get segment")
+			return
+		}
+	}
+	var report *domain.SpamReport
+	if ph.checker != nil {
+		post, err := ph.ps.Get(newsletter.ID, id)
+		if err != nil {
+			WriteError(w, r, err, "failed to get post")
+			return
+		}
+		report = ph.checkContent(post, newsletter, options.ABTest)
+		if !report.Passed {
+			if !request.Force {
+				writeSpamError(w, r, report)
+				return
+			}
+			slog.Warn("sending post failing the content check", "post_id", post.ID, "score", report.Score)
+		}
+	}
+	if err := ph.campaigns.cs.CheckLimits(newsletter.ID); err != nil {
+		WriteError(w, r, err, "failed to check plan limits")
+		return
+	}
+
+	post, err := ph.ps.MarkSent(newsletter.ID, id)
+	if err != nil {
+		WriteError(w, r, err, "failed to send post")
+		return
+	}
+
+	campaign, err := ph.campaigns.cs.Create(newsletter.ID, post.ID, options)
+	if err != nil {
+		slog.Error("post marked as sent without campaign", "post_id", post.ID, "error", err)
+		WriteError(w, r, err, "failed to create campaign")
+		return
+	}
+	ph.campaigns.enqueue(campaign)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(SendResponse{Campaign: campaign, SpamCheck: report}); err != nil {
+		slog.Error("failed to encode campaign response", "campaign_id", campaign.ID, "error", err)
+	}
+}
+
+// checkContent scores the email of post with each subject it is sent with,
+// its title or the subjects of the A/B test, and returns the worst report.
+// Merge tags are expanded for a sample subscriber. The HTML part checked is
+// the body of the post, without the layout and footer of the newsletter.
+func (ph *PostHandler) checkContent(post *domain.Post, newsletter *newsletterdomain.Newsletter, test *campaigndomain.ABTest) *domain.SpamReport {
+	subjects := []string{post.Title}
+	if test != nil {
+		subjects = []string{test.SubjectA, test.SubjectB}
+	}
+
+	fields := domain.MergeFields{
+		Email:             "subscriber@example.com",
+		UnsubscribeURL:    ph.campaigns.links.Unsubscribe("sample"),
+		UnsubscribeAllURL: ph.campaigns.links.UnsubscribeAll("sample"),
+		NewsletterName:    newsletter.Name,
+	}
+	email := renderPost(post, newsletter, fields, i18n.New(newsletter.Language))
+	body := fields.ExpandHTML(post.Body)
+
+	var worst *domain.SpamReport
+	for _, subject := range subjects {
+		report := ph.checker.Check(fields.Expand(subject), body, email.Text)
+		if worst == nil || report.Score > worst.Score {
+			worst = report
+		}
+	}
+	return worst
+}
+
+// writeSpamError writes report as a 422 Unprocessable Entity JSON response.
+func writeSpamError(w http.ResponseWriter, r *http.Request, report *domain.SpamReport) {
+	message, lang := localize(r, domain.ErrSpammyContent, domain.ErrSpammyContent)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	if err := json.NewEncoder(w).Encode(SpamErrorResponse{Error: message, SpamCheck: report}); err != nil {
+		slog.Error("failed to encode spam error response", "error", err)
+	}
+}
+
+// maxTestRecipients is the number of addresses a test email can be sent to.
+const maxTestRecipients = 5
+
+// TestRequest represents the payload for sending a test email of a post.
+type TestRequest struct {
+	Recipients []string `json:"recipients"` // Addresses to send to; defaults to the owner's address
+}
+
+// TestResponse lists the addresses a test email was sent to.
+type TestResponse struct {
+	Recipients []string `json:"recipients"`
+	Failed     []string `json:"failed,omitempty"` // Addresses not emailed because the job queue filled up
+}
+
+// Test handles sending a test email of a post.
+//
+// Route:
+//
+//	POST /newsletters/{newsletter_id}/posts/{post_id}/test
+//
+// Description:
+//
+//	Sends the post, rendered as subscribers would receive it, to the
+//	authenticated owner or to up to 5 supplied addresses, so that authors
+//	can check the rendering before sending. Posts of any status can be
+//	tested; subscribers are never emailed and the post is not marked as sent.
+//	Merge tags are expanded with the address of each recipient, and custom
+//	attributes with their fallbacks. Test emails get the reply-to address
+//	and custom headers of the newsletter, but are not copied to its cc and
+//	bcc addresses. When the job queue fills up after some of the emails
+//	were queued, the others are not sent and are listed as failed.
+//
+// Request Body (application/json, optional):
+//
+//	{
+//	  "recipients": ["editor@example.com"]
+//	}
+//
+// Responses:
+//
+//	202 Accepted
+//	  {
+//	    "recipients": ["editor@example.com"],
+//	    "failed": ["reviewer@example.com"]
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter or post ID
+//	  - Invalid JSON body
+//	  - Invalid address or more than 5 recipients
+//	  - Unknown merge tag in the post
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter or post does not exist
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	503 Service Unavailable
+//	  - The job queue is full, and no email was queued
+//
+// Side Effects:
+//   - Sends one email per recipient in the background, with the subject
+//     prefixed by "[Test]"
+func (ph *PostHandler) Test(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, ph.ns)
+	if !ok {
+		return
+	}
+	id, ok := postID(w, r)
+	if !ok {
+		return
+	}
+
+	var request TestRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &request, maxBodyBytes) {
+		return
+	}
+
+	recipients, err := testRecipients(r, request.Recipients)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	post, err := ph.ps.Get(newsletter.ID, id)
+	if err != nil {
+		WriteError(w, r, err, "failed to get post")
+		return
+	}
+
+	if err := post.ValidateMergeTags(); err != nil {
+		WriteError(w, r, err, "invalid merge tags")
+		return
+	}
+
+	localizer := i18n.New(i18n.Match(r.Header.Get("Accept-Language"), newsletter.Language))
+	response := TestResponse{Recipients: make([]string, 0, len(recipients))}
+	for i, recipient := range recipients {
+		email := renderPost(post, newsletter, domain.MergeFields{Email: recipient, UnsubscribeURL: "#", UnsubscribeAllURL: "#", NewsletterName: newsletter.Name}, localizer)
+		email.Subject = localizer.T("TestSubject", map[string]any{"Title": email.Subject})
+		email.Envelope = notifications.Envelope{ReplyTo: newsletter.ReplyTo, Headers: newsletter.Headers}
+		if err := ph.wp.TrySubmit(&jobs.SendEmailJob{Email: email, Service: ph.es, Transactional: true}); err != nil {
+			if i == 0 {
+				WriteError(w, r, err, "failed to queue test email")
+				return
+			}
+			// The queue stays full for a while: the emails already queued
+			// are sent, the others reported.
+			response.Failed = recipients[i:]
+			slog.Warn("failed to queue test email", "post_id", post.ID, "failed", len(response.Failed), "error", err)
+			break
+		}
+		response.Recipients = append(response.Recipients, recipient)
+	}
+	slog.Info("sending test email", "post_id", post.ID, "recipients", len(response.Recipients))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode test response", "post_id", post.ID, "error", err)
+	}
+}
+
+// testRecipients validates the requested test recipients, defaulting to the
+// address of the authenticated user when none are given.
+func testRecipients(r *http.Request, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		owner, _ := r.Context().Value(userdomain.UserEmail).(string)
+		if owner == "" {
+			return nil, errors.New("no recipients given and the account has no email address")
+		}
+		return []string{owner}, nil
+	}
+
+	if len(requested) > maxTestRecipients {
+		return nil, fmt.Errorf("at most %d recipients are allowed", maxTestRecipients)
+	}
+
+	recipients := make([]string, 0, len(requested))
+	for _, recipient := range requested {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q", recipient)
+		}
+		recipients = append(recipients, address.Address)
+	}
+	return recipients, nil
+}
+
+// PreviewRequest represents the payload for rendering unsaved post content.
+type PreviewRequest struct {
+	NewsletterID uuid.UUID         `json:"newsletter_id"` // Newsletter the post would be sent by
+	Title        string            `json:"title"`         // Title, used as email subject
+	Body         string            `json:"body"`          // HTML content
+	Subscriber   PreviewSubscriber `json:"subscriber"`    // Sample recipient
+}
+
+// PreviewSubscriber is the sample subscriber a preview is rendered for.
+type PreviewSubscriber struct {
+	Email      string            `json:"email"`      // Defaults to the owner's address
+	Language   string            `json:"language"`   // Defaults to the language of the newsletter
+	Attributes map[string]string `json:"attributes"` // Custom attributes of merge tags
+}
+
+// PreviewResponse is an email rendered by Preview.
+type PreviewResponse struct {
+	From    string `json:"from,omitempty"` // Empty when the provider's default sender is used
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// Preview handles rendering post content as an email, for live previews.
+//
+// Route:
+//
+//	POST /render/preview
+//
+// Description:
+//
+//	Returns the HTML and text versions of the email subscribers would receive
+//	for a post with the given title and body, without storing it. The content
+//	is sanitized as on creation and rendered as by campaigns for the sample
+//	subscriber: merge tags are expanded with its address and attributes,
+//	custom attributes without a value with their fallbacks, and the
+//	unsubscribe link and the footer of the newsletter are added in the
+//	language of the subscriber. Unsubscribe links point to "#". The body is
+//	HTML, as posts store it.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "newsletter_id": "uuid",
+//	  "title": "Issue #1 for {{ attributes.first_name | you }}",
+//	  "body": "<p>Hello {{ attributes.first_name | there }}</p>",
+//	  "subscriber": {
+//	    "email": "ada@example.com",
+//	    "language": "de",
+//	    "attributes": {"first_name": "Ada"}
+//	  }
+//	}
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "from": "Weekly News <news@example.com>",
+//	    "to": "ada@example.com",
+//	    "subject": "Issue #1 for Ada",
+//	    "html": "<p>Hello Ada</p><p>...</p>",
+//	    "text": "Issue #1 for Ada\n\n..."
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid JSON body or missing title
+//	  - Body longer than the configured limit
+//	  - Invalid subscriber address
+//	  - Unknown merge tag in the content
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 4 MiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+func (ph *PostHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	var request PreviewRequest
+	if !decodeJSON(w, r, &request, maxPostBodyBytes) {
+		return
+	}
+
+	newsletter, ok := newsletterOwnedBy(w, r, ph.ns, request.NewsletterID, ownerID)
+	if !ok {
+		return
+	}
+
+	address, _ := r.Context().Value(userdomain.UserEmail).(string)
+	if request.Subscriber.Email != "" {
+		parsed, err := mail.ParseAddress(request.Subscriber.Email)
+		if err != nil {
+			http.Error(w, "invalid subscriber address", http.StatusBadRequest)
+			return
+		}
+		address = parsed.Address
+	}
+
+	post := &domain.Post{NewsletterID: newsletter.ID, Title: request.Title, Body: request.Body}
+	if err := ph.ps.Prepare(post); err != nil {
+		WriteError(w, r, err, "invalid post")
+		return
+	}
+	if err := post.ValidateMergeTags(); err != nil {
+		WriteError(w, r, err, "invalid merge tags")
+		return
+	}
+
+	fields := domain.MergeFields{
+		Email:             address,
+		UnsubscribeURL:    "#",
+		UnsubscribeAllURL: "#",
+		NewsletterName:    newsletter.Name,
+		Attributes:        request.Subscriber.Attributes,
+	}
+	email := renderPost(post, newsletter, fields, i18n.New(i18n.Match(request.Subscriber.Language, newsletter.Language)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(PreviewResponse{From: email.From, To: email.To, Subject: email.Subject, HTML: email.HTML, Text: email.Text}); err != nil {
+		slog.Error("failed to encode preview response", "newsletter_id", newsletter.ID, "error", err)
+	}
+}
+
+// renderPost builds the email of a post of newsletter for one recipient,
+// with its merge tags expanded from fields, a link to unsubscribe from the
+// newsletter in the language of localizer, followed by a link to unsubscribe
+// from every newsletter when fields has one, and the custom footer of the
+// newsletter.
+func renderPost(post *domain.Post, newsletter *newsletterdomain.Newsletter, fields domain.MergeFields, localizer *i18n.Localizer) notifications.Email {
+	subject := fields.Expand(post.Title)
+	email := notifications.Email{
+		From:    newsletter.Sender(),
+		To:      fields.Email,
+		Subject: subject,
+		Text:    subject + "\n\n" + localizer.T("PostUnsubscribeText", map[string]any{"Link": fields.UnsubscribeURL}),
+		HTML:    fields.ExpandHTML(post.Body) + "<p>" + localizer.T("PostUnsubscribeHTML", map[string]any{"Link": html.EscapeString(fields.UnsubscribeURL)}) + "</p>",
+	}
+	if fields.UnsubscribeAllURL != "" {
+		email.Text += "\n\n" + localizer.T("PostUnsubscribeAllText", map[string]any{"Link": fields.UnsubscribeAllURL})
+		email.HTML += "\n<p>" + localizer.T("PostUnsubscribeAllHTML", map[string]any{"Link": html.EscapeString(fields.UnsubscribeAllURL)}) + "</p>"
+	}
+	appendFooter(&email, newsletter)
+	return email
+}
@@ -0,0 +1,470 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	campaigndomain "newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/i18n"
+	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/internal/infrastructure/workerpool/jobs"
+	limitsdomain "newsletter/internal/limits/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"newsletter/internal/posts/domain"
+	segmentdomain "newsletter/internal/segments/domain"
+	userdomain "newsletter/internal/users/domain"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Post Service ---
+type MockPostService struct {
+	mock.Mock
+}
+
+func (m *MockPostService) post(args mock.Arguments) (*domain.Post, error) {
+	p := args.Get(0)
+	if p == nil {
+		return nil, args.Error(1)
+	}
+	return p.(*domain.Post), args.Error(1)
+}
+
+func (m *MockPostService) Create(post *domain.Post) (*domain.Post, error) {
+	return m.post(m.Called(post))
+}
+
+func (m *MockPostService) Get(newsletterID, id uuid.UUID) (*domain.Post, error) {
+	return m.post(m.Called(newsletterID, id))
+}
+
+func (m *MockPostService) List(newsletterID uuid.UUID, status string) ([]*domain.Post, error) {
+	args := m.Called(newsletterID, status)
+	return args.Get(0).([]*domain.Post), args.Error(1)
+}
+
+func (m *MockPostService) Update(post *domain.Post) (*domain.Post, error) {
+	return m.post(m.Called(post))
+}
+
+func (m *MockPostService) Prepare(post *domain.Post) error {
+	return m.Called(post).Error(0)
+}
+
+func (m *MockPostService) Publish(newsletterID, id uuid.UUID) (*domain.Post, error) {
+	return m.post(m.Called(newsletterID, id))
+}
+
+func (m *MockPostService) Archive(newsletterID, id uuid.UUID) (*domain.Post, error) {
+	return m.post(m.Called(newsletterID, id))
+}
+
+func (m *MockPostService) MarkSent(newsletterID, id uuid.UUID) (*domain.Post, error) {
+	return m.post(m.Called(newsletterID, id))
+}
+
+// postRequest builds a request on a post of newsletter, authenticated as its owner.
+func postRequest(method string, newsletter *newsletterdomain.Newsletter, postID uuid.UUID, body any) *http.Request {
+	var payload bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&payload).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, "/newsletters/"+newsletter.ID.String()+"/posts/"+postID.String(), &payload)
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletter.ID.String(), "post_id": postID.String()})
+	return req.WithContext(contextWithUserID(req.Context(), newsletter.OwnerID.String()))
+}
+
+func TestCreatePost_Success(t *testing.T) {
+	mockNS, mockPS := new(MockNewsletterService), new(MockPostService)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, nil, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	created := &domain.Post{ID: uuid.New(), NewsletterID: newsletter.ID, Title: "Issue #1", Status: domain.StatusDraft}
+
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockPS.On("Create", &domain.Post{NewsletterID: newsletter.ID, Title: "Issue #1"}).Return(created, nil)
+
+	rec := httptest.NewRecorder()
+	h.Create(rec, postRequest(http.MethodPost, newsletter, uuid.Nil, PostRequest{Title: "Issue #1"}))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	mockPS.AssertExpectations(t)
+}
+
+func TestUpdatePost_NotDraft(t *testing.T) {
+	mockNS, mockPS := new(MockNewsletterService), new(MockPostService)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, nil, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockPS.On("Update", mock.Anything).Return(nil, domain.ErrPostNotEditable)
+
+	rec := httptest.NewRecorder()
+	h.Update(rec, postRequest(http.MethodPut, newsletter, uuid.New(), PostRequest{Title: "Edited"}))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestSendPost_Draft(t *testing.T) {
+	mockNS, mockPS, mockWP, mockCS := new(MockNewsletterService), new(MockPostService), new(MockWorkerPool), new(MockCampaignService)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, mockWP, mockCS, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	postID := uuid.New()
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("CheckLimits", newsletter.ID).Return(nil)
+	mockPS.On("MarkSent", newsletter.ID, postID).Return(nil, domain.ErrPostNotSendable)
+
+	rec := httptest.NewRecorder()
+	h.Send(rec, postRequest(http.MethodPost, newsletter, postID, nil))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	mockWP.AssertNotCalled(t, "Submit", mock.Anything)
+}
+
+func TestSendPost_Published(t *testing.T) {
+	mockNS, mockPS, mockWP, mockCS := new(MockNewsletterService), new(MockPostService), new(MockWorkerPool), new(MockCampaignService)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, mockWP, mockCS, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	post := &domain.Post{ID: uuid.New(), NewsletterID: newsletter.ID, Status: domain.StatusPublished}
+	campaign := &campaigndomain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID, PostID: post.ID, Status: campaigndomain.StatusQueued}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("CheckLimits", newsletter.ID).Return(nil)
+	mockPS.On("MarkSent", newsletter.ID, post.ID).Return(post, nil)
+	mockCS.On("Create", newsletter.ID, post.ID, campaigndomain.SendOptions{}).Return(campaign, nil)
+	mockWP.On("Submit", mock.AnythingOfType("*handler.campaignJob")).Return()
+
+	rec := httptest.NewRecorder()
+	h.Send(rec, postRequest(http.MethodPost, newsletter, post.ID, nil))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var got campaigndomain.Campaign
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, campaign.ID, got.ID)
+	mockWP.AssertExpectations(t)
+}
+
+// --- Mock Content Checker ---
+type MockContentChecker struct {
+	mock.Mock
+}
+
+func (m *MockContentChecker) Check(subject, html, text string) *domain.SpamReport {
+	args := m.Called(subject, html, text)
+	return args.Get(0).(*domain.SpamReport)
+}
+
+func TestSendPost_SpammyContent(t *testing.T) {
+	mockNS, mockPS, mockCS, mockChecker := new(MockNewsletterService), new(MockPostService), new(MockCampaignService), new(MockContentChecker)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, nil, mockCS, testLinks, nil, nil)
+	h.SetContentChecker(mockChecker)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	post := &domain.Post{ID: uuid.New(), NewsletterID: newsletter.ID, Title: "WIN NOW", Body: "<p>Act now, {{email}}!</p>", Status: domain.StatusPublished}
+	report := &domain.SpamReport{Score: 6, Threshold: 5, Rules: []domain.SpamRule{{Name: "SPAMMY_WORDS", Score: 6}}}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockPS.On("Get", newsletter.ID, post.ID).Return(post, nil)
+	mockChecker.On("Check", "WIN NOW", "<p>Act now, subscriber@example.com!</p>", mock.Anything).Return(report)
+
+	rec := httptest.NewRecorder()
+	h.Send(rec, postRequest(http.MethodPost, newsletter, post.ID, nil))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	var got SpamErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, domain.ErrSpammyContent.Error(), got.Error)
+	assert.Equal(t, report, got.SpamCheck)
+	mockPS.AssertNotCalled(t, "MarkSent", mock.Anything, mock.Anything)
+}
+
+func TestSendPost_ForceSpammyContent(t *testing.T) {
+	mockNS, mockPS, mockWP, mockCS, mockChecker := new(MockNewsletterService), new(MockPostService), new(MockWorkerPool), new(MockCampaignService), new(MockContentChecker)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, mockWP, mockCS, testLinks, nil, nil)
+	h.SetContentChecker(mockChecker)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	post := &domain.Post{ID: uuid.New(), NewsletterID: newsletter.ID, Title: "Issue #1", Body: "<p>Hello</p>", Status: domain.StatusPublished}
+	campaign := &campaigndomain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID, PostID: post.ID, Status: campaigndomain.StatusQueued}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockPS.On("Get", newsletter.ID, post.ID).Return(post, nil)
+	mockChecker.On("Check", "Issue #1", mock.Anything, mock.Anything).Return(&domain.SpamReport{Score: 5, Threshold: 5, Rules: []domain.SpamRule{}})
+	mockChecker.On("Check", "Do not miss issue #1", mock.Anything, mock.Anything).Return(&domain.SpamReport{Score: 6, Threshold: 5, Rules: []domain.SpamRule{}})
+	mockCS.On("CheckLimits", newsletter.ID).Return(nil)
+	mockPS.On("MarkSent", newsletter.ID, post.ID).Return(post, nil)
+	mockCS.On("Create", newsletter.ID, post.ID, mock.Anything).Return(campaign, nil)
+	mockWP.On("Submit", mock.AnythingOfType("*handler.campaignJob")).Return()
+
+	rec := httptest.NewRecorder()
+	h.Send(rec, postRequest(http.MethodPost, newsletter, post.ID, SendRequest{Force: true, ABTest: &campaigndomain.ABTest{
+		SubjectB: "Do not miss issue #1", SamplePercent: 10, WindowMinutes: 60,
+	}}))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var got SendResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, campaign.ID, got.ID)
+	require.NotNil(t, got.SpamCheck)
+	assert.Equal(t, 6.0, got.SpamCheck.Score, "worst subject")
+	mockChecker.AssertExpectations(t)
+}
+
+func TestSendPost_ABTestDefaultsToTitle(t *testing.T) {
+	mockNS, mockPS, mockWP, mockCS := new(MockNewsletterService), new(MockPostService), new(MockWorkerPool), new(MockCampaignService)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, mockWP, mockCS, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	post := &domain.Post{ID: uuid.New(), NewsletterID: newsletter.ID, Title: "Issue #1", Status: domain.StatusPublished}
+	campaign := &campaigndomain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID, PostID: post.ID, Status: campaigndomain.StatusQueued}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockPS.On("Get", newsletter.ID, post.ID).Return(post, nil)
+	mockCS.On("CheckLimits", newsletter.ID).Return(nil)
+	mockPS.On("MarkSent", newsletter.ID, post.ID).Return(post, nil)
+	mockCS.On("Create", newsletter.ID, post.ID, campaigndomain.SendOptions{ABTest: &campaigndomain.ABTest{
+		SubjectA: "Issue #1", SubjectB: "Do not miss issue #1", SamplePercent: 10, WindowMinutes: 60,
+	}}).Return(campaign, nil)
+	mockWP.On("Submit", mock.AnythingOfType("*handler.campaignJob")).Return()
+
+	rec := httptest.NewRecorder()
+	h.Send(rec, postRequest(http.MethodPost, newsletter, post.ID, SendRequest{ABTest: &campaigndomain.ABTest{
+		SubjectB: "Do not miss issue #1", SamplePercent: 10, WindowMinutes: 60,
+	}}))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	mockCS.AssertExpectations(t)
+}
+
+func TestSendPost_UnknownSegment(t *testing.T) {
+	mockNS, mockPS, mockCS, mockSeg := new(MockNewsletterService), new(MockPostService), new(MockCampaignService), new(MockSegmentService)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, nil, mockCS, testLinks, nil, mockSeg)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	postID, segmentID := uuid.New(), uuid.New()
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockSeg.On("Get", newsletter.ID, segmentID).Return(nil, segmentdomain.ErrSegmentNotFound)
+
+	rec := httptest.NewRecorder()
+	h.Send(rec, postRequest(http.MethodPost, newsletter, postID, SendRequest{SegmentID: &segmentID}))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockPS.AssertNotCalled(t, "MarkSent", mock.Anything, mock.Anything)
+}
+
+func TestSendPost_EmailLimit(t *testing.T) {
+	mockNS, mockPS, mockCS := new(MockNewsletterService), new(MockPostService), new(MockCampaignService)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, nil, mockCS, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("CheckLimits", newsletter.ID).Return(limitsdomain.ErrEmailLimit)
+
+	rec := httptest.NewRecorder()
+	h.Send(rec, postRequest(http.MethodPost, newsletter, uuid.New(), nil))
+
+	assert.Equal(t, http.StatusPaymentRequired, rec.Code)
+	mockPS.AssertNotCalled(t, "MarkSent", mock.Anything, mock.Anything)
+}
+
+func TestSendPost_InvalidABTest(t *testing.T) {
+	mockNS, mockPS, mockCS := new(MockNewsletterService), new(MockPostService), new(MockCampaignService)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, nil, mockCS, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+
+	rec := httptest.NewRecorder()
+	h.Send(rec, postRequest(http.MethodPost, newsletter, uuid.New(), SendRequest{ABTest: &campaigndomain.ABTest{
+		SubjectA: "Issue #1", SubjectB: "Issue #1 again", SamplePercent: 60, WindowMinutes: 60,
+	}}))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockPS.AssertNotCalled(t, "MarkSent", mock.Anything, mock.Anything)
+}
+
+func TestSendPost_InvalidSendWindow(t *testing.T) {
+	mockNS, mockPS, mockCS := new(MockNewsletterService), new(MockPostService), new(MockCampaignService)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, nil, mockCS, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+
+	rec := httptest.NewRecorder()
+	h.Send(rec, postRequest(http.MethodPost, newsletter, uuid.New(), SendRequest{SendWindow: &campaigndomain.SendWindow{
+		Start: "09:00", End: "17:00", Timezone: "Europe/Atlantis",
+	}}))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockPS.AssertNotCalled(t, "MarkSent", mock.Anything, mock.Anything)
+}
+
+func TestTestPost_DefaultsToOwner(t *testing.T) {
+	mockNS, mockPS, mockWP := new(MockNewsletterService), new(MockPostService), new(MockWorkerPool)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, mockWP, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	post := &domain.Post{ID: uuid.New(), NewsletterID: newsletter.ID, Title: "Issue #1", Status: domain.StatusDraft}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockPS.On("Get", newsletter.ID, post.ID).Return(post, nil)
+	mockWP.On("TrySubmit", mock.MatchedBy(func(job *jobs.SendEmailJob) bool {
+		return job.Email.To == "owner@example.com" && job.Email.Subject == "[Test] Issue #1"
+	})).Return(nil).Once()
+
+	req := postRequest(http.MethodPost, newsletter, post.ID, nil)
+	req = req.WithContext(context.WithValue(req.Context(), userdomain.UserEmail, "owner@example.com"))
+	rec := httptest.NewRecorder()
+	h.Test(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	mockWP.AssertExpectations(t)
+	mockPS.AssertNotCalled(t, "MarkSent", mock.Anything, mock.Anything)
+}
+
+func TestTestPost_QueueFills(t *testing.T) {
+	mockNS, mockPS, mockWP := new(MockNewsletterService), new(MockPostService), new(MockWorkerPool)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, mockWP, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	post := &domain.Post{ID: uuid.New(), NewsletterID: newsletter.ID, Title: "Issue #1", Status: domain.StatusDraft}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockPS.On("Get", newsletter.ID, post.ID).Return(post, nil)
+	mockWP.On("TrySubmit", mock.Anything).Return(nil).Once()
+	mockWP.On("TrySubmit", mock.Anything).Return(workerpool.ErrQueueFull).Once()
+
+	rec := httptest.NewRecorder()
+	h.Test(rec, postRequest(http.MethodPost, newsletter, post.ID, TestRequest{Recipients: []string{"a@example.com", "b@example.com", "c@example.com"}}))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var response TestResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, []string{"a@example.com"}, response.Recipients)
+	assert.Equal(t, []string{"b@example.com", "c@example.com"}, response.Failed)
+	mockWP.AssertNumberOfCalls(t, "TrySubmit", 2)
+
+	// Nothing queued
+	mockWP.On("TrySubmit", mock.Anything).Return(workerpool.ErrQueueFull).Once()
+	rec = httptest.NewRecorder()
+	h.Test(rec, postRequest(http.MethodPost, newsletter, post.ID, TestRequest{Recipients: []string{"a@example.com"}}))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestTestPost_TooManyRecipients(t *testing.T) {
+	mockNS, mockPS, mockWP := new(MockNewsletterService), new(MockPostService), new(MockWorkerPool)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, mockWP, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+
+	recipients := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com", "f@example.com"}
+	rec := httptest.NewRecorder()
+	h.Test(rec, postRequest(http.MethodPost, newsletter, uuid.New(), TestRequest{Recipients: recipients}))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockWP.AssertNotCalled(t, "TrySubmit", mock.Anything)
+}
+
+func TestRenderPost_MergeTags(t *testing.T) {
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), Name: "Weekly <News>"}
+	post := &domain.Post{
+		Title: "{{newsletter_name}} for {{ attributes.first_name | you }}",
+		Body:  `<p>Hi {{attributes.first_name|there}}, this is {{email}}.</p><a href="{{unsubscribe_url}}">Leave</a>`,
+	}
+	fields := domain.MergeFields{
+		Email:          "ada@example.com",
+		UnsubscribeURL: "https://example.com/unsubscribe?token=a&b",
+		NewsletterName: newsletter.Name,
+		Attributes:     map[string]string{"first_name": "Ada & Co"},
+	}
+
+	email := renderPost(post, newsletter, fields, i18n.New("en"))
+
+	assert.Equal(t, "ada@example.com", email.To)
+	assert.Equal(t, "Weekly <News> for Ada & Co", email.Subject)
+	assert.Contains(t, email.HTML, `<p>Hi Ada &amp; Co, this is ada@example.com.</p><a href="https://example.com/unsubscribe?token=a&amp;b">Leave</a>`)
+
+	email = renderPost(post, newsletter, domain.MergeFields{Email: "bob@example.com"}, i18n.New("en"))
+	assert.Equal(t, " for you", email.Subject)
+	assert.Contains(t, email.HTML, "<p>Hi there, this is bob@example.com.</p>")
+}
+
+func TestTestPost_UnknownMergeTag(t *testing.T) {
+	mockNS, mockPS, mockWP := new(MockNewsletterService), new(MockPostService), new(MockWorkerPool)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, mockWP, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	post := &domain.Post{ID: uuid.New(), NewsletterID: newsletter.ID, Title: "Issue #1", Body: "<p>Hi {{ firstname }}</p>"}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockPS.On("Get", newsletter.ID, post.ID).Return(post, nil)
+
+	rec := httptest.NewRecorder()
+	h.Test(rec, postRequest(http.MethodPost, newsletter, post.ID, TestRequest{Recipients: []string{"editor@example.com"}}))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "{{firstname}}")
+	mockWP.AssertNotCalled(t, "TrySubmit", mock.Anything)
+}
+
+func TestPreviewPost(t *testing.T) {
+	mockNS, mockPS := new(MockNewsletterService), new(MockPostService)
+	h := NewPostHandler(mockPS, mockNS, nil, nil, nil, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New(), Name: "Weekly"}
+	newsletter.Language = "en"
+	newsletter.FooterText = "Weekly News Ltd"
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockPS.On("Prepare", mock.AnythingOfType("*domain.Post")).Return(nil)
+
+	preview := func(request PreviewRequest) *httptest.ResponseRecorder {
+		var payload bytes.Buffer
+		_ = json.NewEncoder(&payload).Encode(request)
+		req := httptest.NewRequest(http.MethodPost, "/render/preview", &payload)
+		req = req.WithContext(context.WithValue(contextWithUserID(req.Context(), newsletter.OwnerID.String()), userdomain.UserEmail, "owner@example.com"))
+		rec := httptest.NewRecorder()
+		h.Preview(rec, req)
+		return rec
+	}
+
+	t.Run("sample subscriber", func(t *testing.T) {
+		rec := preview(PreviewRequest{
+			NewsletterID: newsletter.ID,
+			Title:        "Issue #1 for {{ attributes.first_name | you }}",
+			Body:         "<p>Hello {{ attributes.first_name | there }}</p>",
+			Subscriber:   PreviewSubscriber{Email: "Ada <ada@example.com>", Language: "de", Attributes: map[string]string{"first_name": "Ada"}},
+		})
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response PreviewResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+		assert.Equal(t, "ada@example.com", response.To)
+		assert.Equal(t, "Issue #1 for Ada", response.Subject)
+		assert.True(t, strings.HasPrefix(response.HTML, "<p>Hello Ada</p>"))
+		assert.Contains(t, response.HTML, "Weekly News Ltd")
+		assert.Equal(t, renderPost(&domain.Post{Title: "Issue #1 for Ada"}, newsletter, domain.MergeFields{UnsubscribeURL: "#", UnsubscribeAllURL: "#"}, i18n.New("de")).Text, response.Text)
+	})
+
+	t.Run("defaults to the owner", func(t *testing.T) {
+		rec := preview(PreviewRequest{NewsletterID: newsletter.ID, Title: "Issue #1", Body: "<p>Hi {{ email }}</p>"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Hi owner@example.com")
+	})
+
+	t.Run("unknown merge tag", func(t *testing.T) {
+		rec := preview(PreviewRequest{NewsletterID: newsletter.ID, Title: "Issue #1", Body: "<p>Hi {{ firstname }}</p>"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("other owner", func(t *testing.T) {
+		other := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+		mockNS.On("Get", other.ID).Return(other, nil)
+		rec := preview(PreviewRequest{NewsletterID: other.ID, Title: "Issue #1"})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/infrastructure/i18n"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	postdomain "newsletter/internal/posts/domain"
+	"sort"
+
+	"github.com/gorilla/mux"
+)
+
+// PublicHandler serves the public pages of newsletters, addressed by their
+// slug, to readers without an account.
+type PublicHandler struct {
+	ns newsletterdomain.NewsletterService
+	ps postdomain.PostService
+
+	links *LinkBuilder // Builds the absolute links of feeds
+}
+
+// NewPublicHandler creates a new PublicHandler.
+func NewPublicHandler(ns newsletterdomain.NewsletterService, ps postdomain.PostService, links *LinkBuilder) *PublicHandler {
+	return &PublicHandler{ns: ns, ps: ps, links: links}
+}
+
+// archivePage renders the public archive of a newsletter. Post bodies are
+// the HTML written by the owner and are included as is.
+var archivePage = template.Must(template.New("archive").Parse(`<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+<link rel="alternate" type="application/rss+xml" title="{{.Name}}" href="{{.RSS}}">
+<link rel="alternate" type="application/atom+xml" title="{{.Name}}" href="{{.Atom}}">
+<style>
+body { font-family: sans-serif; max-width: 40rem; margin: 4rem auto; padding: 0 1rem; color: #222; }
+img { max-height: 4rem; }
+h1 { color: {{.Color}}; }
+article { border-top: 1px solid #ddd; padding: 1rem 0; }
+time { color: #666; font-size: .875rem; }
+</style>
+</head>
+<body>
+<header>
+{{if .LogoURL}}<img src="{{.LogoURL}}" alt="{{.Name}}">{{end}}
+<h1>{{.Name}}</h1>
+<p>{{.Description}}</p>
+<p><a href="{{.Slug}}/subscribe">{{.Subscribe}}</a></p>
+</header>
+{{range .Posts}}<article id="{{.ID}}">
+<h2>{{.Title}}</h2>
+<time datetime="{{.PublishedAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.PublishedAt.Format "2006-01-02"}}</time>
+{{.Body}}
+</article>
+{{else}}<p>{{.Empty}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+// archivePost is a published post as shown in the archive, with its merge
+// tags expanded for an anonymous reader.
+type archivePost struct {
+	*postdomain.Post
+	Title string
+	Body  template.HTML
+}
+
+// archivePageData is the content of the archive page of a newsletter.
+type archivePageData struct {
+	Language    string
+	Name        string
+	Description string
+	Slug        string
+	Subscribe   string // Text of the link to the hosted subscribe form
+	RSS         string // Link to the RSS feed
+	Atom        string // Link to the Atom feed
+	LogoURL     string
+	Color       string
+	Posts       []archivePost
+	Empty       string
+}
+
+// Archive shows the public archive of a newsletter.
+//
+// Route:
+//
+//	GET /public/{slug}
+//
+// Description:
+//
+//	Public page listing the published posts of a newsletter, newest first,
+//	with their full content and a link to the hosted subscribe form. Feed
+//	readers discover the feeds of the newsletter from the page. Drafts and archived posts are not shown. The
+//	page carries the logo and brand color of the newsletter; its texts are
+//	in the language negotiated from the Accept-Language header, falling
+//	back to the language of the newsletter.
+//
+// Path Parameters:
+//
+//	slug (string) - Slug of the newsletter
+//
+// Responses:
+//
+//	200 OK
+//	  - HTML archive page
+//
+//	404 Not Found
+//	  - No newsletter has the slug
+//
+//	500 Internal Server Error
+//	  - Newsletter or post retrieval failure
+func (ph *PublicHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	newsletter, err := ph.ns.GetBySlug(mux.Vars(r)["slug"])
+	if err != nil {
+		WriteError(w, r, err, "failed to get newsletter")
+		return
+	}
+
+	posts, err := ph.ps.List(newsletter.ID, postdomain.StatusPublished)
+	if err != nil {
+		WriteError(w, r, err, "failed to list posts")
+		return
+	}
+	localizer := i18n.New(i18n.Match(r.Header.Get("Accept-Language"), newsletter.Language))
+	page := archivePageData{
+		Language:    localizer.Language(),
+		Name:        newsletter.Name,
+		Description: newsletter.Description,
+		Slug:        newsletter.Slug,
+		Subscribe:   localizer.T("SubscribeButton", nil),
+		RSS:         ph.links.Feed(newsletter.Slug, ""),
+		Atom:        ph.links.Feed(newsletter.Slug, "atom"),
+		LogoURL:     newsletter.LogoURL,
+		Color:       defaultBrandColor,
+		Empty:       localizer.T("ArchiveEmpty", nil),
+	}
+	if newsletter.BrandColor != "" {
+		page.Color = newsletter.BrandColor
+	}
+	reader := postdomain.MergeFields{NewsletterName: newsletter.Name}
+	for _, post := range posts {
+		if post.PublishedAt == nil {
+			continue
+		}
+		page.Posts = append(page.Posts, archivePost{Post: post, Title: reader.Expand(post.Title), Body: template.HTML(reader.ExpandHTML(post.Body))})
+	}
+	sort.SliceStable(page.Posts, func(i, j int) bool {
+		return page.Posts[i].PublishedAt.After(*page.Posts[j].PublishedAt)
+	})
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", page.Language)
+	w.WriteHeader(http.StatusOK)
+	if err := archivePage.Execute(w, page); err != nil {
+		slog.Error("failed to render archive page", "newsletter_id", newsletter.ID, "error", err)
+	}
+}
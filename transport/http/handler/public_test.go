@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	postdomain "newsletter/internal/posts/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchive_Success(t *testing.T) {
+	mockNS, mockPS := new(MockNewsletterService), new(MockPostService)
+	h := NewPublicHandler(mockNS, mockPS, testLinks)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), Name: "Tech <News>", Slug: "tech-news", Settings: newsletterdomain.Settings{Language: "de"}}
+	older, newer := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 5, 0, 0, 0, 0, time.UTC)
+	posts := []*postdomain.Post{
+		{ID: uuid.New(), Title: "January", Body: "<p>First issue</p>", Status: postdomain.StatusPublished, PublishedAt: &older},
+		{ID: uuid.New(), Title: "February", Body: "<p>Second issue</p>", Status: postdomain.StatusPublished, PublishedAt: &newer},
+	}
+	mockNS.On("GetBySlug", "tech-news").Return(newsletter, nil)
+	mockPS.On("List", newsletter.ID, postdomain.StatusPublished).Return(posts, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/public/tech-news", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	rec := httptest.NewRecorder()
+
+	h.Archive(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "de", rec.Header().Get("Content-Language"))
+	body := rec.Body.String()
+	assert.Contains(t, body, "<title>Tech &lt;News&gt;</title>")
+	assert.Contains(t, body, "<p>Second issue</p>")
+	assert.Contains(t, body, `<a href="tech-news/subscribe">Abonnieren</a>`)
+	assert.Less(t, strings.Index(body, "February"), strings.Index(body, "January"), "newest post first")
+}
+
+func TestArchive_Empty(t *testing.T) {
+	mockNS, mockPS := new(MockNewsletterService), new(MockPostService)
+	h := NewPublicHandler(mockNS, mockPS, testLinks)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), Name: "Tech News", Slug: "tech-news"}
+	mockNS.On("GetBySlug", "tech-news").Return(newsletter, nil)
+	mockPS.On("List", newsletter.ID, postdomain.StatusPublished).Return([]*postdomain.Post{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/public/tech-news", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	rec := httptest.NewRecorder()
+
+	h.Archive(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "No issues have been published yet.")
+}
+
+func TestArchive_NotFound(t *testing.T) {
+	mockNS, mockPS := new(MockNewsletterService), new(MockPostService)
+	h := NewPublicHandler(mockNS, mockPS, testLinks)
+
+	mockNS.On("GetBySlug", "unknown").Return((*newsletterdomain.Newsletter)(nil), newsletterdomain.ErrNewsletterNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/public/unknown", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "unknown"})
+	rec := httptest.NewRecorder()
+
+	h.Archive(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockPS.AssertNotCalled(t, "List")
+}
+
+// feedNewsletter returns the services of a newsletter with two published posts.
+func feedNewsletter() (*MockNewsletterService, *MockPostService, []*postdomain.Post) {
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), Name: "Tech News", Slug: "tech-news", Description: "Weekly updates", CreatedAt: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)}
+	older, newer := time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC), time.Date(2026, 2, 5, 8, 0, 0, 0, time.UTC)
+	posts := []*postdomain.Post{
+		{ID: uuid.New(), Title: "January", Body: "<p>First & issue</p>", Status: postdomain.StatusPublished, PublishedAt: &older},
+		{ID: uuid.New(), Title: "February", Body: "<p>Second issue</p>", Status: postdomain.StatusPublished, PublishedAt: &newer},
+	}
+
+	mockNS, mockPS := new(MockNewsletterService), new(MockPostService)
+	mockNS.On("GetBySlug", "tech-news").Return(newsletter, nil)
+	mockPS.On("List", newsletter.ID, postdomain.StatusPublished).Return(posts, nil)
+	return mockNS, mockPS, posts
+}
+
+func TestFeed_RSS(t *testing.T) {
+	mockNS, mockPS, posts := feedNewsletter()
+	h := NewPublicHandler(mockNS, mockPS, testLinks)
+
+	req := httptest.NewRequest(http.MethodGet, "/public/tech-news/feed.xml", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	rec := httptest.NewRecorder()
+
+	h.Feed(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/rss+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=300", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "Thu, 05 Feb 2026 08:00:00 GMT", rec.Header().Get("Last-Modified"))
+	assert.NotEmpty(t, rec.Header().Get("ETag"))
+
+	var feed rssFeed
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &feed))
+	assert.Equal(t, "2.0", feed.Version)
+	assert.Equal(t, "Tech News", feed.Channel.Title)
+	require.Len(t, feed.Channel.Items, 2)
+	assert.Equal(t, "February", feed.Channel.Items[0].Title)
+	assert.Equal(t, "<p>First & issue</p>", feed.Channel.Items[1].Description)
+	assert.Equal(t, testLinks.Archive("tech-news")+"#"+posts[0].ID.String(), feed.Channel.Items[1].Link)
+	assert.Equal(t, "urn:uuid:"+posts[0].ID.String(), feed.Channel.Items[1].GUID.Value)
+}
+
+func TestFeed_Atom(t *testing.T) {
+	mockNS, mockPS, _ := feedNewsletter()
+	h := NewPublicHandler(mockNS, mockPS, testLinks)
+
+	req := httptest.NewRequest(http.MethodGet, "/public/tech-news/feed.xml?format=atom", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	rec := httptest.NewRecorder()
+
+	h.Feed(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/atom+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var feed atomFeed
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &feed))
+	assert.Equal(t, "2026-02-05T08:00:00Z", feed.Updated)
+	require.Len(t, feed.Entries, 2)
+	assert.Equal(t, "February", feed.Entries[0].Title)
+	assert.Equal(t, "html", feed.Entries[0].Content.Type)
+}
+
+func TestFeed_NotModified(t *testing.T) {
+	mockNS, mockPS, _ := feedNewsletter()
+	h := NewPublicHandler(mockNS, mockPS, testLinks)
+
+	req := httptest.NewRequest(http.MethodGet, "/public/tech-news/feed.xml", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	rec := httptest.NewRecorder()
+	h.Feed(rec, req)
+
+	for name, header := range map[string][2]string{
+		"etag":          {"If-None-Match", rec.Header().Get("ETag")},
+		"last modified": {"If-Modified-Since", rec.Header().Get("Last-Modified")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/public/tech-news/feed.xml", nil)
+			req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+			req.Header.Set(header[0], header[1])
+			rec := httptest.NewRecorder()
+
+			h.Feed(rec, req)
+
+			assert.Equal(t, http.StatusNotModified, rec.Code)
+			assert.Empty(t, rec.Body.String())
+		})
+	}
+}
+
+func TestFeed_InvalidFormat(t *testing.T) {
+	h := NewPublicHandler(new(MockNewsletterService), new(MockPostService), testLinks)
+
+	req := httptest.NewRequest(http.MethodGet, "/public/tech-news/feed.xml?format=json", nil)
+	req = mux.SetURLVars(req, map[string]string{"slug": "tech-news"})
+	rec := httptest.NewRecorder()
+
+	h.Feed(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/campaigns/domain"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// reportWriter writes the deliveries of a campaign report in one format.
+type reportWriter interface {
+	begin() error
+	write(delivery *domain.Delivery) error
+	end() error
+}
+
+// reportTime formats an optional time of a CSV report, empty if unset.
+func reportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// csvReport writes a report as CSV, one row per recipient.
+type csvReport struct {
+	w *csv.Writer
+}
+
+func (cr *csvReport) begin() error {
+	return cr.w.Write([]string{"email", "status", "variant", "sent_at", "opened", "opened_at", "bounced", "complained", "error", "updated_at"})
+}
+
+func (cr *csvReport) write(delivery *domain.Delivery) error {
+	return cr.w.Write([]string{
+		delivery.Email,
+		delivery.Status,
+		delivery.Variant,
+		reportTime(delivery.SentAt),
+		strconv.FormatBool(delivery.OpenedAt != nil),
+		reportTime(delivery.OpenedAt),
+		strconv.FormatBool(delivery.Status == domain.DeliveryBounced),
+		strconv.FormatBool(delivery.Status == domain.DeliveryComplained),
+		delivery.Error,
+		delivery.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func (cr *csvReport) end() error {
+	cr.w.Flush()
+	return cr.w.Error()
+}
+
+// jsonReport writes a report as a JSON object holding the array of
+// deliveries, encoding them one at a time.
+type jsonReport struct {
+	w          io.Writer
+	campaignID uuid.UUID
+	written    int
+}
+
+func (jr *jsonReport) begin() error {
+	_, err := fmt.Fprintf(jr.w, `{"campaign_id":%q,"deliveries":[`, jr.campaignID)
+	return err
+}
+
+func (jr *jsonReport) write(delivery *domain.Delivery) error {
+	encoded, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	if jr.written > 0 {
+		encoded = append([]byte{','}, encoded...)
+	}
+	jr.written++
+	_, err = jr.w.Write(encoded)
+	return err
+}
+
+func (jr *jsonReport) end() error {
+	_, err := io.WriteString(jr.w, "]}\n")
+	return err
+}
+
+// Report handles exporting the per-recipient report of a campaign.
+//
+// Route:
+//
+//	GET /campaigns/{campaign_id}/report[?format=csv|json]
+//
+// Description:
+//
+//	Returns every delivery of a campaign, in the order they were made, for
+//	analysis in external tools: the delivery status reported by the email
+//	provider, the A/B test variant, when the email was sent and first
+//	opened, and whether it bounced or drew a complaint, with the reason.
+//	Opens are only tracked for A/B test samples; clicks are counted per
+//	short link, not per recipient (see GET /r/{code}).
+//	The report is streamed as it is read, so large campaigns are neither
+//	held in memory nor paginated.
+//
+// Query Parameters:
+//
+//	format (string, optional) - "csv" (default) or "json"
+//
+// Responses:
+//
+//	200 OK
+//	  - text/csv attachment, with a header row:
+//	    email,status,variant,sent_at,opened,opened_at,bounced,complained,error,updated_at
+//	    reader@example.com,bounced,a,2026-01-10T12:00:02Z,false,,true,false,Permanent/General,2026-01-10T12:00:09Z
+//
+//	  - application/json attachment, the deliveries as listed by
+//	    GET /campaigns/{campaign_id}/deliveries:
+//	    {"campaign_id": "uuid", "deliveries": [{"email": "reader@example.com", "status": "bounced", ...}]}
+//
+//	400 Bad Request
+//	  - Invalid campaign ID or format
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Campaign does not exist or belongs to another user's newsletter
+//
+//	500 Internal Server Error
+//	  - Failure reading the deliveries; once the report started, it is
+//	    truncated instead
+func (ch *CampaignHandler) Report(w http.ResponseWriter, r *http.Request) {
+	campaign, ok := ch.ownedCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	var report reportWriter
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "csv":
+		format = "csv"
+		report = &csvReport{w: csv.NewWriter(w)}
+	case "json":
+		report = &jsonReport{w: w, campaignID: campaign.ID}
+	default:
+		http.Error(w, "invalid format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	// The response starts with the first delivery, so that a failure to read
+	// the first batch is still reported with an error status.
+	started := false
+	start := func() error {
+		started = true
+		contentType := "text/csv; charset=utf-8"
+		if format == "json" {
+			contentType = "application/json"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="campaign-%s.%s"`, campaign.ID, format))
+		return report.begin()
+	}
+
+	err := ch.cs.Report(campaign.ID, func(delivery *domain.Delivery) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return report.write(delivery)
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = report.end()
+	}
+	if err != nil {
+		if !started {
+			WriteError(w, r, err, "failed to export campaign report")
+			return
+		}
+		slog.Error("campaign report truncated", "campaign_id", campaign.ID, "error", err)
+	}
+}
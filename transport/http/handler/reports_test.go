@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/campaigns/domain"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reportRequest builds a report request on campaign in format, authenticated as ownerID.
+func reportRequest(campaign *domain.Campaign, ownerID uuid.UUID, format string) *http.Request {
+	req := campaignRequest(http.MethodGet, campaign, ownerID)
+	req.URL.RawQuery = "format=" + format
+	return req
+}
+
+func TestCampaignReport_CSV(t *testing.T) {
+	mockCS, mockNS := new(MockCampaignService), new(MockNewsletterService)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID}
+	sent := time.Date(2026, 1, 10, 12, 0, 2, 0, time.UTC)
+	opened := sent.Add(time.Minute)
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("Report", campaign.ID).Return([]*domain.Delivery{
+		{Email: "a@example.com", Status: domain.DeliveryDelivered, Variant: "a", SentAt: &sent, OpenedAt: &opened, UpdatedAt: opened},
+		{Email: "b@example.com", Status: domain.DeliveryBounced, SentAt: &sent, Error: "Permanent/General", UpdatedAt: sent},
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.Report(rec, reportRequest(campaign, newsletter.OwnerID, ""))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "campaign-"+campaign.ID.String()+".csv")
+	assert.Equal(t, "email,status,variant,sent_at,opened,opened_at,bounced,complained,error,updated_at\n"+
+		"a@example.com,delivered,a,2026-01-10T12:00:02Z,true,2026-01-10T12:01:02Z,false,false,,2026-01-10T12:01:02Z\n"+
+		"b@example.com,bounced,,2026-01-10T12:00:02Z,false,,true,false,Permanent/General,2026-01-10T12:00:02Z\n", rec.Body.String())
+}
+
+func TestCampaignReport_JSON(t *testing.T) {
+	mockCS, mockNS := new(MockCampaignService), new(MockNewsletterService)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID}
+	at := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("Report", campaign.ID).Return([]*domain.Delivery{
+		{CampaignID: campaign.ID, Email: "a@example.com", Status: domain.DeliverySent, CreatedAt: at, UpdatedAt: at},
+		{CampaignID: campaign.ID, Email: "b@example.com", Status: domain.DeliveryFailed, Error: "rejected", CreatedAt: at, UpdatedAt: at},
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.Report(rec, reportRequest(campaign, newsletter.OwnerID, "json"))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	id := campaign.ID.String()
+	assert.JSONEq(t, `{"campaign_id":"`+id+`","deliveries":[
+		{"campaign_id":"`+id+`","email":"a@example.com","status":"sent","created_at":"2026-01-10T12:00:00Z","updated_at":"2026-01-10T12:00:00Z"},
+		{"campaign_id":"`+id+`","email":"b@example.com","status":"failed","error":"rejected","created_at":"2026-01-10T12:00:00Z","updated_at":"2026-01-10T12:00:00Z"}
+	]}`, rec.Body.String())
+}
+
+func TestCampaignReport_Empty(t *testing.T) {
+	mockCS, mockNS := new(MockCampaignService), new(MockNewsletterService)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID}
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("Report", campaign.ID).Return(nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.Report(rec, reportRequest(campaign, newsletter.OwnerID, "json"))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"campaign_id":"`+campaign.ID.String()+`","deliveries":[]}`, rec.Body.String())
+}
+
+func TestCampaignReport_Errors(t *testing.T) {
+	mockCS, mockNS := new(MockCampaignService), new(MockNewsletterService)
+	h := NewCampaignHandler(mockCS, nil, mockNS, nil, nil, nil, testLinks, nil, nil)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: newsletter.ID}
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockCS.On("Report", campaign.ID).Return(nil, errors.New("connection reset"))
+
+	rec := httptest.NewRecorder()
+	h.Report(rec, reportRequest(campaign, newsletter.OwnerID, "pdf"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.Report(rec, reportRequest(campaign, newsletter.OwnerID, "csv"))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Disposition"))
+}
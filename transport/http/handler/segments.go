@@ -0,0 +1,424 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"newsletter/internal/segments/domain"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// SegmentHandler handles HTTP requests related to the subscriber segments of
+// newsletters.
+type SegmentHandler struct {
+	ss domain.SegmentService
+	ns newsletterdomain.NewsletterService
+}
+
+// NewSegmentHandler creates a new SegmentHandler.
+func NewSegmentHandler(ss domain.SegmentService, ns newsletterdomain.NewsletterService) *SegmentHandler {
+	return &SegmentHandler{ss: ss, ns: ns}
+}
+
+// SegmentRequest represents the payload for creating or editing a segment.
+type SegmentRequest struct {
+	Name   string        `json:"name"`   // Name shown to the owner
+	Filter domain.Filter `json:"filter"` // Conditions subscribers of the segment meet
+}
+
+// SegmentCount is the number of subscribers a segment selects.
+type SegmentCount struct {
+	Count int `json:"count"`
+}
+
+// segmentID parses the segment ID from the request path. It writes a 400
+// response and returns false when the ID is not a valid UUID.
+func segmentID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["segment_id"])
+	if err != nil {
+		http.Error(w, "invalid segment ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeSegmentJSON writes v as a JSON response with the given status code.
+func writeSegmentJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to encode segment response", "error", err)
+	}
+}
+
+// Create handles defining a new segment.
+//
+// Route:
+//
+//	POST /newsletters/{newsletter_id}/segments
+//
+// Description:
+//
+//	Creates a segment of the subscribers of a newsletter owned by the
+//	authenticated user. A subscriber belongs to the segment when they meet
+//	every condition of the filter:
+//	  - attributes: custom attributes with the given values
+//	  - tags: tags the subscriber carries, all of them
+//	  - subscribed_after, subscribed_before: range of subscription times
+//	  - engagement: "engaged" (opened an email of the newsletter in the last
+//	    90 days), "unengaged" (received emails but opened none) or "new"
+//	    (received none). Opens are only tracked for A/B test samples.
+//	Campaigns sent to a segment resolve it when they are dispatched.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "name": "Engaged readers in Berlin",
+//	  "filter": {
+//	    "attributes": {"city": "Berlin"},
+//	    "tags": ["beta"],
+//	    "subscribed_after": "2026-01-01T00:00:00Z",
+//	    "engagement": "engaged"
+//	  }
+//	}
+//
+// Responses:
+//
+//	201 Created
+//	  - The created segment
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID or JSON body
+//	  - Missing name, or name longer than 100 characters
+//	  - Filter without conditions, invalid attribute key, empty tag,
+//	    subscribed_after not before subscribed_before or unknown engagement
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	500 Internal Server Error
+//	  - Segment creation failure
+func (sh *SegmentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+
+	var request SegmentRequest
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
+		return
+	}
+
+	segment, err := sh.ss.Create(&domain.Segment{NewsletterID: newsletter.ID, Name: request.Name, Filter: request.Filter})
+	if err != nil {
+		WriteError(w, r, err, "failed to create segment")
+		return
+	}
+
+	writeSegmentJSON(w, http.StatusCreated, segment)
+}
+
+// List handles listing the segments of a newsletter.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/segments
+//
+// Query Parameters:
+//
+//	fields (string, optional) - Comma-separated fields of the items to return, such as id,name
+//
+// Responses:
+//
+//	200 OK
+//	  - List of segments, by name
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	500 Internal Server Error
+//	  - Segment retrieval failure
+func (sh *SegmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+
+	segments, err := sh.ss.List(newsletter.ID)
+	if err != nil {
+		WriteError(w, r, err, "failed to list segments")
+		return
+	}
+
+	writeSegmentJSON(w, http.StatusOK, segments)
+}
+
+// Get handles retrieving a single segment.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/segments/{segment_id}
+//
+// Responses:
+//
+//	200 OK
+//	  - The segment
+//
+//	400 Bad Request
+//	  - Invalid newsletter or segment ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter or segment does not exist
+func (sh *SegmentHandler) Get(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+	id, ok := segmentID(w, r)
+	if !ok {
+		return
+	}
+
+	segment, err := sh.ss.Get(newsletter.ID, id)
+	if err != nil {
+		WriteError(w, r, err, "failed to get segment")
+		return
+	}
+
+	writeSegmentJSON(w, http.StatusOK, segment)
+}
+
+// Update handles editing a segment.
+//
+// Route:
+//
+//	PUT /newsletters/{newsletter_id}/segments/{segment_id}
+//
+// Description:
+//
+//	Replaces the name and filter of a segment, validated as on creation.
+//	Campaigns sent to the segment use the new filter for the subscribers
+//	they have not emailed yet.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "name": "Engaged readers",
+//	  "filter": {"engagement": "engaged"}
+//	}
+//
+// Responses:
+//
+//	200 OK
+//	  - The updated segment
+//
+//	400 Bad Request
+//	  - Invalid newsletter or segment ID
+//	  - Invalid JSON body, name or filter, as on creation
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter or segment does not exist
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+func (sh *SegmentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+	id, ok := segmentID(w, r)
+	if !ok {
+		return
+	}
+
+	var request SegmentRequest
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
+		return
+	}
+
+	segment, err := sh.ss.Update(&domain.Segment{ID: id, NewsletterID: newsletter.ID, Name: request.Name, Filter: request.Filter})
+	if err != nil {
+		WriteError(w, r, err, "failed to update segment")
+		return
+	}
+
+	writeSegmentJSON(w, http.StatusOK, segment)
+}
+
+// Delete handles removing a segment.
+//
+// Route:
+//
+//	DELETE /newsletters/{newsletter_id}/segments/{segment_id}
+//
+// Responses:
+//
+//	204 No Content
+//	  - The segment was deleted
+//
+//	400 Bad Request
+//	  - Invalid newsletter or segment ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter or segment does not exist
+//
+//	409 Conflict
+//	  - A campaign sent to the segment is not completed
+func (sh *SegmentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+	id, ok := segmentID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := sh.ss.Delete(newsletter.ID, id); err != nil {
+		WriteError(w, r, err, "failed to delete segment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Count handles previewing the number of subscribers of a segment.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/segments/{segment_id}/count
+//
+// Description:
+//
+//	Resolves the segment now and counts the active subscribers in it, that
+//	is the recipients of a campaign sent to it at this time.
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "count": 42
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter or segment ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter or segment does not exist
+//
+//	500 Internal Server Error
+//	  - Subscriber or engagement retrieval failure
+func (sh *SegmentHandler) Count(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+	id, ok := segmentID(w, r)
+	if !ok {
+		return
+	}
+
+	segment, err := sh.ss.Get(newsletter.ID, id)
+	if err != nil {
+		WriteError(w, r, err, "failed to get segment")
+		return
+	}
+
+	count, err := sh.ss.Count(newsletter.ID, segment.Filter)
+	if err != nil {
+		WriteError(w, r, err, "failed to count segment")
+		return
+	}
+
+	writeSegmentJSON(w, http.StatusOK, SegmentCount{Count: count})
+}
+
+// Preview handles counting the subscribers a filter would select, before
+// it is saved as a segment.
+//
+// Route:
+//
+//	POST /newsletters/{newsletter_id}/segments/preview
+//
+// Request Body (application/json):
+//
+//	{
+//	  "filter": {"tags": ["beta"], "engagement": "unengaged"}
+//	}
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "count": 42
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID, JSON body or filter, as on creation
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	500 Internal Server Error
+//	  - Subscriber or engagement retrieval failure
+func (sh *SegmentHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+
+	var request SegmentRequest
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
+		return
+	}
+
+	count, err := sh.ss.Count(newsletter.ID, request.Filter)
+	if err != nil {
+		WriteError(w, r, err, "failed to count segment")
+		return
+	}
+
+	writeSegmentJSON(w, http.StatusOK, SegmentCount{Count: count})
+}
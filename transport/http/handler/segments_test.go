@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	"newsletter/internal/segments/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Segment Service ---
+type MockSegmentService struct {
+	mock.Mock
+}
+
+func (m *MockSegmentService) segment(args mock.Arguments) (*domain.Segment, error) {
+	s := args.Get(0)
+	if s == nil {
+		return nil, args.Error(1)
+	}
+	return s.(*domain.Segment), args.Error(1)
+}
+
+func (m *MockSegmentService) Create(segment *domain.Segment) (*domain.Segment, error) {
+	return m.segment(m.Called(segment))
+}
+
+func (m *MockSegmentService) Get(newsletterID, id uuid.UUID) (*domain.Segment, error) {
+	return m.segment(m.Called(newsletterID, id))
+}
+
+func (m *MockSegmentService) List(newsletterID uuid.UUID) ([]*domain.Segment, error) {
+	args := m.Called(newsletterID)
+	return args.Get(0).([]*domain.Segment), args.Error(1)
+}
+
+func (m *MockSegmentService) Update(segment *domain.Segment) (*domain.Segment, error) {
+	return m.segment(m.Called(segment))
+}
+
+func (m *MockSegmentService) Delete(newsletterID, id uuid.UUID) error {
+	return m.Called(newsletterID, id).Error(0)
+}
+
+func (m *MockSegmentService) Count(newsletterID uuid.UUID, filter domain.Filter) (int, error) {
+	args := m.Called(newsletterID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSegmentService) Resolve(newsletterID, id uuid.UUID) (*domain.Audience, error) {
+	args := m.Called(newsletterID, id)
+	audience := args.Get(0)
+	if audience == nil {
+		return nil, args.Error(1)
+	}
+	return audience.(*domain.Audience), args.Error(1)
+}
+
+// segmentRequest builds a request on a segment of newsletter, authenticated as its owner.
+func segmentRequest(method string, newsletter *newsletterdomain.Newsletter, segmentID uuid.UUID, body any) *http.Request {
+	var payload bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&payload).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, "/newsletters/"+newsletter.ID.String()+"/segments/"+segmentID.String(), &payload)
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletter.ID.String(), "segment_id": segmentID.String()})
+	return req.WithContext(contextWithUserID(req.Context(), newsletter.OwnerID.String()))
+}
+
+func TestCreateSegment_Success(t *testing.T) {
+	mockNS, mockSS := new(MockNewsletterService), new(MockSegmentService)
+	h := NewSegmentHandler(mockSS, mockNS)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	filter := domain.Filter{Attributes: map[string]string{"city": "Berlin"}, Engagement: domain.EngagementEngaged}
+	created := &domain.Segment{ID: uuid.New(), NewsletterID: newsletter.ID, Name: "Berlin", Filter: filter}
+
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockSS.On("Create", &domain.Segment{NewsletterID: newsletter.ID, Name: "Berlin", Filter: filter}).Return(created, nil)
+
+	rec := httptest.NewRecorder()
+	h.Create(rec, segmentRequest(http.MethodPost, newsletter, uuid.Nil, SegmentRequest{Name: "Berlin", Filter: filter}))
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var body domain.Segment
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, created.ID, body.ID)
+	assert.Equal(t, filter, body.Filter)
+}
+
+func TestCreateSegment_Invalid(t *testing.T) {
+	mockNS, mockSS := new(MockNewsletterService), new(MockSegmentService)
+	h := NewSegmentHandler(mockSS, mockNS)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockSS.On("Create", mock.Anything).Return(nil, domain.ErrInvalidSegment)
+
+	rec := httptest.NewRecorder()
+	h.Create(rec, segmentRequest(http.MethodPost, newsletter, uuid.Nil, SegmentRequest{Name: "Everyone"}))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDeleteSegment_InUse(t *testing.T) {
+	mockNS, mockSS := new(MockNewsletterService), new(MockSegmentService)
+	h := NewSegmentHandler(mockSS, mockNS)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	id := uuid.New()
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockSS.On("Delete", newsletter.ID, id).Return(domain.ErrSegmentInUse)
+
+	rec := httptest.NewRecorder()
+	h.Delete(rec, segmentRequest(http.MethodDelete, newsletter, id, nil))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestCountSegment(t *testing.T) {
+	mockNS, mockSS := new(MockNewsletterService), new(MockSegmentService)
+	h := NewSegmentHandler(mockSS, mockNS)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	segment := &domain.Segment{ID: uuid.New(), NewsletterID: newsletter.ID, Name: "Beta", Filter: domain.Filter{Tags: []string{"beta"}}}
+	mockNS.On("Get", newsletter.ID).Return(newsletter, nil)
+	mockSS.On("Get", newsletter.ID, segment.ID).Return(segment, nil)
+	mockSS.On("Count", newsletter.ID, segment.Filter).Return(42, nil)
+
+	rec := httptest.NewRecorder()
+	h.Count(rec, segmentRequest(http.MethodGet, newsletter, segment.ID, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"count":42}`, rec.Body.String())
+}
+
+func TestPreviewSegment_OtherOwner(t *testing.T) {
+	mockNS, mockSS := new(MockNewsletterService), new(MockSegmentService)
+	h := NewSegmentHandler(mockSS, mockNS)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), OwnerID: uuid.New()}
+	mockNS.On("Get", newsletter.ID).Return(&newsletterdomain.Newsletter{ID: newsletter.ID, OwnerID: uuid.New()}, nil)
+
+	rec := httptest.NewRecorder()
+	h.Preview(rec, segmentRequest(http.MethodPost, newsletter, uuid.Nil, SegmentRequest{Filter: domain.Filter{Tags: []string{"beta"}}}))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockSS.AssertNotCalled(t, "Count", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/newsletters/domain"
+	notifications "newsletter/internal/notifications/domain"
+	"strings"
+)
+
+// SenderHandler handles HTTP requests related to the verification of the
+// custom sender address of newsletters and the authentication of its domain.
+type SenderHandler struct {
+	ns domain.NewsletterService
+	sv notifications.SenderVerifier
+	dc notifications.DomainChecker
+}
+
+// NewSenderHandler creates a new SenderHandler. sv may be nil when the
+// configured email provider does not support sender verification; dc checks
+// the DNS records of sending domains.
+func NewSenderHandler(ns domain.NewsletterService, sv notifications.SenderVerifier, dc notifications.DomainChecker) *SenderHandler {
+	return &SenderHandler{ns: ns, sv: sv, dc: dc}
+}
+
+// SenderResponse describes the sender of a newsletter and its verification state.
+type SenderResponse struct {
+	FromName  string                           `json:"from_name"`
+	FromEmail string                           `json:"from_email"`
+	Status    notifications.VerificationStatus `json:"status"`
+}
+
+// StartVerification handles requesting the verification of a newsletter sender address.
+//
+// Route:
+//
+//	POST /newsletters/{newsletter_id}/sender/verification
+//
+// Description:
+//
+//	Asks the email provider to send a verification email to the sender
+//	address configured in the newsletter settings. The address is used as
+//	"from" of outgoing emails once its owner follows the link in that email
+//	and the status endpoint has observed the verification.
+//
+// Responses:
+//
+//	202 Accepted
+//	  {
+//	    "from_name": "My Newsletter",
+//	    "from_email": "news@example.com",
+//	    "status": "pending"
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//	  - No sender address configured
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	500 Internal Server Error
+//	  - Provider failure
+//
+//	501 Not Implemented
+//	  - The email provider does not support sender verification
+//
+// Side Effects:
+//   - Sends a verification email to the sender address.
+func (sh *SenderHandler) StartVerification(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+
+	if sh.sv == nil {
+		http.Error(w, "sender verification is not supported by the email provider", http.StatusNotImplemented)
+		return
+	}
+	if newsletter.FromEmail == "" {
+		http.Error(w, "no sender address configured", http.StatusBadRequest)
+		return
+	}
+
+	if err := sh.sv.StartVerification(newsletter.FromEmail); err != nil {
+		slog.Error("failed to start sender verification", "newsletter_id", newsletter.ID, "error", err)
+		http.Error(w, "failed to start sender verification: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeSender(w, http.StatusAccepted, newsletter, notifications.VerificationPending)
+}
+
+// Status handles polling the verification state of a newsletter sender address.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/sender
+//
+// Description:
+//
+//	Returns the sender configured for the newsletter together with its
+//	verification state as reported by the email provider. The newsletter is
+//	only sent from this address after a "success" status has been observed.
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "from_name": "My Newsletter",
+//	    "from_email": "news@example.com",
+//	    "status": "not_started" | "pending" | "success" | "failed"
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	500 Internal Server Error
+//	  - Provider failure
+//
+//	501 Not Implemented
+//	  - The email provider does not support sender verification
+//
+// Side Effects:
+//   - Records the verification state on the newsletter when it changed.
+func (sh *SenderHandler) Status(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+
+	if newsletter.FromEmail == "" {
+		writeSender(w, http.StatusOK, newsletter, notifications.VerificationNotStarted)
+		return
+	}
+	if sh.sv == nil {
+		http.Error(w, "sender verification is not supported by the email provider", http.StatusNotImplemented)
+		return
+	}
+
+	status, err := sh.sv.VerificationStatus(newsletter.FromEmail)
+	if err != nil {
+		slog.Error("failed to get sender verification status", "newsletter_id", newsletter.ID, "error", err)
+		http.Error(w, "failed to get sender verification status: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	verified := status == notifications.VerificationSuccess
+	if verified != newsletter.SenderVerified {
+		if err := sh.ns.SetSenderVerified(newsletter.ID, newsletter.FromEmail, verified); err != nil && !errors.Is(err, domain.ErrNewsletterNotFound) {
+			http.Error(w, "failed to record sender verification: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeSender(w, http.StatusOK, newsletter, status)
+}
+
+// CheckDomain handles checking the email authentication of a sending domain.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/sender/domain[?domain=example.com]
+//
+// Description:
+//
+//	Reads the DNS records that authenticate the emails of a domain and
+//	explains what to change: the SPF record must include the email
+//	provider, the CNAME records of its DKIM keys (the Easy DKIM tokens
+//	with AWS SES) must be published, and a DMARC record must set a policy.
+//	Poorly authenticated newsletters land in spam. The domain of the
+//	sender address of the newsletter is checked unless another one is
+//	given, e.g. before setting the sender address.
+//
+// Query Parameters:
+//
+//	domain (string, optional) - Domain to check; defaults to the domain of the sender address
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "domain": "example.com",
+//	    "status": "pass" | "unknown" | "warn" | "fail",
+//	    "checks": [
+//	      {"mechanism": "spf", "status": "pass", "name": "example.com", "records": ["v=spf1 include:amazonses.com ~all"]},
+//	      {
+//	        "mechanism": "dkim",
+//	        "status": "fail",
+//	        "name": "_domainkey.example.com",
+//	        "records": [],
+//	        "guidance": ["Publish a CNAME record on abc._domainkey.example.com pointing to abc.dkim.amazonses.com"]
+//	      },
+//	      {
+//	        "mechanism": "dmarc",
+//	        "status": "warn",
+//	        "name": "_dmarc.example.com",
+//	        "records": ["v=DMARC1; p=none; rua=mailto:dmarc@example.com"],
+//	        "guidance": ["p=none only monitors: move to p=quarantine or p=reject once the reports show your emails pass"]
+//	      }
+//	    ]
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID or domain
+//	  - No domain given and no sender address configured
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+func (sh *SenderHandler) CheckDomain(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+
+	name := r.URL.Query().Get("domain")
+	if name == "" {
+		_, after, found := strings.Cut(newsletter.FromEmail, "@")
+		if !found {
+			http.Error(w, "no domain given and no sender address configured", http.StatusBadRequest)
+			return
+		}
+		name = after
+	}
+
+	report, err := sh.dc.Check(name)
+	if err != nil {
+		WriteError(w, r, err, "failed to check domain")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("failed to encode domain report", "newsletter_id", newsletter.ID, "error", err)
+	}
+}
+
+// writeSender writes the sender of newsletter as JSON.
+func writeSender(w http.ResponseWriter, code int, newsletter *domain.Newsletter, status notifications.VerificationStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(SenderResponse{
+		FromName:  newsletter.FromName,
+		FromEmail: newsletter.FromEmail,
+		Status:    status,
+	}); err != nil {
+		slog.Error("failed to encode sender response", "newsletter_id", newsletter.ID, "error", err)
+	}
+}
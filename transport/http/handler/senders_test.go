@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/newsletters/domain"
+	notifications "newsletter/internal/notifications/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mock Sender Verifier ---
+type MockSenderVerifier struct {
+	mock.Mock
+}
+
+func (m *MockSenderVerifier) StartVerification(address string) error {
+	args := m.Called(address)
+	return args.Error(0)
+}
+
+func (m *MockSenderVerifier) VerificationStatus(address string) (notifications.VerificationStatus, error) {
+	args := m.Called(address)
+	return args.Get(0).(notifications.VerificationStatus), args.Error(1)
+}
+
+// --- Mock Domain Checker ---
+type MockDomainChecker struct {
+	mock.Mock
+}
+
+func (m *MockDomainChecker) Check(domain string) (*notifications.DomainReport, error) {
+	args := m.Called(domain)
+	report := args.Get(0)
+	if report == nil {
+		return nil, args.Error(1)
+	}
+	return report.(*notifications.DomainReport), args.Error(1)
+}
+
+// senderRequest builds a request for newsletterID authenticated as ownerID.
+func senderRequest(method string, newsletterID, ownerID uuid.UUID) *http.Request {
+	req := httptest.NewRequest(method, "/newsletters/"+newsletterID.String()+"/sender", nil)
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String()})
+	return req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+}
+
+func TestStartVerification_Success(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	verifier := new(MockSenderVerifier)
+	h := NewSenderHandler(mockSvc, verifier, nil)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	newsletter := &domain.Newsletter{ID: newsletterID, OwnerID: ownerID, Settings: domain.Settings{FromEmail: "news@example.com"}}
+
+	mockSvc.On("Get", newsletterID).Return(newsletter, nil)
+	verifier.On("StartVerification", "news@example.com").Return(nil)
+
+	rec := httptest.NewRecorder()
+	h.StartVerification(rec, senderRequest(http.MethodPost, newsletterID, ownerID))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var resp SenderResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, notifications.VerificationPending, resp.Status)
+
+	mockSvc.AssertExpectations(t)
+	verifier.AssertExpectations(t)
+}
+
+func TestStartVerification_NotOwner(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	verifier := new(MockSenderVerifier)
+	h := NewSenderHandler(mockSvc, verifier, nil)
+
+	newsletterID := uuid.New()
+	newsletter := &domain.Newsletter{ID: newsletterID, OwnerID: uuid.New(), Settings: domain.Settings{FromEmail: "news@example.com"}}
+
+	mockSvc.On("Get", newsletterID).Return(newsletter, nil)
+
+	rec := httptest.NewRecorder()
+	h.StartVerification(rec, senderRequest(http.MethodPost, newsletterID, uuid.New()))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	verifier.AssertNotCalled(t, "StartVerification", mock.Anything)
+}
+
+func TestStartVerification_Unsupported(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	h := NewSenderHandler(mockSvc, nil, nil)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	newsletter := &domain.Newsletter{ID: newsletterID, OwnerID: ownerID, Settings: domain.Settings{FromEmail: "news@example.com"}}
+
+	mockSvc.On("Get", newsletterID).Return(newsletter, nil)
+
+	rec := httptest.NewRecorder()
+	h.StartVerification(rec, senderRequest(http.MethodPost, newsletterID, ownerID))
+
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestSenderStatus_RecordsVerification(t *testing.T) {
+	mockSvc := new(MockNewsletterService)
+	verifier := new(MockSenderVerifier)
+	h := NewSenderHandler(mockSvc, verifier, nil)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	newsletter := &domain.Newsletter{ID: newsletterID, OwnerID: ownerID, Settings: domain.Settings{FromEmail: "news@example.com"}}
+
+	mockSvc.On("Get", newsletterID).Return(newsletter, nil)
+	verifier.On("VerificationStatus", "news@example.com").Return(notifications.VerificationSuccess, nil)
+	mockSvc.On("SetSenderVerified", newsletterID, "news@example.com", true).Return(nil)
+
+	rec := httptest.NewRecorder()
+	h.Status(rec, senderRequest(http.MethodGet, newsletterID, ownerID))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp SenderResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, notifications.VerificationSuccess, resp.Status)
+
+	mockSvc.AssertExpectations(t)
+	verifier.AssertExpectations(t)
+}
+
+func TestCheckDomain_SenderDomain(t *testing.T) {
+	mockSvc, checker := new(MockNewsletterService), new(MockDomainChecker)
+	h := NewSenderHandler(mockSvc, nil, checker)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	newsletter := &domain.Newsletter{ID: newsletterID, OwnerID: ownerID, Settings: domain.Settings{FromEmail: "news@example.com"}}
+	mockSvc.On("Get", newsletterID).Return(newsletter, nil)
+	checker.On("Check", "example.com").Return(&notifications.DomainReport{
+		Domain: "example.com",
+		Status: notifications.CheckWarn,
+		Checks: []notifications.RecordCheck{{Mechanism: notifications.MechanismDMARC, Status: notifications.CheckWarn, Guidance: []string{"p=none only monitors"}}},
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.CheckDomain(rec, senderRequest(http.MethodGet, newsletterID, ownerID))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report notifications.DomainReport
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(t, notifications.CheckWarn, report.Status)
+	assert.Equal(t, []string{"p=none only monitors"}, report.Checks[0].Guidance)
+}
+
+func TestCheckDomain_InvalidDomain(t *testing.T) {
+	mockSvc, checker := new(MockNewsletterService), new(MockDomainChecker)
+	h := NewSenderHandler(mockSvc, nil, checker)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	mockSvc.On("Get", newsletterID).Return(&domain.Newsletter{ID: newsletterID, OwnerID: ownerID}, nil)
+	checker.On("Check", "not a domain").Return(nil, notifications.ErrInvalidDomain)
+
+	// Without a sender address, a domain must be given.
+	rec := httptest.NewRecorder()
+	h.CheckDomain(rec, senderRequest(http.MethodGet, newsletterID, ownerID))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req := senderRequest(http.MethodGet, newsletterID, ownerID)
+	req.URL.RawQuery = "domain=not+a+domain"
+	rec = httptest.NewRecorder()
+	h.CheckDomain(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	checker.AssertExpectations(t)
+}
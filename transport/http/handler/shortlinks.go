@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"newsletter/internal/links/domain"
+
+	"github.com/gorilla/mux"
+)
+
+// ShortLinkHandler handles the short links of the emails of campaigns.
+type ShortLinkHandler struct {
+	ls domain.LinkService
+}
+
+func NewShortLinkHandler(ls domain.LinkService) *ShortLinkHandler {
+	return &ShortLinkHandler{ls: ls}
+}
+
+// Redirect handles following a short link.
+//
+// Route:
+//
+//	GET /r/{code}
+//
+// Description:
+//
+//	Redirects to the URL a short link stands for and counts the click.
+//	Campaigns replace the links of their emails with short links when click
+//	tracking is enabled (CLICK_TRACKING). Redirects are not cached, so that
+//	every click is counted.
+//
+// Path Parameters:
+//
+//	code (string) - Code of the short link
+//
+// Responses:
+//
+//	302 Found
+//	  - Location: the URL of the link
+//
+//	404 Not Found
+//	  - No link has the code
+//
+//	500 Internal Server Error
+//	  - Failed to look up the link
+//
+// Side Effects:
+//   - Increments the clicks of the link
+func (sh *ShortLinkHandler) Redirect(w http.ResponseWriter, r *http.Request) {
+	link, err := sh.ls.Follow(mux.Vars(r)["code"])
+	if err != nil {
+		WriteError(w, r, err, "failed to follow link")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store, private")
+	http.Redirect(w, r, link.URL, http.StatusFound)
+}
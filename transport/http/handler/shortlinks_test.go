@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/links/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mock Link Service ---
+type MockLinkService struct {
+	mock.Mock
+}
+
+func (m *MockLinkService) link(args mock.Arguments) (*domain.Link, error) {
+	l := args.Get(0)
+	if l == nil {
+		return nil, args.Error(1)
+	}
+	return l.(*domain.Link), args.Error(1)
+}
+
+func (m *MockLinkService) Shorten(campaignID uuid.UUID, url string) (*domain.Link, error) {
+	return m.link(m.Called(campaignID, url))
+}
+
+func (m *MockLinkService) Follow(code string) (*domain.Link, error) {
+	return m.link(m.Called(code))
+}
+
+func (m *MockLinkService) Stats() domain.Stats {
+	return m.Called().Get(0).(domain.Stats)
+}
+
+func TestRedirect(t *testing.T) {
+	mockLS := new(MockLinkService)
+	h := NewShortLinkHandler(mockLS)
+	mockLS.On("Follow", "Ab3dE6gH").Return(&domain.Link{Code: "Ab3dE6gH", URL: "https://example.com/a?b=c"}, nil)
+	mockLS.On("Follow", "unknown1").Return(nil, domain.ErrLinkNotFound)
+
+	follow := func(code string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/r/"+code, nil), map[string]string{"code": code})
+		rec := httptest.NewRecorder()
+		h.Redirect(rec, req)
+		return rec
+	}
+
+	rec := follow("Ab3dE6gH")
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://example.com/a?b=c", rec.Header().Get("Location"))
+	assert.Equal(t, "no-store, private", rec.Header().Get("Cache-Control"))
+
+	assert.Equal(t, http.StatusNotFound, follow("unknown1").Code)
+}
+
+func TestTrackClicks(t *testing.T) {
+	mockLS := new(MockLinkService)
+	cr := &campaignRunner{links: testLinks, shortener: mockLS}
+	campaignID := uuid.New()
+	mockLS.On("Shorten", campaignID, "https://example.com/a?x=1&y=2").Return(&domain.Link{Code: "Ab3dE6gH"}, nil).Once()
+	mockLS.On("Shorten", campaignID, "https://example.com/down").Return(nil, errors.New("database unavailable"))
+
+	body := cr.trackClicks(campaignID, `<p><a href="https://example.com/a?x=1&amp;y=2" rel="nofollow">A</a> <a href="https://example.com/a?x=1&amp;y=2">again</a></p>`+
+		`<a href="https://example.com/down">Down</a> <a href="mailto:hi@example.com">Mail</a> <a href="https://example.com/?u={{email}}">Mine</a>`)
+
+	short := testLinks.ShortLink("Ab3dE6gH")
+	assert.Equal(t, `<p><a href="`+short+`" rel="nofollow">A</a> <a href="`+short+`">again</a></p>`+
+		`<a href="https://example.com/down">Down</a> <a href="mailto:hi@example.com">Mail</a> <a href="https://example.com/?u={{email}}">Mine</a>`, body)
+	mockLS.AssertExpectations(t)
+}
@@ -2,39 +2,67 @@ package handler
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
+	"html"
 	"log/slog"
+	"net"
 	"net/http"
-	"newsletter/config"
+	"newsletter/internal/infrastructure/i18n"
 	"newsletter/internal/infrastructure/workerpool"
 	"newsletter/internal/infrastructure/workerpool/jobs"
+	newsletterdomain "newsletter/internal/newsletters/domain"
 	notifications "newsletter/internal/notifications/domain"
 	"newsletter/internal/subscriptions/domain"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type SubscriptionHandler struct {
 	ss domain.SubscriptionService
+	ns newsletterdomain.NewsletterService
 	es notifications.EmailService
 	wp workerpool.JobSubmiter
+	cv domain.CaptchaVerifier
+
+	links   *LinkBuilder
+	captcha *hostedCaptcha // CAPTCHA widget of the hosted subscribe form; nil when CAPTCHA is disabled
+}
+
+// NewSubscriptionHandler creates a new SubscriptionHandler. cv may be nil to
+// disable CAPTCHA verification of subscribe requests. links builds the
+// unsubscribe links of confirmation emails.
+func NewSubscriptionHandler(ss domain.SubscriptionService, ns newsletterdomain.NewsletterService, es notifications.EmailService, wp workerpool.JobSubmiter, cv domain.CaptchaVerifier, links *LinkBuilder) *SubscriptionHandler {
+	return &SubscriptionHandler{ss: ss, ns: ns, es: es, wp: wp, cv: cv, links: links}
 }
 
-func NewSubscriptionHandler(ss domain.SubscriptionService, es notifications.EmailService, wp workerpool.JobSubmiter) *SubscriptionHandler {
-	return &SubscriptionHandler{ss: ss, es: es, wp: wp}
+// SetCaptchaWidget renders the CAPTCHA widget of provider, such as
+// "hcaptcha", with siteKey on the hosted subscribe form. An empty or unknown
+// provider renders none.
+func (sh *SubscriptionHandler) SetCaptchaWidget(provider, siteKey string) {
+	sh.captcha = newHostedCaptcha(provider, siteKey)
 }
 
 // SubscribeRequest represents the payload for subscribing to a newsletter.
 type SubscribeRequest struct {
-	Email string `json:"email"` // Email of the subscriber
+	Email        string `json:"email"`         // Email of the subscriber
+	CaptchaToken string `json:"captcha_token"` // CAPTCHA response token, required when CAPTCHA is enabled
+	Website      string `json:"website"`       // Honeypot: hidden from humans, so only bots fill it in
+	Language     string `json:"language"`      // Preferred language of the emails, such as "de"
+	Timezone     string `json:"timezone"`      // IANA timezone of the subscriber, such as "Europe/Berlin"
+
+	Attributes map[string]string `json:"attributes"` // Custom attributes of the subscriber, such as "first_name"
 }
 
 // SubscribeResponse represents the response returned after a subscription is created.
 type SubscribeResponse struct {
 	ID           string    `json:"id"`
-	NewsletterID string    `json:"newsletter_id"`
+	NewsletterID uuid.UUID `json:"newsletter_id"`
 	Email        string    `json:"email"`
+	Status       string    `json:"status"` // "active", or "pending" until the subscriber confirms
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -49,16 +77,43 @@ type SubscribeResponse struct {
 //	Subscribes an email address to a specific newsletter. Upon successful
 //	subscription, a confirmation email is sent containing an unsubscribe link.
 //
+//	An email already subscribed gets its subscription back. An email that
+//	unsubscribed before gets the same subscription back with a new
+//	unsubscribe token, "pending" until the subscriber follows the link of
+//	the confirmation email: it receives no campaign until then. Its earlier
+//	subscriptions and unsubscriptions are kept in the consent history.
+//
+//	Clients may send an Idempotency-Key header: a retry with the same key
+//	is answered with the original response instead of subscribing again.
+//
 // Path Parameters:
 //
-//	newsletter_id (string) - The ID of the newsletter to subscribe to
+//	newsletter_id (UUID) - The ID of the newsletter to subscribe to
 //
 // Request Body (application/json):
 //
 //	{
-//	  "email": "user@example.com"
+//	  "email": "user@example.com",
+//	  "captcha_token": "token from the CAPTCHA widget (when enabled)",
+//	  "website": "",
+//	  "language": "de",
+//	  "timezone": "Europe/Berlin",
+//	  "attributes": {"first_name": "Ada", "source": "landing-page"}
 //	}
 //
+//	The optional "language" selects the language of the emails sent to the
+//	subscriber. It defaults to the Accept-Language header and then to the
+//	language of the newsletter. The optional "timezone" lets campaigns with
+//	a send window reach the subscriber in their local time. The optional
+//	"attributes" are custom data about the subscriber, available to merge
+//	tags such as {{attributes.first_name}}: at most 50, with keys of lower
+//	case letters, digits and underscores, and values of up to 1000
+//	characters.
+//
+//	The "website" field is a honeypot: forms should render it hidden and
+//	leave it empty. Requests that fill it in are answered as if successful
+//	but no subscription is created.
+//
 // Responses:
 //
 //	201 Created
@@ -66,66 +121,70 @@ type SubscribeResponse struct {
 //	    "id": "subscription_id",
 //	    "newsletter_id": "newsletter_id",
 //	    "email": "user@example.com",
+//	    "status": "active",
 //	    "created_at": "2026-01-10T12:00:00Z"
 //	  }
 //
 //	400 Bad Request
-//	  - Missing newsletter_id in path
+//	  - Invalid newsletter ID
 //	  - Invalid JSON body
+//	  - Missing or invalid CAPTCHA token
+//	  - Unknown timezone
+//	  - Invalid attributes
+//
+//	403 Forbidden
+//	  - The newsletter has as many active subscribers as the plan of its
+//	    owner allows
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	422 Unprocessable Entity
+//	  {
+//	    "error": "invalid email address",
+//	    "reason": "syntax | no_mx | disposable",
+//	    "detail": "mailinator.com is a disposable email provider"
+//	  }
+//	  - The email address was rejected by validation; "error" is localized
+//
+//	429 Too Many Requests
+//	  - The email subscribed to this newsletter too recently
 //
 //	500 Internal Server Error
 //	  - Subscription creation failure
 //
 // Side Effects:
-//   - Sends a confirmation email containing an unsubscribe link with a token.
+//   - Sends a confirmation email containing an unsubscribe link with a token
+//     and, when configured, an unsubscribe-all link with a signed global token.
+//     A pending subscription gets a link confirming it instead, valid for a
+//     week. The email is sent from the verified sender of the newsletter, if
+//     any, in the language of the subscriber.
 func (sh *SubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	newsletterID, found := vars["newsletter_id"]
-	if !found {
-		http.Error(w, "newsletter ID is missing from path parameters", http.StatusBadRequest)
+	newsletterID, err := uuid.Parse(mux.Vars(r)["newsletter_id"])
+	if err != nil {
+		http.Error(w, "invalid newsletter ID", http.StatusBadRequest)
 		return
 	}
 
 	var request SubscribeRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
 		return
 	}
 
-	subscription := domain.Subscription{
-		NewsletterID: newsletterID,
-		Email:        request.Email,
+	newSubscription, err := sh.subscribe(r, newsletterID, nil, request)
+	var emailErr *domain.EmailError
+	if errors.As(err, &emailErr) {
+		writeEmailError(w, r, emailErr)
+		return
 	}
-	newSubscription, err := sh.ss.Subscribe(&subscription)
 	if err != nil {
-		http.Error(w, "failed to create subscription: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err, "failed to create subscription")
 		return
 	}
 
-	// Send confirmation email to the subscriber with unsubscribe link
-	job := jobs.SendEmailJob{
-		Email: notifications.Email{
-			To:      newSubscription.Email,
-			Subject: "Confirmation",
-			Text: fmt.Sprintf(
-				`You are receiving this email because you subscribed to this newsletter.
-                If you no longer wish to receive these emails, you can unsubscribe using the link below:
-                %s/subscriptions/unsubscribe?token=%s`,
-				config.GetEnv("BASE_URL", ""),
-				newSubscription.UnsubscribeToken,
-			),
-			HTML: fmt.Sprintf(
-				`<p>You are receiving this email because you subscribed to this newsletter.</p>
-				<p>If you no longer wish to receive these emails, you can
-				<a href="%s/subscriptions/unsubscribe?token=%s">unsubscribe here</a>.</p>`,
-				config.GetEnv("BASE_URL", ""),
-				newSubscription.UnsubscribeToken,
-			),
-		},
-		Service: sh.es,
-	}
-	sh.wp.Submit(&job)
-
 	// Immediate response with created subscription in JSON
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -134,6 +193,7 @@ func (sh *SubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request)
 		ID:           newSubscription.ID,
 		NewsletterID: newSubscription.NewsletterID,
 		Email:        newSubscription.Email,
+		Status:       newSubscription.Status,
 		CreatedAt:    newSubscription.CreatedAt,
 	}
 	if err := json.NewEncoder(w).Encode(subscribeResponse); err != nil {
@@ -145,20 +205,504 @@ func (sh *SubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// Unsubscribe removes a subscription using an unsubscribe token.
+// EmailErrorResponse reports why an email address was rejected.
+type EmailErrorResponse struct {
+	Error  string `json:"error"`  // Localized message
+	Reason string `json:"reason"` // domain.EmailReasonSyntax, EmailReasonNoMX or EmailReasonDisposable
+	Detail string `json:"detail"`
+}
+
+// writeEmailError writes err as a 422 Unprocessable Entity JSON response.
+func writeEmailError(w http.ResponseWriter, r *http.Request, err *domain.EmailError) {
+	message, lang := localize(r, domain.ErrInvalidEmail, domain.ErrInvalidEmail)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	if err := json.NewEncoder(w).Encode(EmailErrorResponse{Error: message, Reason: err.Reason, Detail: err.Detail}); err != nil {
+		slog.Error("failed to encode email error response", "error", err)
+	}
+}
+
+// subscribe subscribes the email of request to a newsletter and queues the
+// confirmation email. newsletter provides the sender, default language and
+// footer of the email; when nil, it is loaded once the request passed the
+// honeypot and CAPTCHA checks.
+//
+// Requests caught by the honeypot get a subscription that is not stored, so
+// that bots are answered as if successful. A failed CAPTCHA verification
+// returns the error of the verifier.
+func (sh *SubscriptionHandler) subscribe(r *http.Request, newsletterID uuid.UUID, newsletter *newsletterdomain.Newsletter, request SubscribeRequest) (*domain.Subscription, error) {
+	if request.Website != "" {
+		// Do not tell bots that they were detected.
+		slog.Warn("subscription rejected by honeypot", "newsletter_id", newsletterID, "remote_ip", remoteIP(r))
+		return &domain.Subscription{NewsletterID: newsletterID, Email: request.Email, Status: domain.StatusActive, CreatedAt: time.Now()}, nil
+	}
+
+	if sh.cv != nil {
+		if err := sh.cv.Verify(r.Context(), request.CaptchaToken, remoteIP(r)); err != nil {
+			slog.Warn("captcha verification failed", "newsletter_id", newsletterID, "error", err)
+			return nil, err
+		}
+	}
+
+	// The newsletter may be unavailable: the default sender and language are used then.
+	if newsletter == nil {
+		newsletter = sh.newsletter(newsletterID)
+	}
+	from, defaultLanguage := "", ""
+	if newsletter != nil {
+		from, defaultLanguage = newsletter.Sender(), newsletter.Language
+	}
+
+	subscription := domain.Subscription{
+		NewsletterID: newsletterID,
+		Email:        request.Email,
+		Language:     i18n.Match(request.Language, r.Header.Get("Accept-Language"), defaultLanguage),
+		Timezone:     request.Timezone,
+		Attributes:   request.Attributes,
+	}
+	newSubscription, err := sh.ss.Subscribe(&subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	localizer := i18n.New(subscription.Language)
+	var text, htmlBody string
+	if newSubscription.Status == domain.StatusPending {
+		// The address unsubscribed before: it must confirm before receiving emails again.
+		confirmLink := sh.links.Confirm(sh.ss.ConfirmToken(newSubscription))
+		text = localizer.T("ConfirmationResubscribeText", map[string]any{"Link": confirmLink})
+		htmlBody = "<p>" + localizer.T("ConfirmationResubscribeHTML", map[string]any{"Link": html.EscapeString(confirmLink)}) + "</p>"
+	} else {
+		text, htmlBody = sh.confirmationBody(localizer, newSubscription)
+	}
+
+	job := jobs.SendEmailJob{
+		Email: notifications.Email{
+			From:    from,
+			To:      newSubscription.Email,
+			Subject: localizer.T("ConfirmationSubject", nil),
+			Text:    text,
+			HTML:    htmlBody,
+		},
+		Service:       sh.es,
+		Transactional: true,
+	}
+	appendFooter(&job.Email, newsletter)
+	if err := sh.wp.TrySubmit(&job); err != nil {
+		// The subscription exists, so it is still reported as created.
+		slog.Error("confirmation email not queued", "newsletter_id", newSubscription.NewsletterID, "email", newSubscription.Email, "error", err)
+	}
+
+	return newSubscription, nil
+}
+
+// confirmationBody returns the text and HTML bodies of the email confirming
+// an active subscription, with its unsubscribe links.
+func (sh *SubscriptionHandler) confirmationBody(localizer *i18n.Localizer, subscription *domain.Subscription) (string, string) {
+	unsubscribeLink := sh.links.Unsubscribe(sh.ss.UnsubscribeToken(subscription))
+
+	text := localizer.T("ConfirmationIntro", nil) + "\n\n" +
+		localizer.T("ConfirmationUnsubscribeText", map[string]any{"Link": unsubscribeLink})
+	htmlBody := "<p>" + html.EscapeString(localizer.T("ConfirmationIntro", nil)) + "</p>\n" +
+		"<p>" + localizer.T("ConfirmationUnsubscribeHTML", map[string]any{"Link": html.EscapeString(unsubscribeLink)}) + "</p>"
+
+	globalToken, err := sh.ss.GlobalUnsubscribeToken(subscription)
+	if err != nil {
+		slog.Warn("omitting unsubscribe-all link from confirmation email", "subscription_id", subscription.ID, "error", err)
+	} else {
+		unsubscribeAllURL := sh.links.UnsubscribeAll(globalToken)
+		text += "\n\n" + localizer.T("ConfirmationUnsubscribeAllText", map[string]any{"Link": unsubscribeAllURL})
+		htmlBody += "\n<p>" + localizer.T("ConfirmationUnsubscribeAllHTML", map[string]any{"Link": html.EscapeString(unsubscribeAllURL)}) + "</p>"
+	}
+	return text, htmlBody
+}
+
+// ListSubscribers handles listing the subscribers of a newsletter.
+//
+// Route:
+//
+//	GET /newsletters/{newsletter_id}/subscribers
+//
+// Description:
+//
+//	Returns a page of the subscribers of a newsletter owned by the
+//	authenticated user, newest first. Pages are cursor based: pass the
+//	next_cursor of a response as the cursor of the next request. With q,
+//	only the subscribers whose email address starts with q are returned,
+//	ordered by email address.
+//
+// Query Parameters:
+//
+//	q                 (string, optional)  - Email address prefix (case-sensitive); not combinable with the date range
+//	status            (string, optional)  - "active", "pending" or "unsubscribed"
+//	tag               (string, optional)  - Only subscribers carrying this tag
+//	subscribed_after  (RFC 3339, optional) - Only subscriptions created at or after this time
+//	subscribed_before (RFC 3339, optional) - Only subscriptions created before this time
+//	limit             (int, optional)     - Page size (default 50, max 100)
+//	cursor            (string, optional)  - Cursor returned with the previous page
+//	fields            (string, optional)  - Comma-separated fields of the items to return, such as id,name
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "subscriptions": [
+//	      {
+//	        "id": "subscription_id",
+//	        "newsletter_id": "newsletter_id",
+//	        "email": "user@example.com",
+//	        "status": "active",
+//	        "created_at": "2026-01-10T12:00:00Z",
+//	        "attributes": {"first_name": "Ada"}
+//	      }
+//	    ],
+//	    "next_cursor": "opaque cursor, omitted on the last page"
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//	  - Invalid filter, limit or cursor
+//	  - q while email addresses are encrypted (PII_ENCRYPTION_KEY)
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	500 Internal Server Error
+//	  - Subscriber retrieval failure
+func (sh *SubscriptionHandler) ListSubscribers(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := domain.SubscriberFilter{
+		Status:      query.Get("status"),
+		Tag:         query.Get("tag"),
+		EmailPrefix: strings.TrimSpace(query.Get("q")),
+	}
+	if filter.Status != "" && filter.Status != domain.StatusActive && filter.Status != domain.StatusPending && filter.Status != domain.StatusUnsubscribed {
+		http.Error(w, "invalid status: "+filter.Status, http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if value := query.Get("subscribed_after"); value != "" {
+		if filter.SubscribedAfter, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, "invalid subscribed_after: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("subscribed_before"); value != "" {
+		if filter.SubscribedBefore, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, "invalid subscribed_before: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if filter.EmailPrefix != "" && (!filter.SubscribedAfter.IsZero() || !filter.SubscribedBefore.IsZero()) {
+		http.Error(w, "q cannot be combined with subscribed_after or subscribed_before", http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := sh.ss.List(newsletter.ID, filter, limit, query.Get("cursor"))
+	if err != nil {
+		WriteError(w, r, err, "failed to list subscribers")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		slog.Error("failed to encode subscribers response", "newsletter_id", newsletter.ID, "error", err)
+	}
+}
+
+// AttributesRequest represents the payload for editing the custom attributes
+// of a subscriber. A null value removes the attribute.
+type AttributesRequest struct {
+	Attributes map[string]*string `json:"attributes"`
+}
+
+// UpdateAttributes handles editing the custom attributes of a subscriber.
+//
+// Route:
+//
+//	PATCH /newsletters/{newsletter_id}/subscribers/{subscription_id}/attributes
+//
+// Description:
+//
+//	Sets the given attributes of a subscriber of a newsletter owned by the
+//	authenticated user, and removes the ones set to null. Attributes that
+//	are not mentioned are kept.
+//
+// Path Parameters:
+//
+//	newsletter_id   (UUID)   - The ID of the newsletter
+//	subscription_id (string) - The ID of the subscription
+//
+// Request Body (application/json):
+//
+//	{
+//	  "attributes": {"first_name": "Ada", "source": null}
+//	}
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "id": "subscription_id",
+//	    "newsletter_id": "newsletter_id",
+//	    "email": "user@example.com",
+//	    "status": "active",
+//	    "created_at": "2026-01-10T12:00:00Z",
+//	    "attributes": {"first_name": "Ada"}
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//	  - Invalid JSON body or no attributes
+//	  - Invalid key, value longer than 1000 characters or more than 50
+//	    attributes
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter or subscription does not exist, or the newsletter is
+//	    owned by another user
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	500 Internal Server Error
+//	  - Update failure
+func (sh *SubscriptionHandler) UpdateAttributes(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+
+	var request AttributesRequest
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
+		return
+	}
+	if len(request.Attributes) == 0 {
+		http.Error(w, "no attributes to update", http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := sh.ss.UpdateAttributes(newsletter.ID, mux.Vars(r)["subscription_id"], request.Attributes)
+	if err != nil {
+		WriteError(w, r, err, "failed to update subscriber attributes")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(subscription); err != nil {
+		slog.Error("failed to encode subscription response", "subscription_id", subscription.ID, "error", err)
+	}
+}
+
+// BulkUnsubscribeRequest represents the payload for unsubscribing
+// subscribers in bulk, selected by either Emails or Filter.
+type BulkUnsubscribeRequest struct {
+	Emails []string               `json:"emails"`
+	Filter *BulkUnsubscribeFilter `json:"filter"`
+	Reason string                 `json:"reason"` // One of the domain.UnsubscribeReason constants
+}
+
+// BulkUnsubscribeFilter selects the active subscribers to unsubscribe, like
+// the filters of ListSubscribers.
+type BulkUnsubscribeFilter struct {
+	Tag              string     `json:"tag"`
+	EmailPrefix      string     `json:"email_prefix"`
+	SubscribedAfter  *time.Time `json:"subscribed_after"`
+	SubscribedBefore *time.Time `json:"subscribed_before"`
+}
+
+// BulkUnsubscribeResponse represents the response returned after a bulk
+// unsubscription.
+type BulkUnsubscribeResponse struct {
+	Unsubscribed int `json:"unsubscribed"` // Subscriptions deactivated
+}
+
+// BulkUnsubscribe handles unsubscribing subscribers of a newsletter in bulk.
+//
+// Route:
+//
+//	POST /newsletters/{newsletter_id}/subscriptions/bulk-unsubscribe
+//
+// Description:
+//
+//	Unsubscribes subscribers of a newsletter owned by the authenticated
+//	user, such as the addresses that bounced or subscribers who asked to be
+//	removed. Subscribers are selected either by email address, up to 1000,
+//	or by a filter matching active subscribers. The reason is recorded with
+//	each subscription and shown in the activity feed. Addresses that are
+//	not subscribed are skipped. No email is sent to the subscribers.
+//
+// Path Parameters:
+//
+//	newsletter_id (UUID) - The ID of the newsletter
+//
+// Request Body (application/json):
+//
+//	{
+//	  "emails": ["bounced@example.com"],
+//	  "reason": "bounced | complaint | legal_request | cleanup"
+//	}
+//
+//	or
+//
+//	{
+//	  "filter": {
+//	    "tag": "imported",
+//	    "email_prefix": "test-",
+//	    "subscribed_after": "2026-01-01T00:00:00Z",
+//	    "subscribed_before": "2026-02-01T00:00:00Z"
+//	  },
+//	  "reason": "cleanup"
+//	}
+//
+//	A filter needs at least one criterion; email_prefix cannot be combined
+//	with the date range.
+//
+// Responses:
+//
+//	200 OK
+//	  {
+//	    "unsubscribed": 42
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid newsletter ID
+//	  - Invalid JSON body
+//	  - Missing or unknown reason
+//	  - Neither or both of emails and filter, an empty filter, or more than
+//	    1000 emails
+//	  - email_prefix while email addresses are encrypted (PII_ENCRYPTION_KEY)
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	404 Not Found
+//	  - Newsletter does not exist or is owned by another user
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 512 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	500 Internal Server Error
+//	  - Unsubscription failure; subscribers unsubscribed before it stay
+//	    unsubscribed, so the request can be retried
+//
+// Side Effects:
+//   - Marks the selected subscriptions as unsubscribed with the reason.
+func (sh *SubscriptionHandler) BulkUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	newsletter, ok := ownedNewsletter(w, r, sh.ns)
+	if !ok {
+		return
+	}
+
+	var request BulkUnsubscribeRequest
+	if !decodeJSON(w, r, &request, maxBulkBodyBytes) {
+		return
+	}
+
+	emails := make([]string, 0, len(request.Emails))
+	for _, email := range request.Emails {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+
+	var filter *domain.SubscriberFilter
+	if f := request.Filter; f != nil {
+		filter = &domain.SubscriberFilter{Tag: f.Tag, EmailPrefix: strings.TrimSpace(f.EmailPrefix)}
+		if f.SubscribedAfter != nil {
+			filter.SubscribedAfter = *f.SubscribedAfter
+		}
+		if f.SubscribedBefore != nil {
+			filter.SubscribedBefore = *f.SubscribedBefore
+		}
+		if filter.EmailPrefix != "" && (f.SubscribedAfter != nil || f.SubscribedBefore != nil) {
+			http.Error(w, "email_prefix cannot be combined with subscribed_after or subscribed_before", http.StatusBadRequest)
+			return
+		}
+	}
+
+	count, err := sh.ss.BulkUnsubscribe(newsletter.ID, emails, filter, request.Reason)
+	if err != nil {
+		WriteError(w, r, err, "failed to unsubscribe subscribers")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(BulkUnsubscribeResponse{Unsubscribed: count}); err != nil {
+		slog.Error("failed to encode bulk unsubscribe response", "newsletter_id", newsletter.ID, "error", err)
+	}
+}
+
+// remoteIP returns the IP address of the client that sent the request.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// newsletter returns the newsletter with the given ID, or nil when it
+// cannot be loaded.
+func (sh *SubscriptionHandler) newsletter(newsletterID uuid.UUID) *newsletterdomain.Newsletter {
+	newsletter, err := sh.ns.Get(newsletterID)
+	if err != nil {
+		slog.Warn("using default newsletter settings", "newsletter_id", newsletterID, "error", err)
+		return nil
+	}
+
+	return newsletter
+}
+
+// Unsubscribe deactivates a subscription using an unsubscribe token.
 //
 // This endpoint allows a user to unsubscribe from a newsletter by providing
 // a unique token, typically included in the newsletter email. If the token
-// is valid, the associated subscription is deleted from the system.
+// is valid, the associated subscription is marked as unsubscribed. The record itself
+// is kept so that subscription history is preserved.
 //
 // HTTP Method: DELETE
 //
 // Query Parameters:
-//   - token (string) - The unique unsubscribe token identifying the subscription.
+//   - token (string) - The signed unsubscribe token identifying the subscription.
 //
 // Behavior:
 //   - Returns 400 Bad Request if the token is missing.
+//   - Returns 401 Unauthorized if the token has expired.
 //   - Returns 404 Not Found if no subscription matches the given token.
+//   - Returns 500 Internal Server Error if the unsubscription fails.
 //   - Returns 204 No Content on successful unsubscription.
 //
 // Example usage:
@@ -166,7 +710,8 @@ func (sh *SubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request)
 //	DELETE /subscriptions/unsubscribe?token=abcd1234
 //
 // Notes:
-//   - The unsubscribe token should be globally unique for each subscription.
+//   - Tokens encode the subscription ID and an expiry, signed with
+//     UNSUBSCRIBE_SECRET_KEY; see SubscriptionService.UnsubscribeToken.
 func (sh *SubscriptionHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
 	token := r.URL.Query().Get("token")
 	if token == "" {
@@ -176,7 +721,54 @@ func (sh *SubscriptionHandler) Unsubscribe(w http.ResponseWriter, r *http.Reques
 
 	err := sh.ss.Unsubscribe(token)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		WriteError(w, r, err, "failed to unsubscribe")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnsubscribeAll removes an email address from every newsletter at once.
+//
+// Route:
+//
+//	DELETE /subscriptions/unsubscribe-all?token=<global_token>
+//
+// Description:
+//
+//	Unsubscribes the email address identified by a signed global token from
+//	all newsletters in the system. The token is included in outgoing emails
+//	next to the regular per-newsletter unsubscribe link, and expires with
+//	it.
+//
+// Query Parameters:
+//   - token (string) - The signed global unsubscribe token.
+//
+// Responses:
+//
+//	204 No Content
+//	  - All active subscriptions of the email address were deactivated
+//
+//	400 Bad Request
+//	  - Missing token
+//	  - Invalid or tampered token, or one issued before the subscription it
+//	    identifies was renewed
+//
+//	401 Unauthorized
+//	  - The token has expired
+//
+//	500 Internal Server Error
+//	  - Unsubscription failure
+func (sh *SubscriptionHandler) UnsubscribeAll(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "missing token", http.StatusBadRequest)
+		return
+	}
+
+	_, err := sh.ss.UnsubscribeAll(token)
+	if err != nil {
+		WriteError(w, r, err, "failed to unsubscribe")
 		return
 	}
 
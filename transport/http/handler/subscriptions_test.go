@@ -2,16 +2,22 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"newsletter/internal/infrastructure/pagination"
 	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/internal/infrastructure/workerpool/jobs"
+	newsletterdomain "newsletter/internal/newsletters/domain"
 	notifications "newsletter/internal/notifications/domain"
 	"newsletter/internal/subscriptions/domain"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -28,18 +34,93 @@ func (m *MockSubscriptionService) Subscribe(s *domain.Subscription) (*domain.Sub
 	return args.Get(0).(*domain.Subscription), args.Error(1)
 }
 
+func (m *MockSubscriptionService) UnsubscribeToken(s *domain.Subscription) string {
+	args := m.Called(s)
+	return args.String(0)
+}
+
+func (m *MockSubscriptionService) GetByToken(token string) (*domain.Subscription, error) {
+	args := m.Called(token)
+	sub := args.Get(0)
+	if sub == nil {
+		return nil, args.Error(1)
+	}
+	return sub.(*domain.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionService) ConfirmToken(s *domain.Subscription) string {
+	args := m.Called(s)
+	return args.String(0)
+}
+
+func (m *MockSubscriptionService) GetByConfirmToken(token string) (*domain.Subscription, error) {
+	args := m.Called(token)
+	sub := args.Get(0)
+	if sub == nil {
+		return nil, args.Error(1)
+	}
+	return sub.(*domain.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionService) Confirm(token string) (*domain.Subscription, error) {
+	args := m.Called(token)
+	sub := args.Get(0)
+	if sub == nil {
+		return nil, args.Error(1)
+	}
+	return sub.(*domain.Subscription), args.Error(1)
+}
+
 func (m *MockSubscriptionService) Unsubscribe(token string) error {
 	args := m.Called(token)
 	return args.Error(0)
 }
 
+func (m *MockSubscriptionService) UnsubscribeAll(token string) (int, error) {
+	args := m.Called(token)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSubscriptionService) GlobalUnsubscribeToken(s *domain.Subscription) (string, error) {
+	args := m.Called(s)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSubscriptionService) List(newsletterID uuid.UUID, filter domain.SubscriberFilter, limit int, cursor string) (*domain.SubscriberPage, error) {
+	args := m.Called(newsletterID, filter, limit, cursor)
+	page := args.Get(0)
+	if page == nil {
+		return nil, args.Error(1)
+	}
+	return page.(*domain.SubscriberPage), args.Error(1)
+}
+
+func (m *MockSubscriptionService) UpdateAttributes(newsletterID uuid.UUID, id string, changes map[string]*string) (*domain.Subscription, error) {
+	args := m.Called(newsletterID, id, changes)
+	sub := args.Get(0)
+	if sub == nil {
+		return nil, args.Error(1)
+	}
+	return sub.(*domain.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionService) BulkUnsubscribe(newsletterID uuid.UUID, emails []string, filter *domain.SubscriberFilter, reason string) (int, error) {
+	args := m.Called(newsletterID, emails, filter, reason)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSubscriptionService) CopySubscribers(fromNewsletterID, toNewsletterID uuid.UUID) (int, error) {
+	args := m.Called(fromNewsletterID, toNewsletterID)
+	return args.Int(0), args.Error(1)
+}
+
 // -- Mock email service ---
 
 type MockEmailService struct {
 	mock.Mock
 }
 
-func (m *MockEmailService) Send(email *notifications.Email) error {
+func (m *MockEmailService) Send(ctx context.Context, email *notifications.Email) error {
 	args := m.Called(email)
 	return args.Error(0)
 }
@@ -54,6 +135,41 @@ func (m *MockWorkerPool) Submit(job workerpool.Job) {
 	m.Called(job)
 }
 
+func (m *MockWorkerPool) TrySubmit(job workerpool.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockWorkerPool) SubmitAt(job workerpool.Job, at time.Time) {
+	m.Called(job, at)
+}
+
+func (m *MockWorkerPool) SubmitAfter(job workerpool.Job, delay time.Duration) {
+	m.Called(job, delay)
+}
+
+// Mock captcha verifier
+
+type MockCaptchaVerifier struct {
+	mock.Mock
+}
+
+func (m *MockCaptchaVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	args := m.Called(token, remoteIP)
+	return args.Error(0)
+}
+
+// testNewsletterID is the newsletter subscribed to by the tests.
+var testNewsletterID = uuid.MustParse("7b0c6a4e-3f0d-4f7a-9a39-2f1d5c8e6b21")
+
+// unknownNewsletters returns a newsletter service that finds no newsletter,
+// so that subscriptions use the default settings.
+func unknownNewsletters() *MockNewsletterService {
+	ns := new(MockNewsletterService)
+	ns.On("Get", mock.Anything).Return((*newsletterdomain.Newsletter)(nil), newsletterdomain.ErrNewsletterNotFound)
+	return ns
+}
+
 // Tests
 
 func TestSubscribe_Success(t *testing.T) {
@@ -62,24 +178,26 @@ func TestSubscribe_Success(t *testing.T) {
 	es := new(MockEmailService)
 	wp := new(MockWorkerPool)
 
-	h := NewSubscriptionHandler(ss, es, wp)
+	h := NewSubscriptionHandler(ss, unknownNewsletters(), es, wp, nil, testLinks)
 
 	sub := &domain.Subscription{
 		ID:               "sub-123",
-		NewsletterID:     "news-1",
+		NewsletterID:     testNewsletterID,
 		Email:            "user@test.com",
 		UnsubscribeToken: "token-123",
 		CreatedAt:        time.Now(),
 	}
 
+	ss.On("UnsubscribeToken", mock.Anything).Return("token-123")
 	ss.On("Subscribe", mock.AnythingOfType("*domain.Subscription")).Return(sub, nil)
-	wp.On("Submit", mock.AnythingOfType("*jobs.SendEmailJob")).Return()
+	ss.On("GlobalUnsubscribeToken", mock.Anything).Return("global-token", nil)
+	wp.On("TrySubmit", mock.AnythingOfType("*jobs.SendEmailJob")).Return(nil)
 
 	body := map[string]string{"email": "user@test.com"}
 	payload, _ := json.Marshal(body)
 
-	req := httptest.NewRequest(http.MethodPost, "/subscriptions/news-1", bytes.NewReader(payload))
-	req = mux.SetURLVars(req, map[string]string{"newsletter_id": "news-1"})
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/"+testNewsletterID.String(), bytes.NewReader(payload))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": testNewsletterID.String()})
 
 	rec := httptest.NewRecorder()
 
@@ -102,12 +220,150 @@ func TestSubscribe_Success(t *testing.T) {
 	wp.AssertExpectations(t)
 }
 
+func TestSubscribe_LocalizedConfirmation(t *testing.T) {
+	ss, ns, wp := new(MockSubscriptionService), new(MockNewsletterService), new(MockWorkerPool)
+	h := NewSubscriptionHandler(ss, ns, new(MockEmailService), wp, nil, testLinks)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), Settings: newsletterdomain.Settings{Language: "fr"}}
+	ns.On("Get", newsletter.ID).Return(newsletter, nil)
+	ss.On("UnsubscribeToken", mock.Anything).Return("token-123")
+	ss.On("Subscribe", mock.MatchedBy(func(s *domain.Subscription) bool {
+		return s.Language == "de"
+	})).Return(&domain.Subscription{ID: "sub-1", NewsletterID: newsletter.ID, Email: "user@test.com", Language: "de"}, nil)
+	ss.On("GlobalUnsubscribeToken", mock.Anything).Return("global-token", nil)
+
+	var job *jobs.SendEmailJob
+	wp.On("TrySubmit", mock.AnythingOfType("*jobs.SendEmailJob")).Run(func(args mock.Arguments) {
+		job = args.Get(0).(*jobs.SendEmailJob)
+	}).Return(nil)
+
+	payload, _ := json.Marshal(map[string]string{"email": "user@test.com"})
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/"+newsletter.ID.String(), bytes.NewReader(payload))
+	req.Header.Set("Accept-Language", "de-AT, en;q=0.5")
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletter.ID.String()})
+	rec := httptest.NewRecorder()
+
+	h.Subscribe(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	if assert.NotNil(t, job) {
+		assert.Equal(t, "Bestätigung", job.Email.Subject)
+	}
+}
+
+func TestSubscribe_Honeypot(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	wp := new(MockWorkerPool)
+
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), new(MockEmailService), wp, nil, testLinks)
+
+	payload, _ := json.Marshal(map[string]string{"email": "victim@test.com", "website": "http://spam.example"})
+
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/"+testNewsletterID.String(), bytes.NewReader(payload))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": testNewsletterID.String()})
+	rec := httptest.NewRecorder()
+
+	h.Subscribe(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	ss.AssertNotCalled(t, "Subscribe", mock.Anything)
+	wp.AssertNotCalled(t, "TrySubmit", mock.Anything)
+}
+
+func TestSubscribe_CaptchaFailed(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	cv := new(MockCaptchaVerifier)
+
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), new(MockEmailService), new(MockWorkerPool), cv, testLinks)
+
+	cv.On("Verify", "bad-token", "192.0.2.1").Return(domain.ErrCaptchaFailed)
+
+	payload, _ := json.Marshal(map[string]string{"email": "user@test.com", "captcha_token": "bad-token"})
+
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/"+testNewsletterID.String(), bytes.NewReader(payload))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": testNewsletterID.String()})
+	rec := httptest.NewRecorder()
+
+	h.Subscribe(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	ss.AssertNotCalled(t, "Subscribe", mock.Anything)
+	cv.AssertExpectations(t)
+}
+
+func TestSubscribe_InvalidNewsletterID(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+
+	payload, _ := json.Marshal(map[string]string{"email": "user@test.com"})
+
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/news-1", bytes.NewReader(payload))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": "news-1"})
+	rec := httptest.NewRecorder()
+
+	h.Subscribe(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	ss.AssertNotCalled(t, "Subscribe", mock.Anything)
+}
+
+func TestSubscribe_Cooldown(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	wp := new(MockWorkerPool)
+
+	h := NewSubscriptionHandler(ss, unknownNewsletters(), new(MockEmailService), wp, nil, testLinks)
+
+	ss.On("Subscribe", mock.AnythingOfType("*domain.Subscription")).Return((*domain.Subscription)(nil), domain.ErrSubscribeCooldown)
+
+	payload, _ := json.Marshal(map[string]string{"email": "user@test.com"})
+
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/"+testNewsletterID.String(), bytes.NewReader(payload))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": testNewsletterID.String()})
+	rec := httptest.NewRecorder()
+
+	h.Subscribe(rec, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	wp.AssertNotCalled(t, "TrySubmit", mock.Anything)
+}
+
+func TestSubscribe_InvalidEmail(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	wp := new(MockWorkerPool)
+
+	h := NewSubscriptionHandler(ss, unknownNewsletters(), new(MockEmailService), wp, nil, testLinks)
+
+	rejected := &domain.EmailError{Email: "user@mailinator.com", Reason: domain.EmailReasonDisposable, Detail: "mailinator.com is a disposable email provider"}
+	ss.On("Subscribe", mock.AnythingOfType("*domain.Subscription")).Return((*domain.Subscription)(nil), rejected)
+
+	payload, _ := json.Marshal(map[string]string{"email": "user@mailinator.com"})
+
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/"+testNewsletterID.String(), bytes.NewReader(payload))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": testNewsletterID.String()})
+	req.Header.Set("Accept-Language", "de")
+	rec := httptest.NewRecorder()
+
+	h.Subscribe(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var response EmailErrorResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, EmailErrorResponse{
+		Error:  "Ungültige E-Mail-Adresse.",
+		Reason: domain.EmailReasonDisposable,
+		Detail: "mailinator.com is a disposable email provider",
+	}, response)
+	wp.AssertNotCalled(t, "TrySubmit", mock.Anything)
+}
+
 func TestUnsubscribe_Success(t *testing.T) {
 	ss := new(MockSubscriptionService)
 	es := new(MockEmailService)
 	wp := new(MockWorkerPool)
 
-	h := NewSubscriptionHandler(ss, es, wp)
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), es, wp, nil, testLinks)
 
 	ss.On("Unsubscribe", "token123").Return(nil)
 
@@ -130,7 +386,7 @@ func TestUnsubscribe_Fails_NoToken(t *testing.T) {
 	es := new(MockEmailService)
 	wp := new(MockWorkerPool)
 
-	h := NewSubscriptionHandler(ss, es, wp)
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), es, wp, nil, testLinks)
 
 	req := httptest.NewRequest(http.MethodDelete, "/subscriptions/unsubscribe", nil)
 
@@ -148,20 +404,257 @@ func TestUnsubscribe_Fails(t *testing.T) {
 	es := new(MockEmailService)
 	wp := new(MockWorkerPool)
 
-	h := NewSubscriptionHandler(ss, es, wp)
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), es, wp, nil, testLinks)
 
-	ss.On("Unsubscribe", mock.Anything).Return(errors.New("something went wrong"))
+	ss.On("Unsubscribe", "unknown").Return(domain.ErrSubscriptionNotFound)
+	ss.On("Unsubscribe", "token123").Return(errors.New("something went wrong"))
 
-	req := httptest.NewRequest(http.MethodDelete, "/subscriptions/unsubscribe", nil)
-	query := req.URL.Query()
-	query.Set("token", "token123")
-	req.URL.RawQuery = query.Encode()
+	for token, status := range map[string]int{"unknown": http.StatusNotFound, "token123": http.StatusInternalServerError} {
+		req := httptest.NewRequest(http.MethodDelete, "/subscriptions/unsubscribe?token="+token, nil)
+		rec := httptest.NewRecorder()
+
+		h.Unsubscribe(rec, req)
+
+		assert.Equal(t, status, rec.Code, token)
+	}
+
+	ss.AssertExpectations(t)
+}
+
+func TestUnsubscribeAll_Success(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	es := new(MockEmailService)
+	wp := new(MockWorkerPool)
+
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), es, wp, nil, testLinks)
+
+	ss.On("UnsubscribeAll", "global-token").Return(3, nil)
 
+	req := httptest.NewRequest(http.MethodDelete, "/subscriptions/unsubscribe-all?token=global-token", nil)
 	rec := httptest.NewRecorder()
 
-	h.Unsubscribe(rec, req)
+	h.UnsubscribeAll(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	ss.AssertExpectations(t)
+}
+
+func TestUnsubscribeAll_Fails_NoToken(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	es := new(MockEmailService)
+	wp := new(MockWorkerPool)
 
-	assert.Equal(t, http.StatusNotFound, rec.Code)
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), es, wp, nil, testLinks)
+
+	req := httptest.NewRequest(http.MethodDelete, "/subscriptions/unsubscribe-all", nil)
+	rec := httptest.NewRecorder()
 
+	h.UnsubscribeAll(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	ss.AssertExpectations(t)
+}
+
+func TestUnsubscribeAll_Fails_InvalidToken(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	es := new(MockEmailService)
+	wp := new(MockWorkerPool)
+
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), es, wp, nil, testLinks)
+
+	ss.On("UnsubscribeAll", "forged").Return(0, domain.ErrInvalidToken)
+
+	req := httptest.NewRequest(http.MethodDelete, "/subscriptions/unsubscribe-all?token=forged", nil)
+	rec := httptest.NewRecorder()
+
+	h.UnsubscribeAll(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	ss.AssertExpectations(t)
+}
+
+func TestListSubscribers_Success(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	ns := new(MockNewsletterService)
+
+	h := NewSubscriptionHandler(ss, ns, new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	ns.On("Get", newsletterID).Return(&newsletterdomain.Newsletter{ID: newsletterID, OwnerID: ownerID}, nil)
+
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := domain.SubscriberFilter{Status: domain.StatusActive, Tag: "vip", SubscribedAfter: after}
+	page := &domain.SubscriberPage{
+		Subscriptions: []*domain.Subscription{{ID: "sub-1", NewsletterID: newsletterID, Email: "user@test.com"}},
+		NextCursor:    "next",
+	}
+	ss.On("List", newsletterID, filter, 20, "cursor-1").Return(page, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/newsletters/"+newsletterID.String()+"/subscribers?status=active&tag=vip&subscribed_after=2026-01-01T00:00:00Z&limit=20&cursor=cursor-1", nil)
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String()})
+	req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+	rec := httptest.NewRecorder()
+
+	h.ListSubscribers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp domain.SubscriberPage
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Len(t, resp.Subscriptions, 1)
+	assert.Equal(t, "next", resp.NextCursor)
+
+	ss.AssertExpectations(t)
+	ns.AssertExpectations(t)
+}
+
+func TestListSubscribers_EmailPrefix(t *testing.T) {
+	ss, ns := new(MockSubscriptionService), new(MockNewsletterService)
+	h := NewSubscriptionHandler(ss, ns, new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	ns.On("Get", newsletterID).Return(&newsletterdomain.Newsletter{ID: newsletterID, OwnerID: ownerID}, nil)
+	ss.On("List", newsletterID, domain.SubscriberFilter{EmailPrefix: "jane"}, 0, "").Return(&domain.SubscriberPage{}, nil)
+
+	list := func(query string) int {
+		req := httptest.NewRequest(http.MethodGet, "/newsletters/"+newsletterID.String()+"/subscribers?"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String()})
+		req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+		rec := httptest.NewRecorder()
+		h.ListSubscribers(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, list("q=jane"))
+	assert.Equal(t, http.StatusBadRequest, list("q=jane&subscribed_after=2026-01-01T00:00:00Z"))
+	ss.AssertNumberOfCalls(t, "List", 1)
+}
+
+func TestListSubscribers_InvalidCursor(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	ns := new(MockNewsletterService)
+
+	h := NewSubscriptionHandler(ss, ns, new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	ns.On("Get", newsletterID).Return(&newsletterdomain.Newsletter{ID: newsletterID, OwnerID: ownerID}, nil)
+	ss.On("List", newsletterID, domain.SubscriberFilter{}, 0, "bad").Return(nil, pagination.ErrInvalidCursor)
+
+	req := httptest.NewRequest(http.MethodGet, "/newsletters/"+newsletterID.String()+"/subscribers?cursor=bad", nil)
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String()})
+	req = req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+	rec := httptest.NewRecorder()
+
+	h.ListSubscribers(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSubscribe_Attributes(t *testing.T) {
+	ss, wp := new(MockSubscriptionService), new(MockWorkerPool)
+	h := NewSubscriptionHandler(ss, unknownNewsletters(), new(MockEmailService), wp, nil, testLinks)
+
+	ss.On("UnsubscribeToken", mock.Anything).Return("token-123")
+	ss.On("Subscribe", mock.MatchedBy(func(s *domain.Subscription) bool {
+		return s.Attributes["first_name"] == "Ada" && s.Attributes["source"] == "landing-page"
+	})).Return(&domain.Subscription{ID: "sub-1", NewsletterID: testNewsletterID, Email: "ada@test.com"}, nil)
+	ss.On("GlobalUnsubscribeToken", mock.Anything).Return("global-token", nil)
+	wp.On("TrySubmit", mock.AnythingOfType("*jobs.SendEmailJob")).Return(nil)
+
+	payload := `{"email":"ada@test.com","attributes":{"first_name":"Ada","source":"landing-page"}}`
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/"+testNewsletterID.String(), strings.NewReader(payload))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": testNewsletterID.String()})
+	rec := httptest.NewRecorder()
+
+	h.Subscribe(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	ss.AssertExpectations(t)
+}
+
+// attributesRequest builds a request editing the attributes of the
+// subscription sub-1 of a newsletter owned by ownerID.
+func attributesRequest(newsletterID, ownerID uuid.UUID, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/newsletters/"+newsletterID.String()+"/subscribers/sub-1/attributes", strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String(), "subscription_id": "sub-1"})
+	return req.WithContext(contextWithUserID(req.Context(), ownerID.String()))
+}
+
+func TestUpdateAttributes_Success(t *testing.T) {
+	ss, ns := new(MockSubscriptionService), new(MockNewsletterService)
+	h := NewSubscriptionHandler(ss, ns, new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	ns.On("Get", newsletterID).Return(&newsletterdomain.Newsletter{ID: newsletterID, OwnerID: ownerID}, nil)
+	name := "Ada"
+	updated := &domain.Subscription{ID: "sub-1", NewsletterID: newsletterID, Email: "ada@test.com", Attributes: map[string]string{"first_name": "Ada"}}
+	ss.On("UpdateAttributes", newsletterID, "sub-1", map[string]*string{"first_name": &name, "source": nil}).Return(updated, nil)
+
+	rec := httptest.NewRecorder()
+	h.UpdateAttributes(rec, attributesRequest(newsletterID, ownerID, `{"attributes":{"first_name":"Ada","source":null}}`))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var got domain.Subscription
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, updated.Attributes, got.Attributes)
+	ss.AssertExpectations(t)
+}
+
+func TestUpdateAttributes_Failures(t *testing.T) {
+	ss, ns := new(MockSubscriptionService), new(MockNewsletterService)
+	h := NewSubscriptionHandler(ss, ns, new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	ns.On("Get", newsletterID).Return(&newsletterdomain.Newsletter{ID: newsletterID, OwnerID: ownerID}, nil)
+	ss.On("UpdateAttributes", newsletterID, "sub-1", mock.MatchedBy(func(changes map[string]*string) bool { _, ok := changes["Bad Key"]; return ok })).
+		Return(nil, domain.ErrInvalidAttributes)
+	ss.On("UpdateAttributes", newsletterID, "sub-1", mock.MatchedBy(func(changes map[string]*string) bool { _, ok := changes["plan"]; return ok })).
+		Return(nil, domain.ErrSubscriptionNotFound)
+
+	tests := []struct {
+		body string
+		code int
+	}{
+		{`{"attributes":{}}`, http.StatusBadRequest},
+		{`{"attributes":{"Bad Key":"x"}}`, http.StatusBadRequest},
+		{`{"attributes":{"plan":"pro"}}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.UpdateAttributes(rec, attributesRequest(newsletterID, ownerID, tt.body))
+		assert.Equal(t, tt.code, rec.Code, tt.body)
+	}
+}
+
+func TestBulkUnsubscribe(t *testing.T) {
+	ss, ns := new(MockSubscriptionService), new(MockNewsletterService)
+	h := NewSubscriptionHandler(ss, ns, new(MockEmailService), new(MockWorkerPool), nil, testLinks)
+
+	ownerID, newsletterID := uuid.New(), uuid.New()
+	ns.On("Get", newsletterID).Return(&newsletterdomain.Newsletter{ID: newsletterID, OwnerID: ownerID}, nil)
+	ss.On("BulkUnsubscribe", newsletterID, []string{"a@example.com", "b@example.com"}, (*domain.SubscriberFilter)(nil), "bounced").Return(2, nil)
+	ss.On("BulkUnsubscribe", newsletterID, []string{}, &domain.SubscriberFilter{Tag: "imported"}, "cleanup").Return(5, nil)
+	ss.On("BulkUnsubscribe", newsletterID, []string{"a@example.com"}, (*domain.SubscriberFilter)(nil), "boredom").Return(0, domain.ErrInvalidUnsubscribeReason)
+
+	tests := []struct {
+		body string
+		code int
+		want string
+	}{
+		{`{"emails":[" a@example.com","b@example.com",""],"reason":"bounced"}`, http.StatusOK, `{"unsubscribed":2}`},
+		{`{"filter":{"tag":"imported"},"reason":"cleanup"}`, http.StatusOK, `{"unsubscribed":5}`},
+		{`{"emails":["a@example.com"],"reason":"boredom"}`, http.StatusBadRequest, ""},
+		{`{"filter":{"email_prefix":"test-","subscribed_after":"2026-01-01T00:00:00Z"},"reason":"cleanup"}`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/newsletters/"+newsletterID.String()+"/subscriptions/bulk-unsubscribe", strings.NewReader(tt.body))
+		req = mux.SetURLVars(req, map[string]string{"newsletter_id": newsletterID.String()})
+		rec := httptest.NewRecorder()
+		h.BulkUnsubscribe(rec, req.WithContext(contextWithUserID(req.Context(), ownerID.String())))
+
+		assert.Equal(t, tt.code, rec.Code, tt.body)
+		if tt.want != "" {
+			assert.JSONEq(t, tt.want, rec.Body.String())
+		}
+	}
 	ss.AssertExpectations(t)
 }
@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/users/domain"
+)
+
+// TwoFactorCodeRequest carries a TOTP code, or a recovery code where
+// accepted.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorSigninRequest represents the payload of the second sign in step.
+type TwoFactorSigninRequest struct {
+	ChallengeToken string `json:"challenge_token"` // Token returned by the first step
+	Code           string `json:"code"`            // TOTP or recovery code
+}
+
+// TwoFactorChallengeResponse is returned by the first sign in step of
+// accounts with two-factor authentication.
+type TwoFactorChallengeResponse struct {
+	Status         string `json:"status"`
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// writeChallenge answers the first sign in step of user, whose account
+// requires a TOTP code, with a challenge to complete.
+func (uh *UserHandler) writeChallenge(w http.ResponseWriter, r *http.Request, user *domain.User) {
+	challenge, err := uh.tf.Challenge(user)
+	if err != nil {
+		slog.Error("failed to create sign in challenge", "user_id", user.ID.String(), "error", err)
+		WriteError(w, r, err, "failed to create sign in challenge")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	response := TwoFactorChallengeResponse{Status: "two_factor_required", ChallengeToken: challenge}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode challenge response", "user_id", user.ID.String(), "error", err)
+	}
+}
+
+// decodeCode decodes the code of a TwoFactorCodeRequest, answering 400 Bad
+// Request when it is missing.
+func decodeCode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var request TwoFactorCodeRequest
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
+		return "", false
+	}
+	if request.Code == "" {
+		http.Error(w, "invalid request payload: code is required", http.StatusBadRequest)
+		return "", false
+	}
+	return request.Code, true
+}
+
+// EnrollTwoFactor handles starting the enrollment of the authenticated user
+// in two-factor authentication.
+//
+// Route:
+//
+//	POST /users/me/2fa/enroll
+//
+// Description:
+//
+//	Generates a TOTP secret and 10 single-use recovery codes. The
+//	provisioning URI is rendered as a QR code for authenticator apps; the
+//	secret and recovery codes are only shown once. Two-factor
+//	authentication is enabled by POST /users/me/2fa/verify. Enrolling again
+//	before verifying replaces the pending secret.
+//
+// Responses:
+//
+//	201 Created
+//	  {
+//	    "secret": "JBSWY3DPEHPK3PXP...",
+//	    "provisioning_uri": "otpauth://totp/Newsletter:user%40example.com?...",
+//	    "recovery_codes": ["abcd-efgh", ...]
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid user ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	409 Conflict
+//	  - Two-factor authentication is already enabled
+//
+//	500 Internal Server Error
+//	  - Enrollment failure
+//
+//	501 Not Implemented
+//	  - No TOTP encryption key is configured
+//
+// Side Effects:
+//   - Stores the encrypted TOTP secret and the hashes of the recovery codes
+func (uh *UserHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(domain.UserEmail).(string)
+
+	enrollment, err := uh.tf.Enroll(userID, email)
+	if err != nil {
+		WriteError(w, r, err, "failed to enroll in two-factor authentication")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(enrollment); err != nil {
+		slog.Error("failed to encode enrollment response", "user_id", userID, "error", err)
+	}
+}
+
+// VerifyTwoFactor handles enabling two-factor authentication.
+//
+// Route:
+//
+//	POST /users/me/2fa/verify
+//
+// Description:
+//
+//	Enables two-factor authentication of the authenticated user when the
+//	code is the current TOTP code of the enrolled secret, proving that the
+//	authenticator app is set up. Later sign ins require a code.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "code": "123456"
+//	}
+//
+// Responses:
+//
+//	204 No Content
+//
+//	400 Bad Request
+//	  - Invalid JSON payload or missing code
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//	  - Invalid code
+//
+//	409 Conflict
+//	  - Not enrolled, or already enabled
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	429 Too Many Requests
+//	  - Too many invalid codes for the account
+//
+//	501 Not Implemented
+//	  - No TOTP encryption key is configured
+//
+// Side Effects:
+//   - Enables two-factor authentication
+//   - Records a "two_factor_enabled" security event
+func (uh *UserHandler) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := uh.tf.Verify(userID, code); err != nil {
+		WriteError(w, r, err, "failed to enable two-factor authentication")
+		return
+	}
+
+	email, _ := r.Context().Value(domain.UserEmail).(string)
+	uh.recordSecurityEvent(r, domain.SecurityEventTwoFactorOn, userID, email)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DisableTwoFactor handles disabling two-factor authentication.
+//
+// Route:
+//
+//	POST /users/me/2fa/disable
+//
+// Description:
+//
+//	Disables two-factor authentication of the authenticated user when the
+//	code is a current TOTP code or an unused recovery code. The secret and
+//	remaining recovery codes are deleted.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "code": "123456"
+//	}
+//
+// Responses:
+//
+//	204 No Content
+//
+//	400 Bad Request
+//	  - Invalid JSON payload or missing code
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//	  - Invalid code
+//
+//	409 Conflict
+//	  - Two-factor authentication is not enabled
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	429 Too Many Requests
+//	  - Too many invalid codes for the account
+//
+//	501 Not Implemented
+//	  - No TOTP encryption key is configured
+//
+// Side Effects:
+//   - Disables two-factor authentication
+//   - Records a "two_factor_disabled" security event
+func (uh *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := uh.tf.Disable(userID, code); err != nil {
+		WriteError(w, r, err, "failed to disable two-factor authentication")
+		return
+	}
+
+	email, _ := r.Context().Value(domain.UserEmail).(string)
+	uh.recordSecurityEvent(r, domain.SecurityEventTwoFactorOff, userID, email)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SigninTwoFactor handles the second step of the sign in of accounts with
+// two-factor authentication.
+//
+// Route:
+//
+//	POST /users/signin/2fa
+//
+// Description:
+//
+//	Completes a sign in started by POST /users/signin or
+//	GET /users/magic-login with a TOTP code or an unused recovery code. The
+//	challenge token expires after 5 minutes.
+//
+//	A TOTP code is accepted once: it is rejected after it, or a code of a
+//	later period, signed the account in. After 5 invalid codes within 5
+//	minutes for the account or the challenge, codes are refused for 15
+//	minutes.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "challenge_token": "...",
+//	  "code": "123456"
+//	}
+//
+// Responses:
+//
+//	200 OK
+//	  Headers:
+//	    Authorization: Bearer <access_token>
+//	  Body:
+//	    {
+//	      "id": "uuid",
+//	      "email": "user@example.com",
+//	      "created_at": "2026-01-10T12:00:00Z"
+//	    }
+//
+//	400 Bad Request
+//	  - Invalid JSON payload
+//
+//	401 Unauthorized
+//	  - Invalid or expired challenge token
+//	  - Invalid or already used code
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	429 Too Many Requests
+//	  - Too many invalid codes for the account or the challenge
+//
+//	500 Internal Server Error
+//	  - Token generation failure
+//
+// Side Effects:
+//   - Consumes the recovery code, if one is used
+//   - Records a "signin" security event
+//   - Generates a new access token
+func (uh *UserHandler) SigninTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var request TwoFactorSigninRequest
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
+		return
+	}
+
+	user, err := uh.tf.Complete(request.ChallengeToken, request.Code)
+	if err != nil {
+		WriteError(w, r, err, "failed to sign in")
+		return
+	}
+
+	uh.recordSecurityEvent(r, domain.SecurityEventSignin, user.ID, user.Email)
+
+	uh.writeSignin(w, user)
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/users/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTwoFactorService mocks domain.TwoFactorService
+type MockTwoFactorService struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorService) Enroll(userID uuid.UUID, email string) (*domain.TwoFactorEnrollment, error) {
+	args := m.Called(userID, email)
+	enrollment, _ := args.Get(0).(*domain.TwoFactorEnrollment)
+	return enrollment, args.Error(1)
+}
+
+func (m *MockTwoFactorService) Verify(userID uuid.UUID, code string) error {
+	return m.Called(userID, code).Error(0)
+}
+
+func (m *MockTwoFactorService) Disable(userID uuid.UUID, code string) error {
+	return m.Called(userID, code).Error(0)
+}
+
+func (m *MockTwoFactorService) Challenge(user *domain.User) (string, error) {
+	args := m.Called(user)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTwoFactorService) Complete(challenge, code string) (*domain.User, error) {
+	args := m.Called(challenge, code)
+	user, _ := args.Get(0).(*domain.User)
+	return user, args.Error(1)
+}
+
+func TestUserHandler_Signin_TwoFactorChallenge(t *testing.T) {
+	mockAS := new(MockAuthService)
+	mockSE := new(MockSecurityEventService)
+	mockTF := new(MockTwoFactorService)
+	handler := &UserHandler{as: mockAS, se: mockSE, tf: mockTF}
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", TOTPEnabled: true}
+	mockAS.On("Authenticate", "test@example.com", "password123").Return(user, nil)
+	mockTF.On("Challenge", user).Return("challenge123", nil)
+
+	body, _ := json.Marshal(LoginRequest{Email: "test@example.com", Password: "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/users/signin", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.Signin(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Header().Get("Authorization"))
+
+	var response TwoFactorChallengeResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	assert.Equal(t, "two_factor_required", response.Status)
+	assert.Equal(t, "challenge123", response.ChallengeToken)
+	mockAS.AssertNotCalled(t, "GenerateAccessToken", mock.Anything)
+	mockSE.AssertNotCalled(t, "Record", mock.Anything)
+}
+
+func TestUserHandler_SigninTwoFactor_Success(t *testing.T) {
+	mockAS := new(MockAuthService)
+	mockSE := new(MockSecurityEventService)
+	mockTF := new(MockTwoFactorService)
+	handler := &UserHandler{as: mockAS, se: mockSE, tf: mockTF}
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", TOTPEnabled: true}
+	mockTF.On("Complete", "challenge123", "123456").Return(user, nil)
+	mockSE.On("Record", recordedEvent(domain.SecurityEventSignin)).Return()
+	mockAS.On("GenerateAccessToken", user).Return("token123", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/signin/2fa", bytes.NewBufferString(`{"challenge_token":"challenge123","code":"123456"}`))
+	w := httptest.NewRecorder()
+
+	handler.SigninTwoFactor(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Bearer token123", w.Header().Get("Authorization"))
+	mockSE.AssertExpectations(t)
+}
+
+func TestUserHandler_SigninTwoFactor_InvalidCode(t *testing.T) {
+	mockTF := new(MockTwoFactorService)
+	handler := &UserHandler{tf: mockTF}
+
+	mockTF.On("Complete", "challenge123", "000000").Return(nil, domain.ErrInvalidTwoFactorCode)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/signin/2fa", bytes.NewBufferString(`{"challenge_token":"challenge123","code":"000000"}`))
+	w := httptest.NewRecorder()
+
+	handler.SigninTwoFactor(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestUserHandler_EnrollTwoFactor(t *testing.T) {
+	mockTF := new(MockTwoFactorService)
+	handler := &UserHandler{tf: mockTF}
+
+	userID := uuid.New()
+	enrollment := &domain.TwoFactorEnrollment{Secret: "SECRET", ProvisioningURI: "otpauth://totp/x", RecoveryCodes: []string{"abcd-efgh"}}
+	mockTF.On("Enroll", userID, "test@example.com").Return(enrollment, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/me/2fa/enroll", nil)
+	ctx := context.WithValue(contextWithUserID(req.Context(), userID.String()), domain.UserEmail, "test@example.com")
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	handler.EnrollTwoFactor(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var response domain.TwoFactorEnrollment
+	json.NewDecoder(w.Body).Decode(&response)
+	assert.Equal(t, *enrollment, response)
+}
+
+func TestUserHandler_EnrollTwoFactor_NotConfigured(t *testing.T) {
+	mockTF := new(MockTwoFactorService)
+	handler := &UserHandler{tf: mockTF}
+
+	userID := uuid.New()
+	mockTF.On("Enroll", userID, "").Return(nil, domain.ErrTwoFactorNotConfigured)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/me/2fa/enroll", nil)
+	req = req.WithContext(contextWithUserID(req.Context(), userID.String()))
+	w := httptest.NewRecorder()
+
+	handler.EnrollTwoFactor(w, req)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestUserHandler_VerifyTwoFactor_RecordsEvent(t *testing.T) {
+	mockSE := new(MockSecurityEventService)
+	mockTF := new(MockTwoFactorService)
+	handler := &UserHandler{se: mockSE, tf: mockTF}
+
+	userID := uuid.New()
+	mockTF.On("Verify", userID, "123456").Return(nil)
+	mockSE.On("Record", recordedEvent(domain.SecurityEventTwoFactorOn)).Return()
+
+	req := httptest.NewRequest(http.MethodPost, "/users/me/2fa/verify", bytes.NewBufferString(`{"code":"123456"}`))
+	req = req.WithContext(contextWithUserID(req.Context(), userID.String()))
+	w := httptest.NewRecorder()
+
+	handler.VerifyTwoFactor(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockSE.AssertExpectations(t)
+}
+
+func TestUserHandler_DisableTwoFactor_MissingCode(t *testing.T) {
+	mockTF := new(MockTwoFactorService)
+	handler := &UserHandler{tf: mockTF}
+
+	req := httptest.NewRequest(http.MethodPost, "/users/me/2fa/disable", bytes.NewBufferString(`{}`))
+	req = req.WithContext(contextWithUserID(req.Context(), uuid.NewString()))
+	w := httptest.NewRecorder()
+
+	handler.DisableTwoFactor(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockTF.AssertNotCalled(t, "Disable", mock.Anything, mock.Anything)
+}
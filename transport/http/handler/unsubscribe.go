@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"errors"
+	"html"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"newsletter/internal/infrastructure/i18n"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	notifications "newsletter/internal/notifications/domain"
+	"newsletter/internal/subscriptions/domain"
+	"strings"
+)
+
+// defaultBrandColor is the accent color of the unsubscribe pages of
+// newsletters without a brand color.
+const defaultBrandColor = "#333333"
+
+// unsubscribePage renders the pages shown to subscribers following the
+// unsubscribe or confirmation link of an email. The form posts back to the
+// same URL.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 32rem; margin: 4rem auto; padding: 0 1rem; text-align: center; color: #222; }
+img { max-height: 4rem; margin-bottom: 1rem; }
+h1 { color: {{.Color}}; font-size: 1.5rem; }
+button { background: {{.Color}}; color: #fff; border: 0; border-radius: 4px; padding: .75rem 1.5rem; font-size: 1rem; cursor: pointer; }
+</style>
+</head>
+<body>
+{{if .LogoURL}}<img src="{{.LogoURL}}" alt="{{.Newsletter}}">{{end}}
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .Button}}<form method="post"><button type="submit">{{.Button}}</button></form>{{end}}
+</body>
+</html>
+`))
+
+// unsubscribePageData is the content of an unsubscribe page. Button is empty
+// on pages without a form.
+type unsubscribePageData struct {
+	Language   string
+	Title      string
+	Newsletter string
+	LogoURL    string
+	Color      string
+	Message    string
+	Button     string
+
+	redirectURL string // Where to go once unsubscribed, if configured
+}
+
+// UnsubscribePage shows the branded page confirming an unsubscription.
+//
+// Route:
+//
+//	GET /subscriptions/unsubscribe?token=<unsubscribe_token>
+//
+// Description:
+//
+//	Landing page of the unsubscribe link included in every email. It asks
+//	the subscriber to confirm with a button posting to UnsubscribeConfirm,
+//	so that link scanners following the link do not unsubscribe anyone.
+//	The page shows the logo and brand color of the newsletter, in the
+//	language of the subscription.
+//
+// Responses:
+//
+//	200 OK
+//	  - HTML confirmation page, or a notice if already unsubscribed
+//
+//	400 Bad Request
+//	  - Missing token
+//
+//	404 Not Found
+//	  - No subscription matches the token
+//
+//	410 Gone
+//	  - The token has expired
+func (sh *SubscriptionHandler) UnsubscribePage(w http.ResponseWriter, r *http.Request) {
+	subscription, page, ok := sh.tokenTarget(w, r, sh.ss.GetByToken, unsubscribeMessages)
+	if !ok {
+		return
+	}
+
+	data := map[string]any{"Email": subscription.Email, "Newsletter": page.Newsletter}
+	localizer := i18n.New(page.Language)
+	if subscription.IsActive() {
+		page.Message = localizer.T("UnsubscribeConfirm", data)
+		page.Button = localizer.T("UnsubscribeButton", nil)
+	} else {
+		page.Message = localizer.T("UnsubscribeDone", data)
+	}
+
+	renderUnsubscribePage(w, http.StatusOK, page)
+}
+
+// UnsubscribeConfirm unsubscribes from a newsletter through its branded page.
+//
+// Route:
+//
+//	POST /subscriptions/unsubscribe?token=<unsubscribe_token>
+//
+// Description:
+//
+//	Submitted by the button of UnsubscribePage, and by mail clients
+//	supporting one-click unsubscription. The subscription is marked as
+//	unsubscribed, then the subscriber is redirected to the unsubscribe
+//	redirect URL of the newsletter or, when none is configured, shown a
+//	branded confirmation page. Unsubscribing twice is not an error.
+//
+// Responses:
+//
+//	303 See Other
+//	  - Location: the unsubscribe redirect URL of the newsletter
+//
+//	200 OK
+//	  - HTML confirmation page
+//
+//	400 Bad Request
+//	  - Missing token
+//
+//	404 Not Found
+//	  - No subscription matches the token
+//
+//	410 Gone
+//	  - The token has expired
+//
+//	500 Internal Server Error
+//	  - Unsubscription failure
+//
+// Side Effects:
+//   - Marks the subscription as unsubscribed
+func (sh *SubscriptionHandler) UnsubscribeConfirm(w http.ResponseWriter, r *http.Request) {
+	subscription, page, ok := sh.tokenTarget(w, r, sh.ss.GetByToken, unsubscribeMessages)
+	if !ok {
+		return
+	}
+
+	localizer := i18n.New(page.Language)
+	if subscription.IsActive() {
+		err := sh.ss.Unsubscribe(r.URL.Query().Get("token"))
+		if err != nil && !errors.Is(err, domain.ErrSubscriptionNotFound) {
+			slog.Error("failed to unsubscribe", "newsletter_id", subscription.NewsletterID, "error", err)
+			page.Message = localizer.T("UnsubscribeFailed", nil)
+			renderUnsubscribePage(w, http.StatusInternalServerError, page)
+			return
+		}
+	}
+
+	if page.redirectURL != "" {
+		http.Redirect(w, r, page.redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	page.Message = localizer.T("UnsubscribeDone", map[string]any{"Email": subscription.Email, "Newsletter": page.Newsletter})
+	renderUnsubscribePage(w, http.StatusOK, page)
+}
+
+// tokenPageMessages are the IDs of the messages of the pages of a link
+// carrying a token, such as an unsubscribe link.
+type tokenPageMessages struct {
+	Title   string
+	Invalid string // The token is missing or matches no subscription
+	Expired string
+}
+
+// unsubscribeMessages are the messages of the unsubscribe pages.
+var unsubscribeMessages = tokenPageMessages{Title: "UnsubscribeTitle", Invalid: "UnsubscribeInvalid", Expired: "UnsubscribeExpired"}
+
+// tokenTarget returns the subscription that lookup finds for the token of
+// the request and a page branded after its newsletter, titled with
+// messages. Otherwise it writes an error page and returns false.
+func (sh *SubscriptionHandler) tokenTarget(w http.ResponseWriter, r *http.Request, lookup func(token string) (*domain.Subscription, error), messages tokenPageMessages) (*domain.Subscription, unsubscribePageData, bool) {
+	localizer := i18n.New(i18n.Match(r.Header.Get("Accept-Language")))
+	page := unsubscribePageData{
+		Language: localizer.Language(),
+		Title:    localizer.T(messages.Title, nil),
+		Color:    defaultBrandColor,
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		page.Message = localizer.T(messages.Invalid, nil)
+		renderUnsubscribePage(w, http.StatusBadRequest, page)
+		return nil, page, false
+	}
+
+	subscription, err := lookup(token)
+	if err != nil {
+		status, message := http.StatusInternalServerError, messages.Invalid
+		switch {
+		case errors.Is(err, domain.ErrSubscriptionNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, domain.ErrTokenExpired):
+			status, message = http.StatusGone, messages.Expired
+		}
+		page.Message = localizer.T(message, nil)
+		renderUnsubscribePage(w, status, page)
+		return nil, page, false
+	}
+
+	// The newsletter may be unavailable: the default branding is used then.
+	defaultLanguage := ""
+	newsletter := sh.newsletter(subscription.NewsletterID)
+	if newsletter != nil {
+		defaultLanguage = newsletter.Language
+	}
+
+	localizer = i18n.New(i18n.Match(subscription.Language, r.Header.Get("Accept-Language"), defaultLanguage))
+	page.Language = localizer.Language()
+	page.Title = localizer.T(messages.Title, nil)
+	page.Newsletter = localizer.T("ThisNewsletter", nil)
+	if newsletter != nil {
+		page.Newsletter = newsletter.Name
+		page.LogoURL = newsletter.LogoURL
+		page.redirectURL = newsletter.UnsubscribeRedirectURL
+		if newsletter.BrandColor != "" {
+			page.Color = newsletter.BrandColor
+		}
+	}
+
+	return subscription, page, true
+}
+
+// renderUnsubscribePage writes page with the given status code.
+func renderUnsubscribePage(w http.ResponseWriter, status int, page unsubscribePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", page.Language)
+	w.WriteHeader(status)
+	if err := unsubscribePage.Execute(w, page); err != nil {
+		slog.Error("failed to render unsubscribe page", "error", err)
+	}
+}
+
+// appendFooter adds the custom footer text of a newsletter to both bodies of
+// email. The footer is plain text; its line breaks are kept in the HTML body.
+func appendFooter(email *notifications.Email, newsletter *newsletterdomain.Newsletter) {
+	if newsletter == nil || newsletter.FooterText == "" {
+		return
+	}
+
+	email.Text += "\n\n" + newsletter.FooterText
+	email.HTML += "\n<p>" + strings.ReplaceAll(html.EscapeString(newsletter.FooterText), "\n", "<br>") + "</p>"
+}
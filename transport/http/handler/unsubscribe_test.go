@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	notifications "newsletter/internal/notifications/domain"
+	"newsletter/internal/subscriptions/domain"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUnsubscribePage_Branded(t *testing.T) {
+	ss, ns := new(MockSubscriptionService), new(MockNewsletterService)
+	h := NewSubscriptionHandler(ss, ns, nil, nil, nil, testLinks)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), Name: "Weekly"}
+	newsletter.LogoURL = "https://example.com/logo.png"
+	newsletter.BrandColor = "#0055ff"
+	ss.On("GetByToken", "token-1").Return(&domain.Subscription{
+		NewsletterID: newsletter.ID, Email: "user@test.com", Status: domain.StatusActive, Language: "fr",
+	}, nil)
+	ns.On("Get", newsletter.ID).Return(newsletter, nil)
+
+	rec := httptest.NewRecorder()
+	h.UnsubscribePage(rec, httptest.NewRequest(http.MethodGet, "/subscriptions/unsubscribe?token=token-1", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "fr", rec.Header().Get("Content-Language"))
+	assert.Contains(t, rec.Body.String(), `<img src="https://example.com/logo.png" alt="Weekly">`)
+	assert.Contains(t, rec.Body.String(), "#0055ff")
+	assert.Contains(t, rec.Body.String(), `<form method="post">`)
+	ss.AssertNotCalled(t, "Unsubscribe", mock.Anything)
+}
+
+func TestUnsubscribePage_UnknownToken(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), nil, nil, nil, testLinks)
+
+	ss.On("GetByToken", "nope").Return(nil, domain.ErrSubscriptionNotFound)
+
+	rec := httptest.NewRecorder()
+	h.UnsubscribePage(rec, httptest.NewRequest(http.MethodGet, "/subscriptions/unsubscribe?token=nope", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "<form")
+}
+
+func TestUnsubscribePage_ExpiredToken(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	h := NewSubscriptionHandler(ss, new(MockNewsletterService), nil, nil, nil, testLinks)
+
+	ss.On("GetByToken", "old").Return(nil, domain.ErrTokenExpired)
+
+	rec := httptest.NewRecorder()
+	h.UnsubscribePage(rec, httptest.NewRequest(http.MethodGet, "/subscriptions/unsubscribe?token=old", nil))
+
+	assert.Equal(t, http.StatusGone, rec.Code)
+	assert.Contains(t, rec.Body.String(), "This unsubscribe link has expired.")
+}
+
+func TestUnsubscribeConfirm_Redirect(t *testing.T) {
+	ss, ns := new(MockSubscriptionService), new(MockNewsletterService)
+	h := NewSubscriptionHandler(ss, ns, nil, nil, nil, testLinks)
+
+	newsletter := &newsletterdomain.Newsletter{ID: uuid.New(), Name: "Weekly"}
+	newsletter.UnsubscribeRedirectURL = "https://example.com/goodbye"
+	ss.On("GetByToken", "token-1").Return(&domain.Subscription{
+		NewsletterID: newsletter.ID, Email: "user@test.com", Status: domain.StatusActive, UnsubscribeToken: "token-1",
+	}, nil)
+	ss.On("Unsubscribe", "token-1").Return(nil)
+	ns.On("Get", newsletter.ID).Return(newsletter, nil)
+
+	rec := httptest.NewRecorder()
+	h.UnsubscribeConfirm(rec, httptest.NewRequest(http.MethodPost, "/subscriptions/unsubscribe?token=token-1", nil))
+
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "https://example.com/goodbye", rec.Header().Get("Location"))
+	ss.AssertExpectations(t)
+}
+
+func TestUnsubscribeConfirm_AlreadyUnsubscribed(t *testing.T) {
+	ss := new(MockSubscriptionService)
+	h := NewSubscriptionHandler(ss, unknownNewsletters(), nil, nil, nil, testLinks)
+
+	ss.On("GetByToken", "token-1").Return(&domain.Subscription{
+		NewsletterID: uuid.New(), Email: "user@test.com", Status: domain.StatusUnsubscribed, UnsubscribeToken: "token-1",
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.UnsubscribeConfirm(rec, httptest.NewRequest(http.MethodPost, "/subscriptions/unsubscribe?token=token-1", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "user@test.com has been unsubscribed from this newsletter.")
+	ss.AssertNotCalled(t, "Unsubscribe", mock.Anything)
+}
+
+func TestAppendFooter(t *testing.T) {
+	email := notifications.Email{Text: "Hello", HTML: "<p>Hello</p>"}
+	newsletter := &newsletterdomain.Newsletter{}
+	newsletter.FooterText = "ACME <Inc>\n1 Main St"
+
+	appendFooter(&email, newsletter)
+
+	assert.Equal(t, "Hello\n\nACME <Inc>\n1 Main St", email.Text)
+	assert.Equal(t, "<p>Hello</p>\n<p>ACME &lt;Inc&gt;<br>1 Main St</p>", email.HTML)
+}
@@ -2,9 +2,17 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"html"
 	"log/slog"
 	"net/http"
+	"newsletter/internal/infrastructure/i18n"
+	"newsletter/internal/infrastructure/pagination"
+	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/internal/infrastructure/workerpool/jobs"
+	notifications "newsletter/internal/notifications/domain"
 	"newsletter/internal/users/domain"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,11 +23,32 @@ import (
 type UserHandler struct {
 	us domain.UserService
 	as domain.AuthenticationService
+	se domain.SecurityEventService
+	ml domain.MagicLinkService
+	tf domain.TwoFactorService
+
+	es    notifications.EmailService
+	wp    workerpool.JobSubmiter
+	links *LinkBuilder
+}
+
+// NewUserHandler creates a new UserHandler. Passwordless sign in links are
+// built by links and emailed with es through the worker pool wp; sign ins of
+// accounts with two-factor authentication are completed with tf.
+func NewUserHandler(us domain.UserService, as domain.AuthenticationService, se domain.SecurityEventService, ml domain.MagicLinkService, tf domain.TwoFactorService, es notifications.EmailService, wp workerpool.JobSubmiter, links *LinkBuilder) *UserHandler {
+	return &UserHandler{us: us, as: as, se: se, ml: ml, tf: tf, es: es, wp: wp, links: links}
 }
 
-// NewUserHandler creates a new UserHandler.
-func NewUserHandler(us domain.UserService, as domain.AuthenticationService) *UserHandler {
-	return &UserHandler{us: us, as: as}
+// recordSecurityEvent records account activity together with the client
+// IP address and user agent of the request.
+func (uh *UserHandler) recordSecurityEvent(r *http.Request, eventType domain.SecurityEventType, userID uuid.UUID, email string) {
+	uh.se.Record(&domain.SecurityEvent{
+		UserID:    userID,
+		Email:     email,
+		Type:      eventType,
+		IP:        remoteIP(r),
+		UserAgent: r.UserAgent(),
+	})
 }
 
 // SignupRequest represents the payload required to register a new user.
@@ -70,19 +99,30 @@ type UserResponse struct {
 //
 //	400 Bad Request
 //	  - Invalid JSON payload
-//	  - User creation failure (e.g. validation errors)
+//
+//	409 Conflict
+//	  - Email already registered
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+//	422 Unprocessable Entity
+//	  - Password does not meet the password policy (8 to 72 characters)
 //
 //	500 Internal Server Error
+//	  - User creation failure
 //	  - Token generation failure
 //
 // Side Effects:
 //   - Persists a new user record
+//   - Records a "signup" security event
 //   - Generates an access token for authentication
 func (uh *UserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 	var request SignupRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		slog.Error("failed to decode request body", "error", err)
-		http.Error(w, "invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
 		return
 	}
 
@@ -93,11 +133,12 @@ func (uh *UserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 	newUser, err := uh.us.Create(&user)
 	if err != nil {
 		slog.Error("failed to create user", "email", user.Email, "error", err)
-		http.Error(w, "failed to create user", http.StatusBadRequest)
+		WriteError(w, r, err, "failed to create user")
 		return
 	}
 
 	newUser.Password = ""
+	uh.recordSecurityEvent(r, domain.SecurityEventSignup, newUser.ID, newUser.Email)
 
 	accessToken, err := uh.as.GenerateAccessToken(newUser)
 	if err != nil {
@@ -142,7 +183,9 @@ type LoginRequest struct {
 //
 //	Authenticates a user using email and password. On success, an access
 //	token is returned in the "Authorization" response header and the
-//	authenticated user is returned in the response body.
+//	authenticated user is returned in the response body. Accounts with
+//	two-factor authentication get a challenge instead, to be completed with
+//	POST /users/signin/2fa.
 //
 // Request Body (application/json):
 //
@@ -163,41 +206,68 @@ type LoginRequest struct {
 //	      "created_at": "2026-01-10T12:00:00Z"
 //	    }
 //
+//	202 Accepted (two-factor authentication enabled)
+//	  {
+//	    "status": "two_factor_required",
+//	    "challenge_token": "..."
+//	  }
+//
 //	400 Bad Request
 //	  - Invalid JSON payload
 //
 //	401 Unauthorized
 //	  - Invalid email or password
 //
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
 //	500 Internal Server Error
-//	  - Token generation failure
+//	  - Account lookup or token generation failure
 //
 // Side Effects:
+//   - Records a "signin" or "signin_failed" security event
 //   - Generates a new access token
 func (uh *UserHandler) Signin(w http.ResponseWriter, r *http.Request) {
 	var request LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		slog.Error("failed to decode login request", "error", err)
-		http.Error(w, "invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
 		return
 	}
 
 	slog.Debug("login attempt", "email", request.Email)
 
 	authUser, err := uh.as.Authenticate(request.Email, request.Password)
-	if err != nil {
+	if errors.Is(err, domain.ErrInvalidCredentials) {
 		slog.Warn("authentication failed", "email", request.Email, "error", err)
+		uh.recordSecurityEvent(r, domain.SecurityEventSigninFailed, uuid.Nil, request.Email)
 		http.Error(w, "invalid email or password", http.StatusUnauthorized)
 		return
 	}
+	if err != nil {
+		WriteError(w, r, err, "failed to sign in")
+		return
+	}
 
 	authUser.Password = ""
+	if authUser.TOTPEnabled {
+		uh.writeChallenge(w, r, authUser)
+		return
+	}
+	uh.recordSecurityEvent(r, domain.SecurityEventSignin, authUser.ID, authUser.Email)
 
 	slog.Info("user authenticated successfully", "user_id", authUser.ID.String(), "email", authUser.Email)
 
-	accessToken, err := uh.as.GenerateAccessToken(authUser)
+	uh.writeSignin(w, authUser)
+}
+
+// writeSignin answers a successful sign in of user with a new access token
+// in the "Authorization" header and the user in the body.
+func (uh *UserHandler) writeSignin(w http.ResponseWriter, user *domain.User) {
+	accessToken, err := uh.as.GenerateAccessToken(user)
 	if err != nil {
-		slog.Error("failed to generate access token", "user_id", authUser.ID.String(), "error", err)
+		slog.Error("failed to generate access token", "user_id", user.ID.String(), "error", err)
 		http.Error(w, "failed to generate access token", http.StatusInternalServerError)
 		return
 	}
@@ -207,13 +277,226 @@ func (uh *UserHandler) Signin(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 
 	response := UserResponse{
-		ID:        authUser.ID,
-		Email:     authUser.Email,
-		CreatedAt: authUser.CreatedAt,
+		ID:        user.ID,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		slog.Error("failed to encode login response", "user_id", authUser.ID.String(), "error", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		slog.Error("failed to encode login response", "user_id", user.ID.String(), "error", err)
+		return
+	}
+}
+
+// MagicLinkRequest represents the payload requesting a passwordless sign in link.
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// MagicLinkResponse acknowledges a sign in link request.
+type MagicLinkResponse struct {
+	Status string `json:"status"`
+}
+
+// RequestMagicLink handles requesting a passwordless sign in link.
+//
+// Route:
+//
+//	POST /users/magic-link
+//
+// Description:
+//
+//	Emails a single-use sign in link, valid for 15 minutes, to the account
+//	of the given email, in the language of the Accept-Language header. The
+//	request is accepted whether or not an account uses the email, so that
+//	the endpoint cannot be used to discover registered addresses.
+//
+// Request Body (application/json):
+//
+//	{
+//	  "email": "user@example.com"
+//	}
+//
+// Responses:
+//
+//	202 Accepted
+//	  {
+//	    "status": "sent"
+//	  }
+//
+//	400 Bad Request
+//	  - Invalid JSON payload
+//	  - Missing email
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	415 Unsupported Media Type
+//	  - Content-Type is not JSON
+//
+// Side Effects:
+//   - Stores the hash of a new login token
+//   - Queues the email with the link on the worker pool
+func (uh *UserHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var request MagicLinkRequest
+	if !decodeJSON(w, r, &request, maxBodyBytes) {
+		return
+	}
+	if request.Email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
 		return
 	}
+
+	token, user, err := uh.ml.Issue(request.Email)
+	if err == nil {
+		uh.sendMagicLink(r, user, token)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(MagicLinkResponse{Status: "sent"}); err != nil {
+		slog.Error("failed to encode magic link response", "error", err)
+	}
+}
+
+// sendMagicLink queues the email with the sign in link of token to user.
+func (uh *UserHandler) sendMagicLink(r *http.Request, user *domain.User, token string) {
+	link := uh.links.MagicLogin(token)
+	localizer := i18n.New(i18n.Match(r.Header.Get("Accept-Language")))
+	ttl := domain.LoginTokenTTL.String()
+
+	job := jobs.SendEmailJob{
+		Email: notifications.Email{
+			To:      user.Email,
+			Subject: localizer.T("MagicLinkSubject", nil),
+			Text:    localizer.T("MagicLinkText", map[string]any{"TTL": ttl, "Link": link}),
+			HTML:    "<p>" + localizer.T("MagicLinkHTML", map[string]any{"TTL": ttl, "Link": html.EscapeString(link)}) + "</p>",
+		},
+		Service:       uh.es,
+		Transactional: true,
+	}
+	if err := uh.wp.TrySubmit(&job); err != nil {
+		slog.Error("magic link email not queued", "user_id", user.ID.String(), "error", err)
+	}
+}
+
+// MagicLogin handles signing in with a passwordless sign in link.
+//
+// Route:
+//
+//	GET /users/magic-login?token=...
+//
+// Description:
+//
+//	Exchanges the token of a link sent by POST /users/magic-link for an
+//	access token, returned in the "Authorization" response header like on
+//	sign in. Each link works once. Accounts with two-factor authentication
+//	get a challenge instead, like on sign in.
+//
+// Query Parameters:
+//
+//	token (string, required) - Token of the sign in link
+//
+// Responses:
+//
+//	200 OK
+//	  Headers:
+//	    Authorization: Bearer <access_token>
+//	  Body:
+//	    {
+//	      "id": "uuid",
+//	      "email": "user@example.com",
+//	      "created_at": "2026-01-10T12:00:00Z"
+//	    }
+//
+//	202 Accepted (two-factor authentication enabled)
+//	  {
+//	    "status": "two_factor_required",
+//	    "challenge_token": "..."
+//	  }
+//
+//	401 Unauthorized
+//	  - Unknown, expired or already used token
+//
+//	500 Internal Server Error
+//	  - Token redemption failure
+//	  - Token generation failure
+//
+// Side Effects:
+//   - Marks the login token as used
+//   - Records a "signin" security event
+//   - Generates a new access token
+func (uh *UserHandler) MagicLogin(w http.ResponseWriter, r *http.Request) {
+	user, err := uh.ml.Redeem(r.URL.Query().Get("token"))
+	if err != nil {
+		WriteError(w, r, err, "failed to sign in")
+		return
+	}
+	if user.TOTPEnabled {
+		uh.writeChallenge(w, r, user)
+		return
+	}
+
+	uh.recordSecurityEvent(r, domain.SecurityEventSignin, user.ID, user.Email)
+
+	uh.writeSignin(w, user)
+}
+
+// SecurityEvents handles listing the account activity of the authenticated user.
+//
+// Route:
+//
+//	GET /users/me/security-events
+//
+// Description:
+//
+//	Returns the most recent security events of the authenticated user,
+//	newest first, so that users can spot activity they do not recognise.
+//	Failed sign ins with the user's email are included.
+//
+// Query Parameters:
+//
+//	limit  (int, optional)    - Number of events (default 50, max 100)
+//	fields (string, optional) - Comma-separated fields of the items to return, such as id,name
+//
+// Responses:
+//
+//	200 OK
+//	  [
+//	    {
+//	      "id": "uuid",
+//	      "email": "user@example.com",
+//	      "type": "signin" | "signin_failed" | "signup" | "password_changed" | "token_refreshed" | "two_factor_enabled" | "two_factor_disabled",
+//	      "ip": "203.0.113.7",
+//	      "user_agent": "Mozilla/5.0 ...",
+//	      "created_at": "2026-01-10T12:00:00Z"
+//	    }
+//	  ]
+//
+//	400 Bad Request
+//	  - Invalid user ID
+//
+//	401 Unauthorized
+//	  - Missing or invalid authentication context
+//
+//	500 Internal Server Error
+//	  - Event retrieval failure
+func (uh *UserHandler) SecurityEvents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ownerIDFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	events, err := uh.se.List(userID, pagination.Limit(limit))
+	if err != nil {
+		http.Error(w, "failed to retrieve security events: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		slog.Error("failed to encode security events response", "user_id", userID, "error", err)
+	}
 }
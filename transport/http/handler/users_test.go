@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"newsletter/internal/infrastructure/workerpool/jobs"
 	"newsletter/internal/users/domain"
 	"testing"
 
@@ -24,6 +26,14 @@ func (m *MockUserService) Create(user *domain.User) (*domain.User, error) {
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockUserService) Get(id uuid.UUID) (*domain.User, error) {
+	args := m.Called(id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 // MockAuthService mocks domain.AuthenticationService
 type MockAuthService struct {
 	mock.Mock
@@ -39,15 +49,55 @@ func (m *MockAuthService) GenerateAccessToken(user *domain.User) (string, error)
 	return args.String(0), args.Error(1)
 }
 
+// MockSecurityEventService mocks domain.SecurityEventService
+type MockSecurityEventService struct {
+	mock.Mock
+}
+
+func (m *MockSecurityEventService) Record(event *domain.SecurityEvent) {
+	m.Called(event)
+}
+
+func (m *MockSecurityEventService) List(userID uuid.UUID, limit int) ([]*domain.SecurityEvent, error) {
+	args := m.Called(userID, limit)
+	return args.Get(0).([]*domain.SecurityEvent), args.Error(1)
+}
+
+// MockMagicLinkService mocks domain.MagicLinkService
+type MockMagicLinkService struct {
+	mock.Mock
+}
+
+func (m *MockMagicLinkService) Issue(email string) (string, *domain.User, error) {
+	args := m.Called(email)
+	user, _ := args.Get(1).(*domain.User)
+	return args.String(0), user, args.Error(2)
+}
+
+func (m *MockMagicLinkService) Redeem(token string) (*domain.User, error) {
+	args := m.Called(token)
+	user, _ := args.Get(0).(*domain.User)
+	return user, args.Error(1)
+}
+
+// recordedEvent matches a recorded security event of the given type.
+func recordedEvent(eventType domain.SecurityEventType) any {
+	return mock.MatchedBy(func(event *domain.SecurityEvent) bool {
+		return event.Type == eventType
+	})
+}
+
 // ------------------- SignUp Tests -------------------
 
 func TestUserHandler_SignUp_Success(t *testing.T) {
 	mockUS := new(MockUserService)
 	mockAS := new(MockAuthService)
+	mockSE := new(MockSecurityEventService)
 
 	handler := &UserHandler{
 		us: mockUS,
 		as: mockAS,
+		se: mockSE,
 	}
 
 	inputUser := &domain.User{
@@ -62,8 +112,9 @@ func TestUserHandler_SignUp_Success(t *testing.T) {
 
 	mockUS.On("Create", inputUser).Return(createdUser, nil)
 	mockAS.On("GenerateAccessToken", createdUser).Return("token123", nil)
+	mockSE.On("Record", recordedEvent(domain.SecurityEventSignup)).Return()
 
-	body, _ := json.Marshal(inputUser)
+	body, _ := json.Marshal(SignupRequest{Email: inputUser.Email, Password: inputUser.Password})
 	req := httptest.NewRequest(http.MethodPost, "/signup", bytes.NewBuffer(body))
 	w := httptest.NewRecorder()
 
@@ -86,10 +137,12 @@ func TestUserHandler_SignUp_Success(t *testing.T) {
 func TestUserHandler_SignUp_CreateUserError(t *testing.T) {
 	mockUS := new(MockUserService)
 	mockAS := new(MockAuthService)
+	mockSE := new(MockSecurityEventService)
 
 	handler := &UserHandler{
 		us: mockUS,
 		as: mockAS,
+		se: mockSE,
 	}
 
 	inputUser := &domain.User{
@@ -99,7 +152,7 @@ func TestUserHandler_SignUp_CreateUserError(t *testing.T) {
 
 	mockUS.On("Create", inputUser).Return((*domain.User)(nil), errors.New("create failed"))
 
-	body, _ := json.Marshal(inputUser)
+	body, _ := json.Marshal(SignupRequest{Email: inputUser.Email, Password: inputUser.Password})
 	req := httptest.NewRequest(http.MethodPost, "/signup", bytes.NewBuffer(body))
 	w := httptest.NewRecorder()
 
@@ -108,19 +161,51 @@ func TestUserHandler_SignUp_CreateUserError(t *testing.T) {
 	resp := w.Result()
 	defer resp.Body.Close()
 
-	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 	mockUS.AssertExpectations(t)
 }
 
+func TestUserHandler_SignUp_MapsDomainErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"email already exists", domain.ErrEmailAlreadyExists, http.StatusConflict},
+		{"weak password", fmt.Errorf("%w: must be at least 8 characters", domain.ErrWeakPassword), http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUS := new(MockUserService)
+			handler := &UserHandler{us: mockUS, as: new(MockAuthService), se: new(MockSecurityEventService)}
+
+			mockUS.On("Create", mock.Anything).Return((*domain.User)(nil), tt.err)
+
+			body, _ := json.Marshal(SignupRequest{Email: "test@example.com", Password: "password123"})
+			req := httptest.NewRequest(http.MethodPost, "/signup", bytes.NewBuffer(body))
+			w := httptest.NewRecorder()
+
+			handler.SignUp(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), tt.err.Error())
+			mockUS.AssertExpectations(t)
+		})
+	}
+}
+
 // ------------------- Signin Tests -------------------
 
 func TestUserHandler_Signin_Success(t *testing.T) {
 	mockUS := new(MockUserService)
 	mockAS := new(MockAuthService)
+	mockSE := new(MockSecurityEventService)
 
 	handler := &UserHandler{
 		us: mockUS,
 		as: mockAS,
+		se: mockSE,
 	}
 
 	input := LoginRequest{
@@ -135,6 +220,7 @@ func TestUserHandler_Signin_Success(t *testing.T) {
 
 	mockAS.On("Authenticate", input.Email, input.Password).Return(authUser, nil)
 	mockAS.On("GenerateAccessToken", authUser).Return("token123", nil)
+	mockSE.On("Record", recordedEvent(domain.SecurityEventSignin)).Return()
 
 	body, _ := json.Marshal(input)
 	req := httptest.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(body))
@@ -158,10 +244,12 @@ func TestUserHandler_Signin_Success(t *testing.T) {
 func TestUserHandler_Signin_AuthFailed(t *testing.T) {
 	mockUS := new(MockUserService)
 	mockAS := new(MockAuthService)
+	mockSE := new(MockSecurityEventService)
 
 	handler := &UserHandler{
 		us: mockUS,
 		as: mockAS,
+		se: mockSE,
 	}
 
 	input := LoginRequest{
@@ -169,7 +257,8 @@ func TestUserHandler_Signin_AuthFailed(t *testing.T) {
 		Password: "wrongpass",
 	}
 
-	mockAS.On("Authenticate", input.Email, input.Password).Return((*domain.User)(nil), errors.New("auth failed"))
+	mockAS.On("Authenticate", input.Email, input.Password).Return((*domain.User)(nil), domain.ErrInvalidCredentials)
+	mockSE.On("Record", recordedEvent(domain.SecurityEventSigninFailed)).Return()
 
 	body, _ := json.Marshal(input)
 	req := httptest.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(body))
@@ -182,4 +271,130 @@ func TestUserHandler_Signin_AuthFailed(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
 	mockAS.AssertExpectations(t)
+	mockSE.AssertExpectations(t)
+}
+
+func TestUserHandler_Signin_LookupFailed(t *testing.T) {
+	mockAS := new(MockAuthService)
+	mockSE := new(MockSecurityEventService)
+	handler := &UserHandler{as: mockAS, se: mockSE}
+
+	mockAS.On("Authenticate", "test@example.com", "password123").Return((*domain.User)(nil), errors.New("connection refused"))
+
+	body, _ := json.Marshal(LoginRequest{Email: "test@example.com", Password: "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.Signin(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockSE.AssertNotCalled(t, "Record", mock.Anything)
+}
+
+// ------------------- Magic Link Tests -------------------
+
+func TestUserHandler_RequestMagicLink_QueuesEmail(t *testing.T) {
+	mockML := new(MockMagicLinkService)
+	mockWP := new(MockWorkerPool)
+	handler := NewUserHandler(new(MockUserService), new(MockAuthService), new(MockSecurityEventService), mockML, new(MockTwoFactorService), new(MockEmailService), mockWP, testLinks)
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com"}
+	mockML.On("Issue", "test@example.com").Return("abc123", user, nil)
+
+	var queued *jobs.SendEmailJob
+	mockWP.On("TrySubmit", mock.Anything).Run(func(args mock.Arguments) {
+		queued = args.Get(0).(*jobs.SendEmailJob)
+	}).Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/magic-link", bytes.NewBufferString(`{"email":"test@example.com"}`))
+	req.Header.Set("Accept-Language", "de")
+	w := httptest.NewRecorder()
+
+	handler.RequestMagicLink(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	if assert.NotNil(t, queued) {
+		assert.Equal(t, "test@example.com", queued.Email.To)
+		assert.Equal(t, "Ihr Anmeldelink", queued.Email.Subject)
+		assert.Contains(t, queued.Email.Text, "https://api.example.com/v1/users/magic-login?token=abc123")
+		assert.True(t, queued.Transactional)
+	}
+	mockML.AssertExpectations(t)
+}
+
+func TestUserHandler_RequestMagicLink_UnknownEmailIsAccepted(t *testing.T) {
+	mockML := new(MockMagicLinkService)
+	mockWP := new(MockWorkerPool)
+	handler := NewUserHandler(new(MockUserService), new(MockAuthService), new(MockSecurityEventService), mockML, new(MockTwoFactorService), new(MockEmailService), mockWP, testLinks)
+
+	mockML.On("Issue", "missing@example.com").Return("", nil, errors.New("no rows in result set"))
+
+	req := httptest.NewRequest(http.MethodPost, "/users/magic-link", bytes.NewBufferString(`{"email":"missing@example.com"}`))
+	w := httptest.NewRecorder()
+
+	handler.RequestMagicLink(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	mockWP.AssertNotCalled(t, "TrySubmit", mock.Anything)
+}
+
+func TestUserHandler_MagicLogin_Success(t *testing.T) {
+	mockAS := new(MockAuthService)
+	mockSE := new(MockSecurityEventService)
+	mockML := new(MockMagicLinkService)
+	handler := NewUserHandler(new(MockUserService), mockAS, mockSE, mockML, new(MockTwoFactorService), new(MockEmailService), new(MockWorkerPool), testLinks)
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com"}
+	mockML.On("Redeem", "abc123").Return(user, nil)
+	mockSE.On("Record", recordedEvent(domain.SecurityEventSignin)).Return()
+	mockAS.On("GenerateAccessToken", user).Return("token123", nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/magic-login?token=abc123", nil)
+	w := httptest.NewRecorder()
+
+	handler.MagicLogin(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Bearer token123", w.Header().Get("Authorization"))
+	mockSE.AssertExpectations(t)
+	mockAS.AssertExpectations(t)
+}
+
+func TestUserHandler_MagicLogin_InvalidToken(t *testing.T) {
+	mockML := new(MockMagicLinkService)
+	handler := NewUserHandler(new(MockUserService), new(MockAuthService), new(MockSecurityEventService), mockML, new(MockTwoFactorService), new(MockEmailService), new(MockWorkerPool), testLinks)
+
+	mockML.On("Redeem", "used").Return(nil, domain.ErrInvalidLoginToken)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/magic-login?token=used", nil)
+	w := httptest.NewRecorder()
+
+	handler.MagicLogin(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// ------------------- Security Events Tests -------------------
+
+func TestUserHandler_SecurityEvents_Success(t *testing.T) {
+	mockSE := new(MockSecurityEventService)
+	handler := NewUserHandler(new(MockUserService), new(MockAuthService), mockSE, new(MockMagicLinkService), new(MockTwoFactorService), new(MockEmailService), new(MockWorkerPool), testLinks)
+
+	userID := uuid.New()
+	events := []*domain.SecurityEvent{{ID: uuid.New(), UserID: userID, Email: "test@example.com", Type: domain.SecurityEventSignin, IP: "192.0.2.1"}}
+	mockSE.On("List", userID, 50).Return(events, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me/security-events", nil)
+	req = req.WithContext(contextWithUserID(req.Context(), userID.String()))
+	w := httptest.NewRecorder()
+
+	handler.SecurityEvents(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp []*domain.SecurityEvent
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp, 1)
+	assert.Equal(t, domain.SecurityEventSignin, resp[0].Type)
+
+	mockSE.AssertExpectations(t)
 }
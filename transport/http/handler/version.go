@@ -0,0 +1,28 @@
+package handler
+
+import "context"
+
+// APIPrefix is the path prefix of the current API version. Links generated
+// by handlers (for example in emails) point to versioned routes.
+const APIPrefix = "/v1"
+
+// CurrentAPIVersion is the API version served when a client does not ask
+// for a specific one.
+const CurrentAPIVersion = 1
+
+type apiVersionKey struct{}
+
+// WithAPIVersion returns a copy of ctx carrying the negotiated API version.
+func WithAPIVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, apiVersionKey{}, version)
+}
+
+// APIVersion returns the API version negotiated for the request, so that
+// handlers can shape responses for older clients when response envelopes
+// change. It defaults to CurrentAPIVersion.
+func APIVersion(ctx context.Context) int {
+	if version, ok := ctx.Value(apiVersionKey{}).(int); ok {
+		return version
+	}
+	return CurrentAPIVersion
+}
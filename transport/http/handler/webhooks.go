@@ -0,0 +1,410 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	campaigndomain "newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/i18n"
+	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/internal/infrastructure/workerpool/jobs"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	notifications "newsletter/internal/notifications/domain"
+	"newsletter/internal/notifications/infrastructure/rawmail"
+	userdomain "newsletter/internal/users/domain"
+	"strings"
+	"time"
+)
+
+// maxWebhookBody is the largest SNS message accepted by the SES webhooks.
+const maxWebhookBody = 256 << 10
+
+// WebhookHandler handles notifications sent by email providers.
+type WebhookHandler struct {
+	cs campaigndomain.CampaignService
+	ns newsletterdomain.NewsletterService
+	us userdomain.UserService
+	es notifications.EmailService
+	wp workerpool.JobSubmiter
+
+	mail   notifications.InboundMailStore // nil ignores the received emails stored in S3
+	token  string
+	client *http.Client
+}
+
+// NewWebhookHandler creates a new WebhookHandler. Requests must carry token
+// in their "token" query parameter; an empty token disables the webhooks.
+// Replies received by the inbound webhook are emailed to the owner of the
+// newsletter with es; mail, which may be nil, reads the received emails that
+// SES stored in S3.
+func NewWebhookHandler(cs campaigndomain.CampaignService, ns newsletterdomain.NewsletterService, us userdomain.UserService, es notifications.EmailService, wp workerpool.JobSubmiter, mail notifications.InboundMailStore, token string) *WebhookHandler {
+	return &WebhookHandler{cs: cs, ns: ns, us: us, es: es, wp: wp, mail: mail, token: token, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// snsMessage is the envelope of messages delivered by Amazon SNS over HTTPS.
+type snsMessage struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+	TopicArn     string `json:"TopicArn"`
+}
+
+// sesNotification is an SES feedback notification or event publishing
+// record, as carried in the Message of an SNS notification.
+type sesNotification struct {
+	NotificationType string `json:"notificationType"` // Feedback notifications
+	EventType        string `json:"eventType"`        // Event publishing
+	Mail             struct {
+		MessageID string `json:"messageId"`
+	} `json:"mail"`
+	Bounce *struct {
+		BounceType    string `json:"bounceType"`
+		BounceSubType string `json:"bounceSubType"`
+	} `json:"bounce"`
+	Complaint *struct {
+		ComplaintFeedbackType string `json:"complaintFeedbackType"`
+	} `json:"complaint"`
+}
+
+// delivery returns the delivery status and detail described by the notification,
+// or an empty status for notifications that do not change deliveries.
+func (n *sesNotification) delivery() (string, string) {
+	kind := n.NotificationType
+	if kind == "" {
+		kind = n.EventType
+	}
+
+	switch kind {
+	case "Delivery":
+		return campaigndomain.DeliveryDelivered, ""
+	case "Bounce":
+		if n.Bounce == nil {
+			return campaigndomain.DeliveryBounced, ""
+		}
+		return campaigndomain.DeliveryBounced, n.Bounce.BounceType + "/" + n.Bounce.BounceSubType
+	case "Complaint":
+		if n.Complaint == nil {
+			return campaigndomain.DeliveryComplained, ""
+		}
+		return campaigndomain.DeliveryComplained, n.Complaint.ComplaintFeedbackType
+	default:
+		return "", ""
+	}
+}
+
+// SES handles delivery, bounce and complaint notifications published by SES.
+//
+// Route:
+//
+//	POST /webhooks/ses?token={token}
+//
+// Description:
+//
+//	Receives the SNS topic that SES publishes its notifications to, and
+//	updates the delivery log of campaigns with them. Subscription
+//	confirmations sent by SNS are confirmed automatically. Notifications of
+//	emails that were not sent by a campaign, such as test emails, are
+//	ignored.
+//
+// Request Body (text/plain, SNS message):
+//
+//	{
+//	  "Type": "Notification",
+//	  "Message": "{\"notificationType\":\"Bounce\",\"mail\":{\"messageId\":\"...\"},\"bounce\":{...}}"
+//	}
+//
+// Responses:
+//
+//	204 No Content
+//	  - The message was processed or ignored
+//
+//	400 Bad Request
+//	  - Malformed SNS message or SES notification
+//
+//	403 Forbidden
+//	  - Missing or invalid token
+//
+//	500 Internal Server Error
+//	  - The delivery could not be updated; SNS retries the message
+//
+//	501 Not Implemented
+//	  - No webhook token is configured
+func (wh *WebhookHandler) SES(w http.ResponseWriter, r *http.Request) {
+	message, ok := wh.receive(w, r)
+	if !ok {
+		return
+	}
+
+	var notification sesNotification
+	if err := json.Unmarshal([]byte(message), &notification); err != nil {
+		http.Error(w, "invalid SES notification", http.StatusBadRequest)
+		return
+	}
+
+	status, detail := notification.delivery()
+	if status == "" || notification.Mail.MessageID == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err := wh.cs.RecordEvent(notification.Mail.MessageID, status, detail)
+	if errors.Is(err, campaigndomain.ErrDeliveryNotFound) {
+		slog.Debug("ignoring SES notification of unknown message", "message", notification.Mail.MessageID, "status", status)
+		err = nil
+	}
+	if err != nil {
+		slog.Error("failed to record SES notification", "message", notification.Mail.MessageID, "status", status, "error", err)
+		http.Error(w, "failed to record notification", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// receive authorizes an SNS request and reads its message. Subscription
+// confirmations are confirmed. It returns the message of notifications, and
+// false once it has written the response of any other request.
+func (wh *WebhookHandler) receive(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if wh.token == "" {
+		http.Error(w, "SES webhook is not configured", http.StatusNotImplemented)
+		return "", false
+	}
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(wh.token)) != 1 {
+		http.Error(w, "invalid webhook token", http.StatusForbidden)
+		return "", false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return "", false
+	}
+
+	var message snsMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		http.Error(w, "invalid SNS message", http.StatusBadRequest)
+		return "", false
+	}
+
+	switch message.Type {
+	case "Notification":
+		return message.Message, true
+	case "SubscriptionConfirmation":
+		if err := wh.confirm(message.SubscribeURL); err != nil {
+			slog.Error("failed to confirm SNS subscription", "topic", message.TopicArn, "error", err)
+			http.Error(w, "failed to confirm subscription", http.StatusBadRequest)
+			return "", false
+		}
+		slog.Info("SNS subscription confirmed", "topic", message.TopicArn)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return "", false
+}
+
+// sesReceipt is an SES receiving notification, published by the SNS or S3
+// action of a receipt rule, as carried in the Message of an SNS
+// notification.
+type sesReceipt struct {
+	NotificationType string `json:"notificationType"`
+	Receipt          struct {
+		SpamVerdict  sesVerdict `json:"spamVerdict"`
+		VirusVerdict sesVerdict `json:"virusVerdict"`
+		Action       struct {
+			Type       string `json:"type"`       // "SNS" or "S3"
+			Encoding   string `json:"encoding"`   // Encoding of Content with the SNS action: "UTF8" or "BASE64"
+			BucketName string `json:"bucketName"` // Location of the email with the S3 action
+			ObjectKey  string `json:"objectKey"`
+		} `json:"action"`
+	} `json:"receipt"`
+	Content string `json:"content"` // Raw email, with the SNS action
+}
+
+// sesVerdict is the outcome of a spam or virus scan of SES.
+type sesVerdict struct {
+	Status string `json:"status"` // PASS, FAIL, GRAY or PROCESSING_FAILED
+}
+
+// content returns the raw email of the notification, read from S3 with the
+// S3 action.
+func (wh *WebhookHandler) content(ctx context.Context, receipt *sesReceipt) ([]byte, error) {
+	action := receipt.Receipt.Action
+	switch {
+	case action.Type == "S3" && wh.mail == nil:
+		return nil, errors.New("received emails stored in S3 cannot be read")
+	case action.Type == "S3":
+		return wh.mail.Fetch(ctx, action.BucketName, action.ObjectKey)
+	case strings.EqualFold(action.Encoding, "BASE64"):
+		return base64.StdEncoding.DecodeString(receipt.Content)
+	default:
+		return []byte(receipt.Content), nil
+	}
+}
+
+// SESInbound handles the emails received by SES, storing the replies to
+// campaign emails.
+//
+// Route:
+//
+//	POST /webhooks/ses/inbound?token={token}
+//
+// Description:
+//
+//	Receives the SNS topic that the receipt rule of the reply address
+//	publishes received emails to, with the SNS action or, for emails larger
+//	than 150 KB, the S3 action. Emails answering a campaign email, found
+//	from their In-Reply-To and References headers, are stored against the
+//	campaign without the quoted email, and forwarded to the owner of the
+//	newsletter with the subscriber as reply-to address. Other emails, emails
+//	failing the spam or virus scans of SES and emails that SNS delivers
+//	again are ignored. Subscription confirmations sent by SNS are confirmed
+//	automatically.
+//
+// Request Body (text/plain, SNS message):
+//
+//	{
+//	  "Type": "Notification",
+//	  "Message": "{\"notificationType\":\"Received\",\"receipt\":{\"action\":{\"type\":\"SNS\",\"encoding\":\"BASE64\"},...},\"content\":\"...\"}"
+//	}
+//
+// Responses:
+//
+//	204 No Content
+//	  - The email was stored or ignored
+//
+//	400 Bad Request
+//	  - Malformed SNS message, SES notification or email
+//
+//	403 Forbidden
+//	  - Missing or invalid token
+//
+//	500 Internal Server Error
+//	  - The email could not be read or stored; SNS retries the message
+//
+//	501 Not Implemented
+//	  - No webhook token is configured
+//
+// Side Effects:
+//   - Stores the reply
+//   - Queues an email with the reply to the owner of the newsletter
+func (wh *WebhookHandler) SESInbound(w http.ResponseWriter, r *http.Request) {
+	message, ok := wh.receive(w, r)
+	if !ok {
+		return
+	}
+
+	var receipt sesReceipt
+	if err := json.Unmarshal([]byte(message), &receipt); err != nil {
+		http.Error(w, "invalid SES notification", http.StatusBadRequest)
+		return
+	}
+	if receipt.NotificationType != "Received" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if receipt.Receipt.SpamVerdict.Status == "FAIL" || receipt.Receipt.VirusVerdict.Status == "FAIL" {
+		slog.Info("ignoring received email that failed the spam or virus scan")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	raw, err := wh.content(r.Context(), &receipt)
+	if err != nil {
+		slog.Error("failed to read received email", "action", receipt.Receipt.Action.Type, "error", err)
+		http.Error(w, "failed to read email", http.StatusInternalServerError)
+		return
+	}
+
+	email, err := rawmail.Parse(raw)
+	if err != nil {
+		slog.Warn("ignoring malformed received email", "error", err)
+		http.Error(w, "invalid email", http.StatusBadRequest)
+		return
+	}
+
+	reply := &campaigndomain.Reply{
+		Email:      email.From,
+		Subject:    email.Subject,
+		Text:       email.Reply(),
+		MessageID:  email.MessageID,
+		ReceivedAt: email.ReceivedAt,
+	}
+	created, err := wh.cs.RecordReply(reply, email.References)
+	if errors.Is(err, campaigndomain.ErrDeliveryNotFound) {
+		slog.Debug("ignoring received email answering no campaign", "message", email.MessageID)
+		err = nil
+	}
+	if err != nil {
+		http.Error(w, "failed to record reply", http.StatusInternalServerError)
+		return
+	}
+	if created {
+		if err := wh.forward(reply); err != nil {
+			slog.Error("failed to forward reply to the newsletter owner", "reply_id", reply.ID, "campaign_id", reply.CampaignID, "error", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// forward queues an email with reply to the owner of the newsletter of its
+// campaign, in the language of the newsletter, so that the owner can answer
+// the subscriber directly.
+func (wh *WebhookHandler) forward(reply *campaigndomain.Reply) error {
+	campaign, err := wh.cs.Get(reply.CampaignID)
+	if err != nil {
+		return err
+	}
+	newsletter, err := wh.ns.Get(campaign.NewsletterID)
+	if err != nil {
+		return err
+	}
+	owner, err := wh.us.Get(newsletter.OwnerID)
+	if err != nil {
+		return fmt.Errorf("get owner %s: %w", newsletter.OwnerID, err)
+	}
+
+	localizer := i18n.New(newsletter.Language)
+	values := map[string]any{"Email": reply.Email, "Newsletter": newsletter.Name}
+	intro := localizer.T("ReplyIntro", values)
+
+	job := jobs.SendEmailJob{
+		Email: notifications.Email{
+			To:       owner.Email,
+			Subject:  localizer.T("ReplySubject", values),
+			Text:     intro + "\n\n" + reply.Subject + "\n\n" + reply.Text,
+			HTML:     "<p>" + html.EscapeString(intro) + "</p><p><strong>" + html.EscapeString(reply.Subject) + "</strong></p><blockquote>" + strings.ReplaceAll(html.EscapeString(reply.Text), "\n", "<br>") + "</blockquote>",
+			Envelope: notifications.Envelope{ReplyTo: reply.Email},
+		},
+		Service: wh.es,
+	}
+	return wh.wp.TrySubmit(&job)
+}
+
+// confirm visits the SubscribeURL of an SNS subscription confirmation. Only
+// HTTPS URLs of AWS hosts are followed.
+func (wh *WebhookHandler) confirm(subscribeURL string) error {
+	target, err := url.Parse(subscribeURL)
+	if err != nil || target.Scheme != "https" || !strings.HasSuffix(target.Hostname(), ".amazonaws.com") {
+		return errors.New("subscribe URL is not an AWS HTTPS URL")
+	}
+
+	resp, err := wh.client.Get(target.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("SNS responded with status " + resp.Status)
+	}
+	return nil
+}
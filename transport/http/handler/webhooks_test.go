@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/workerpool/jobs"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	userdomain "newsletter/internal/users/domain"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// snsRequest builds an SNS notification carrying the SES notification.
+func snsRequest(token string, notification string) *http.Request {
+	envelope, _ := json.Marshal(snsMessage{Type: "Notification", Message: notification})
+	return httptest.NewRequest(http.MethodPost, "/webhooks/ses?token="+token, strings.NewReader(string(envelope)))
+}
+
+func TestSESWebhook_InvalidToken(t *testing.T) {
+	mockCS := new(MockCampaignService)
+	h := NewWebhookHandler(mockCS, nil, nil, nil, nil, nil, "secret")
+
+	rec := httptest.NewRecorder()
+	h.SES(rec, snsRequest("wrong", `{}`))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	mockCS.AssertNotCalled(t, "RecordEvent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSESWebhook_RecordsBounce(t *testing.T) {
+	mockCS := new(MockCampaignService)
+	h := NewWebhookHandler(mockCS, nil, nil, nil, nil, nil, "secret")
+
+	mockCS.On("RecordEvent", "msg-1", domain.DeliveryBounced, "Permanent/NoEmail").Return(nil)
+
+	rec := httptest.NewRecorder()
+	h.SES(rec, snsRequest("secret", `{"notificationType":"Bounce","mail":{"messageId":"msg-1"},"bounce":{"bounceType":"Permanent","bounceSubType":"NoEmail"}}`))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	mockCS.AssertExpectations(t)
+}
+
+func TestSESWebhook_IgnoresUnknownMessages(t *testing.T) {
+	mockCS := new(MockCampaignService)
+	h := NewWebhookHandler(mockCS, nil, nil, nil, nil, nil, "secret")
+
+	mockCS.On("RecordEvent", "test-email", domain.DeliveryDelivered, "").Return(domain.ErrDeliveryNotFound)
+
+	rec := httptest.NewRecorder()
+	h.SES(rec, snsRequest("secret", `{"eventType":"Delivery","mail":{"messageId":"test-email"}}`))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestSESWebhook_RejectsForeignSubscribeURL(t *testing.T) {
+	h := NewWebhookHandler(new(MockCampaignService), nil, nil, nil, nil, nil, "secret")
+
+	envelope := `{"Type":"SubscriptionConfirmation","SubscribeURL":"https://attacker.example.com/confirm"}`
+	rec := httptest.NewRecorder()
+	h.SES(rec, httptest.NewRequest(http.MethodPost, "/webhooks/ses?token=secret", strings.NewReader(envelope)))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// receivedNotification builds the SES notification of a received email
+// carried by the SNS action.
+func receivedNotification(raw string) string {
+	notification, _ := json.Marshal(map[string]any{
+		"notificationType": "Received",
+		"receipt": map[string]any{
+			"action":       map[string]string{"type": "SNS", "encoding": "BASE64"},
+			"spamVerdict":  map[string]string{"status": "PASS"},
+			"virusVerdict": map[string]string{"status": "PASS"},
+		},
+		"content": base64.StdEncoding.EncodeToString([]byte(raw)),
+	})
+	return string(notification)
+}
+
+const testReply = "From: Jane <jane@example.com>\r\n" +
+	"Subject: Re: Issue 1\r\n" +
+	"Message-ID: <reply-1@example.com>\r\n" +
+	"In-Reply-To: <msg-1@mail.example.com>\r\n" +
+	"\r\n" +
+	"Great issue!\r\n" +
+	"\r\n" +
+	"On Mon, Jan 5, 2026, Tech News wrote:\r\n" +
+	"> Hello\r\n"
+
+func TestSESInbound_RecordsAndForwardsReply(t *testing.T) {
+	mockCS, mockNS, mockUS, mockWP := new(MockCampaignService), new(MockNewsletterService), new(MockUserService), new(MockWorkerPool)
+	h := NewWebhookHandler(mockCS, mockNS, mockUS, new(MockEmailService), mockWP, nil, "secret")
+
+	campaign := &domain.Campaign{ID: uuid.New(), NewsletterID: uuid.New()}
+	newsletter := &newsletterdomain.Newsletter{ID: campaign.NewsletterID, OwnerID: uuid.New(), Name: "Tech News"}
+	mockCS.On("RecordReply", mock.MatchedBy(func(reply *domain.Reply) bool {
+		reply.CampaignID = campaign.ID
+		return reply.Email == "jane@example.com" && reply.Text == "Great issue!" && reply.MessageID == "<reply-1@example.com>"
+	}), []string{"<msg-1@mail.example.com>"}).Return(true, nil)
+	mockCS.On("Get", campaign.ID).Return(campaign, nil)
+	mockNS.On("Get", campaign.NewsletterID).Return(newsletter, nil)
+	mockUS.On("Get", newsletter.OwnerID).Return(&userdomain.User{ID: newsletter.OwnerID, Email: "owner@example.com"}, nil)
+	mockWP.On("TrySubmit", mock.MatchedBy(func(job *jobs.SendEmailJob) bool {
+		return job.Email.To == "owner@example.com" && job.Email.Envelope.ReplyTo == "jane@example.com" && strings.Contains(job.Email.Text, "Great issue!")
+	})).Return(nil)
+
+	rec := httptest.NewRecorder()
+	h.SESInbound(rec, snsRequest("secret", receivedNotification(testReply)))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	mockCS.AssertExpectations(t)
+	mockWP.AssertExpectations(t)
+}
+
+func TestSESInbound_IgnoresUnknownReplies(t *testing.T) {
+	mockCS, mockWP := new(MockCampaignService), new(MockWorkerPool)
+	h := NewWebhookHandler(mockCS, nil, nil, nil, mockWP, nil, "secret")
+
+	mockCS.On("RecordReply", mock.Anything, mock.Anything).Return(false, domain.ErrDeliveryNotFound)
+
+	rec := httptest.NewRecorder()
+	h.SESInbound(rec, snsRequest("secret", receivedNotification(testReply)))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	mockWP.AssertNotCalled(t, "TrySubmit", mock.Anything)
+}
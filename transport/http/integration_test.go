@@ -0,0 +1,436 @@
+//go:build integration
+
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"newsletter/config"
+	campaigndomain "newsletter/internal/campaigns/domain"
+	"newsletter/internal/infrastructure/workerpool"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	postdomain "newsletter/internal/posts/domain"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	userdomain "newsletter/internal/users/domain"
+	transporthttp "newsletter/transport/http"
+
+	_ "github.com/jackc/pgx/v4/stdlib"
+)
+
+// The integration suite boots the complete application against real
+// dependencies started in Docker:
+//   - PostgreSQL, with all migrations applied
+//   - the Firestore emulator
+//
+// Run it with:
+//
+//	go test -tags integration ./transport/http/...
+//
+// SES is not emulated; confirmation emails fail to send and are only logged.
+
+const projectID = "newsletter-integration"
+
+// migrationDirs lists the migration directories in dependency order.
+var migrationDirs = []string{"users", "newsletters", "posts", "segments", "campaigns", "links", "idempotency"}
+
+var (
+	serverURL       string
+	firestoreClient *firestore.Client
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		log.Printf("could not construct docker pool: %v", err)
+		return 1
+	}
+	if err := pool.Client.Ping(); err != nil {
+		log.Printf("could not connect to docker: %v", err)
+		return 1
+	}
+	pool.MaxWait = 2 * time.Minute
+
+	hostConfig := func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	}
+
+	postgres, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "16-alpine",
+		Env: []string{
+			"POSTGRES_DB=newsletter",
+			"POSTGRES_USER=root",
+			"POSTGRES_PASSWORD=rootpassword",
+		},
+	}, hostConfig)
+	if err != nil {
+		log.Printf("could not start postgres: %v", err)
+		return 1
+	}
+	defer pool.Purge(postgres)
+
+	dsn := fmt.Sprintf("postgres://root:rootpassword@%s/newsletter?sslmode=disable", postgres.GetHostPort("5432/tcp"))
+	if err := pool.Retry(func() error { return ping(dsn) }); err != nil {
+		log.Printf("postgres did not become ready: %v", err)
+		return 1
+	}
+	if err := migrate(dsn); err != nil {
+		log.Printf("could not apply migrations: %v", err)
+		return 1
+	}
+
+	emulator, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository:   "gcr.io/google.com/cloudsdktool/google-cloud-cli",
+		Tag:          "emulators",
+		Cmd:          []string{"gcloud", "emulators", "firestore", "start", "--host-port=0.0.0.0:8080"},
+		ExposedPorts: []string{"8080/tcp"},
+	}, hostConfig)
+	if err != nil {
+		log.Printf("could not start firestore emulator: %v", err)
+		return 1
+	}
+	defer pool.Purge(emulator)
+
+	emulatorHost := emulator.GetHostPort("8080/tcp")
+	if err := pool.Retry(func() error {
+		resp, err := http.Get("http://" + emulatorHost)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}); err != nil {
+		log.Printf("firestore emulator did not become ready: %v", err)
+		return 1
+	}
+
+	os.Setenv("DSN", dsn)
+	os.Setenv("FIRESTORE_EMULATOR_HOST", emulatorHost)
+	os.Setenv("GOOGLE_CLOUD_PROJECT", projectID)
+	os.Setenv("JWT_SECRET_KEY", "integration-secret")
+	os.Setenv("UNSUBSCRIBE_SECRET_KEY", "integration-unsubscribe-secret")
+	os.Setenv("AWS_REGION", "us-east-1")
+	os.Setenv("EMAIL_FROM", "newsletter@example.com")
+
+	firestoreClient, err = firestore.NewClient(context.Background(), projectID)
+	if err != nil {
+		log.Printf("could not create firestore client: %v", err)
+		return 1
+	}
+	defer firestoreClient.Close()
+
+	wp, err := workerpool.NewWorkerPool("1", "10", &sync.WaitGroup{})
+	if err != nil {
+		log.Printf("could not create worker pool: %v", err)
+		return 1
+	}
+	wp.Start()
+
+	// BASE_URL is validated when the app is built, so the server is started
+	// first and serves the routes once they exist.
+	var routes http.Handler
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routes.ServeHTTP(w, r)
+	}))
+	serverURL = server.URL
+	os.Setenv("BASE_URL", serverURL)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("invalid configuration: %v", err)
+		return 1
+	}
+	routes = transporthttp.NewApp(cfg, wp, nil).Routes()
+
+	code := m.Run()
+
+	server.Close()
+	wp.Shutdown()
+	wp.Wait()
+
+	return code
+}
+
+// ping opens a connection to the database and verifies it is reachable.
+func ping(dsn string) error {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Ping()
+}
+
+// migrate applies every up migration, directory by directory in dependency order.
+func migrate(dsn string) error {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, dir := range migrationDirs {
+		files, err := filepath.Glob(filepath.Join("..", "..", "migrations", dir, "*.up.sql"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			migration, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if _, err := db.Exec(string(migration)); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// do sends a JSON request to the test server.
+func do(t *testing.T, method, path, accessToken string, body any) *http.Response {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&payload).Encode(body))
+	}
+
+	req, err := http.NewRequest(method, serverURL+path, &payload)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", accessToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+// findSubscription reads the subscription of email to newsletterID directly from Firestore.
+func findSubscription(t *testing.T, newsletterID, email string) *subscriptiondomain.Subscription {
+	t.Helper()
+
+	docs, err := firestoreClient.Collection("subscriptions").
+		Where("newsletterId", "==", newsletterID).
+		Where("email", "==", email).
+		Documents(context.Background()).
+		GetAll()
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+
+	var subscription subscriptiondomain.Subscription
+	require.NoError(t, docs[0].DataTo(&subscription))
+	subscription.ID = docs[0].Ref.ID
+
+	return &subscription
+}
+
+func TestSubscriptionFlow(t *testing.T) {
+	credentials := map[string]string{"email": "owner@example.com", "password": "password123"}
+
+	// Sign up
+	resp := do(t, http.MethodPost, "/v1/users/signup", "", credentials)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Authorization"))
+
+	// Signing up twice with the same email conflicts
+	resp = do(t, http.MethodPost, "/v1/users/signup", "", credentials)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// Sign in
+	resp = do(t, http.MethodPost, "/v1/users/signin", "", credentials)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	accessToken := resp.Header.Get("Authorization")
+
+	// Review account activity
+	resp = do(t, http.MethodGet, "/v1/users/me/security-events", accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var events []userdomain.SecurityEvent
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&events))
+	require.Len(t, events, 2)
+	assert.Equal(t, userdomain.SecurityEventSignin, events[0].Type)
+	assert.Equal(t, userdomain.SecurityEventSignup, events[1].Type)
+
+	// Create a newsletter
+	resp = do(t, http.MethodPost, "/v1/newsletters", accessToken, map[string]string{
+		"name":        "Integration Weekly",
+		"description": "Created by the integration suite",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var newsletter newsletterdomain.Newsletter
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&newsletter))
+	require.NotEmpty(t, newsletter.ID)
+
+	// List newsletters
+	resp = do(t, http.MethodGet, "/v1/newsletters", accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var newsletters []newsletterdomain.Newsletter
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&newsletters))
+	require.Len(t, newsletters, 1)
+	assert.Equal(t, newsletter.ID, newsletters[0].ID)
+
+	// Search newsletters by name and description
+	resp = do(t, http.MethodGet, "/v1/newsletters?q=integration+suite", accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&newsletters))
+	assert.Len(t, newsletters, 1)
+
+	resp = do(t, http.MethodGet, "/v1/newsletters?q=cooking", accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	newsletters = nil
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&newsletters))
+	assert.Empty(t, newsletters)
+
+	// Sort and filter newsletters by creation time
+	resp = do(t, http.MethodGet, "/v1/newsletters?sort=name&order=desc&created_after="+url.QueryEscape(newsletter.CreatedAt.Add(-time.Minute).Format(time.RFC3339)), accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&newsletters))
+	assert.Len(t, newsletters, 1)
+
+	resp = do(t, http.MethodGet, "/v1/newsletters?created_before="+url.QueryEscape(newsletter.CreatedAt.Add(-time.Minute).Format(time.RFC3339)), accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	newsletters = nil
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&newsletters))
+	assert.Empty(t, newsletters)
+
+	resp = do(t, http.MethodGet, "/v1/newsletters?sort=owner_id", accessToken, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Subscribe
+	resp = do(t, http.MethodPost, "/v1/subscriptions/"+newsletter.ID.String(), "", map[string]string{
+		"email": "reader@example.com",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	subscription := findSubscription(t, newsletter.ID.String(), "reader@example.com")
+	assert.True(t, subscription.IsActive())
+
+	// List subscribers
+	resp = do(t, http.MethodGet, "/v1/newsletters/"+newsletter.ID.String()+"/subscribers?status=active", accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var subscribers subscriptiondomain.SubscriberPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&subscribers))
+	require.Len(t, subscribers.Subscriptions, 1)
+	assert.Equal(t, "reader@example.com", subscribers.Subscriptions[0].Email)
+	assert.Empty(t, subscribers.NextCursor)
+
+	// Unsubscribe
+	resp = do(t, http.MethodDelete, "/v1/subscriptions/unsubscribe?token="+subscription.UnsubscribeToken, "", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	subscription = findSubscription(t, newsletter.ID.String(), "reader@example.com")
+	assert.False(t, subscription.IsActive())
+	assert.NotNil(t, subscription.UnsubscribedAt)
+
+	// Unsubscribing twice reports the subscription as gone
+	resp = do(t, http.MethodDelete, "/v1/subscriptions/unsubscribe?token="+subscription.UnsubscribeToken, "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestPostLifecycle(t *testing.T) {
+	credentials := map[string]string{"email": "editor@example.com", "password": "password123"}
+
+	resp := do(t, http.MethodPost, "/v1/users/signup", "", credentials)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	accessToken := resp.Header.Get("Authorization")
+
+	resp = do(t, http.MethodPost, "/v1/newsletters", accessToken, map[string]string{"name": "Posts Weekly", "description": "Lifecycle"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var newsletter newsletterdomain.Newsletter
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&newsletter))
+	posts := "/v1/newsletters/" + newsletter.ID.String() + "/posts"
+
+	// Write a draft
+	resp = do(t, http.MethodPost, posts, accessToken, map[string]string{"title": "Issue #1", "body": "<p>Hello</p>"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var post postdomain.Post
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&post))
+	assert.Equal(t, postdomain.StatusDraft, post.Status)
+	postPath := posts + "/" + post.ID.String()
+
+	// Drafts cannot be sent
+	resp = do(t, http.MethodPost, postPath+"/send", accessToken, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// Drafts are editable
+	resp = do(t, http.MethodPut, postPath, accessToken, map[string]string{"title": "Issue #1", "body": "<p>Hello again</p>"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Publishing freezes the post
+	resp = do(t, http.MethodPost, postPath+"/publish", accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = do(t, http.MethodPut, postPath, accessToken, map[string]string{"title": "Edited"})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// Published posts are sent once, through a campaign reporting its progress
+	resp = do(t, http.MethodPost, postPath+"/send", accessToken, nil)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var campaign campaigndomain.Campaign
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&campaign))
+	assert.Equal(t, post.ID, campaign.PostID)
+
+	resp = do(t, http.MethodGet, "/v1/campaigns/"+campaign.ID.String(), accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = do(t, http.MethodGet, "/v1/campaigns/"+campaign.ID.String()+"/deliveries", accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = do(t, http.MethodPost, postPath+"/send", accessToken, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// Archived posts stay archived
+	resp = do(t, http.MethodPost, postPath+"/archive", accessToken, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = do(t, http.MethodPost, postPath+"/publish", accessToken, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestNewsletters_RequireAuthentication(t *testing.T) {
+	resp := do(t, http.MethodGet, "/v1/newsletters", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestLegacyRoutes_AreDeprecated(t *testing.T) {
+	resp := do(t, http.MethodGet, "/newsletters", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Deprecation"))
+	assert.NotEmpty(t, resp.Header.Get("Sunset"))
+	assert.Equal(t, `</v1/newsletters>; rel="successor-version"`, resp.Header.Get("Link"))
+	assert.Equal(t, "1", resp.Header.Get("API-Version"))
+
+	resp = do(t, http.MethodGet, "/v1/newsletters", "", nil)
+	assert.Empty(t, resp.Header.Get("Deprecation"))
+}
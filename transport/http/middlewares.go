@@ -1,21 +1,60 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
-	"newsletter/config"
+	"newsletter/internal/infrastructure/idempotency"
 	"newsletter/internal/users/domain"
+	"newsletter/transport/http/handler"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
+// Recover is a middleware that turns a panic in a handler into an HTTP 500
+// Internal Server Error response, logging the panic with its stack.
+//
+// http.ErrAbortHandler is re-panicked so that net/http aborts the response
+// silently, as documented.
+//
+// Usage:
+//
+//	http.ListenAndServe(":8001", Recover(router))
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			slog.Error("handler panicked", "method", r.Method, "path", r.URL.Path, "panic", recovered, "stack", string(debug.Stack()))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // Validate is a middleware that verifies the JWT access token for incoming requests.
 //
 // It checks the "Authorization" header for a Bearer token, validates the token,
 // and extracts the user ID from its claims. If the token is valid, the middleware
-// stores the user ID in the request context under `domain.UserID` and calls the next handler.
+// stores the user ID in the request context under `domain.UserID`, the granted scopes
+// under `domain.Scopes`, and calls the next handler.
 //
 // On failure, it returns an HTTP 401 Unauthorized response for invalid tokens or
 // missing bearer tokens, and HTTP 500 Internal Server Error if the JWT secret is not configured.
@@ -34,35 +73,342 @@ func (app *App) Validate(next http.Handler) http.Handler {
 
 		tokenString := strings.TrimSpace(strings.TrimPrefix(bearer, "Bearer "))
 
-		secret := config.GetEnv("JWT_SECRET_KEY", "")
-		if secret == "" {
+		keys := app.jwtKeys.Verifying()
+		if len(keys) == 0 {
 			slog.Error("JWT secret is not set")
 			http.Error(w, "server configuration error", http.StatusInternalServerError)
 			return
 		}
 
-		token, err := jwt.ParseWithClaims(
-			tokenString,
-			&domain.Claims{},
-			func(t *jwt.Token) (any, error) {
-				return []byte(secret), nil
-			},
-		)
-		if err != nil || !token.Valid {
+		claims, err := parseAccessToken(tokenString, keys)
+		if err != nil {
 			slog.Warn("invalid token", "error", err)
 			http.Error(w, "token invalid", http.StatusUnauthorized)
 			return
 		}
 
-		claims, ok := token.Claims.(*domain.Claims)
-		if !ok || claims == nil {
-			http.Error(w, "invalid claims", http.StatusUnauthorized)
-			return
-		}
-
 		ctx := context.WithValue(r.Context(), domain.UserID, claims.Subject)
+		ctx = context.WithValue(ctx, domain.UserEmail, claims.Email)
+		ctx = context.WithValue(ctx, domain.Scopes, claims.Scopes)
 
 		slog.Debug("authorized request", "user_id", claims.Subject, "path", r.URL.Path)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireScope is a middleware that authorizes requests based on the scopes
+// granted to the access token.
+//
+// It must be chained after Validate, which stores the token scopes in the
+// request context. Requests whose token does not carry the required scope are
+// rejected with HTTP 403 Forbidden.
+//
+// Usage:
+//
+//	http.Handle("/protected", app.Validate(app.RequireScope(domain.ScopeNewslettersRead)(protectedHandler)))
+func (app *App) RequireScope(scope domain.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, _ := r.Context().Value(domain.Scopes).([]domain.Scope)
+			if !domain.HasScope(scopes, scope) {
+				slog.Warn("missing required scope", "scope", scope, "path", r.URL.Path)
+				http.Error(w, "insufficient scope", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SubscribeCORS is a middleware that enforces the per-newsletter CORS policy
+// on the public subscribe endpoint.
+//
+// Requests without an Origin header (server-to-server calls, curl) are passed
+// through unchanged. For browser requests, the newsletter identified by the
+// "newsletter_id" path variable is loaded and the Origin must be listed in its
+// allowed_origins setting; otherwise the request is rejected with HTTP 403.
+// Preflight (OPTIONS) requests are answered directly with HTTP 204.
+func (app *App) SubscribeCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Allow", "POST, OPTIONS")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		newsletterID, err := uuid.Parse(mux.Vars(r)["newsletter_id"])
+		if err != nil {
+			http.Error(w, "invalid newsletter ID", http.StatusBadRequest)
+			return
+		}
+
+		newsletter, err := app.ns.Get(newsletterID)
+		if err != nil {
+			handler.WriteError(w, r, err, "failed to retrieve newsletter")
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		if !newsletter.AllowsOrigin(origin) {
+			slog.Warn("origin not allowed", "origin", origin, "newsletter_id", newsletterID)
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Idempotency-Key")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Limits of the requests handled by Idempotent.
+const (
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodyBytes  = 64 << 10 // 64 KiB
+)
+
+// Idempotent is a middleware that makes POST requests sent with an
+// Idempotency-Key header safe to retry.
+//
+// The first request with a key is executed and its response stored; a
+// retry with the same key, method, path and body is answered with the
+// stored response and an "Idempotent-Replayed: true" header, without
+// reaching the handler. Keys are scoped to the authenticated user, or shared
+// by anonymous clients, and remembered for idempotency.TTL. Requests
+// without the header are passed through unchanged.
+//
+// Responses:
+//
+//	400 Bad Request
+//	  - The key is longer than 255 characters
+//
+//	409 Conflict
+//	  - The request of the key has not completed yet
+//
+//	413 Request Entity Too Large
+//	  - Request body larger than 64 KiB
+//
+//	422 Unprocessable Entity
+//	  - The key was already used for a different request
+//
+// Server errors (5xx) are not stored, so that the request can be retried.
+func (app *App) Idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "Idempotency-Key must be at most 255 characters", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := "public"
+		if userID, ok := r.Context().Value(domain.UserID).(string); ok && userID != "" {
+			scope = "user:" + userID
+		}
+		hash := idempotency.Hash(r.Method, r.URL.Path, body)
+
+		stored, err := app.idempotency.Begin(r.Context(), scope, key, hash, time.Now())
+		if err != nil {
+			handler.WriteError(w, r, err, "failed to check idempotency key")
+			return
+		}
+		if stored != nil {
+			slog.Info("replaying idempotent response", "path", r.URL.Path, "scope", scope)
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", stored.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			// The handler failed or panicked: let the client retry.
+			if err := app.idempotency.Release(context.WithoutCancel(r.Context()), scope, key); err != nil {
+				slog.Error("failed to release idempotency key", "scope", scope, "error", err)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status >= http.StatusInternalServerError {
+			return
+		}
+		response := idempotency.Response{
+			Status:      recorder.status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}
+		if err := app.idempotency.Complete(context.WithoutCancel(r.Context()), scope, key, response, time.Now()); err != nil {
+			slog.Error("failed to store idempotent response", "scope", scope, "error", err)
+			return
+		}
+		completed = true
+	})
+}
+
+// GuardTokens is a middleware that detects the guessing of the tokens of
+// public endpoints, such as the unsubscribe tokens of
+// /subscriptions/unsubscribe.
+//
+// Every response with HTTP 404 Not Found, which these endpoints send for
+// unknown tokens, is logged with the client IP address and, when the request
+// carries a valid access token, the user, and counted against both. A
+// client with a burst of them is blocked for a while: its requests are
+// answered with HTTP 429 Too Many Requests and a Retry-After header,
+// without reaching the handler. See config.Runtime for the limits.
+//
+// Usage:
+//
+//	http.Handle("/subscriptions/unsubscribe", app.GuardTokens(unsubscribeHandler))
+func (app *App) GuardTokens(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys := []string{"ip:" + clientIP(r)}
+		userID := app.tokenSubject(r)
+		if userID != "" {
+			keys = append(keys, "user:"+userID)
+		}
+
+		if remaining, blocked := app.abuse.Blocked(time.Now(), keys...); blocked {
+			slog.Warn("refused request of blocked client", "method", r.Method, "path", r.URL.Path, "ip", clientIP(r), "user_id", userID)
+			w.Header().Set("Retry-After", strconv.Itoa(int(remaining.Round(time.Second).Seconds())))
+			http.Error(w, "too many invalid tokens", http.StatusTooManyRequests)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.status != http.StatusNotFound {
+			return
+		}
+
+		slog.Warn("invalid token", "method", r.Method, "path", r.URL.Path, "ip", clientIP(r), "user_id", userID, "user_agent", r.UserAgent())
+		for _, key := range app.abuse.Fail(time.Now(), keys...) {
+			slog.Warn("blocking client after repeated invalid tokens", "client", key, "path", r.URL.Path)
+		}
+	})
+}
+
+// tokenSubject returns the ID of the user of the valid access token of r, or
+// an empty string for anonymous requests.
+func (app *App) tokenSubject(r *http.Request) string {
+	bearer := r.Header.Get("Authorization")
+	if !strings.HasPrefix(bearer, "Bearer ") {
+		return ""
+	}
+
+	claims, err := parseAccessToken(strings.TrimSpace(strings.TrimPrefix(bearer, "Bearer ")), app.jwtKeys.Verifying())
+	if err != nil {
+		return ""
+	}
+	return claims.Subject
+}
+
+// parseAccessToken returns the claims of an access token signed with any of
+// keys, the current one first, so that tokens signed before a key rotation
+// stay valid until they expire. Only HS256 tokens, the algorithm access
+// tokens are signed with, are accepted.
+func parseAccessToken(tokenString string, keys []string) (*domain.Claims, error) {
+	err := errors.New("no signing key")
+	for _, key := range keys {
+		claims := &domain.Claims{}
+		var token *jwt.Token
+		token, err = jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
+			return []byte(key), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+		if err == nil && token.Valid {
+			return claims, nil
+		}
+		if err == nil {
+			return nil, errors.New("invalid token")
+		}
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// clientIP returns the IP address of the client that sent the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// statusRecorder passes a response through while keeping its status.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if !sr.wroteHeader {
+		sr.status, sr.wroteHeader = status, true
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(p []byte) (int, error) {
+	sr.wroteHeader = true
+	return sr.ResponseWriter.Write(p)
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status, rr.wroteHeader = status, true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(p)
+	return rr.ResponseWriter.Write(p)
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	userdomain "newsletter/internal/users/domain"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccessToken_SigningMethod(t *testing.T) {
+	claims := &userdomain.Claims{RegisteredClaims: &jwt.RegisteredClaims{
+		Subject:   uuid.NewString(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	got, err := parseAccessToken(signed, []string{"secret"})
+	require.NoError(t, err)
+	assert.Equal(t, claims.Subject, got.Subject)
+
+	for _, method := range []jwt.SigningMethod{jwt.SigningMethodHS384, jwt.SigningMethodHS512} {
+		signed, err := jwt.NewWithClaims(method, claims).SignedString([]byte("secret"))
+		require.NoError(t, err)
+		_, err = parseAccessToken(signed, []string{"secret"})
+		assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid, method.Alg())
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = parseAccessToken(unsigned, []string{"secret"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RateLimit is a middleware that limits the requests of API clients per
+// minute, and tells them where they stand so that they can throttle
+// themselves.
+//
+// Requests carrying a valid access token are counted per user, against the
+// requests per minute of the plan of the user (see GET /users/me/limits).
+// Other requests are counted per client IP address, against
+// ANONYMOUS_MAX_REQUESTS_PER_MINUTE. A limit of 0 means unlimited.
+//
+// Limited responses carry the headers:
+//   - X-RateLimit-Limit: the requests allowed per minute
+//   - X-RateLimit-Remaining: the requests left in the current minute
+//   - X-RateLimit-Reset: when the current minute ends, in Unix seconds
+//
+// Requests beyond the limit are answered with HTTP 429 Too Many Requests and
+// a Retry-After header, without reaching the handler. Requests are counted
+// per instance.
+//
+// Usage:
+//
+//	router.Use(app.RateLimit)
+func (app *App) RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.rateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, limit := "ip:"+clientIP(r), app.anonymousRateLimit
+		if userID := app.tokenSubject(r); userID != "" {
+			key, limit = "user:"+userID, 0
+			if id, err := uuid.Parse(userID); err == nil && app.plans != nil {
+				// On failure, the user is limited as on the default plan.
+				plan, _ := app.plans.PlanOf(id)
+				limit = plan.Limits.RequestsPerMinute
+			}
+		}
+		if limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		decision := app.rateLimiter.Take(now, key, limit)
+		header := w.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(decision.Reset.Unix(), 10))
+
+		if !decision.Allowed {
+			slog.Warn("refused request beyond the rate limit", "method", r.Method, "path", r.URL.Path, "client", key, "limit", limit)
+			header.Set("Retry-After", strconv.Itoa(int(decision.Reset.Sub(now).Round(time.Second).Seconds())))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
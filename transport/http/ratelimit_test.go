@@ -0,0 +1,87 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"newsletter/internal/infrastructure/ratelimit"
+	limitsdomain "newsletter/internal/limits/domain"
+	userdomain "newsletter/internal/users/domain"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plansOf serves every user the same plan.
+type plansOf struct {
+	limitsdomain.LimitService
+	plan limitsdomain.Plan
+}
+
+func (p plansOf) PlanOf(uuid.UUID) (limitsdomain.Plan, error) { return p.plan, nil }
+
+func TestRateLimit(t *testing.T) {
+	app := &App{
+		jwtKeys:            userdomain.NewKeyset("secret"),
+		rateLimiter:        ratelimit.New(time.Hour),
+		plans:              plansOf{plan: limitsdomain.Plan{Limits: limitsdomain.Limits{RequestsPerMinute: 2}}},
+		anonymousRateLimit: 1,
+	}
+	handler := app.RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	claims := &userdomain.Claims{RegisteredClaims: &jwt.RegisteredClaims{
+		Subject:   uuid.NewString(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	serve := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/newsletters", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("user", func(t *testing.T) {
+		rec := serve("198.51.100.1:1234", token)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+		reset, err := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64)
+		require.NoError(t, err)
+		assert.Greater(t, reset, time.Now().Unix())
+
+		// The user is counted wherever the requests come from.
+		assert.Equal(t, http.StatusNoContent, serve("198.51.100.2:1234", token).Code)
+		rec = serve("198.51.100.3:1234", token)
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+		assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, serve("203.0.113.7:1234", "").Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve("203.0.113.7:5678", "").Code)
+
+		// Other clients have limits of their own.
+		assert.Equal(t, http.StatusNoContent, serve("203.0.113.8:1234", "").Code)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		app.anonymousRateLimit = 0
+		rec := serve("203.0.113.7:1234", "")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-RateLimit-Limit"))
+	})
+}
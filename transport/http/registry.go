@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net/http"
+	userdomain "newsletter/internal/users/domain"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware wraps a handler, such as GuardTokens or Idempotent.
+type Middleware func(http.Handler) http.Handler
+
+// Route is an endpoint of the API, registered by the RouteGroup of its
+// module.
+type Route struct {
+	Methods []string
+	Path    string // Relative to the prefix of the group, such as "/{post_id}"
+	Handler http.HandlerFunc
+
+	// Scope is the scope the access token must grant, on top of the one of
+	// the group; none when empty. Only routes of authenticated groups have
+	// one.
+	Scope userdomain.Scope
+
+	// Middleware wraps the handler of the route only, inside the middleware
+	// of the group, the first one outermost.
+	Middleware []Middleware
+}
+
+// RouteGroup is a set of routes under a common prefix sharing a middleware
+// chain. Requests go through, in order: Validate when the group is
+// authenticated, RequireScope of the group scope then of the route scope,
+// the middleware of the group and the middleware of the route.
+type RouteGroup struct {
+	Name   string // Name of the group in logs and tests, such as "posts"
+	Prefix string // Path prefix of the routes, such as "/newsletters"
+
+	Authenticated bool             // Whether requests need a valid access token
+	Scope         userdomain.Scope // Scope every route of the group requires; none when empty
+	Middleware    []Middleware
+	Routes        []Route
+}
+
+// Chain wraps h with middleware, the first one outermost.
+func Chain(h http.Handler, middleware ...Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// chain returns the middleware route goes through as part of group.
+func (app *App) chain(group RouteGroup, route Route) []Middleware {
+	var chain []Middleware
+	if group.Authenticated {
+		chain = append(chain, app.Validate)
+	}
+	for _, scope := range []userdomain.Scope{group.Scope, route.Scope} {
+		if scope != "" {
+			chain = append(chain, app.RequireScope(scope))
+		}
+	}
+	chain = append(chain, group.Middleware...)
+	return append(chain, route.Middleware...)
+}
+
+// register registers the routes of group on r, in order, so that a route
+// is matched before the ones registered after it.
+func (app *App) register(r *mux.Router, group RouteGroup) {
+	if group.Prefix != "" {
+		r = r.PathPrefix(group.Prefix).Subrouter()
+	}
+
+	for _, route := range group.Routes {
+		r.Handle(route.Path, Chain(route.Handler, app.chain(group, route)...)).Methods(route.Methods...)
+	}
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	userdomain "newsletter/internal/users/domain"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { calls = append(calls, "handler") }), mark("outer"), mark("inner"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, []string{"outer", "inner", "handler"}, calls)
+}
+
+// TestRouteGroups checks that the routes of authenticated groups reject
+// requests before reaching their handler, which are not set up here.
+func TestRouteGroups(t *testing.T) {
+	app := &App{jwtKeys: userdomain.NewKeyset("secret")}
+	routes := app.Routes()
+	variables := regexp.MustCompile(`\{[^}]+\}`)
+
+	token := func(scopes ...userdomain.Scope) string {
+		claims := &userdomain.Claims{
+			Email:  "owner@example.com",
+			Scopes: scopes,
+			RegisteredClaims: &jwt.RegisteredClaims{
+				Subject:   uuid.NewString(),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		require.NoError(t, err)
+		return signed
+	}
+
+	seen := make(map[string]bool)
+	for _, group := range app.routeGroups() {
+		for _, route := range group.Routes {
+			path := group.Prefix + route.Path
+			for _, method := range route.Methods {
+				key := method + " " + path
+				assert.False(t, seen[key], "%s registered twice", key)
+				seen[key] = true
+			}
+
+			if !group.Authenticated {
+				assert.Empty(t, route.Scope, "%s requires a scope without authentication", path)
+				continue
+			}
+
+			t.Run(group.Name+" "+path, func(t *testing.T) {
+				url := "/v1" + variables.ReplaceAllStringFunc(path, func(string) string { return uuid.NewString() })
+				serve := func(method, bearer string) int {
+					req := httptest.NewRequest(method, url, nil)
+					if bearer != "" {
+						req.Header.Set("Authorization", "Bearer "+bearer)
+					}
+					rec := httptest.NewRecorder()
+					routes.ServeHTTP(rec, req)
+					return rec.Code
+				}
+
+				for _, method := range route.Methods {
+					assert.Equal(t, http.StatusUnauthorized, serve(method, ""), method)
+
+					required := slices.DeleteFunc([]userdomain.Scope{group.Scope, route.Scope}, func(s userdomain.Scope) bool { return s == "" })
+					if len(required) == 0 {
+						continue
+					}
+					granted := slices.DeleteFunc(append(slices.Clone(userdomain.AllScopes), userdomain.ScopeAdmin), func(s userdomain.Scope) bool {
+						return slices.Contains(required, s)
+					})
+					assert.Equal(t, http.StatusForbidden, serve(method, token(granted...)), "%s without %v", method, required)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"fmt"
+	"log/slog"
+	"newsletter/config"
+	"newsletter/transport/http/handler"
+)
+
+// SetLogLevel sets the level of the logs that Reload changes to LOG_LEVEL.
+func (app *App) SetLogLevel(level *slog.LevelVar) {
+	app.logLevel = level
+}
+
+// Reload reads the reloadable settings again (see config.Reloadable) and
+// applies them without a restart: the log level, the number of workers, the
+// default cap on the campaigns of a newsletter sent at once and the limits
+// on clients guessing tokens. Settings edited in the .env file are read
+// again first. Nothing is applied when a setting is invalid.
+//
+// It is called on SIGHUP and by POST /admin/reload. Removed workers stop
+// after their current job, so that campaign sends in flight are not
+// interrupted, and the caps of single newsletters set with
+// PUT /admin/throttling are kept.
+func (app *App) Reload() (*handler.RuntimeSettings, error) {
+	changed, err := config.ReloadEnvFile()
+	if err != nil {
+		return nil, err
+	}
+
+	rt, err := config.LoadRuntime()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", config.ErrInvalidSettings, err)
+	}
+
+	if app.logLevel != nil {
+		app.logLevel.Set(rt.LogLevel)
+	}
+	app.wp.Resize(rt.Workers)
+	throttling := app.throttle.Limits()
+	throttling.PerNewsletter = rt.CampaignsPerNewsletter
+	if err := app.throttle.SetLimits(throttling); err != nil {
+		return nil, fmt.Errorf("%w: %w", config.ErrInvalidSettings, err)
+	}
+	app.abuse.SetLimits(rt.Abuse)
+
+	settings := &handler.RuntimeSettings{
+		LogLevel:               rt.LogLevel.String(),
+		Workers:                app.wp.Workers(),
+		CampaignsPerNewsletter: rt.CampaignsPerNewsletter,
+		AbuseThreshold:         rt.Abuse.Threshold,
+		AbuseWindow:            rt.Abuse.Window.String(),
+		AbuseBlock:             rt.Abuse.Block.String(),
+		Changed:                changed,
+	}
+	if settings.Changed == nil {
+		settings.Changed = []string{}
+	}
+
+	slog.Info("configuration reloaded",
+		"log_level", settings.LogLevel,
+		"workers", settings.Workers,
+		"campaigns_per_newsletter", settings.CampaignsPerNewsletter,
+		"abuse_threshold", settings.AbuseThreshold,
+		"changed", settings.Changed,
+	)
+	return settings, nil
+}
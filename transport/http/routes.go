@@ -2,108 +2,789 @@ package http
 
 import (
 	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"newsletter/config"
 	"newsletter/transport/http/handler"
+	"time"
 
 	"github.com/gorilla/mux"
 
-	awsrepo "newsletter/internal/infrastructure/aws"
+	activityapp "newsletter/internal/activity/application"
+	activitydomain "newsletter/internal/activity/domain"
+	activityfirebase "newsletter/internal/activity/infrastructure/firebase"
+	activityrepo "newsletter/internal/activity/infrastructure/postgres"
+	adminapp "newsletter/internal/admin/application"
+	admindomain "newsletter/internal/admin/domain"
+	adminrepo "newsletter/internal/admin/infrastructure/postgres"
+	analyticsapp "newsletter/internal/analytics/application"
+	analyticsdomain "newsletter/internal/analytics/domain"
+	analyticsrepo "newsletter/internal/analytics/infrastructure/firebase"
+	campaignapp "newsletter/internal/campaigns/application"
+	campaignrepo "newsletter/internal/campaigns/infrastructure/postgres"
+	"newsletter/internal/infrastructure/abuse"
+	"newsletter/internal/infrastructure/alerting"
+	"newsletter/internal/infrastructure/artifacts"
 	"newsletter/internal/infrastructure/database"
 	"newsletter/internal/infrastructure/firebase"
+	"newsletter/internal/infrastructure/idempotency"
+	"newsletter/internal/infrastructure/ratelimit"
+	"newsletter/internal/infrastructure/secretbox"
 	"newsletter/internal/infrastructure/workerpool"
+	limitsapp "newsletter/internal/limits/application"
+	limitsdomain "newsletter/internal/limits/domain"
+	limitsrepo "newsletter/internal/limits/infrastructure/postgres"
+	linkapp "newsletter/internal/links/application"
+	linkrepo "newsletter/internal/links/infrastructure/postgres"
 	newsletterapp "newsletter/internal/newsletters/application"
+	newsletterdomain "newsletter/internal/newsletters/domain"
+	newslettermemory "newsletter/internal/newsletters/infrastructure/memory"
 	newsletterrepo "newsletter/internal/newsletters/infrastructure/postgres"
 	serviceapp "newsletter/internal/notifications/application"
+	notificationdomain "newsletter/internal/notifications/domain"
+	notificationinfra "newsletter/internal/notifications/infrastructure"
+	"newsletter/internal/notifications/infrastructure/outbox"
+	postapp "newsletter/internal/posts/application"
+	postdomain "newsletter/internal/posts/domain"
+	postrepo "newsletter/internal/posts/infrastructure/postgres"
+	"newsletter/internal/posts/infrastructure/spamcheck"
+	segmentapp "newsletter/internal/segments/application"
+	segmentrepo "newsletter/internal/segments/infrastructure/postgres"
 	subscribeapp "newsletter/internal/subscriptions/application"
+	subscriptiondomain "newsletter/internal/subscriptions/domain"
+	"newsletter/internal/subscriptions/infrastructure/captcha"
+	"newsletter/internal/subscriptions/infrastructure/emailcheck"
 	subscriberepo "newsletter/internal/subscriptions/infrastructure/firebase"
+	subscriptionmemory "newsletter/internal/subscriptions/infrastructure/memory"
 	userapp "newsletter/internal/users/application"
+	userdomain "newsletter/internal/users/domain"
+	usermemory "newsletter/internal/users/infrastructure/memory"
 	userrepo "newsletter/internal/users/infrastructure/postgres"
 )
 
+// subscriptionStore is what the services need of the subscription
+// repository: storing subscriptions and counting subscribers.
+type subscriptionStore interface {
+	subscriptiondomain.SubscriptionRepository
+	newsletterdomain.SubscriberCounter
+	admindomain.SubscriptionCounter
+}
+
 type App struct {
+	ns      newsletterdomain.NewsletterService
+	monitor *alerting.Monitor
+	jwtKeys *userdomain.Keyset // Keys verifying access tokens, see Validate
+
+	idempotency idempotency.Store // Responses of requests with an Idempotency-Key, see Idempotent
+	abuse       *abuse.Detector   // Clients guessing tokens, see GuardTokens
+
+	// Requests per minute of API clients, see RateLimit
+	rateLimiter        *ratelimit.Limiter
+	plans              limitsdomain.LimitService // Requests per minute of users
+	anonymousRateLimit int                       // Requests per minute of anonymous clients, 0 for unlimited
+
+	legacySunset       time.Time // Sunset of the unversioned routes, see Deprecated
+	compressionMinSize int       // Size from which responses are compressed, see Compress
+
+	// Components whose settings are applied again by Reload
+	wp       *workerpool.WorkerPool
+	throttle *campaignapp.Throttle
+	logLevel *slog.LevelVar // nil until SetLogLevel
+
+	closers []func() // Close the database connections, see Close
+
 	uh handler.UserHandler
 	nh handler.NewsletterHandler
 	sh handler.SubscriptionHandler
+	eh handler.SenderHandler
+	ph handler.PostHandler
+	xh handler.ExportHandler
+	dh handler.DownloadHandler
+	wh handler.WebhookHandler
+	ch handler.CampaignHandler
+	ah handler.AnalyticsHandler
+	vh handler.ActivityHandler
+	mh handler.MetricsHandler
+	th handler.AdminHandler
+	lh handler.LimitHandler
+	gh handler.SegmentHandler
+	bh handler.PublicHandler
+	rh handler.ShortLinkHandler
+	oh *handler.OutboxHandler // nil unless EMAIL_DRY_RUN and DEBUG_OUTBOX are enabled
 }
 
 // NewApp initializes and returns a new instance of the App.
 //
 // It performs the following steps:
-// 1. Connects to the Postgres database with retry logic. Panics if the connection fails.
-// 2. Initializes a Firebase Firestore client. Panics if initialization fails.
-// 3. Creates repositories for users, newsletters, and subscriptions.
-// 4. Creates application services for user management, authentication, newsletters, and subscriptions.
-// 5. Creates HTTP handlers for users, newsletters, and subscriptions.
-// 6. Returns a pointer to an App struct containing the initialized handlers.
+// 1. Connects to the Postgres database with retry logic and initializes a Firebase Firestore client, unless cfg.Store is memory. Panics if either fails.
+// 2. Initializes the configured email provider. Panics if initialization fails.
+// 3. Creates repositories for users, newsletters, posts, campaigns, short links, segments, subscriptions, analytics, activity events, plan usage, and system-wide statistics.
+// 4. Creates application services for user management, authentication, newsletters, posts, campaigns, short links, segments, subscriptions, analytics, the activity feed, plan limits, and administration.
+// 5. Creates HTTP handlers for users, newsletters, newsletter senders, posts, campaigns, segments, subscriptions, exports, downloads, analytics, the activity feed, provider webhooks, metrics, administration, plan limits, public pages, short links, and the dry-run outbox.
+// 6. Returns a pointer to an App struct containing the initialized handlers and the services used by middlewares.
+//
+// With the memory store, users, newsletters and subscriptions are kept in
+// memory and the features needing Postgres, such as posts, campaigns and
+// segments, answer with errors. The activity feed then only lists subscriber
+// events.
 //
-// This function is typically called once at application startup to prepare the app for handling HTTP requests.
-func NewApp(wp *workerpool.WorkerPool) *App {
-	dbConnection := database.InitPostgres()
-	if dbConnection == nil {
-		log.Fatalf("Can't connect to Postgres!")
+// cfg is the validated configuration returned by config.Load. recentErrors,
+// which may be nil, provides the errors listed by the administration API.
+//
+// This function is called once by Run, which also starts wp and closes the
+// App on shutdown, and by tests needing the routes of the complete app.
+func NewApp(cfg *config.Config, wp *workerpool.WorkerPool, recentErrors handler.RecentErrors) *App {
+	links, err := handler.NewLinkBuilder(cfg.BaseURL)
+	if err != nil {
+		log.Fatalf("Invalid link configuration! Error: %v", err)
 	}
 
-	firebaseClient, err := firebase.InitFirestore(context.TODO())
+	emailProvider, err := notificationinfra.NewProvider(cfg.Email)
 	if err != nil {
-		log.Fatalf("Can't connect to Firebase! Error: %v", err)
+		log.Fatalf("Can't initialize email provider! Error: %v", err)
 	}
 
-	sesClient, err := awsrepo.InitSESClient()
+	passwordHasher, err := userapp.NewPasswordHasher(cfg.Passwords.Algorithm, cfg.Passwords.BcryptCost, cfg.Passwords.Argon2)
 	if err != nil {
-		log.Fatalf("Can't initialize SES client! Error: %v", err)
+		log.Fatalf("Can't configure password hashing! Error: %v", err)
+	}
+
+	// Keys signing access tokens and two-factor challenges, replaced by
+	// ReloadKeys when secrets change
+	jwtKeys := userdomain.NewKeyset(cfg.JWTSecret, cfg.JWTPreviousSecrets...)
+
+	// Two-factor authentication is disabled when no encryption key is configured
+	var totpCipher userdomain.SecretCipher
+	if len(cfg.TOTPKey) > 0 {
+		if totpCipher, err = secretbox.New(cfg.TOTPKey); err != nil {
+			log.Fatalf("Can't configure TOTP encryption! Error: %v", err)
+		}
+	}
+
+	// Email addresses of subscribers are stored in clear when no encryption
+	// key is configured
+	var pii *secretbox.PII
+	if len(cfg.PIIKey) > 0 {
+		if pii, err = secretbox.NewPII(cfg.PIIKey); err != nil {
+			log.Fatalf("Can't configure PII encryption! Error: %v", err)
+		}
 	}
 
 	// Initialize repositories
-	userRepo := userrepo.NewUserRepository(dbConnection)
-	newsletterRepo := newsletterrepo.NewNewsletterRepository(dbConnection)
-	subscriptionRepo := subscriberepo.NewSubscriptionRepository(firebaseClient)
+	var (
+		dbConnection     database.DB
+		poolStats        func() database.Stats
+		userRepo         userdomain.UserRepository
+		newsletterRepo   newsletterdomain.NewsletterRepository
+		subscriptionRepo subscriptionStore
+		analyticsRepo    analyticsdomain.AnalyticsRepository
+		activitySources  []activitydomain.EventSource
+		idempotencyStore idempotency.Store
+		emailCounter     limitsdomain.EmailCounter // nil with the memory store, which has no campaigns
+		planStore        limitsdomain.PlanStore    // nil with the memory store: everyone is on the default plan
+		closers          []func()
+	)
+	switch cfg.Store {
+	case config.StoreMemory:
+		slog.Warn("STORE=memory: data is lost on restart, and posts, campaigns, segments, security events, magic links, two-factor authentication and administration statistics are unavailable")
+		dbConnection = database.Unavailable(errors.New("not available with STORE=memory"))
+		poolStats = func() database.Stats { return database.Stats{} }
+		userRepo = usermemory.NewUserRepository()
+		newsletterRepo = newslettermemory.NewNewsletterRepository()
+		memorySubscriptions := subscriptionmemory.NewSubscriptionRepository()
+		subscriptionRepo, analyticsRepo = memorySubscriptions, memorySubscriptions
+		activitySources = []activitydomain.EventSource{memorySubscriptions}
+		idempotencyStore = idempotency.NewMemoryStore()
+	default:
+		pool := database.InitPostgres(cfg.DSN, cfg.Database.Pool, cfg.Database.SlowQueryThreshold)
+		if pool == nil {
+			log.Fatalf("Can't connect to Postgres!")
+		}
+
+		firebaseClient, err := firebase.InitFirestore(context.TODO())
+		if err != nil {
+			log.Fatalf("Can't connect to Firebase! Error: %v", err)
+		}
+		closers = append(closers, pool.Close, func() {
+			if err := firebaseClient.Close(); err != nil {
+				slog.Warn("failed to close the Firestore client", "error", err)
+			}
+		})
+		if cfg.FirestoreIndexesFile != "" {
+			go verifyFirestoreIndexes(cfg.FirestoreIndexesFile)
+		}
+
+		firestoreSubscriptions := subscriberepo.NewSubscriptionRepository(firebaseClient)
+		firestoreSubscriptions.SetEncryption(pii)
+		subscriptionEvents := activityfirebase.NewSubscriptionEventRepository(firebaseClient)
+		subscriptionEvents.SetEncryption(pii)
+
+		dbConnection = pool
+		poolStats = func() database.Stats { return database.PoolStats(pool) }
+		userRepo = userrepo.NewUserRepository(pool)
+		newsletterRepo = newsletterrepo.NewNewsletterRepository(pool)
+		subscriptionRepo = firestoreSubscriptions
+		analyticsRepo = analyticsrepo.NewAnalyticsRepository(firebaseClient)
+		activitySources = []activitydomain.EventSource{
+			subscriptionEvents,
+			activityrepo.NewCampaignEventRepository(pool),
+		}
+		idempotencyStore = idempotency.NewPostgresStore(pool)
+		emailCounter = limitsrepo.NewUsageRepository(pool)
+		planStore = limitsrepo.NewPlanRepository(pool)
+	}
+	securityEventRepo := userrepo.NewSecurityEventRepository(dbConnection)
+	loginTokenRepo := userrepo.NewLoginTokenRepository(dbConnection)
+	twoFactorRepo := userrepo.NewTwoFactorRepository(dbConnection)
+	postRepo := postrepo.NewPostRepository(dbConnection)
+	campaignRepo := campaignrepo.NewCampaignRepository(dbConnection)
+	linkRepo := linkrepo.NewLinkRepository(dbConnection)
+	segmentRepo := segmentrepo.NewSegmentRepository(dbConnection)
+	statsRepo := adminrepo.NewStatsRepository(dbConnection)
 
 	// Initialize services
-	userService := userapp.NewUserService(userRepo)
-	authService := userapp.NewAuthenticationService(userRepo)
-	newsletterService := newsletterapp.NewNewsletterService(newsletterRepo)
+	userService := userapp.NewUserService(userRepo, passwordHasher)
+	authService := userapp.NewAuthenticationService(userRepo, passwordHasher, userapp.TokenConfig{Keys: jwtKeys})
+	securityEventService := userapp.NewSecurityEventService(securityEventRepo)
+	magicLinkService := userapp.NewMagicLinkService(userRepo, loginTokenRepo)
+	twoFactorService := userapp.NewTwoFactorService(twoFactorRepo, totpCipher, jwtKeys)
+	limitService := limitsapp.NewLimitService(limitsdomain.Plan{Name: limitsdomain.DefaultPlanName, Limits: cfg.Plan}, newsletterRepo, subscriptionRepo, emailCounter)
+	limitService.SetPlans(planStore, cfg.Plans...)
+	newsletterService := newsletterapp.NewNewsletterService(newsletterRepo, subscriptionRepo)
+	newsletterService.SetLimits(limitService)
+	newsletterService.SetLengthLimits(cfg.Content.MaxNewsletterName, cfg.Content.MaxNewsletterDescription)
+	postService := postapp.NewPostService(postRepo)
+	postService.SetMaxBodyLength(cfg.Content.MaxPostBody)
+	campaignService := campaignapp.NewCampaignService(campaignRepo)
+	campaignService.SetLimits(limitService)
+	campaignThrottle := campaignapp.NewThrottle(cfg.Workers.CampaignsPerNewsletter)
+	linkService := linkapp.NewLinkService(linkRepo)
+	segmentService := segmentapp.NewSegmentService(segmentRepo, subscriptionRepo, segmentRepo)
 	subscriptionService := subscribeapp.NewSubscriptionService(subscriptionRepo)
-	emailService := serviceapp.NewEmailService(sesClient)
+	subscriptionService.SetSubscribeCooldown(cfg.Subscriptions.Cooldown)
+	subscriptionService.SetUnsubscribeTokens(cfg.Unsubscribe.SecretKey, cfg.Unsubscribe.PreviousSecretKeys, cfg.Unsubscribe.TokenTTL, cfg.Unsubscribe.LegacyTokens)
+	emailService := serviceapp.NewEmailService(emailProvider)
+	emailService.SetMaxAttachmentSize(cfg.Email.MaxAttachmentSize)
+	analyticsService := analyticsapp.NewAnalyticsService(analyticsRepo)
+	activityService := activityapp.NewActivityService(activitySources...)
+	adminService := adminapp.NewAdminService(statsRepo, subscriptionRepo)
+
+	// Initialize operational alerting (disabled when no destination is configured)
+	monitor := alerting.NewMonitorFromConfig(wp, emailService, cfg.Alerting)
+
+	// Initialize the detection of clients guessing unsubscribe tokens
+	abuseDetector := abuse.NewDetector(cfg.Abuse)
+
+	// Initialize CAPTCHA verification of public subscriptions (disabled when no provider is configured)
+	captchaVerifier, err := captcha.NewVerifierFromConfig(cfg.Subscriptions.Captcha)
+	if err != nil {
+		log.Fatalf("Can't configure CAPTCHA verification! Error: %v", err)
+	}
+
+	// Initialize validation of subscriber addresses
+	subscriptionService.SetEmailValidator(emailcheck.NewValidatorFromConfig(cfg.Subscriptions.EmailCheck))
+	subscriptionService.SetLimits(limitService)
+
+	// Initialize the content check of posts before sending (disabled when SPAM_SCORE_THRESHOLD is 0)
+	var spamChecker postdomain.ContentChecker
+	if cfg.SpamScoreThreshold > 0 {
+		spamChecker = spamcheck.NewChecker(cfg.SpamScoreThreshold)
+	}
+
+	// Initialize storage of generated downloads (exports are disabled when no secret is configured)
+	artifactStore, err := artifacts.NewStoreFromConfig(cfg.Artifacts)
+	if err != nil {
+		log.Fatalf("Can't configure artifact storage! Error: %v", err)
+	}
+
+	// Initialize reading of the received emails stored in S3 (only when the SES webhooks are enabled)
+	webhookToken := cfg.HTTP.SESWebhookToken
+	var inboundMail notificationdomain.InboundMailStore
+	if webhookToken != "" {
+		if inboundMail, err = notificationinfra.NewInboundMailStore(); err != nil {
+			log.Fatalf("Can't configure reading of received emails! Error: %v", err)
+		}
+	}
 
 	// Initialize handlers
-	userHandler := handler.NewUserHandler(userService, authService)
-	newsletterHandler := handler.NewNewsletterHandler(newsletterService)
-	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService, emailService, wp)
+	userHandler := handler.NewUserHandler(userService, authService, securityEventService, magicLinkService, twoFactorService, emailService, wp, links)
+	newsletterHandler := handler.NewNewsletterHandler(newsletterService, links)
+	newsletterHandler.SetCacheTTL(cfg.HTTP.NewsletterCacheTTL)
+	newsletterHandler.SetCaptchaWidget(cfg.Subscriptions.Captcha.Provider, cfg.Subscriptions.Captcha.SiteKey)
+	newsletterHandler.SetSubscriberCopier(subscriptionService)
+	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService, newsletterService, emailService, wp, captchaVerifier, links)
+	subscriptionHandler.SetCaptchaWidget(cfg.Subscriptions.Captcha.Provider, cfg.Subscriptions.Captcha.SiteKey)
+	senderVerifier, _ := emailProvider.(notificationdomain.SenderVerifier) // nil when unsupported
+	var outboxHandler *handler.OutboxHandler
+	if recorder, ok := emailProvider.(*outbox.Provider); ok && cfg.HTTP.DebugOutbox {
+		outboxHandler = handler.NewOutboxHandler(recorder, cfg.HTTP.MetricsToken)
+	}
+	dkimTokens, _ := emailProvider.(notificationdomain.DKIMTokenSource) // nil when unsupported
+	domainChecker := serviceapp.NewDomainChecker(net.DefaultResolver, serviceapp.SPFInclude(emailProvider.Name()), dkimTokens)
+	senderHandler := handler.NewSenderHandler(newsletterService, senderVerifier, domainChecker)
+	postHandler := handler.NewPostHandler(postService, newsletterService, subscriptionService, emailService, wp, campaignService, links, campaignThrottle, segmentService)
+	postHandler.SetContentChecker(spamChecker)
+	campaignHandler := handler.NewCampaignHandler(campaignService, postService, newsletterService, subscriptionService, emailService, wp, links, campaignThrottle, segmentService)
+	if cfg.ClickTracking {
+		postHandler.SetClickTracking(linkService)
+		campaignHandler.SetClickTracking(linkService)
+	}
+	shortLinkHandler := handler.NewShortLinkHandler(linkService)
+	exportHandler := handler.NewExportHandler(newsletterService, postService, subscriptionService, emailService, wp, artifactStore, links)
+	exportHandler.SetAttachmentLimit(cfg.Email.MaxAttachmentSize)
+	downloadHandler := handler.NewDownloadHandler(artifactStore)
+	analyticsHandler := handler.NewAnalyticsHandler(analyticsService, newsletterService)
+	activityHandler := handler.NewActivityHandler(activityService, newsletterService)
+	webhookHandler := handler.NewWebhookHandler(campaignService, newsletterService, userService, emailService, wp, inboundMail, webhookToken)
+	metricsHandler := handler.NewMetricsHandler(poolStats, wp, abuseDetector, cfg.HTTP.MetricsToken)
+	metricsHandler.SetLinks(linkService)
+	adminHandler := handler.NewAdminHandler(adminService, wp, recentErrors, campaignThrottle)
+	limitHandler := handler.NewLimitHandler(limitService)
+	segmentHandler := handler.NewSegmentHandler(segmentService, newsletterService)
+	publicHandler := handler.NewPublicHandler(newsletterService, postService, links)
+
+	app := &App{
+		ns:      newsletterService,
+		monitor: monitor,
+		jwtKeys: jwtKeys,
+
+		idempotency: idempotencyStore,
+		abuse:       abuseDetector,
+
+		rateLimiter:        ratelimit.New(ratelimit.Window),
+		plans:              limitService,
+		anonymousRateLimit: cfg.AnonymousRequestsPerMinute,
+
+		legacySunset:       cfg.HTTP.LegacySunset,
+		compressionMinSize: cfg.HTTP.CompressionMinSize,
+
+		wp:       wp,
+		throttle: campaignThrottle,
+
+		closers: closers,
 
-	return &App{
 		uh: *userHandler,
 		nh: *newsletterHandler,
 		sh: *subscriptionHandler,
+		eh: *senderHandler,
+		ph: *postHandler,
+		xh: *exportHandler,
+		dh: *downloadHandler,
+		wh: *webhookHandler,
+		ch: *campaignHandler,
+		ah: *analyticsHandler,
+		vh: *activityHandler,
+		mh: *metricsHandler,
+		th: *adminHandler,
+		lh: *limitHandler,
+		gh: *segmentHandler,
+		bh: *publicHandler,
+		rh: *shortLinkHandler,
+		oh: outboxHandler,
+	}
+	app.th.SetReloader(app)
+	return app
+}
+
+// Close closes the connections to the databases. The App must not serve
+// requests or run jobs afterwards.
+func (app *App) Close() {
+	for _, close := range app.closers {
+		close()
+	}
+}
+
+// StartMonitoring runs the worker pool alerting monitor until ctx is cancelled.
+// It is a no-op when alerting is not configured.
+func (app *App) StartMonitoring(ctx context.Context) {
+	if app.monitor == nil {
+		return
+	}
+	go app.monitor.Run(ctx)
+}
+
+// ReloadKeys replaces the keys signing and verifying access tokens with
+// JWT_SECRET_KEY and JWT_PREVIOUS_SECRET_KEYS, after secrets were
+// refreshed. Tokens signed with a key listed in JWT_PREVIOUS_SECRET_KEYS
+// stay valid.
+func (app *App) ReloadKeys() {
+	current, previous := config.JWTKeysFromEnv()
+	if current == "" {
+		slog.Error("JWT_SECRET_KEY is empty after refreshing secrets: keeping the previous keys")
+		return
+	}
+	app.jwtKeys.Set(current, previous...)
+	slog.Info("reloaded JWT keys", "previous_keys", len(previous))
+}
+
+// ResumeCampaigns queues again the campaigns interrupted by the last
+// shutdown or crash. Recipients that were already emailed are skipped.
+func (app *App) ResumeCampaigns() {
+	if err := app.ch.ResumeUnfinished(); err != nil {
+		log.Printf("Can't resume unfinished campaigns! Error: %v", err)
 	}
 }
 
 // Routes sets up all the HTTP routes for the application and returns an http.Handler.
 //
-// It uses Gorilla Mux to create subrouters for different resource types:
+// Each module declares its routes, with the authentication and scopes they
+// require, as a RouteGroup of routeGroups.
+//
+// Every route is served under the /v1 prefix. The same routes are also served
+// without a prefix for existing API consumers; those responses carry
+// Deprecation and Sunset headers pointing to their /v1 successor. Only the
+// /metrics endpoint of monitoring systems and the /debug/outbox endpoint of
+// the email dry run are not versioned. API requests are limited per minute
+// (see RateLimit). Large responses are compressed (see
+// Compress). Panics in handlers are answered with 500 Internal Server Error
+// (see Recover).
 func (app *App) Routes() http.Handler {
 	r := mux.NewRouter()
 
-	// User routes
-	userRoutes := r.PathPrefix("/users").Subrouter()
-	// POST /users/signup - Handles user registration
-	userRoutes.HandleFunc("/signup", app.uh.SignUp).Methods("POST")
-	// POST /users/signin - Handles user login
-	userRoutes.HandleFunc("/signin", app.uh.Signin).Methods("POST")
-
-	// Newsletter routes
-	newsletterRoutes := r.PathPrefix("/newsletters").Subrouter()
-	// POST /newsletters - Creates a new newsletter (requires validation)
-	newsletterRoutes.Handle("", app.Validate(http.HandlerFunc(app.nh.Create))).Methods("POST")
-	// GET /newsletters - Retrieves all newsletters (requires validation)
-	newsletterRoutes.Handle("", app.Validate(http.HandlerFunc(app.nh.GetAll))).Methods("GET")
-
-	// Subscription routes
-	subscriptionRoutes := r.PathPrefix("/subscriptions").Subrouter()
-	// POST /subscriptions/{newsletter_id} - Subscribes the current user to a newsletter.
-	subscriptionRoutes.HandleFunc("/{newsletter_id}", app.sh.Subscribe).Methods("POST")
-	// POST /subscriptions/{newsletter_id} - Unsubscribes the current user from a newsletter.
-	subscriptionRoutes.HandleFunc("/unsubscribe", app.sh.Unsubscribe).Methods("DELETE")
-
-	return r
+	// GET /metrics - Database pool and job queue statistics for monitoring systems (requires the metrics token); not versioned
+	r.HandleFunc("/metrics", app.mh.Metrics).Methods("GET")
+	if app.oh != nil {
+		// GET /debug/outbox - Emails recorded instead of being sent (only with EMAIL_DRY_RUN and DEBUG_OUTBOX; requires the metrics token); not versioned
+		r.HandleFunc("/debug/outbox", app.oh.Outbox).Methods("GET")
+	}
+
+	v1 := r.PathPrefix(handler.APIPrefix).Subrouter()
+	v1.Use(NegotiateVersion(1), app.RateLimit)
+	app.registerRoutes(v1)
+
+	sunset := app.legacySunset
+	if sunset.IsZero() {
+		sunset = defaultLegacySunset
+	}
+	legacy := r.NewRoute().Subrouter()
+	legacy.Use(Deprecated(legacyRoutesDeprecatedAt, sunset, handler.APIPrefix), NegotiateVersion(0), app.RateLimit)
+	app.registerRoutes(legacy)
+
+	return Recover(Compress(app.compressionMinSize)(r))
+}
+
+// registerRoutes registers the routes of every group of routeGroups on r.
+func (app *App) registerRoutes(r *mux.Router) {
+	for _, group := range app.routeGroups() {
+		app.register(r, group)
+	}
+}
+
+// routeGroups returns the routes of the API by module, in the order they
+// are registered.
+func (app *App) routeGroups() []RouteGroup {
+	return []RouteGroup{
+		app.userRoutes(),
+		app.accountRoutes(),
+		app.downloadRoutes(),
+		app.newsletterRoutes(),
+		app.postRoutes(),
+		app.renderRoutes(),
+		app.segmentRoutes(),
+		app.adminRoutes(),
+		app.campaignRoutes(),
+		app.webhookRoutes(),
+		app.publicRoutes(),
+		app.subscriptionRoutes(),
+	}
+}
+
+// userRoutes are the routes signing users up and in.
+func (app *App) userRoutes() RouteGroup {
+	return RouteGroup{
+		Name:   "users",
+		Prefix: "/users",
+		Routes: []Route{
+			// POST /users/signup - Handles user registration
+			{Methods: []string{"POST"}, Path: "/signup", Handler: app.uh.SignUp},
+			// POST /users/signin - Handles user login
+			{Methods: []string{"POST"}, Path: "/signin", Handler: app.uh.Signin},
+			// POST /users/signin/2fa - Completes the sign in of accounts with two-factor authentication
+			{Methods: []string{"POST"}, Path: "/signin/2fa", Handler: app.uh.SigninTwoFactor},
+			// POST /users/magic-link - Emails a passwordless sign in link
+			{Methods: []string{"POST"}, Path: "/magic-link", Handler: app.uh.RequestMagicLink},
+			// GET /users/magic-login - Exchanges a sign in link for an access token
+			{Methods: []string{"GET"}, Path: "/magic-login", Handler: app.uh.MagicLogin},
+		},
+	}
+}
+
+// accountRoutes are the routes of the account of the current user.
+func (app *App) accountRoutes() RouteGroup {
+	return RouteGroup{
+		Name:          "account",
+		Prefix:        "/users/me",
+		Authenticated: true,
+		Routes: []Route{
+			// GET /users/me/security-events - Lists the account activity of the current user
+			{Methods: []string{"GET"}, Path: "/security-events", Handler: app.uh.SecurityEvents, Middleware: []Middleware{SelectFields}},
+			// POST /users/me/2fa/enroll - Generates a TOTP secret and recovery codes
+			{Methods: []string{"POST"}, Path: "/2fa/enroll", Handler: app.uh.EnrollTwoFactor},
+			// POST /users/me/2fa/verify - Enables two-factor authentication with a TOTP code
+			{Methods: []string{"POST"}, Path: "/2fa/verify", Handler: app.uh.VerifyTwoFactor},
+			// POST /users/me/2fa/disable - Disables two-factor authentication with a TOTP or recovery code
+			{Methods: []string{"POST"}, Path: "/2fa/disable", Handler: app.uh.DisableTwoFactor},
+			// GET /users/me/limits - Returns the plan limits of the current user and their usage
+			{Methods: []string{"GET"}, Path: "/limits", Handler: app.lh.Get},
+			// GET /users/me/export - Emails a download link to an archive of the account
+			{Methods: []string{"GET"}, Path: "/export", Handler: app.xh.Export, Scope: userdomain.ScopeNewslettersRead},
+		},
+	}
+}
+
+// downloadRoutes are the routes of the files generated for download,
+// authorized by their signed links.
+func (app *App) downloadRoutes() RouteGroup {
+	return RouteGroup{
+		Name: "downloads",
+		Routes: []Route{
+			// GET /downloads/{name} - Downloads a generated file
+			{Methods: []string{"GET"}, Path: "/downloads/{name}", Handler: app.dh.Download},
+			// GET /exports/{name} - Downloads a generated archive through a link emailed before /downloads existed
+			{Methods: []string{"GET"}, Path: "/exports/{name}", Handler: app.dh.Download},
+		},
+	}
+}
+
+// newsletterRoutes are the routes of the newsletters of the current user,
+// their subscribers and statistics.
+func (app *App) newsletterRoutes() RouteGroup {
+	return RouteGroup{
+		Name:          "newsletters",
+		Prefix:        "/newsletters",
+		Authenticated: true,
+		Routes: []Route{
+			// POST /newsletters - Creates a new newsletter
+			{Methods: []string{"POST"}, Path: "", Handler: app.nh.Create, Scope: userdomain.ScopeNewslettersWrite},
+			// GET /newsletters - Retrieves all newsletters
+			{Methods: []string{"GET"}, Path: "", Handler: app.nh.GetAll, Scope: userdomain.ScopeNewslettersRead, Middleware: []Middleware{SelectFields}},
+			// PUT /newsletters/{newsletter_id}/settings - Replaces the settings of a newsletter
+			{Methods: []string{"PUT"}, Path: "/{newsletter_id}/settings", Handler: app.nh.UpdateSettings, Scope: userdomain.ScopeNewslettersWrite},
+			// PUT /newsletters/{newsletter_id}/slug - Changes the slug of the public URLs of a newsletter
+			{Methods: []string{"PUT"}, Path: "/{newsletter_id}/slug", Handler: app.nh.UpdateSlug, Scope: userdomain.ScopeNewslettersWrite},
+			// POST /newsletters/{newsletter_id}/duplicate - Creates a newsletter with the settings, and optionally the subscribers, of another
+			{Methods: []string{"POST"}, Path: "/{newsletter_id}/duplicate", Handler: app.nh.Duplicate, Scope: userdomain.ScopeNewslettersWrite},
+			// GET /newsletters/{newsletter_id}/subscribers - Lists the subscribers of a newsletter
+			{Methods: []string{"GET"}, Path: "/{newsletter_id}/subscribers", Handler: app.sh.ListSubscribers, Scope: userdomain.ScopeNewslettersRead, Middleware: []Middleware{SelectFields}},
+			// PATCH /newsletters/{newsletter_id}/subscribers/{subscription_id}/attributes - Edits the custom attributes of a subscriber
+			{Methods: []string{"PATCH"}, Path: "/{newsletter_id}/subscribers/{subscription_id}/attributes", Handler: app.sh.UpdateAttributes, Scope: userdomain.ScopeSubscribersWrite},
+			// POST /newsletters/{newsletter_id}/subscriptions/bulk-unsubscribe - Unsubscribes subscribers by email or filter, recording the reason
+			{Methods: []string{"POST"}, Path: "/{newsletter_id}/subscriptions/bulk-unsubscribe", Handler: app.sh.BulkUnsubscribe, Scope: userdomain.ScopeSubscribersWrite},
+			// GET /newsletters/{newsletter_id}/subscribers/export - Emails a download link to a CSV file of the subscribers
+			{Methods: []string{"GET"}, Path: "/{newsletter_id}/subscribers/export", Handler: app.xh.ExportSubscribers, Scope: userdomain.ScopeNewslettersRead},
+			// GET /newsletters/{newsletter_id}/stats/export - Emails a download link to a CSV file of the statistics
+			{Methods: []string{"GET"}, Path: "/{newsletter_id}/stats/export", Handler: app.xh.ExportStats, Scope: userdomain.ScopeAnalyticsRead},
+			// GET /newsletters/{newsletter_id}/analytics - Returns the subscriber growth time series
+			{Methods: []string{"GET"}, Path: "/{newsletter_id}/analytics", Handler: app.ah.Growth, Scope: userdomain.ScopeAnalyticsRead},
+			// GET /newsletters/{newsletter_id}/activity - Returns a page of the activity feed of a newsletter
+			{Methods: []string{"GET"}, Path: "/{newsletter_id}/activity", Handler: app.vh.Feed, Scope: userdomain.ScopeAnalyticsRead, Middleware: []Middleware{SelectFields}},
+			// GET /newsletters/{newsletter_id}/sender - Returns the sender verification status
+			{Methods: []string{"GET"}, Path: "/{newsletter_id}/sender", Handler: app.eh.Status, Scope: userdomain.ScopeNewslettersRead},
+			// POST /newsletters/{newsletter_id}/sender/verification - Sends a verification email to the sender address
+			{Methods: []string{"POST"}, Path: "/{newsletter_id}/sender/verification", Handler: app.eh.StartVerification, Scope: userdomain.ScopeNewslettersWrite},
+			// GET /newsletters/{newsletter_id}/sender/domain - Checks the SPF, DKIM and DMARC records of the sending domain
+			{Methods: []string{"GET"}, Path: "/{newsletter_id}/sender/domain", Handler: app.eh.CheckDomain, Scope: userdomain.ScopeNewslettersRead},
+		},
+	}
+}
+
+// postRoutes are the routes of the posts of a newsletter.
+func (app *App) postRoutes() RouteGroup {
+	return RouteGroup{
+		Name:          "posts",
+		Prefix:        "/newsletters/{newsletter_id}/posts",
+		Authenticated: true,
+		Routes: []Route{
+			// POST /newsletters/{newsletter_id}/posts - Creates a draft post
+			{Methods: []string{"POST"}, Path: "", Handler: app.ph.Create, Scope: userdomain.ScopeNewslettersWrite},
+			// GET /newsletters/{newsletter_id}/posts - Lists the posts of a newsletter
+			{Methods: []string{"GET"}, Path: "", Handler: app.ph.List, Scope: userdomain.ScopeNewslettersRead, Middleware: []Middleware{SelectFields}},
+			// GET /newsletters/{newsletter_id}/posts/{post_id} - Retrieves a post
+			{Methods: []string{"GET"}, Path: "/{post_id}", Handler: app.ph.Get, Scope: userdomain.ScopeNewslettersRead},
+			// PUT /newsletters/{newsletter_id}/posts/{post_id} - Edits a draft post
+			{Methods: []string{"PUT"}, Path: "/{post_id}", Handler: app.ph.Update, Scope: userdomain.ScopeNewslettersWrite},
+			// POST /newsletters/{newsletter_id}/posts/{post_id}/publish - Publishes a draft post
+			{Methods: []string{"POST"}, Path: "/{post_id}/publish", Handler: app.ph.Publish, Scope: userdomain.ScopeNewslettersWrite},
+			// POST /newsletters/{newsletter_id}/posts/{post_id}/archive - Archives a published post
+			{Methods: []string{"POST"}, Path: "/{post_id}/archive", Handler: app.ph.Archive, Scope: userdomain.ScopeNewslettersWrite},
+			// POST /newsletters/{newsletter_id}/posts/{post_id}/send - Sends a published post to subscribers (retries with the same Idempotency-Key are replayed)
+			{Methods: []string{"POST"}, Path: "/{post_id}/send", Handler: app.ph.Send, Scope: userdomain.ScopeIssuesSend, Middleware: []Middleware{app.Idempotent}},
+			// POST /newsletters/{newsletter_id}/posts/{post_id}/test - Sends a test email of a post to the owner or given addresses
+			{Methods: []string{"POST"}, Path: "/{post_id}/test", Handler: app.ph.Test, Scope: userdomain.ScopeNewslettersWrite},
+		},
+	}
+}
+
+// renderRoutes are the routes rendering unsaved content, for the live
+// previews of editors.
+func (app *App) renderRoutes() RouteGroup {
+	return RouteGroup{
+		Name:          "render",
+		Prefix:        "/render",
+		Authenticated: true,
+		Routes: []Route{
+			// POST /render/preview - Renders post content as the email a sample subscriber would receive
+			{Methods: []string{"POST"}, Path: "/preview", Handler: app.ph.Preview, Scope: userdomain.ScopeNewslettersRead},
+		},
+	}
+}
+
+// segmentRoutes are the routes of the segments of subscribers of a
+// newsletter.
+func (app *App) segmentRoutes() RouteGroup {
+	return RouteGroup{
+		Name:          "segments",
+		Prefix:        "/newsletters/{newsletter_id}/segments",
+		Authenticated: true,
+		Routes: []Route{
+			// POST /newsletters/{newsletter_id}/segments - Creates a segment of subscribers
+			{Methods: []string{"POST"}, Path: "", Handler: app.gh.Create, Scope: userdomain.ScopeNewslettersWrite},
+			// GET /newsletters/{newsletter_id}/segments - Lists the segments of a newsletter
+			{Methods: []string{"GET"}, Path: "", Handler: app.gh.List, Scope: userdomain.ScopeNewslettersRead, Middleware: []Middleware{SelectFields}},
+			// POST /newsletters/{newsletter_id}/segments/preview - Counts the subscribers a filter selects
+			{Methods: []string{"POST"}, Path: "/preview", Handler: app.gh.Preview, Scope: userdomain.ScopeNewslettersRead},
+			// GET /newsletters/{newsletter_id}/segments/{segment_id} - Retrieves a segment
+			{Methods: []string{"GET"}, Path: "/{segment_id}", Handler: app.gh.Get, Scope: userdomain.ScopeNewslettersRead},
+			// PUT /newsletters/{newsletter_id}/segments/{segment_id} - Edits a segment
+			{Methods: []string{"PUT"}, Path: "/{segment_id}", Handler: app.gh.Update, Scope: userdomain.ScopeNewslettersWrite},
+			// DELETE /newsletters/{newsletter_id}/segments/{segment_id} - Deletes a segment no unfinished campaign is sent to
+			{Methods: []string{"DELETE"}, Path: "/{segment_id}", Handler: app.gh.Delete, Scope: userdomain.ScopeNewslettersWrite},
+			// GET /newsletters/{newsletter_id}/segments/{segment_id}/count - Counts the subscribers currently in a segment
+			{Methods: []string{"GET"}, Path: "/{segment_id}/count", Handler: app.gh.Count, Scope: userdomain.ScopeNewslettersRead},
+		},
+	}
+}
+
+// adminRoutes are the routes of administrators, requiring the admin scope.
+func (app *App) adminRoutes() RouteGroup {
+	return RouteGroup{
+		Name:          "admin",
+		Prefix:        "/admin",
+		Authenticated: true,
+		Scope:         userdomain.ScopeAdmin,
+		Routes: []Route{
+			// GET /admin/stats - Returns system-wide statistics
+			{Methods: []string{"GET"}, Path: "/stats", Handler: app.th.Stats},
+			// GET /admin/errors - Lists the errors recently logged by the instance
+			{Methods: []string{"GET"}, Path: "/errors", Handler: app.th.Errors},
+			// GET /admin/throttling - Returns the caps on the campaigns sent at once by newsletter
+			{Methods: []string{"GET"}, Path: "/throttling", Handler: app.th.Throttling},
+			// PUT /admin/throttling - Changes the caps on the campaigns sent at once by newsletter until restart
+			{Methods: []string{"PUT"}, Path: "/throttling", Handler: app.th.UpdateThrottling},
+			// POST /admin/reload - Reloads the log level, workers, campaign throttling and abuse limits, as on SIGHUP
+			{Methods: []string{"POST"}, Path: "/reload", Handler: app.th.Reload},
+			// PUT /admin/users/{user_id}/plan - Puts a user on a plan
+			{Methods: []string{"PUT"}, Path: "/users/{user_id}/plan", Handler: app.lh.SetPlan},
+		},
+	}
+}
+
+// campaignRoutes are the routes of the campaigns of the current user.
+func (app *App) campaignRoutes() RouteGroup {
+	return RouteGroup{
+		Name:          "campaigns",
+		Prefix:        "/campaigns",
+		Authenticated: true,
+		Routes: []Route{
+			// GET /campaigns/{campaign_id} - Returns the progress of a campaign
+			{Methods: []string{"GET"}, Path: "/{campaign_id}", Handler: app.ch.Get, Scope: userdomain.ScopeNewslettersRead},
+			// GET /campaigns/{campaign_id}/events - Streams the progress of a campaign as Server-Sent Events
+			{Methods: []string{"GET"}, Path: "/{campaign_id}/events", Handler: app.ch.Events, Scope: userdomain.ScopeNewslettersRead},
+			// GET /campaigns/{campaign_id}/deliveries - Lists the per-recipient delivery log of a campaign
+			{Methods: []string{"GET"}, Path: "/{campaign_id}/deliveries", Handler: app.ch.Deliveries, Scope: userdomain.ScopeNewslettersRead, Middleware: []Middleware{SelectFields}},
+			// GET /campaigns/{campaign_id}/report - Streams the per-recipient report of a campaign as CSV or JSON
+			{Methods: []string{"GET"}, Path: "/{campaign_id}/report", Handler: app.ch.Report, Scope: userdomain.ScopeAnalyticsRead},
+			// GET /campaigns/{campaign_id}/replies - Lists the replies recipients sent to the emails of a campaign
+			{Methods: []string{"GET"}, Path: "/{campaign_id}/replies", Handler: app.ch.Replies, Scope: userdomain.ScopeNewslettersRead, Middleware: []Middleware{SelectFields}},
+			// POST /campaigns/{campaign_id}/pause - Pauses a queued or sending campaign
+			{Methods: []string{"POST"}, Path: "/{campaign_id}/pause", Handler: app.ch.Pause, Scope: userdomain.ScopeIssuesSend},
+			// POST /campaigns/{campaign_id}/resume - Resumes a paused or failed campaign
+			{Methods: []string{"POST"}, Path: "/{campaign_id}/resume", Handler: app.ch.Resume, Scope: userdomain.ScopeIssuesSend},
+		},
+	}
+}
+
+// webhookRoutes are the routes of the notifications of email providers,
+// authorized by the token query parameter.
+func (app *App) webhookRoutes() RouteGroup {
+	return RouteGroup{
+		Name:   "webhooks",
+		Prefix: "/webhooks",
+		Routes: []Route{
+			// POST /webhooks/ses - Receives SES delivery, bounce and complaint notifications from SNS
+			{Methods: []string{"POST"}, Path: "/ses", Handler: app.wh.SES},
+			// POST /webhooks/ses/inbound - Receives the emails SES receives, storing and forwarding the replies to campaigns
+			{Methods: []string{"POST"}, Path: "/ses/inbound", Handler: app.wh.SESInbound},
+		},
+	}
+}
+
+// publicRoutes are the routes of the public pages, feeds and scripts of
+// newsletters, and of the tracking of emails.
+func (app *App) publicRoutes() RouteGroup {
+	return RouteGroup{
+		Name: "public",
+		Routes: []Route{
+			// GET /embed/{slug}.js - Serves a script rendering a subscribe form, also by newsletter ID
+			{Methods: []string{"GET"}, Path: "/embed/{slug}.js", Handler: app.nh.EmbedScript},
+			// GET /track/open/{tracking_id} - Open tracking pixel of the emails of A/B tested campaigns
+			{Methods: []string{"GET"}, Path: "/track/open/{tracking_id}", Handler: app.ch.TrackOpen},
+			// GET /r/{code} - Short link of a campaign email, redirecting to its URL and counting the click
+			{Methods: []string{"GET"}, Path: "/r/{code}", Handler: app.rh.Redirect},
+			// GET /public/{slug} - Archive page of the published posts of a newsletter
+			{Methods: []string{"GET"}, Path: "/public/{slug}", Handler: app.bh.Archive},
+			// GET /public/{slug}/feed.xml - RSS 2.0 feed of the published posts, or Atom with ?format=atom
+			{Methods: []string{"GET"}, Path: "/public/{slug}/feed.xml", Handler: app.bh.Feed},
+			// GET /public/{slug}/subscribe - Hosted subscribe form, which can be shown in an iframe
+			{Methods: []string{"GET"}, Path: "/public/{slug}/subscribe", Handler: app.sh.SubscribePage},
+			// POST /public/{slug}/subscribe - Subscribes from the hosted subscribe form
+			{Methods: []string{"POST"}, Path: "/public/{slug}/subscribe", Handler: app.sh.SubscribeForm},
+			// GET /public/{slug}/subscribe/jsonp - Subscribes from a script tag, answering with a JSONP callback
+			{Methods: []string{"GET"}, Path: "/public/{slug}/subscribe/jsonp", Handler: app.sh.SubscribeJSONP},
+		},
+	}
+}
+
+// subscriptionRoutes are the routes subscribing and unsubscribing email
+// addresses. Clients guessing unsubscribe or confirm tokens are blocked.
+func (app *App) subscriptionRoutes() RouteGroup {
+	return RouteGroup{
+		Name:   "subscriptions",
+		Prefix: "/subscriptions",
+		Routes: []Route{
+			// GET /subscriptions/unsubscribe - Branded page confirming an unsubscription (linked from emails)
+			{Methods: []string{"GET"}, Path: "/unsubscribe", Handler: app.sh.UnsubscribePage, Middleware: []Middleware{app.GuardTokens}},
+			// POST /subscriptions/unsubscribe - Unsubscribes from the branded page, then redirects if configured.
+			// Registered before /{newsletter_id}, which would match it too.
+			{Methods: []string{"POST"}, Path: "/unsubscribe", Handler: app.sh.UnsubscribeConfirm, Middleware: []Middleware{app.GuardTokens}},
+			// GET /subscriptions/confirm - Branded page confirming a re-subscription (linked from emails)
+			{Methods: []string{"GET"}, Path: "/confirm", Handler: app.sh.ConfirmPage, Middleware: []Middleware{app.GuardTokens}},
+			// POST /subscriptions/confirm - Reactivates the pending subscription from the branded page
+			{Methods: []string{"POST"}, Path: "/confirm", Handler: app.sh.ConfirmSubscription, Middleware: []Middleware{app.GuardTokens}},
+			// POST /subscriptions/{newsletter_id} - Subscribes the current user to a newsletter (CORS per newsletter; retries with the same Idempotency-Key are replayed)
+			{Methods: []string{"POST", "OPTIONS"}, Path: "/{newsletter_id}", Handler: app.sh.Subscribe, Middleware: []Middleware{app.SubscribeCORS, app.Idempotent}},
+			// DELETE /subscriptions/unsubscribe - Unsubscribes the current user from a newsletter
+			{Methods: []string{"DELETE"}, Path: "/unsubscribe", Handler: app.sh.Unsubscribe, Middleware: []Middleware{app.GuardTokens}},
+			// DELETE /subscriptions/unsubscribe-all - Unsubscribes an email address from all newsletters
+			{Methods: []string{"DELETE"}, Path: "/unsubscribe-all", Handler: app.sh.UnsubscribeAll},
+		},
+	}
+}
+
+// verifyFirestoreIndexes logs an error when indexes declared in the manifest
+// at path are not deployed, as the subscriber queries needing them fail
+// until they are. It runs in the background so as not to delay startup.
+func verifyFirestoreIndexes(path string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	checked, err := firebase.VerifyIndexes(ctx, path)
+	if err != nil {
+		slog.Error("Firestore indexes are not ready", "error", err)
+		return
+	}
+	slog.Info("Firestore indexes ready", "indexes", checked)
 }
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"newsletter/config"
+	"newsletter/internal/infrastructure/secrets"
+	"newsletter/internal/infrastructure/workerpool"
+	"newsletter/transport/http/handler"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// addr is the address the API listens on.
+const addr = ":8001"
+
+// shutdownTimeout is how long requests in flight have to complete once the
+// API is asked to stop.
+const shutdownTimeout = 5 * time.Second
+
+// RunOptions are what Run needs besides the configuration.
+type RunOptions struct {
+	// LogLevel is the level of the logs, changed by Reload. The level of
+	// cfg is not applied when it is nil.
+	LogLevel *slog.LevelVar
+	// RecentErrors are the errors listed by GET /admin/errors; may be nil.
+	RecentErrors handler.RecentErrors
+
+	// Secrets, when not nil, are fetched again every SecretsRefresh to
+	// reload the JWT keys; other secrets, such as the DSN, take effect on
+	// restart.
+	Secrets        secrets.Source
+	SecretsRefresh time.Duration
+}
+
+// Run runs the API until ctx is cancelled. It is the single entrypoint of
+// the API:
+//
+//  1. Starts the worker pool sized by cfg.
+//  2. Builds the App with NewApp.
+//  3. Starts the alerting monitor and the refresh of secrets, and resumes the
+//     campaigns an earlier instance did not finish.
+//  4. Serves the routes of the App on port 8001, reloading the configuration
+//     on SIGHUP.
+//
+// Once ctx is cancelled, it stops accepting requests, waits up to
+// shutdownTimeout for the requests in flight, waits for the queued jobs to
+// complete and closes the database connections. It returns an error when
+// the server cannot listen.
+func Run(ctx context.Context, cfg *config.Config, opts RunOptions) error {
+	wp := newWorkerPool(cfg)
+	wp.Start()
+
+	app := NewApp(cfg, wp, opts.RecentErrors)
+	defer app.Close()
+	if opts.LogLevel != nil {
+		opts.LogLevel.Set(cfg.LogLevel)
+		app.SetLogLevel(opts.LogLevel)
+	}
+
+	background, stopBackground := context.WithCancel(ctx)
+	defer stopBackground()
+	app.StartMonitoring(background)
+	if opts.Secrets != nil && opts.SecretsRefresh > 0 {
+		go secrets.Refresh(background, opts.Secrets, opts.SecretsRefresh, func(changed []string) {
+			if slices.Contains(changed, "JWT_SECRET_KEY") || slices.Contains(changed, "JWT_PREVIOUS_SECRET_KEYS") {
+				app.ReloadKeys()
+			}
+		})
+	}
+	app.ResumeCampaigns()
+
+	// SIGHUP reloads the configuration without restarting.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+	go func() {
+		for {
+			select {
+			case <-background.Done():
+				return
+			case <-reload:
+				if _, err := app.Reload(); err != nil {
+					slog.Error("failed to reload the configuration", "error", err)
+				}
+			}
+		}
+	}()
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: app.Routes(),
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe()
+	}()
+	slog.Info("listening", "addr", addr)
+
+	var err error
+	select {
+	case err = <-served:
+		err = fmt.Errorf("serve: %w", err)
+	case <-ctx.Done():
+		slog.Info("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("requests in flight did not complete", "error", err)
+		}
+		cancel()
+		if err = <-served; errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	}
+
+	stopBackground()
+	wp.Shutdown()
+	wp.Wait()
+	return err
+}
+
+// newWorkerPool returns the worker pool of the background jobs, sized and
+// configured by cfg.Workers.
+func newWorkerPool(cfg *config.Config) *workerpool.WorkerPool {
+	wp := workerpool.New(cfg.Workers.Count, cfg.Workers.BufferSize, &sync.WaitGroup{})
+	wp.SetOverflow(cfg.Workers.Overflow)
+	wp.SetJobTimeout(cfg.Workers.JobTimeout)
+	return wp
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"newsletter/transport/http/handler"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// supportedAPIVersions lists the API versions the server can respond with.
+var supportedAPIVersions = map[int]bool{1: true}
+
+// legacyRoutesDeprecatedAt is when the unversioned routes were deprecated in
+// favour of the /v1 prefix.
+var legacyRoutesDeprecatedAt = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+// defaultLegacySunset is when the unversioned routes stop being served unless
+// LEGACY_API_SUNSET overrides it.
+var defaultLegacySunset = legacyRoutesDeprecatedAt.AddDate(0, 6, 0)
+
+// vendorMediaType matches versioned media types such as "application/vnd.newsletter.v1+json".
+var vendorMediaType = regexp.MustCompile(`application/vnd\.newsletter\.v(\d+)\+json`)
+
+// NegotiateVersion is a middleware that selects the API version of a request.
+//
+// The version comes from, in order of precedence:
+//   - the path prefix of versioned routes (e.g. /v1/...), passed as pathVersion
+//   - the "API-Version" request header
+//   - a vendor media type in the "Accept" header (application/vnd.newsletter.v1+json)
+//
+// and defaults to handler.CurrentAPIVersion. The selected version is stored in
+// the request context (see handler.APIVersion) and echoed in the "API-Version"
+// response header. Requests for an unsupported version are rejected with
+// 406 Not Acceptable. A pathVersion of 0 means the route is not versioned.
+func NegotiateVersion(pathVersion int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			version := pathVersion
+			if version == 0 {
+				requested, err := requestedAPIVersion(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				version = requested
+			}
+
+			if !supportedAPIVersions[version] {
+				slog.Warn("unsupported API version requested", "version", version, "path", r.URL.Path)
+				http.Error(w, fmt.Sprintf("unsupported API version %d", version), http.StatusNotAcceptable)
+				return
+			}
+
+			w.Header().Set("API-Version", strconv.Itoa(version))
+			w.Header().Add("Vary", "API-Version")
+			w.Header().Add("Vary", "Accept")
+
+			next.ServeHTTP(w, r.WithContext(handler.WithAPIVersion(r.Context(), version)))
+		})
+	}
+}
+
+// requestedAPIVersion reads the version asked for in the request headers.
+func requestedAPIVersion(r *http.Request) (int, error) {
+	if header := r.Header.Get("API-Version"); header != "" {
+		version, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(header), "v"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid API-Version header %q", header)
+		}
+		return version, nil
+	}
+
+	if match := vendorMediaType.FindStringSubmatch(r.Header.Get("Accept")); match != nil {
+		return strconv.Atoi(match[1])
+	}
+
+	return handler.CurrentAPIVersion, nil
+}
+
+// Deprecated is a middleware that marks responses of legacy routes as
+// deprecated.
+//
+// It sets the "Deprecation" (RFC 9745) and "Sunset" (RFC 8594) headers, and a
+// "Link" header pointing to the same path under the successor prefix, so that
+// API consumers can detect and migrate away from the route before it is removed.
+func Deprecated(deprecatedAt, sunset time.Time, successorPrefix string) func(http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Sunset", sunsetDate)
+			w.Header().Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successorPrefix, r.URL.Path))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}